@@ -3,7 +3,12 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 */
 package main
 
-import "github.com/trobanga/aether/cmd"
+import (
+	// Embedded zone database, so time_zone works in minimal container images
+	_ "time/tzdata"
+
+	"github.com/trobanga/aether/cmd"
+)
 
 func main() {
 	cmd.Execute()
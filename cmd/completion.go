@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
 )
 
 var completionCmd = &cobra.Command{
@@ -71,3 +76,81 @@ PowerShell:
 func init() {
 	rootCmd.AddCommand(completionCmd)
 }
+
+// completeJobIDs completes the job ID argument from the jobs directory
+// Each candidate carries the job's status and current step as description
+func completeJobIDs(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	jobIDs, err := services.ListAllJobs(config.JobsDir)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	completions := make([]cobra.Completion, 0, len(jobIDs))
+	for _, jobID := range jobIDs {
+		if !strings.HasPrefix(jobID, toComplete) {
+			continue
+		}
+		job, err := services.LoadJobState(config.JobsDir, jobID)
+		if err != nil {
+			completions = append(completions, jobID)
+			continue
+		}
+		completions = append(completions, cobra.CompletionWithDesc(jobID, fmt.Sprintf("%s, %s", job.Status, job.CurrentStep)))
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeStepNames completes --step with the steps enabled for the job given as first
+// argument, or with all known steps if the job cannot be loaded
+func completeStepNames(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	steps := models.AllStepNames
+	if len(args) > 0 {
+		if config, err := loadConfig(); err == nil {
+			if job, err := services.LoadJobState(config.JobsDir, args[0]); err == nil && len(job.Config.Pipeline.EnabledSteps) > 0 {
+				steps = job.Config.Pipeline.EnabledSteps
+			}
+		}
+	}
+
+	completions := make([]cobra.Completion, 0, len(steps))
+	for _, step := range steps {
+		if strings.HasPrefix(string(step), toComplete) {
+			completions = append(completions, string(step))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeInspectArgs completes the job ID, then the job's NDJSON files relative to its directory
+func completeInspectArgs(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeJobIDs(cmd, args, toComplete)
+	}
+	if len(args) > 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	jobDir := services.GetJobDir(config.JobsDir, args[0])
+	var completions []cobra.Completion
+	for _, dir := range services.JobDataDirs {
+		matches, _ := filepath.Glob(filepath.Join(jobDir, dir, "*.ndjson"))
+		for _, match := range matches {
+			name := filepath.ToSlash(filepath.Join(dir, filepath.Base(match)))
+			if strings.HasPrefix(name, toComplete) {
+				completions = append(completions, name)
+			}
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var (
+	conformanceCRTDL string
+	conformanceJSON  bool
+)
+
+// conformanceCmd represents the conformance command
+var conformanceCmd = &cobra.Command{
+	Use:   "conformance",
+	Short: "Check compatibility with the configured services and report their versions",
+	Long: `Run a defined battery of safe, small operations against every configured
+service and report compatibility per service version, e.g. after a TORCH or
+DIMP upgrade or before rolling out a new aether release.
+
+Checks per service:
+  torch              - version: software version from the CapabilityStatement
+                       auth: credentials accepted
+                       tiny_extraction: extract the --crtdl cohort (skipped without --crtdl)
+                       result_cleanup: delete the extraction result on the server
+  dimp               - version: software version from the CapabilityStatement
+                       pseudonymize_resource: round-trip a synthetic Patient
+                       pseudonymize_bundle: round-trip a synthetic Bundle, checking
+                       that references follow the pseudonymized IDs
+  csv_conversion,
+  parquet_conversion - version (Server header), convert a 3-line NDJSON file
+  dicomweb           - version (Server header), metadata of a non-existent study
+
+Unlike preflight, every configured service is checked, whether or not its
+step is enabled. A missing version is reported but not a failure. Only the
+tiny extraction touches real data: choose a CRTDL selecting a handful of
+patients. Its result is not downloaded and is deleted afterwards.
+
+Exit status is non-zero when any check fails.
+
+Examples:
+  # Check the production services
+  aether conformance --config prod.yaml
+
+  # Include a tiny extraction and keep the report
+  aether conformance --config prod.yaml --crtdl tiny.crtdl --json > conformance.json`,
+	Args: cobra.NoArgs,
+	RunE: runConformance,
+}
+
+func init() {
+	rootCmd.AddCommand(conformanceCmd)
+
+	conformanceCmd.Flags().StringVar(&conformanceCRTDL, "crtdl", "", "CRTDL for a tiny TORCH extraction (skipped if not given)")
+	conformanceCmd.Flags().BoolVar(&conformanceJSON, "json", false, "Output the report as JSON")
+	_ = conformanceCmd.MarkFlagFilename("crtdl", "crtdl", "json")
+}
+
+func runConformance(cmd *cobra.Command, args []string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+
+	// Single attempt - like preflight, report incompatibilities instead of retrying them
+	retry := config.Retry
+	retry.MaxAttempts = 1
+	httpClient := services.NewHTTPClient(30*time.Second, retry, logger)
+
+	report := pipeline.RunConformance(config, httpClient, logger, pipeline.ConformanceOptions{CRTDLPath: conformanceCRTDL})
+
+	if conformanceJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printConformanceReport(report)
+	}
+
+	if len(report.Services) == 0 {
+		return fmt.Errorf("no services configured")
+	}
+	if !report.IsCompatible() {
+		return fmt.Errorf("conformance checks failed")
+	}
+	return nil
+}
+
+func printConformanceReport(report pipeline.ConformanceReport) {
+	fmt.Printf("Conformance of aether %s with the configured services\n", report.AetherVersion)
+
+	for _, service := range report.Services {
+		fmt.Printf("\n%s  %s\n", service.Service, service.URL)
+		fmt.Printf("  version: %s\n", service.Version)
+		for _, check := range service.Checks {
+			fmt.Printf("  %s %-22s %s (%dms)\n", getPreflightStatusSymbol(check.Status), check.Name, check.Message, check.DurationMs)
+		}
+		if service.IsCompatible() {
+			fmt.Println("  ✓ compatible")
+		} else {
+			fmt.Println("  ✗ incompatible")
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case len(report.Services) == 0:
+		fmt.Println("No services configured")
+	case report.IsCompatible():
+		fmt.Println("✓ All configured services are compatible")
+	default:
+		fmt.Println("✗ Incompatible services found: see the failed checks above")
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/server"
+)
+
+var (
+	manDir     string
+	openAPIOut string
+)
+
+// docsCmd represents the docs command group
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate reference documentation",
+	Long: `Generate reference documentation from the command tree.
+
+Available subcommands:
+  man     - Write man pages for all commands
+  openapi - Write the OpenAPI document of the REST API`,
+}
+
+// docsManCmd represents the docs man command
+var docsManCmd = &cobra.Command{
+	Use:   "man",
+	Short: "Generate man pages",
+	Long: `Generate a man page (section 1) for aether and every subcommand.
+
+The pages are generated from the same command tree as --help, so they always
+match the installed binary.
+
+Examples:
+  # Write the pages to ./man
+  aether docs man
+
+  # Install them for the current user
+  aether docs man --dir ~/.local/share/man/man1
+  man aether-pipeline-start`,
+	Args: cobra.NoArgs,
+	RunE: runDocsMan,
+}
+
+// docsOpenAPICmd represents the docs openapi command
+var docsOpenAPICmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Generate the OpenAPI document of the REST API",
+	Long: `Write the OpenAPI 3 document that 'aether serve' publishes at /openapi.json,
+without starting a server.
+
+The document describes the stable job status schema (JobStatusView) shared
+by the REST API, the gRPC API and 'aether job list --json'. Client
+generators can use it directly; 'make python-client' builds a Python client
+from the copy in api/openapi.json.
+
+Examples:
+  # Print the document
+  aether docs openapi
+
+  # Refresh the committed copy
+  aether docs openapi --out api/openapi.json`,
+	Args: cobra.NoArgs,
+	RunE: runDocsOpenAPI,
+}
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+	docsCmd.AddCommand(docsManCmd)
+	docsCmd.AddCommand(docsOpenAPICmd)
+
+	docsOpenAPICmd.Flags().StringVarP(&openAPIOut, "out", "o", "-", "File to write the document to ('-' = stdout)")
+
+	docsManCmd.Flags().StringVar(&manDir, "dir", "./man", "Directory to write the man pages to")
+	_ = docsManCmd.MarkFlagDirname("dir")
+}
+
+func runDocsMan(cmd *cobra.Command, args []string) error {
+	if err := os.MkdirAll(manDir, 0755); err != nil {
+		return fmt.Errorf("failed to create man page directory: %w", err)
+	}
+
+	root := cmd.Root()
+	header := &doc.GenManHeader{
+		Title:   "AETHER",
+		Section: "1",
+		Source:  "Aether " + root.Version,
+		Manual:  "Aether Manual",
+	}
+	// Leave out the "Auto generated by spf13/cobra on <date>" footer
+	root.DisableAutoGenTag = true
+	if err := doc.GenManTree(root, header, manDir); err != nil {
+		return fmt.Errorf("failed to generate man pages: %w", err)
+	}
+
+	fmt.Printf("✓ Man pages written to %s\n", manDir)
+	return nil
+}
+
+func runDocsOpenAPI(cmd *cobra.Command, args []string) error {
+	// The document only depends on the route table, not on the configuration
+	document := server.New(models.ProjectConfig{}, lib.DefaultLogger).OpenAPI()
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode OpenAPI document: %w", err)
+	}
+	data = append(data, '\n')
+
+	if openAPIOut == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(openAPIOut, data, 0644); err != nil {
+		return fmt.Errorf("failed to write OpenAPI document: %w", err)
+	}
+	fmt.Printf("✓ OpenAPI document written to %s\n", openAPIOut)
+	return nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// downloadCmd represents the download command
+var downloadCmd = &cobra.Command{
+	Use:   "download <torch-result-url>",
+	Short: "Download a TORCH extraction result without creating a job",
+	Long: `Poll a TORCH extraction until it is complete and download its NDJSON files
+into a directory, using the same retries, backoff and progress display as the
+import step of a pipeline job, but without creating a job.
+
+TORCH credentials, polling intervals, the extraction timeout, limits.max_files
+and retry settings are taken from the configuration file. Without
+services.torch.base_url, file URLs are resolved against the result URL's server.
+
+Examples:
+  # Download into the current directory
+  aether download http://torch.hospital.org/fhir/extraction/abc-123
+
+  # Download into a directory and delete the result on the server afterwards
+  aether download http://torch.hospital.org/fhir/extraction/abc-123 -o ./export --cleanup
+
+  # In scripts: no progress bars, non-zero exit code on failure
+  aether download "$RESULT_URL" -o /data/torch --no-progress`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDownload,
+}
+
+var (
+	downloadOutput  string
+	downloadCleanup bool
+)
+
+func init() {
+	rootCmd.AddCommand(downloadCmd)
+
+	downloadCmd.Flags().StringVarP(&downloadOutput, "output", "o", ".", "Directory to write the NDJSON files to")
+	downloadCmd.Flags().BoolVar(&downloadCleanup, "cleanup", false, "Delete the result on the TORCH server after the download (default: services.torch.cleanup_after_download)")
+	downloadCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Disable progress indicators")
+	downloadCmd.Flags().BoolVar(&jsonProgress, "json-progress", false, "Write progress as JSON lines to stderr instead of spinners and bars")
+}
+
+func runDownload(cmd *cobra.Command, args []string) error {
+	resultURL := args[0]
+	parsedURL, err := url.Parse(resultURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return fmt.Errorf("not a TORCH result URL: %s", resultURL)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	// Relative and internal file URLs are resolved against the TORCH server of the result
+	if config.Services.TORCH.BaseURL == "" {
+		config.Services.TORCH.BaseURL = parsedURL.Scheme + "://" + parsedURL.Host
+	}
+	cleanup := config.Services.TORCH.CleanupAfterDownload
+	if cmd.Flags().Changed("cleanup") {
+		cleanup = downloadCleanup
+	}
+
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+
+	// Stop polling and retries on Ctrl-C; files downloaded so far are kept
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	httpClient := services.NewHTTPClient(
+		time.Duration(config.Retry.InitialBackoffMs)*time.Millisecond*10, // Longer timeout for downloads
+		config.Retry,
+		logger,
+	)
+	httpClient.SetContext(ctx)
+	torchClient := services.NewTORCHClient(config.Services.TORCH, httpClient, logger)
+	torchClient.SetResponseCache(services.NewTORCHResponseCache(config.JobsDir, config.Services.TORCH))
+	progress := newProgressReporter(noProgress)
+
+	start := time.Now()
+	fileURLs, err := torchClient.PollExtractionStatus(resultURL, progress)
+	if err != nil {
+		return fmt.Errorf("failed to get TORCH result: %w", err)
+	}
+	if len(fileURLs) == 0 {
+		fmt.Println("TORCH result contains no files (empty cohort)")
+		return nil
+	}
+	if err := config.Limits.CheckFileCount(len(fileURLs)); err != nil {
+		return err
+	}
+
+	files, err := torchClient.DownloadExtractionFiles(fileURLs, downloadOutput, progress)
+	if err != nil {
+		return fmt.Errorf("failed to download TORCH files: %w", err)
+	}
+
+	var totalBytes int64
+	for _, file := range files {
+		totalBytes += file.FileSize
+		fmt.Printf("  %s (%d resources, %s)\n", file.FileName, file.LineCount, formatBytes(file.FileSize))
+	}
+	fmt.Printf("✓ Downloaded %d files (%s) to %s in %s\n", len(files), formatBytes(totalBytes), downloadOutput, time.Since(start).Round(time.Second))
+
+	if cleanup {
+		err := torchClient.DeleteExtractionResult(resultURL, fileURLs)
+		switch {
+		case errors.Is(err, services.ErrTORCHCleanupUnsupported):
+			logger.Warn("TORCH server does not support deleting extraction results", "url", resultURL)
+		case err != nil:
+			logger.Warn("Failed to clean up TORCH extraction result", "url", resultURL, "error", err)
+		default:
+			fmt.Println("✓ Deleted the result on the TORCH server")
+		}
+	}
+	return nil
+}
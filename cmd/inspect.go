@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <job-id> <file>",
+	Short: "Browse FHIR resources in a job's NDJSON file",
+	Long: `Stream an NDJSON file from a job and print matching resources.
+
+The file can be a bare filename, which is looked up in the job's import/,
+pseudonymized/ and quarantine/ directories (in that order), or a path
+relative to the job directory.
+
+Filters (combined with AND):
+  --type   Resource type (e.g., Patient)
+  --id     Resource id
+  --where  FHIRPath expression (subset: paths, comparisons, and/or,
+           exists(), empty(), count(), first(), not(), where())
+
+Examples:
+  # Pretty-print the first 5 resources of an imported file
+  aether inspect abc123 Patient.ndjson --limit 5
+
+  # Spot-check pseudonymized Patients born after 2010
+  aether inspect abc123 pseudonymized/dimped_Patient.ndjson --where "birthDate > @2010-01-01"
+
+  # Find a single resource
+  aether inspect abc123 dimped_patients.ndjson --type Patient --id pseudo-123
+
+  # Emit compact NDJSON for further processing
+  aether inspect abc123 dimped_Observation.ndjson --where "status = 'final'" --compact`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeInspectArgs,
+	RunE:              runInspect,
+}
+
+var (
+	inspectType    string
+	inspectID      string
+	inspectWhere   string
+	inspectLimit   int
+	inspectCompact bool
+)
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().StringVar(&inspectType, "type", "", "Only show resources of this resourceType")
+	inspectCmd.Flags().StringVar(&inspectID, "id", "", "Only show the resource with this id")
+	inspectCmd.Flags().StringVar(&inspectWhere, "where", "", "Only show resources matching this FHIRPath expression")
+	inspectCmd.Flags().IntVar(&inspectLimit, "limit", 0, "Stop after this many matches (0 = no limit)")
+	inspectCmd.Flags().BoolVar(&inspectCompact, "compact", false, "Print one resource per line instead of pretty-printing")
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	jobID := args[0]
+	fileName := args[1]
+
+	// Load configuration
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	filter := lib.ResourceFilter{ResourceType: inspectType, ID: inspectID}
+	if inspectWhere != "" {
+		filter.Where, err = lib.CompileFHIRPath(inspectWhere)
+		if err != nil {
+			return err
+		}
+	}
+
+	path, err := services.ResolveJobFile(config.JobsDir, jobID, fileName)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	maxLineBytes := config.Limits.GetMaxLineBytes()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+
+	lineNumber := 0
+	scanned := 0
+	matched := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		scanned++
+
+		var resource map[string]any
+		if err := json.Unmarshal([]byte(line), &resource); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping line %d (invalid JSON: %v)\n", lineNumber, err)
+			continue
+		}
+
+		if !filter.Matches(resource) {
+			continue
+		}
+		matched++
+
+		if inspectCompact {
+			fmt.Println(line)
+		} else {
+			pretty, err := json.MarshalIndent(resource, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to format resource at line %d: %w", lineNumber, err)
+			}
+			resourceType, _ := resource["resourceType"].(string)
+			resourceID, _ := resource["id"].(string)
+			fmt.Printf("# line %d: %s/%s\n%s\n\n", lineNumber, resourceType, resourceID, pretty)
+		}
+
+		if inspectLimit > 0 && matched >= inspectLimit {
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %s at line %d: %w", filepath.Base(path), lineNumber+1, err)
+	}
+
+	// Summary goes to stderr so --compact output can be piped
+	fmt.Fprintf(os.Stderr, "%d of %d resources matched in %s\n", matched, scanned, filepath.Base(path))
+	return nil
+}
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -10,6 +14,7 @@ import (
 	"github.com/trobanga/aether/internal/models"
 	"github.com/trobanga/aether/internal/pipeline"
 	"github.com/trobanga/aether/internal/services"
+	"github.com/trobanga/aether/internal/ui"
 )
 
 // jobCmd represents the job command group
@@ -19,8 +24,14 @@ var jobCmd = &cobra.Command{
 	Long: `Manage pipeline jobs: list, inspect, and control job execution.
 
 Available subcommands:
-  list - List all pipeline jobs
-  run  - Execute a specific pipeline step manually`,
+  list   - List all pipeline jobs
+  status - Show a job's status (same as 'pipeline status')
+  show   - Show a job's step timeline
+  run    - Execute a specific pipeline step manually
+  check  - Run configured sanity checks against a job's output
+  export - Package a job into an archive for another machine
+  import - Import a job archive created by 'job export'
+  share  - Create a read-only, expiring link to a job's status`,
 }
 
 // jobListCmd represents the job list command
@@ -37,7 +48,8 @@ Shows:
   • Retry count for current step
   • Job age (elapsed time since creation)
 
-Jobs are sorted by creation time (newest first).
+Jobs are sorted by creation time (newest first). Use --status to show only
+jobs in the given states (pending, in_progress, completed, failed).
 
 Status Symbols:
   ✓  - Job completed successfully
@@ -49,17 +61,64 @@ Examples:
   # List all jobs
   aether job list
 
+  # Jobs that need attention
+  aether job list --status failed
+
+  # Jobs that are not finished yet
+  aether job list --status pending,in_progress
+
   # Continuously monitor all jobs
   watch -n 5 aether job list
 
+  # Machine-readable output (same schema as GET /api/v1/jobs)
+  aether job list --json
+
 Typical Workflow:
   1. Start pipeline:  aether pipeline start /data
   2. List jobs:       aether job list
   3. Get job ID from list
-  4. Check status:    aether pipeline status <job-id>`,
+  4. Check status:    aether job status <job-id>
+  5. Step timeline:   aether job show <job-id>`,
 	RunE: runJobList,
 }
 
+// jobStatusCmd represents the job status command
+var jobStatusCmd = &cobra.Command{
+	Use:   "status <job-id>",
+	Short: "Check pipeline job status",
+	Long: `Display the current status of a pipeline job.
+
+This is the same as 'aether pipeline status', see there for details.
+
+Examples:
+  # Check job status
+  aether job status abc-123-def
+
+  # Include the event timeline
+  aether job status abc-123-def --events`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runPipelineStatus,
+}
+
+// jobShowCmd represents the job show command
+var jobShowCmd = &cobra.Command{
+	Use:   "show <job-id>",
+	Short: "Show a job's step timeline",
+	Long: `Show when each step of a job started and finished.
+
+For every step the timeline lists its status, start time, duration, retries
+and the last error. The offset column is the time since the job was created,
+so waiting between steps (e.g. a job resumed the next day) is visible.
+
+Examples:
+  # Find where a slow job spent its time
+  aether job show abc-123-def`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runJobShow,
+}
+
 // jobRunCmd represents the job run command
 var jobRunCmd = &cobra.Command{
 	Use:   "run <job-id> --step <step-name>",
@@ -74,8 +133,9 @@ useful for:
   • Manual recovery after errors
 
 Available Steps:
-  import            - Import FHIR data from local or HTTP source
+  import            - Import FHIR data: the job's torch, local_import or http_import step
   dimp              - Pseudonymize data via DIMP service
+  imaging           - Retrieve DICOM metadata for ImagingStudy resources, rewrite WADO URLs
   validation        - Validate FHIR data (placeholder)
   csv_conversion    - Convert FHIR to CSV format
   parquet_conversion - Convert FHIR to Parquet format
@@ -99,109 +159,216 @@ Error Handling:
   • Transient errors (network, 5xx) are retried automatically
   • Non-transient errors (4xx, validation) stop execution
   • Use 'pipeline status' to check step status after execution`,
-	Args: cobra.ExactArgs(1),
-	RunE: runJobRun,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runJobRun,
+}
+
+// jobCheckCmd represents the job check command
+var jobCheckCmd = &cobra.Command{
+	Use:   "check <job-id>",
+	Short: "Run configured sanity checks against a job's output",
+	Long: `Evaluate the sanity_checks from the configuration against a job's output
+and assert that each count lies in its expected range.
+
+Each check counts resources of a given type matching an optional FHIRPath
+expression. Checks run against pseudonymized/ if it contains data, otherwise
+against import/.
+
+Configuration example:
+  sanity_checks:
+    - name: "Patients in cohort"
+      resource_type: Patient
+      min: 100
+      max: 5000
+    - name: "No patients born after 2010"
+      resource_type: Patient
+      where: "birthDate > @2010-01-01"
+      max: 0
+
+Examples:
+  # Check a job before delivery
+  aether job check abc123
+
+Exit status is non-zero if any check fails.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runJobCheck,
 }
 
-var stepFlag string
+var (
+	stepFlag   string
+	listJSON   bool
+	listStatus []string
+)
 
 func init() {
 	rootCmd.AddCommand(jobCmd)
 	jobCmd.AddCommand(jobListCmd)
+	jobCmd.AddCommand(jobStatusCmd)
+	jobCmd.AddCommand(jobShowCmd)
 	jobCmd.AddCommand(jobRunCmd)
+	jobCmd.AddCommand(jobCheckCmd)
 
 	// Add --step flag to job run command
 	jobRunCmd.Flags().StringVar(&stepFlag, "step", "", "Pipeline step to execute (required)")
+	jobRunCmd.Flags().StringVar(&emitTrace, "emit-trace", "", "Write the step's trace file to this directory and use workflow exit codes (0, 1, 3, 75)")
+	jobListCmd.Flags().BoolVar(&listJSON, "json", false, "Output jobs as JSON in the schema of the REST API")
+	jobListCmd.Flags().StringSliceVar(&listStatus, "status", nil, "Only list jobs with these statuses (pending, in_progress, completed, failed)")
+	jobStatusCmd.Flags().BoolVar(&showEvents, "events", false, "Show the job's event timeline")
+	jobStatusCmd.Flags().BoolVar(&statusJSON, "json", false, "Output job state as JSON (includes log context of failed steps)")
 	if err := jobRunCmd.MarkFlagRequired("step"); err != nil {
 		panic(fmt.Sprintf("failed to mark 'step' flag as required: %v", err))
 	}
+	if err := jobRunCmd.RegisterFlagCompletionFunc("step", completeStepNames); err != nil {
+		panic(fmt.Sprintf("failed to register 'step' flag completion: %v", err))
+	}
 }
 
 func runJobList(cmd *cobra.Command, args []string) error {
+	statuses, err := parseJobStatuses(listStatus)
+	if err != nil {
+		return err
+	}
+
 	// Load configuration
-	config, err := services.LoadConfig(cfgFile)
+	config, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
 	}
 
-	// List all job IDs
-	jobIDs, err := services.ListAllJobs(config.JobsDir)
+	// Load matching jobs, newest first
+	jobs, err := services.ListJobs(config.JobsDir, statuses, lib.DefaultLogger)
 	if err != nil {
 		return fmt.Errorf("failed to list jobs: %w", err)
 	}
 
-	if len(jobIDs) == 0 {
-		fmt.Println("No jobs found")
-		return nil
+	if listJSON {
+		return printJobViewsJSON(jobs)
 	}
 
-	// Load each job and display summary
-	type jobSummary struct {
-		ID         string
-		Status     string
-		Step       string
-		Files      int
-		RetryCount int
-		CreatedAt  time.Time
-		ElapsedStr string
+	if len(jobs) == 0 {
+		fmt.Println("No jobs found")
+		return nil
 	}
 
-	var jobs []jobSummary
-	for _, jobID := range jobIDs {
-		job, err := pipeline.LoadJob(config.JobsDir, jobID)
-		if err != nil {
-			lib.DefaultLogger.Warn("Failed to load job", "job_id", jobID, "error", err)
-			continue
-		}
-
-		elapsed := time.Since(job.CreatedAt)
-		elapsedStr := formatDuration(elapsed)
+	// Print table header
+	fmt.Printf("%-38s %-15s %-20s %-8s %-8s %-6s %s\n", "JOB ID", "STATUS", "STEP", "FILES", "RETRIES", "AGE", "STUDY")
+	fmt.Println("------------------------------------------------------------------------------------------------------------------------")
 
+	// Print jobs
+	for _, job := range jobs {
 		// Get retry count from current step
 		retryCount := 0
 		if currentStep, found := pipeline.GetCurrentStep(job); found {
 			retryCount = currentStep.RetryCount
 		}
 
-		jobs = append(jobs, jobSummary{
-			ID:         job.JobID,
-			Status:     string(job.Status),
-			Step:       job.CurrentStep,
-			Files:      job.TotalFiles,
-			RetryCount: retryCount,
-			CreatedAt:  job.CreatedAt,
-			ElapsedStr: elapsedStr,
-		})
+		fmt.Printf("%-38s %s %-13s %-20s %-8d %-8d %-6s %s\n",
+			job.JobID,
+			getJobStatusSymbol(string(job.Status)),
+			job.Status,
+			job.CurrentStep,
+			job.TotalFiles,
+			retryCount,
+			formatDuration(time.Since(job.CreatedAt)),
+			job.Annotations.Label(),
+		)
 	}
 
-	// Sort by creation time (newest first)
-	sort.Slice(jobs, func(i, j int) bool {
-		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
-	})
+	fmt.Printf("\nTotal: %d jobs\n", len(jobs))
 
-	// Print table header
-	fmt.Printf("%-38s %-15s %-20s %-8s %-8s %s\n", "JOB ID", "STATUS", "STEP", "FILES", "RETRIES", "AGE")
-	fmt.Println("------------------------------------------------------------------------------------------------------------------------")
+	return nil
+}
 
-	// Print jobs
-	for _, j := range jobs {
-		statusSymbol := getJobStatusSymbol(j.Status)
-		fmt.Printf("%-38s %s %-13s %-20s %-8d %-8d %s\n",
-			j.ID,
-			statusSymbol,
-			j.Status,
-			j.Step,
-			j.Files,
-			j.RetryCount,
-			j.ElapsedStr,
-		)
+// parseJobStatuses validates the values of --status
+func parseJobStatuses(values []string) ([]models.JobStatus, error) {
+	statuses := make([]models.JobStatus, 0, len(values))
+	for _, value := range values {
+		status := models.JobStatus(value)
+		if !models.IsValidJobStatus(status) {
+			return nil, fmt.Errorf("invalid --status '%s' (must be pending, in_progress, completed or failed)", value)
+		}
+		statuses = append(statuses, status)
 	}
+	return statuses, nil
+}
 
-	fmt.Printf("\nTotal: %d jobs\n", len(jobs))
+// printJobViewsJSON prints the jobs in the schema of GET /api/v1/jobs (api/openapi.json)
+func printJobViewsJSON(jobs []*models.PipelineJob) error {
+	views := make([]models.JobStatusView, 0, len(jobs))
+	for _, job := range jobs {
+		views = append(views, models.NewJobStatusView(*job))
+	}
+	data, err := json.MarshalIndent(views, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode jobs: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func runJobShow(cmd *cobra.Command, args []string) error {
+	jobID := args[0]
+
+	// Load configuration
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
 
+	job, err := pipeline.LoadJob(config.JobsDir, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to load job: %w", err)
+	}
+
+	fmt.Printf("Job %s %s %s\n", job.JobID, getJobStatusSymbol(string(job.Status)), job.Status)
+	if study := job.Annotations.Label(); study != "" {
+		fmt.Printf("Study:   %s\n", study)
+	}
+	if job.Template != nil {
+		fmt.Printf("Template: %s (%s)\n", job.Template.Name, job.Template.Path)
+	}
+	if len(job.Labels) > 0 {
+		fmt.Printf("Labels:  %s\n", formatLabels(job.Labels))
+	}
+	fmt.Printf("Created: %s\n", lib.FormatTimestamp(job.CreatedAt))
+	fmt.Printf("Updated: %s\n\n", lib.FormatTimestamp(job.UpdatedAt))
+
+	fmt.Printf("%-20s %-14s %-25s %-9s %-10s %s\n", "STEP", "STATUS", "STARTED", "OFFSET", "DURATION", "RETRIES")
+	for _, step := range job.Steps {
+		started, offset, duration := "-", "-", "-"
+		if step.StartedAt != nil {
+			started = lib.FormatTimestamp(*step.StartedAt)
+			offset = "+" + formatDuration(step.StartedAt.Sub(job.CreatedAt))
+			end := time.Now()
+			if step.CompletedAt != nil {
+				end = *step.CompletedAt
+			}
+			duration = end.Sub(*step.StartedAt).Round(time.Second).String()
+		}
+		fmt.Printf("%-20s %s %-12s %-25s %-9s %-10s %d\n",
+			step.Name, getStatusSymbol(step.Status), step.Status, started, offset, duration, step.RetryCount)
+		if step.LastError != nil {
+			fmt.Printf("  └ %s\n", step.LastError.Message)
+		}
+	}
+
+	if job.ErrorMessage != "" {
+		fmt.Printf("\nError: %s\n", job.ErrorMessage)
+	}
 	return nil
 }
 
+// formatLabels returns labels as sorted key=value pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
 func getJobStatusSymbol(status string) string {
 	switch status {
 	case "completed":
@@ -231,25 +398,21 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dd", days)
 }
 
-func runJobRun(cmd *cobra.Command, args []string) error {
+func runJobRun(cmd *cobra.Command, args []string) (err error) {
 	jobID := args[0]
 
 	// Validate step name
-	stepName, err := validateStepName(stepFlag)
-	if err != nil {
+	if err := validateStepName(stepFlag); err != nil {
 		return err
 	}
 
 	// Load configuration
-	config, err := services.LoadConfig(cfgFile)
+	config, err := loadConfigForJobs()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Check if step is enabled in configuration
-	if !isStepEnabledInConfig(config, stepName) {
-		return fmt.Errorf("step '%s' is not enabled in configuration (check enabled_steps in config file)", stepName)
+		return err
 	}
+	start := time.Now()
+	defer func() { err = finishTraced(config.JobsDir, jobID, start, err) }()
 
 	// Load job
 	job, err := pipeline.LoadJob(config.JobsDir, jobID)
@@ -257,6 +420,14 @@ func runJobRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load job: %w", err)
 	}
 
+	// Aliases resolve per job: "import" is the import step of the job's input type
+	stepName := models.CanonicalStepName(stepFlag, job.InputType)
+
+	// Check if step is enabled in configuration
+	if !isStepEnabledInConfig(config, stepName) {
+		return fmt.Errorf("step '%s' is not enabled in configuration (check enabled_steps in config file)", stepName)
+	}
+
 	fmt.Printf("Job: %s\n", job.JobID)
 	fmt.Printf("Executing step: %s\n\n", stepName)
 
@@ -278,9 +449,16 @@ func runJobRun(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
+	stopSupervision := superviseRun(job, config, lock, logger)
+	defer stopSupervision()
+
+	ctx, stop := signalContext()
+	defer stop()
+
 	// Execute the step (with lock held)
-	err = executeStepManually(job, stepName, config, logger)
+	err = executeStepManually(ctx, job, stepName, config, logger)
 	if err != nil {
+		printCancelHint(jobID, err)
 		return fmt.Errorf("step execution failed: %w", err)
 	}
 
@@ -289,24 +467,12 @@ func runJobRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// validateStepName validates and converts step flag to StepName type
-func validateStepName(step string) (models.StepName, error) {
-	validSteps := map[string]models.StepName{
-		"torch":              models.StepTorchImport,
-		"local_import":       models.StepLocalImport,
-		"http_import":        models.StepHttpImport,
-		"dimp":               models.StepDIMP,
-		"validation":         models.StepValidation,
-		"csv_conversion":     models.StepCSVConversion,
-		"parquet_conversion": models.StepParquetConversion,
-	}
-
-	stepName, ok := validSteps[step]
-	if !ok {
-		return "", fmt.Errorf("invalid step name '%s'. Valid steps: torch, local_import, http_import, dimp, validation, csv_conversion, parquet_conversion", step)
+// validateStepName checks that the step flag names a step or a step alias (see models.StepAliases)
+func validateStepName(step string) error {
+	if _, ok := models.ResolveStepName(step); !ok {
+		return fmt.Errorf("invalid step name '%s'. Valid steps: torch, local_import, http_import, dimp, imaging, validation, csv_conversion, parquet_conversion (aliases: import, torch_import)", step)
 	}
-
-	return stepName, nil
+	return nil
 }
 
 // isStepEnabledInConfig checks if a step is enabled in the project configuration
@@ -321,7 +487,7 @@ func isStepEnabledInConfig(config *models.ProjectConfig, stepName models.StepNam
 
 // executeStepManually executes a specific pipeline step manually
 // This is similar to executeStep in pipeline.go but simplified for manual execution
-func executeStepManually(job *models.PipelineJob, stepName models.StepName, config *models.ProjectConfig, logger *lib.Logger) error {
+func executeStepManually(ctx context.Context, job *models.PipelineJob, stepName models.StepName, config *models.ProjectConfig, logger *lib.Logger) error {
 	jobDir := services.GetJobDir(config.JobsDir, job.JobID)
 
 	switch stepName {
@@ -333,7 +499,7 @@ func executeStepManually(job *models.PipelineJob, stepName models.StepName, conf
 			expectedStep = models.StepTorchImport
 		case models.InputTypeLocal:
 			expectedStep = models.StepLocalImport
-		case models.InputTypeHTTP:
+		case models.InputTypeHTTP, models.InputTypeFHIRSearch, models.InputTypeBulkExport, models.InputTypeS3:
 			expectedStep = models.StepHttpImport
 		default:
 			return fmt.Errorf("unknown input type: %s", job.InputType)
@@ -345,10 +511,12 @@ func executeStepManually(job *models.PipelineJob, stepName models.StepName, conf
 
 		// Create HTTP client
 		httpClient := services.NewHTTPClient(30*time.Second, job.Config.Retry, logger)
-		showProgress := true
 
-		importedJob, err := pipeline.ExecuteImportStep(job, logger, httpClient, showProgress)
+		importedJob, err := pipeline.ExecuteImportStep(ctx, job, logger, httpClient, ui.NewTerminalProgress())
 		if err != nil {
+			if saveErr := pipeline.UpdateJob(config.JobsDir, importedJob); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
 			return fmt.Errorf("%s step failed: %w", stepName, err)
 		}
 
@@ -362,7 +530,7 @@ func executeStepManually(job *models.PipelineJob, stepName models.StepName, conf
 	case models.StepDIMP:
 		// Execute DIMP pseudonymization step
 		fmt.Println("Starting DIMP pseudonymization step...")
-		if err := pipeline.ExecuteDIMPStep(job, jobDir, logger); err != nil {
+		if err := pipeline.ExecuteDIMPStep(ctx, job, jobDir, logger, ui.NewTerminalProgress()); err != nil {
 			// Save failed state
 			if saveErr := pipeline.UpdateJob(config.JobsDir, job); saveErr != nil {
 				logger.Error("Failed to save job state", "error", saveErr)
@@ -378,16 +546,122 @@ func executeStepManually(job *models.PipelineJob, stepName models.StepName, conf
 		fmt.Printf("\n✓ DIMP pseudonymization completed\n")
 		return nil
 
+	case models.StepImaging:
+		fmt.Println("Starting imaging step...")
+		if err := pipeline.ExecuteImagingStep(ctx, job, jobDir, logger); err != nil {
+			if saveErr := pipeline.UpdateJob(config.JobsDir, job); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
+			return fmt.Errorf("imaging step failed: %w", err)
+		}
+
+		if err := pipeline.UpdateJob(config.JobsDir, job); err != nil {
+			return fmt.Errorf("failed to save job state: %w", err)
+		}
+
+		fmt.Printf("\n✓ Imaging step completed\n")
+		return nil
+
 	case models.StepValidation:
 		return fmt.Errorf("validation step not yet implemented")
 
 	case models.StepCSVConversion:
-		return fmt.Errorf("CSV conversion step not yet implemented")
+		if config.Services.CSVConversion.URL != "" {
+			return fmt.Errorf("CSV conversion via external service not yet implemented")
+		}
+		if err := checkOutputSchemas(job, config, stepName, logger); err != nil {
+			return err
+		}
+		fmt.Println("Starting CSV conversion step...")
+		if err := pipeline.ExecuteCSVConversionStep(ctx, job, jobDir, logger); err != nil {
+			failedJob := pipeline.FailJob(job, err.Error())
+			if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
+			return fmt.Errorf("CSV conversion step failed: %w", err)
+		}
+
+		if err := pipeline.UpdateJob(config.JobsDir, job); err != nil {
+			return fmt.Errorf("failed to save job state: %w", err)
+		}
+
+		fmt.Printf("\n✓ CSV conversion step completed\n")
+		return nil
 
 	case models.StepParquetConversion:
-		return fmt.Errorf("parquet conversion step not yet implemented")
+		if !config.Pipeline.Packaging.WritesParquetLocally() {
+			return fmt.Errorf("parquet conversion via external service not yet implemented (set pipeline.packaging.mode: local)")
+		}
+		if err := checkOutputSchemas(job, config, stepName, logger); err != nil {
+			return err
+		}
+		fmt.Println("Starting Parquet conversion step...")
+		if err := pipeline.ExecuteParquetConversionStep(ctx, job, jobDir, logger); err != nil {
+			failedJob := pipeline.FailJob(job, err.Error())
+			if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
+			return fmt.Errorf("parquet conversion step failed: %w", err)
+		}
+
+		if err := pipeline.UpdateJob(config.JobsDir, job); err != nil {
+			return fmt.Errorf("failed to save job state: %w", err)
+		}
+
+		fmt.Printf("\n✓ Parquet conversion step completed\n")
+		return nil
 
 	default:
 		return fmt.Errorf("unknown step: %s", stepName)
 	}
 }
+
+func runJobCheck(cmd *cobra.Command, args []string) error {
+	jobID := args[0]
+
+	// Load configuration
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(config.SanityChecks) == 0 {
+		fmt.Println("No sanity_checks configured")
+		return nil
+	}
+
+	// Verify the job exists
+	if _, err := pipeline.LoadJob(config.JobsDir, jobID); err != nil {
+		return fmt.Errorf("failed to load job: %w", err)
+	}
+
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+
+	dataDir := pipeline.SanityCheckDir(services.GetJobDir(config.JobsDir, jobID))
+	fmt.Printf("Running %d sanity check(s) against %s\n\n", len(config.SanityChecks), dataDir)
+
+	results, err := pipeline.RunSanityChecks(dataDir, config.SanityChecks, config.Limits.GetMaxLineBytes(), logger)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		symbol := "✓"
+		if !result.Passed {
+			symbol = "✗"
+		}
+		fmt.Printf("  %s %s: %d (expected %s)\n", symbol, result.Check.Name, result.Count, result.Check.ExpectedRange())
+	}
+	fmt.Println()
+
+	if !pipeline.AllSanityChecksPassed(results) {
+		return fmt.Errorf("sanity checks failed - review the extraction before delivery")
+	}
+
+	fmt.Println("✓ All sanity checks passed")
+	return nil
+}
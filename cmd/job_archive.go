@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var (
+	exportOut     string
+	exportInclude string
+)
+
+// jobExportCmd represents the job export command
+var jobExportCmd = &cobra.Command{
+	Use:   "export <job-id> [--out job.tar.zst] [--include import,pseudonymized]",
+	Short: "Package a job into an archive for another machine",
+	Long: `Write a job's state, event timeline and step outputs to a compressed tar
+archive, so a partially completed job can be moved to another machine and
+resumed there with 'aether job import' and 'aether pipeline continue'.
+
+The archive is zstd-compressed (.tar.zst). An --out file ending in .tar.gz
+or .tgz is written with gzip instead, for tools without zstd support.
+
+Lock, heartbeat and wait files are not archived. Symlinked imports are
+archived by content. The TORCH password is removed from the job's
+configuration snapshot.
+
+Examples:
+  # Export a job with all step outputs
+  aether job export abc123 --out abc123.tar.zst
+
+  # Export as .tar.gz for a machine without zstd
+  aether job export abc123 --out abc123.tar.gz
+
+  # Export only what is needed to resume after the DIMP step
+  aether job export abc123 --include pseudonymized`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runJobExport,
+}
+
+// jobImportCmd represents the job import command
+var jobImportCmd = &cobra.Command{
+	Use:   "import <archive>",
+	Short: "Import a job archive created by 'aether job export'",
+	Long: `Unpack a job archive (.tar.zst or .tar.gz) into the jobs directory.
+The job keeps its ID.
+
+Its configuration snapshot is rebound to this machine: jobs_dir and the
+service endpoints and credentials are taken from the current configuration,
+so the job resumes against the services configured here.
+
+Examples:
+  # Import and resume a job exported on a laptop
+  aether job import abc123.tar.zst
+  aether pipeline continue abc123`,
+	Args: cobra.ExactArgs(1),
+	RunE: runJobImport,
+}
+
+func init() {
+	jobCmd.AddCommand(jobExportCmd)
+	jobCmd.AddCommand(jobImportCmd)
+
+	jobExportCmd.Flags().StringVarP(&exportOut, "out", "o", "", "Archive file to write; .tar.gz or .tgz selects gzip (default: <job-id>.tar.zst)")
+	jobExportCmd.Flags().StringVar(&exportInclude, "include", "", "Comma-separated step output directories to include (import, pseudonymized, imaging, quarantine, dead-letter, csv, parquet; default: all)")
+}
+
+func runJobExport(cmd *cobra.Command, args []string) error {
+	jobID := args[0]
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var dataDirs []string
+	for _, dir := range strings.Split(exportInclude, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dataDirs = append(dataDirs, dir)
+		}
+	}
+
+	out := exportOut
+	if out == "" {
+		out = jobID + ".tar.zst"
+	}
+
+	// Hold the job lock so the state cannot change while it is archived
+	logger := lib.DefaultLogger
+	lock, err := services.AcquireJobLock(config.JobsDir, jobID, logger)
+	if err != nil {
+		return fmt.Errorf("cannot export job: %w\n\nAnother process may be working on this job. Wait for it to complete or check job status", err)
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			logger.Error("Failed to release job lock", "error", err)
+		}
+	}()
+
+	file, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	if err := services.ExportJobArchive(config.JobsDir, jobID, dataDirs, services.ArchiveCompressionForPath(out), file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(out)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	fmt.Printf("✓ Job %s exported to %s\n", jobID, out)
+	return nil
+}
+
+func runJobImport(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigForJobs()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	job, err := services.ImportJobArchive(config.JobsDir, file, *config)
+	if err != nil {
+		return fmt.Errorf("failed to import job: %w", err)
+	}
+
+	fmt.Printf("✓ Job %s imported (status: %s, current step: %s)\n", job.JobID, job.Status, job.CurrentStep)
+	fmt.Printf("\nResume with: aether pipeline continue %s\n", job.JobID)
+	return nil
+}
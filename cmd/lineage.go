@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var lineageJSON bool
+
+// lineageCmd represents the lineage command
+var lineageCmd = &cobra.Command{
+	Use:   "lineage <job-id>",
+	Short: "Show the jobs a job was created from and the jobs derived from it",
+	Long: `Show the provenance chain of a job.
+
+Jobs started with 'aether pipeline start --from-job <job-id>' record their
+parent jobs. The lineage shows two trees:
+  • Created from: the job, its parents, their parents, ... back to the
+    jobs that imported from a CRTDL, directory or URL
+  • Derived jobs: every job created from this job's output, recursively
+
+Each job is listed with its status, input and completed steps. Parent jobs
+that have been deleted are shown as such.
+
+Examples:
+  # Trace a delivered CSV export back to its CRTDL extraction
+  aether lineage abc-123-def
+
+  # Machine-readable lineage
+  aether lineage abc-123-def --json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runLineage,
+}
+
+func init() {
+	rootCmd.AddCommand(lineageCmd)
+
+	lineageCmd.Flags().BoolVar(&lineageJSON, "json", false, "Output the lineage as JSON")
+}
+
+func runLineage(cmd *cobra.Command, args []string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	lineage, err := services.BuildLineage(config.JobsDir, args[0], lib.DefaultLogger)
+	if err != nil {
+		return err
+	}
+
+	if lineageJSON {
+		data, err := json.MarshalIndent(lineage, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode lineage: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Lineage of job %s\n\n", args[0])
+	fmt.Println("Created from:")
+	printLineageTree(lineage.Ancestors, func(node models.LineageNode) []models.LineageNode { return node.Parents })
+	fmt.Println("\nDerived jobs:")
+	printLineageTree(lineage.Descendants, func(node models.LineageNode) []models.LineageNode { return node.Children })
+	return nil
+}
+
+// printLineageTree prints a node and its relatives (parents or children) as an indented tree
+func printLineageTree(root models.LineageNode, relatives func(models.LineageNode) []models.LineageNode) {
+	fmt.Println("  " + formatLineageNode(root))
+	if len(relatives(root)) == 0 {
+		fmt.Println("  (none)")
+		return
+	}
+
+	var walk func(node models.LineageNode, prefix string)
+	walk = func(node models.LineageNode, prefix string) {
+		nodes := relatives(node)
+		for i, relative := range nodes {
+			branch, indent := "├── ", "│   "
+			if i == len(nodes)-1 {
+				branch, indent = "└── ", "    "
+			}
+			fmt.Println(prefix + branch + formatLineageNode(relative))
+			walk(relative, prefix+indent)
+		}
+	}
+	walk(root, "  ")
+}
+
+func formatLineageNode(node models.LineageNode) string {
+	if node.Missing {
+		return fmt.Sprintf("? %s  (deleted)", node.JobID)
+	}
+	line := fmt.Sprintf("%s %s  %s  %s: %s", getJobStatusSymbol(string(node.Status)), node.JobID, node.Status, node.InputType, node.InputSource)
+	if len(node.CompletedSteps) > 0 {
+		steps := make([]string, len(node.CompletedSteps))
+		for i, step := range node.CompletedSteps {
+			steps[i] = string(step)
+		}
+		line += "  [" + strings.Join(steps, ", ") + "]"
+	}
+	return line
+}
@@ -1,18 +1,33 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/i18n"
 	"github.com/trobanga/aether/internal/lib"
 	"github.com/trobanga/aether/internal/models"
 	"github.com/trobanga/aether/internal/pipeline"
 	"github.com/trobanga/aether/internal/services"
+	"github.com/trobanga/aether/internal/ui"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
-	noProgress bool
+	noProgress   bool
+	jsonProgress bool
+	showEvents   bool
+	statusJSON   bool
+	fromJobs     []string
 )
 
 // pipelineCmd represents the pipeline command group
@@ -29,7 +44,7 @@ Available subcommands:
 
 // pipelineStartCmd represents the pipeline start command
 var pipelineStartCmd = &cobra.Command{
-	Use:   "start <input>",
+	Use:   "start [input...] [--from-job <job-id>...]",
 	Short: "Start a new pipeline job",
 	Long: `Start a new Data Use Process pipeline job.
 
@@ -39,6 +54,16 @@ The input can be:
   • HTTP(S) URL to download FHIR data from
   • TORCH result URL for direct download
 
+Several inputs can be combined into one job. The first input determines the
+import step; additional inputs (local directories, HTTP URLs, TORCH result
+URLs) are imported into the same import directory, and each file records the
+source it came from. Clashing file names get a .src<N> suffix.
+
+With --from-job, the FHIR output of a completed job (its last completed
+imaging, dimp or import step) is imported as an additional input. Repeat
+the flag to merge several jobs. The parent job IDs are recorded in the new
+job's state; 'aether lineage <job-id>' shows the resulting provenance chain.
+
 Examples:
   # Extract data using CRTDL query via TORCH
   aether pipeline start query.crtdl
@@ -52,9 +77,15 @@ Examples:
   # Download from TORCH result URL
   aether pipeline start http://torch-server/fhir/extraction/result-123
 
+  # Combine a site-local export with a central TORCH extraction result
+  aether pipeline start /data/site-export http://torch-server/fhir/result/abc
+
+  # Derive a job from the pseudonymized output of two earlier jobs
+  aether pipeline start --from-job abc-123 --from-job def-456
+
   # Start without progress indicators
   aether pipeline start query.crtdl --no-progress`,
-	Args: cobra.ExactArgs(1),
+	Args: cobra.ArbitraryArgs,
 	RunE: runPipelineStart,
 }
 
@@ -70,6 +101,8 @@ Shows:
   • Progress for each step (files processed, errors, retries)
   • Total files and data processed
   • Error messages if job failed
+  • With --events: the timeline from events.ndjson (step starts,
+    per-file progress, downloads, retries)
 
 The status command is designed for quick checks (<2s response time).
 Use 'watch' for continuous monitoring:
@@ -79,10 +112,14 @@ Examples:
   # Check job status
   aether pipeline status abc-123-def
 
+  # Show the event timeline of a long job
+  aether pipeline status abc-123-def --events
+
   # Continuous monitoring (every 5 seconds)
   watch -n 5 aether pipeline status abc-123-def`,
-	Args: cobra.ExactArgs(1),
-	RunE: runPipelineStatus,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runPipelineStatus,
 }
 
 // pipelineContinueCmd represents the pipeline continue command
@@ -121,8 +158,22 @@ Examples:
   # Check status first, then resume
   aether pipeline status abc-123-def
   aether pipeline continue abc-123-def`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runPipelineContinue,
+}
+
+// pipelineRunCmd runs a job created by the API; serve starts it as a child process
+var pipelineRunCmd = &cobra.Command{
+	Use:    "run <job-id>",
+	Short:  "Run a created job through all enabled steps",
+	Hidden: true,
+	Long: `Run a pending job through all enabled steps, like 'pipeline start' does
+after creating it. Used by 'aether serve' to run jobs submitted through the
+API in their own process. If the services of the job are unreachable, the
+job is marked failed.`,
 	Args: cobra.ExactArgs(1),
-	RunE: runPipelineContinue,
+	RunE: runPipelineRun,
 }
 
 func init() {
@@ -130,9 +181,35 @@ func init() {
 	pipelineCmd.AddCommand(pipelineStartCmd)
 	pipelineCmd.AddCommand(pipelineStatusCmd)
 	pipelineCmd.AddCommand(pipelineContinueCmd)
+	pipelineCmd.AddCommand(pipelineRunCmd)
 
 	// Flags for pipeline start
 	pipelineStartCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Disable progress indicators")
+	pipelineRunCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Disable progress indicators")
+	for _, command := range []*cobra.Command{pipelineStartCmd, pipelineRunCmd} {
+		command.Flags().BoolVar(&jsonProgress, "json-progress", false, "Write progress as JSON lines to stderr instead of spinners and bars")
+	}
+	pipelineStartCmd.Flags().StringSliceVar(&fromJobs, "from-job", nil, "Use the FHIR output of a completed job as input (repeatable)")
+	_ = pipelineStartCmd.RegisterFlagCompletionFunc("from-job", completeJobIDs)
+	for _, command := range []*cobra.Command{pipelineStartCmd, pipelineContinueCmd, pipelineRunCmd} {
+		command.Flags().StringVar(&emitTrace, "emit-trace", "", "Write per-step trace files to this directory and use workflow exit codes (0, 1, 3, 75)")
+	}
+
+	// Flags for pipeline status
+	pipelineStatusCmd.Flags().BoolVar(&showEvents, "events", false, "Show the job's event timeline")
+	pipelineStatusCmd.Flags().BoolVar(&statusJSON, "json", false, "Output job state as JSON (includes log context of failed steps)")
+}
+
+// newProgressReporter returns the reporter selected by --no-progress and --json-progress
+func newProgressReporter(noProgress bool) lib.ProgressReporter {
+	switch {
+	case noProgress:
+		return lib.NoProgress
+	case jsonProgress:
+		return lib.NewJSONProgress(os.Stderr)
+	default:
+		return ui.NewTerminalProgress()
+	}
 }
 
 // validateImportStepMatch ensures the step name matches the input type
@@ -140,15 +217,15 @@ func validateImportStepMatch(inputType models.InputType, stepName models.StepNam
 	switch inputType {
 	case models.InputTypeCRTDL, models.InputTypeTORCHURL:
 		if stepName != models.StepTorchImport {
-			return fmt.Errorf("input type %s requires step '%s', but got '%s'", inputType, models.StepTorchImport, stepName)
+			return i18n.Errorf(i18n.MsgImportStepMismatch, inputType, models.StepTorchImport, stepName)
 		}
 	case models.InputTypeLocal:
 		if stepName != models.StepLocalImport {
-			return fmt.Errorf("input type %s requires step '%s', but got '%s'", inputType, models.StepLocalImport, stepName)
+			return i18n.Errorf(i18n.MsgImportStepMismatch, inputType, models.StepLocalImport, stepName)
 		}
-	case models.InputTypeHTTP:
+	case models.InputTypeHTTP, models.InputTypeFHIRSearch, models.InputTypeBulkExport, models.InputTypeS3:
 		if stepName != models.StepHttpImport {
-			return fmt.Errorf("input type %s requires step '%s', but got '%s'", inputType, models.StepHttpImport, stepName)
+			return i18n.Errorf(i18n.MsgImportStepMismatch, inputType, models.StepHttpImport, stepName)
 		}
 	default:
 		return fmt.Errorf("unknown input type: %s", inputType)
@@ -158,63 +235,122 @@ func validateImportStepMatch(inputType models.InputType, stepName models.StepNam
 
 // executeStep executes a single pipeline step based on its name
 // Returns error if step execution fails
-func executeStep(job *models.PipelineJob, stepName models.StepName, config *models.ProjectConfig, logger *lib.Logger, noProgress bool) error {
+func executeStep(ctx context.Context, job *models.PipelineJob, stepName models.StepName, config *models.ProjectConfig, logger *lib.Logger, noProgress bool) error {
 	jobDir := services.GetJobDir(config.JobsDir, job.JobID)
 
 	switch stepName {
 	case models.StepTorchImport, models.StepLocalImport, models.StepHttpImport:
 		// Validate step name matches input type
 		if err := validateImportStepMatch(job.InputType, stepName); err != nil {
-			return fmt.Errorf("step validation failed: %w", err)
+			return i18n.Errorf(i18n.MsgStepValidationFailed, err)
 		}
 
 		// Create HTTP client
 		httpClient := services.NewHTTPClient(30*time.Second, job.Config.Retry, logger)
-		showProgress := !noProgress
 
-		importedJob, err := pipeline.ExecuteImportStep(job, logger, httpClient, showProgress)
+		importedJob, err := pipeline.ExecuteImportStep(ctx, job, logger, httpClient, newProgressReporter(noProgress))
 		if err != nil {
-			return fmt.Errorf("%s step failed: %w", stepName, err)
+			if saveErr := pipeline.UpdateJob(config.JobsDir, importedJob); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
+			return i18n.Errorf(i18n.MsgStepFailed, stepName, err)
 		}
 
 		if err := pipeline.UpdateJob(config.JobsDir, importedJob); err != nil {
-			return fmt.Errorf("failed to save job state: %w", err)
+			return i18n.Errorf(i18n.MsgSaveJobFailed, err)
 		}
 
-		fmt.Printf("\n✓ %s step completed (%d files)\n", stepName, importedJob.TotalFiles)
+		fmt.Printf("\n%s\n", i18n.T(i18n.MsgImportStepCompleted, stepName, importedJob.TotalFiles))
 		return nil
 
 	case models.StepDIMP:
 		// Execute DIMP pseudonymization step
-		fmt.Println("Starting DIMP pseudonymization step...")
-		if err := pipeline.ExecuteDIMPStep(job, jobDir, logger); err != nil {
+		fmt.Println(i18n.T(i18n.MsgStartingDIMP))
+		if err := pipeline.ExecuteDIMPStep(ctx, job, jobDir, logger, newProgressReporter(noProgress)); err != nil {
 			// Mark job as failed
 			failedJob := pipeline.FailJob(job, err.Error())
 			// Save failed state
 			if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
 				logger.Error("Failed to save job state", "error", saveErr)
 			}
-			return fmt.Errorf("DIMP step failed: %w", err)
+			return i18n.Errorf(i18n.MsgStepFailed, "DIMP", err)
 		}
 
 		// Save successful state
 		if err := pipeline.UpdateJob(config.JobsDir, job); err != nil {
-			return fmt.Errorf("failed to save job state: %w", err)
+			return i18n.Errorf(i18n.MsgSaveJobFailed, err)
 		}
 
-		fmt.Printf("\n✓ DIMP pseudonymization completed\n")
+		fmt.Printf("\n%s\n", i18n.T(i18n.MsgDIMPCompleted))
+		return nil
+
+	case models.StepImaging:
+		fmt.Println(i18n.T(i18n.MsgStartingImaging))
+		if err := pipeline.ExecuteImagingStep(ctx, job, jobDir, logger); err != nil {
+			failedJob := pipeline.FailJob(job, err.Error())
+			if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
+			return i18n.Errorf(i18n.MsgStepFailed, "imaging", err)
+		}
+
+		if err := pipeline.UpdateJob(config.JobsDir, job); err != nil {
+			return i18n.Errorf(i18n.MsgSaveJobFailed, err)
+		}
+
+		fmt.Printf("\n%s\n", i18n.T(i18n.MsgImagingCompleted))
 		return nil
 
 	case models.StepValidation:
-		fmt.Println("Validation step not yet implemented - job will remain at this step")
+		fmt.Println(i18n.T(i18n.MsgStepNotImplemented, "Validation"))
 		return nil
 
 	case models.StepCSVConversion:
-		fmt.Println("CSV conversion step not yet implemented - job will remain at this step")
+		if err := checkOutputSchemas(job, config, stepName, logger); err != nil {
+			return err
+		}
+		if config.Services.CSVConversion.URL != "" {
+			fmt.Println(i18n.T(i18n.MsgStepNotImplemented, "CSV conversion"))
+			return nil
+		}
+		fmt.Println(i18n.T(i18n.MsgStartingCSVConversion))
+		if err := pipeline.ExecuteCSVConversionStep(ctx, job, jobDir, logger); err != nil {
+			failedJob := pipeline.FailJob(job, err.Error())
+			if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
+			return i18n.Errorf(i18n.MsgStepFailed, "CSV conversion", err)
+		}
+
+		if err := pipeline.UpdateJob(config.JobsDir, job); err != nil {
+			return i18n.Errorf(i18n.MsgSaveJobFailed, err)
+		}
+
+		fmt.Printf("\n%s\n", i18n.T(i18n.MsgCSVConversionCompleted))
 		return nil
 
 	case models.StepParquetConversion:
-		fmt.Println("Parquet conversion step not yet implemented - job will remain at this step")
+		if !config.Pipeline.Packaging.WritesParquetLocally() {
+			fmt.Println(i18n.T(i18n.MsgStepNotImplemented, "Parquet conversion"))
+			return nil
+		}
+		if err := checkOutputSchemas(job, config, stepName, logger); err != nil {
+			return err
+		}
+		fmt.Println(i18n.T(i18n.MsgStartingParquetConversion))
+		if err := pipeline.ExecuteParquetConversionStep(ctx, job, jobDir, logger); err != nil {
+			failedJob := pipeline.FailJob(job, err.Error())
+			if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
+			return i18n.Errorf(i18n.MsgStepFailed, "Parquet conversion", err)
+		}
+
+		if err := pipeline.UpdateJob(config.JobsDir, job); err != nil {
+			return i18n.Errorf(i18n.MsgSaveJobFailed, err)
+		}
+
+		fmt.Printf("\n%s\n", i18n.T(i18n.MsgParquetConversionCompleted))
 		return nil
 
 	default:
@@ -222,21 +358,29 @@ func executeStep(job *models.PipelineJob, stepName models.StepName, config *mode
 	}
 }
 
+// checkOutputSchemas fails a packaging step whose tables break the schemas registered for downstream ETL
+func checkOutputSchemas(job *models.PipelineJob, config *models.ProjectConfig, stepName models.StepName, logger *lib.Logger) error {
+	httpClient := services.NewHTTPClient(30*time.Second, job.Config.Retry, logger)
+	if err := pipeline.CheckOutputSchemas(job.Config, httpClient, logger); err != nil {
+		failedJob := pipeline.FailJob(job, err.Error())
+		if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
+			logger.Error("Failed to save job state", "error", saveErr)
+		}
+		return i18n.Errorf(i18n.MsgStepFailed, stepName, err)
+	}
+	return nil
+}
+
 func runPipelineStart(cmd *cobra.Command, args []string) error {
-	inputSource := args[0]
+	if len(args) == 0 && len(fromJobs) == 0 {
+		return fmt.Errorf("requires at least one input or --from-job")
+	}
 
 	// Load configuration
-	config, err := services.LoadConfig(cfgFile)
+	config, err := loadConfigForJobs()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Validate service connectivity (T062)
-	fmt.Println("Validating service connectivity...")
-	if err := config.ValidateServiceConnectivity(); err != nil {
-		return fmt.Errorf("service connectivity check failed: %w\n\nPlease ensure all required services are running and accessible", err)
+		return err
 	}
-	fmt.Println("✓ All required services are reachable")
 
 	// Create logger
 	logLevel := lib.LogLevelInfo
@@ -244,26 +388,172 @@ func runPipelineStart(cmd *cobra.Command, args []string) error {
 		logLevel = lib.LogLevelDebug
 	}
 	logger := lib.NewLogger(logLevel)
+	installCohortSizePrompt()
+
+	inputs := args
+	var parentJobs []string
+	if len(fromJobs) > 0 {
+		parentSources, parents, err := pipeline.ParentJobSources(config.JobsDir, fromJobs)
+		if err != nil {
+			return err
+		}
+		inputs = append(append([]string(nil), args...), parentSources...)
+		parentJobs = parents
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	start := time.Now()
+	jobID, err := startPipeline(ctx, inputs, parentJobs, nil, config, logger, noProgress)
+	return finishTraced(config.JobsDir, jobID, start, err)
+}
+
+// startPipeline checks service connectivity, creates a job for the given inputs and
+// runs it through all enabled steps. Shared by 'pipeline start' and 'watch'
+// parentJobs are recorded as the job's lineage; their outputs must be among args
+// template, if not nil, is stored with the job; config must already have it applied
+// Cancelling ctx stops the running step and leaves the job resumable
+// Returns the job ID (empty if no job was created) and any error
+func startPipeline(ctx context.Context, args []string, parentJobs []string, template *models.JobTemplate, config *models.ProjectConfig, logger *lib.Logger, noProgress bool) (string, error) {
+	inputSource := args[0]
+
+	// Validate connectivity of the services this job will actually use (T062)
+	inputType, err := lib.DetectInputType(inputSource)
+	if err != nil {
+		return "", i18n.Errorf(i18n.MsgDetectInputFailed, err)
+	}
+
+	var extraTypes []models.InputType
+	for _, extraSource := range args[1:] {
+		if extraType, err := lib.DetectInputType(extraSource); err == nil {
+			extraTypes = append(extraTypes, extraType)
+		}
+	}
+	if err := checkJobServices(config, inputType, extraTypes); err != nil {
+		return "", err
+	}
 
 	// Create job
 	logger.Info("Creating new pipeline job", "input", inputSource)
-	job, err := pipeline.CreateJob(inputSource, *config, logger)
+	var job *models.PipelineJob
+	if template != nil {
+		job, err = pipeline.CreateTemplateJob(args, template, *config, logger)
+	} else {
+		job, err = pipeline.CreateDerivedJob(args, parentJobs, *config, logger)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create job: %w", err)
+		return "", i18n.Errorf(i18n.MsgCreateJobFailed, err)
 	}
 
 	lib.LogJobCreated(logger, job.JobID, inputSource)
 
-	fmt.Printf("✓ Created pipeline job: %s\n", job.JobID)
-	fmt.Printf("  Input: %s\n", inputSource)
-	fmt.Printf("  Type: %s\n", job.InputType)
+	fmt.Println(i18n.T(i18n.MsgJobCreated, job.JobID))
+	fmt.Println(i18n.T(i18n.MsgJobInput, inputSource))
+	fmt.Println(i18n.T(i18n.MsgJobInputType, job.InputType))
+	for _, extra := range job.ExtraSources {
+		fmt.Println(i18n.T(i18n.MsgJobExtraInput, extra.Source, extra.Type))
+	}
+	for _, parent := range job.ParentJobs {
+		fmt.Println(i18n.T(i18n.MsgJobParent, parent))
+	}
+	if job.Template != nil {
+		fmt.Println(i18n.T(i18n.MsgJobTemplate, job.Template.Name))
+	}
 	fmt.Printf("\n")
 
+	return runCreatedJob(ctx, job, config, logger, noProgress)
+}
+
+func runPipelineRun(cmd *cobra.Command, args []string) (err error) {
+	config, err := loadConfigForJobs()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	defer func() { err = finishTraced(config.JobsDir, args[0], start, err) }()
+
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+
+	job, err := pipeline.LoadJob(config.JobsDir, args[0])
+	if err != nil {
+		return i18n.Errorf(i18n.MsgLoadJobFailed, err)
+	}
+	if job.Status != models.JobStatusPending {
+		return fmt.Errorf("job %s is %s, not pending; use 'aether pipeline continue' to resume it", job.JobID, job.Status)
+	}
+
+	extraTypes := make([]models.InputType, len(job.ExtraSources))
+	for i, extra := range job.ExtraSources {
+		extraTypes[i] = extra.Type
+	}
+	if err := checkJobServices(config, job.InputType, extraTypes); err != nil {
+		// Nobody watches this process, so the failure must end up in the job
+		if saveErr := pipeline.UpdateJob(config.JobsDir, pipeline.FailJob(job, err.Error())); saveErr != nil {
+			logger.Error("Failed to save job state", "error", saveErr)
+		}
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	_, err = runCreatedJob(ctx, job, config, logger, noProgress)
+	return err
+}
+
+// installCohortSizePrompt lets an operator at a terminal confirm an empty or too large cohort
+// (services.torch.cohort_size_action: prompt). Unattended runs (watch, serve) keep aborting
+func installCohortSizePrompt() {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	pipeline.SetCohortSizePrompt(func(question string) bool {
+		fmt.Print(i18n.T(i18n.MsgCohortSizePrompt, question))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes", "j", "ja":
+			return true
+		}
+		return false
+	})
+}
+
+// checkJobServices validates connectivity of the services a job with these inputs will use
+func checkJobServices(config *models.ProjectConfig, inputType models.InputType, extraTypes []models.InputType) error {
+	fmt.Println(i18n.T(i18n.MsgCheckingServices))
+	requiredSteps := models.StepsForInputType(config.Pipeline.EnabledSteps, inputType)
+	for _, extraType := range extraTypes {
+		// Additional TORCH result URLs need TORCH even if the primary input does not
+		if extraType == models.InputTypeTORCHURL && inputType != models.InputTypeTORCHURL && inputType != models.InputTypeCRTDL {
+			requiredSteps = append(requiredSteps, models.StepTorchImport)
+			break
+		}
+	}
+	if err := config.ValidateServiceConnectivityForSteps(requiredSteps); err != nil {
+		return i18n.Errorf(i18n.MsgServiceCheckFailed, err)
+	}
+	fmt.Println(i18n.T(i18n.MsgServicesReachable))
+	return nil
+}
+
+// runCreatedJob runs a pending job through all enabled steps while holding its lock
+// Cancelling ctx stops the running step (or the run between steps) and leaves the job resumable
+// Returns the job ID and any error
+func runCreatedJob(ctx context.Context, job *models.PipelineJob, config *models.ProjectConfig, logger *lib.Logger, noProgress bool) (jobID string, err error) {
+	// One trace per run: the spans of all steps and their service calls are its children
+	ctx, span := lib.StartSpan(ctx, "pipeline run", attribute.String("aether.job_id", job.JobID))
+	defer func() { lib.EndSpan(span, err) }()
+
 	// Acquire job lock to prevent concurrent execution
 	// Lock is automatically released when function returns (via defer)
 	lock, err := services.AcquireJobLock(config.JobsDir, job.JobID, logger)
 	if err != nil {
-		return fmt.Errorf("cannot start pipeline: %w\n\nAnother process may be working on this job", err)
+		return job.JobID, i18n.Errorf(i18n.MsgStartLocked, err)
 	}
 	defer func() {
 		if err := lock.Release(); err != nil {
@@ -271,24 +561,26 @@ func runPipelineStart(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
+	stopSupervision := superviseRun(job, config, lock, logger)
+	defer stopSupervision()
+
 	// Start the job (with lock held)
 	startedJob := pipeline.StartJob(job)
 
 	// Save updated state
 	if err := pipeline.UpdateJob(config.JobsDir, startedJob); err != nil {
-		return fmt.Errorf("failed to update job state: %w", err)
+		return job.JobID, fmt.Errorf("failed to update job state: %w", err)
 	}
 
 	// Execute import step
-	fmt.Printf("Starting %s step...\n", startedJob.CurrentStep)
+	fmt.Println(i18n.T(i18n.MsgStartingStep, startedJob.CurrentStep))
 	httpClient := services.NewHTTPClient(
 		time.Duration(config.Retry.InitialBackoffMs)*time.Millisecond*10, // Longer timeout for downloads
 		config.Retry,
 		logger,
 	)
 
-	showProgress := !noProgress
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, showProgress)
+	importedJob, err := pipeline.ExecuteImportStep(ctx, startedJob, logger, httpClient, newProgressReporter(noProgress))
 
 	if err != nil {
 		// Mark job as failed
@@ -297,7 +589,8 @@ func runPipelineStart(cmd *cobra.Command, args []string) error {
 		if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
 			logger.Error("Failed to save job state", "error", saveErr)
 		}
-		return fmt.Errorf("%s step failed: %w", startedJob.CurrentStep, err)
+		printCancelHint(job.JobID, err)
+		return job.JobID, i18n.Errorf(i18n.MsgStepFailed, startedJob.CurrentStep, err)
 	}
 
 	// Save successful state after import
@@ -305,9 +598,9 @@ func runPipelineStart(cmd *cobra.Command, args []string) error {
 		logger.Error("Failed to save job state", "error", saveErr)
 	}
 
-	fmt.Printf("\n✓ %s completed successfully\n", importedJob.CurrentStep)
-	fmt.Printf("  Files: %d\n", importedJob.TotalFiles)
-	fmt.Printf("  Size: %s\n", formatBytes(importedJob.TotalBytes))
+	fmt.Printf("\n%s\n", i18n.T(i18n.MsgImportCompleted, importedJob.CurrentStep))
+	fmt.Println(i18n.T(i18n.MsgImportFiles, importedJob.TotalFiles))
+	fmt.Println(i18n.T(i18n.MsgImportSize, formatBytes(importedJob.TotalBytes)))
 	fmt.Printf("\n")
 
 	// Continue with remaining enabled steps automatically
@@ -319,35 +612,46 @@ func runPipelineStart(cmd *cobra.Command, args []string) error {
 
 		if nextStepName == "" {
 			// No more steps - mark job as complete
-			fmt.Println("All steps completed, marking job as complete...")
+			fmt.Println(i18n.T(i18n.MsgAllStepsCompleted))
 			completedJob := pipeline.CompleteJob(currentJob)
 			if err := pipeline.UpdateJob(config.JobsDir, completedJob); err != nil {
-				return fmt.Errorf("failed to update job: %w", err)
+				return job.JobID, i18n.Errorf(i18n.MsgUpdateJobFailed, err)
 			}
-			fmt.Printf("\n✓ Pipeline completed successfully\n")
-			fmt.Printf("Job ID: %s\n", completedJob.JobID)
-			return nil
+			fmt.Printf("\n%s\n", i18n.T(i18n.MsgPipelineCompleted))
+			fmt.Println(i18n.T(i18n.MsgJobIDLine, completedJob.JobID))
+			return completedJob.JobID, nil
+		}
+
+		// Stop between steps if the run was cancelled; 'pipeline continue' starts the next step
+		if ctx.Err() != nil {
+			err := fmt.Errorf("%w before %s: %v", pipeline.ErrStepCancelled, nextStepName, context.Cause(ctx))
+			if saveErr := pipeline.UpdateJob(config.JobsDir, pipeline.FailJob(currentJob, err.Error())); saveErr != nil {
+				logger.Error("Failed to save job state", "error", saveErr)
+			}
+			printCancelHint(job.JobID, err)
+			return job.JobID, err
 		}
 
 		// Advance to next step
-		fmt.Printf("\nAdvancing to step: %s\n", nextStepName)
+		fmt.Printf("\n%s\n", i18n.T(i18n.MsgAdvancingToStep, nextStepName))
 		advancedJob, err := pipeline.AdvanceToNextStep(currentJob)
 		if err != nil {
-			return fmt.Errorf("failed to advance to next step: %w", err)
+			return job.JobID, i18n.Errorf(i18n.MsgAdvanceStepFailed, err)
 		}
 
 		if err := pipeline.UpdateJob(config.JobsDir, advancedJob); err != nil {
-			return fmt.Errorf("failed to save job state: %w", err)
+			return job.JobID, i18n.Errorf(i18n.MsgSaveJobFailed, err)
 		}
 
 		// Execute the next step
-		if err := executeStep(advancedJob, nextStepName, config, logger, noProgress); err != nil {
+		if err := executeStep(ctx, advancedJob, nextStepName, config, logger, noProgress); err != nil {
 			// Mark job as failed
 			failedJob := pipeline.FailJob(advancedJob, err.Error())
 			if saveErr := pipeline.UpdateJob(config.JobsDir, failedJob); saveErr != nil {
 				logger.Error("Failed to save failed job state", "error", saveErr)
 			}
-			return err
+			printCancelHint(job.JobID, err)
+			return job.JobID, err
 		}
 
 		// Update current job reference
@@ -359,28 +663,43 @@ func runPipelineStatus(cmd *cobra.Command, args []string) error {
 	jobID := args[0]
 
 	// Load configuration
-	config, err := services.LoadConfig(cfgFile)
+	config, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
 	}
 
 	// Load job
 	job, err := pipeline.LoadJob(config.JobsDir, jobID)
 	if err != nil {
-		return fmt.Errorf("failed to load job: %w", err)
+		return i18n.Errorf(i18n.MsgLoadJobFailed, err)
+	}
+
+	if statusJSON {
+		return printJobJSON(job)
 	}
 
 	// Display job status
 	fmt.Println(pipeline.GetJobSummary(job))
 
+	// A stale heartbeat on an in-progress job points to a wedged or killed process
+	if job.Status == models.JobStatusInProgress {
+		if lastBeat, err := services.ReadHeartbeat(config.JobsDir, jobID); err == nil {
+			fmt.Printf("%s\n\n", i18n.T(i18n.MsgLastHeartbeat, time.Since(lastBeat).Round(time.Second)))
+		}
+		// A pending retry or TORCH poll explains why a running job shows no progress
+		if wait, err := services.LoadWaitState(config.JobsDir, jobID); err == nil && wait != nil {
+			fmt.Printf("%s\n\n", i18n.T(i18n.MsgWaiting, wait.Describe(lib.Now())))
+		}
+	}
+
 	// Display step details
-	fmt.Println("Steps:")
+	fmt.Println(i18n.T(i18n.MsgSteps))
 	for _, step := range job.Steps {
 		status := getStatusSymbol(step.Status)
 		fmt.Printf("  %s %-20s - %s", status, step.Name, step.Status)
 
 		if step.Status == models.StepStatusCompleted || step.Status == models.StepStatusInProgress {
-			fmt.Printf(" (%d files", step.FilesProcessed)
+			fmt.Printf(" (%s", i18n.T(i18n.MsgStepFiles, step.FilesProcessed))
 			if step.BytesProcessed > 0 {
 				fmt.Printf(", %s", formatBytes(step.BytesProcessed))
 			}
@@ -388,27 +707,95 @@ func runPipelineStatus(cmd *cobra.Command, args []string) error {
 		}
 
 		if step.RetryCount > 0 {
-			fmt.Printf(" [%d retries]", step.RetryCount)
+			fmt.Printf(" [%s]", i18n.T(i18n.MsgStepRetries, step.RetryCount))
 		}
 
 		if step.LastError != nil {
-			fmt.Printf("\n    Error: %s", step.LastError.Message)
+			fmt.Printf("\n    %s", i18n.T(i18n.MsgStepError, step.LastError.Message))
+			if step.LastError.RequestID != "" {
+				fmt.Printf("\n    %s", i18n.T(i18n.MsgStepRequestID, step.LastError.RequestID))
+			}
+			if len(step.LastError.Context) > 0 {
+				fmt.Printf("\n    %s", i18n.T(i18n.MsgStepLogLines, len(step.LastError.Context)))
+			}
+		}
+
+		for _, warning := range step.Warnings {
+			fmt.Printf("\n    %s", i18n.T(i18n.MsgStepWarning, warning.Message))
+		}
+		if step.WarningsDropped > 0 {
+			fmt.Printf("\n    %s", i18n.T(i18n.MsgStepWarningsDropped, step.WarningsDropped))
 		}
 
 		fmt.Println()
 	}
 
+	if showEvents {
+		events, err := services.LoadJobEvents(config.JobsDir, jobID)
+		if err != nil {
+			return i18n.Errorf(i18n.MsgLoadEventsFailed, err)
+		}
+		printJobEvents(events)
+	}
+
 	return nil
 }
 
-func runPipelineContinue(cmd *cobra.Command, args []string) error {
+// printJobJSON writes the job state as indented JSON with credentials redacted
+func printJobJSON(job *models.PipelineJob) error {
+	redacted := *job
+	for _, secret := range []*string{
+		&redacted.Config.Services.TORCH.Password,
+		&redacted.Config.Services.TORCH.ClientSecret,
+		&redacted.Config.Services.TORCH.BearerToken,
+		&redacted.Config.Services.FHIRExport.Password,
+		&redacted.Config.Services.FHIRExport.BearerToken,
+		&redacted.Config.Services.S3.SecretAccessKey,
+		&redacted.Config.Services.S3.SessionToken,
+	} {
+		if *secret != "" {
+			*secret = "***"
+		}
+	}
+
+	data, err := json.MarshalIndent(redacted, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job state: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printJobEvents renders the event timeline with elapsed time since the first event
+func printJobEvents(events []models.JobEvent) {
+	fmt.Printf("\n%s\n", i18n.T(i18n.MsgEvents))
+	if len(events) == 0 {
+		fmt.Println(i18n.T(i18n.MsgNoEvents))
+		return
+	}
+
+	start := events[0].Timestamp
+	for _, event := range events {
+		elapsed := event.Timestamp.Sub(start).Round(time.Second)
+		step := event.Step
+		if step == "" {
+			step = "-"
+		}
+		fmt.Printf("  %s  +%-8s %-18s %-18s %s\n",
+			lib.FormatTimestamp(event.Timestamp), elapsed, step, event.Type, event.Message)
+	}
+}
+
+func runPipelineContinue(cmd *cobra.Command, args []string) (err error) {
 	jobID := args[0]
 
 	// Load configuration
-	config, err := services.LoadConfig(cfgFile)
+	config, err := loadConfigForJobs()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
 	}
+	start := time.Now()
+	defer func() { err = finishTraced(config.JobsDir, jobID, start, err) }()
 
 	// Create logger
 	logLevel := lib.LogLevelInfo
@@ -416,27 +803,45 @@ func runPipelineContinue(cmd *cobra.Command, args []string) error {
 		logLevel = lib.LogLevelDebug
 	}
 	logger := lib.NewLogger(logLevel)
+	installCohortSizePrompt()
 
 	// Load existing job
-	fmt.Printf("Loading job %s...\n", jobID)
+	fmt.Println(i18n.T(i18n.MsgLoadingJob, jobID))
 	job, err := pipeline.LoadJob(config.JobsDir, jobID)
 	if err != nil {
-		return fmt.Errorf("failed to load job: %w", err)
+		return i18n.Errorf(i18n.MsgLoadJobFailed, err)
+	}
+
+	// Clean up after a process that ended while running the job
+	recovery, err := pipeline.RecoverJob(config.JobsDir, jobID, logger)
+	if err != nil {
+		return i18n.Errorf(i18n.MsgRecoverJobFailed, err)
+	}
+	if recovery.HasActions() {
+		if recovery.InterruptedStep != "" {
+			fmt.Println(i18n.T(i18n.MsgRecoveredInterrupted, recovery.InterruptedStep))
+		}
+		if len(recovery.RemovedFiles) > 0 {
+			fmt.Println(i18n.T(i18n.MsgRemovedPartialFiles, len(recovery.RemovedFiles)))
+		}
+		if job, err = pipeline.LoadJob(config.JobsDir, jobID); err != nil {
+			return i18n.Errorf(i18n.MsgLoadJobFailed, err)
+		}
 	}
 
 	// Check job status
 	if job.Status == models.JobStatusCompleted {
-		fmt.Println("✓ Job already completed")
+		fmt.Println(i18n.T(i18n.MsgJobAlreadyCompleted))
 		return nil
 	}
 
-	fmt.Printf("Current status: %s\n", job.Status)
-	fmt.Printf("Current step: %s\n", job.CurrentStep)
+	fmt.Println(i18n.T(i18n.MsgCurrentStatus, job.Status))
+	fmt.Println(i18n.T(i18n.MsgCurrentStep, job.CurrentStep))
 
 	// Acquire job lock to prevent concurrent execution
 	lock, err := services.AcquireJobLock(config.JobsDir, jobID, logger)
 	if err != nil {
-		return fmt.Errorf("cannot continue pipeline: %w\n\nAnother process may be working on this job. Wait for it to complete or check job status", err)
+		return i18n.Errorf(i18n.MsgContinueLocked, err)
 	}
 	defer func() {
 		if err := lock.Release(); err != nil {
@@ -444,6 +849,9 @@ func runPipelineContinue(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
+	stopSupervision := superviseRun(job, config, lock, logger)
+	defer stopSupervision()
+
 	// Get current step and check if it's completed
 	currentStepName := models.StepName(job.CurrentStep)
 	currentStep, found := models.GetStepByName(*job, currentStepName)
@@ -452,7 +860,7 @@ func runPipelineContinue(cmd *cobra.Command, args []string) error {
 	var jobToExecute *models.PipelineJob
 
 	if !found {
-		return fmt.Errorf("current step %s not found in job", currentStepName)
+		return i18n.Errorf(i18n.MsgCurrentStepNotInJob, currentStepName)
 	}
 
 	// Check if current step is completed
@@ -462,49 +870,110 @@ func runPipelineContinue(cmd *cobra.Command, args []string) error {
 
 		if nextStepName == "" {
 			// No more steps - mark job as complete
-			fmt.Println("All steps completed, marking job as complete...")
+			fmt.Println(i18n.T(i18n.MsgAllStepsCompleted))
 			completedJob := pipeline.CompleteJob(job)
 			if err := pipeline.UpdateJob(config.JobsDir, completedJob); err != nil {
-				return fmt.Errorf("failed to update job: %w", err)
+				return i18n.Errorf(i18n.MsgUpdateJobFailed, err)
 			}
-			fmt.Println("✓ Job completed successfully")
+			fmt.Println(i18n.T(i18n.MsgJobCompleted))
 			return nil
 		}
 
 		// Advance to next step
-		fmt.Printf("Current step '%s' is completed, advancing to next step: %s\n", currentStepName, nextStepName)
+		fmt.Println(i18n.T(i18n.MsgStepCompletedAdvance, currentStepName, nextStepName))
 		advancedJob, err := pipeline.AdvanceToNextStep(job)
 		if err != nil {
-			return fmt.Errorf("failed to advance to next step: %w", err)
+			return i18n.Errorf(i18n.MsgAdvanceStepFailed, err)
 		}
 
 		if err := pipeline.UpdateJob(config.JobsDir, advancedJob); err != nil {
-			return fmt.Errorf("failed to save job state: %w", err)
+			return i18n.Errorf(i18n.MsgSaveJobFailed, err)
 		}
 
 		stepToExecute = nextStepName
 		jobToExecute = advancedJob
 	} else {
 		// Current step is NOT completed (in_progress, failed, or pending) - resume it
-		fmt.Printf("Resuming incomplete step: %s (status: %s)\n", currentStepName, currentStep.Status)
+		fmt.Println(i18n.T(i18n.MsgResumingStep, currentStepName, currentStep.Status))
 		stepToExecute = currentStepName
 		jobToExecute = job
 	}
 
-	fmt.Printf("\nResuming pipeline execution...\n")
-	fmt.Printf("Executing step: %s\n\n", stepToExecute)
+	fmt.Printf("\n%s\n", i18n.T(i18n.MsgResumingPipeline))
+	fmt.Printf("%s\n\n", i18n.T(i18n.MsgExecutingStep, stepToExecute))
+
+	ctx, stop := signalContext()
+	defer stop()
+	ctx, span := lib.StartSpan(ctx, "pipeline continue", attribute.String("aether.job_id", jobID))
+	defer func() { lib.EndSpan(span, err) }()
 
 	// Execute the step
-	if err := executeStep(jobToExecute, stepToExecute, config, logger, noProgress); err != nil {
+	if err := executeStep(ctx, jobToExecute, stepToExecute, config, logger, noProgress); err != nil {
+		printCancelHint(jobID, err)
 		return err
 	}
 
-	fmt.Printf("\nUse 'aether pipeline status %s' to check progress\n", jobID)
-	fmt.Printf("Or run 'aether pipeline continue %s' to proceed to the next step\n", jobID)
+	fmt.Printf("\n%s\n", i18n.T(i18n.MsgStatusHint, jobID))
+	fmt.Println(i18n.T(i18n.MsgContinueHint, jobID))
 
 	return nil
 }
 
+// signalContext returns a context cancelled by SIGINT (Ctrl-C) or SIGTERM (e.g. 'aether job
+// cancel', docker stop), with the signal as its cause. After the first signal the default
+// handling is restored, so a second Ctrl-C ends the process at once
+// The returned stop function must be called when the run ends
+func signalContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			cancel(fmt.Errorf("received %s", sig))
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
+
+// printCancelHint tells how to resume a job whose run was cancelled
+func printCancelHint(jobID string, err error) {
+	if errors.Is(err, pipeline.ErrStepCancelled) {
+		fmt.Fprintf(os.Stderr, "\n%s\n", i18n.T(i18n.MsgRunCancelled, jobID))
+		fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgResumeHint, jobID))
+	}
+}
+
+// exitCodeMaxRuntime is the exit status of a run aborted by pipeline.max_runtime_minutes
+const exitCodeMaxRuntime = 3
+
+// superviseRun refreshes the job's heartbeat file while a run holds its lock and ends the
+// process when the run exceeds pipeline.max_runtime_minutes
+// Deferred calls do not run on os.Exit, so the heartbeat and job lock are released first
+// Returns a stop function that must be called when the run ends
+func superviseRun(job *models.PipelineJob, config *models.ProjectConfig, lock *services.JobLock, logger *lib.Logger) func() {
+	heartbeat := services.StartHeartbeat(config.JobsDir, job.JobID, config.Heartbeat, logger)
+	maxRuntime := config.Pipeline.GetMaxRuntime()
+	stopRuntimeWatch := pipeline.WatchJobRuntime(job, maxRuntime, logger, func() {
+		heartbeat.Stop()
+		if err := lock.Release(); err != nil {
+			logger.Error("Failed to release job lock", "error", err)
+		}
+		fmt.Fprintf(os.Stderr, "\n%s\n", i18n.T(i18n.MsgMaxRuntimeAborted, job.JobID, maxRuntime))
+		fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgResumeHint, job.JobID))
+		os.Exit(exitCodeMaxRuntime)
+	})
+	return func() {
+		stopRuntimeWatch()
+		heartbeat.Stop()
+	}
+}
+
 func getStatusSymbol(status models.StepStatus) string {
 	switch status {
 	case models.StepStatusCompleted:
@@ -513,6 +982,8 @@ func getStatusSymbol(status models.StepStatus) string {
 		return "→"
 	case models.StepStatusFailed:
 		return "✗"
+	case models.StepStatusCancelled:
+		return "⊘"
 	case models.StepStatusPending:
 		return " "
 	default:
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// preflightCmd represents the preflight command
+var preflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Smoke-test every enabled step before a production run",
+	Long: `Run a tiny synthetic payload through every enabled pipeline step and
+report a go/no-go verdict before launching a production extraction.
+
+Checks per step:
+  torch              - Authenticate against TORCH with the configured credentials
+  local_import       - No external service required
+  http_import        - No external service required
+  dimp               - Round-trip a dummy Patient through DIMP
+  imaging            - Reach the DICOMweb server (if dicomweb_url is set)
+  validation         - Skipped (not yet implemented)
+  csv_conversion     - Convert a 3-line NDJSON file
+  parquet_conversion - Convert a 3-line NDJSON file
+
+No job is created and no data is written to the jobs directory.
+Synthetic resources contain no patient data.
+
+Exit status is non-zero when any check fails (NO-GO).
+
+Examples:
+  # Check the default configuration
+  aether preflight
+
+  # Check a specific configuration before a production run
+  aether preflight --config prod.yaml && aether pipeline start query.crtdl --config prod.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runPreflight,
+}
+
+func init() {
+	rootCmd.AddCommand(preflightCmd)
+}
+
+func runPreflight(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Create logger
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+
+	// Single attempt - preflight should report failures quickly, not retry them
+	retry := config.Retry
+	retry.MaxAttempts = 1
+	httpClient := services.NewHTTPClient(30*time.Second, retry, logger)
+
+	fmt.Printf("Running preflight checks for %d enabled steps...\n\n", len(config.Pipeline.EnabledSteps))
+
+	report := pipeline.RunPreflight(config, httpClient, logger)
+
+	for _, check := range report.Checks {
+		fmt.Printf("  %s %-20s %s (%s)\n",
+			getPreflightStatusSymbol(check.Status),
+			check.Step,
+			check.Message,
+			check.Duration.Round(time.Millisecond),
+		)
+	}
+
+	fmt.Println()
+	if !report.IsGo() {
+		fmt.Println("✗ NO-GO: fix the failed checks before starting a production run")
+		return fmt.Errorf("preflight failed")
+	}
+
+	fmt.Println("✓ GO: all enabled steps are ready")
+	return nil
+}
+
+// getPreflightStatusSymbol returns the display symbol for a preflight check status
+func getPreflightStatusSymbol(status pipeline.PreflightStatus) string {
+	switch status {
+	case pipeline.PreflightPassed:
+		return "✓"
+	case pipeline.PreflightFailed:
+		return "✗"
+	case pipeline.PreflightSkipped:
+		return "○"
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var repackageFormats string
+
+// repackageCmd represents the repackage command
+var repackageCmd = &cobra.Command{
+	Use:   "repackage <job-id> [--formats csv,parquet]",
+	Short: "Regenerate packaging outputs from a job's pseudonymized data",
+	Long: `Re-run only the packaging steps (csv_conversion, parquet_conversion) of a job
+on its existing pseudonymized output, without re-running import or DIMP.
+
+The current configuration's flatten and packaging settings are used, so an
+updated column mapping (flatten.mapping_file) or Parquet options take effect.
+Previous output of the selected formats is removed before they run again.
+Delta tables (pipeline.packaging.table_format: delta) are kept and get a new
+version instead.
+
+Without --formats, every format the configuration can produce is regenerated:
+csv, and parquet with pipeline.packaging.mode: local. Formats that would need a
+conversion service are refused before any output is removed.
+
+The job must have a completed dimp step.
+
+Examples:
+  # Regenerate all packaging output after changing the column mapping
+  aether repackage abc123
+
+  # Regenerate only the CSV tables
+  aether repackage abc123 --formats csv`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runRepackage,
+}
+
+func init() {
+	rootCmd.AddCommand(repackageCmd)
+
+	repackageCmd.Flags().StringVar(&repackageFormats, "formats", "", "Comma-separated output formats to regenerate (csv, parquet; default: all the configuration can produce)")
+	_ = repackageCmd.RegisterFlagCompletionFunc("formats", cobra.FixedCompletions([]cobra.Completion{"csv", "parquet", "csv,parquet"}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func runRepackage(cmd *cobra.Command, args []string) error {
+	jobID := args[0]
+
+	// Load configuration
+	config, err := loadConfigForJobs()
+	if err != nil {
+		return err
+	}
+
+	steps := pipeline.DefaultRepackageSteps(*config)
+	if repackageFormats != "" {
+		steps, err = pipeline.ParseRepackageFormats(repackageFormats)
+		if err != nil {
+			return fmt.Errorf("invalid --formats: %w", err)
+		}
+	} else if len(steps) == 0 {
+		return fmt.Errorf("no packaging format can be regenerated in-process with this configuration (unset services.csv_conversion.url or set pipeline.packaging.mode: local)")
+	}
+
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+
+	// Acquire job lock to prevent concurrent execution
+	lock, err := services.AcquireJobLock(config.JobsDir, jobID, logger)
+	if err != nil {
+		return fmt.Errorf("cannot repackage job: %w\n\nAnother process may be working on this job. Wait for it to complete or check job status", err)
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			logger.Error("Failed to release job lock", "error", err)
+		}
+	}()
+
+	job, err := pipeline.LoadJob(config.JobsDir, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to load job: %w", err)
+	}
+
+	job, err = pipeline.PrepareRepackage(job, steps, *config, logger)
+	if err != nil {
+		return err
+	}
+	if err := pipeline.UpdateJob(config.JobsDir, job); err != nil {
+		return fmt.Errorf("failed to save job state: %w", err)
+	}
+
+	stopSupervision := superviseRun(job, config, lock, logger)
+	defer stopSupervision()
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	fmt.Printf("Job: %s\n", job.JobID)
+	for _, stepName := range steps {
+		fmt.Printf("Repackaging: %s\n\n", stepName)
+		if err := executeStepManually(ctx, job, stepName, &job.Config, logger); err != nil {
+			printCancelHint(job.JobID, err)
+			return fmt.Errorf("%s failed: %w", stepName, err)
+		}
+	}
+
+	fmt.Printf("\n✓ Repackaging completed\n")
+	return nil
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var reportDiffJSON bool
+
+// reportCmd represents the report command group
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Reports about pipeline runs",
+	Long:  `Reports comparing and summarizing pipeline runs.`,
+}
+
+// reportDiffCmd represents the report diff command
+var reportDiffCmd = &cobra.Command{
+	Use:   "diff <job-a> <job-b>",
+	Short: "Compare step durations, resource counts and warnings of two runs",
+	Long: `Compare two runs, typically of the same CRTDL before and after an upgrade
+of aether or DIMP.
+
+For every step that ran in either job, the diff shows:
+  • Status and duration, with the relative change
+  • Resources in the step's FHIR output (non-empty NDJSON lines), with the
+    difference; not shown for conversion steps or deleted output
+  • Warnings per class (see 'aether pipeline status'), with the difference
+
+Changed resource counts, new warning classes and steps that no longer
+complete are marked with '!'. A note is printed if the jobs ran on
+different inputs; CRTDLs are compared by content.
+
+Examples:
+  # Compare the run before an upgrade with the run after it
+  aether report diff abc-123-def fed-321-cba
+
+  # Machine-readable diff
+  aether report diff abc-123-def fed-321-cba --json`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) >= 2 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeJobIDs(cmd, nil, toComplete)
+	},
+	RunE: runReportDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportDiffCmd)
+
+	reportDiffCmd.Flags().BoolVar(&reportDiffJSON, "json", false, "Output the diff as JSON")
+}
+
+func runReportDiff(cmd *cobra.Command, args []string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	diff, err := services.DiffJobs(config.JobsDir, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if reportDiffJSON {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode diff: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Comparing job %s (A) with job %s (B)\n", diff.JobA, diff.JobB)
+	if diff.StudyA != "" || diff.StudyB != "" {
+		fmt.Printf("Study: %s → %s\n", orDash(diff.StudyA), orDash(diff.StudyB))
+	}
+	if diff.SameInput {
+		fmt.Printf("Input: %s\n", diff.InputNote)
+	} else {
+		fmt.Printf("⚠ Input: %s\n", diff.InputNote)
+	}
+
+	for _, step := range diff.Steps {
+		fmt.Printf("\n%s\n", step.Step)
+		printStepDiff(step.A, step.B)
+	}
+	return nil
+}
+
+// printStepDiff prints the status, duration, resources and warnings of a step in both runs
+func printStepDiff(a, b *services.StepReport) {
+	if a == nil || b == nil {
+		ran, missing := "A", "B"
+		if a == nil {
+			ran, missing = "B", "A"
+		}
+		fmt.Printf("  ! only ran in %s, not in %s\n", ran, missing)
+		a, b = orEmptyStepReport(a), orEmptyStepReport(b)
+	}
+
+	statusMark := " "
+	if a.Status == models.StepStatusCompleted && b.Status != models.StepStatusCompleted {
+		statusMark = "!"
+	}
+	fmt.Printf("%s %-10s %-14s → %s\n", statusMark, "status", a.Status, b.Status)
+
+	if a.DurationSeconds > 0 || b.DurationSeconds > 0 {
+		fmt.Printf("  %-10s %-14s → %-14s %s\n", "duration", formatDiffSeconds(a.DurationSeconds), formatDiffSeconds(b.DurationSeconds),
+			formatRelativeChange(a.DurationSeconds, b.DurationSeconds))
+	}
+
+	if a.Resources != nil || b.Resources != nil {
+		mark := " "
+		if a.Resources != nil && b.Resources != nil && *a.Resources != *b.Resources {
+			mark = "!"
+		}
+		fmt.Printf("%s %-10s %-14s → %-14s %s\n", mark, "resources", formatDiffCount(a.Resources), formatDiffCount(b.Resources),
+			formatCountChange(a.Resources, b.Resources))
+	}
+
+	codes := make([]models.WarningCode, 0, len(a.Warnings)+len(b.Warnings))
+	for code := range a.Warnings {
+		codes = append(codes, code)
+	}
+	for code := range b.Warnings {
+		if _, seen := a.Warnings[code]; !seen {
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		mark := " "
+		if _, seen := a.Warnings[code]; !seen {
+			mark = "!"
+		}
+		countA, countB := a.Warnings[code], b.Warnings[code]
+		fmt.Printf("%s %-10s %-14d → %-14d %+d  (%s)\n", mark, "warnings", countA, countB, countB-countA, code)
+	}
+}
+
+// orDash returns value, or "-" if it is empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func orEmptyStepReport(report *services.StepReport) *services.StepReport {
+	if report == nil {
+		return &services.StepReport{Status: "-"}
+	}
+	return report
+}
+
+func formatDiffSeconds(seconds float64) string {
+	if seconds == 0 {
+		return "-"
+	}
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
+
+func formatDiffCount(count *int) string {
+	if count == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *count)
+}
+
+// formatRelativeChange formats the change from a to b in percent, e.g. "+12%"
+func formatRelativeChange(a, b float64) string {
+	if a == 0 || b == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+.0f%%", (b-a)*100/a)
+}
+
+func formatCountChange(a, b *int) string {
+	if a == nil || b == nil {
+		return ""
+	}
+	return fmt.Sprintf("%+d", *b-*a)
+}
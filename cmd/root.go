@@ -6,18 +6,34 @@ Aether is a CLI tool for orchestrating Data Use Process (DUP) pipelines.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/i18n"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
 )
 
 var (
 	// Global flags
 	cfgFile string
+	jobsDir string
+	tenant  string
 	verbose bool
+
+	// stopTracing flushes the spans of the command; set by loadConfig when tracing is configured
+	stopTracing = func(context.Context) error { return nil }
 )
 
+// tracingFlushTimeout bounds the export of the last spans when the command ends
+const tracingFlushTimeout = 5 * time.Second
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "aether",
@@ -54,28 +70,122 @@ Quick Start:
 Configuration:
   The CLI looks for configuration in the following order:
     1. --config flag
-    2. ./aether.yaml (current directory)
-    3. ~/.config/aether/aether.yaml (user config directory)
+    2. AETHER_CONFIG environment variable
+    3. ./aether.yaml (current directory)
+    4. ~/.config/aether/config.yaml (user config directory)
+  --config - reads the configuration from stdin; --config https://host/aether.yaml
+  fetches it, optionally pinned with #sha256=<hex> (required for plain http://).
+  Use --verbose to print which file was loaded.
+
+  The jobs directory is taken from --jobs-dir, then AETHER_JOBS_DIR,
+  then jobs_dir in the configuration file (default: ./jobs).
+  With --tenant (or AETHER_TENANT), jobs live in <jobs_dir>/tenants/<name>
+  and the tenant's service credentials and quotas apply.
 
 For more information:
   Documentation: https://github.com/trobanga/aether
   Report issues: https://github.com/trobanga/aether/issues`,
-	Version: "1.0.0",
+	Version: lib.Version,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	if flushErr := stopTracing(ctx); flushErr != nil {
+		lib.DefaultLogger.Warn("Failed to export traces", "error", flushErr)
+	}
+	cancel()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgError, err))
+		os.Exit(exitCode(err))
+	}
+}
+
+// loadConfig loads the configuration file, applies --jobs-dir, switches messages to
+// the configured locale, timestamps to the configured time zone, sets how outbound
+// requests identify themselves and starts exporting traces. In verbose mode it reports which file was loaded, on
+// stderr so that JSON output stays parseable
+func loadConfig() (*models.ProjectConfig, error) {
+	path, source := services.ResolveConfigFile(cfgFile)
+	if jobsDir != "" {
+		services.SetConfigValue("jobs_dir", jobsDir)
+	}
+
+	config, err := services.LoadConfig(path)
+	if err != nil {
+		return nil, i18n.Errorf(i18n.MsgLoadConfigFailed, err)
+	}
+	i18n.SetLocale(i18n.Resolve(config.Locale))
+	timeZone, _ := config.GetTimeZone() // validated by LoadConfig
+	lib.ConfigureTimeZone(timeZone)
+	lib.ConfigureClientIdentity(config.HTTPClient)
+	lib.ConfigureChaos(config.HTTPClient.Chaos)
+	shutdownTracing, err := lib.ConfigureTracing(config.Tracing, lib.DefaultLogger)
+	if err != nil {
+		return nil, i18n.Errorf(i18n.MsgLoadConfigFailed, err)
+	}
+	stopTracing = shutdownTracing
+	if config.HTTPClient.Chaos.Enabled {
+		fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgChaosEnabled, services.ChaosEnvVar))
+	}
+
+	// Scope jobs, credentials and quotas to the selected tenant
+	if tenant == "" {
+		tenant = os.Getenv(services.TenantEnvVar)
+	}
+	config, err = services.ScopeConfigToTenant(config, tenant)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return nil, i18n.Errorf(i18n.MsgLoadConfigFailed, err)
+	}
+
+	if verbose {
+		if path == "" {
+			fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgNoConfigFile, strings.Join(services.ConfigSearchPaths(), ", ")))
+		} else {
+			fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgConfigFileUsed, redactConfigPath(path), source))
+		}
+		fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgJobsDirUsed, config.JobsDir))
 	}
+	return config, nil
+}
+
+// loadConfigForJobs loads the configuration for a command that creates or runs jobs and
+// checks that the jobs directory is usable, so that permission, locking and free space
+// problems fail before a job starts instead of as write errors in the middle of a step
+func loadConfigForJobs() (*models.ProjectConfig, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := services.CheckJobsDir(config.JobsDir, config.Storage); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// redactConfigPath hides credentials of a configuration URL
+func redactConfigPath(path string) string {
+	if !services.IsRemoteConfig(path) {
+		return path
+	}
+	if parsed, err := url.Parse(path); err == nil {
+		return parsed.Redacted()
+	}
+	return path
 }
 
 func init() {
+	// Messages follow the environment until a configuration selects a locale
+	i18n.SetLocale(i18n.Resolve(""))
+
 	// Persistent flags (available to all subcommands)
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ./aether.yaml, ~/.config/aether/aether.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file, - for stdin or an http(s) URL with optional #sha256=<hex> pin (default: $AETHER_CONFIG, ./aether.yaml, ~/.config/aether/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&jobsDir, "jobs-dir", "", "jobs directory (overrides $AETHER_JOBS_DIR and jobs_dir in the config file)")
+	rootCmd.PersistentFlags().StringVar(&tenant, "tenant", "", "tenant whose jobs, credentials and quotas to use (default: $AETHER_TENANT)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose logging")
 
 	// Add version template
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var runTemplate string
+
+// runCmd starts a pipeline job from a job template
+var runCmd = &cobra.Command{
+	Use:   "run --template <file> [input...]",
+	Short: "Start a pipeline job from a job template",
+	Long: `Start a pipeline job from a job template, for runs that repeat with the
+same settings (e.g. a nightly extraction).
+
+A template bundles the input type, the CRTDL file or other inputs, the
+enabled steps, labels and notification settings:
+
+  # nightly.yaml
+  name: nightly
+  input_type: crtdl_file
+  crtdl: queries/diabetes.crtdl
+  enabled_steps: [torch, dimp, parquet_conversion]
+  labels:
+    schedule: nightly
+    study: diabetes
+  notifications:
+    webhook_url: https://chat.example.org/hooks/aether
+
+Relative paths are resolved against the template's directory. The template
+is validated together with the configuration it is applied to, and stored
+with the job (with its path and SHA-256 digest) so the run can be
+reproduced. Inputs given as arguments replace the template's inputs; the
+first one must still be of the template's input type.
+
+Examples:
+  # Run the nightly extraction
+  aether run --template nightly.yaml
+
+  # Same settings, different CRTDL
+  aether run --template nightly.yaml queries/hypertension.crtdl`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runFromTemplate,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringVar(&runTemplate, "template", "", "Job template file (required)")
+	_ = runCmd.MarkFlagRequired("template")
+	_ = runCmd.MarkFlagFilename("template", "yaml", "yml")
+	runCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Disable progress indicators")
+	runCmd.Flags().BoolVar(&jsonProgress, "json-progress", false, "Write progress as JSON lines to stderr instead of spinners and bars")
+	runCmd.Flags().StringVar(&emitTrace, "emit-trace", "", "Write per-step trace files to this directory and use workflow exit codes (0, 1, 3, 75)")
+}
+
+func runFromTemplate(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigForJobs()
+	if err != nil {
+		return err
+	}
+
+	template, err := services.LoadJobTemplate(runTemplate)
+	if err != nil {
+		return err
+	}
+	if err := template.Apply(config); err != nil {
+		return fmt.Errorf("job template %s: %w", template.Name, err)
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("configuration with job template %s applied is invalid: %w", template.Name, err)
+	}
+
+	inputs := args
+	if len(inputs) == 0 {
+		inputs = template.Sources()
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("job template %s has no crtdl or inputs; pass the input as argument", template.Name)
+	}
+
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+	installCohortSizePrompt()
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	start := time.Now()
+	jobID, err := startPipeline(ctx, inputs, nil, template, config, logger, noProgress)
+	return finishTraced(config.JobsDir, jobID, start, err)
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/server"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// runLogFileName is the file in the job directory that receives the output of jobs submitted through the API
+const runLogFileName = "run.log"
+
+var (
+	serveListen     string
+	serveGRPCListen string
+	shareTTL        time.Duration
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve [--listen :8080]",
+	Short: "Serve job status over a REST API",
+	Long: `Serve a REST API over the jobs directory.
+
+Endpoints:
+  GET  /api/v1/jobs                         List jobs (newest first)
+  GET  /api/v1/jobs/{id}                    Status of one job
+  POST /api/v1/jobs/{id}/share?ttl=24h      Create a read-only share link
+  GET  /share/{token}                       Job status via share link
+  GET  /share/{token}/artifacts/{path}      Report file via share link
+  GET  /openapi.json                        OpenAPI 3 description of the API
+
+With server.grpc_listen (or --grpc-listen), a gRPC JobService is served as
+well: SubmitJob, GetStatus (streams status and events), CancelJob and
+ListJobs. The contract is api/proto/aether/v1/aether.proto. Inputs of
+submitted jobs are URLs, S3 prefixes, CRTDL files, or server paths under
+server.allowed_input_dirs. Submitted jobs run in a child 'aether pipeline
+run' process; their output is written to run.log in the job directory.
+Without server.grpc_tls_cert and server.grpc_tls_key, the gRPC API is only
+served on a loopback address (e.g. 127.0.0.1:9091).
+
+Share links are signed with server.share_secret and expire after
+server.share_ttl_hours (default 72). They grant read-only access to one
+job's status and report files (imaging report, data dictionaries) - never
+to its data. Links point to server.public_url.
+
+With server.tokens configured, /api/v1 requests need an
+"Authorization: Bearer <token>" header. Roles: viewer (read), submitter
+(read, share, submit), operator (all, incl. job control). A token with a
+tenant only reaches that tenant's endpoints. Every request is recorded in
+<jobs_dir>/audit.ndjson.
+
+Configuration example:
+  server:
+    listen: ":8080"
+    public_url: "https://aether.example.org"
+    share_secret: "${AETHER_SHARE_SECRET}"
+    share_ttl_hours: 72
+    tokens:
+      - name: dashboard
+        token: "${AETHER_DASHBOARD_TOKEN}"
+        role: viewer
+
+Examples:
+  # Serve on the configured address
+  aether serve
+
+  # Serve on a different port
+  aether serve --listen :9090
+
+  # Serve the gRPC API to local clients, too
+  aether serve --grpc-listen 127.0.0.1:9091`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+// jobShareCmd represents the job share command
+var jobShareCmd = &cobra.Command{
+	Use:   "share <job-id> [--ttl 24h]",
+	Short: "Create a read-only, expiring link to a job's status",
+	Long: `Print a signed link to the job's status page served by 'aether serve'.
+
+The link can be handed to the requester of an extraction to follow its
+progress without access to the rest of the API. It expires after --ttl
+(default: server.share_ttl_hours). Requires server.share_secret and
+server.public_url to be configured; the serving instance must use the
+same share_secret.
+
+Examples:
+  # Share a job for one week
+  aether job share abc123 --ttl 168h`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeJobIDs,
+	RunE:              runJobShare,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	jobCmd.AddCommand(jobShareCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "Listen address (default: server.listen or :8080)")
+	serveCmd.Flags().StringVar(&serveGRPCListen, "grpc-listen", "", "Listen address of the gRPC API (default: server.grpc_listen, empty = disabled)")
+	jobShareCmd.Flags().DurationVar(&shareTTL, "ttl", 0, "Validity of the link (default: server.share_ttl_hours)")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	// Jobs run in child processes that load the configuration again, which stdin cannot provide
+	if path, _ := services.ResolveConfigFile(cfgFile); path == services.ConfigStdin {
+		return fmt.Errorf("aether serve cannot read its configuration from stdin; use a file or URL")
+	}
+
+	config, err := loadConfigForJobs()
+	if err != nil {
+		return err
+	}
+	if serveListen != "" {
+		config.Server.Listen = serveListen
+	}
+	if serveGRPCListen != "" {
+		config.Server.GRPCListen = serveGRPCListen
+	}
+
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+
+	recoverInterruptedJobs(*config, logger)
+
+	srv := server.New(*config, logger)
+	srv.SetLauncher(launchJob)
+	return srv.ListenAndServe()
+}
+
+// recoverInterruptedJobs reconciles the jobs of all tenants that were left behind by processes
+// that ended while running them, e.g. when the host went down together with the server.
+// Recovered jobs are failed and can be resumed with 'aether pipeline continue'
+func recoverInterruptedJobs(config models.ProjectConfig, logger *lib.Logger) {
+	jobsDirs := []string{config.JobsDir}
+	if config.Tenant == "" {
+		for _, tenant := range config.Tenants {
+			if scoped, err := config.ForTenant(tenant.Name); err == nil {
+				jobsDirs = append(jobsDirs, scoped.JobsDir)
+			}
+		}
+	}
+
+	for _, jobsDir := range jobsDirs {
+		recovered, err := pipeline.RecoverJobs(jobsDir, logger)
+		if err != nil {
+			logger.Warn("Failed to scan jobs for interrupted runs", "jobs_dir", jobsDir, "error", err)
+			continue
+		}
+		for _, recovery := range recovered {
+			if recovery.InterruptedStep != "" {
+				logger.Warn("Job was interrupted; resume it with 'aether pipeline continue'", "job_id", recovery.JobID,
+					"step", recovery.InterruptedStep, "removed_files", len(recovery.RemovedFiles))
+			}
+		}
+		if len(recovered) > 0 {
+			logger.Info("Recovered interrupted jobs", "jobs_dir", jobsDir, "jobs", len(recovered))
+		}
+	}
+}
+
+// launchJob runs a job submitted through the API in a child 'aether pipeline run' process
+// that uses the same configuration file, jobs directory and tenant. Its output goes to run.log
+// in the job directory; the job's state and events report its progress
+func launchJob(config models.ProjectConfig, jobID string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate aether executable: %w", err)
+	}
+
+	args := []string{"pipeline", "run", jobID, "--no-progress", "--jobs-dir", config.BaseJobsDir()}
+	if cfgFile != "" {
+		args = append(args, "--config", cfgFile)
+	}
+	if config.Tenant != "" {
+		args = append(args, "--tenant", config.Tenant)
+	}
+	if verbose {
+		args = append(args, "--verbose")
+	}
+
+	logPath := filepath.Join(services.GetJobDir(config.JobsDir, jobID), runLogFileName)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create run log: %w", err)
+	}
+
+	child := exec.Command(executable, args...)
+	child.Stdout = logFile
+	child.Stderr = logFile
+	if err := child.Start(); err != nil {
+		_ = logFile.Close()
+		return err
+	}
+	go func() {
+		_ = child.Wait()
+		_ = logFile.Close()
+	}()
+	return nil
+}
+
+func runJobShare(cmd *cobra.Command, args []string) error {
+	jobID := args[0]
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if _, err := pipeline.LoadJob(config.JobsDir, jobID); err != nil {
+		return fmt.Errorf("failed to load job: %w", err)
+	}
+
+	link, err := server.CreateShareLink(*config, jobID, shareTTL, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(link.URL)
+	fmt.Printf("Expires: %s\n", link.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var (
+	statsSince string
+	statsJSON  bool
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Aggregate volumes, durations and failure rates of past jobs",
+	Long: `Aggregate the persisted state of all jobs in the jobs directory into a
+summary for capacity planning, without external monitoring.
+
+The summary shows:
+  • Jobs by status, with the files and data volume they processed
+  • Per step: runs, failures, failure rate, retries, volume and the
+    median, 95th percentile and maximum duration of completed runs
+  • Per service (TORCH, DIMP, ...): the same for the steps using it, plus
+    failures of transient type (network, 5xx, timeout after all retries)
+  • Requests, resources and bytes sent to DIMP, by the jobs shown and per
+    calendar month (the monthly counters also cover deleted jobs), against
+    services.dimp.usage_quota if configured
+
+Only job state is read, so jobs whose data was cleaned up are included;
+deleted jobs are not. --since selects jobs by creation time: a number of
+days or weeks (30d, 2w), a Go duration (36h) or a date (2025-01-31).
+
+Examples:
+  # Last 30 days
+  aether stats --since 30d
+
+  # All jobs, machine-readable
+  aether stats --json`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVar(&statsSince, "since", "", "Only jobs created within this period (30d, 2w, 36h) or since this date (YYYY-MM-DD)")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Output the statistics as JSON")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	since, err := parseStatsSince(statsSince, time.Now())
+	if err != nil {
+		return err
+	}
+
+	logger := lib.NewLogger(lib.LogLevelWarn)
+	stats, err := services.AggregateJobStats(config.JobsDir, since, logger)
+	if err != nil {
+		return err
+	}
+
+	if statsJSON {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode statistics: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printJobStats(stats)
+	printDIMPUsage(stats, config.Services.DIMP.UsageQuota)
+	return nil
+}
+
+// parseStatsSince resolves --since to the earliest creation time; empty means all jobs
+func parseStatsSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, lib.TimeZone()); err == nil {
+		return date, nil
+	}
+	period, err := lib.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: use e.g. 30d, 2w, 36h or 2025-01-31", value)
+	}
+	return now.Add(-period), nil
+}
+
+func printJobStats(stats *services.JobStats) {
+	if stats.Since != nil {
+		fmt.Printf("Jobs created since %s: %d\n", lib.FormatTimestamp(*stats.Since), stats.Jobs)
+	} else {
+		fmt.Printf("Jobs: %d\n", stats.Jobs)
+	}
+	if stats.Unreadable > 0 {
+		fmt.Printf("⚠ %d job(s) could not be read\n", stats.Unreadable)
+	}
+	if stats.Jobs == 0 {
+		return
+	}
+
+	fmt.Printf("  %d completed, %d failed, %d other\n", stats.Completed, stats.Failed, stats.Other)
+	fmt.Printf("  %d files, %s\n", stats.Files, lib.FormatBytes(stats.Bytes))
+	if stats.Durations.Count > 0 {
+		fmt.Printf("  duration of completed jobs: median %s, p95 %s, max %s\n",
+			formatDiffSeconds(stats.Durations.Median), formatDiffSeconds(stats.Durations.P95), formatDiffSeconds(stats.Durations.Max))
+	}
+
+	if len(stats.Steps) > 0 {
+		fmt.Printf("\n%-20s %6s %7s %6s %8s %8s %12s %9s %9s %9s\n",
+			"STEP", "RUNS", "FAILED", "FAIL%", "RETRIES", "FILES", "DATA", "MEDIAN", "P95", "MAX")
+		for _, step := range stats.Steps {
+			fmt.Printf("%-20s %6d %7d %5.1f%% %8d %8d %12s %9s %9s %9s\n",
+				step.Step, step.Runs, step.Failed, step.FailureRate*100, step.Retries, step.Files, lib.FormatBytes(step.Bytes),
+				formatDiffSeconds(step.Durations.Median), formatDiffSeconds(step.Durations.P95), formatDiffSeconds(step.Durations.Max))
+		}
+	}
+
+	if len(stats.Services) > 0 {
+		fmt.Printf("\n%-20s %6s %7s %10s %6s %8s %9s %9s %9s\n",
+			"SERVICE", "RUNS", "FAILED", "TRANSIENT", "FAIL%", "RETRIES", "MEDIAN", "P95", "MAX")
+		for _, service := range stats.Services {
+			fmt.Printf("%-20s %6d %7d %10d %5.1f%% %8d %9s %9s %9s\n",
+				service.Service, service.Runs, service.Failed, service.TransientFailures, service.FailureRate*100, service.Retries,
+				formatDiffSeconds(service.Durations.Median), formatDiffSeconds(service.Durations.P95), formatDiffSeconds(service.Durations.Max))
+		}
+	}
+}
+
+// printDIMPUsage prints the DIMP usage of the jobs and the monthly counters, which also
+// cover deleted jobs and are therefore shown without any job
+func printDIMPUsage(stats *services.JobStats, quota models.DIMPQuotaConfig) {
+	if !stats.DIMPUsage.IsZero() || len(stats.DIMPMonths) > 0 {
+		fmt.Printf("\nDIMP usage: %d requests, %d resources, %s\n",
+			stats.DIMPUsage.Requests, stats.DIMPUsage.Resources, lib.FormatBytes(stats.DIMPUsage.Bytes))
+	}
+	if len(stats.DIMPMonths) > 0 {
+		fmt.Printf("%-20s %9s %10s %12s", "MONTH", "REQUESTS", "RESOURCES", "DATA")
+		if quota.IsActive() {
+			fmt.Printf(" %6s", "QUOTA")
+		}
+		fmt.Println()
+		for _, month := range stats.DIMPMonths {
+			fmt.Printf("%-20s %9d %10d %12s", month.Month, month.Requests, month.Resources, lib.FormatBytes(month.Bytes))
+			if quota.IsActive() {
+				fmt.Printf(" %5d%%", quota.UsedPercent(month.DIMPUsage))
+			}
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// jobIDFileName is written to the trace directory so workflow engines can pass the job on
+const jobIDFileName = "job_id.txt"
+
+// emitTrace is the directory of --emit-trace (empty = disabled)
+var emitTrace string
+
+// exitCodeError carries the exit status of a failed run with --emit-trace
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// exitCode returns the exit status for an error returned by a command
+func exitCode(err error) int {
+	var coded *exitCodeError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return services.ExitCodeFailed
+}
+
+// finishTraced writes a trace file for every step of the job that started at or after start,
+// plus the job ID, to the --emit-trace directory. A failed run's error then carries the exit
+// code of its failed step (75 for transient failures). Without --emit-trace err is returned as is
+func finishTraced(jobsDir string, jobID string, start time.Time, err error) error {
+	if emitTrace == "" || jobID == "" {
+		return err
+	}
+	logger := lib.DefaultLogger
+
+	if writeErr := os.MkdirAll(emitTrace, 0755); writeErr != nil {
+		logger.Warn("Failed to create trace directory", "dir", emitTrace, "error", writeErr)
+		return err
+	}
+	if writeErr := os.WriteFile(filepath.Join(emitTrace, jobIDFileName), []byte(jobID+"\n"), 0644); writeErr != nil {
+		logger.Warn("Failed to write job ID file", "dir", emitTrace, "error", writeErr)
+	}
+
+	job, loadErr := services.LoadJobState(jobsDir, jobID)
+	if loadErr != nil {
+		logger.Warn("Failed to load job state for trace", "job_id", jobID, "error", loadErr)
+		return err
+	}
+	code := 0
+	for _, step := range job.Steps {
+		if step.StartedAt == nil || step.StartedAt.Before(start) {
+			continue
+		}
+		trace := services.NewStepTrace(job.JobID, step)
+		if writeErr := services.WriteStepTrace(emitTrace, trace); writeErr != nil {
+			logger.Warn("Failed to write step trace", "step", step.Name, "error", writeErr)
+		}
+		if code == 0 {
+			code = trace.Exit
+		}
+	}
+
+	if err == nil {
+		return nil
+	}
+	if code == 0 {
+		code = services.ExitCodeFailed
+	}
+	return &exitCodeError{code: code, err: err}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var (
+	watchInterval  time.Duration
+	watchStableFor time.Duration
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch <drop-dir>",
+	Short: "Start a pipeline job for every new fileset in a drop directory",
+	Long: `Monitor a drop directory and automatically create and run a pipeline job
+for each new complete fileset.
+
+A fileset is a subdirectory of the drop directory containing FHIR NDJSON files,
+e.g. one export dumped by an upstream system. A fileset is complete once none of
+its NDJSON files has been added, resized or modified for the stability window
+(--stable-for). Each complete fileset is imported with local_import and runs
+through all enabled steps, exactly like 'aether pipeline start <fileset>'.
+
+Hidden subdirectories (starting with ".") are ignored, so uploaders can write to
+.incoming-<name> and rename the directory when done.
+
+Filesets already used as input by an existing job are skipped, so restarting
+the watcher does not import them again. A failed job does not stop the watcher;
+fix the cause and run 'aether pipeline continue <job-id>'.
+
+Jobs run one at a time. Stop the watcher with Ctrl+C; a running job is cancelled
+and can be resumed with 'aether pipeline continue <job-id>'.
+
+Examples:
+  # Watch a drop directory with the default 60s stability window
+  aether watch /data/drop
+
+  # Poll every 30 seconds, require 5 minutes without changes
+  aether watch /data/drop --interval 30s --stable-for 5m`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 10*time.Second, "How often to scan the drop directory")
+	watchCmd.Flags().DurationVar(&watchStableFor, "stable-for", 60*time.Second, "How long a fileset must stay unchanged before a job is started")
+	watchCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Disable progress indicators")
+	watchCmd.Flags().BoolVar(&jsonProgress, "json-progress", false, "Write progress as JSON lines to stderr instead of spinners and bars")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	if watchInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	if watchStableFor < 0 {
+		return fmt.Errorf("--stable-for must not be negative")
+	}
+
+	// Load configuration
+	config, err := loadConfigForJobs()
+	if err != nil {
+		return err
+	}
+
+	if !config.Pipeline.IsStepEnabled(models.StepLocalImport) {
+		return fmt.Errorf("watch requires local_import in pipeline.enabled_steps")
+	}
+
+	// Create logger
+	logLevel := lib.LogLevelInfo
+	if verbose {
+		logLevel = lib.LogLevelDebug
+	}
+	logger := lib.NewLogger(logLevel)
+
+	watcher, err := services.NewDropWatcher(args[0], watchStableFor, config.JobMetadata)
+	if err != nil {
+		return err
+	}
+
+	// Skip filesets that earlier jobs already imported
+	processed, err := services.ProcessedJobSources(config.JobsDir)
+	if err != nil {
+		return fmt.Errorf("failed to scan existing jobs: %w", err)
+	}
+	for _, source := range processed {
+		watcher.MarkProcessed(source)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Watching %s (interval %s, stable for %s). Press Ctrl+C to stop.\n", watcher.Dir(), watchInterval, watchStableFor)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		filesets, err := watcher.Poll(time.Now())
+		if err != nil {
+			logger.Warn("Failed to scan drop directory", "dir", watcher.Dir(), "error", err)
+		}
+
+		for _, fileset := range filesets {
+			if ctx.Err() != nil {
+				break
+			}
+			fmt.Printf("\n=== New fileset: %s ===\n", fileset)
+			jobID, err := startPipeline(ctx, []string{fileset}, nil, nil, config, logger, noProgress)
+			if err != nil {
+				logger.Error("Pipeline job failed", "fileset", fileset, "job_id", jobID, "error", err)
+				fmt.Printf("✗ Job for %s failed: %v\n", fileset, err)
+				continue
+			}
+			fmt.Printf("✓ Job %s completed for %s\n", jobID, fileset)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("\nStopping watcher")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -30,7 +30,8 @@ export default defineConfig({
           items: [
             { text: 'TORCH Integration', link: '/guides/torch-integration' },
             { text: 'DIMP Pseudonymization', link: '/guides/dimp-pseudonymization' },
-            { text: 'Pipeline Steps', link: '/guides/pipeline-steps' }
+            { text: 'Pipeline Steps', link: '/guides/pipeline-steps' },
+            { text: 'Workflow Engine Integration', link: '/guides/workflow-integration' }
           ]
         },
         {
@@ -0,0 +1,90 @@
+package i18n
+
+// german translates the English catalog; step names, keys and commands stay untranslated
+var german = map[Key]string{
+	MsgError:             "Fehler: %v",
+	MsgLoadConfigFailed:  "Konfiguration konnte nicht geladen werden: %w",
+	MsgLoadJobFailed:     "Job konnte nicht geladen werden: %w",
+	MsgSaveJobFailed:     "Job-Status konnte nicht gespeichert werden: %w",
+	MsgUpdateJobFailed:   "Job konnte nicht aktualisiert werden: %w",
+	MsgAdvanceStepFailed: "Wechsel zum nächsten Schritt fehlgeschlagen: %w",
+	MsgConfigFileUsed:    "Verwende Konfigurationsdatei: %s (aus %s)",
+	MsgNoConfigFile:      "Keine Konfigurationsdatei gefunden (gesucht: %s), verwende Standardwerte",
+	MsgJobsDirUsed:       "Verwende Job-Verzeichnis: %s",
+	MsgChaosEnabled:      "⚠ Fehlerinjektion ist aktiv (http_client.chaos oder %s): Dienstanfragen schlagen absichtlich fehl",
+
+	MsgDetectInputFailed:          "Eingabetyp konnte nicht erkannt werden: %w",
+	MsgCheckingServices:           "Prüfe Erreichbarkeit der Dienste...",
+	MsgServiceCheckFailed:         "Dienste nicht erreichbar: %w\n\nBitte stellen Sie sicher, dass alle benötigten Dienste laufen und erreichbar sind",
+	MsgServicesReachable:          "✓ Alle benötigten Dienste sind erreichbar",
+	MsgCreateJobFailed:            "Job konnte nicht angelegt werden: %w",
+	MsgJobCreated:                 "✓ Pipeline-Job angelegt: %s",
+	MsgJobInput:                   "  Eingabe: %s",
+	MsgJobInputType:               "  Typ: %s",
+	MsgJobExtraInput:              "  Weitere Eingabe: %s (%s)",
+	MsgJobParent:                  "  Ausgangs-Job: %s",
+	MsgJobTemplate:                "  Vorlage: %s",
+	MsgStartLocked:                "Pipeline kann nicht gestartet werden: %w\n\nMöglicherweise bearbeitet ein anderer Prozess diesen Job",
+	MsgCohortSizePrompt:           "\n%s. Trotzdem extrahieren? [j/N] ",
+	MsgStartingStep:               "Starte Schritt %s...",
+	MsgStepFailed:                 "Schritt %s fehlgeschlagen: %w",
+	MsgImportCompleted:            "✓ %s erfolgreich abgeschlossen",
+	MsgImportFiles:                "  Dateien: %d",
+	MsgImportSize:                 "  Größe: %s",
+	MsgAllStepsCompleted:          "Alle Schritte abgeschlossen, Job wird als abgeschlossen markiert...",
+	MsgPipelineCompleted:          "✓ Pipeline erfolgreich abgeschlossen",
+	MsgJobIDLine:                  "Job-ID: %s",
+	MsgAdvancingToStep:            "Weiter mit Schritt: %s",
+	MsgImportStepCompleted:        "✓ Schritt %s abgeschlossen (%d Dateien)",
+	MsgStartingDIMP:               "Starte DIMP-Pseudonymisierung...",
+	MsgDIMPCompleted:              "✓ DIMP-Pseudonymisierung abgeschlossen",
+	MsgStartingImaging:            "Starte Bildgebungs-Schritt...",
+	MsgImagingCompleted:           "✓ Bildgebungs-Schritt abgeschlossen",
+	MsgStartingCSVConversion:      "Starte CSV-Konvertierung...",
+	MsgCSVConversionCompleted:     "✓ CSV-Konvertierung abgeschlossen",
+	MsgStartingParquetConversion:  "Starte Parquet-Konvertierung...",
+	MsgParquetConversionCompleted: "✓ Parquet-Konvertierung abgeschlossen",
+	MsgStepNotImplemented:         "Schritt %s ist noch nicht implementiert - der Job bleibt bei diesem Schritt",
+	MsgStepValidationFailed:       "Schrittprüfung fehlgeschlagen: %w",
+	MsgImportStepMismatch:         "Eingabetyp %s erfordert Schritt '%s', erhalten wurde '%s'",
+	MsgMaxRuntimeAborted:          "Fehler: Job %s hat die maximale Laufzeit von %s überschritten und wurde abgebrochen",
+	MsgResumeHint:                 "Mit 'aether pipeline continue %s' fortsetzen",
+	MsgRunCancelled:               "Job %s wurde abgebrochen; abgeschlossene Schritte bleiben erhalten",
+
+	MsgCurrentStepNotInJob:  "aktueller Schritt %s nicht im Job gefunden",
+	MsgContinueLocked:       "Pipeline kann nicht fortgesetzt werden: %w\n\nMöglicherweise bearbeitet ein anderer Prozess diesen Job. Warten Sie, bis er fertig ist, oder prüfen Sie den Job-Status",
+	MsgRecoverJobFailed:     "Aufräumen nach dem unterbrochenen Lauf fehlgeschlagen: %w",
+	MsgRecoveredInterrupted: "⚠ Der vorige Lauf endete unerwartet in Schritt %s; der Schritt ist als fehlgeschlagen markiert und wird erneut ausgeführt",
+	MsgRemovedPartialFiles:  "%d unvollständige Datei(en) des unterbrochenen Laufs entfernt",
+	MsgLoadingJob:           "Lade Job %s...",
+	MsgJobAlreadyCompleted:  "✓ Job ist bereits abgeschlossen",
+	MsgCurrentStatus:        "Aktueller Status: %s",
+	MsgCurrentStep:          "Aktueller Schritt: %s",
+	MsgJobCompleted:         "✓ Job erfolgreich abgeschlossen",
+	MsgStepCompletedAdvance: "Aktueller Schritt '%s' ist abgeschlossen, weiter mit Schritt: %s",
+	MsgResumingStep:         "Setze unvollständigen Schritt fort: %s (Status: %s)",
+	MsgResumingPipeline:     "Setze Pipeline fort...",
+	MsgExecutingStep:        "Führe Schritt aus: %s",
+	MsgStatusHint:           "Fortschritt prüfen mit 'aether pipeline status %s'",
+	MsgContinueHint:         "Oder mit 'aether pipeline continue %s' zum nächsten Schritt übergehen",
+
+	MsgLastHeartbeat:       "Letzter Heartbeat: vor %s",
+	MsgWaiting:             "Wartet: %s",
+	MsgSteps:               "Schritte:",
+	MsgStepFiles:           "%d Dateien",
+	MsgStepRetries:         "%d Wiederholungen",
+	MsgStepError:           "Fehler: %s",
+	MsgStepLogLines:        "(%d Logzeilen angehängt, siehe --json)",
+	MsgStepRequestID:       "Request-ID: %s",
+	MsgStepWarning:         "Warnung: %s",
+	MsgStepWarningsDropped: "(%d weitere Warnungen nicht gespeichert, siehe Job-Ereignisse)",
+	MsgLoadEventsFailed:    "Job-Ereignisse konnten nicht geladen werden: %w",
+	MsgEvents:              "Ereignisse:",
+	MsgNoEvents:            "  (keine Ereignisse aufgezeichnet)",
+
+	MsgDirectoryNotExist:     "Verzeichnis existiert nicht: %s",
+	MsgExpectedDirectory:     "Verzeichnis erwartet, aber Datei erhalten: %s%s",
+	MsgHintCRTDLFile:         "\n\nDies scheint eine JSON/CRTDL-Datei zu sein. Mögliche Ursachen:\n  - Die Datei hat keine gültige CRTDL-Struktur (cohortDefinition oder dataExtraction fehlt)\n  - Die Datei verwendet das FHIR-Parameters-Format statt des flachen CRTDL-Formats\n\nMit ausführlichem Logging (--verbose) werden die Prüffehler im Detail angezeigt.",
+	MsgHintNDJSONFile:        "\n\nDies ist eine NDJSON-Datei. Bitte das Verzeichnis angeben, das sie enthält, nicht die Datei selbst.",
+	MsgCRTDLParametersFormat: "CRTDL-Datei '%s' verwendet das FHIR-Parameters-Format\n\nDieses Format wird nicht unterstützt. Bitte in die flache CRTDL-Struktur umwandeln:\n{\n  \"cohortDefinition\": { \"inclusionCriteria\": [...] },\n  \"dataExtraction\": { \"attributeGroups\": [...] }\n}\n\nBeispiel: .github/test/torch/queries/example-crtdl.json",
+}
@@ -0,0 +1,178 @@
+package i18n
+
+// Message keys of the CLI output and of errors shown to operators
+const (
+	// General
+	MsgError             Key = "error"
+	MsgLoadConfigFailed  Key = "load_config_failed"
+	MsgLoadJobFailed     Key = "load_job_failed"
+	MsgSaveJobFailed     Key = "save_job_failed"
+	MsgUpdateJobFailed   Key = "update_job_failed"
+	MsgAdvanceStepFailed Key = "advance_step_failed"
+	MsgConfigFileUsed    Key = "config_file_used"
+	MsgNoConfigFile      Key = "no_config_file"
+	MsgJobsDirUsed       Key = "jobs_dir_used"
+	MsgChaosEnabled      Key = "chaos_enabled"
+
+	// Pipeline start
+	MsgDetectInputFailed          Key = "detect_input_failed"
+	MsgCheckingServices           Key = "checking_services"
+	MsgServiceCheckFailed         Key = "service_check_failed"
+	MsgServicesReachable          Key = "services_reachable"
+	MsgCreateJobFailed            Key = "create_job_failed"
+	MsgJobCreated                 Key = "job_created"
+	MsgJobInput                   Key = "job_input"
+	MsgJobInputType               Key = "job_input_type"
+	MsgJobExtraInput              Key = "job_extra_input"
+	MsgJobParent                  Key = "job_parent"
+	MsgJobTemplate                Key = "job_template"
+	MsgStartLocked                Key = "start_locked"
+	MsgCohortSizePrompt           Key = "cohort_size_prompt"
+	MsgStartingStep               Key = "starting_step"
+	MsgStepFailed                 Key = "step_failed"
+	MsgImportCompleted            Key = "import_completed"
+	MsgImportFiles                Key = "import_files"
+	MsgImportSize                 Key = "import_size"
+	MsgAllStepsCompleted          Key = "all_steps_completed"
+	MsgPipelineCompleted          Key = "pipeline_completed"
+	MsgJobIDLine                  Key = "job_id_line"
+	MsgAdvancingToStep            Key = "advancing_to_step"
+	MsgImportStepCompleted        Key = "import_step_completed"
+	MsgStartingDIMP               Key = "starting_dimp"
+	MsgDIMPCompleted              Key = "dimp_completed"
+	MsgStartingImaging            Key = "starting_imaging"
+	MsgImagingCompleted           Key = "imaging_completed"
+	MsgStartingCSVConversion      Key = "starting_csv_conversion"
+	MsgCSVConversionCompleted     Key = "csv_conversion_completed"
+	MsgStartingParquetConversion  Key = "starting_parquet_conversion"
+	MsgParquetConversionCompleted Key = "parquet_conversion_completed"
+	MsgStepNotImplemented         Key = "step_not_implemented"
+	MsgStepValidationFailed       Key = "step_validation_failed"
+	MsgImportStepMismatch         Key = "import_step_mismatch"
+	MsgMaxRuntimeAborted          Key = "max_runtime_aborted"
+	MsgResumeHint                 Key = "resume_hint"
+	MsgRunCancelled               Key = "run_cancelled"
+	MsgCurrentStepNotInJob        Key = "current_step_not_in_job"
+	MsgContinueLocked             Key = "continue_locked"
+	MsgRecoverJobFailed           Key = "recover_job_failed"
+	MsgRecoveredInterrupted       Key = "recovered_interrupted"
+	MsgRemovedPartialFiles        Key = "removed_partial_files"
+	MsgLoadingJob                 Key = "loading_job"
+	MsgJobAlreadyCompleted        Key = "job_already_completed"
+	MsgCurrentStatus              Key = "current_status"
+	MsgCurrentStep                Key = "current_step"
+	MsgJobCompleted               Key = "job_completed"
+	MsgStepCompletedAdvance       Key = "step_completed_advance"
+	MsgResumingStep               Key = "resuming_step"
+	MsgResumingPipeline           Key = "resuming_pipeline"
+	MsgExecutingStep              Key = "executing_step"
+	MsgStatusHint                 Key = "status_hint"
+	MsgContinueHint               Key = "continue_hint"
+	MsgLastHeartbeat              Key = "last_heartbeat"
+	MsgWaiting                    Key = "waiting"
+	MsgSteps                      Key = "steps"
+	MsgStepFiles                  Key = "step_files"
+	MsgStepRetries                Key = "step_retries"
+	MsgStepError                  Key = "step_error"
+	MsgStepLogLines               Key = "step_log_lines"
+	MsgStepRequestID              Key = "step_request_id"
+	MsgStepWarning                Key = "step_warning"
+	MsgStepWarningsDropped        Key = "step_warnings_dropped"
+	MsgLoadEventsFailed           Key = "load_events_failed"
+	MsgEvents                     Key = "events"
+	MsgNoEvents                   Key = "no_events"
+	MsgDirectoryNotExist          Key = "directory_not_exist"
+	MsgExpectedDirectory          Key = "expected_directory"
+	MsgHintCRTDLFile              Key = "hint_crtdl_file"
+	MsgHintNDJSONFile             Key = "hint_ndjson_file"
+	MsgCRTDLParametersFormat      Key = "crtdl_parameters_format"
+)
+
+// english is the reference catalog every other locale is checked against
+var english = map[Key]string{
+	MsgError:             "Error: %v",
+	MsgLoadConfigFailed:  "failed to load configuration: %w",
+	MsgLoadJobFailed:     "failed to load job: %w",
+	MsgSaveJobFailed:     "failed to save job state: %w",
+	MsgUpdateJobFailed:   "failed to update job: %w",
+	MsgAdvanceStepFailed: "failed to advance to next step: %w",
+	MsgConfigFileUsed:    "Using config file: %s (from %s)",
+	MsgNoConfigFile:      "No config file found (searched %s), using defaults",
+	MsgJobsDirUsed:       "Using jobs directory: %s",
+	MsgChaosEnabled:      "⚠ Fault injection is enabled (http_client.chaos or %s): service requests fail on purpose",
+
+	MsgDetectInputFailed:          "failed to detect input type: %w",
+	MsgCheckingServices:           "Validating service connectivity...",
+	MsgServiceCheckFailed:         "service connectivity check failed: %w\n\nPlease ensure all required services are running and accessible",
+	MsgServicesReachable:          "✓ All required services are reachable",
+	MsgCreateJobFailed:            "failed to create job: %w",
+	MsgJobCreated:                 "✓ Created pipeline job: %s",
+	MsgJobInput:                   "  Input: %s",
+	MsgJobInputType:               "  Type: %s",
+	MsgJobExtraInput:              "  Additional input: %s (%s)",
+	MsgJobParent:                  "  Parent job: %s",
+	MsgJobTemplate:                "  Template: %s",
+	MsgStartLocked:                "cannot start pipeline: %w\n\nAnother process may be working on this job",
+	MsgCohortSizePrompt:           "\n%s. Extract anyway? [y/N] ",
+	MsgStartingStep:               "Starting %s step...",
+	MsgStepFailed:                 "%s step failed: %w",
+	MsgImportCompleted:            "✓ %s completed successfully",
+	MsgImportFiles:                "  Files: %d",
+	MsgImportSize:                 "  Size: %s",
+	MsgAllStepsCompleted:          "All steps completed, marking job as complete...",
+	MsgPipelineCompleted:          "✓ Pipeline completed successfully",
+	MsgJobIDLine:                  "Job ID: %s",
+	MsgAdvancingToStep:            "Advancing to step: %s",
+	MsgImportStepCompleted:        "✓ %s step completed (%d files)",
+	MsgStartingDIMP:               "Starting DIMP pseudonymization step...",
+	MsgDIMPCompleted:              "✓ DIMP pseudonymization completed",
+	MsgStartingImaging:            "Starting imaging step...",
+	MsgImagingCompleted:           "✓ Imaging step completed",
+	MsgStartingCSVConversion:      "Starting CSV conversion step...",
+	MsgCSVConversionCompleted:     "✓ CSV conversion step completed",
+	MsgStartingParquetConversion:  "Starting Parquet conversion step...",
+	MsgParquetConversionCompleted: "✓ Parquet conversion step completed",
+	MsgStepNotImplemented:         "%s step not yet implemented - job will remain at this step",
+	MsgStepValidationFailed:       "step validation failed: %w",
+	MsgImportStepMismatch:         "input type %s requires step '%s', but got '%s'",
+	MsgMaxRuntimeAborted:          "Error: job %s exceeded the maximum runtime of %s and was aborted",
+	MsgResumeHint:                 "Run 'aether pipeline continue %s' to resume it",
+	MsgRunCancelled:               "Job %s was cancelled; completed steps are kept",
+
+	MsgCurrentStepNotInJob:  "current step %s not found in job",
+	MsgContinueLocked:       "cannot continue pipeline: %w\n\nAnother process may be working on this job. Wait for it to complete or check job status",
+	MsgRecoverJobFailed:     "failed to clean up after the interrupted run: %w",
+	MsgRecoveredInterrupted: "⚠ The previous run ended unexpectedly during step %s; the step is marked as failed and will be re-run",
+	MsgRemovedPartialFiles:  "Removed %d partial file(s) of the interrupted run",
+	MsgLoadingJob:           "Loading job %s...",
+	MsgJobAlreadyCompleted:  "✓ Job already completed",
+	MsgCurrentStatus:        "Current status: %s",
+	MsgCurrentStep:          "Current step: %s",
+	MsgJobCompleted:         "✓ Job completed successfully",
+	MsgStepCompletedAdvance: "Current step '%s' is completed, advancing to next step: %s",
+	MsgResumingStep:         "Resuming incomplete step: %s (status: %s)",
+	MsgResumingPipeline:     "Resuming pipeline execution...",
+	MsgExecutingStep:        "Executing step: %s",
+	MsgStatusHint:           "Use 'aether pipeline status %s' to check progress",
+	MsgContinueHint:         "Or run 'aether pipeline continue %s' to proceed to the next step",
+
+	MsgLastHeartbeat:       "Last heartbeat: %s ago",
+	MsgWaiting:             "Waiting: %s",
+	MsgSteps:               "Steps:",
+	MsgStepFiles:           "%d files",
+	MsgStepRetries:         "%d retries",
+	MsgStepError:           "Error: %s",
+	MsgStepLogLines:        "(%d log lines attached, see --json)",
+	MsgStepRequestID:       "Request ID: %s",
+	MsgStepWarning:         "Warning: %s",
+	MsgStepWarningsDropped: "(%d more warnings not kept, see the job events)",
+	MsgLoadEventsFailed:    "failed to load job events: %w",
+	MsgEvents:              "Events:",
+	MsgNoEvents:            "  (no events recorded)",
+
+	MsgDirectoryNotExist:     "directory does not exist: %s",
+	MsgExpectedDirectory:     "expected directory but got file: %s%s",
+	MsgHintCRTDLFile:         "\n\nThis appears to be a JSON/CRTDL file. Possible issues:\n  - File may not have valid CRTDL structure (missing cohortDefinition or dataExtraction)\n  - File may be using FHIR Parameters format instead of flat CRTDL format\n\nRun with verbose logging to see detailed validation errors.",
+	MsgHintNDJSONFile:        "\n\nThis is an NDJSON file. Please provide the directory containing it, not the file itself.",
+	MsgCRTDLParametersFormat: "CRTDL file '%s' uses FHIR Parameters format\n\nThis format is not supported. Please convert to flat CRTDL structure:\n{\n  \"cohortDefinition\": { \"inclusionCriteria\": [...] },\n  \"dataExtraction\": { \"attributeGroups\": [...] }\n}\n\nSee .github/test/torch/queries/example-crtdl.json for reference",
+}
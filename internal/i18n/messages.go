@@ -0,0 +1,141 @@
+// Package i18n holds the catalog of user-facing CLI messages and their translations
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Locale identifies a message catalog by its ISO 639-1 language code
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleGerman  Locale = "de"
+)
+
+// DefaultLocale is used when neither the configuration nor the environment selects a supported locale
+const DefaultLocale = LocaleEnglish
+
+// Key identifies a message in the catalog
+type Key string
+
+// catalogs maps each supported locale to its messages; English is complete by definition
+var catalogs = map[Locale]map[Key]string{
+	LocaleEnglish: english,
+	LocaleGerman:  german,
+}
+
+var (
+	mu      sync.RWMutex
+	current = DefaultLocale
+)
+
+// SupportedLocales returns the locales that have a catalog, sorted
+func SupportedLocales() []Locale {
+	locales := make([]Locale, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i] < locales[j] })
+	return locales
+}
+
+// IsSupported returns true if a catalog exists for the locale
+func IsSupported(locale Locale) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// ParseLocale reduces a POSIX locale name such as "de_DE.UTF-8" to its language code
+func ParseLocale(value string) Locale {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexAny(value, "_.@-"); i >= 0 {
+		value = value[:i]
+	}
+	return Locale(strings.ToLower(value))
+}
+
+// Resolve picks the locale to use: the configured one if set, otherwise the first of
+// LC_ALL, LC_MESSAGES and LANG naming a supported language, otherwise DefaultLocale
+func Resolve(configured string) Locale {
+	if configured != "" {
+		if locale := ParseLocale(configured); IsSupported(locale) {
+			return locale
+		}
+		return DefaultLocale
+	}
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		// The first variable set wins, as in POSIX; C/POSIX mean untranslated
+		if locale := ParseLocale(value); IsSupported(locale) {
+			return locale
+		}
+		return DefaultLocale
+	}
+	return DefaultLocale
+}
+
+// SetLocale selects the catalog used by T and Errorf; unsupported locales fall back to DefaultLocale
+func SetLocale(locale Locale) {
+	if !IsSupported(locale) {
+		locale = DefaultLocale
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current = locale
+}
+
+// CurrentLocale returns the selected locale
+func CurrentLocale() Locale {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// format returns the message template for the current locale, falling back to English
+// and finally to the key itself so a missing entry never hides the message entirely
+func format(key Key) string {
+	if message, ok := catalogs[CurrentLocale()][key]; ok {
+		return message
+	}
+	if message, ok := english[key]; ok {
+		return message
+	}
+	return string(key)
+}
+
+// T returns the translated message, formatted with args like fmt.Sprintf
+func T(key Key, args ...any) string {
+	if len(args) == 0 {
+		return format(key)
+	}
+	return fmt.Sprintf(format(key), args...)
+}
+
+// Errorf returns an error with the translated message, formatted like fmt.Errorf (%w wraps)
+func Errorf(key Key, args ...any) error {
+	return fmt.Errorf(format(key), args...)
+}
+
+// Keys returns all keys of the English catalog, sorted
+func Keys() []Key {
+	keys := make([]Key, 0, len(english))
+	for key := range english {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// Lookup returns a locale's template for a key without falling back
+func Lookup(locale Locale, key Key) (string, bool) {
+	message, ok := catalogs[locale][key]
+	return message, ok
+}
@@ -0,0 +1,179 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// ChaosHeader marks responses produced or altered by fault injection with the injected fault
+const ChaosHeader = "X-Aether-Chaos"
+
+// ChaosFault is a fault injected into a request (see models.ChaosConfig)
+type ChaosFault string
+
+const (
+	ChaosNone     ChaosFault = ""
+	ChaosError    ChaosFault = "error"    // HTTP 503 without contacting the service
+	ChaosTimeout  ChaosFault = "timeout"  // Timeout error without contacting the service
+	ChaosTruncate ChaosFault = "truncate" // Response body breaks off halfway
+)
+
+// chaos is the process-wide fault injection of service requests (see ConfigureChaos)
+var chaos struct {
+	mu     sync.Mutex
+	config models.ChaosConfig
+	rng    *rand.Rand
+}
+
+// ConfigureChaos sets the faults injected into requests of clients using NewChaosTransport
+// A disabled configuration turns fault injection off
+func ConfigureChaos(config models.ChaosConfig) {
+	seed := config.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	chaos.mu.Lock()
+	defer chaos.mu.Unlock()
+	chaos.config = config
+	chaos.rng = rand.New(rand.NewPCG(seed, seed))
+}
+
+// ChaosEnabled reports whether faults are being injected
+func ChaosEnabled() bool {
+	chaos.mu.Lock()
+	defer chaos.mu.Unlock()
+	return chaos.config.Enabled
+}
+
+// drawChaosFault decides which fault a request to host gets and how long it is delayed
+func drawChaosFault(host string) (ChaosFault, time.Duration) {
+	chaos.mu.Lock()
+	defer chaos.mu.Unlock()
+
+	config := chaos.config
+	if !config.AppliesTo(host) {
+		return ChaosNone, 0
+	}
+
+	var delay time.Duration
+	if chaos.rng.Float64() < config.SlowRate {
+		delay = config.GetSlowDelay()
+	}
+
+	r := chaos.rng.Float64()
+	switch {
+	case r < config.ErrorRate:
+		return ChaosError, delay
+	case r < config.ErrorRate+config.TimeoutRate:
+		return ChaosTimeout, delay
+	case r < config.ErrorRate+config.TimeoutRate+config.TruncateRate:
+		return ChaosTruncate, delay
+	}
+	return ChaosNone, delay
+}
+
+// chaosTimeoutError is returned for injected timeouts; it is a net.Error like a real timeout
+type chaosTimeoutError struct{}
+
+func (chaosTimeoutError) Error() string   { return "injected fault: i/o timeout" }
+func (chaosTimeoutError) Timeout() bool   { return true }
+func (chaosTimeoutError) Temporary() bool { return true }
+
+// chaosTransport injects the configured faults before and after passing requests to its base
+type chaosTransport struct {
+	base http.RoundTripper
+}
+
+// NewChaosTransport wraps base with fault injection; a nil base uses http.DefaultTransport at
+// request time, so the client identity (see ConfigureClientIdentity) still applies
+// Requests pass through unchanged while fault injection is disabled
+func NewChaosTransport(base http.RoundTripper) http.RoundTripper {
+	return &chaosTransport{base: base}
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	fault, delay := drawChaosFault(req.URL.Host)
+	if fault == ChaosNone && delay == 0 {
+		return base.RoundTrip(req)
+	}
+	DefaultLogger.Debug("Injecting fault", "fault", fault, "delay", delay, "method", req.Method, "url", req.URL.String())
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeRequestBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+
+	switch fault {
+	case ChaosError:
+		closeRequestBody(req)
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+			StatusCode: http.StatusServiceUnavailable,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header: http.Header{
+				"Content-Type": {"text/plain"},
+				ChaosHeader:    {string(ChaosError)},
+			},
+			Body:          io.NopCloser(strings.NewReader("injected fault: service unavailable\n")),
+			ContentLength: -1,
+			Request:       req,
+		}, nil
+	case ChaosTimeout:
+		closeRequestBody(req)
+		return nil, chaosTimeoutError{}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || fault != ChaosTruncate {
+		return resp, err
+	}
+
+	// Break the body off halfway, the way a dropped connection does
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body[:len(body)/2]), errorReader{io.ErrUnexpectedEOF}))
+	resp.Header = resp.Header.Clone()
+	resp.Header.Set(ChaosHeader, string(ChaosTruncate))
+	return resp, nil
+}
+
+// closeRequestBody closes the body of a request that is not sent, as RoundTrippers must
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+// errorReader fails every read with err
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
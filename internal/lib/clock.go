@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// timeZone is the zone of all written timestamps; nil means UTC
+// It is read by goroutines that may already run when the configuration is loaded
+// (e.g. the HTTP transport fetching a remote config), so it is never a plain variable
+var timeZone atomic.Pointer[time.Location]
+
+// ConfigureTimeZone sets the zone of all timestamps: job state, events, reports, trace
+// and heartbeat files and log lines
+// time.Local is left alone; timestamps are converted with Now and FormatTimestamp instead
+func ConfigureTimeZone(loc *time.Location) {
+	timeZone.Store(loc)
+}
+
+// TimeZone returns the configured time zone (default: UTC)
+func TimeZone() *time.Location {
+	if loc := timeZone.Load(); loc != nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Now returns the current time in the configured time zone
+// Use it for timestamps that are stored or shown; time.Now is fine for measuring durations
+func Now() time.Time {
+	return time.Now().In(TimeZone())
+}
+
+// FormatTimestamp formats t as RFC3339 in the configured time zone
+func FormatTimestamp(t time.Time) string {
+	return t.In(TimeZone()).Format(time.RFC3339)
+}
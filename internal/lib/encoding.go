@@ -0,0 +1,296 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// TextEncoding identifies the encoding detected at the start of a text file
+type TextEncoding string
+
+const (
+	EncodingUTF8    TextEncoding = "utf-8"
+	EncodingUTF8BOM TextEncoding = "utf-8-bom"
+	EncodingUTF16LE TextEncoding = "utf-16le"
+	EncodingUTF16BE TextEncoding = "utf-16be"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// NormalizationResult describes what NormalizeNDJSONEncoding changed
+type NormalizationResult struct {
+	Encoding     TextEncoding // Encoding detected before normalization
+	Changed      bool         // File was rewritten
+	HadBOM       bool         // A byte order mark was stripped
+	FixedNewline bool         // CRLF or CR line endings were converted to LF
+	NewSize      int64        // File size after normalization
+}
+
+// DetectTextEncoding inspects the first bytes of a file
+// UTF-16 without BOM is recognized by NUL bytes next to ASCII characters, which is
+// unambiguous for NDJSON since every line starts with '{'
+func DetectTextEncoding(prefix []byte) (TextEncoding, bool) {
+	switch {
+	case bytes.HasPrefix(prefix, bomUTF8):
+		return EncodingUTF8BOM, true
+	case bytes.HasPrefix(prefix, bomUTF16LE):
+		return EncodingUTF16LE, true
+	case bytes.HasPrefix(prefix, bomUTF16BE):
+		return EncodingUTF16BE, true
+	case len(prefix) >= 2 && prefix[0] != 0 && prefix[1] == 0:
+		return EncodingUTF16LE, false
+	case len(prefix) >= 2 && prefix[0] == 0 && prefix[1] != 0:
+		return EncodingUTF16BE, false
+	default:
+		return EncodingUTF8, false
+	}
+}
+
+// NormalizeNDJSONEncoding rewrites an NDJSON file in place as BOM-less UTF-8 with LF line endings
+// UTF-8 BOMs are stripped, UTF-16 (with or without BOM) is converted, and CRLF/CR line
+// endings become LF. Clean UTF-8 files are only read, never rewritten.
+// The rewrite goes through a .part file and an atomic rename.
+func NormalizeNDJSONEncoding(path string) (NormalizationResult, error) {
+	result := NormalizationResult{Encoding: EncodingUTF8}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return result, fmt.Errorf("failed to stat file: %w", err)
+	}
+	result.NewSize = info.Size()
+
+	prefix, err := readPrefix(path, 4)
+	if err != nil {
+		return result, err
+	}
+
+	encoding, hasBOM := DetectTextEncoding(prefix)
+	result.Encoding = encoding
+	result.HadBOM = hasBOM
+
+	if encoding == EncodingUTF8 {
+		hasCR, err := fileContainsByte(path, '\r')
+		if err != nil {
+			return result, err
+		}
+		if !hasCR {
+			return result, nil // Already clean
+		}
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return result, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	tempPath := path + ".part"
+	out, err := os.Create(tempPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	fail := func(err error) (NormalizationResult, error) {
+		_ = out.Close()
+		_ = os.Remove(tempPath)
+		return result, err
+	}
+
+	reader := bufio.NewReader(in)
+	if hasBOM {
+		bomLen := len(bomUTF8)
+		if encoding != EncodingUTF8BOM {
+			bomLen = 2
+		}
+		if _, err := reader.Discard(bomLen); err != nil {
+			return fail(fmt.Errorf("failed to skip byte order mark: %w", err))
+		}
+	}
+
+	writer := bufio.NewWriter(out)
+	newlines := &newlineNormalizer{w: writer}
+
+	switch encoding {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		err = transcodeUTF16(reader, newlines, encoding == EncodingUTF16BE)
+	default:
+		_, err = io.Copy(newlines, reader)
+	}
+	if err != nil {
+		return fail(fmt.Errorf("failed to normalize %s: %w", encoding, err))
+	}
+
+	if err := newlines.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fail(fmt.Errorf("failed to write normalized file: %w", err))
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tempPath)
+		return result, fmt.Errorf("failed to close normalized file: %w", err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return result, fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	result.Changed = true
+	result.FixedNewline = newlines.fixed
+	result.NewSize = GetFileSize(path)
+	return result, nil
+}
+
+// readPrefix reads up to n bytes from the start of a file
+func readPrefix(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	buf := make([]byte, n)
+	read, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return buf[:read], nil
+}
+
+// fileContainsByte reports whether b occurs anywhere in the file
+func fileContainsByte(path string, b byte) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	buf := make([]byte, 256*1024)
+	for {
+		n, err := f.Read(buf)
+		if bytes.IndexByte(buf[:n], b) >= 0 {
+			return true, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+}
+
+// transcodeUTF16 streams UTF-16 code units from r and writes UTF-8 to w
+func transcodeUTF16(r *bufio.Reader, w io.Writer, bigEndian bool) error {
+	unit := make([]byte, 2)
+	var pendingHigh rune = -1
+	out := make([]byte, 0, utf8.UTFMax)
+
+	for {
+		if _, err := io.ReadFull(r, unit); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return errors.New("truncated UTF-16 input (odd number of bytes)")
+			}
+			return err
+		}
+
+		var codeUnit rune
+		if bigEndian {
+			codeUnit = rune(unit[0])<<8 | rune(unit[1])
+		} else {
+			codeUnit = rune(unit[1])<<8 | rune(unit[0])
+		}
+
+		var decoded rune
+		switch {
+		case pendingHigh >= 0 && codeUnit >= 0xDC00 && codeUnit <= 0xDFFF:
+			decoded = utf16.DecodeRune(pendingHigh, codeUnit)
+			pendingHigh = -1
+		case pendingHigh >= 0:
+			// Unpaired high surrogate - emit replacement and reprocess this unit
+			out = utf8.AppendRune(out[:0], utf8.RuneError)
+			if _, err := w.Write(out); err != nil {
+				return err
+			}
+			pendingHigh = -1
+			if utf16.IsSurrogate(codeUnit) {
+				pendingHigh = codeUnit
+				continue
+			}
+			decoded = codeUnit
+		case utf16.IsSurrogate(codeUnit):
+			pendingHigh = codeUnit
+			continue
+		default:
+			decoded = codeUnit
+		}
+
+		out = utf8.AppendRune(out[:0], decoded)
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+	}
+
+	if pendingHigh >= 0 {
+		out = utf8.AppendRune(out[:0], utf8.RuneError)
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newlineNormalizer converts CRLF and lone CR line endings to LF
+type newlineNormalizer struct {
+	w         io.Writer
+	pendingCR bool
+	fixed     bool
+}
+
+func (n *newlineNormalizer) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p)+1)
+	for _, b := range p {
+		if n.pendingCR {
+			n.pendingCR = false
+			out = append(out, '\n')
+			if b == '\n' {
+				continue // CRLF -> LF
+			}
+		}
+		if b == '\r' {
+			n.pendingCR = true
+			n.fixed = true
+			continue
+		}
+		out = append(out, b)
+	}
+
+	if _, err := n.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes a trailing CR (file ending in a lone CR) as LF
+func (n *newlineNormalizer) Flush() error {
+	if !n.pendingCR {
+		return nil
+	}
+	n.pendingCR = false
+	_, err := n.w.Write([]byte{'\n'})
+	return err
+}
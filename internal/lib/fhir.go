@@ -2,10 +2,13 @@ package lib
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 )
 
 // FHIRResource represents a generic FHIR resource as a map
@@ -159,12 +162,129 @@ func ValidateFHIRResource(resource FHIRResource) error {
 	return nil
 }
 
-// CountResourcesInFile counts the number of resources in an NDJSON file
+// CountResourcesInFile counts the resources (non-empty lines) in an NDJSON file
+// Lines are not parsed, so multi-GB files are counted at disk speed
 func CountResourcesInFile(filePath string) (int, error) {
-	count := 0
-	_, err := ReadNDJSONFile(filePath, func(r FHIRResource) error {
-		count++
-		return nil
-	})
-	return count, err
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	return CountResources(file)
+}
+
+// CountResources counts the resources (non-empty lines) in NDJSON read from reader
+func CountResources(reader io.Reader) (int, error) {
+	var counter LineCounter
+	buf := make([]byte, lineCountBufferSize)
+	if _, err := io.CopyBuffer(&counter, reader, buf); err != nil {
+		return 0, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+	return counter.Count(), nil
+}
+
+// lineCountBufferSize is the read size of CountResources; large reads keep syscalls rare
+const lineCountBufferSize = 1024 * 1024
+
+// LineCounter is an io.Writer that counts the non-empty lines written to it
+// Use it with io.MultiWriter to count resources while a file is copied or downloaded,
+// instead of reading the file again afterwards. A final line without newline is counted
+type LineCounter struct {
+	lines          int
+	lineHasContent bool
+}
+
+// Write counts the lines completed by p; it never fails
+func (c *LineCounter) Write(p []byte) (int, error) {
+	rest := p
+	for len(rest) > 0 {
+		end := bytes.IndexByte(rest, '\n')
+		segment := rest
+		if end >= 0 {
+			segment = rest[:end]
+		}
+		if !c.lineHasContent && len(bytes.TrimSpace(segment)) > 0 {
+			c.lineHasContent = true
+		}
+		if end < 0 {
+			break
+		}
+		if c.lineHasContent {
+			c.lines++
+		}
+		c.lineHasContent = false
+		rest = rest[end+1:]
+	}
+	return len(p), nil
+}
+
+// Count returns the number of non-empty lines written so far, including an unterminated last line
+func (c *LineCounter) Count() int {
+	if c.lineHasContent {
+		return c.lines + 1
+	}
+	return c.lines
+}
+
+// SampleResourceTypes returns the distinct resourceType values of the first maxResources
+// resources of an NDJSON file, sorted. Resources are decoded as a stream, so large lines
+// (e.g. Bundles) are not limited by a line buffer. Sampling stops at the first value that is
+// not a JSON object; an error is only returned if nothing could be sampled
+func SampleResourceTypes(filePath string, maxResources int) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	seen := make(map[string]bool)
+	decoder := json.NewDecoder(bufio.NewReaderSize(file, 64*1024))
+	for sampled := 0; sampled < maxResources; sampled++ {
+		var header struct {
+			ResourceType string `json:"resourceType"`
+		}
+		if err := decoder.Decode(&header); err != nil {
+			if errors.Is(err, io.EOF) || len(seen) > 0 {
+				break
+			}
+			return nil, fmt.Errorf("failed to sample resource types: %w", err)
+		}
+		if header.ResourceType != "" {
+			seen[header.ResourceType] = true
+		}
+	}
+
+	types := make([]string, 0, len(seen))
+	for resourceType := range seen {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// ResourceFilter selects resources by type, id and an optional FHIRPath expression
+// Empty fields match everything
+type ResourceFilter struct {
+	ResourceType string
+	ID           string
+	Where        *FHIRPath
+}
+
+// Matches returns true if the resource satisfies all configured criteria
+func (f ResourceFilter) Matches(resource map[string]any) bool {
+	if f.ResourceType != "" {
+		if resourceType, _ := resource["resourceType"].(string); resourceType != f.ResourceType {
+			return false
+		}
+	}
+	if f.ID != "" {
+		if id, _ := resource["id"].(string); id != f.ID {
+			return false
+		}
+	}
+	if f.Where != nil && !f.Where.IsTrue(resource) {
+		return false
+	}
+	return true
 }
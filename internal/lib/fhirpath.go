@@ -0,0 +1,536 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FHIRPath is a compiled expression in a small, practical subset of FHIRPath
+//
+// Supported:
+//   - Path navigation with implicit array flattening: Patient.name.family
+//   - An optional leading resource type: Patient.birthDate and birthDate are equivalent
+//   - Literals: 'string', 42, 3.14, true, false, @2010-01-01 (dates compare as ISO strings)
+//   - Comparison: = != < > <= >= (true if any left/right pair matches)
+//   - Boolean logic: and, or, parentheses
+//   - Functions: exists(), exists(criteria), empty(), count(), first(), not(), where(criteria)
+//
+// Enough for spot checks and cohort sanity checks without pulling in a full FHIRPath engine
+type FHIRPath struct {
+	source string
+	root   fhirPathNode
+}
+
+// CompileFHIRPath parses an expression
+func CompileFHIRPath(expression string) (*FHIRPath, error) {
+	tokens, err := tokenizeFHIRPath(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FHIRPath %q: %w", expression, err)
+	}
+
+	p := &fhirPathParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid FHIRPath %q: %w", expression, err)
+	}
+	if !p.atEnd() {
+		return nil, fmt.Errorf("invalid FHIRPath %q: unexpected %q", expression, p.peek().text)
+	}
+
+	return &FHIRPath{source: expression, root: root}, nil
+}
+
+// String returns the original expression
+func (f *FHIRPath) String() string {
+	return f.source
+}
+
+// Evaluate returns the collection produced by the expression for a resource
+func (f *FHIRPath) Evaluate(resource map[string]any) []any {
+	return f.root.eval([]any{resource})
+}
+
+// IsTrue evaluates the expression in a boolean context
+// Empty results are false, a single boolean is itself, any other non-empty result is true
+func (f *FHIRPath) IsTrue(resource map[string]any) bool {
+	return fhirPathTruthy(f.Evaluate(resource))
+}
+
+func fhirPathTruthy(collection []any) bool {
+	if len(collection) == 0 {
+		return false
+	}
+	if len(collection) == 1 {
+		if b, ok := collection[0].(bool); ok {
+			return b
+		}
+	}
+	return true
+}
+
+// AST
+
+type fhirPathNode interface {
+	eval(input []any) []any
+}
+
+type fhirPathLiteral struct {
+	value any
+}
+
+func (n fhirPathLiteral) eval(input []any) []any {
+	return []any{n.value}
+}
+
+// fhirPathMember navigates to a child element; a nil target means the current input
+type fhirPathMember struct {
+	target fhirPathNode
+	name   string
+}
+
+func (n fhirPathMember) eval(input []any) []any {
+	items := input
+	if n.target != nil {
+		items = n.target.eval(input)
+	}
+
+	var result []any
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		// Leading resource type (Patient.birthDate) selects the resource itself
+		if n.target == nil && obj["resourceType"] == n.name {
+			result = append(result, obj)
+			continue
+		}
+
+		switch value := obj[n.name].(type) {
+		case nil:
+		case []any:
+			result = append(result, value...)
+		default:
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+type fhirPathFunction struct {
+	target fhirPathNode
+	name   string
+	args   []fhirPathNode
+}
+
+func (n fhirPathFunction) eval(input []any) []any {
+	items := input
+	if n.target != nil {
+		items = n.target.eval(input)
+	}
+
+	switch n.name {
+	case "where":
+		return filterFHIRPath(items, n.args[0])
+	case "exists":
+		if len(n.args) == 1 {
+			items = filterFHIRPath(items, n.args[0])
+		}
+		return []any{len(items) > 0}
+	case "empty":
+		return []any{len(items) == 0}
+	case "count":
+		return []any{float64(len(items))}
+	case "first":
+		if len(items) == 0 {
+			return nil
+		}
+		return items[:1]
+	case "not":
+		if len(items) == 0 {
+			return nil
+		}
+		return []any{!fhirPathTruthy(items)}
+	}
+	return nil
+}
+
+func filterFHIRPath(items []any, criteria fhirPathNode) []any {
+	var result []any
+	for _, item := range items {
+		if fhirPathTruthy(criteria.eval([]any{item})) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+type fhirPathBinary struct {
+	op          string
+	left, right fhirPathNode
+}
+
+func (n fhirPathBinary) eval(input []any) []any {
+	left := n.left.eval(input)
+
+	switch n.op {
+	case "and":
+		if !fhirPathTruthy(left) {
+			return []any{false}
+		}
+		return []any{fhirPathTruthy(n.right.eval(input))}
+	case "or":
+		if fhirPathTruthy(left) {
+			return []any{true}
+		}
+		return []any{fhirPathTruthy(n.right.eval(input))}
+	}
+
+	right := n.right.eval(input)
+	if len(left) == 0 || len(right) == 0 {
+		return nil
+	}
+
+	for _, l := range left {
+		for _, r := range right {
+			if compareFHIRPathValues(n.op, l, r) {
+				return []any{true}
+			}
+		}
+	}
+	return []any{false}
+}
+
+// compareFHIRPathValues compares two primitive values
+// Numbers compare numerically, strings (including ISO dates) lexically, booleans by equality
+func compareFHIRPathValues(op string, left, right any) bool {
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return op == "!="
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return op == "!="
+		}
+		cmp = strings.Compare(l, r)
+	case bool:
+		r, ok := right.(bool)
+		if !ok || (op != "=" && op != "!=") {
+			return op == "!="
+		}
+		if l != r {
+			cmp = 1
+		}
+	default:
+		return false
+	}
+
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// Tokenizer
+
+type fhirPathTokenKind int
+
+const (
+	tokIdent fhirPathTokenKind = iota
+	tokString
+	tokNumber
+	tokDate
+	tokOperator
+	tokPunct
+)
+
+type fhirPathToken struct {
+	kind fhirPathTokenKind
+	text string
+}
+
+func tokenizeFHIRPath(expr string) ([]fhirPathToken, error) {
+	var tokens []fhirPathToken
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'':
+			j := i + 1
+			var sb strings.Builder
+			for j < len(runes) && runes[j] != '\'' {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+				j++
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			tokens = append(tokens, fhirPathToken{tokString, sb.String()})
+			i = j + 1
+		case c == '@':
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || strings.ContainsRune("-:T.+Z", runes[j])) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("empty date literal")
+			}
+			tokens = append(tokens, fhirPathToken{tokDate, string(runes[i+1 : j])})
+			i = j
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				// A dot followed by a letter is member access, not a decimal point
+				if runes[j] == '.' && (j+1 >= len(runes) || !unicode.IsDigit(runes[j+1])) {
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, fhirPathToken{tokNumber, string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, fhirPathToken{tokIdent, string(runes[i:j])})
+			i = j
+		case c == '!' || c == '<' || c == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, fhirPathToken{tokOperator, string(runes[i : i+2])})
+				i += 2
+			} else if c == '!' {
+				return nil, fmt.Errorf("unexpected '!'")
+			} else {
+				tokens = append(tokens, fhirPathToken{tokOperator, string(c)})
+				i++
+			}
+		case c == '=':
+			tokens = append(tokens, fhirPathToken{tokOperator, "="})
+			i++
+		case strings.ContainsRune(".(),", c):
+			tokens = append(tokens, fhirPathToken{tokPunct, string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parser
+
+type fhirPathParser struct {
+	tokens []fhirPathToken
+	pos    int
+}
+
+func (p *fhirPathParser) atEnd() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *fhirPathParser) peek() fhirPathToken {
+	if p.atEnd() {
+		return fhirPathToken{tokPunct, "<end>"}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *fhirPathParser) accept(kind fhirPathTokenKind, text string) bool {
+	if !p.atEnd() && p.tokens[p.pos].kind == kind && p.tokens[p.pos].text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *fhirPathParser) expect(kind fhirPathTokenKind, text string) error {
+	if !p.accept(kind, text) {
+		return fmt.Errorf("expected %q, got %q", text, p.peek().text)
+	}
+	return nil
+}
+
+func (p *fhirPathParser) parseOr() (fhirPathNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokIdent, "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = fhirPathBinary{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *fhirPathParser) parseAnd() (fhirPathNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokIdent, "and") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = fhirPathBinary{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *fhirPathParser) parseComparison() (fhirPathNode, error) {
+	left, err := p.parseInvocation()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() && p.peek().kind == tokOperator {
+		op := p.peek().text
+		p.pos++
+		right, err := p.parseInvocation()
+		if err != nil {
+			return nil, err
+		}
+		return fhirPathBinary{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+// parseInvocation parses a term followed by any number of .member / .function() invocations
+func (p *fhirPathParser) parseInvocation() (fhirPathNode, error) {
+	node, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokPunct, ".") {
+		tok := p.peek()
+		if tok.kind != tokIdent {
+			return nil, fmt.Errorf("expected identifier after '.', got %q", tok.text)
+		}
+		p.pos++
+		node, err = p.parseMemberOrFunction(node, tok.text)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (p *fhirPathParser) parseTerm() (fhirPathNode, error) {
+	if p.atEnd() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	tok := p.peek()
+	switch tok.kind {
+	case tokString, tokDate:
+		p.pos++
+		return fhirPathLiteral{value: tok.text}, nil
+	case tokNumber:
+		p.pos++
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return fhirPathLiteral{value: value}, nil
+	case tokIdent:
+		p.pos++
+		switch tok.text {
+		case "true":
+			return fhirPathLiteral{value: true}, nil
+		case "false":
+			return fhirPathLiteral{value: false}, nil
+		}
+		return p.parseMemberOrFunction(nil, tok.text)
+	case tokPunct:
+		if p.accept(tokPunct, "(") {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokPunct, ")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+func (p *fhirPathParser) parseMemberOrFunction(target fhirPathNode, name string) (fhirPathNode, error) {
+	if !p.accept(tokPunct, "(") {
+		return fhirPathMember{target: target, name: name}, nil
+	}
+
+	var args []fhirPathNode
+	if !p.accept(tokPunct, ")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(tokPunct, ")") {
+				break
+			}
+			if err := p.expect(tokPunct, ","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	minArgs, maxArgs, known := fhirPathFunctionArity(name)
+	if !known {
+		return nil, fmt.Errorf("unsupported function %s()", name)
+	}
+	if len(args) < minArgs || len(args) > maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments for %s()", name)
+	}
+
+	return fhirPathFunction{target: target, name: name, args: args}, nil
+}
+
+func fhirPathFunctionArity(name string) (int, int, bool) {
+	switch name {
+	case "where":
+		return 1, 1, true
+	case "exists":
+		return 0, 1, true
+	case "empty", "count", "first", "not":
+		return 0, 0, true
+	}
+	return 0, 0, false
+}
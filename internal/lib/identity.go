@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// Version is the aether release, set at build time via -ldflags "-X .../internal/lib.Version=..."
+var Version = "1.0.0"
+
+// RequestIDHeader carries the ID of an outbound request when http_client.request_ids is enabled
+const RequestIDHeader = "X-Request-ID"
+
+// userAgentJobPrefix is how many characters of the job ID the User-Agent carries
+const userAgentJobPrefix = 8
+
+// clientIdentity is the process-wide identification of outbound requests (see ConfigureClientIdentity)
+var clientIdentity struct {
+	mu         sync.RWMutex
+	product    string
+	requestIDs bool
+	install    sync.Once
+}
+
+// ConfigureClientIdentity sets how outbound requests identify themselves to services
+// It also wraps http.DefaultTransport, so clients without their own transport (health
+// checks, webhooks) send the same User-Agent and request IDs
+func ConfigureClientIdentity(config models.HTTPClientConfig) {
+	clientIdentity.mu.Lock()
+	clientIdentity.product = config.UserAgent
+	clientIdentity.requestIDs = config.RequestIDs
+	clientIdentity.mu.Unlock()
+
+	clientIdentity.install.Do(func() {
+		http.DefaultTransport = &identityTransport{base: http.DefaultTransport}
+	})
+}
+
+// UserAgent returns the User-Agent of requests made for a job, e.g. "aether/1.0.0 job=3f2a9c1e"
+// An empty jobID omits the job token
+func UserAgent(jobID string) string {
+	clientIdentity.mu.RLock()
+	product := clientIdentity.product
+	clientIdentity.mu.RUnlock()
+
+	if product == "" {
+		product = "aether/" + Version
+	}
+	if jobID == "" {
+		return product
+	}
+	if len(jobID) > userAgentJobPrefix {
+		jobID = jobID[:userAgentJobPrefix]
+	}
+	return product + " job=" + jobID
+}
+
+// ApplyClientIdentity sets the User-Agent and, if enabled, an X-Request-ID on req
+// Headers already present are kept, so a request retried with the same headers keeps its ID
+// Returns the request ID ("" if request IDs are disabled)
+func ApplyClientIdentity(req *http.Request, jobID string) string {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent(jobID))
+	}
+
+	clientIdentity.mu.RLock()
+	requestIDs := clientIdentity.requestIDs
+	clientIdentity.mu.RUnlock()
+
+	if !requestIDs {
+		return req.Header.Get(RequestIDHeader)
+	}
+	if req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, uuid.NewString())
+	}
+	return req.Header.Get(RequestIDHeader)
+}
+
+// identityTransport applies the client identity to requests of clients using http.DefaultTransport
+type identityTransport struct {
+	base http.RoundTripper
+}
+
+func (t *identityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	ApplyClientIdentity(req, "")
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,51 @@
+package lib
+
+import "sync"
+
+// LogSampler limits repetitive debug messages, such as one line per DIMP request
+// Each message is counted separately: the first N occurrences are logged, afterwards only
+// every Kth, so debug logging stays usable on jobs with millions of resources
+type LogSampler struct {
+	logger *Logger
+	first  uint64
+	every  uint64
+
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// NewLogSampler creates a sampler logging the first occurrences of each message, then every
+// every-th one; every <= 1 logs all messages
+func NewLogSampler(logger *Logger, first, every int) *LogSampler {
+	return &LogSampler{
+		logger: logger,
+		first:  uint64(max(first, 0)),
+		every:  uint64(max(every, 1)),
+		counts: make(map[string]uint64),
+	}
+}
+
+// Allow counts an occurrence of message and reports whether it should be logged
+// A nil sampler allows everything. When a message is first suppressed, a note is logged
+// so readers of the log know that later lines are a sample
+func (s *LogSampler) Allow(message string) bool {
+	if s == nil || s.every <= 1 {
+		return true
+	}
+
+	s.mu.Lock()
+	s.counts[message]++
+	n := s.counts[message]
+	s.mu.Unlock()
+
+	if n <= s.first {
+		return true
+	}
+	if n == s.first+1 {
+		s.logger.Debug("Debug message sampled from now on",
+			"message", message,
+			"logged", s.first,
+			"every", s.every)
+	}
+	return (n-s.first)%s.every == 0
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,17 +19,30 @@ const (
 	LogLevelError
 )
 
+// recentLogCapacity is how many log lines a Logger keeps in memory for error diagnostics
+const recentLogCapacity = 500
+
 // Logger provides structured logging for the application
+// Every message (including levels below the output threshold) is also kept in a
+// bounded in-memory history so failures can carry the log lines that led up to them
 type Logger struct {
 	level  LogLevel
 	logger *log.Logger
+
+	mu      sync.Mutex
+	recent  []string // Ring buffer of formatted lines
+	written uint64   // Total lines ever recorded (sequence number of the next line)
 }
 
+// LogMark identifies a position in a Logger's history (see Mark and LinesSince)
+type LogMark uint64
+
 // NewLogger creates a new logger instance
 func NewLogger(level LogLevel) *Logger {
 	return &Logger{
 		level:  level,
-		logger: log.New(os.Stderr, "", log.LstdFlags),
+		logger: log.New(os.Stderr, "", 0), // Lines carry their own timestamp in the configured time zone
+		recent: make([]string, recentLogCapacity),
 	}
 }
 
@@ -37,39 +51,84 @@ var DefaultLogger = NewLogger(LogLevelInfo)
 
 // Debug logs a debug message
 func (l *Logger) Debug(message string, fields ...any) {
-	if l.level <= LogLevelDebug {
-		l.log("DEBUG", message, fields...)
-	}
+	l.log(LogLevelDebug, "DEBUG", message, fields...)
 }
 
 // Info logs an informational message
 func (l *Logger) Info(message string, fields ...any) {
-	if l.level <= LogLevelInfo {
-		l.log("INFO", message, fields...)
-	}
+	l.log(LogLevelInfo, "INFO", message, fields...)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(message string, fields ...any) {
-	if l.level <= LogLevelWarn {
-		l.log("WARN", message, fields...)
-	}
+	l.log(LogLevelWarn, "WARN", message, fields...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(message string, fields ...any) {
-	if l.level <= LogLevelError {
-		l.log("ERROR", message, fields...)
-	}
+	l.log(LogLevelError, "ERROR", message, fields...)
 }
 
-// log formats and writes a log message with optional fields
-func (l *Logger) log(level string, message string, fields ...any) {
+// log formats a log message with optional fields, records it in the history
+// and writes it if level is at or above the configured threshold
+func (l *Logger) log(level LogLevel, levelName string, message string, fields ...any) {
 	var fieldsStr string
 	if len(fields) > 0 {
 		fieldsStr = fmt.Sprintf(" | %v", fields)
 	}
-	l.logger.Printf("[%s] %s%s", level, message, fieldsStr)
+	line := fmt.Sprintf("[%s] %s%s", levelName, message, fieldsStr)
+
+	line = Now().Format("2006/01/02 15:04:05") + " " + line
+	l.remember(line)
+
+	if l.level <= level {
+		l.logger.Print(line)
+	}
+}
+
+// remember appends a line to the in-memory history, overwriting the oldest when full
+func (l *Logger) remember(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.recent) == 0 {
+		return // Zero-value Logger: no history
+	}
+	l.recent[l.written%uint64(len(l.recent))] = line
+	l.written++
+}
+
+// Mark returns the current position in the log history
+// Pass it to LinesSince to retrieve everything logged afterwards (e.g., during one step)
+func (l *Logger) Mark() LogMark {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LogMark(l.written)
+}
+
+// LinesSince returns up to max of the most recent lines logged after mark, oldest first
+// Lines that have already been evicted from the bounded history are not returned
+func (l *Logger) LinesSince(mark LogMark, max int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := uint64(len(l.recent))
+	start := uint64(mark)
+	if l.written > capacity && start < l.written-capacity {
+		start = l.written - capacity
+	}
+	if max > 0 && l.written-start > uint64(max) {
+		start = l.written - uint64(max)
+	}
+	if start >= l.written {
+		return nil
+	}
+
+	lines := make([]string, 0, l.written-start)
+	for i := start; i < l.written; i++ {
+		lines = append(lines, l.recent[i%capacity])
+	}
+	return lines
 }
 
 // LogOperation logs the start and completion of an operation
@@ -90,13 +149,13 @@ func LogOperation(logger *Logger, operation string, fn func() error) error {
 }
 
 // LogRetry logs retry attempts
-func LogRetry(logger *Logger, operation string, attempt int, maxAttempts int, err error) {
+func LogRetry(logger *Logger, operation string, attempt int, maxAttempts int, err error, fields ...any) {
 	// Remove line breaks from operation to prevent log spoofing
 	safeOperation := strings.ReplaceAll(operation, "\n", "")
 	safeOperation = strings.ReplaceAll(safeOperation, "\r", "")
 	logger.Warn(
 		fmt.Sprintf("Retry attempt %d/%d for: %s", attempt+1, maxAttempts, safeOperation),
-		"error", err,
+		append([]any{"error", err}, fields...)...,
 	)
 }
 
@@ -150,32 +209,21 @@ func LogJobCompleted(logger *Logger, jobID string, totalFiles int, duration time
 	)
 }
 
-// LogServiceCall logs HTTP service calls
-func LogServiceCall(logger *Logger, service string, endpoint string, method string) {
+// LogServiceCall logs HTTP service calls; fields are appended (e.g., the request ID)
+func LogServiceCall(logger *Logger, service string, endpoint string, method string, fields ...any) {
 	logger.Debug(
 		"Service call",
-		"service", service,
-		"endpoint", endpoint,
-		"method", method,
+		append([]any{"service", service, "endpoint", endpoint, "method", method}, fields...)...,
 	)
 }
 
-// LogServiceResponse logs HTTP service responses
-func LogServiceResponse(logger *Logger, service string, statusCode int, duration time.Duration) {
+// LogServiceResponse logs HTTP service responses; fields are appended (e.g., the request ID)
+func LogServiceResponse(logger *Logger, service string, statusCode int, duration time.Duration, fields ...any) {
+	fields = append([]any{"service", service, "status", statusCode, "duration", duration}, fields...)
 	if statusCode >= 400 {
-		logger.Warn(
-			"Service response",
-			"service", service,
-			"status", statusCode,
-			"duration", duration,
-		)
+		logger.Warn("Service response", fields...)
 	} else {
-		logger.Debug(
-			"Service response",
-			"service", service,
-			"status", statusCode,
-			"duration", duration,
-		)
+		logger.Debug("Service response", fields...)
 	}
 }
 
@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressReporter reports long-running operations (TORCH polling, downloads, DIMP files)
+// Service and pipeline code only use this interface; the terminal rendering lives in the ui
+// package, so the same code runs with spinners, JSON events or no output at all
+type ProgressReporter interface {
+	// Start begins a task; total is the expected amount of work, or 0 if unknown
+	Start(description string, total int64) ProgressTask
+}
+
+// ProgressTask is a single operation started through a ProgressReporter
+type ProgressTask interface {
+	SetStatus(status string) // Transient status shown with the description, e.g. the next poll time
+	SetTotal(total int64)    // Corrects the expected amount of work, e.g. when an estimate was too low
+	Add(amount int64)        // Records completed work
+	Clear()                  // Removes the task from the terminal before other output is printed
+	Done(err error)          // Ends the task as succeeded (nil) or failed
+}
+
+// NoProgress discards all progress, e.g. for --no-progress and in tests
+var NoProgress ProgressReporter = noProgress{}
+
+type noProgress struct{}
+
+func (noProgress) Start(string, int64) ProgressTask { return noProgress{} }
+func (noProgress) SetStatus(string)                 {}
+func (noProgress) SetTotal(int64)                   {}
+func (noProgress) Add(int64)                        {}
+func (noProgress) Clear()                           {}
+func (noProgress) Done(error)                       {}
+
+// jsonProgressInterval limits how often a task's progress is written
+const jsonProgressInterval = time.Second
+
+// ProgressEvent is one line written by a JSON progress reporter
+type ProgressEvent struct {
+	Time        time.Time `json:"time"`
+	Task        int       `json:"task"`
+	Event       string    `json:"event"` // start, status, progress or done
+	Description string    `json:"description"`
+	Current     int64     `json:"current,omitempty"`
+	Total       int64     `json:"total,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	ElapsedMs   int64     `json:"elapsed_ms,omitempty"`
+}
+
+// NewJSONProgress returns a reporter that writes each task's start, status changes, progress
+// (at most once per second) and end as one JSON object per line to w, for scripts and CI logs
+func NewJSONProgress(w io.Writer) ProgressReporter {
+	return &jsonProgress{encoder: json.NewEncoder(w)}
+}
+
+type jsonProgress struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	tasks   int
+}
+
+func (p *jsonProgress) Start(description string, total int64) ProgressTask {
+	p.mu.Lock()
+	p.tasks++
+	task := &jsonProgressTask{reporter: p, id: p.tasks, description: description, total: total, started: time.Now()}
+	p.mu.Unlock()
+	task.write("start", "", "")
+	return task
+}
+
+func (p *jsonProgress) write(event ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_ = p.encoder.Encode(event)
+}
+
+type jsonProgressTask struct {
+	reporter    *jsonProgress
+	id          int
+	description string
+	current     int64
+	total       int64
+	started     time.Time
+	written     time.Time
+}
+
+func (t *jsonProgressTask) write(event, status, errText string) {
+	t.written = time.Now()
+	progressEvent := ProgressEvent{
+		Time:        t.written,
+		Task:        t.id,
+		Event:       event,
+		Description: t.description,
+		Current:     t.current,
+		Total:       t.total,
+		Status:      status,
+		Error:       errText,
+	}
+	if event == "done" {
+		progressEvent.ElapsedMs = time.Since(t.started).Milliseconds()
+	}
+	t.reporter.write(progressEvent)
+}
+
+func (t *jsonProgressTask) SetStatus(status string) { t.write("status", status, "") }
+func (t *jsonProgressTask) SetTotal(total int64)    { t.total = total }
+func (t *jsonProgressTask) Clear()                  {}
+
+func (t *jsonProgressTask) Add(amount int64) {
+	t.current += amount
+	if time.Since(t.written) >= jsonProgressInterval {
+		t.write("progress", "", "")
+	}
+}
+
+func (t *jsonProgressTask) Done(err error) {
+	if err != nil {
+		t.write("done", "", err.Error())
+		return
+	}
+	t.write("done", "", "")
+}
@@ -0,0 +1,178 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// tracerName is the instrumentation scope of aether's spans
+const tracerName = "github.com/trobanga/aether"
+
+// tracing is the process-wide trace export (see ConfigureTracing)
+var tracing struct {
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+}
+
+// ConfigureTracing exports spans to the configured OTLP collector and propagates W3C trace
+// context on requests of clients using NewTracingTransport. A disabled configuration turns
+// tracing off; spans are then no-ops. The returned function flushes pending spans and must be
+// called before the process exits
+func ConfigureTracing(config models.TracingConfig, logger *Logger) (func(context.Context) error, error) {
+	tracing.mu.Lock()
+	defer tracing.mu.Unlock()
+
+	if tracing.provider != nil {
+		_ = tracing.provider.Shutdown(context.Background())
+		tracing.provider = nil
+	}
+	if !config.IsEnabled() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	if config.GetProtocol() == models.TracingGRPC {
+		exporter, err = otlptracegrpc.New(context.Background(), otlptracegrpc.WithEndpointURL(config.Endpoint), otlptracegrpc.WithHeaders(config.Headers))
+	} else {
+		exporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(config.Endpoint), otlptracehttp.WithHeaders(config.Headers))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", config.GetServiceName()),
+		attribute.String("service.version", Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.GetSampleRatio()))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	// A collector that is down must not fail the pipeline; export errors are logged instead
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("Trace export failed", "endpoint", config.Endpoint, "error", err)
+	}))
+	tracing.provider = provider
+
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span as child of the span in ctx (or a new trace)
+// The span must be ended, usually with EndSpan
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan marks span as failed if err is not nil and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the trace in ctx, or "" if ctx is not traced
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// tracingTransport records each request as a client span and sends its trace context along
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+// NewTracingTransport wraps base with a client span per request, which carries the W3C
+// traceparent header so the service's spans join the trace; a nil base uses http.DefaultTransport
+// The span ends when the response body is closed, so it covers the transfer of downloads.
+// Only method, host and path are recorded: query strings may hold tokens
+func NewTracingTransport(base http.RoundTripper) http.RoundTripper {
+	return &tracingTransport{base: base}
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Hostname()),
+			attribute.String("url.path", req.URL.Path),
+		))
+	if !span.SpanContext().IsValid() {
+		return base.RoundTrip(req) // Tracing is off
+	}
+
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		EndSpan(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+// spanBody ends the span of a request when its response body is closed
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+	once sync.Once
+	err  error // First read error other than EOF
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { EndSpan(b.span, b.err) })
+	return err
+}
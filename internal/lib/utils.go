@@ -1,7 +1,10 @@
 package lib
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -39,3 +42,40 @@ func GetFileSize(path string) int64 {
 	}
 	return info.Size()
 }
+
+// FormatBytes formats bytes as human-readable size
+func FormatBytes(bytes int64) string {
+	const (
+		KB = 1024
+		MB = 1024 * KB
+		GB = 1024 * MB
+		TB = 1024 * GB
+	)
+
+	fbytes := float64(bytes)
+
+	if bytes >= TB {
+		return fmt.Sprintf("%.2f TB", fbytes/TB)
+	} else if bytes >= GB {
+		return fmt.Sprintf("%.2f GB", fbytes/GB)
+	} else if bytes >= MB {
+		return fmt.Sprintf("%.2f MB", fbytes/MB)
+	} else if bytes >= KB {
+		return fmt.Sprintf("%.2f KB", fbytes/KB)
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
+
+// ParseDuration parses a Go duration (e.g. "36h") or a number of days or weeks ("30d", "2w")
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if count, found := strings.CutSuffix(s, suffix); found {
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
@@ -3,9 +3,13 @@ package lib
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
 	"strings"
 
+	"github.com/trobanga/aether/internal/i18n"
 	"github.com/trobanga/aether/internal/models"
 )
 
@@ -16,6 +20,7 @@ var StepPrerequisites = map[models.StepName][]models.StepName{
 	models.StepLocalImport:       {},         // No prerequisites - can always run
 	models.StepHttpImport:        {},         // No prerequisites - can always run
 	models.StepDIMP:              {"import"}, // Requires any import step to complete
+	models.StepImaging:           {"import"}, // Works on imported or pseudonymized data
 	models.StepValidation:        {"import"}, // Can validate after import (regardless of DIMP)
 	models.StepCSVConversion:     {"import"}, // Can convert original or pseudonymized data
 	models.StepParquetConversion: {"import"}, // Can convert original or pseudonymized data
@@ -84,7 +89,8 @@ func GetStepDependencies(stepName models.StepName) []models.StepName {
 
 // DetectInputType determines the input source type from the input string
 // Returns InputTypeLocal for directories, InputTypeHTTP for HTTP URLs,
-// InputTypeTORCHURL for TORCH result URLs, InputTypeCRTDL for CRTDL files
+// InputTypeTORCHURL for TORCH result URLs, InputTypeBulkExport for FHIR $export kick-off URLs,
+// InputTypeFHIRSearch for FHIR search URLs, InputTypeS3 for s3:// URLs, InputTypeCRTDL for CRTDL files
 func DetectInputType(inputSource string) (models.InputType, error) {
 	if inputSource == "" {
 		return "", fmt.Errorf("input source cannot be empty")
@@ -95,12 +101,23 @@ func DetectInputType(inputSource string) (models.InputType, error) {
 		return models.InputTypeLocal, nil
 	}
 
+	// Check if S3 URL (bucket and prefix are validated with the job)
+	if strings.HasPrefix(inputSource, "s3://") {
+		return models.InputTypeS3, nil
+	}
+
 	// Check if HTTP URL
 	if strings.HasPrefix(inputSource, "http://") || strings.HasPrefix(inputSource, "https://") {
 		// Check if TORCH result URL pattern (contains /fhir/extraction/ or /fhir/result/)
 		if strings.Contains(inputSource, "/fhir/extraction/") || strings.Contains(inputSource, "/fhir/result/") {
 			return models.InputTypeTORCHURL, nil
 		}
+		if IsBulkExportURL(inputSource) {
+			return models.InputTypeBulkExport, nil
+		}
+		if IsFHIRSearchURL(inputSource) {
+			return models.InputTypeFHIRSearch, nil
+		}
 		return models.InputTypeHTTP, nil
 	}
 
@@ -120,6 +137,31 @@ func DetectInputType(inputSource string) (models.InputType, error) {
 	return models.InputTypeLocal, nil
 }
 
+// fhirResourceTypePattern matches FHIR resource type names (Patient, MedicationRequest, ...)
+var fhirResourceTypePattern = regexp.MustCompile(`^[A-Z][A-Za-z]+$`)
+
+// IsFHIRSearchURL reports whether an HTTP(S) URL is a FHIR search on a resource type,
+// i.e. its last path segment is a resource type name (https://fhir.example.org/fhir/Observation?date=ge2024-01-01)
+// File downloads keep their extension (Patient.ndjson) and are not search URLs
+func IsFHIRSearchURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+	segments := strings.Split(strings.TrimSuffix(parsed.Path, "/"), "/")
+	return fhirResourceTypePattern.MatchString(segments[len(segments)-1])
+}
+
+// IsBulkExportURL reports whether an HTTP(S) URL kicks off a FHIR Bulk Data export, i.e. its
+// last path segment is $export (system, Patient or Group level: .../fhir/Group/cohort-1/$export)
+func IsBulkExportURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+	return path.Base(parsed.Path) == "$export"
+}
+
 // IsCRTDLFile checks if the file at the given path is a valid CRTDL file
 // by verifying it contains required cohortDefinition and dataExtraction keys
 func IsCRTDLFile(path string) bool {
@@ -181,7 +223,7 @@ func ValidateCRTDLSyntax(crtdlPath string) error {
 
 	// Check for FHIR Parameters format (common mistake)
 	if resourceType, ok := crtdl["resourceType"].(string); ok && resourceType == "Parameters" {
-		return fmt.Errorf("CRTDL file '%s' uses FHIR Parameters format\n\nThis format is not supported. Please convert to flat CRTDL structure:\n{\n  \"cohortDefinition\": { \"inclusionCriteria\": [...] },\n  \"dataExtraction\": { \"attributeGroups\": [...] }\n}\n\nSee .github/test/torch/queries/example-crtdl.json for reference", crtdlPath)
+		return i18n.Errorf(i18n.MsgCRTDLParametersFormat, crtdlPath)
 	}
 
 	// Check required keys
@@ -232,6 +274,30 @@ func ValidateCRTDLSyntax(crtdlPath string) error {
 	return nil
 }
 
+// ReadCRTDLAnnotations reads the display and version fields of a CRTDL's cohortDefinition
+// Returns nil if the cohortDefinition has neither; non-string values are ignored
+func ReadCRTDLAnnotations(crtdlPath string) (*models.JobAnnotations, error) {
+	data, err := os.ReadFile(crtdlPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRTDL file '%s': %w", crtdlPath, err)
+	}
+
+	var crtdl struct {
+		CohortDefinition map[string]any `json:"cohortDefinition"`
+	}
+	if err := json.Unmarshal(data, &crtdl); err != nil {
+		return nil, fmt.Errorf("CRTDL file '%s' contains invalid JSON: %w", crtdlPath, err)
+	}
+
+	display, _ := crtdl.CohortDefinition["display"].(string)
+	version, _ := crtdl.CohortDefinition["version"].(string)
+	annotations := &models.JobAnnotations{Display: strings.TrimSpace(display), Version: strings.TrimSpace(version)}
+	if annotations.Display == "" && annotations.Version == "" {
+		return nil, nil
+	}
+	return annotations, nil
+}
+
 // ValidateSplitConfig validates the Bundle split threshold configuration
 // Ensures threshold is positive, within limits, and logs warnings if appropriate
 func ValidateSplitConfig(thresholdMB int) error {
@@ -0,0 +1,52 @@
+package models
+
+import "fmt"
+
+// AttachmentMode controls what happens to Binary resources and base64 Attachment data on import
+type AttachmentMode string
+
+const (
+	AttachmentKeep        AttachmentMode = "keep"        // Leave data inline (default)
+	AttachmentStrip       AttachmentMode = "strip"       // Remove the data, keep contentType/size/title
+	AttachmentExternalize AttachmentMode = "externalize" // Move the data to jobs/<id>/attachments/ and reference the file
+)
+
+// IsValid returns true if the mode is recognized (empty means the default, keep)
+func (m AttachmentMode) IsValid() bool {
+	switch m {
+	case "", AttachmentKeep, AttachmentStrip, AttachmentExternalize:
+		return true
+	}
+	return false
+}
+
+// AttachmentConfig is the attachment handling policy applied after import
+// Attachments inflate bundle sizes and may contain identifying documents (scanned letters, photos)
+type AttachmentConfig struct {
+	Mode      AttachmentMode `yaml:"mode" json:"mode"`               // keep | strip | externalize
+	MaxSizeKB int            `yaml:"max_size_kb" json:"max_size_kb"` // Strip attachments larger than this, regardless of mode (0 = no limit)
+}
+
+// IsActive returns true if the policy changes anything, so imports can skip the extra pass otherwise
+func (c AttachmentConfig) IsActive() bool {
+	return (c.Mode != "" && c.Mode != AttachmentKeep) || c.MaxSizeKB > 0
+}
+
+// GetMaxSizeBytes returns the size limit in bytes, or 0 for no limit
+func (c AttachmentConfig) GetMaxSizeBytes() int64 {
+	if c.MaxSizeKB <= 0 {
+		return 0
+	}
+	return int64(c.MaxSizeKB) * 1024
+}
+
+// Validate checks the attachment policy
+func (c AttachmentConfig) Validate() error {
+	if !c.Mode.IsValid() {
+		return fmt.Errorf("invalid attachments mode '%s' (must be keep, strip, or externalize)", c.Mode)
+	}
+	if c.MaxSizeKB < 0 {
+		return fmt.Errorf("attachments.max_size_kb must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeBudgetConfig is a total wall-clock budget for a job, apportioned across its steps
+// Each step receives a share of the budget that is still left when it starts, in proportion
+// to its weight among the steps not completed yet; time a step does not use rolls over
+type TimeBudgetConfig struct {
+	TotalMinutes int              `yaml:"total_minutes" json:"total_minutes,omitempty"` // 0 = no budget
+	Weights      map[StepName]int `yaml:"weights" json:"weights,omitempty"`             // Relative share per step (default 1)
+}
+
+// IsActive returns true if a budget is configured
+func (c TimeBudgetConfig) IsActive() bool {
+	return c.TotalMinutes > 0
+}
+
+// Total returns the budget as a duration, or 0 if no budget is configured
+func (c TimeBudgetConfig) Total() time.Duration {
+	if c.TotalMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(c.TotalMinutes) * time.Minute
+}
+
+// Weight returns the relative share of a step (1 unless configured)
+func (c TimeBudgetConfig) Weight(step StepName) int {
+	if weight, ok := c.Weights[step]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// Validate checks the budget and its step weights
+func (c TimeBudgetConfig) Validate() error {
+	if c.TotalMinutes < 0 {
+		return fmt.Errorf("pipeline.time_budget.total_minutes must not be negative")
+	}
+	for step, weight := range c.Weights {
+		if !IsValidStepName(step) {
+			return fmt.Errorf("pipeline.time_budget.weights: unknown step '%s'", step)
+		}
+		if weight <= 0 {
+			return fmt.Errorf("pipeline.time_budget.weights.%s must be positive", step)
+		}
+	}
+	return nil
+}
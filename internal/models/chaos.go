@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultChaosSlowMs is the delay of requests slowed down by fault injection by default
+const DefaultChaosSlowMs = 2000
+
+// ChaosConfig injects faults into outbound service requests (http_client.chaos)
+// Meant for resilience tests of retries, checkpoints and resumption, e.g. in CI; never enable it
+// against production services. Error, timeout and truncation faults exclude each other, so their
+// rates must not add up to more than 1; slow responses are drawn independently
+type ChaosConfig struct {
+	Enabled      bool     `yaml:"enabled" json:"enabled"`
+	ErrorRate    float64  `yaml:"error_rate" json:"error_rate,omitempty"`       // Fraction of requests answered with an injected HTTP 503
+	TimeoutRate  float64  `yaml:"timeout_rate" json:"timeout_rate,omitempty"`   // Fraction of requests failing with an injected timeout
+	TruncateRate float64  `yaml:"truncate_rate" json:"truncate_rate,omitempty"` // Fraction of responses whose body breaks off halfway
+	SlowRate     float64  `yaml:"slow_rate" json:"slow_rate,omitempty"`         // Fraction of requests delayed by slow_ms
+	SlowMs       int      `yaml:"slow_ms" json:"slow_ms,omitempty"`             // Delay of slowed requests (default 2000)
+	Hosts        []string `yaml:"hosts" json:"hosts,omitempty"`                 // Only affect requests to these hosts (host or host:port); empty = all
+	Seed         uint64   `yaml:"seed" json:"seed,omitempty"`                   // Seed of the fault sequence, for reproducible runs (0 = random)
+}
+
+// GetSlowDelay returns the delay of slowed requests
+func (c ChaosConfig) GetSlowDelay() time.Duration {
+	if c.SlowMs <= 0 {
+		return DefaultChaosSlowMs * time.Millisecond
+	}
+	return time.Duration(c.SlowMs) * time.Millisecond
+}
+
+// AppliesTo reports whether faults are injected into requests to host (host or host:port)
+func (c ChaosConfig) AppliesTo(host string) bool {
+	if !c.Enabled {
+		return false
+	}
+	if len(c.Hosts) == 0 {
+		return true
+	}
+	hostname, _, _ := strings.Cut(host, ":")
+	return slices.Contains(c.Hosts, host) || slices.Contains(c.Hosts, hostname)
+}
+
+// Validate checks the fault rates
+func (c ChaosConfig) Validate() error {
+	for name, rate := range map[string]float64{
+		"error_rate":    c.ErrorRate,
+		"timeout_rate":  c.TimeoutRate,
+		"truncate_rate": c.TruncateRate,
+		"slow_rate":     c.SlowRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("http_client.chaos.%s must be between 0 and 1, got %g", name, rate)
+		}
+	}
+	if c.ErrorRate+c.TimeoutRate+c.TruncateRate > 1 {
+		return errors.New("http_client.chaos error_rate, timeout_rate and truncate_rate must not add up to more than 1")
+	}
+	if c.SlowMs < 0 {
+		return errors.New("http_client.chaos.slow_ms must not be negative")
+	}
+	return nil
+}
+
+// ParseChaosSpec parses the fault injection settings of the AETHER_CHAOS environment variable,
+// e.g. "error_rate=0.1,timeout_rate=0.05,slow_rate=0.2,slow_ms=500,seed=42,hosts=dimp|torch"
+// The keys are those of http_client.chaos; a parsed spec is always enabled
+func ParseChaosSpec(spec string) (ChaosConfig, error) {
+	config := ChaosConfig{Enabled: true}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return ChaosConfig{}, fmt.Errorf("invalid chaos setting '%s' (expected key=value)", part)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "error_rate":
+			config.ErrorRate, err = strconv.ParseFloat(value, 64)
+		case "timeout_rate":
+			config.TimeoutRate, err = strconv.ParseFloat(value, 64)
+		case "truncate_rate":
+			config.TruncateRate, err = strconv.ParseFloat(value, 64)
+		case "slow_rate":
+			config.SlowRate, err = strconv.ParseFloat(value, 64)
+		case "slow_ms":
+			config.SlowMs, err = strconv.Atoi(value)
+		case "seed":
+			config.Seed, err = strconv.ParseUint(value, 10, 64)
+		case "hosts":
+			config.Hosts = strings.Split(value, "|")
+		default:
+			return ChaosConfig{}, fmt.Errorf("unknown chaos setting '%s'", key)
+		}
+		if err != nil {
+			return ChaosConfig{}, fmt.Errorf("invalid chaos setting '%s': %w", part, err)
+		}
+	}
+	return config, config.Validate()
+}
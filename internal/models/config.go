@@ -3,14 +3,54 @@ package models
 import (
 	"fmt"
 	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 )
 
 // ProjectConfig is the top-level configuration for the Aether pipeline
 type ProjectConfig struct {
-	Services ServiceConfig  `yaml:"services" json:"services"`
-	Pipeline PipelineConfig `yaml:"pipeline" json:"pipeline"`
-	Retry    RetryConfig    `yaml:"retry" json:"retry"`
-	JobsDir  string         `yaml:"jobs_dir" json:"jobs_dir"`
+	Services     ServiceConfig         `yaml:"services" json:"services"`
+	Pipeline     PipelineConfig        `yaml:"pipeline" json:"pipeline"`
+	Retry        RetryConfig           `yaml:"retry" json:"retry"`
+	Import       ImportConfig          `yaml:"import" json:"import"`
+	Attachments  AttachmentConfig      `yaml:"attachments" json:"attachments"`
+	Narrative    NarrativeConfig       `yaml:"narrative" json:"narrative"`
+	Extensions   ExtensionFilterConfig `yaml:"extensions" json:"extensions"`
+	Consent      ConsentFilterConfig   `yaml:"consent" json:"consent"`
+	Flatten      FlattenConfig         `yaml:"flatten" json:"flatten"`
+	Limits       LimitsConfig          `yaml:"limits" json:"limits"`
+	Storage      StorageConfig         `yaml:"storage" json:"storage"`
+	SLA          SLAConfig             `yaml:"sla" json:"sla"`
+	Heartbeat    HeartbeatConfig       `yaml:"heartbeat" json:"heartbeat"`
+	HTTPClient   HTTPClientConfig      `yaml:"http_client" json:"http_client"`
+	Tracing      TracingConfig         `yaml:"tracing" json:"tracing"`
+	SanityChecks []SanityCheck         `yaml:"sanity_checks" json:"sanity_checks,omitempty"`
+	JobMetadata  JobMetadataConfig     `yaml:"job_metadata" json:"job_metadata"`
+	Server       ServerConfig          `yaml:"server" json:"server"`
+	Output       OutputConfig          `yaml:"output" json:"output"`
+	Tenants      []TenantConfig        `yaml:"tenants" json:"-"`                     // Not persisted with jobs: holds every tenant's credentials
+	Tenant       string                `yaml:"-" json:"tenant,omitempty"`            // Tenant the configuration is scoped to (see ForTenant)
+	Quota        QuotaConfig           `yaml:"quota" json:"quota"`                   // Job limits of the (tenant's) jobs directory
+	Locale       string                `yaml:"locale" json:"locale,omitempty"`       // CLI message language; empty = from LC_ALL/LC_MESSAGES/LANG
+	TimeZone     string                `yaml:"time_zone" json:"time_zone,omitempty"` // IANA zone of all timestamps (default UTC, "Local" = system zone)
+	JobsDir      string                `yaml:"jobs_dir" json:"jobs_dir"`
+}
+
+// GetTimeZone returns the zone timestamps are written in (default UTC)
+func (c ProjectConfig) GetTimeZone() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(c.TimeZone)
+}
+
+// HTTPClientConfig controls how outbound requests identify themselves to services
+type HTTPClientConfig struct {
+	UserAgent  string      `yaml:"user_agent" json:"user_agent,omitempty"` // Product token of the User-Agent (default "aether/<version>"); " job=<id-prefix>" is appended
+	RequestIDs bool        `yaml:"request_ids" json:"request_ids"`         // Send a unique X-Request-ID with every request and log it
+	Chaos      ChaosConfig `yaml:"chaos" json:"chaos"`                     // Fault injection for resilience tests (see ChaosConfig)
 }
 
 // ServiceConfig contains connection details for external HTTP services
@@ -19,12 +59,125 @@ type ServiceConfig struct {
 	CSVConversion     CSVConversionConfig     `yaml:"csv_conversion" json:"csv_conversion"`
 	ParquetConversion ParquetConversionConfig `yaml:"parquet_conversion" json:"parquet_conversion"`
 	TORCH             TORCHConfig             `yaml:"torch" json:"torch"`
+	FHIRExport        FHIRExportConfig        `yaml:"fhir_export" json:"fhir_export"`
+	S3                S3Config                `yaml:"s3" json:"s3"`
+	HealthCheck       HealthCheckConfig       `yaml:"healthcheck" json:"healthcheck"`
+	Imaging           ImagingConfig           `yaml:"imaging" json:"imaging"`
 }
 
 // DIMPConfig contains DIMP pseudonymization service settings
 type DIMPConfig struct {
-	URL                    string `yaml:"url" json:"url"`
-	BundleSplitThresholdMB int    `yaml:"bundle_split_threshold_mb" json:"bundle_split_threshold_mb"` // Default 10MB - threshold for splitting large Bundles to prevent HTTP 413 errors
+	URL                     string          `yaml:"url" json:"url"`
+	BundleSplitThresholdMB  int             `yaml:"bundle_split_threshold_mb" json:"bundle_split_threshold_mb"`   // Default 10MB - threshold for splitting large Bundles to prevent HTTP 413 errors
+	SplitByResourceType     bool            `yaml:"split_by_resource_type" json:"split_by_resource_type"`         // Write output partitioned by resourceType (dimped_<Type>.ndjson)
+	NonFHIRLines            NonFHIRLineMode `yaml:"non_fhir_lines" json:"non_fhir_lines"`                         // How to handle JSON lines without resourceType (default: fail)
+	ProgressIntervalSeconds int             `yaml:"progress_interval_seconds" json:"progress_interval_seconds"`   // How often progress within a file is logged and recorded (default 60, -1 disables)
+	DebugLogFirst           int             `yaml:"debug_log_first" json:"debug_log_first"`                       // Per-resource debug lines logged before sampling starts (default 1000)
+	DebugLogEvery           int             `yaml:"debug_log_every" json:"debug_log_every"`                       // Once sampling, log every Kth per-resource debug line (default 1000, 1 logs all)
+	FileRetryAttempts       int             `yaml:"file_retry_attempts" json:"file_retry_attempts"`               // Retries of a file after a transient failure, resuming where it stopped (default 0)
+	FileRetryBackoffSeconds int             `yaml:"file_retry_backoff_seconds" json:"file_retry_backoff_seconds"` // Wait before the first file retry, doubled for each further one (default 60)
+	OutputCacheTTLMinutes   int             `yaml:"output_cache_ttl_minutes" json:"output_cache_ttl_minutes"`     // Reuse the output of identical input files for this long (0 = disabled)
+	AdaptiveSplit           bool            `yaml:"adaptive_split" json:"adaptive_split"`                         // Tune the split threshold within the step from DIMP response times, 413s and timeouts
+	SplitTargetSeconds      int             `yaml:"split_target_seconds" json:"split_target_seconds"`             // Response time a Bundle request should take with adaptive_split (default 10)
+	BatchSize               int             `yaml:"batch_size" json:"batch_size"`                                 // Non-Bundle resources sent per request as a transaction Bundle (default 1: one request per resource)
+	UsageQuota              DIMPQuotaConfig `yaml:"usage_quota" json:"usage_quota"`                               // Monthly volume billed by the trust center, warned about when approached
+}
+
+// DefaultDIMPProgressIntervalSeconds is how often progress within a file is reported by default
+const DefaultDIMPProgressIntervalSeconds = 60
+
+// GetProgressInterval returns how often progress within a file is reported, or 0 if disabled
+func (c DIMPConfig) GetProgressInterval() time.Duration {
+	switch {
+	case c.ProgressIntervalSeconds < 0:
+		return 0
+	case c.ProgressIntervalSeconds == 0:
+		return DefaultDIMPProgressIntervalSeconds * time.Second
+	}
+	return time.Duration(c.ProgressIntervalSeconds) * time.Second
+}
+
+// Defaults for sampling per-resource DIMP debug logging
+const (
+	DefaultDIMPDebugLogFirst = 1000
+	DefaultDIMPDebugLogEvery = 1000
+)
+
+// GetDebugLogSampling returns how many per-resource debug lines are logged before sampling,
+// and the sampling interval afterwards
+func (c DIMPConfig) GetDebugLogSampling() (first, every int) {
+	first, every = c.DebugLogFirst, c.DebugLogEvery
+	if first == 0 {
+		first = DefaultDIMPDebugLogFirst
+	}
+	if every == 0 {
+		every = DefaultDIMPDebugLogEvery
+	}
+	return first, every
+}
+
+// DefaultDIMPFileRetryBackoffSeconds is the wait before the first retry of a failed file
+const DefaultDIMPFileRetryBackoffSeconds = 60
+
+// MaxDIMPFileRetryBackoff caps the doubling wait between retries of a failed file
+const MaxDIMPFileRetryBackoff = 15 * time.Minute
+
+// GetFileRetryBackoff returns the wait before retry attempt+1 of a failed file
+func (c DIMPConfig) GetFileRetryBackoff(attempt int) time.Duration {
+	seconds := c.FileRetryBackoffSeconds
+	if seconds <= 0 {
+		seconds = DefaultDIMPFileRetryBackoffSeconds
+	}
+	backoff := time.Duration(seconds) * time.Second
+	for i := 0; i < attempt && backoff < MaxDIMPFileRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, MaxDIMPFileRetryBackoff)
+}
+
+// GetOutputCacheTTL returns how long pseudonymized output is reused for identical input files (0 = disabled)
+func (c DIMPConfig) GetOutputCacheTTL() time.Duration {
+	if c.OutputCacheTTLMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(c.OutputCacheTTLMinutes) * time.Minute
+}
+
+// DefaultSplitTargetSeconds is the DIMP response time a Bundle request should take with adaptive_split
+const DefaultSplitTargetSeconds = 10
+
+// GetSplitTarget returns the DIMP response time adaptive splitting aims for per Bundle request
+func (c DIMPConfig) GetSplitTarget() time.Duration {
+	if c.SplitTargetSeconds <= 0 {
+		return DefaultSplitTargetSeconds * time.Second
+	}
+	return time.Duration(c.SplitTargetSeconds) * time.Second
+}
+
+// MaxDIMPBatchSize is the largest services.dimp.batch_size
+const MaxDIMPBatchSize = 1000
+
+// GetBatchSize returns the number of non-Bundle resources sent per DIMP request, at least 1
+func (c DIMPConfig) GetBatchSize() int {
+	return max(c.BatchSize, 1)
+}
+
+// NonFHIRLineMode controls how NDJSON lines lacking a resourceType are handled
+type NonFHIRLineMode string
+
+const (
+	NonFHIRLinesFail        NonFHIRLineMode = "fail"         // Treat like any other resource (DIMP rejects it, failing the file)
+	NonFHIRLinesPassThrough NonFHIRLineMode = "pass_through" // Copy the line to the output unchanged, without sending it to DIMP
+	NonFHIRLinesQuarantine  NonFHIRLineMode = "quarantine"   // Move the line to the job's quarantine/ directory
+)
+
+// IsValid returns true if the mode is recognized (empty means the default, fail)
+func (m NonFHIRLineMode) IsValid() bool {
+	switch m {
+	case "", NonFHIRLinesFail, NonFHIRLinesPassThrough, NonFHIRLinesQuarantine:
+		return true
+	}
+	return false
 }
 
 // CSVConversionConfig contains CSV conversion service settings
@@ -42,14 +195,304 @@ type TORCHConfig struct {
 	BaseURL                   string `yaml:"base_url" json:"base_url"`
 	Username                  string `yaml:"username" json:"username"`
 	Password                  string `yaml:"password" json:"password"`
+	AuthMode                  string `yaml:"auth_mode" json:"auth_mode,omitempty"`         // basic (default), oauth2_client_credentials or bearer_token
+	TokenURL                  string `yaml:"token_url" json:"token_url,omitempty"`         // OAuth2 token endpoint (oauth2_client_credentials)
+	ClientID                  string `yaml:"client_id" json:"client_id,omitempty"`         // OAuth2 client (oauth2_client_credentials)
+	ClientSecret              string `yaml:"client_secret" json:"client_secret,omitempty"` // OAuth2 client secret (oauth2_client_credentials)
+	Scope                     string `yaml:"scope" json:"scope,omitempty"`                 // Space-separated OAuth2 scopes to request (optional)
+	BearerToken               string `yaml:"bearer_token" json:"bearer_token,omitempty"`   // Static access token (bearer_token)
 	ExtractionTimeoutMinutes  int    `yaml:"extraction_timeout_minutes" json:"extraction_timeout_minutes"`
 	PollingIntervalSeconds    int    `yaml:"polling_interval_seconds" json:"polling_interval_seconds"`
 	MaxPollingIntervalSeconds int    `yaml:"max_polling_interval_seconds" json:"max_polling_interval_seconds"`
+	CleanupAfterDownload      bool   `yaml:"cleanup_after_download" json:"cleanup_after_download,omitempty"`     // Delete extraction results on the server once imported
+	MaxActiveExtractions      int    `yaml:"max_active_extractions" json:"max_active_extractions,omitempty"`     // Extractions running at once on this server across all jobs (0 = unlimited)
+	ResultCacheTTLMinutes     int    `yaml:"result_cache_ttl_minutes" json:"result_cache_ttl_minutes,omitempty"` // Reuse results of the same CRTDL for this long (0 = disabled)
+	ResponseCacheSeconds      int    `yaml:"response_cache_seconds" json:"response_cache_seconds,omitempty"`     // Share status GET and file HEAD answers between processes for this long (0 = disabled)
+	ResponseCacheMaxMB        int    `yaml:"response_cache_max_mb" json:"response_cache_max_mb,omitempty"`       // Size limit of the response cache (default 16)
+	SplitPeriod               string `yaml:"split_period" json:"split_period,omitempty"`                         // Submit one extraction per month, quarter or year of the CRTDL's date filters (empty = off)
+	SplitConcurrency          int    `yaml:"split_concurrency" json:"split_concurrency,omitempty"`               // Sub-period extractions of a job running at once (default 2)
+	FeasibilityURL            string `yaml:"feasibility_url" json:"feasibility_url,omitempty"`                   // Endpoint counting a CRTDL's cohort before extraction (empty = no check)
+	MaxCohortSize             int    `yaml:"max_cohort_size" json:"max_cohort_size,omitempty"`                   // Largest cohort to extract (0 = no maximum)
+	CohortSizeAction          string `yaml:"cohort_size_action" json:"cohort_size_action,omitempty"`             // "abort" (default) or "prompt" for empty or too large cohorts
+}
+
+// Authentication modes of services.torch.auth_mode
+const (
+	TORCHAuthBasic                   = "basic"
+	TORCHAuthOAuth2ClientCredentials = "oauth2_client_credentials"
+	TORCHAuthBearerToken             = "bearer_token"
+)
+
+// GetAuthMode returns the authentication mode, basic if none is configured
+func (c TORCHConfig) GetAuthMode() string {
+	if c.AuthMode == "" {
+		return TORCHAuthBasic
+	}
+	return c.AuthMode
+}
+
+// Actions of services.torch.cohort_size_action
+const (
+	CohortSizeActionAbort  = "abort"
+	CohortSizeActionPrompt = "prompt"
+)
+
+// Split periods of services.torch.split_period
+const (
+	SplitPeriodMonth   = "month"
+	SplitPeriodQuarter = "quarter"
+	SplitPeriodYear    = "year"
+)
+
+// DefaultSplitConcurrency is how many sub-period extractions of a job run at once by default
+const DefaultSplitConcurrency = 2
+
+// GetSplitConcurrency returns how many sub-period extractions of a job run at once
+func (c TORCHConfig) GetSplitConcurrency() int {
+	if c.SplitConcurrency <= 0 {
+		return DefaultSplitConcurrency
+	}
+	return c.SplitConcurrency
+}
+
+// GetResultCacheTTL returns how long extraction results are reused, or 0 if caching is off
+// Caching is off with cleanup_after_download, which deletes the results it would reuse
+func (c TORCHConfig) GetResultCacheTTL() time.Duration {
+	if c.CleanupAfterDownload || c.ResultCacheTTLMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(c.ResultCacheTTLMinutes) * time.Minute
+}
+
+// DefaultResponseCacheMaxMB is the size limit of the TORCH response cache if none is configured
+const DefaultResponseCacheMaxMB = 16
+
+// GetResponseCacheTTL returns how long TORCH status and file answers are reused, or 0 if off
+func (c TORCHConfig) GetResponseCacheTTL() time.Duration {
+	if c.ResponseCacheSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(c.ResponseCacheSeconds) * time.Second
+}
+
+// GetResponseCacheMaxBytes returns the size limit of the TORCH response cache
+func (c TORCHConfig) GetResponseCacheMaxBytes() int64 {
+	if c.ResponseCacheMaxMB <= 0 {
+		return DefaultResponseCacheMaxMB * 1024 * 1024
+	}
+	return int64(c.ResponseCacheMaxMB) * 1024 * 1024
+}
+
+// FHIRExportConfig contains settings for importing through the FHIR Bulk Data $export operation
+// The server is given by the kick-off URL used as input source; these settings apply to all of them
+type FHIRExportConfig struct {
+	Username                  string   `yaml:"username" json:"username,omitempty"`
+	Password                  string   `yaml:"password" json:"password,omitempty"`
+	BearerToken               string   `yaml:"bearer_token" json:"bearer_token,omitempty"`                                 // Static access token, instead of username and password
+	Types                     []string `yaml:"types" json:"types,omitempty"`                                               // Resource types exported if the kick-off URL has no _type parameter (default: all)
+	TimeoutMinutes            int      `yaml:"timeout_minutes" json:"timeout_minutes,omitempty"`                           // Give up waiting for the export after this long (default 60)
+	PollingIntervalSeconds    int      `yaml:"polling_interval_seconds" json:"polling_interval_seconds,omitempty"`         // First wait between status requests (default 5)
+	MaxPollingIntervalSeconds int      `yaml:"max_polling_interval_seconds" json:"max_polling_interval_seconds,omitempty"` // Longest wait between status requests, also caps Retry-After (default 60)
+	CleanupAfterDownload      bool     `yaml:"cleanup_after_download" json:"cleanup_after_download,omitempty"`             // DELETE the export on the server once its files are imported
+}
+
+// Defaults of services.fhir_export
+const (
+	DefaultFHIRExportTimeoutMinutes            = 60
+	DefaultFHIRExportPollingIntervalSeconds    = 5
+	DefaultFHIRExportMaxPollingIntervalSeconds = 60
+)
+
+// GetTimeout returns how long to wait for an export to complete
+func (c FHIRExportConfig) GetTimeout() time.Duration {
+	if c.TimeoutMinutes <= 0 {
+		return DefaultFHIRExportTimeoutMinutes * time.Minute
+	}
+	return time.Duration(c.TimeoutMinutes) * time.Minute
+}
+
+// GetPollingIntervals returns the first and the longest wait between status requests
+func (c FHIRExportConfig) GetPollingIntervals() (initial, maximum time.Duration) {
+	initial = DefaultFHIRExportPollingIntervalSeconds * time.Second
+	if c.PollingIntervalSeconds > 0 {
+		initial = time.Duration(c.PollingIntervalSeconds) * time.Second
+	}
+	maximum = DefaultFHIRExportMaxPollingIntervalSeconds * time.Second
+	if c.MaxPollingIntervalSeconds > 0 {
+		maximum = time.Duration(c.MaxPollingIntervalSeconds) * time.Second
+	}
+	return initial, max(initial, maximum)
+}
+
+// Validate checks that credentials are complete and durations are not negative
+func (c FHIRExportConfig) Validate() error {
+	if (c.Username == "") != (c.Password == "") {
+		return fmt.Errorf("fhir_export username and password must be set together")
+	}
+	if c.BearerToken != "" && c.Username != "" {
+		return fmt.Errorf("fhir_export takes either bearer_token or username and password, not both")
+	}
+	if c.TimeoutMinutes < 0 || c.PollingIntervalSeconds < 0 || c.MaxPollingIntervalSeconds < 0 {
+		return fmt.Errorf("fhir_export timeout_minutes, polling_interval_seconds and max_polling_interval_seconds must not be negative")
+	}
+	for _, resourceType := range c.Types {
+		if !IsKnownFHIRResourceType(resourceType) {
+			return fmt.Errorf("fhir_export types: unknown resource type '%s'", resourceType)
+		}
+	}
+	return nil
+}
+
+// S3Config contains the connection to S3-compatible object storage (AWS S3, MinIO, Ceph RGW)
+// used by s3:// input sources and the output.s3 sink. Empty credentials, region and endpoint
+// fall back to the standard AWS_* environment variables when a client is created
+type S3Config struct {
+	Endpoint           string `yaml:"endpoint" json:"endpoint,omitempty"`                         // e.g. http://minio:9000 (default: https://s3.<region>.amazonaws.com)
+	Region             string `yaml:"region" json:"region,omitempty"`                             // Region requests are signed for (default us-east-1)
+	AccessKeyID        string `yaml:"access_key_id" json:"access_key_id,omitempty"`               // Falls back to AWS_ACCESS_KEY_ID
+	SecretAccessKey    string `yaml:"secret_access_key" json:"secret_access_key,omitempty"`       // Falls back to AWS_SECRET_ACCESS_KEY
+	SessionToken       string `yaml:"session_token" json:"session_token,omitempty"`               // Temporary credentials; falls back to AWS_SESSION_TOKEN
+	VirtualHostedStyle bool   `yaml:"virtual_hosted_style" json:"virtual_hosted_style,omitempty"` // Address buckets as <bucket>.<host> instead of <host>/<bucket> (path style, as MinIO expects)
+	PartSizeMB         int    `yaml:"part_size_mb" json:"part_size_mb,omitempty"`                 // Larger files are uploaded in parts of this size (default 16, at least 5)
+}
+
+// Defaults and limits of services.s3
+const (
+	DefaultS3Region     = "us-east-1"
+	DefaultS3PartSizeMB = 16
+	MinS3PartSizeMB     = 5    // Smallest part S3 accepts in a multipart upload (except the last)
+	MaxS3PartSizeMB     = 5120 // Largest part S3 accepts
+)
+
+// GetPartSize returns the size above which files are uploaded in parts, and the size of each part
+func (c S3Config) GetPartSize() int64 {
+	if c.PartSizeMB <= 0 {
+		return DefaultS3PartSizeMB * 1024 * 1024
+	}
+	return int64(c.PartSizeMB) * 1024 * 1024
+}
+
+// Validate checks the endpoint, that static credentials are complete and the part size
+func (c S3Config) Validate() error {
+	if c.Endpoint != "" {
+		parsed, err := url.Parse(c.Endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("s3 endpoint must be an HTTP(S) URL, got '%s'", c.Endpoint)
+		}
+	}
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return fmt.Errorf("s3 access_key_id and secret_access_key must be set together")
+	}
+	if c.PartSizeMB != 0 && (c.PartSizeMB < MinS3PartSizeMB || c.PartSizeMB > MaxS3PartSizeMB) {
+		return fmt.Errorf("s3 part_size_mb must be between %d and %d, got %d", MinS3PartSizeMB, MaxS3PartSizeMB, c.PartSizeMB)
+	}
+	return nil
+}
+
+// ParseS3URL splits an s3://bucket/prefix URL into bucket and key prefix (without leading slash)
+func ParseS3URL(rawURL string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(rawURL, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("'%s' is not an s3://bucket/prefix URL", rawURL)
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if !s3BucketPattern.MatchString(bucket) {
+		return "", "", fmt.Errorf("invalid S3 bucket name '%s' in %s", bucket, rawURL)
+	}
+	return bucket, prefix, nil
+}
+
+// s3BucketPattern matches valid bucket names: 3-63 lowercase letters, digits, dots and hyphens
+var s3BucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// OutputConfig contains sinks that receive step outputs in addition to the jobs directory
+type OutputConfig struct {
+	S3 S3OutputConfig `yaml:"s3" json:"s3"`
+}
+
+// S3OutputConfig uploads the output of each completed step to object storage (connection from
+// services.s3). Files are written to <prefix>/<job-id>/<output directory>/<relative path>
+type S3OutputConfig struct {
+	URL   string     `yaml:"url" json:"url,omitempty"`     // s3://bucket/prefix; empty disables the sink
+	Steps []StepName `yaml:"steps" json:"steps,omitempty"` // Steps whose output is uploaded (default: DefaultS3OutputSteps)
+}
+
+// DefaultS3OutputSteps are the steps whose output output.s3 uploads unless steps are configured
+var DefaultS3OutputSteps = []StepName{StepDIMP, StepCSVConversion, StepParquetConversion}
+
+// UploadsStep reports whether the output of step is uploaded
+func (c S3OutputConfig) UploadsStep(step StepName) bool {
+	if c.URL == "" {
+		return false
+	}
+	if len(c.Steps) == 0 {
+		return slices.Contains(DefaultS3OutputSteps, step)
+	}
+	return slices.Contains(c.Steps, step)
+}
+
+// Validate checks the target URL and step names
+func (c S3OutputConfig) Validate() error {
+	if c.URL == "" {
+		if len(c.Steps) > 0 {
+			return fmt.Errorf("output.s3 steps require output.s3 url")
+		}
+		return nil
+	}
+	if _, _, err := ParseS3URL(c.URL); err != nil {
+		return fmt.Errorf("invalid output.s3 url: %w", err)
+	}
+	for _, step := range c.Steps {
+		if !IsValidStepName(step) {
+			return fmt.Errorf("unrecognized step in output.s3 steps: %s", step)
+		}
+		if step == StepValidation {
+			return fmt.Errorf("step %s has no output to upload (output.s3 steps)", step)
+		}
+	}
+	return nil
 }
 
 // PipelineConfig defines which steps are enabled and their execution order
 type PipelineConfig struct {
-	EnabledSteps []StepName `yaml:"enabled_steps" json:"enabled_steps"`
+	EnabledSteps      []StepName                     `yaml:"enabled_steps" json:"enabled_steps"`
+	Packaging         PackagingConfig                `yaml:"packaging" json:"packaging"`
+	MaxRuntimeMinutes int                            `yaml:"max_runtime_minutes" json:"max_runtime_minutes,omitempty"` // Abort a run after this long (0 = no limit)
+	TimeBudget        TimeBudgetConfig               `yaml:"time_budget" json:"time_budget"`                           // Wall-clock budget apportioned across steps
+	PostConditions    map[StepName]StepPostCondition `yaml:"post_conditions" json:"post_conditions,omitempty"`         // Output checks per step, evaluated before the step completes
+	Strict            bool                           `yaml:"strict" json:"strict,omitempty"`                           // Fail steps with warnings of the strict warning classes
+	StrictWarnings    []WarningCode                  `yaml:"strict_warnings" json:"strict_warnings,omitempty"`         // Warning classes escalated by strict (default: DefaultStrictWarnings)
+	DIMP              PipelineDIMPConfig             `yaml:"dimp" json:"dimp"`                                         // How the DIMP step schedules its work
+}
+
+// MaxDIMPParallelism is the largest number of files the DIMP step processes at once
+const MaxDIMPParallelism = 32
+
+// PipelineDIMPConfig controls how the DIMP step schedules its input files
+// (the DIMP service itself is configured under services.dimp)
+type PipelineDIMPConfig struct {
+	Parallelism int `yaml:"parallelism" json:"parallelism,omitempty"` // Files pseudonymized concurrently (default: 1)
+}
+
+// GetParallelism returns the number of files processed concurrently, at least 1
+func (c PipelineDIMPConfig) GetParallelism() int {
+	return max(c.Parallelism, 1)
+}
+
+// Validate checks the parallelism bounds
+func (c PipelineDIMPConfig) Validate() error {
+	if c.Parallelism < 0 || c.Parallelism > MaxDIMPParallelism {
+		return fmt.Errorf("pipeline.dimp.parallelism must be between 1 and %d", MaxDIMPParallelism)
+	}
+	return nil
+}
+
+// GetMaxRuntime returns the maximum runtime of one pipeline run, or 0 for no limit
+func (c PipelineConfig) GetMaxRuntime() time.Duration {
+	if c.MaxRuntimeMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(c.MaxRuntimeMinutes) * time.Minute
 }
 
 // RetryConfig controls retry behavior for transient errors
@@ -81,15 +524,32 @@ func DefaultConfig() ProjectConfig {
 				PollingIntervalSeconds:    5,
 				MaxPollingIntervalSeconds: 30,
 			},
+			HealthCheck: HealthCheckConfig{
+				TimeoutSeconds:  5,
+				CacheTTLSeconds: 60,
+			},
 		},
 		Pipeline: PipelineConfig{
 			EnabledSteps: []StepName{StepLocalImport, StepHttpImport},
+			Packaging: PackagingConfig{
+				Parquet: ParquetOptions{
+					Compression:        ParquetSnappy,
+					DictionaryEncoding: true,
+					TargetFileSizeMB:   DefaultParquetTargetFileSizeMB,
+				},
+			},
 		},
 		Retry: RetryConfig{
 			MaxAttempts:      5,
 			InitialBackoffMs: 1000,
 			MaxBackoffMs:     30000,
 		},
+		Limits: LimitsConfig{
+			MaxLineSizeMB: DefaultMaxLineSizeMB,
+		},
+		Heartbeat: HeartbeatConfig{
+			IntervalSeconds: DefaultHeartbeatIntervalSeconds,
+		},
 		JobsDir: "./jobs",
 	}
 }
@@ -110,7 +570,29 @@ func (c *TORCHConfig) Validate() error {
 		return fmt.Errorf("invalid TORCH base_url: must use http or https scheme, got '%s'", parsedURL.Scheme)
 	}
 
-	// Username and password are optional - TORCH may not require authentication in all environments
+	// Username and password are optional - TORCH may not require authentication in all environments -
+	// but one without the other is a misconfiguration (e.g. an unset ${TORCH_PASSWORD})
+	if (c.Username == "") != (c.Password == "") {
+		return fmt.Errorf("TORCH username and password must be set together")
+	}
+
+	switch c.GetAuthMode() {
+	case TORCHAuthBasic:
+	case TORCHAuthOAuth2ClientCredentials:
+		if c.TokenURL == "" || c.ClientID == "" || c.ClientSecret == "" {
+			return fmt.Errorf("TORCH auth_mode %s requires token_url, client_id and client_secret", TORCHAuthOAuth2ClientCredentials)
+		}
+		if tokenURL, err := url.Parse(c.TokenURL); err != nil || (tokenURL.Scheme != "http" && tokenURL.Scheme != "https") {
+			return fmt.Errorf("invalid TORCH token_url '%s': must be an http or https URL", c.TokenURL)
+		}
+	case TORCHAuthBearerToken:
+		if c.BearerToken == "" {
+			return fmt.Errorf("TORCH auth_mode %s requires bearer_token", TORCHAuthBearerToken)
+		}
+	default:
+		return fmt.Errorf("TORCH auth_mode must be %s, %s or %s, got '%s'",
+			TORCHAuthBasic, TORCHAuthOAuth2ClientCredentials, TORCHAuthBearerToken, c.AuthMode)
+	}
 
 	if c.ExtractionTimeoutMinutes <= 0 {
 		return fmt.Errorf("extraction_timeout_minutes must be > 0, got %d", c.ExtractionTimeoutMinutes)
@@ -125,6 +607,49 @@ func (c *TORCHConfig) Validate() error {
 			c.MaxPollingIntervalSeconds, c.PollingIntervalSeconds)
 	}
 
+	if c.MaxActiveExtractions < 0 {
+		return fmt.Errorf("max_active_extractions must be >= 0, got %d", c.MaxActiveExtractions)
+	}
+
+	if c.ResultCacheTTLMinutes < 0 {
+		return fmt.Errorf("result_cache_ttl_minutes must be >= 0, got %d", c.ResultCacheTTLMinutes)
+	}
+
+	if c.ResponseCacheSeconds < 0 {
+		return fmt.Errorf("response_cache_seconds must be >= 0, got %d", c.ResponseCacheSeconds)
+	}
+
+	if c.ResponseCacheMaxMB < 0 {
+		return fmt.Errorf("response_cache_max_mb must be >= 0, got %d", c.ResponseCacheMaxMB)
+	}
+
+	switch c.SplitPeriod {
+	case "", SplitPeriodMonth, SplitPeriodQuarter, SplitPeriodYear:
+	default:
+		return fmt.Errorf("split_period must be month, quarter or year, got '%s'", c.SplitPeriod)
+	}
+
+	if c.SplitConcurrency < 0 {
+		return fmt.Errorf("split_concurrency must be >= 0, got %d", c.SplitConcurrency)
+	}
+
+	if c.FeasibilityURL != "" {
+		parsedURL, err := url.Parse(c.FeasibilityURL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			return fmt.Errorf("invalid feasibility_url: must be an http or https URL, got '%s'", c.FeasibilityURL)
+		}
+	}
+
+	if c.MaxCohortSize < 0 {
+		return fmt.Errorf("max_cohort_size must be >= 0, got %d", c.MaxCohortSize)
+	}
+
+	switch c.CohortSizeAction {
+	case "", CohortSizeActionAbort, CohortSizeActionPrompt:
+	default:
+		return fmt.Errorf("cohort_size_action must be abort or prompt, got '%s'", c.CohortSizeAction)
+	}
+
 	return nil
 }
 
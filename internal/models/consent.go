@@ -0,0 +1,60 @@
+package models
+
+import "fmt"
+
+// ConsentSource is where each patient's consent validity window is read from
+type ConsentSource string
+
+const (
+	ConsentFromResources ConsentSource = "consent_resources" // Active Consent resources among the imported data
+	ConsentFromCSV       ConsentSource = "csv"               // A CSV file with patient_id,start,end rows
+)
+
+// ConsentMissingPolicy decides what happens to patients without any consent window
+type ConsentMissingPolicy string
+
+const (
+	ConsentMissingDrop ConsentMissingPolicy = "drop" // Remove all of the patient's resources (default)
+	ConsentMissingKeep ConsentMissingPolicy = "keep" // Keep the patient's resources unfiltered
+)
+
+// ConsentFilterConfig removes resources dated outside their patient's consent validity window after import
+// Windows are compared at day precision and include their start and end date; a patient with
+// several windows keeps resources dated in any of them
+type ConsentFilterConfig struct {
+	Source  ConsentSource        `yaml:"source" json:"source,omitempty"`     // consent_resources | csv (empty disables the filter)
+	CSVPath string               `yaml:"csv_path" json:"csv_path,omitempty"` // CSV with a patient_id,start,end header (source csv)
+	Missing ConsentMissingPolicy `yaml:"missing" json:"missing,omitempty"`   // drop | keep patients without a window (default: drop)
+}
+
+// IsActive returns true if the filter is configured, so imports can skip the extra pass otherwise
+func (c ConsentFilterConfig) IsActive() bool {
+	return c.Source != ""
+}
+
+// GetMissing returns the policy for patients without a window, or drop if not set
+func (c ConsentFilterConfig) GetMissing() ConsentMissingPolicy {
+	if c.Missing == "" {
+		return ConsentMissingDrop
+	}
+	return c.Missing
+}
+
+// Validate checks the consent source and missing-window policy
+func (c ConsentFilterConfig) Validate() error {
+	switch c.Source {
+	case "", ConsentFromResources:
+	case ConsentFromCSV:
+		if c.CSVPath == "" {
+			return fmt.Errorf("consent.csv_path is required when consent.source is csv")
+		}
+	default:
+		return fmt.Errorf("invalid consent.source '%s' (must be consent_resources or csv)", c.Source)
+	}
+	switch c.Missing {
+	case "", ConsentMissingDrop, ConsentMissingKeep:
+	default:
+		return fmt.Errorf("invalid consent.missing '%s' (must be drop or keep)", c.Missing)
+	}
+	return nil
+}
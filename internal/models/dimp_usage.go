@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DIMPUsage counts what was sent to the DIMP service
+// Recorded per job and per calendar month, since trust centers bill pseudonymization by volume
+type DIMPUsage struct {
+	Requests  int64 `json:"requests"`  // HTTP requests sent to /$de-identify
+	Resources int64 `json:"resources"` // Resources sent; a Bundle counts its entries
+	Bytes     int64 `json:"bytes"`     // Request body bytes
+}
+
+// Add adds other to the usage
+func (u *DIMPUsage) Add(other DIMPUsage) {
+	u.Requests += other.Requests
+	u.Resources += other.Resources
+	u.Bytes += other.Bytes
+}
+
+// IsZero reports whether nothing was sent
+func (u DIMPUsage) IsZero() bool {
+	return u.Requests == 0 && u.Resources == 0 && u.Bytes == 0
+}
+
+// DIMPQuotaConfig contains the monthly usage quota of the DIMP service (services.dimp.usage_quota)
+// Exceeding it does not stop a job; a dimp_quota warning is recorded once usage reaches warn_percent
+type DIMPQuotaConfig struct {
+	MonthlyResources int64 `yaml:"monthly_resources" json:"monthly_resources,omitempty"` // Resources per calendar month (0 = no limit)
+	MonthlyMB        int64 `yaml:"monthly_mb" json:"monthly_mb,omitempty"`               // Request volume in MB per calendar month (0 = no limit)
+	WarnPercent      int   `yaml:"warn_percent" json:"warn_percent,omitempty"`           // Share of a limit at which to warn (default 80)
+}
+
+// DefaultDIMPQuotaWarnPercent is the share of a quota at which usage is reported by default
+const DefaultDIMPQuotaWarnPercent = 80
+
+// IsActive reports whether a monthly limit is configured
+func (q DIMPQuotaConfig) IsActive() bool {
+	return q.MonthlyResources > 0 || q.MonthlyMB > 0
+}
+
+// GetWarnPercent returns the share of a limit at which usage is reported
+func (q DIMPQuotaConfig) GetWarnPercent() int {
+	if q.WarnPercent <= 0 {
+		return DefaultDIMPQuotaWarnPercent
+	}
+	return q.WarnPercent
+}
+
+// Validate checks the quota limits
+func (q DIMPQuotaConfig) Validate() error {
+	if q.MonthlyResources < 0 || q.MonthlyMB < 0 {
+		return fmt.Errorf("dimp usage_quota limits must not be negative")
+	}
+	if q.WarnPercent < 0 || q.WarnPercent > 100 {
+		return fmt.Errorf("dimp usage_quota warn_percent must be between 1 and 100, got %d", q.WarnPercent)
+	}
+	return nil
+}
+
+// UsedPercent returns the share of the most used monthly limit in percent, 0 without limits
+func (q DIMPQuotaConfig) UsedPercent(usage DIMPUsage) int64 {
+	var percent int64
+	if q.MonthlyResources > 0 {
+		percent = usage.Resources * 100 / q.MonthlyResources
+	}
+	if q.MonthlyMB > 0 {
+		percent = max(percent, usage.Bytes*100/(q.MonthlyMB*1024*1024))
+	}
+	return percent
+}
+
+// Check compares a month's usage against the quota
+// Returns a message naming every limit reached to warn_percent or more, or "" if none is
+func (q DIMPQuotaConfig) Check(usage DIMPUsage) string {
+	var reached []string
+	warnAt := int64(q.GetWarnPercent())
+	if q.MonthlyResources > 0 && usage.Resources*100 >= q.MonthlyResources*warnAt {
+		reached = append(reached, fmt.Sprintf("%d of %d resources (%d%%)",
+			usage.Resources, q.MonthlyResources, usage.Resources*100/q.MonthlyResources))
+	}
+	if limit := q.MonthlyMB * 1024 * 1024; limit > 0 && usage.Bytes*100 >= limit*warnAt {
+		reached = append(reached, fmt.Sprintf("%.1f of %d MB (%d%%)",
+			float64(usage.Bytes)/(1024*1024), q.MonthlyMB, usage.Bytes*100/limit))
+	}
+	if len(reached) == 0 {
+		return ""
+	}
+	return "DIMP usage quota this month: " + strings.Join(reached, ", ")
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// JobEventType categorizes entries in a job's event timeline
+type JobEventType string
+
+const (
+	EventJobCreated       JobEventType = "job_created"
+	EventJobCompleted     JobEventType = "job_completed"
+	EventJobFailed        JobEventType = "job_failed"
+	EventStepStarted      JobEventType = "step_started"
+	EventStepCompleted    JobEventType = "step_completed"
+	EventStepFailed       JobEventType = "step_failed"
+	EventStepCancelled    JobEventType = "step_cancelled"    // The run was stopped (SIGINT, SIGTERM) while the step was running
+	EventFileProcessed    JobEventType = "file_processed"    // e.g., DIMP finished file 12/40
+	EventDownloadFinished JobEventType = "download_finished" // e.g., TORCH download 3/7 finished
+	EventRetryScheduled   JobEventType = "retry_scheduled"
+	EventSLAExceeded      JobEventType = "sla_exceeded"     // Step is still running past its configured SLA
+	EventRepackage        JobEventType = "repackage"        // Packaging steps were reset by `aether repackage`
+	EventRuntimeExceeded  JobEventType = "runtime_exceeded" // The run was aborted after pipeline.max_runtime_minutes
+	EventJobCancelled     JobEventType = "job_cancelled"    // The run was stopped through the API
+	EventTORCHCleanup     JobEventType = "torch_cleanup"    // A downloaded extraction result was deleted on the TORCH server
+	EventTORCHQueued      JobEventType = "torch_queued"     // The extraction waits for a free slot (services.torch.max_active_extractions)
+	EventTORCHCacheHit    JobEventType = "torch_cache_hit"  // The result of an earlier extraction of the same CRTDL was reused
+	EventCohortSize       JobEventType = "cohort_size"      // The CRTDL's cohort was counted before extraction (services.torch.feasibility_url)
+	EventTORCHSplit       JobEventType = "torch_split"      // The extraction was split into one per period (services.torch.split_period)
+	EventDIMPProgress     JobEventType = "dimp_progress"    // Periodic progress within a file being pseudonymized (services.dimp.progress_interval_seconds)
+	EventStepWarning      JobEventType = "step_warning"     // A non-fatal issue was recorded on the step (see PipelineStep.Warnings)
+	EventDIMPCacheHit     JobEventType = "dimp_cache_hit"   // The output of an earlier run on an identical file was reused (services.dimp.output_cache_ttl_minutes)
+	EventJobInterrupted   JobEventType = "job_interrupted"  // The process running the job ended unexpectedly; found and reconciled by RecoverJob
+	EventSplitAdjusted    JobEventType = "split_adjusted"   // DIMP rejected a Bundle request as too large or too slow and the split threshold was lowered (services.dimp.adaptive_split)
+	EventOutputUploaded   JobEventType = "output_uploaded"  // The output of a completed step was uploaded to object storage (output.s3)
+)
+
+// JobEvent is a single entry in a job's append-only event timeline (events.ndjson)
+// Unlike step state, events are never overwritten, so they record exactly when things happened
+type JobEvent struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Type      JobEventType   `json:"type"`
+	Step      string         `json:"step,omitempty"`
+	Message   string         `json:"message"`
+	Fields    map[string]any `json:"fields,omitempty"` // Structured details (file, index, total, backoff_ms, ...)
+}
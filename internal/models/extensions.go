@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExtensionFilterConfig filters FHIR extensions by URL after import
+// Vendor-specific extensions add schema noise when resources are flattened to CSV/Parquet.
+// Patterns are exact URLs or prefixes ending in '*', e.g.
+// "https://www.medizininformatik-initiative.de/fhir/*"
+type ExtensionFilterConfig struct {
+	Allow []string `yaml:"allow" json:"allow,omitempty"` // Keep only extensions matching one of these (empty = keep all)
+	Deny  []string `yaml:"deny" json:"deny,omitempty"`   // Drop extensions matching one of these (wins over allow)
+}
+
+// IsActive returns true if any filter is configured, so imports can skip the extra pass otherwise
+func (c ExtensionFilterConfig) IsActive() bool {
+	return len(c.Allow) > 0 || len(c.Deny) > 0
+}
+
+// Keeps returns true if an extension with the given URL passes the filter
+func (c ExtensionFilterConfig) Keeps(url string) bool {
+	if matchesAnyURLPattern(url, c.Deny) {
+		return false
+	}
+	return len(c.Allow) == 0 || matchesAnyURLPattern(url, c.Allow)
+}
+
+// matchesAnyURLPattern returns true if url equals a pattern or starts with a pattern's prefix before '*'
+func matchesAnyURLPattern(url string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, isPrefix := strings.CutSuffix(pattern, "*"); isPrefix {
+			if strings.HasPrefix(url, prefix) {
+				return true
+			}
+		} else if url == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the extension patterns
+func (c ExtensionFilterConfig) Validate() error {
+	for _, list := range []struct {
+		name     string
+		patterns []string
+	}{{"allow", c.Allow}, {"deny", c.Deny}} {
+		for i, pattern := range list.patterns {
+			if pattern == "" {
+				return fmt.Errorf("extensions.%s[%d] must not be empty", list.name, i)
+			}
+			if strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+				return fmt.Errorf("invalid extensions.%s pattern '%s': '*' is only allowed at the end", list.name, pattern)
+			}
+		}
+	}
+	return nil
+}
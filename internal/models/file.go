@@ -8,13 +8,15 @@ import (
 
 // FHIRDataFile represents a single FHIR NDJSON file in the pipeline
 type FHIRDataFile struct {
-	FileName     string    `json:"file_name"`
-	FilePath     string    `json:"file_path"`     // Relative to job directory
-	ResourceType string    `json:"resource_type"` // e.g., "Patient", "Observation"
-	FileSize     int64     `json:"file_size"`     // Bytes
-	SourceStep   StepName  `json:"source_step"`   // Which step produced this file
-	LineCount    int       `json:"line_count"`    // Number of FHIR resources
-	CreatedAt    time.Time `json:"created_at"`
+	FileName      string    `json:"file_name"`
+	FilePath      string    `json:"file_path"`                // Relative to job directory
+	ResourceType  string    `json:"resource_type"`            // e.g., "Patient", "Observation", or "mixed"
+	ResourceTypes []string  `json:"resource_types,omitempty"` // Resource types found in a mixed file
+	FileSize      int64     `json:"file_size"`                // Bytes
+	SourceStep    StepName  `json:"source_step"`              // Which step produced this file
+	LineCount     int       `json:"line_count"`               // Number of FHIR resources
+	Source        string    `json:"source,omitempty"`         // Input source the file was imported from (provenance)
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 // IsValidFHIRFile checks if the file has valid FHIR NDJSON format
@@ -0,0 +1,291 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FlattenConfig contains settings for flattening FHIR resources into tables
+type FlattenConfig struct {
+	MappingFile  string                 `yaml:"mapping_file" json:"mapping_file,omitempty"` // Project column mapping; overrides the shipped defaults per resource type
+	Observations ObservationPivotConfig `yaml:"observations" json:"observations"`           // Long/wide shape of the Observation table
+}
+
+// Validate checks the flattening settings
+// The mapping file itself is parsed and compiled by the services layer
+func (c FlattenConfig) Validate() error {
+	return c.Observations.Validate()
+}
+
+// ColumnClassification tells downstream teams how to handle a column's values
+type ColumnClassification string
+
+const (
+	ColumnIdentifier ColumnClassification = "identifier" // Resource IDs, references, business identifiers (pseudonymized by DIMP)
+	ColumnClinical   ColumnClassification = "clinical"   // Clinical values, codes, dates
+)
+
+// FlattenColumn is one output column: a name and the FHIRPath expression producing its value
+type FlattenColumn struct {
+	Name           string               `json:"name"`
+	Path           string               `json:"path"`
+	Classification ColumnClassification `json:"classification,omitempty"` // Empty = derived from the path
+}
+
+// GetClassification returns the configured classification, or derives it from the path:
+// columns ending in id or reference, or reading an identifier, are identifiers
+func (c FlattenColumn) GetClassification() ColumnClassification {
+	if c.Classification != "" {
+		return c.Classification
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(c.Path, ".") {
+		// Drop function calls such as first() or where(...)
+		if name, _, isCall := strings.Cut(segment, "("); isCall {
+			if name == "where" || name == "first" || name == "exists" {
+				continue
+			}
+			segment = name
+		}
+		segment = strings.TrimSpace(segment)
+		if segment == "" || strings.ContainsAny(segment, " ')=") {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+
+	for _, segment := range segments {
+		if segment == "identifier" {
+			return ColumnIdentifier
+		}
+	}
+	if len(segments) > 0 {
+		last := segments[len(segments)-1]
+		if last == "id" || last == "reference" {
+			return ColumnIdentifier
+		}
+	}
+	return ColumnClinical
+}
+
+// FlattenMapping maps a resource type to its ordered table columns
+// In YAML each column is a single-key map, so the order of the CRF is preserved:
+//
+//	Patient:
+//	  - patient_id: id
+//	  - birth_date: birthDate
+type FlattenMapping map[string][]FlattenColumn
+
+// UnmarshalYAML reads a column written as `name: path` or `name: {path: ..., classification: ...}`
+func (c *FlattenColumn) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw map[string]any
+	if err := unmarshal(&raw); err != nil {
+		return fmt.Errorf("a column must be written as 'name: FHIRPath': %w", err)
+	}
+	if len(raw) != 1 {
+		return fmt.Errorf("a column must be written as 'name: FHIRPath', got %d keys", len(raw))
+	}
+	for name, value := range raw {
+		c.Name = name
+		switch v := value.(type) {
+		case string:
+			c.Path = v
+		case map[string]any:
+			c.Path, _ = v["path"].(string)
+			classification, _ := v["classification"].(string)
+			c.Classification = ColumnClassification(classification)
+		default:
+			return fmt.Errorf("column '%s' must be a FHIRPath or {path, classification}", name)
+		}
+	}
+	return nil
+}
+
+// Merge returns a copy of m where every resource type in override replaces the one in m
+// An override with no columns removes the table
+func (m FlattenMapping) Merge(override FlattenMapping) FlattenMapping {
+	merged := make(FlattenMapping, len(m)+len(override))
+	for resourceType, columns := range m {
+		merged[resourceType] = columns
+	}
+	for resourceType, columns := range override {
+		if len(columns) == 0 {
+			delete(merged, resourceType)
+			continue
+		}
+		merged[resourceType] = columns
+	}
+	return merged
+}
+
+// ColumnNames returns the column names of a resource type's table, or nil if it has none
+func (m FlattenMapping) ColumnNames(resourceType string) []string {
+	columns := m[resourceType]
+	if columns == nil {
+		return nil
+	}
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// ResourceTypes returns the mapped resource types in sorted order
+func (m FlattenMapping) ResourceTypes() []string {
+	types := make([]string, 0, len(m))
+	for resourceType := range m {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks that every column has a name and a path and that names are unique per table
+// The FHIRPath expressions themselves are compiled when the flattener is built
+func (m FlattenMapping) Validate() error {
+	for _, resourceType := range m.ResourceTypes() {
+		seen := map[string]bool{}
+		for i, column := range m[resourceType] {
+			if column.Name == "" {
+				return fmt.Errorf("mapping %s column %d: name is required", resourceType, i+1)
+			}
+			if column.Path == "" {
+				return fmt.Errorf("mapping %s column '%s': FHIRPath is required", resourceType, column.Name)
+			}
+			switch column.Classification {
+			case "", ColumnIdentifier, ColumnClinical:
+			default:
+				return fmt.Errorf("mapping %s column '%s': invalid classification '%s' (must be identifier or clinical)", resourceType, column.Name, column.Classification)
+			}
+			if seen[column.Name] {
+				return fmt.Errorf("mapping %s: duplicate column '%s'", resourceType, column.Name)
+			}
+			seen[column.Name] = true
+		}
+	}
+	return nil
+}
+
+// ObservationFormat selects the shape of the Observation table
+type ObservationFormat string
+
+const (
+	ObservationFormatLong ObservationFormat = "long" // One row per Observation (default)
+	ObservationFormatWide ObservationFormat = "wide" // One row per group, one column per configured code
+	ObservationFormatBoth ObservationFormat = "both" // Long and wide tables
+)
+
+// Default columns of the long Observation table used by the pivot (see mappings/mii-kds.yaml)
+const (
+	DefaultPivotCodeColumn  = "loinc_code"
+	DefaultPivotValueColumn = "value"
+)
+
+// DefaultPivotGroupBy identifies a wide row: one row per patient and point in time
+var DefaultPivotGroupBy = []string{"patient", "effective"}
+
+// ObservationPivotConfig controls pivoting Observations by code into a wide table
+// The pivot reads the long Observation table, so group, code and value columns refer to its column names
+type ObservationPivotConfig struct {
+	Format      ObservationFormat `yaml:"format" json:"format"`                              // long | wide | both
+	GroupBy     []string          `yaml:"group_by" json:"group_by,omitempty"`                // Long columns identifying a wide row (default: patient, effective)
+	CodeColumn  string            `yaml:"code_column" json:"code_column,omitempty"`          // Long column holding the code (default: loinc_code)
+	ValueColumn string            `yaml:"value_column" json:"value_column,omitempty"`        // Long column holding the value (default: value)
+	Codes       []PivotCode       `yaml:"codes" json:"codes,omitempty" mapstructure:"codes"` // One wide column per code, in this order
+}
+
+// PivotCode maps an Observation code to a wide column
+type PivotCode struct {
+	Code   string `yaml:"code" json:"code" mapstructure:"code"`
+	Column string `yaml:"column" json:"column" mapstructure:"column"` // Column name (default: the code)
+}
+
+// ColumnName returns the wide column name for the code
+func (p PivotCode) ColumnName() string {
+	if p.Column != "" {
+		return p.Column
+	}
+	return p.Code
+}
+
+// WantsLong returns true if the long Observation table is written
+func (c ObservationPivotConfig) WantsLong() bool {
+	return c.Format != ObservationFormatWide
+}
+
+// WantsWide returns true if the wide Observation table is written
+func (c ObservationPivotConfig) WantsWide() bool {
+	return c.Format == ObservationFormatWide || c.Format == ObservationFormatBoth
+}
+
+// GetGroupBy returns the group columns, or the default if not configured
+func (c ObservationPivotConfig) GetGroupBy() []string {
+	if len(c.GroupBy) > 0 {
+		return c.GroupBy
+	}
+	return DefaultPivotGroupBy
+}
+
+// GetCodeColumn returns the code column, or the default if not configured
+func (c ObservationPivotConfig) GetCodeColumn() string {
+	if c.CodeColumn != "" {
+		return c.CodeColumn
+	}
+	return DefaultPivotCodeColumn
+}
+
+// GetValueColumn returns the value column, or the default if not configured
+func (c ObservationPivotConfig) GetValueColumn() string {
+	if c.ValueColumn != "" {
+		return c.ValueColumn
+	}
+	return DefaultPivotValueColumn
+}
+
+// Validate checks the pivot settings
+func (c ObservationPivotConfig) Validate() error {
+	switch c.Format {
+	case "", ObservationFormatLong, ObservationFormatWide, ObservationFormatBoth:
+	default:
+		return fmt.Errorf("invalid flatten.observations.format '%s' (must be long, wide, or both)", c.Format)
+	}
+	if !c.WantsWide() {
+		return nil
+	}
+	if len(c.Codes) == 0 {
+		return fmt.Errorf("flatten.observations.codes is required for the wide format")
+	}
+
+	seen := map[string]bool{}
+	for _, column := range c.GetGroupBy() {
+		seen[column] = true
+	}
+	for i, code := range c.Codes {
+		if code.Code == "" {
+			return fmt.Errorf("flatten.observations.codes[%d]: code is required", i)
+		}
+		if seen[code.ColumnName()] {
+			return fmt.Errorf("flatten.observations.codes[%d]: duplicate column '%s'", i, code.ColumnName())
+		}
+		seen[code.ColumnName()] = true
+	}
+	return nil
+}
+
+// ValidateAgainst checks that the group, code and value columns exist in the long Observation table
+func (c ObservationPivotConfig) ValidateAgainst(longColumns []string) error {
+	available := map[string]bool{}
+	for _, column := range longColumns {
+		available[column] = true
+	}
+	required := append(append([]string{}, c.GetGroupBy()...), c.GetCodeColumn(), c.GetValueColumn())
+	for _, column := range required {
+		if !available[column] {
+			return fmt.Errorf("flatten.observations: column '%s' is not in the Observation mapping", column)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// HealthCheckCacheFileName is the file in jobs_dir that stores recent successful connectivity checks
+const HealthCheckCacheFileName = ".healthcheck_cache.json"
+
+// HealthCheckConfig controls service connectivity checks performed before a pipeline starts
+type HealthCheckConfig struct {
+	TimeoutSeconds  int `yaml:"timeout_seconds" json:"timeout_seconds"`     // Per-service check timeout (default 5s)
+	CacheTTLSeconds int `yaml:"cache_ttl_seconds" json:"cache_ttl_seconds"` // How long a successful check is reused (0 disables caching)
+}
+
+// ServiceCheck describes a single service endpoint to verify
+type ServiceCheck struct {
+	Name string // Human-readable service name (e.g., "DIMP")
+	URL  string // Base URL checked (scheme://host)
+}
+
+// healthCheckCacheEntry records when a service endpoint was last confirmed reachable
+type healthCheckCacheEntry struct {
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// GetTimeout returns the per-service check timeout, falling back to 5 seconds
+func (h HealthCheckConfig) GetTimeout() time.Duration {
+	if h.TimeoutSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// GetCacheTTL returns how long successful checks remain valid
+func (h HealthCheckConfig) GetCacheTTL() time.Duration {
+	if h.CacheTTLSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(h.CacheTTLSeconds) * time.Second
+}
+
+// RequiredServiceChecks returns the external services needed by the given steps
+// Only services backing one of the steps are returned; unconfigured URLs are skipped
+// because missing URLs are already reported by Validate()
+func (c *ProjectConfig) RequiredServiceChecks(steps []StepName) ([]ServiceCheck, error) {
+	var checks []ServiceCheck
+	seen := make(map[string]bool)
+
+	for _, step := range steps {
+		var serviceURL string
+		var serviceName string
+
+		switch step {
+		case StepTorchImport:
+			serviceURL = c.Services.TORCH.BaseURL
+			serviceName = "TORCH"
+		case StepDIMP:
+			serviceURL = c.Services.DIMP.URL
+			serviceName = "DIMP"
+		case StepCSVConversion:
+			serviceURL = c.Services.CSVConversion.URL
+			serviceName = "CSV Conversion"
+		case StepParquetConversion:
+			if c.Pipeline.Packaging.WritesParquetLocally() {
+				continue // Written in-process
+			}
+			serviceURL = c.Services.ParquetConversion.URL
+			serviceName = "Parquet Conversion"
+		case StepImaging:
+			serviceURL = c.Services.Imaging.DICOMwebURL
+			serviceName = "DICOMweb"
+		default:
+			continue // Step doesn't require an external service
+		}
+
+		if serviceURL == "" {
+			continue
+		}
+
+		parsedURL, err := url.Parse(serviceURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s service URL: %w", serviceName, err)
+		}
+
+		// Just the base (scheme + host) - we're checking connectivity, not service health
+		checkURL := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
+		if seen[serviceName+checkURL] {
+			continue
+		}
+		seen[serviceName+checkURL] = true
+
+		checks = append(checks, ServiceCheck{Name: serviceName, URL: checkURL})
+	}
+
+	return checks, nil
+}
+
+// ValidateServiceConnectivityForSteps checks that the services required by the given steps are reachable
+// Checks run concurrently, each with its own timeout. Successful checks are cached in
+// jobs_dir for the configured TTL so repeated invocations don't ping services again.
+func (c *ProjectConfig) ValidateServiceConnectivityForSteps(steps []StepName) error {
+	checks, err := c.RequiredServiceChecks(steps)
+	if err != nil {
+		return err
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+
+	ttl := c.Services.HealthCheck.GetCacheTTL()
+	cachePath := filepath.Join(c.JobsDir, HealthCheckCacheFileName)
+
+	cache := map[string]healthCheckCacheEntry{}
+	if ttl > 0 {
+		cache = loadHealthCheckCache(cachePath)
+	}
+
+	client := &http.Client{
+		Timeout: c.Services.HealthCheck.GetTimeout(),
+	}
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		if entry, ok := cache[check.URL]; ok && time.Since(entry.CheckedAt) < ttl {
+			continue // Recently confirmed reachable
+		}
+
+		wg.Add(1)
+		go func(i int, check ServiceCheck) {
+			defer wg.Done()
+			errs[i] = pingService(client, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	// Report the first failure in step order for deterministic output
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	if ttl > 0 {
+		now := time.Now()
+		for i, check := range checks {
+			if errs[i] == nil {
+				if entry, ok := cache[check.URL]; !ok || time.Since(entry.CheckedAt) >= ttl {
+					cache[check.URL] = healthCheckCacheEntry{CheckedAt: now}
+				}
+			}
+		}
+		// Cache is an optimization only - ignore write failures
+		_ = saveHealthCheckCache(cachePath, cache)
+	}
+
+	return nil
+}
+
+// pingService performs a lightweight HEAD request against a service base URL
+// Any response (even 404) means the host is reachable
+func pingService(client *http.Client, check ServiceCheck) error {
+	req, err := http.NewRequest("HEAD", check.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s service: %w", check.Name, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s service unreachable at %s: %w", check.Name, check.URL, err)
+	}
+	_ = resp.Body.Close()
+
+	return nil
+}
+
+// loadHealthCheckCache reads the connectivity cache, returning an empty cache on any error
+func loadHealthCheckCache(path string) map[string]healthCheckCacheEntry {
+	cache := map[string]healthCheckCacheEntry{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return map[string]healthCheckCacheEntry{}
+	}
+
+	return cache
+}
+
+// saveHealthCheckCache writes the connectivity cache atomically
+func saveHealthCheckCache(path string, cache map[string]healthCheckCacheEntry) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, path)
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultHeartbeatIntervalSeconds is how often the heartbeat file is refreshed by default
+const DefaultHeartbeatIntervalSeconds = 30
+
+// HeartbeatConfig controls the heartbeat file written while a job is executing
+// External monitoring (e.g., a cron job checking the file's age) can detect wedged processes
+type HeartbeatConfig struct {
+	IntervalSeconds int  `yaml:"interval_seconds" json:"interval_seconds"` // Refresh interval (default 30, 0 = disabled)
+	Global          bool `yaml:"global" json:"global"`                     // Also write <jobs_dir>/heartbeat for "is any aether alive" checks
+}
+
+// GetInterval returns the refresh interval, or 0 if heartbeats are disabled
+func (c HeartbeatConfig) GetInterval() time.Duration {
+	if c.IntervalSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(c.IntervalSeconds) * time.Second
+}
+
+// Validate checks the heartbeat interval
+func (c HeartbeatConfig) Validate() error {
+	if c.IntervalSeconds < 0 {
+		return errors.New("heartbeat.interval_seconds must not be negative")
+	}
+	return nil
+}
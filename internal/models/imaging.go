@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ImagingConfig contains settings for the imaging step
+// The step recognizes ImagingStudy resources, fetches their DICOM metadata (no pixel data)
+// from a DICOMweb server and rewrites WADO endpoint addresses
+type ImagingConfig struct {
+	DICOMwebURL           string        `yaml:"dicomweb_url" json:"dicomweb_url"`                         // WADO-RS base URL for metadata retrieval (empty = rewrite only)
+	WADORewrites          []WADORewrite `yaml:"wado_rewrite" json:"wado_rewrite,omitempty"`               // Prefix rewrites applied to Endpoint.address
+	FailOnMissingMetadata bool          `yaml:"fail_on_missing_metadata" json:"fail_on_missing_metadata"` // Fail the step if a study's metadata cannot be retrieved
+}
+
+// WADORewrite replaces the From prefix of an endpoint address with To
+// Used to point exported data at the recipient's PACS proxy instead of the internal archive
+type WADORewrite struct {
+	From string `yaml:"from" json:"from"`
+	To   string `yaml:"to" json:"to"`
+}
+
+// RewriteAddress applies the first matching rewrite to an endpoint address
+// Returns the address unchanged if no rewrite matches
+func (c ImagingConfig) RewriteAddress(address string) (string, bool) {
+	for _, rewrite := range c.WADORewrites {
+		if strings.HasPrefix(address, rewrite.From) {
+			return rewrite.To + strings.TrimPrefix(address, rewrite.From), true
+		}
+	}
+	return address, false
+}
+
+// Validate checks the imaging settings
+func (c ImagingConfig) Validate() error {
+	if c.DICOMwebURL != "" {
+		parsedURL, err := url.Parse(c.DICOMwebURL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			return fmt.Errorf("invalid imaging dicomweb_url '%s': must be an http(s) URL", c.DICOMwebURL)
+		}
+	}
+	for i, rewrite := range c.WADORewrites {
+		if rewrite.From == "" {
+			return fmt.Errorf("imaging wado_rewrite[%d]: from must not be empty", i)
+		}
+	}
+	if c.FailOnMissingMetadata && c.DICOMwebURL == "" {
+		return fmt.Errorf("imaging fail_on_missing_metadata requires dicomweb_url")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// ImportLinkMode controls how local import places source files into the job's import directory
+type ImportLinkMode string
+
+const (
+	ImportLinkCopy     ImportLinkMode = "copy"     // Copy file contents (default)
+	ImportLinkHardlink ImportLinkMode = "hardlink" // Hardlink; falls back to copy across filesystems
+	ImportLinkSymlink  ImportLinkMode = "symlink"  // Symlink to the absolute source path
+)
+
+// IsValid returns true if the mode is recognized (empty means the default, copy)
+func (m ImportLinkMode) IsValid() bool {
+	switch m {
+	case "", ImportLinkCopy, ImportLinkHardlink, ImportLinkSymlink:
+		return true
+	}
+	return false
+}
+
+// ResourceTypeDetection controls how the resource type of an imported file is determined
+type ResourceTypeDetection string
+
+const (
+	ResourceTypeFromContent  ResourceTypeDetection = "content"  // Sample the first lines, fall back to the file name (default)
+	ResourceTypeFromFilename ResourceTypeDetection = "filename" // Only the file name (Patient_001.ndjson -> Patient)
+)
+
+// ResourceTypeMixed is the resource type of a file holding more than one resource type
+const ResourceTypeMixed = "mixed"
+
+// DefaultResourceTypeSampleLines is how many resources are sampled without resource_type_sample_lines
+const DefaultResourceTypeSampleLines = 100
+
+// IsValid returns true if the mode is recognized (empty means the default, content)
+func (d ResourceTypeDetection) IsValid() bool {
+	switch d {
+	case "", ResourceTypeFromContent, ResourceTypeFromFilename:
+		return true
+	}
+	return false
+}
+
+// ImportConfig contains settings for the import steps
+type ImportConfig struct {
+	LinkMode ImportLinkMode `yaml:"link_mode" json:"link_mode"`                 // copy | hardlink | symlink (local import only)
+	Include  []string       `yaml:"include,omitempty" json:"include,omitempty"` // Only import files matching one of these patterns (local import only)
+	Exclude  []string       `yaml:"exclude,omitempty" json:"exclude,omitempty"` // Skip files matching any of these patterns (local import only)
+
+	ResourceTypeDetection   ResourceTypeDetection `yaml:"resource_type_detection,omitempty" json:"resource_type_detection,omitempty"`       // content | filename
+	ResourceTypeSampleLines int                   `yaml:"resource_type_sample_lines,omitempty" json:"resource_type_sample_lines,omitempty"` // Resources read per file for content detection
+}
+
+// GetResourceTypeSampleLines returns the number of resources sampled per file, applying the default
+func (c ImportConfig) GetResourceTypeSampleLines() int {
+	if c.ResourceTypeSampleLines <= 0 {
+		return DefaultResourceTypeSampleLines
+	}
+	return c.ResourceTypeSampleLines
+}
+
+// Validate checks the import settings
+func (c ImportConfig) Validate() error {
+	if !c.LinkMode.IsValid() {
+		return fmt.Errorf("invalid import link_mode '%s' (must be copy, hardlink, or symlink)", c.LinkMode)
+	}
+	for _, pattern := range append(append([]string{}, c.Include...), c.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid import include/exclude pattern '%s': %w", pattern, err)
+		}
+	}
+	if !c.ResourceTypeDetection.IsValid() {
+		return fmt.Errorf("invalid import resource_type_detection '%s' (must be content or filename)", c.ResourceTypeDetection)
+	}
+	if c.ResourceTypeSampleLines < 0 {
+		return fmt.Errorf("import resource_type_sample_lines must be >= 0, got %d", c.ResourceTypeSampleLines)
+	}
+	return nil
+}
+
+// HasFilters returns true if include or exclude patterns are configured
+func (c ImportConfig) HasFilters() bool {
+	return len(c.Include) > 0 || len(c.Exclude) > 0
+}
+
+// Includes reports whether a file passes the include/exclude patterns
+// Patterns are shell globs matched against the file name (Patient_001.ndjson) and against
+// the resource type derived from it (Patient). Exclude wins over include; without include
+// patterns every file is included
+func (c ImportConfig) Includes(fileName string) bool {
+	resourceType := GetResourceTypeFromFilename(fileName)
+	if matchesAnyPattern(c.Exclude, fileName, resourceType) {
+		return false
+	}
+	return len(c.Include) == 0 || matchesAnyPattern(c.Include, fileName, resourceType)
+}
+
+// matchesAnyPattern returns true if any pattern matches one of the names
+func matchesAnyPattern(patterns []string, names ...string) bool {
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,32 +1,113 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // PipelineJob represents a single execution of the Data Use Process pipeline
 type PipelineJob struct {
-	JobID              string         `json:"job_id"`
-	CreatedAt          time.Time      `json:"created_at"`
-	UpdatedAt          time.Time      `json:"updated_at"`
-	InputSource        string         `json:"input_source"`                   // Local path, HTTP(S) URL, or CRTDL file
-	InputType          InputType      `json:"input_type"`                     // "local_directory" | "http_url" | "crtdl_file" | "torch_result_url"
-	TORCHExtractionURL string         `json:"torch_extraction_url,omitempty"` // Content-Location URL for TORCH polling/resume
-	CurrentStep        string         `json:"current_step"`                   // Current pipeline step
-	Status             JobStatus      `json:"status"`                         // Job execution status
-	Steps              []PipelineStep `json:"steps"`                          // Ordered list of pipeline steps
-	Config             ProjectConfig  `json:"config"`                         // Project configuration snapshot
-	TotalFiles         int            `json:"total_files"`                    // Total FHIR files processed
-	TotalBytes         int64          `json:"total_bytes"`                    // Total data volume in bytes
-	ErrorMessage       string         `json:"error_message,omitempty"`        // Last error if failed
+	JobID              string            `json:"job_id"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+	InputSource        string            `json:"input_source"`                   // Local path, HTTP(S) URL, or CRTDL file
+	InputType          InputType         `json:"input_type"`                     // "local_directory" | "http_url" | "crtdl_file" | "torch_result_url" | "fhir_search" | "fhir_bulk_export" | "s3_url"
+	TORCHExtractionURL string            `json:"torch_extraction_url,omitempty"` // Content-Location URL for TORCH polling/resume
+	CurrentStep        string            `json:"current_step"`                   // Current pipeline step
+	Status             JobStatus         `json:"status"`                         // Job execution status
+	Steps              []PipelineStep    `json:"steps"`                          // Ordered list of pipeline steps
+	Config             ProjectConfig     `json:"config"`                         // Project configuration snapshot
+	TotalFiles         int               `json:"total_files"`                    // Total FHIR files processed
+	TotalBytes         int64             `json:"total_bytes"`                    // Total data volume in bytes
+	ErrorMessage       string            `json:"error_message,omitempty"`        // Last error if failed
+	ExtraSources       []InputSource     `json:"extra_sources,omitempty"`        // Additional sources imported alongside InputSource
+	ImportedFiles      []FHIRDataFile    `json:"imported_files,omitempty"`       // File inventory of the import step, with per-source provenance
+	ParentJobs         []string          `json:"parent_jobs,omitempty"`          // Jobs whose output this job was created from (--from-job)
+	CohortSize         *int              `json:"cohort_size,omitempty"`          // Patients in the CRTDL's cohort (services.torch.feasibility_url)
+	Annotations        *JobAnnotations   `json:"annotations,omitempty"`          // Study of a CRTDL job, from its cohortDefinition
+	Labels             map[string]string `json:"labels,omitempty"`               // Free-form labels, e.g. from the job template
+	Template           *JobTemplate      `json:"template,omitempty"`             // Template the job was created from (aether run --template)
+	DIMPUsage          *DIMPUsage        `json:"dimp_usage,omitempty"`           // Requests, resources and bytes sent to DIMP
+}
+
+// InLocation returns a copy of the job whose timestamps, including those of its steps, are in loc
+// The job state is written through it, so state.json is in the configured time zone
+func (j PipelineJob) InLocation(loc *time.Location) PipelineJob {
+	j.CreatedAt = j.CreatedAt.In(loc)
+	j.UpdatedAt = j.UpdatedAt.In(loc)
+
+	steps := make([]PipelineStep, len(j.Steps))
+	for i, step := range j.Steps {
+		if step.StartedAt != nil {
+			startedAt := step.StartedAt.In(loc)
+			step.StartedAt = &startedAt
+		}
+		if step.CompletedAt != nil {
+			completedAt := step.CompletedAt.In(loc)
+			step.CompletedAt = &completedAt
+		}
+		if step.LastError != nil {
+			lastError := *step.LastError
+			lastError.Timestamp = lastError.Timestamp.In(loc)
+			step.LastError = &lastError
+		}
+		if step.Warnings != nil {
+			warnings := make([]StepWarning, len(step.Warnings))
+			for k, warning := range step.Warnings {
+				warning.Timestamp = warning.Timestamp.In(loc)
+				warnings[k] = warning
+			}
+			step.Warnings = warnings
+		}
+		steps[i] = step
+	}
+	if j.Steps != nil {
+		j.Steps = steps
+	}
+	return j
+}
+
+// JobAnnotations identify the study a job belongs to
+// Read from the display and version fields of a CRTDL's cohortDefinition when the job is created
+type JobAnnotations struct {
+	Display string `json:"display,omitempty"` // Study or cohort name
+	Version string `json:"version,omitempty"` // Version of the cohort definition
+}
+
+// Label returns the study name with its version, e.g. "Diabetes 2024 (v2)", or "" without a name
+func (a *JobAnnotations) Label() string {
+	if a == nil || a.Display == "" {
+		return ""
+	}
+	if a.Version == "" {
+		return a.Display
+	}
+	return fmt.Sprintf("%s (%s)", a.Display, a.Version)
+}
+
+// InputSource is one input of a job together with its detected type
+type InputSource struct {
+	Source string    `json:"source"`
+	Type   InputType `json:"type"`
+}
+
+// IsValidExtraSourceType checks if an input type may be used as an additional source
+// CRTDL files are excluded: a job tracks a single TORCH extraction for resumption
+func IsValidExtraSourceType(t InputType) bool {
+	return t == InputTypeLocal || t == InputTypeHTTP || t == InputTypeTORCHURL || t == InputTypeFHIRSearch || t == InputTypeBulkExport || t == InputTypeS3
 }
 
 // InputType defines the source type for FHIR data
 type InputType string
 
 const (
-	InputTypeLocal    InputType = "local_directory"
-	InputTypeHTTP     InputType = "http_url"
-	InputTypeCRTDL    InputType = "crtdl_file"
-	InputTypeTORCHURL InputType = "torch_result_url"
+	InputTypeLocal      InputType = "local_directory"
+	InputTypeHTTP       InputType = "http_url"
+	InputTypeCRTDL      InputType = "crtdl_file"
+	InputTypeTORCHURL   InputType = "torch_result_url"
+	InputTypeFHIRSearch InputType = "fhir_search"      // FHIR search URL, e.g. .../Observation?category=laboratory
+	InputTypeBulkExport InputType = "fhir_bulk_export" // FHIR Bulk Data kick-off URL, e.g. .../Patient/$export
+	InputTypeS3         InputType = "s3_url"           // NDJSON objects under an S3 prefix, e.g. s3://staging/study-42/
 )
 
 // JobStatus defines the execution state of a pipeline job
@@ -41,7 +122,7 @@ const (
 
 // IsValidInputType checks if the input type is recognized
 func IsValidInputType(t InputType) bool {
-	return t == InputTypeLocal || t == InputTypeHTTP || t == InputTypeCRTDL || t == InputTypeTORCHURL
+	return t == InputTypeLocal || t == InputTypeHTTP || t == InputTypeCRTDL || t == InputTypeTORCHURL || t == InputTypeFHIRSearch || t == InputTypeBulkExport || t == InputTypeS3
 }
 
 // IsValidJobStatus checks if the job status is recognized
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JobMetadataMode controls how identifying job metadata is persisted in state.json and reports
+type JobMetadataMode string
+
+const (
+	JobMetadataKeep JobMetadataMode = "keep" // Persist input sources and TORCH URLs as given (default)
+	JobMetadataHash JobMetadataMode = "hash" // Persist a keyed HMAC-SHA256 instead, so equal sources stay comparable
+	JobMetadataOmit JobMetadataMode = "omit" // Persist a placeholder instead
+)
+
+const (
+	// OmittedJobMetadata replaces omitted metadata values
+	OmittedJobMetadata = "[omitted]"
+
+	// hashedJobMetadataPrefix marks hashed metadata values
+	hashedJobMetadataPrefix = "hmac-sha256:"
+
+	// legacyHashedJobMetadataPrefix marks values hashed without a key by earlier versions
+	legacyHashedJobMetadataPrefix = "sha256:"
+
+	// MinJobMetadataHashKeyLength is the minimum length of job_metadata.hash_key
+	MinJobMetadataHashKeyLength = 16
+)
+
+// IsValid returns true if the mode is recognized (empty means the default, keep)
+func (m JobMetadataMode) IsValid() bool {
+	switch m {
+	case "", JobMetadataKeep, JobMetadataHash, JobMetadataOmit:
+		return true
+	}
+	return false
+}
+
+// JobMetadataConfig controls anonymization of the job's own metadata
+// Input paths, URLs and TORCH extraction URLs may embed identifying query details
+// (e.g. patient identifiers in a search URL or a cohort name in a path)
+// In hash mode values are hashed with HashKey, so a hash cannot be reversed by hashing
+// guessed paths or URLs without access to the key
+type JobMetadataConfig struct {
+	Mode    JobMetadataMode `yaml:"mode" json:"mode"`  // keep | hash | omit
+	HashKey string          `yaml:"hash_key" json:"-"` // HMAC key of hash mode (empty = key file in jobs_dir)
+}
+
+// IsActive returns true if metadata is rewritten before it is persisted
+func (c JobMetadataConfig) IsActive() bool {
+	return c.Mode == JobMetadataHash || c.Mode == JobMetadataOmit
+}
+
+// Validate checks the job metadata policy
+func (c JobMetadataConfig) Validate() error {
+	if !c.Mode.IsValid() {
+		return fmt.Errorf("invalid job_metadata mode '%s' (must be keep, hash, or omit)", c.Mode)
+	}
+	if c.HashKey != "" && len(c.HashKey) < MinJobMetadataHashKeyLength {
+		return fmt.Errorf("job_metadata.hash_key must be at least %d characters", MinJobMetadataHashKeyLength)
+	}
+	return nil
+}
+
+// Hash returns the persisted form of a value in hash mode: the HMAC-SHA256 of the value under HashKey
+func (c JobMetadataConfig) Hash(value string) string {
+	mac := hmac.New(sha256.New, []byte(c.HashKey))
+	mac.Write([]byte(value))
+	return hashedJobMetadataPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsRedactedJobMetadata returns true if a persisted value was hashed or omitted
+func IsRedactedJobMetadata(value string) bool {
+	return value == OmittedJobMetadata ||
+		strings.HasPrefix(value, hashedJobMetadataPrefix) ||
+		strings.HasPrefix(value, legacyHashedJobMetadataPrefix)
+}
+
+// Redact returns the persisted form of a metadata value under this policy
+// Empty and already redacted values are returned unchanged
+func (c JobMetadataConfig) Redact(value string) string {
+	if value == "" || IsRedactedJobMetadata(value) {
+		return value
+	}
+	switch c.Mode {
+	case JobMetadataHash:
+		return c.Hash(value)
+	case JobMetadataOmit:
+		return OmittedJobMetadata
+	}
+	return value
+}
+
+// SensitiveValues returns the raw metadata values of the job that this policy redacts
+func (c JobMetadataConfig) SensitiveValues(job PipelineJob) []string {
+	if !c.IsActive() {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var values []string
+	add := func(value string) {
+		if value != "" && !IsRedactedJobMetadata(value) && !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+
+	add(job.InputSource)
+	add(job.TORCHExtractionURL)
+	for _, extra := range job.ExtraSources {
+		add(extra.Source)
+	}
+	for _, file := range job.ImportedFiles {
+		add(file.Source)
+	}
+	if job.Annotations != nil {
+		add(job.Annotations.Display)
+	}
+	if job.Template != nil {
+		for _, source := range job.Template.Sources() {
+			add(source)
+		}
+	}
+	return values
+}
+
+// RedactText replaces every sensitive metadata value of the job occurring in text
+// Used for error messages, log lines and events that mention a source
+func (c JobMetadataConfig) RedactText(job PipelineJob, text string) string {
+	values := c.SensitiveValues(job)
+	if len(values) == 0 || text == "" {
+		return text
+	}
+
+	// Longest values first, so a URL is replaced before a prefix of it
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		pairs = append(pairs, value, c.Redact(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// RedactJob returns a copy of the job with sensitive metadata redacted for persistence
+// Covers the input sources, the per-file sources, the TORCH extraction URL, the study name
+// of the CRTDL (annotations.display) and the inputs of the job template; occurrences
+// of these values in error messages and attached log lines are replaced as well.
+// The given job is not modified
+func (c JobMetadataConfig) RedactJob(job PipelineJob) PipelineJob {
+	if len(c.SensitiveValues(job)) == 0 {
+		return job
+	}
+	redacted := job
+
+	redacted.ErrorMessage = c.RedactText(job, job.ErrorMessage)
+	redacted.Steps = make([]PipelineStep, len(job.Steps))
+	for i, step := range job.Steps {
+		if step.LastError != nil {
+			lastError := *step.LastError
+			lastError.Message = c.RedactText(job, lastError.Message)
+			if lastError.Context != nil {
+				lastError.Context = make([]string, len(step.LastError.Context))
+				for j, line := range step.LastError.Context {
+					lastError.Context[j] = c.RedactText(job, line)
+				}
+			}
+			step.LastError = &lastError
+		}
+		redacted.Steps[i] = step
+	}
+
+	redacted.InputSource = c.Redact(job.InputSource)
+	redacted.TORCHExtractionURL = c.Redact(job.TORCHExtractionURL)
+	if job.ExtraSources != nil {
+		redacted.ExtraSources = make([]InputSource, len(job.ExtraSources))
+		for i, extra := range job.ExtraSources {
+			extra.Source = c.Redact(extra.Source)
+			redacted.ExtraSources[i] = extra
+		}
+	}
+	if job.Annotations != nil {
+		annotations := *job.Annotations
+		annotations.Display = c.Redact(annotations.Display)
+		redacted.Annotations = &annotations
+	}
+	if job.ImportedFiles != nil {
+		redacted.ImportedFiles = make([]FHIRDataFile, len(job.ImportedFiles))
+		for i, file := range job.ImportedFiles {
+			file.Source = c.Redact(file.Source)
+			redacted.ImportedFiles[i] = file
+		}
+	}
+	if job.Template != nil {
+		template := *job.Template
+		template.CRTDL = c.Redact(template.CRTDL)
+		template.Inputs = make([]string, len(job.Template.Inputs))
+		for i, input := range job.Template.Inputs {
+			template.Inputs[i] = c.Redact(input)
+		}
+		redacted.Template = &template
+	}
+	return redacted
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxLineSizeMB is the default upper bound for a single NDJSON line
+const DefaultMaxLineSizeMB = 100
+
+// LimitsConfig guards against pathological input (huge single-line files,
+// Bundles with millions of entries, directories with thousands of files)
+// Zero values mean "use the default" for MaxLineSizeMB and "unlimited" for the others
+type LimitsConfig struct {
+	MaxLineSizeMB    int `yaml:"max_line_size_mb" json:"max_line_size_mb"`     // Largest accepted NDJSON line (default 100MB)
+	MaxBundleEntries int `yaml:"max_bundle_entries" json:"max_bundle_entries"` // Largest accepted Bundle.entry count (0 = unlimited)
+	MaxFiles         int `yaml:"max_files" json:"max_files"`                   // Largest accepted number of input files (0 = unlimited)
+}
+
+// GetMaxLineBytes returns the maximum NDJSON line size in bytes
+func (l LimitsConfig) GetMaxLineBytes() int {
+	if l.MaxLineSizeMB <= 0 {
+		return DefaultMaxLineSizeMB * 1024 * 1024
+	}
+	return l.MaxLineSizeMB * 1024 * 1024
+}
+
+// CheckFileCount returns an error if count exceeds the configured file limit
+func (l LimitsConfig) CheckFileCount(count int) error {
+	if l.MaxFiles > 0 && count > l.MaxFiles {
+		return fmt.Errorf("input contains %d files, exceeding limits.max_files (%d)", count, l.MaxFiles)
+	}
+	return nil
+}
+
+// CheckBundleEntries returns an error if a Bundle has more entries than allowed
+func (l LimitsConfig) CheckBundleEntries(count int) error {
+	if l.MaxBundleEntries > 0 && count > l.MaxBundleEntries {
+		return fmt.Errorf("bundle has %d entries, exceeding limits.max_bundle_entries (%d)", count, l.MaxBundleEntries)
+	}
+	return nil
+}
+
+// Validate checks that limits are non-negative
+func (l LimitsConfig) Validate() error {
+	if l.MaxLineSizeMB < 0 {
+		return errors.New("limits.max_line_size_mb must not be negative")
+	}
+	if l.MaxBundleEntries < 0 {
+		return errors.New("limits.max_bundle_entries must not be negative")
+	}
+	if l.MaxFiles < 0 {
+		return errors.New("limits.max_files must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// LineageNode is one job of a lineage tree built from the parent_jobs of job states
+// Parents and Children are only filled in the direction the tree is rendered
+type LineageNode struct {
+	JobID          string        `json:"job_id"`
+	Status         JobStatus     `json:"status,omitempty"`
+	InputSource    string        `json:"input_source,omitempty"`
+	InputType      InputType     `json:"input_type,omitempty"`
+	CreatedAt      *time.Time    `json:"created_at,omitempty"`
+	CompletedSteps []StepName    `json:"completed_steps,omitempty"`
+	Missing        bool          `json:"missing,omitempty"` // Job was deleted; only its ID is known
+	Parents        []LineageNode `json:"parents,omitempty"`
+	Children       []LineageNode `json:"children,omitempty"`
+}
+
+// NewLineageNode describes a job without its relatives
+func NewLineageNode(job PipelineJob) LineageNode {
+	createdAt := job.CreatedAt
+	node := LineageNode{
+		JobID:       job.JobID,
+		Status:      job.Status,
+		InputSource: job.InputSource,
+		InputType:   job.InputType,
+		CreatedAt:   &createdAt,
+	}
+	for _, step := range job.Steps {
+		if step.Status == StepStatusCompleted {
+			node.CompletedSteps = append(node.CompletedSteps, step.Name)
+		}
+	}
+	return node
+}
@@ -0,0 +1,41 @@
+package models
+
+import "fmt"
+
+// NarrativeMode controls what happens to resource.text narratives on import
+type NarrativeMode string
+
+const (
+	NarrativeKeep     NarrativeMode = "keep"     // Leave narratives untouched (default)
+	NarrativeRemove   NarrativeMode = "remove"   // Delete resource.text
+	NarrativeSanitize NarrativeMode = "sanitize" // Replace resource.text with a fixed placeholder (status "empty")
+)
+
+// IsValid returns true if the mode is recognized (empty means the default, keep)
+func (m NarrativeMode) IsValid() bool {
+	switch m {
+	case "", NarrativeKeep, NarrativeRemove, NarrativeSanitize:
+		return true
+	}
+	return false
+}
+
+// NarrativeConfig is the narrative scrubbing policy applied after import
+// Narratives are free-text XHTML and often contain names, addresses or identifiers
+// that the de-identification service does not recognize, so they are handled locally
+type NarrativeConfig struct {
+	Mode NarrativeMode `yaml:"mode" json:"mode"` // keep | remove | sanitize
+}
+
+// IsActive returns true if the policy changes anything, so imports can skip the extra pass otherwise
+func (c NarrativeConfig) IsActive() bool {
+	return c.Mode != "" && c.Mode != NarrativeKeep
+}
+
+// Validate checks the narrative policy
+func (c NarrativeConfig) Validate() error {
+	if !c.Mode.IsValid() {
+		return fmt.Errorf("invalid narrative mode '%s' (must be keep, remove, or sanitize)", c.Mode)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParquetCompression is the codec used for Parquet column chunks
+type ParquetCompression string
+
+const (
+	ParquetSnappy ParquetCompression = "snappy" // Fast, widely supported (default)
+	ParquetZstd   ParquetCompression = "zstd"   // Smaller files, supported by Spark 3+, Trino and DuckDB
+	ParquetGzip   ParquetCompression = "gzip"   // Maximum compatibility with older readers
+	ParquetNone   ParquetCompression = "none"   // Uncompressed
+)
+
+// ParquetPartition is a key the Parquet output can be partitioned by
+type ParquetPartition string
+
+const (
+	PartitionByResourceType ParquetPartition = "resource_type" // resource_type=Observation/
+	PartitionByYear         ParquetPartition = "year"          // year=2024/ from the resource's clinical date
+)
+
+// TableFormat selects whether Parquet output is registered as a table
+type TableFormat string
+
+const (
+	TableFormatNone  TableFormat = "none"  // Plain Parquet files (default)
+	TableFormatDelta TableFormat = "delta" // Delta Lake table with _delta_log/ per resource type
+)
+
+// PackagingMode selects where Parquet conversion runs
+type PackagingMode string
+
+const (
+	PackagingModeService PackagingMode = "service" // Conversion service at services.parquet_conversion.url (default)
+	PackagingModeLocal   PackagingMode = "local"   // Parquet is written in-process (parquet/<ResourceType>/)
+)
+
+// DefaultParquetTargetFileSizeMB is the default size at which Parquet files are rolled over
+const DefaultParquetTargetFileSizeMB = 128
+
+// PackagingConfig contains settings for output packaging (CSV/Parquet)
+type PackagingConfig struct {
+	Mode           PackagingMode        `yaml:"mode" json:"mode,omitempty"` // service | local (default: service)
+	Parquet        ParquetOptions       `yaml:"parquet" json:"parquet"`
+	TableFormat    TableFormat          `yaml:"table_format" json:"table_format"` // none | delta (default: none)
+	ExpectedSchema ExpectedSchemaConfig `yaml:"expected_schema" json:"expected_schema,omitempty"`
+}
+
+// WritesParquetLocally returns true if the Parquet conversion step runs in-process
+func (c PackagingConfig) WritesParquetLocally() bool {
+	return c.Mode == PackagingModeLocal
+}
+
+// SchemaFormat is the format of an expected table schema in a schema registry
+type SchemaFormat string
+
+const (
+	SchemaFormatJSON SchemaFormat = "json_schema" // JSON Schema of one row (object with a property per column)
+	SchemaFormatAvro SchemaFormat = "avro"        // Avro record with a field per column
+)
+
+// SchemaTablePlaceholder is replaced with the table name (the resource type) in ExpectedSchemaConfig.URL
+const SchemaTablePlaceholder = "{table}"
+
+// ExpectedSchemaConfig points to the schemas downstream ETL expects for each output table
+// Packaging fails when a produced table is incompatible with its registered schema
+type ExpectedSchemaConfig struct {
+	URL    string       `yaml:"url" json:"url,omitempty"`       // Schema URL per table, e.g. https://registry/subjects/aether-{table}/versions/latest
+	Format SchemaFormat `yaml:"format" json:"format,omitempty"` // json_schema | avro (default: detected from the document)
+}
+
+// IsEnabled returns true if produced schemas are checked against a registry
+func (c ExpectedSchemaConfig) IsEnabled() bool {
+	return c.URL != ""
+}
+
+// TableURL returns the schema URL of a table
+func (c ExpectedSchemaConfig) TableURL(table string) string {
+	return strings.ReplaceAll(c.URL, SchemaTablePlaceholder, url.PathEscape(table))
+}
+
+// Validate checks the registry URL and format
+func (c ExpectedSchemaConfig) Validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	parsed, err := url.Parse(strings.ReplaceAll(c.URL, SchemaTablePlaceholder, "table"))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("pipeline.packaging.expected_schema.url must be an http(s) URL, got '%s'", c.URL)
+	}
+	if !strings.Contains(c.URL, SchemaTablePlaceholder) {
+		return fmt.Errorf("pipeline.packaging.expected_schema.url must contain %s, which is replaced with each table name", SchemaTablePlaceholder)
+	}
+	switch c.Format {
+	case "", SchemaFormatJSON, SchemaFormatAvro:
+	default:
+		return fmt.Errorf("invalid pipeline.packaging.expected_schema.format '%s' (must be json_schema or avro)", c.Format)
+	}
+	return nil
+}
+
+// Validate checks the packaging settings
+func (c PackagingConfig) Validate() error {
+	switch c.Mode {
+	case "", PackagingModeService, PackagingModeLocal:
+	default:
+		return fmt.Errorf("invalid pipeline.packaging.mode '%s' (must be service or local)", c.Mode)
+	}
+	switch c.TableFormat {
+	case "", TableFormatNone:
+	case TableFormatDelta:
+		if !c.WritesParquetLocally() {
+			return fmt.Errorf("pipeline.packaging.table_format 'delta' requires pipeline.packaging.mode 'local', which writes the Parquet files and the Delta log")
+		}
+	default:
+		return fmt.Errorf("invalid pipeline.packaging.table_format '%s' (must be none or delta)", c.TableFormat)
+	}
+	if err := c.ExpectedSchema.Validate(); err != nil {
+		return err
+	}
+	return c.Parquet.Validate()
+}
+
+// ParquetOptions tunes the Parquet writer for the consuming query engines
+type ParquetOptions struct {
+	PartitionBy        []ParquetPartition `yaml:"partition_by" json:"partition_by,omitempty"`     // Hive-style partition directories, in this order
+	Compression        ParquetCompression `yaml:"compression" json:"compression"`                 // snappy | zstd | gzip | none (default: snappy)
+	DictionaryEncoding bool               `yaml:"dictionary_encoding" json:"dictionary_encoding"` // Dictionary-encode columns (default: true)
+	TargetFileSizeMB   int                `yaml:"target_file_size_mb" json:"target_file_size_mb"` // Roll over to a new file at this size (default: 128)
+}
+
+// GetCompression returns the configured codec, or snappy if not set
+func (o ParquetOptions) GetCompression() ParquetCompression {
+	if o.Compression == "" {
+		return ParquetSnappy
+	}
+	return o.Compression
+}
+
+// GetTargetFileSizeBytes returns the rollover size in bytes
+func (o ParquetOptions) GetTargetFileSizeBytes() int64 {
+	sizeMB := o.TargetFileSizeMB
+	if sizeMB <= 0 {
+		sizeMB = DefaultParquetTargetFileSizeMB
+	}
+	return int64(sizeMB) * 1024 * 1024
+}
+
+// Validate checks the Parquet options
+func (o ParquetOptions) Validate() error {
+	switch o.Compression {
+	case "", ParquetSnappy, ParquetZstd, ParquetGzip, ParquetNone:
+	default:
+		return fmt.Errorf("invalid packaging.parquet.compression '%s' (must be snappy, zstd, gzip, or none)", o.Compression)
+	}
+
+	seen := map[ParquetPartition]bool{}
+	for _, partition := range o.PartitionBy {
+		if partition != PartitionByResourceType && partition != PartitionByYear {
+			return fmt.Errorf("invalid packaging.parquet.partition_by '%s' (must be resource_type or year)", partition)
+		}
+		if seen[partition] {
+			return fmt.Errorf("packaging.parquet.partition_by lists '%s' twice", partition)
+		}
+		seen[partition] = true
+	}
+
+	if o.TargetFileSizeMB < 0 {
+		return fmt.Errorf("packaging.parquet.target_file_size_mb must not be negative")
+	}
+	return nil
+}
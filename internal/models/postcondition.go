@@ -0,0 +1,54 @@
+package models
+
+import "fmt"
+
+// StepPostCondition is a check of a step's output, evaluated after the step finished its work
+// A violated post-condition fails the step even though every service call succeeded
+type StepPostCondition struct {
+	MinOutputFiles      int      `yaml:"min_output_files" json:"min_output_files,omitempty" mapstructure:"min_output_files"`                // Minimum number of NDJSON output files (0 = no check)
+	MinResources        int      `yaml:"min_resources" json:"min_resources,omitempty" mapstructure:"min_resources"`                         // Minimum number of resources across the output (0 = no check)
+	MaxShrinkagePercent *float64 `yaml:"max_shrinkage_percent" json:"max_shrinkage_percent,omitempty" mapstructure:"max_shrinkage_percent"` // Maximum loss of resources vs the step's input (nil = no check)
+}
+
+// IsActive returns true if any check is configured
+func (c StepPostCondition) IsActive() bool {
+	return c.MinOutputFiles > 0 || c.MinResources > 0 || c.MaxShrinkagePercent != nil
+}
+
+// PostConditionsSupported reports whether a step writes NDJSON output that post-conditions can check
+func PostConditionsSupported(step StepName) bool {
+	switch step {
+	case StepTorchImport, StepLocalImport, StepHttpImport, StepDIMP, StepImaging:
+		return true
+	}
+	return false
+}
+
+// ShrinkageSupported reports whether a step has NDJSON input to compare its output against
+func ShrinkageSupported(step StepName) bool {
+	return step == StepDIMP || step == StepImaging
+}
+
+// ValidatePostConditions checks the step names and bounds of pipeline.post_conditions
+func ValidatePostConditions(conditions map[StepName]StepPostCondition) error {
+	for step, condition := range conditions {
+		if !IsValidStepName(step) {
+			return fmt.Errorf("pipeline.post_conditions: unknown step '%s'", step)
+		}
+		if !PostConditionsSupported(step) {
+			return fmt.Errorf("pipeline.post_conditions: step '%s' has no NDJSON output to check", step)
+		}
+		if condition.MinOutputFiles < 0 || condition.MinResources < 0 {
+			return fmt.Errorf("pipeline.post_conditions.%s: minimums must not be negative", step)
+		}
+		if condition.MaxShrinkagePercent != nil {
+			if !ShrinkageSupported(step) {
+				return fmt.Errorf("pipeline.post_conditions.%s.max_shrinkage_percent: only dimp and imaging have an input to compare against", step)
+			}
+			if *condition.MaxShrinkagePercent < 0 || *condition.MaxShrinkagePercent > 100 {
+				return fmt.Errorf("pipeline.post_conditions.%s.max_shrinkage_percent must be between 0 and 100", step)
+			}
+		}
+	}
+	return nil
+}
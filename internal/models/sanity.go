@@ -0,0 +1,58 @@
+package models
+
+import (
+	"fmt"
+)
+
+// SanityCheck asserts that the number of matching resources in a job's output lies in an expected range
+// Catches obviously wrong extractions (empty cohort, wrong date filter) before delivery
+type SanityCheck struct {
+	Name         string `yaml:"name" json:"name" mapstructure:"name"`
+	ResourceType string `yaml:"resource_type" json:"resource_type" mapstructure:"resource_type"` // Only count resources of this type (empty = all)
+	Where        string `yaml:"where" json:"where" mapstructure:"where"`                         // FHIRPath filter (empty = all resources of the type)
+	Min          *int   `yaml:"min" json:"min,omitempty" mapstructure:"min"`                     // Minimum expected count (nil = no lower bound)
+	Max          *int   `yaml:"max" json:"max,omitempty" mapstructure:"max"`                     // Maximum expected count (nil = no upper bound)
+}
+
+// Validate checks that the sanity check is well-formed
+// The FHIRPath expression itself is compiled when the checks run
+func (s SanityCheck) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("sanity check name is required")
+	}
+	if s.Min == nil && s.Max == nil {
+		return fmt.Errorf("sanity check '%s' needs min and/or max", s.Name)
+	}
+	if s.Min != nil && *s.Min < 0 {
+		return fmt.Errorf("sanity check '%s': min must not be negative", s.Name)
+	}
+	if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
+		return fmt.Errorf("sanity check '%s': min (%d) is greater than max (%d)", s.Name, *s.Min, *s.Max)
+	}
+	return nil
+}
+
+// InRange returns true if count satisfies the configured bounds
+func (s SanityCheck) InRange(count int) bool {
+	if s.Min != nil && count < *s.Min {
+		return false
+	}
+	if s.Max != nil && count > *s.Max {
+		return false
+	}
+	return true
+}
+
+// ExpectedRange renders the bounds for display (e.g., "10..500", ">= 1", "<= 0")
+func (s SanityCheck) ExpectedRange() string {
+	switch {
+	case s.Min != nil && s.Max != nil:
+		return fmt.Sprintf("%d..%d", *s.Min, *s.Max)
+	case s.Min != nil:
+		return fmt.Sprintf(">= %d", *s.Min)
+	case s.Max != nil:
+		return fmt.Sprintf("<= %d", *s.Max)
+	default:
+		return "any"
+	}
+}
@@ -0,0 +1,257 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultServerListen is the address `aether serve` listens on by default
+	DefaultServerListen = ":8080"
+	// DefaultShareTTLHours is how long share links stay valid by default
+	DefaultShareTTLHours = 72
+)
+
+// ServerConfig controls `aether serve`, the REST API over the jobs directory
+type ServerConfig struct {
+	Listen           string     `yaml:"listen" json:"listen,omitempty"`                         // Listen address (default :8080)
+	PublicURL        string     `yaml:"public_url" json:"public_url,omitempty"`                 // Externally reachable base URL used in share links
+	ShareSecret      string     `yaml:"share_secret" json:"-"`                                  // HMAC key for share links (empty = sharing disabled)
+	ShareTTLHours    int        `yaml:"share_ttl_hours" json:"share_ttl_hours,omitempty"`       // Default validity of share links (default 72)
+	Tokens           []APIToken `yaml:"tokens" json:"-"`                                        // Bearer tokens; without tokens the API is read-only
+	GRPCListen       string     `yaml:"grpc_listen" json:"grpc_listen,omitempty"`               // Listen address of the gRPC API (empty = disabled)
+	GRPCTLSCert      string     `yaml:"grpc_tls_cert" json:"grpc_tls_cert,omitempty"`           // PEM certificate (chain) of the gRPC API
+	GRPCTLSKey       string     `yaml:"grpc_tls_key" json:"grpc_tls_key,omitempty"`             // PEM private key of grpc_tls_cert
+	AllowedInputDirs []string   `yaml:"allowed_input_dirs" json:"allowed_input_dirs,omitempty"` // Server directories API clients may submit as inputs
+}
+
+// AuthEnabled returns true if API tokens are configured
+func (c ServerConfig) AuthEnabled() bool {
+	return len(c.Tokens) > 0
+}
+
+// GetListen returns the listen address, falling back to DefaultServerListen
+func (c ServerConfig) GetListen() string {
+	if c.Listen == "" {
+		return DefaultServerListen
+	}
+	return c.Listen
+}
+
+// GetShareTTL returns the default validity of share links
+func (c ServerConfig) GetShareTTL() time.Duration {
+	if c.ShareTTLHours <= 0 {
+		return DefaultShareTTLHours * time.Hour
+	}
+	return time.Duration(c.ShareTTLHours) * time.Hour
+}
+
+// GRPCEnabled returns true if the gRPC API should be served
+func (c ServerConfig) GRPCEnabled() bool {
+	return c.GRPCListen != ""
+}
+
+// GRPCTLSEnabled returns true if the gRPC API is served over TLS
+func (c ServerConfig) GRPCTLSEnabled() bool {
+	return c.GRPCTLSCert != "" && c.GRPCTLSKey != ""
+}
+
+// CheckGRPCTransport refuses plaintext gRPC on addresses reachable from other hosts
+// Bearer tokens and job inputs would travel unencrypted, so without server.grpc_tls_cert
+// and server.grpc_tls_key the API may only listen on a loopback address
+func (c ServerConfig) CheckGRPCTransport() error {
+	if !c.GRPCEnabled() || c.GRPCTLSEnabled() || IsLoopbackAddress(c.GRPCListen) {
+		return nil
+	}
+	return fmt.Errorf("server.grpc_listen '%s' is reachable from other hosts: configure server.grpc_tls_cert and server.grpc_tls_key, or listen on a loopback address such as 127.0.0.1:9091", c.GRPCListen)
+}
+
+// IsLoopbackAddress returns true if a host:port listen address only accepts local connections
+// An empty host listens on all interfaces
+func IsLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// SharingEnabled returns true if a share secret is configured
+func (c ServerConfig) SharingEnabled() bool {
+	return c.ShareSecret != ""
+}
+
+// Validate checks the server settings
+func (c ServerConfig) Validate() error {
+	if c.ShareTTLHours < 0 {
+		return errors.New("server.share_ttl_hours must not be negative")
+	}
+	if c.PublicURL != "" {
+		parsed, err := url.Parse(c.PublicURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid server.public_url '%s': must be an absolute http(s) URL", c.PublicURL)
+		}
+	}
+	if (c.GRPCTLSCert == "") != (c.GRPCTLSKey == "") {
+		return errors.New("server.grpc_tls_cert and server.grpc_tls_key must be set together")
+	}
+	if c.ShareSecret != "" && len(c.ShareSecret) < 16 {
+		return errors.New("server.share_secret must be at least 16 characters")
+	}
+	names := map[string]bool{}
+	for _, token := range c.Tokens {
+		if err := token.Validate(); err != nil {
+			return err
+		}
+		if names[token.Name] {
+			return fmt.Errorf("server.tokens: duplicate name '%s'", token.Name)
+		}
+		names[token.Name] = true
+	}
+	return nil
+}
+
+// JobStatusView is the read-only view of a job served by the REST API and share links
+// It leaves out the configuration snapshot and input sources
+type JobStatusView struct {
+	JobID        string           `json:"job_id"`
+	Status       JobStatus        `json:"status"`
+	CurrentStep  string           `json:"current_step,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	TotalFiles   int              `json:"total_files"`
+	TotalBytes   int64            `json:"total_bytes"`
+	ErrorMessage string           `json:"error_message,omitempty"`
+	Annotations  *JobAnnotations  `json:"annotations,omitempty"` // Study of a CRTDL job
+	Steps        []StepStatusView `json:"steps"`
+}
+
+// StepStatusView is the read-only view of one pipeline step
+type StepStatusView struct {
+	Name           StepName   `json:"name"`
+	Status         StepStatus `json:"status"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	FilesProcessed int        `json:"files_processed"`
+	BytesProcessed int64      `json:"bytes_processed"`
+	RetryCount     int        `json:"retry_count"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// NewJobStatusView builds the read-only view of a job
+func NewJobStatusView(job PipelineJob) JobStatusView {
+	view := JobStatusView{
+		JobID:        job.JobID,
+		Status:       job.Status,
+		CurrentStep:  job.CurrentStep,
+		CreatedAt:    job.CreatedAt,
+		UpdatedAt:    job.UpdatedAt,
+		TotalFiles:   job.TotalFiles,
+		TotalBytes:   job.TotalBytes,
+		ErrorMessage: job.ErrorMessage,
+		Annotations:  job.Annotations,
+		Steps:        make([]StepStatusView, len(job.Steps)),
+	}
+	for i, step := range job.Steps {
+		view.Steps[i] = StepStatusView{
+			Name:           step.Name,
+			Status:         step.Status,
+			StartedAt:      step.StartedAt,
+			CompletedAt:    step.CompletedAt,
+			FilesProcessed: step.FilesProcessed,
+			BytesProcessed: step.BytesProcessed,
+			RetryCount:     step.RetryCount,
+		}
+		if step.LastError != nil {
+			view.Steps[i].LastError = step.LastError.Message
+		}
+	}
+	return view
+}
+
+// APIRole grants a set of permissions on the REST API
+type APIRole string
+
+const (
+	RoleViewer    APIRole = "viewer"    // Read job status
+	RoleSubmitter APIRole = "submitter" // Viewer, plus submit jobs and share them with requesters
+	RoleOperator  APIRole = "operator"  // Submitter, plus control jobs of others (cancel, retry, administration)
+)
+
+// APIPermission is an action on the REST API checked against the caller's role
+type APIPermission string
+
+const (
+	PermissionRead    APIPermission = "read"
+	PermissionShare   APIPermission = "share"
+	PermissionSubmit  APIPermission = "submit"
+	PermissionControl APIPermission = "control"
+)
+
+// rolePermissions lists what each role may do
+var rolePermissions = map[APIRole][]APIPermission{
+	RoleViewer:    {PermissionRead},
+	RoleSubmitter: {PermissionRead, PermissionShare, PermissionSubmit},
+	RoleOperator:  {PermissionRead, PermissionShare, PermissionSubmit, PermissionControl},
+}
+
+// IsValid returns true if the role is recognized
+func (r APIRole) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Allows returns true if the role grants the permission
+func (r APIRole) Allows(permission APIPermission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a bearer token accepted by the REST API
+type APIToken struct {
+	Name   string  `yaml:"name" json:"name" mapstructure:"name"`       // Shown in audit logs
+	Token  string  `yaml:"token" json:"-" mapstructure:"token"`        // Secret value (usually ${ENV_VAR})
+	Role   APIRole `yaml:"role" json:"role" mapstructure:"role"`       // viewer, submitter or operator
+	Tenant string  `yaml:"tenant" json:"tenant" mapstructure:"tenant"` // Restrict to one tenant (empty = all)
+}
+
+// Validate checks an API token definition
+func (t APIToken) Validate() error {
+	if t.Name == "" {
+		return errors.New("server.tokens: name is required")
+	}
+	if len(t.Token) < 16 {
+		return fmt.Errorf("server.tokens[%s]: token must be at least 16 characters", t.Name)
+	}
+	if !t.Role.IsValid() {
+		return fmt.Errorf("server.tokens[%s]: invalid role '%s' (must be viewer, submitter, or operator)", t.Name, t.Role)
+	}
+	if t.Tenant != "" && !IsValidTenantName(t.Tenant) {
+		return fmt.Errorf("server.tokens[%s]: invalid tenant '%s'", t.Name, t.Tenant)
+	}
+	return nil
+}
+
+// AuditEntry records one REST or gRPC API action in <jobs_dir>/audit.ndjson
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Principal string    `json:"principal"` // Token name, "share-link" or "anonymous"
+	Role      APIRole   `json:"role,omitempty"`
+	Method    string    `json:"method"` // HTTP method, or "GRPC"
+	Path      string    `json:"path"`   // URL path, or the full gRPC method name
+	Tenant    string    `json:"tenant,omitempty"`
+	JobID     string    `json:"job_id,omitempty"`
+	Status    int       `json:"status"` // HTTP status; gRPC codes are recorded as their HTTP equivalent
+	Remote    string    `json:"remote,omitempty"`
+}
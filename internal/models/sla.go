@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SLAConfig defines expected per-step durations
+// Exceeding a threshold raises a warning (log, job event, optional webhook) while the step keeps running,
+// so e.g. stuck TORCH polling gets attention long before the extraction timeout
+type SLAConfig struct {
+	StepMinutes map[StepName]int `yaml:"step_minutes" json:"step_minutes,omitempty"` // Expected maximum duration per step (absent = no SLA)
+	WebhookURL  string           `yaml:"webhook_url" json:"webhook_url,omitempty"`   // Optional URL that receives a JSON POST when an SLA is exceeded
+	// Go template rendering the webhook payload (see ParseWebhookTemplate); empty posts the default payload.
+	// Loaded from webhook_template_file when that is set, so resumed jobs keep the template they started with
+	WebhookTemplate string `yaml:"webhook_template" json:"webhook_template,omitempty"`
+}
+
+// webhookTemplateFuncs are the functions available in sla.webhook_template
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes a value as a JSON literal, so strings are quoted and escaped
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseWebhookTemplate parses a webhook payload template
+// Templates use Go text/template syntax with the functions json, upper and lower; missing
+// fields are an error rather than "<no value>", so a typo cannot produce a broken payload
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(webhookTemplateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// Threshold returns the SLA for a step, or 0 if none is configured
+func (c SLAConfig) Threshold(step StepName) time.Duration {
+	minutes, ok := c.StepMinutes[step]
+	if !ok || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Validate checks step names, thresholds, the webhook URL and its payload template
+func (c SLAConfig) Validate() error {
+	for step, minutes := range c.StepMinutes {
+		if !IsValidStepName(step) {
+			return fmt.Errorf("unrecognized step in sla.step_minutes: %s", step)
+		}
+		if minutes <= 0 {
+			return fmt.Errorf("sla.step_minutes.%s must be positive", step)
+		}
+	}
+
+	if c.WebhookURL != "" {
+		parsed, err := url.Parse(c.WebhookURL)
+		if err != nil {
+			return fmt.Errorf("invalid sla.webhook_url: %w", err)
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return fmt.Errorf("invalid sla.webhook_url: must use http or https scheme, got '%s'", parsed.Scheme)
+		}
+	}
+
+	if c.WebhookTemplate != "" {
+		if c.WebhookURL == "" {
+			return errors.New("sla.webhook_template requires sla.webhook_url")
+		}
+		if _, err := ParseWebhookTemplate(c.WebhookTemplate); err != nil {
+			return fmt.Errorf("invalid sla.webhook_template: %w", err)
+		}
+	}
+	return nil
+}
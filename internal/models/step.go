@@ -15,6 +15,9 @@ type PipelineStep struct {
 	BytesProcessed int64      `json:"bytes_processed"`
 	RetryCount     int        `json:"retry_count"`
 	LastError      *StepError `json:"last_error,omitempty"`
+
+	Warnings        []StepWarning `json:"warnings,omitempty"`         // Non-fatal issues, e.g. skipped lines or ignored files
+	WarningsDropped int           `json:"warnings_dropped,omitempty"` // Warnings not kept because MaxStepWarnings was reached
 }
 
 // StepName defines the available pipeline steps
@@ -25,11 +28,24 @@ const (
 	StepLocalImport       StepName = "local_import" // Import from local directory
 	StepHttpImport        StepName = "http_import"  // Import from HTTP URL
 	StepDIMP              StepName = "dimp"
+	StepImaging           StepName = "imaging" // ImagingStudy metadata retrieval and WADO URL rewriting
 	StepValidation        StepName = "validation"
 	StepCSVConversion     StepName = "csv_conversion"
 	StepParquetConversion StepName = "parquet_conversion"
 )
 
+// AllStepNames lists every known step in pipeline order
+var AllStepNames = []StepName{
+	StepTorchImport,
+	StepLocalImport,
+	StepHttpImport,
+	StepDIMP,
+	StepImaging,
+	StepValidation,
+	StepCSVConversion,
+	StepParquetConversion,
+}
+
 // StepStatus defines the execution state of a pipeline step
 type StepStatus string
 
@@ -38,6 +54,7 @@ const (
 	StepStatusInProgress StepStatus = "in_progress"
 	StepStatusCompleted  StepStatus = "completed"
 	StepStatusFailed     StepStatus = "failed"
+	StepStatusCancelled  StepStatus = "cancelled" // The run was stopped (SIGINT, SIGTERM) while the step was running; resumable
 )
 
 // StepError captures error details for a failed step
@@ -45,9 +62,14 @@ type StepError struct {
 	Type       ErrorType `json:"type"` // "transient" | "non_transient"
 	Message    string    `json:"message"`
 	HTTPStatus int       `json:"http_status,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"` // X-Request-ID of the failed request, for locating it in service logs
 	Timestamp  time.Time `json:"timestamp"`
+	Context    []string  `json:"context,omitempty"` // Last log lines of the failed step attempt, for diagnostics
 }
 
+// MaxErrorContextLines is how many log lines are attached to a StepError
+const MaxErrorContextLines = 50
+
 // Error implements the error interface
 func (e *StepError) Error() string {
 	if e.HTTPStatus > 0 {
@@ -67,17 +89,154 @@ const (
 // IsValidStepName checks if the step name is recognized
 func IsValidStepName(name StepName) bool {
 	switch name {
-	case StepTorchImport, StepLocalImport, StepHttpImport, StepDIMP, StepValidation, StepCSVConversion, StepParquetConversion:
+	case StepTorchImport, StepLocalImport, StepHttpImport, StepDIMP, StepImaging, StepValidation, StepCSVConversion, StepParquetConversion:
 		return true
 	default:
 		return false
 	}
 }
 
+// IsImportStep checks if the step is one of the import step types
+func IsImportStep(name StepName) bool {
+	return name == StepTorchImport || name == StepLocalImport || name == StepHttpImport
+}
+
+// StepAliases maps alternative step names accepted in configuration and on the command line
+// to canonical step names. "import" stands for every import step: a job runs the one that
+// matches its input type. Aliases are resolved when configuration and job state are loaded,
+// so only canonical names are persisted
+var StepAliases = map[string][]StepName{
+	"import":       {StepTorchImport, StepLocalImport, StepHttpImport},
+	"torch_import": {StepTorchImport},
+}
+
+// ResolveStepName returns the canonical steps for a step name or alias
+// Returns false if the name is neither a step nor an alias
+func ResolveStepName(name string) ([]StepName, bool) {
+	if IsValidStepName(StepName(name)) {
+		return []StepName{StepName(name)}, true
+	}
+	steps, ok := StepAliases[name]
+	return steps, ok
+}
+
+// CanonicalStepNames resolves the aliases in a list of step names (e.g. enabled_steps)
+// Unknown names are kept as they are for validation to report. A step listed twice,
+// also through an alias ("import" together with "local_import"), is an error
+func CanonicalStepNames(names []string) ([]StepName, error) {
+	steps := make([]StepName, 0, len(names))
+	listedAs := make(map[StepName]string, len(names))
+	for _, name := range names {
+		resolved, ok := ResolveStepName(name)
+		if !ok {
+			resolved = []StepName{StepName(name)}
+		}
+		for _, step := range resolved {
+			if previous, listed := listedAs[step]; listed {
+				if previous == name {
+					return nil, fmt.Errorf("step '%s' is listed more than once", name)
+				}
+				return nil, fmt.Errorf("steps '%s' and '%s' both enable %s; list it once", previous, name, step)
+			}
+			listedAs[step] = name
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+// CanonicalStepName resolves an alias that names a single step for a job with the given
+// input type; "import" becomes the job's import step. Other names are returned unchanged
+func CanonicalStepName(name string, inputType InputType) StepName {
+	resolved, ok := ResolveStepName(name)
+	switch {
+	case !ok:
+		return StepName(name)
+	case len(resolved) == 1:
+		return resolved[0]
+	}
+	for _, step := range resolved {
+		if step == ImportStepForInputType(inputType) {
+			return step
+		}
+	}
+	return StepName(name)
+}
+
+// CanonicalizeJobSteps rewrites step name aliases in a job read from an older or hand-edited
+// state file (steps, current step, enabled steps, file provenance) to canonical names, so the
+// next save persists only canonical names. Returns true if anything was rewritten
+func CanonicalizeJobSteps(job *PipelineJob) bool {
+	changed := false
+	canonical := func(name StepName) StepName {
+		resolved := CanonicalStepName(string(name), job.InputType)
+		if resolved != name {
+			changed = true
+		}
+		return resolved
+	}
+
+	for i := range job.Steps {
+		job.Steps[i].Name = canonical(job.Steps[i].Name)
+	}
+	if job.CurrentStep != "" {
+		job.CurrentStep = string(canonical(StepName(job.CurrentStep)))
+	}
+	for i := range job.ImportedFiles {
+		job.ImportedFiles[i].SourceStep = canonical(job.ImportedFiles[i].SourceStep)
+	}
+
+	names := make([]string, len(job.Config.Pipeline.EnabledSteps))
+	aliased := false
+	for i, step := range job.Config.Pipeline.EnabledSteps {
+		names[i] = string(step)
+		if _, ok := StepAliases[names[i]]; ok {
+			aliased = true
+		}
+	}
+	if aliased {
+		if steps, err := CanonicalStepNames(names); err == nil {
+			job.Config.Pipeline.EnabledSteps = steps
+			changed = true
+		}
+	}
+	return changed
+}
+
+// ImportStepForInputType returns the import step responsible for the given input type
+// Returns empty string if the input type is not recognized
+func ImportStepForInputType(inputType InputType) StepName {
+	switch inputType {
+	case InputTypeCRTDL, InputTypeTORCHURL:
+		return StepTorchImport
+	case InputTypeLocal:
+		return StepLocalImport
+	case InputTypeHTTP, InputTypeFHIRSearch, InputTypeBulkExport, InputTypeS3:
+		return StepHttpImport
+	default:
+		return ""
+	}
+}
+
+// StepsForInputType returns the enabled steps a job with the given input type will execute
+// Only the matching import step is kept; all non-import steps are kept in order
+func StepsForInputType(enabledSteps []StepName, inputType InputType) []StepName {
+	importStep := ImportStepForInputType(inputType)
+
+	steps := make([]StepName, 0, len(enabledSteps))
+	for _, step := range enabledSteps {
+		if IsImportStep(step) && step != importStep {
+			continue
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
 // IsValidStepStatus checks if the step status is recognized
 func IsValidStepStatus(s StepStatus) bool {
 	switch s {
-	case StepStatusPending, StepStatusInProgress, StepStatusCompleted, StepStatusFailed:
+	case StepStatusPending, StepStatusInProgress, StepStatusCompleted, StepStatusFailed, StepStatusCancelled:
 		return true
 	default:
 		return false
@@ -88,15 +247,16 @@ func IsValidStepStatus(s StepStatus) bool {
 // Valid transitions:
 //
 //	pending -> in_progress
-//	in_progress -> completed | failed
+//	in_progress -> completed | failed | cancelled
 //	failed -> in_progress (retry if transient error)
+//	cancelled -> in_progress (resume)
 func (s StepStatus) CanTransitionTo(next StepStatus) bool {
 	switch s {
 	case StepStatusPending:
 		return next == StepStatusInProgress
 	case StepStatusInProgress:
-		return next == StepStatusCompleted || next == StepStatusFailed
-	case StepStatusFailed:
+		return next == StepStatusCompleted || next == StepStatusFailed || next == StepStatusCancelled
+	case StepStatusFailed, StepStatusCancelled:
 		return next == StepStatusInProgress // Allow retry
 	case StepStatusCompleted:
 		return false // Terminal state
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+)
+
+// StorageConfig sets the checks of the jobs directory run before a command starts or resumes
+// jobs, so a full disk or an unsuitable filesystem is reported up front instead of as a write
+// error in the middle of a job
+type StorageConfig struct {
+	MinFreeMB              int  `yaml:"min_free_mb" json:"min_free_mb,omitempty"`                           // Free space required on the jobs directory's filesystem (default 1024, -1 disables)
+	MinFreeInodes          int  `yaml:"min_free_inodes" json:"min_free_inodes,omitempty"`                   // Free inodes required (default 10000, -1 disables)
+	AllowNetworkFilesystem bool `yaml:"allow_network_filesystem" json:"allow_network_filesystem,omitempty"` // Accept NFS, SMB and similar filesystems whose locks may not reach other hosts
+}
+
+// Defaults of storage
+const (
+	DefaultStorageMinFreeMB     = 1024
+	DefaultStorageMinFreeInodes = 10000
+)
+
+// GetMinFreeBytes returns the free space the jobs directory needs, or 0 if not checked
+func (c StorageConfig) GetMinFreeBytes() uint64 {
+	switch {
+	case c.MinFreeMB < 0:
+		return 0
+	case c.MinFreeMB == 0:
+		return DefaultStorageMinFreeMB * 1024 * 1024
+	}
+	return uint64(c.MinFreeMB) * 1024 * 1024
+}
+
+// GetMinFreeInodes returns the free inodes the jobs directory needs, or 0 if not checked
+func (c StorageConfig) GetMinFreeInodes() uint64 {
+	switch {
+	case c.MinFreeInodes < 0:
+		return 0
+	case c.MinFreeInodes == 0:
+		return DefaultStorageMinFreeInodes
+	}
+	return uint64(c.MinFreeInodes)
+}
+
+// Validate checks that thresholds are -1 (disabled) or larger
+func (c StorageConfig) Validate() error {
+	if c.MinFreeMB < -1 || c.MinFreeInodes < -1 {
+		return errors.New("storage.min_free_mb and storage.min_free_inodes must be -1 (disabled), 0 (default) or positive")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// labelKeyPattern restricts label keys to names that are safe in file names, URLs and metrics
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-/]*$`)
+
+// JobTemplate bundles the settings of a repeatable run (aether run --template nightly.yaml)
+// The template is stored with every job created from it, so a run can be reproduced later
+type JobTemplate struct {
+	Name          string                `yaml:"name" json:"name"`                             // Template name (default: file name without extension)
+	InputType     InputType             `yaml:"input_type" json:"input_type,omitempty"`       // Expected type of the first input; checked against the detected type
+	CRTDL         string                `yaml:"crtdl" json:"crtdl,omitempty"`                 // CRTDL file of a TORCH extraction
+	Inputs        []string              `yaml:"inputs" json:"inputs,omitempty"`               // Input sources (directories, URLs); used after crtdl
+	EnabledSteps  []StepName            `yaml:"enabled_steps" json:"enabled_steps,omitempty"` // Replaces pipeline.enabled_steps of the configuration (aliases allowed)
+	Labels        map[string]string     `yaml:"labels" json:"labels,omitempty"`               // Copied to the job, e.g. schedule: nightly
+	Notifications TemplateNotifications `yaml:"notifications" json:"notifications"`
+
+	Path   string `yaml:"-" json:"path"`   // File the template was loaded from
+	SHA256 string `yaml:"-" json:"sha256"` // Digest of the template file, to tell edited templates apart
+}
+
+// TemplateNotifications are the webhook settings of runs created from a template
+// They replace sla.webhook_url and sla.webhook_template of the configuration
+type TemplateNotifications struct {
+	WebhookURL      string `yaml:"webhook_url" json:"webhook_url,omitempty"`
+	WebhookTemplate string `yaml:"webhook_template" json:"webhook_template,omitempty"`
+}
+
+// Sources returns the template's input sources: the CRTDL first, then the other inputs
+func (t JobTemplate) Sources() []string {
+	var sources []string
+	if t.CRTDL != "" {
+		sources = append(sources, t.CRTDL)
+	}
+	return append(sources, t.Inputs...)
+}
+
+// Apply overrides the configuration with the template's steps and notification settings
+// Step aliases are resolved like in pipeline.enabled_steps
+func (t JobTemplate) Apply(config *ProjectConfig) error {
+	if len(t.EnabledSteps) > 0 {
+		names := make([]string, len(t.EnabledSteps))
+		for i, step := range t.EnabledSteps {
+			names[i] = string(step)
+		}
+		steps, err := CanonicalStepNames(names)
+		if err != nil {
+			return fmt.Errorf("invalid template enabled_steps: %w", err)
+		}
+		// As in the configuration, "import" only enables TORCH where TORCH is configured
+		if slices.Contains(names, "import") && config.Services.TORCH.BaseURL == "" {
+			steps = slices.DeleteFunc(steps, func(step StepName) bool { return step == StepTorchImport })
+		}
+		config.Pipeline.EnabledSteps = steps
+	}
+	if t.Notifications.WebhookURL != "" {
+		config.SLA.WebhookURL = t.Notifications.WebhookURL
+	}
+	if t.Notifications.WebhookTemplate != "" {
+		config.SLA.WebhookTemplate = t.Notifications.WebhookTemplate
+	}
+	return nil
+}
+
+// Validate checks the template on its own; the configuration it is applied to is validated separately
+func (t JobTemplate) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("template name must not be empty")
+	}
+	if t.InputType != "" && !IsValidInputType(t.InputType) {
+		return fmt.Errorf("invalid template input_type '%s'", t.InputType)
+	}
+	if t.CRTDL != "" && t.InputType != "" && t.InputType != InputTypeCRTDL {
+		return fmt.Errorf("template has a crtdl but input_type %s", t.InputType)
+	}
+	for i, input := range t.Inputs {
+		if input == "" {
+			return fmt.Errorf("template inputs[%d] must not be empty", i)
+		}
+	}
+	for _, step := range t.EnabledSteps {
+		if _, ok := ResolveStepName(string(step)); !ok {
+			return fmt.Errorf("invalid step in template enabled_steps: %s", step)
+		}
+	}
+	for key := range t.Labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid template label '%s' (letters, digits, '_', '.', '-' and '/')", key)
+		}
+	}
+	if t.Notifications.WebhookTemplate != "" {
+		if _, err := ParseWebhookTemplate(t.Notifications.WebhookTemplate); err != nil {
+			return fmt.Errorf("invalid template notifications.webhook_template: %w", err)
+		}
+	}
+	return nil
+}
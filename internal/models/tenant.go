@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+// TenantsDirName is the subdirectory of jobs_dir that holds one jobs directory per tenant
+const TenantsDirName = "tenants"
+
+// tenantNamePattern restricts tenant names to safe directory and URL path segments
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// TenantConfig scopes jobs, service credentials and quotas to one research project
+// Jobs of a tenant live in <jobs_dir>/tenants/<name>/, isolated from other tenants
+type TenantConfig struct {
+	Name     string         `yaml:"name" json:"name" mapstructure:"name"`
+	Services TenantServices `yaml:"services" json:"services" mapstructure:"services"`
+	Quota    QuotaConfig    `yaml:"quota" json:"quota" mapstructure:"quota"`
+}
+
+// TenantServices overrides service settings for a tenant (empty fields keep the global value)
+type TenantServices struct {
+	TORCH TenantTORCHConfig `yaml:"torch" json:"torch" mapstructure:"torch"`
+	DIMP  TenantDIMPConfig  `yaml:"dimp" json:"dimp" mapstructure:"dimp"`
+}
+
+// TenantTORCHConfig holds a tenant's own TORCH endpoint and credentials
+type TenantTORCHConfig struct {
+	BaseURL  string `yaml:"base_url" json:"base_url,omitempty" mapstructure:"base_url"`
+	Username string `yaml:"username" json:"username,omitempty" mapstructure:"username"`
+	Password string `yaml:"password" json:"password,omitempty" mapstructure:"password"`
+}
+
+// TenantDIMPConfig holds a tenant's own DIMP endpoint (e.g. a project-specific pseudonym domain)
+type TenantDIMPConfig struct {
+	URL string `yaml:"url" json:"url,omitempty" mapstructure:"url"`
+}
+
+// QuotaConfig limits the jobs of a namespace (0 = unlimited)
+type QuotaConfig struct {
+	MaxJobs       int `yaml:"max_jobs" json:"max_jobs,omitempty" mapstructure:"max_jobs"`                      // Jobs kept in the jobs directory
+	MaxActiveJobs int `yaml:"max_active_jobs" json:"max_active_jobs,omitempty" mapstructure:"max_active_jobs"` // Jobs pending or in progress
+}
+
+// Validate checks that quotas are not negative
+func (q QuotaConfig) Validate(scope string) error {
+	if q.MaxJobs < 0 {
+		return fmt.Errorf("%s.max_jobs must not be negative", scope)
+	}
+	if q.MaxActiveJobs < 0 {
+		return fmt.Errorf("%s.max_active_jobs must not be negative", scope)
+	}
+	return nil
+}
+
+// IsValidTenantName checks that a tenant name is usable as a directory and URL segment
+func IsValidTenantName(name string) bool {
+	return tenantNamePattern.MatchString(name)
+}
+
+// Validate checks a tenant definition
+func (t TenantConfig) Validate() error {
+	if !IsValidTenantName(t.Name) {
+		return fmt.Errorf("invalid tenant name '%s' (lowercase letters, digits, '-' and '_'; max 63 characters)", t.Name)
+	}
+	return t.Quota.Validate(fmt.Sprintf("tenants[%s].quota", t.Name))
+}
+
+// ValidateTenants checks tenant definitions and rejects duplicate names
+func ValidateTenants(tenants []TenantConfig) error {
+	seen := map[string]bool{}
+	for _, tenant := range tenants {
+		if err := tenant.Validate(); err != nil {
+			return err
+		}
+		if seen[tenant.Name] {
+			return fmt.Errorf("duplicate tenant '%s'", tenant.Name)
+		}
+		seen[tenant.Name] = true
+	}
+	return nil
+}
+
+// GetTenant returns the tenant with the given name
+func (c ProjectConfig) GetTenant(name string) (TenantConfig, bool) {
+	for _, tenant := range c.Tenants {
+		if tenant.Name == name {
+			return tenant, true
+		}
+	}
+	return TenantConfig{}, false
+}
+
+// BaseJobsDir returns jobs_dir as configured, before scoping to a tenant
+func (c ProjectConfig) BaseJobsDir() string {
+	if c.Tenant == "" {
+		return c.JobsDir
+	}
+	return filepath.Dir(filepath.Dir(c.JobsDir))
+}
+
+// ForTenant returns the configuration scoped to a tenant: its own jobs directory below
+// <jobs_dir>/tenants/, its service overrides and its quota. An empty name returns c unchanged
+func (c ProjectConfig) ForTenant(name string) (ProjectConfig, error) {
+	if name == "" {
+		return c, nil
+	}
+	if c.Tenant != "" {
+		return ProjectConfig{}, errors.New("configuration is already scoped to tenant " + c.Tenant)
+	}
+	tenant, found := c.GetTenant(name)
+	if !found {
+		return ProjectConfig{}, fmt.Errorf("unknown tenant '%s'", name)
+	}
+
+	scoped := c
+	scoped.Tenant = tenant.Name
+	scoped.JobsDir = filepath.Join(c.JobsDir, TenantsDirName, tenant.Name)
+	scoped.Quota = tenant.Quota
+
+	torch := tenant.Services.TORCH
+	if torch.BaseURL != "" {
+		scoped.Services.TORCH.BaseURL = torch.BaseURL
+	}
+	if torch.Username != "" || torch.Password != "" {
+		// Credentials are replaced as a pair so a tenant never runs with another tenant's password
+		scoped.Services.TORCH.Username = torch.Username
+		scoped.Services.TORCH.Password = torch.Password
+	}
+	if tenant.Services.DIMP.URL != "" {
+		scoped.Services.DIMP.URL = tenant.Services.DIMP.URL
+	}
+	return scoped, nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// TracingProtocol is the OTLP transport traces are exported with
+type TracingProtocol string
+
+const (
+	TracingHTTP TracingProtocol = "http/protobuf" // OTLP over HTTP, usually port 4318 (default)
+	TracingGRPC TracingProtocol = "grpc"          // OTLP over gRPC, usually port 4317
+)
+
+// DefaultTracingServiceName is the service.name of exported traces
+const DefaultTracingServiceName = "aether"
+
+// TracingConfig exports OpenTelemetry traces of pipeline steps and service calls to an OTLP collector
+// Outbound requests carry W3C trace context headers, so DIMP and TORCH spans join the same trace
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint" json:"endpoint,omitempty"`         // Collector URL, e.g. http://otel-collector:4318 (empty disables tracing)
+	Protocol    TracingProtocol   `yaml:"protocol" json:"protocol,omitempty"`         // http/protobuf | grpc (default: http/protobuf)
+	Headers     map[string]string `yaml:"headers" json:"-"`                           // Sent with every export, e.g. an API key of a hosted backend; not persisted with jobs
+	ServiceName string            `yaml:"service_name" json:"service_name,omitempty"` // service.name resource attribute (default "aether")
+	SampleRatio float64           `yaml:"sample_ratio" json:"sample_ratio,omitempty"` // Fraction of runs traced (0 = default 1, all runs)
+}
+
+// IsEnabled returns true if traces are exported
+func (c TracingConfig) IsEnabled() bool {
+	return c.Endpoint != ""
+}
+
+// GetProtocol returns the configured protocol, or http/protobuf if not set
+func (c TracingConfig) GetProtocol() TracingProtocol {
+	if c.Protocol == "" {
+		return TracingHTTP
+	}
+	return c.Protocol
+}
+
+// GetServiceName returns the configured service name, or "aether" if not set
+func (c TracingConfig) GetServiceName() string {
+	if c.ServiceName == "" {
+		return DefaultTracingServiceName
+	}
+	return c.ServiceName
+}
+
+// GetSampleRatio returns the fraction of runs traced (default 1)
+func (c TracingConfig) GetSampleRatio() float64 {
+	if c.SampleRatio == 0 {
+		return 1
+	}
+	return c.SampleRatio
+}
+
+// Validate checks the collector URL, protocol and sample ratio
+func (c TracingConfig) Validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	parsed, err := url.Parse(c.Endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("tracing.endpoint must be an http(s) URL, got '%s'", c.Endpoint)
+	}
+	switch c.Protocol {
+	case "", TracingHTTP, TracingGRPC:
+	default:
+		return fmt.Errorf("invalid tracing.protocol '%s' (must be http/protobuf or grpc)", c.Protocol)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %g", c.SampleRatio)
+	}
+	return nil
+}
@@ -93,6 +93,19 @@ func FailStep(step PipelineStep, errorType ErrorType, errorMsg string, httpStatu
 	return step
 }
 
+// CancelStep creates a new PipelineStep with cancelled status
+// The reason is kept as a transient error, so the step is resumed like a retryable failure
+// Pure function - returns new instance
+func CancelStep(step PipelineStep, reason string) PipelineStep {
+	step.Status = StepStatusCancelled
+	step.LastError = &StepError{
+		Type:      ErrorTypeTransient,
+		Message:   reason,
+		Timestamp: time.Now(),
+	}
+	return step
+}
+
 // IncrementRetry creates a new PipelineStep with incremented retry count
 // Pure function - returns new instance
 func IncrementRetry(step PipelineStep) PipelineStep {
@@ -3,13 +3,12 @@ package models
 import (
 	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/google/uuid"
+	"github.com/trobanga/aether/internal/i18n"
 )
 
 // Validate checks if a PipelineJob has valid fields
@@ -27,21 +26,37 @@ func (j *PipelineJob) Validate() error {
 		return errors.New("input_source is required")
 	}
 
-	// Validate InputType matches InputSource
-	if j.InputType == InputTypeHTTP {
+	// Validate InputType matches InputSource (redacted sources are persisted as hash or placeholder)
+	if (j.InputType == InputTypeHTTP || j.InputType == InputTypeFHIRSearch || j.InputType == InputTypeBulkExport) && !IsRedactedJobMetadata(j.InputSource) {
 		if !strings.HasPrefix(j.InputSource, "http://") && !strings.HasPrefix(j.InputSource, "https://") {
-			return errors.New("input_source must be a valid HTTP(S) URL when input_type is http_url")
+			return fmt.Errorf("input_source must be a valid HTTP(S) URL when input_type is %s", j.InputType)
 		}
 		if _, err := url.Parse(j.InputSource); err != nil {
 			return fmt.Errorf("invalid input_source URL: %w", err)
 		}
 	}
 
+	if j.InputType == InputTypeS3 && !IsRedactedJobMetadata(j.InputSource) {
+		if _, _, err := ParseS3URL(j.InputSource); err != nil {
+			return fmt.Errorf("invalid input_source: %w", err)
+		}
+	}
+
 	// Validate InputType is recognized
 	if !IsValidInputType(j.InputType) {
 		return fmt.Errorf("invalid input_type: %s", j.InputType)
 	}
 
+	// Validate additional sources
+	for _, extra := range j.ExtraSources {
+		if extra.Source == "" {
+			return errors.New("extra source cannot be empty")
+		}
+		if !IsValidExtraSourceType(extra.Type) {
+			return fmt.Errorf("input type %s cannot be used as an additional source: %s", extra.Type, extra.Source)
+		}
+	}
+
 	// Validate JobStatus is recognized
 	if !IsValidJobStatus(j.Status) {
 		return fmt.Errorf("invalid status: %s", j.Status)
@@ -160,12 +175,20 @@ func (c *ProjectConfig) Validate() error {
 		}
 	}
 
+	// Validate the enabled steps form a coherent pipeline
+	if err := validateStepOrder(c.Pipeline.EnabledSteps); err != nil {
+		return err
+	}
+
 	// Validate service URLs for enabled steps
+	// CSV conversion without a URL flattens in-process, Parquet conversion with packaging mode local
 	for _, step := range c.Pipeline.EnabledSteps {
 		if !c.Services.HasServiceURL(step) {
-			switch step {
-			case StepDIMP, StepCSVConversion, StepParquetConversion:
+			switch {
+			case step == StepDIMP:
 				return fmt.Errorf("service URL required for enabled step '%s'", step)
+			case step == StepParquetConversion && !c.Pipeline.Packaging.WritesParquetLocally():
+				return fmt.Errorf("service URL required for enabled step '%s' (or set pipeline.packaging.mode: local)", step)
 			}
 		}
 	}
@@ -179,6 +202,16 @@ func (c *ProjectConfig) Validate() error {
 		}
 	}
 
+	if err := c.Services.FHIRExport.Validate(); err != nil {
+		return err
+	}
+	if err := c.Services.S3.Validate(); err != nil {
+		return err
+	}
+	if err := c.Output.S3.Validate(); err != nil {
+		return err
+	}
+
 	// Validate service URLs are well-formed (if provided)
 	if c.Services.DIMP.URL != "" {
 		if _, err := url.Parse(c.Services.DIMP.URL); err != nil {
@@ -196,6 +229,31 @@ func (c *ProjectConfig) Validate() error {
 		}
 	}
 
+	if !c.Services.DIMP.NonFHIRLines.IsValid() {
+		return fmt.Errorf("invalid dimp non_fhir_lines mode '%s' (must be fail, pass_through, or quarantine)", c.Services.DIMP.NonFHIRLines)
+	}
+	if c.Services.DIMP.DebugLogFirst < 0 || c.Services.DIMP.DebugLogEvery < 0 {
+		return errors.New("dimp debug_log_first and debug_log_every must not be negative")
+	}
+	if c.Services.DIMP.FileRetryAttempts < 0 || c.Services.DIMP.FileRetryAttempts > 10 {
+		return errors.New("dimp file_retry_attempts must be between 0 and 10")
+	}
+	if c.Services.DIMP.FileRetryBackoffSeconds < 0 {
+		return errors.New("dimp file_retry_backoff_seconds must not be negative")
+	}
+	if c.Services.DIMP.OutputCacheTTLMinutes < 0 {
+		return fmt.Errorf("dimp output_cache_ttl_minutes must be >= 0, got %d", c.Services.DIMP.OutputCacheTTLMinutes)
+	}
+	if c.Services.DIMP.SplitTargetSeconds < 0 {
+		return fmt.Errorf("dimp split_target_seconds must be >= 0, got %d", c.Services.DIMP.SplitTargetSeconds)
+	}
+	if c.Services.DIMP.BatchSize < 0 || c.Services.DIMP.BatchSize > MaxDIMPBatchSize {
+		return fmt.Errorf("dimp batch_size must be between 1 and %d, got %d", MaxDIMPBatchSize, c.Services.DIMP.BatchSize)
+	}
+	if err := c.Services.DIMP.UsageQuota.Validate(); err != nil {
+		return err
+	}
+
 	// Validate retry configuration
 	if c.Retry.MaxAttempts < 1 || c.Retry.MaxAttempts > 10 {
 		return errors.New("max_attempts must be between 1 and 10")
@@ -210,6 +268,112 @@ func (c *ProjectConfig) Validate() error {
 		return errors.New("initial_backoff_ms must be less than max_backoff_ms")
 	}
 
+	if err := c.Services.Imaging.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Import.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Attachments.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Narrative.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Extensions.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Consent.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Flatten.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Pipeline.Packaging.Validate(); err != nil {
+		return err
+	}
+
+	if c.Pipeline.MaxRuntimeMinutes < 0 {
+		return errors.New("pipeline.max_runtime_minutes must not be negative")
+	}
+
+	if err := c.Pipeline.TimeBudget.Validate(); err != nil {
+		return err
+	}
+
+	if err := ValidatePostConditions(c.Pipeline.PostConditions); err != nil {
+		return err
+	}
+
+	if err := ValidateStrictWarnings(c.Pipeline.StrictWarnings); err != nil {
+		return err
+	}
+
+	if err := c.Pipeline.DIMP.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Limits.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Storage.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.SLA.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Heartbeat.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	for _, check := range c.SanityChecks {
+		if err := check.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.HTTPClient.Chaos.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.JobMetadata.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Server.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Quota.Validate("quota"); err != nil {
+		return err
+	}
+
+	if err := ValidateTenants(c.Tenants); err != nil {
+		return err
+	}
+
+	if c.Locale != "" && !i18n.IsSupported(i18n.ParseLocale(c.Locale)) {
+		return fmt.Errorf("locale '%s' is not supported (available: %v)", c.Locale, i18n.SupportedLocales())
+	}
+
+	if _, err := c.GetTimeZone(); err != nil {
+		return fmt.Errorf("invalid time_zone '%s': %w", c.TimeZone, err)
+	}
+
 	// Validate jobs_dir is not empty
 	if c.JobsDir == "" {
 		return errors.New("jobs_dir is required")
@@ -218,6 +382,58 @@ func (c *ProjectConfig) Validate() error {
 	return nil
 }
 
+// stepOrderRules lists steps that must come before another step in enabled_steps when both are enabled
+// DIMP always reads import/, so imaging before DIMP would leave the job's final FHIR output
+// unpseudonymized; packaging steps only convert the FHIR data produced before them
+var stepOrderRules = []struct {
+	First  StepName
+	Then   StepName
+	Reason string
+}{
+	{StepDIMP, StepImaging, "imaging must work on pseudonymized data"},
+	{StepDIMP, StepCSVConversion, "packaging converts the data of the steps before it"},
+	{StepDIMP, StepParquetConversion, "packaging converts the data of the steps before it"},
+	{StepImaging, StepCSVConversion, "packaging converts the data of the steps before it"},
+	{StepImaging, StepParquetConversion, "packaging converts the data of the steps before it"},
+	{StepValidation, StepCSVConversion, "packaging converts the data of the steps before it"},
+	{StepValidation, StepParquetConversion, "packaging converts the data of the steps before it"},
+}
+
+// validateStepOrder checks that enabled steps are listed once and in an order the pipeline can run
+// Import steps come first: every other step reads the imported data. Several import steps may be
+// enabled together, since a job runs only the one matching its input
+func validateStepOrder(steps []StepName) error {
+	position := make(map[StepName]int, len(steps))
+	for i, step := range steps {
+		if _, listed := position[step]; listed {
+			return fmt.Errorf("step '%s' is listed more than once in enabled_steps", step)
+		}
+		position[step] = i
+	}
+
+	var firstNonImport StepName
+	for _, step := range steps {
+		if !IsImportStep(step) {
+			if firstNonImport == "" {
+				firstNonImport = step
+			}
+			continue
+		}
+		if firstNonImport != "" {
+			return fmt.Errorf("import step '%s' must come before '%s' in enabled_steps: steps after an import read its data", step, firstNonImport)
+		}
+	}
+
+	for _, rule := range stepOrderRules {
+		first, firstEnabled := position[rule.First]
+		then, thenEnabled := position[rule.Then]
+		if firstEnabled && thenEnabled && then < first {
+			return fmt.Errorf("step '%s' must come before '%s' in enabled_steps: %s", rule.First, rule.Then, rule.Reason)
+		}
+	}
+	return nil
+}
+
 // ValidateJobsDir checks if the jobs directory exists and is writable
 // Creates the directory automatically if it doesn't exist
 func ValidateJobsDir(path string) error {
@@ -251,81 +467,7 @@ func ValidateJobsDir(path string) error {
 }
 
 // ValidateServiceConnectivity checks if required service URLs are reachable
-// This performs a lightweight HTTP HEAD request to verify connectivity
-// Validates that configured service URLs are reachable
+// Only services backing the enabled steps are checked (see ValidateServiceConnectivityForSteps)
 func (c *ProjectConfig) ValidateServiceConnectivity() error {
-	client := &http.Client{
-		Timeout: 5 * time.Second, // Quick connectivity check
-	}
-
-	// Check TORCH connectivity if base URL is configured
-	// TORCH is used by InputTypeCRTDL and InputTypeTORCHURL
-	if c.Services.TORCH.BaseURL != "" {
-		parsedURL, err := url.Parse(c.Services.TORCH.BaseURL)
-		if err != nil {
-			return fmt.Errorf("invalid TORCH service URL: %w", err)
-		}
-
-		checkURL := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
-
-		req, err := http.NewRequest("HEAD", checkURL, nil)
-		if err != nil {
-			return fmt.Errorf("failed to create request for TORCH service: %w", err)
-		}
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return fmt.Errorf("TORCH service unreachable at %s: %w", checkURL, err)
-		}
-		defer func() { _ = resp.Body.Close() }()
-	}
-
-	for _, step := range c.Pipeline.EnabledSteps {
-		var serviceURL string
-		var serviceName string
-
-		switch step {
-		case StepDIMP:
-			serviceURL = c.Services.DIMP.URL
-			serviceName = "DIMP"
-		case StepCSVConversion:
-			serviceURL = c.Services.CSVConversion.URL
-			serviceName = "CSV Conversion"
-		case StepParquetConversion:
-			serviceURL = c.Services.ParquetConversion.URL
-			serviceName = "Parquet Conversion"
-		default:
-			continue // Skip steps that don't require external services
-		}
-
-		if serviceURL == "" {
-			continue // Already validated in Validate()
-		}
-
-		// Parse the base URL
-		parsedURL, err := url.Parse(serviceURL)
-		if err != nil {
-			return fmt.Errorf("invalid %s service URL: %w", serviceName, err)
-		}
-
-		// Construct a simple health check URL (just the base)
-		checkURL := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
-
-		// Attempt to connect
-		req, err := http.NewRequest("HEAD", checkURL, nil)
-		if err != nil {
-			return fmt.Errorf("failed to create request for %s service: %w", serviceName, err)
-		}
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return fmt.Errorf("%s service unreachable at %s: %w", serviceName, checkURL, err)
-		}
-		_ = resp.Body.Close()
-
-		// Any response (even 404) means the host is reachable
-		// We're just checking connectivity, not full service health
-	}
-
-	return nil
+	return c.ValidateServiceConnectivityForSteps(c.Pipeline.EnabledSteps)
 }
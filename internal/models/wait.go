@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// WaitReason says why a running step is paused
+type WaitReason string
+
+const (
+	WaitRetry WaitReason = "retry" // Backoff before retrying a failed HTTP request
+	WaitPoll  WaitReason = "poll"  // Interval before the next TORCH extraction status poll
+	WaitSlot  WaitReason = "slot"  // Queued until another job's TORCH extraction finishes
+)
+
+// WaitState describes a pause of a running step, so users see what a quiet job is waiting for
+type WaitState struct {
+	Reason      WaitReason `json:"reason"`
+	Target      string     `json:"target"`                 // e.g. "POST /fhir/$de-identify" or "TORCH extraction"
+	Attempt     int        `json:"attempt"`                // Number of the upcoming retry or poll
+	MaxAttempts int        `json:"max_attempts,omitempty"` // Retries allowed (0 for polls, which run until the timeout)
+	NextAt      time.Time  `json:"next_at"`                // When the retry or poll happens
+	GivesUpAt   time.Time  `json:"gives_up_at,omitempty"`  // Poll timeout
+}
+
+// Describe renders the wait for spinners and status output, relative to now
+func (w WaitState) Describe(now time.Time) string {
+	var what string
+	switch w.Reason {
+	case WaitPoll:
+		what = fmt.Sprintf("poll %d of %s", w.Attempt, w.Target)
+	case WaitSlot:
+		what = fmt.Sprintf("check %d for a free slot of %s", w.Attempt, w.Target)
+	default:
+		what = fmt.Sprintf("retry %d/%d of %s", w.Attempt, w.MaxAttempts, w.Target)
+	}
+
+	when := fmt.Sprintf("at %s (in %s)", w.NextAt.In(now.Location()).Format("15:04:05"), w.NextAt.Sub(now).Round(time.Second))
+	if !w.NextAt.After(now) {
+		when = fmt.Sprintf("at %s (overdue by %s)", w.NextAt.In(now.Location()).Format("15:04:05"), now.Sub(w.NextAt).Round(time.Second))
+	}
+
+	description := what + " " + when
+	if !w.GivesUpAt.IsZero() {
+		description += fmt.Sprintf(", gives up at %s", w.GivesUpAt.In(now.Location()).Format("15:04:05"))
+	}
+	return description
+}
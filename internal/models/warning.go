@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// WarningCode categorizes non-fatal issues recorded on a step
+type WarningCode string
+
+const (
+	WarningEmptyLines          WarningCode = "empty_lines"           // Blank lines skipped in an NDJSON file
+	WarningNonFHIRLines        WarningCode = "non_fhir_lines"        // Lines without resourceType passed through or quarantined (services.dimp.non_fhir_lines)
+	WarningUnknownResourceType WarningCode = "unknown_resource_type" // Resources whose resourceType is not a FHIR R4 resource type
+	WarningFilesExcluded       WarningCode = "files_excluded"        // Files skipped by import.include/import.exclude
+	WarningFilesIgnored        WarningCode = "files_ignored"         // Non-NDJSON files in an imported directory
+	WarningEmptyResult         WarningCode = "empty_result"          // A TORCH extraction or FHIR export returned no files
+	WarningStudiesMissing      WarningCode = "studies_missing"       // Studies not found on the DICOMweb server
+	WarningStudiesWithoutUID   WarningCode = "studies_without_uid"   // ImagingStudy resources without a Study Instance UID
+	WarningExportErrors        WarningCode = "export_errors"         // OperationOutcomes listed in a FHIR $export manifest
+	WarningDIMPQuota           WarningCode = "dimp_quota"            // Monthly DIMP usage approached services.dimp.usage_quota
+)
+
+// AllWarningCodes lists every warning class
+var AllWarningCodes = []WarningCode{
+	WarningEmptyLines,
+	WarningNonFHIRLines,
+	WarningUnknownResourceType,
+	WarningFilesExcluded,
+	WarningFilesIgnored,
+	WarningEmptyResult,
+	WarningStudiesMissing,
+	WarningStudiesWithoutUID,
+	WarningExportErrors,
+	WarningDIMPQuota,
+}
+
+// DefaultStrictWarnings are the warning classes pipeline.strict escalates to step failures
+// unless pipeline.strict_warnings lists others: both mean data reaching the output unchecked
+var DefaultStrictWarnings = []WarningCode{WarningUnknownResourceType, WarningNonFHIRLines}
+
+// IsValidWarningCode checks if the warning code is recognized
+func IsValidWarningCode(code WarningCode) bool {
+	return slices.Contains(AllWarningCodes, code)
+}
+
+// IsStrictWarning reports whether warnings of this class fail the step
+func (c PipelineConfig) IsStrictWarning(code WarningCode) bool {
+	if !c.Strict {
+		return false
+	}
+	if len(c.StrictWarnings) > 0 {
+		return slices.Contains(c.StrictWarnings, code)
+	}
+	return slices.Contains(DefaultStrictWarnings, code)
+}
+
+// ValidateStrictWarnings checks that pipeline.strict_warnings only names known warning classes
+func ValidateStrictWarnings(codes []WarningCode) error {
+	for _, code := range codes {
+		if !IsValidWarningCode(code) {
+			return fmt.Errorf("pipeline.strict_warnings: unknown warning class %q", code)
+		}
+	}
+	return nil
+}
+
+// MaxStepWarnings caps the warnings kept per step; further ones are only counted
+const MaxStepWarnings = 100
+
+// StepWarning is a non-fatal issue found while a step ran
+// Unlike debug log lines, warnings are part of the job state and shown by `aether pipeline status`
+type StepWarning struct {
+	Code      WarningCode `json:"code"`
+	File      string      `json:"file,omitempty"` // File the warning refers to, empty for step-wide warnings
+	Count     int         `json:"count"`          // Affected lines, files or resources
+	Message   string      `json:"message"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// SetWarning records a warning on the step
+// A warning with the same code and file replaces the earlier one, so processing a file again
+// (retry, resume) updates its count instead of adding a duplicate
+func (s *PipelineStep) SetWarning(warning StepWarning) {
+	for i := range s.Warnings {
+		if s.Warnings[i].Code == warning.Code && s.Warnings[i].File == warning.File {
+			s.Warnings[i] = warning
+			return
+		}
+	}
+	if len(s.Warnings) >= MaxStepWarnings {
+		s.WarningsDropped++
+		return
+	}
+	s.Warnings = append(s.Warnings, warning)
+}
+
+// ClearFileWarnings removes the warnings recorded for file, before the file is processed again
+func (s *PipelineStep) ClearFileWarnings(file string) {
+	s.Warnings = slices.DeleteFunc(s.Warnings, func(warning StepWarning) bool {
+		return warning.File == file
+	})
+}
+
+// WarningCount returns the number of warnings recorded on all steps of the job
+func (j PipelineJob) WarningCount() int {
+	count := 0
+	for _, step := range j.Steps {
+		count += len(step.Warnings) + step.WarningsDropped
+	}
+	return count
+}
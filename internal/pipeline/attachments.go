@@ -0,0 +1,242 @@
+package pipeline
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+const (
+	// AttachmentsDirName is the job subdirectory holding externalized attachment data
+	AttachmentsDirName = "attachments"
+
+	// ExternalizedBinaryExtensionURL marks Binary resources whose data was moved to a file
+	// The extension's valueUrl is the file path relative to the job directory
+	ExternalizedBinaryExtensionURL = "https://github.com/trobanga/aether/StructureDefinition/externalized-binary"
+)
+
+// AttachmentStats counts what the attachment policy did
+type AttachmentStats struct {
+	Kept         int   // Attachments left inline
+	Stripped     int   // Attachments whose data was removed
+	Externalized int   // Attachments whose data was moved to attachments/
+	BytesRemoved int64 // Base64 bytes removed from the NDJSON files
+}
+
+// Add accumulates other into s
+func (s *AttachmentStats) Add(other AttachmentStats) {
+	s.Kept += other.Kept
+	s.Stripped += other.Stripped
+	s.Externalized += other.Externalized
+	s.BytesRemoved += other.BytesRemoved
+}
+
+// applyAttachmentPolicy rewrites imported files according to the attachments policy
+// Binary.data and Attachment.data (any object with data and contentType, at any depth,
+// including contained resources and Bundle entries) are kept, stripped or externalized.
+// File sizes are refreshed for rewritten files
+func applyAttachmentPolicy(importDir string, jobDir string, files []models.FHIRDataFile, policy models.AttachmentConfig, maxLineBytes int, logger *lib.Logger) (AttachmentStats, error) {
+	var total AttachmentStats
+	if !policy.IsActive() {
+		return total, nil
+	}
+
+	for i := range files {
+		path := filepath.Join(importDir, files[i].FileName)
+		stats, changed, err := applyAttachmentPolicyToFile(path, jobDir, policy, maxLineBytes)
+		if err != nil {
+			return total, fmt.Errorf("failed to apply attachment policy to %s: %w", files[i].FileName, err)
+		}
+		total.Add(stats)
+
+		if changed {
+			files[i].FileSize = lib.GetFileSize(path)
+			logger.Debug("Applied attachment policy",
+				"file", files[i].FileName,
+				"stripped", stats.Stripped,
+				"externalized", stats.Externalized,
+				"bytes_removed", stats.BytesRemoved)
+		}
+	}
+
+	if total.Stripped > 0 || total.Externalized > 0 {
+		logger.Info("Attachment policy applied",
+			"mode", policy.Mode,
+			"kept", total.Kept,
+			"stripped", total.Stripped,
+			"externalized", total.Externalized,
+			"bytes_removed", total.BytesRemoved)
+	}
+	return total, nil
+}
+
+// applyAttachmentPolicyToFile rewrites one NDJSON file in place
+// Lines without attachment data are copied unchanged; the file is left untouched if nothing changed
+func applyAttachmentPolicyToFile(path string, jobDir string, policy models.AttachmentConfig, maxLineBytes int) (AttachmentStats, bool, error) {
+	var stats AttachmentStats
+	changed, err := rewriteResourcesInPlace(path, maxLineBytes, `"data"`, func(resource map[string]any) (bool, error) {
+		resourceStats, err := applyAttachmentPolicyToValue(resource, jobDir, policy)
+		if err != nil {
+			return false, err
+		}
+		stats.Add(resourceStats)
+		return resourceStats.Stripped > 0 || resourceStats.Externalized > 0, nil
+	})
+	return stats, changed, err
+}
+
+// applyAttachmentPolicyToValue walks a JSON value and applies the policy to every Binary and Attachment
+func applyAttachmentPolicyToValue(value any, jobDir string, policy models.AttachmentConfig) (AttachmentStats, error) {
+	var stats AttachmentStats
+
+	switch v := value.(type) {
+	case map[string]any:
+		data, hasData := v["data"].(string)
+		_, hasContentType := v["contentType"].(string)
+		if hasData && (hasContentType || v["resourceType"] == "Binary") {
+			return applyAttachmentPolicyToData(v, data, jobDir, policy)
+		}
+		for _, child := range v {
+			childStats, err := applyAttachmentPolicyToValue(child, jobDir, policy)
+			if err != nil {
+				return stats, err
+			}
+			stats.Add(childStats)
+		}
+
+	case []any:
+		for _, child := range v {
+			childStats, err := applyAttachmentPolicyToValue(child, jobDir, policy)
+			if err != nil {
+				return stats, err
+			}
+			stats.Add(childStats)
+		}
+	}
+
+	return stats, nil
+}
+
+// applyAttachmentPolicyToData keeps, strips or externalizes the base64 data of one Binary or Attachment
+func applyAttachmentPolicyToData(element map[string]any, data string, jobDir string, policy models.AttachmentConfig) (AttachmentStats, error) {
+	var stats AttachmentStats
+	isBinary := element["resourceType"] == "Binary"
+	decodedSize := base64DecodedSize(data)
+
+	mode := policy.Mode
+	if maxBytes := policy.GetMaxSizeBytes(); maxBytes > 0 && decodedSize > maxBytes {
+		mode = models.AttachmentStrip
+	}
+
+	switch mode {
+	case models.AttachmentStrip:
+		delete(element, "data")
+		if !isBinary {
+			if _, ok := element["size"]; !ok {
+				element["size"] = decodedSize
+			}
+		}
+		stats.Stripped++
+		stats.BytesRemoved += int64(len(data))
+
+	case models.AttachmentExternalize:
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return stats, fmt.Errorf("invalid base64 attachment data: %w", err)
+		}
+		contentType, _ := element["contentType"].(string)
+		relPath, err := writeExternalizedAttachment(jobDir, decoded, contentType)
+		if err != nil {
+			return stats, err
+		}
+
+		delete(element, "data")
+		if isBinary {
+			extensions, _ := element["extension"].([]any)
+			element["extension"] = append(extensions, map[string]any{
+				"url":      ExternalizedBinaryExtensionURL,
+				"valueUrl": relPath,
+			})
+		} else {
+			element["url"] = relPath
+			if _, ok := element["size"]; !ok {
+				element["size"] = len(decoded)
+			}
+			if _, ok := element["hash"]; !ok {
+				sum := sha1.Sum(decoded) // FHIR Attachment.hash is defined as base64 SHA-1
+				element["hash"] = base64.StdEncoding.EncodeToString(sum[:])
+			}
+		}
+		stats.Externalized++
+		stats.BytesRemoved += int64(len(data))
+
+	default:
+		stats.Kept++
+	}
+
+	return stats, nil
+}
+
+// base64DecodedSize returns the number of bytes a padded base64 string decodes to
+func base64DecodedSize(data string) int64 {
+	size := int64(len(data)) / 4 * 3
+	size -= int64(len(data) - len(strings.TrimRight(data, "=")))
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+// writeExternalizedAttachment stores attachment bytes under attachments/<sha256><ext>
+// Content addressing de-duplicates identical documents and makes re-runs idempotent
+// Returns the path relative to the job directory
+func writeExternalizedAttachment(jobDir string, content []byte, contentType string) (string, error) {
+	sum := sha256.Sum256(content)
+	name := hex.EncodeToString(sum[:]) + attachmentExtension(contentType)
+	relPath := filepath.ToSlash(filepath.Join(AttachmentsDirName, name))
+	path := filepath.Join(jobDir, AttachmentsDirName, name)
+
+	if _, err := os.Stat(path); err == nil {
+		return relPath, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+	if err := os.WriteFile(path+".part", content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write attachment: %w", err)
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		_ = os.Remove(path + ".part")
+		return "", fmt.Errorf("failed to write attachment: %w", err)
+	}
+	return relPath, nil
+}
+
+// attachmentExtension returns a file extension for a MIME type, or .bin if unknown
+func attachmentExtension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ".bin"
+	}
+	switch mediaType {
+	case "application/pdf":
+		return ".pdf"
+	case "image/jpeg":
+		return ".jpg"
+	case "text/plain":
+		return ".txt"
+	}
+	if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ".bin"
+}
@@ -0,0 +1,100 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// ErrTimeBudgetExceeded marks steps that ran past their share of pipeline.time_budget
+var ErrTimeBudgetExceeded = errors.New("time budget exceeded")
+
+// StepTimeShare returns a step's share of the job's time budget and the budget left before it starts
+// The remaining budget is the total minus the time spent in earlier step attempts; it is split
+// across the steps not completed yet in proportion to their weights. ok is false without a budget
+func StepTimeShare(job *models.PipelineJob, stepName models.StepName) (share time.Duration, remaining time.Duration, ok bool) {
+	budget := job.Config.Pipeline.TimeBudget
+	if !budget.IsActive() {
+		return 0, 0, false
+	}
+
+	remaining = budget.Total() - consumedStepTime(job)
+	if remaining <= 0 {
+		return 0, 0, true
+	}
+
+	totalWeight := budget.Weight(stepName)
+	for _, step := range job.Steps {
+		if step.Name != stepName && step.Status != models.StepStatusCompleted {
+			totalWeight += budget.Weight(step.Name)
+		}
+	}
+	share = remaining * time.Duration(budget.Weight(stepName)) / time.Duration(totalWeight)
+	return share, remaining, true
+}
+
+// consumedStepTime sums the time spent in completed steps and in the last attempt of failed
+// and cancelled steps
+func consumedStepTime(job *models.PipelineJob) time.Duration {
+	var consumed time.Duration
+	for _, step := range job.Steps {
+		if step.StartedAt == nil {
+			continue
+		}
+		var end time.Time
+		switch {
+		case step.Status == models.StepStatusCompleted && step.CompletedAt != nil:
+			end = *step.CompletedAt
+		case (step.Status == models.StepStatusFailed || step.Status == models.StepStatusCancelled) && step.LastError != nil:
+			end = step.LastError.Timestamp
+		default:
+			continue
+		}
+		if end.After(*step.StartedAt) {
+			consumed += end.Sub(*step.StartedAt)
+		}
+	}
+	return consumed
+}
+
+// stepDeadline is a running step's share of the time budget as a context deadline
+type stepDeadline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	step   models.StepName
+	share  time.Duration
+}
+
+// startStepDeadline derives the step's context from the run's context parent; without a budget
+// the step's context only ends with parent. Stop must be called when the step finishes
+func startStepDeadline(parent context.Context, job *models.PipelineJob, stepName models.StepName, logger *lib.Logger) *stepDeadline {
+	share, remaining, ok := StepTimeShare(job, stepName)
+	if !ok {
+		ctx, cancel := context.WithCancel(parent)
+		return &stepDeadline{ctx: ctx, cancel: cancel, step: stepName}
+	}
+
+	logger.Info("Step time budget",
+		"step", stepName,
+		"share", share.Round(time.Second),
+		"remaining", remaining.Round(time.Second))
+	ctx, cancel := context.WithTimeout(parent, share)
+	return &stepDeadline{ctx: ctx, cancel: cancel, step: stepName, share: share}
+}
+
+// Stop releases the deadline's timer
+func (d *stepDeadline) Stop() {
+	d.cancel()
+}
+
+// Err returns an ErrTimeBudgetExceeded error once the step's share is used up, nil before
+func (d *stepDeadline) Err() error {
+	if d.share == 0 || !errors.Is(d.ctx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+	return fmt.Errorf("%w: step %s used up its share of %s", ErrTimeBudgetExceeded, d.step, d.share.Round(time.Second))
+}
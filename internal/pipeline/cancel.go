@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// ErrJobFinished is returned when cancelling a job that has already completed or failed
+var ErrJobFinished = errors.New("job has already finished")
+
+// cancelStopTimeout is how long CancelJob waits for the process running a job to exit
+const cancelStopTimeout = 10 * time.Second
+
+// CancelJob stops the process running a job and marks the job failed
+// The lock holder is sent SIGTERM (killed where signals are unsupported); a job that is not
+// running, e.g. one that is still pending, is failed directly. The interrupted step is failed
+// (non-transient), later steps stay pending, so 'pipeline continue' can re-run the step
+func CancelJob(config models.ProjectConfig, jobID string, reason string, logger *lib.Logger) (*models.PipelineJob, error) {
+	job, err := services.LoadJobState(config.JobsDir, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == models.JobStatusCompleted || job.Status == models.JobStatusFailed {
+		return job, ErrJobFinished
+	}
+
+	if services.IsJobLocked(config.JobsDir, jobID) {
+		pid, found := services.LockHolderPID(config.JobsDir, jobID)
+		if !found {
+			return nil, fmt.Errorf("job %s is locked by an unknown process", jobID)
+		}
+		logger.Info("Stopping job process", "job_id", jobID, "pid", pid)
+		if err := stopProcess(pid); err != nil {
+			return nil, fmt.Errorf("failed to stop process %d running job %s: %w", pid, jobID, err)
+		}
+		deadline := time.Now().Add(cancelStopTimeout)
+		for services.IsJobLocked(config.JobsDir, jobID) {
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("process %d running job %s did not exit within %s", pid, jobID, cancelStopTimeout)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	message := "job cancelled"
+	if reason != "" {
+		message += ": " + reason
+	}
+
+	var cancelled *models.PipelineJob
+	err = services.WithJobLock(config.JobsDir, jobID, logger, func() error {
+		// Reload: the stopped process may have saved progress before exiting
+		job, err := services.LoadJobState(config.JobsDir, jobID)
+		if err != nil {
+			return err
+		}
+		recordJobEvent(job, logger, models.EventJobCancelled, job.CurrentStep, message, nil)
+		if step, found := models.GetStepByName(*job, models.StepName(job.CurrentStep)); found && step.Status != models.StepStatusCompleted {
+			updated := models.ReplaceStep(*job, models.FailStep(step, models.ErrorTypeNonTransient, message, 0))
+			job = &updated
+		}
+		cancelled = FailJob(job, message)
+		return services.SaveJobState(config.JobsDir, cancelled)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Cancelled job", "job_id", jobID, "reason", reason)
+	return cancelled, nil
+}
+
+// stopProcess asks a process to terminate and kills it where SIGTERM is not supported
+func stopProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			return nil
+		}
+		return process.Kill()
+	}
+	return nil
+}
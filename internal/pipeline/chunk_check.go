@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DeadLetterDirName is the job subdirectory holding DIMP requests whose response was rejected
+const DeadLetterDirName = "dead-letter"
+
+// ChunkEntryMismatchError reports a DIMP response to a Bundle (or Bundle chunk) that does not
+// have as many entries as the request, e.g. because the service silently truncated it
+type ChunkEntryMismatchError struct {
+	Chunk          string // "Bundle" or "chunk 2/5"
+	Sent           int
+	Received       int
+	Attempts       int
+	DeadLetterFile string // Request and response as kept for inspection (empty if not written)
+}
+
+func (e *ChunkEntryMismatchError) Error() string {
+	msg := fmt.Sprintf("DIMP returned %d of %d entries for %s (%d attempts)", e.Received, e.Sent, e.Chunk, e.Attempts)
+	if e.DeadLetterFile != "" {
+		msg += fmt.Sprintf("; request kept in %s", e.DeadLetterFile)
+	}
+	return msg
+}
+
+// deadLetterRecord is the content of a dead-letter file
+type deadLetterRecord struct {
+	File            string         `json:"file"`
+	Resource        int            `json:"resource"` // Position of the Bundle among the file's resources
+	BundleID        string         `json:"bundle_id,omitempty"`
+	Chunk           string         `json:"chunk"`
+	SentEntries     int            `json:"sent_entries"`
+	ReceivedEntries int            `json:"received_entries"`
+	Timestamp       time.Time      `json:"timestamp"`
+	Request         map[string]any `json:"request"`
+	Response        map[string]any `json:"response"`
+}
+
+// writeDeadLetter stores a rejected request and its response in dir
+// Returns the file name relative to the job directory
+func writeDeadLetter(dir string, record deadLetterRecord) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	base := strings.TrimSuffix(record.File, filepath.Ext(record.File))
+	chunk := strings.NewReplacer(" ", "-", "/", "-of-").Replace(strings.ToLower(record.Chunk))
+	name := fmt.Sprintf("%s-resource%d-%s.json", base, record.Resource, chunk)
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode dead-letter record: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write dead-letter record: %w", err)
+	}
+	return filepath.Join(DeadLetterDirName, name), nil
+}
+
+// bundleEntryCount returns the number of entries of a Bundle
+// Entries decoded from JSON are []any; chunks built in Go may use typed slices
+func bundleEntryCount(bundle map[string]any) int {
+	entries := reflect.ValueOf(bundle["entry"])
+	if entries.Kind() != reflect.Slice {
+		return 0
+	}
+	return entries.Len()
+}
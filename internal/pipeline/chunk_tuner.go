@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const (
+	// minAdaptiveSplitBytes is the smallest split threshold adaptive splitting shrinks to
+	minAdaptiveSplitBytes = 256 * 1024
+
+	// maxAdaptiveSplitBytes is the largest split threshold adaptive splitting grows to
+	// (the upper bound of bundle_split_threshold_mb)
+	maxAdaptiveSplitBytes = 100 * 1024 * 1024
+
+	// adaptiveSplitGrowth is the factor the threshold grows by after a fast response
+	adaptiveSplitGrowth = 1.25
+)
+
+// chunkTuner adjusts the Bundle split threshold of a DIMP step from DIMP's responses
+// (services.dimp.adaptive_split): a 413 or a timeout halves the threshold and the Bundle is
+// split again, a response slower than the target shrinks it in proportion, and a fast response
+// to a chunk that filled the threshold grows it by a quarter. The threshold never grows back
+// above a size DIMP rejected with 413
+type chunkTuner struct {
+	ctx    context.Context
+	job    *models.PipelineJob
+	logger *lib.Logger
+	target time.Duration
+
+	mu          sync.Mutex
+	threshold   int
+	ceiling     int // Smallest request DIMP rejected with 413, 0 if none
+	adjustments int
+}
+
+// newChunkTuner returns a tuner starting at the configured split threshold, or nil if
+// adaptive splitting is disabled
+func newChunkTuner(ctx context.Context, job *models.PipelineJob, logger *lib.Logger, thresholdBytes int) *chunkTuner {
+	if !job.Config.Services.DIMP.AdaptiveSplit {
+		return nil
+	}
+	return &chunkTuner{
+		ctx:       ctx,
+		job:       job,
+		logger:    logger,
+		target:    job.Config.Services.DIMP.GetSplitTarget(),
+		threshold: thresholdBytes,
+	}
+}
+
+// Threshold returns the current split threshold in bytes
+func (t *chunkTuner) Threshold() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.threshold
+}
+
+// Observe adjusts the threshold after DIMP answered a request of size bytes in duration
+func (t *chunkTuner) Observe(size int, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case duration > t.target:
+		factor := max(float64(t.target)/float64(duration), 0.5)
+		t.set(int(float64(t.threshold)*factor), "slow response", size, duration)
+	case duration < t.target/2 && size >= t.threshold/2:
+		grown := int(float64(t.threshold) * adaptiveSplitGrowth)
+		if t.ceiling > 0 {
+			grown = min(grown, t.ceiling*3/4)
+		}
+		t.set(grown, "fast response", size, duration)
+	}
+}
+
+// Shrink halves the threshold after a request of size bytes failed with err
+// Returns true if err is a 413 or a timeout and the threshold got smaller, i.e. the request is
+// worth splitting further and sending again
+func (t *chunkTuner) Shrink(size int, err error) bool {
+	reason := ""
+	var dimpErr *services.DIMPError
+	var netErr net.Error
+	switch {
+	case t.ctx.Err() != nil: // The step was stopped, not DIMP
+		return false
+	case errors.As(err, &dimpErr) && dimpErr.StatusCode == http.StatusRequestEntityTooLarge:
+		reason = "HTTP 413"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		reason = "timeout"
+	default:
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if reason == "HTTP 413" && (t.ceiling == 0 || size < t.ceiling) {
+		t.ceiling = size
+	}
+	previous := t.threshold
+	if !t.set(min(t.threshold, size)/2, reason, size, 0) {
+		return false
+	}
+	recordJobEvent(t.job, t.logger, models.EventSplitAdjusted, string(models.StepDIMP),
+		"split threshold lowered after "+reason,
+		map[string]any{"reason": reason, "request_bytes": size, "previous_bytes": previous, "threshold_bytes": t.threshold})
+	return true
+}
+
+// set changes the threshold within the adaptive bounds; returns false if it did not change
+func (t *chunkTuner) set(threshold int, reason string, size int, duration time.Duration) bool {
+	threshold = min(max(threshold, minAdaptiveSplitBytes), maxAdaptiveSplitBytes)
+	if threshold == t.threshold {
+		return false
+	}
+	t.logger.Debug("Adjusted Bundle split threshold",
+		"reason", reason,
+		"request_bytes", size,
+		"duration", duration,
+		"previous_bytes", t.threshold,
+		"threshold_bytes", threshold)
+	t.threshold = threshold
+	t.adjustments++
+	return true
+}
+
+// LogSummary logs the threshold the step converged on
+func (t *chunkTuner) LogSummary() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.adjustments == 0 {
+		return
+	}
+	t.logger.Info("Adaptive Bundle split threshold",
+		"threshold_mb", float64(t.threshold)/(1024*1024),
+		"adjustments", t.adjustments,
+		"job_id", t.job.JobID)
+}
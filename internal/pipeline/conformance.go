@@ -0,0 +1,291 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// conformanceIdentifier is the identifier value of the synthetic Patient sent to DIMP;
+// it must not appear in the pseudonymized output
+const conformanceIdentifier = "aether-conformance-0001"
+
+// ConformanceCheck records one operation of the conformance battery
+type ConformanceCheck struct {
+	Name       string          `json:"name"`
+	Status     PreflightStatus `json:"status"`
+	Message    string          `json:"message"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// ConformanceService holds the checks run against one configured service and the version it reported
+type ConformanceService struct {
+	Service string                  `json:"service"` // torch, dimp, csv_conversion, parquet_conversion or dicomweb
+	URL     string                  `json:"url"`
+	Version services.ServiceVersion `json:"version"`
+	Checks  []ConformanceCheck      `json:"checks"`
+}
+
+// IsCompatible returns true if no check against the service failed
+func (s ConformanceService) IsCompatible() bool {
+	for _, check := range s.Checks {
+		if check.Status == PreflightFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// ConformanceReport is the compatibility report of `aether conformance`
+type ConformanceReport struct {
+	AetherVersion string               `json:"aether_version"`
+	CheckedAt     time.Time            `json:"checked_at"`
+	Services      []ConformanceService `json:"services"`
+}
+
+// IsCompatible returns true if every service passed its checks
+func (r ConformanceReport) IsCompatible() bool {
+	for _, service := range r.Services {
+		if !service.IsCompatible() {
+			return false
+		}
+	}
+	return true
+}
+
+// ConformanceOptions configures the conformance battery
+type ConformanceOptions struct {
+	CRTDLPath string // CRTDL of the tiny TORCH extraction; empty skips the extraction
+}
+
+// RunConformance runs the conformance battery against every configured service, whether or
+// not its step is enabled. All operations are small and use synthetic data, except the TORCH
+// extraction, which only runs with a CRTDL chosen by the operator and is deleted afterwards.
+// Like RunPreflight, it never aborts early
+func RunConformance(config *models.ProjectConfig, httpClient *services.HTTPClient, logger *lib.Logger, opts ConformanceOptions) ConformanceReport {
+	report := ConformanceReport{AetherVersion: lib.Version, CheckedAt: lib.Now()}
+
+	if torch := config.Services.TORCH; torch.BaseURL != "" {
+		report.Services = append(report.Services, conformanceTORCH(torch, httpClient, logger, opts))
+	}
+	if dimpURL := config.Services.DIMP.URL; dimpURL != "" {
+		report.Services = append(report.Services, conformanceDIMP(dimpURL, httpClient, logger))
+	}
+	for _, step := range []models.StepName{models.StepCSVConversion, models.StepParquetConversion} {
+		if step == models.StepParquetConversion && config.Pipeline.Packaging.WritesParquetLocally() {
+			continue
+		}
+		if serviceURL := config.Services.GetServiceURL(step); serviceURL != "" {
+			report.Services = append(report.Services, conformanceConversion(string(step), serviceURL, httpClient, logger))
+		}
+	}
+	if dicomwebURL := config.Services.Imaging.DICOMwebURL; dicomwebURL != "" {
+		report.Services = append(report.Services, conformanceDICOMweb(config, httpClient, logger))
+	}
+
+	return report
+}
+
+// runConformanceCheck times a check and appends it to the service
+func runConformanceCheck(service *ConformanceService, name string, logger *lib.Logger, check func() (PreflightStatus, string)) {
+	start := time.Now()
+	status, message := check()
+	duration := time.Since(start)
+
+	logger.Debug("Conformance check finished", "service", service.Service, "check", name, "status", status, "duration", duration)
+	service.Checks = append(service.Checks, ConformanceCheck{
+		Name:       name,
+		Status:     status,
+		Message:    message,
+		DurationMs: duration.Milliseconds(),
+	})
+}
+
+// versionCheck records the reported version; a service that does not report one is still compatible
+func versionCheck(service *ConformanceService, fetch func() (services.ServiceVersion, error)) func() (PreflightStatus, string) {
+	return func() (PreflightStatus, string) {
+		version, err := fetch()
+		service.Version = version
+		if err != nil {
+			return PreflightSkipped, err.Error()
+		}
+		return PreflightPassed, version.String()
+	}
+}
+
+// conformanceTORCH checks authentication and, with a CRTDL, a tiny extraction and its cleanup
+func conformanceTORCH(config models.TORCHConfig, httpClient *services.HTTPClient, logger *lib.Logger, opts ConformanceOptions) ConformanceService {
+	service := ConformanceService{Service: "torch", URL: config.BaseURL}
+	torchClient := services.NewTORCHClient(config, httpClient, logger)
+
+	runConformanceCheck(&service, "version", logger, versionCheck(&service, torchClient.FetchVersion))
+
+	runConformanceCheck(&service, "auth", logger, func() (PreflightStatus, string) {
+		if err := torchClient.CheckAuth(); err != nil {
+			return PreflightFailed, err.Error()
+		}
+		return PreflightPassed, "credentials accepted"
+	})
+
+	var statusURL string
+	var fileURLs []string
+	runConformanceCheck(&service, "tiny_extraction", logger, func() (PreflightStatus, string) {
+		if opts.CRTDLPath == "" {
+			return PreflightSkipped, "no CRTDL given (--crtdl)"
+		}
+		var err error
+		statusURL, err = torchClient.SubmitExtraction(opts.CRTDLPath)
+		if err != nil {
+			return PreflightFailed, fmt.Sprintf("submission failed: %v", err)
+		}
+		fileURLs, err = torchClient.PollExtractionStatus(statusURL, lib.NoProgress)
+		if err != nil {
+			_ = torchClient.CancelExtraction(statusURL)
+			statusURL = ""
+			return PreflightFailed, fmt.Sprintf("extraction did not complete: %v", err)
+		}
+		return PreflightPassed, fmt.Sprintf("extraction completed with %d file(s)", len(fileURLs))
+	})
+
+	runConformanceCheck(&service, "result_cleanup", logger, func() (PreflightStatus, string) {
+		if statusURL == "" {
+			return PreflightSkipped, "no extraction result to delete"
+		}
+		err := torchClient.DeleteExtractionResult(statusURL, fileURLs)
+		switch {
+		case errors.Is(err, services.ErrTORCHCleanupUnsupported):
+			return PreflightSkipped, "server does not support deleting results; cleanup_after_download cannot be used"
+		case err != nil:
+			return PreflightFailed, err.Error()
+		}
+		return PreflightPassed, "extraction result deleted and no longer served"
+	})
+
+	return service
+}
+
+// conformancePatient is the synthetic Patient sent to DIMP
+func conformancePatient() map[string]any {
+	return map[string]any{
+		"resourceType": "Patient",
+		"id":           "aether-conformance-patient",
+		"identifier": []any{
+			map[string]any{"system": "urn:aether:conformance", "value": conformanceIdentifier},
+		},
+		"gender":    "unknown",
+		"birthDate": "1970-01-01",
+	}
+}
+
+// conformanceBundle is a synthetic batch Bundle with a Patient and an Observation referencing it
+func conformanceBundle() map[string]any {
+	return map[string]any{
+		"resourceType": "Bundle",
+		"type":         "batch",
+		"entry": []any{
+			map[string]any{"resource": conformancePatient()},
+			map[string]any{"resource": map[string]any{
+				"resourceType": "Observation",
+				"id":           "aether-conformance-observation",
+				"status":       "final",
+				"code": map[string]any{"coding": []any{
+					map[string]any{"system": "http://loinc.org", "code": "718-7"},
+				}},
+				"subject":       map[string]any{"reference": "Patient/aether-conformance-patient"},
+				"valueQuantity": map[string]any{"value": 13.5, "unit": "g/dL", "system": "http://unitsofmeasure.org", "code": "g/dL"},
+			}},
+		},
+	}
+}
+
+// conformanceDIMP round-trips a Patient and a Bundle through DIMP
+func conformanceDIMP(dimpURL string, httpClient *services.HTTPClient, logger *lib.Logger) ConformanceService {
+	service := ConformanceService{Service: "dimp", URL: dimpURL}
+	dimpClient := services.NewDIMPClient(dimpURL, httpClient, logger)
+
+	runConformanceCheck(&service, "version", logger, versionCheck(&service, dimpClient.FetchVersion))
+
+	runConformanceCheck(&service, "pseudonymize_resource", logger, func() (PreflightStatus, string) {
+		pseudonymized, err := dimpClient.Pseudonymize(conformancePatient())
+		if err != nil {
+			return PreflightFailed, fmt.Sprintf("round-trip failed: %v", err)
+		}
+		if resourceType, _ := pseudonymized["resourceType"].(string); resourceType != "Patient" {
+			return PreflightFailed, fmt.Sprintf("returned unexpected resourceType %q", resourceType)
+		}
+		if containsIdentifier(pseudonymized) {
+			return PreflightFailed, "identifier was returned unchanged"
+		}
+		return PreflightPassed, "Patient pseudonymized, identifier replaced"
+	})
+
+	runConformanceCheck(&service, "pseudonymize_bundle", logger, func() (PreflightStatus, string) {
+		pseudonymized, err := dimpClient.Pseudonymize(conformanceBundle())
+		if err != nil {
+			return PreflightFailed, fmt.Sprintf("round-trip failed: %v", err)
+		}
+		entries, _ := pseudonymized["entry"].([]any)
+		if resourceType, _ := pseudonymized["resourceType"].(string); resourceType != "Bundle" || len(entries) != 2 {
+			return PreflightFailed, fmt.Sprintf("expected a Bundle with 2 entries, got %q with %d", resourceType, len(entries))
+		}
+		if containsIdentifier(pseudonymized) {
+			return PreflightFailed, "identifier was returned unchanged"
+		}
+
+		// The Observation must reference the Patient by its pseudonymized ID
+		patient, _ := entries[0].(map[string]any)["resource"].(map[string]any)
+		observation, _ := entries[1].(map[string]any)["resource"].(map[string]any)
+		patientID, _ := patient["id"].(string)
+		subject, _ := observation["subject"].(map[string]any)
+		reference, _ := subject["reference"].(string)
+		if patientID != "" && reference != "" && reference != "Patient/"+patientID {
+			return PreflightFailed, fmt.Sprintf("references not rewritten consistently: Patient/%s is referenced as %s", patientID, reference)
+		}
+		return PreflightPassed, "Bundle pseudonymized with consistent references"
+	})
+
+	return service
+}
+
+// containsIdentifier reports whether the synthetic identifier survived pseudonymization
+func containsIdentifier(resource map[string]any) bool {
+	data, err := json.Marshal(resource)
+	return err == nil && strings.Contains(string(data), conformanceIdentifier)
+}
+
+// conformanceConversion converts a 3-line NDJSON file with a conversion service
+func conformanceConversion(name string, serviceURL string, httpClient *services.HTTPClient, logger *lib.Logger) ConformanceService {
+	service := ConformanceService{Service: name, URL: serviceURL}
+
+	runConformanceCheck(&service, "version", logger, versionCheck(&service, func() (services.ServiceVersion, error) {
+		return services.FetchServiceVersion(httpClient, serviceURL, "")
+	}))
+	runConformanceCheck(&service, "convert_ndjson", logger, func() (PreflightStatus, string) {
+		check := preflightConversion(serviceURL, httpClient)
+		return check.Status, check.Message
+	})
+
+	return service
+}
+
+// conformanceDICOMweb asks the DICOMweb server for the metadata of a study that cannot exist
+func conformanceDICOMweb(config *models.ProjectConfig, httpClient *services.HTTPClient, logger *lib.Logger) ConformanceService {
+	serviceURL := config.Services.Imaging.DICOMwebURL
+	service := ConformanceService{Service: "dicomweb", URL: serviceURL}
+
+	runConformanceCheck(&service, "version", logger, versionCheck(&service, func() (services.ServiceVersion, error) {
+		return services.FetchServiceVersion(httpClient, serviceURL, "")
+	}))
+	runConformanceCheck(&service, "study_metadata", logger, func() (PreflightStatus, string) {
+		check := preflightDICOMweb(config, httpClient, logger)
+		return check.Status, check.Message
+	})
+
+	return service
+}
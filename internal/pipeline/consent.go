@@ -0,0 +1,350 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// ConsentReportFileName is the consent filter summary written to the job directory
+const ConsentReportFileName = "consent_report.json"
+
+// ConsentReport summarizes what the consent filter removed
+// Patient IDs are the imported ones, before pseudonymization
+type ConsentReport struct {
+	Source                models.ConsentSource `json:"source"`
+	PatientsWithWindow    int                  `json:"patients_with_window"`              // Patients with at least one consent window
+	ResourcesRemoved      int                  `json:"resources_removed"`                 // Total over all patients
+	RemovedByPatient      map[string]int       `json:"removed_by_patient"`                // Only patients with removed resources
+	PatientsWithoutWindow []string             `json:"patients_without_window,omitempty"` // Patients in the data without any consent window
+	UndatedKept           int                  `json:"undated_kept"`                      // Resources of consented patients kept because they carry no date
+}
+
+// consentWindow is a validity period at day precision; an empty bound is open
+type consentWindow struct {
+	Start string // YYYY-MM-DD, inclusive
+	End   string // YYYY-MM-DD, inclusive
+}
+
+// contains reports whether a FHIR date (year, month, day or dateTime) overlaps the window
+func (w consentWindow) contains(date string) bool {
+	return (w.Start == "" || dateUpperBound(date) >= w.Start) && (w.End == "" || dateLowerBound(date) <= w.End)
+}
+
+// applyConsentFilter removes resources dated outside their patient's consent windows from imported files
+// It runs before DIMP, so the windows refer to the imported patient IDs. Resources without a patient
+// (Organization, Medication, ...) and Consent resources are always kept. The report is written to
+// consent_report.json in the job directory; sizes and line counts are refreshed for rewritten files
+func applyConsentFilter(importDir string, jobDir string, files []models.FHIRDataFile, filter models.ConsentFilterConfig, maxLineBytes int, logger *lib.Logger) (ConsentReport, error) {
+	report := ConsentReport{Source: filter.Source, RemovedByPatient: map[string]int{}}
+	if !filter.IsActive() {
+		return report, nil
+	}
+
+	var windows map[string][]consentWindow
+	var err error
+	if filter.Source == models.ConsentFromCSV {
+		windows, err = loadConsentCSV(filter.CSVPath)
+	} else {
+		windows, err = loadConsentResources(importDir, files, maxLineBytes)
+	}
+	if err != nil {
+		return report, err
+	}
+	report.PatientsWithWindow = len(windows)
+	if len(windows) == 0 {
+		logger.Warn("No consent windows found", "source", filter.Source, "missing", filter.GetMissing())
+	}
+
+	withoutWindow := map[string]bool{}
+	keep := func(resource map[string]any) (bool, error) {
+		if resource["resourceType"] == "Consent" {
+			return true, nil
+		}
+		patientID := resourcePatientID(resource)
+		if patientID == "" {
+			return true, nil
+		}
+
+		patientWindows, ok := windows[patientID]
+		if !ok {
+			withoutWindow[patientID] = true
+			if filter.GetMissing() == models.ConsentMissingKeep {
+				return true, nil
+			}
+			report.RemovedByPatient[patientID]++
+			return false, nil
+		}
+
+		// Patient resources and other undated resources cannot lie outside a window
+		date := services.ResourceDate(resource)
+		if date == "" || resource["resourceType"] == "Patient" {
+			report.UndatedKept++
+			return true, nil
+		}
+		for _, window := range patientWindows {
+			if window.contains(date) {
+				return true, nil
+			}
+		}
+		report.RemovedByPatient[patientID]++
+		return false, nil
+	}
+
+	for i := range files {
+		path := filepath.Join(importDir, files[i].FileName)
+		removed, err := removeResourcesInPlace(path, maxLineBytes, keep)
+		if err != nil {
+			return report, fmt.Errorf("failed to apply consent filter to %s: %w", files[i].FileName, err)
+		}
+		report.ResourcesRemoved += removed
+
+		if removed > 0 {
+			files[i].FileSize = lib.GetFileSize(path)
+			files[i].LineCount = max(files[i].LineCount-removed, 0)
+			logger.Debug("Applied consent filter", "file", files[i].FileName, "removed", removed)
+		}
+	}
+
+	for patientID := range withoutWindow {
+		report.PatientsWithoutWindow = append(report.PatientsWithoutWindow, patientID)
+	}
+	sort.Strings(report.PatientsWithoutWindow)
+
+	if err := writeConsentReport(filepath.Join(jobDir, ConsentReportFileName), report); err != nil {
+		return report, err
+	}
+	logger.Info("Consent filter applied",
+		"source", filter.Source,
+		"removed", report.ResourcesRemoved,
+		"patients_affected", len(report.RemovedByPatient),
+		"patients_without_window", len(report.PatientsWithoutWindow))
+	return report, nil
+}
+
+// loadConsentCSV reads consent windows from a CSV with a patient_id,start,end header
+// Further columns are ignored; an empty start or end leaves that side of the window open
+func loadConsentCSV(path string) (map[string][]consentWindow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open consent CSV: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consent CSV header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"patient_id", "start", "end"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("consent CSV %s has no %s column", path, name)
+		}
+	}
+
+	windows := map[string][]consentWindow{}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read consent CSV: %w", err)
+		}
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		patientID := field("patient_id")
+		if patientID == "" {
+			return nil, fmt.Errorf("consent CSV row %d: patient_id is empty", row)
+		}
+		window, err := newConsentWindow(field("start"), field("end"))
+		if err != nil {
+			return nil, fmt.Errorf("consent CSV row %d: %w", row, err)
+		}
+		windows[patientID] = append(windows[patientID], window)
+	}
+	return windows, nil
+}
+
+// loadConsentResources reads consent windows from the active, permitting Consent resources of the import
+// R4 (patient, provision.period) and R5 (subject, period) are supported; a Consent without a
+// period is valid for all dates
+func loadConsentResources(importDir string, files []models.FHIRDataFile, maxLineBytes int) (map[string][]consentWindow, error) {
+	windows := map[string][]consentWindow{}
+	marker := []byte(`"Consent"`)
+
+	for _, dataFile := range files {
+		file, err := os.Open(filepath.Join(importDir, dataFile.FileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", dataFile.FileName, err)
+		}
+
+		scanner := newLargeBufferScanner(file, maxLineBytes)
+		lineNumber := 0
+		for scanner.Scan() {
+			lineNumber++
+			if !bytes.Contains(scanner.Bytes(), marker) {
+				continue
+			}
+			var resource map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &resource); err != nil {
+				_ = file.Close()
+				return nil, fmt.Errorf("failed to parse resource at line %d of %s: %w", lineNumber, dataFile.FileName, err)
+			}
+			if resource["resourceType"] != "Consent" {
+				continue
+			}
+
+			patientID, window, ok, err := consentResourceWindow(resource)
+			if err != nil {
+				_ = file.Close()
+				return nil, fmt.Errorf("Consent at line %d of %s: %w", lineNumber, dataFile.FileName, err)
+			}
+			if ok {
+				windows[patientID] = append(windows[patientID], window)
+			}
+		}
+		err = scanner.Err()
+		_ = file.Close()
+		if err != nil {
+			return nil, wrapScanError(err, dataFile.FileName, lineNumber+1, maxLineBytes)
+		}
+	}
+	return windows, nil
+}
+
+// consentResourceWindow returns the patient and validity window of a Consent resource
+// ok is false for Consents that grant nothing: inactive, denying or without a patient
+func consentResourceWindow(consent map[string]any) (patientID string, window consentWindow, ok bool, err error) {
+	if status, _ := consent["status"].(string); status != "" && status != "active" {
+		return "", window, false, nil
+	}
+	provision, _ := consent["provision"].(map[string]any)
+	if provision["type"] == "deny" || consent["decision"] == "deny" {
+		return "", window, false, nil
+	}
+
+	for _, element := range []string{"patient", "subject"} {
+		if reference, ok := consent[element].(map[string]any); ok {
+			patientID = patientIDFromReference(reference)
+			break
+		}
+	}
+	if patientID == "" {
+		return "", window, false, nil
+	}
+
+	period, _ := provision["period"].(map[string]any)
+	if period == nil {
+		period, _ = consent["period"].(map[string]any)
+	}
+	start, _ := period["start"].(string)
+	end, _ := period["end"].(string)
+	window, err = newConsentWindow(start, end)
+	return patientID, window, err == nil, err
+}
+
+// newConsentWindow builds a window from FHIR dates; partial dates widen it to the whole year or month
+func newConsentWindow(start, end string) (consentWindow, error) {
+	window := consentWindow{}
+	if start != "" {
+		window.Start = dateLowerBound(start)
+		if _, err := time.Parse(time.DateOnly, window.Start); err != nil {
+			return window, fmt.Errorf("invalid consent start '%s'", start)
+		}
+	}
+	if end != "" {
+		window.End = dateUpperBound(end)
+		if _, err := time.Parse(time.DateOnly, dateLowerBound(end)); err != nil {
+			return window, fmt.Errorf("invalid consent end '%s'", end)
+		}
+	}
+	if window.Start != "" && window.End != "" && window.End < window.Start {
+		return window, fmt.Errorf("consent end '%s' is before start '%s'", end, start)
+	}
+	return window, nil
+}
+
+// dateLowerBound returns the first day a FHIR date or dateTime covers, as YYYY-MM-DD
+func dateLowerBound(date string) string {
+	switch len(date) {
+	case 4:
+		return date + "-01-01"
+	case 7:
+		return date + "-01"
+	}
+	return date[:min(len(date), 10)]
+}
+
+// dateUpperBound returns the last day a FHIR date or dateTime covers, as YYYY-MM-DD
+// Months end on day 31 regardless of their length: the bound is only compared as a string
+func dateUpperBound(date string) string {
+	switch len(date) {
+	case 4:
+		return date + "-12-31"
+	case 7:
+		return date + "-31"
+	}
+	return date[:min(len(date), 10)]
+}
+
+// resourcePatientID returns the ID of the patient a resource belongs to, or "" if it has none
+func resourcePatientID(resource map[string]any) string {
+	if resource["resourceType"] == "Patient" {
+		id, _ := resource["id"].(string)
+		return id
+	}
+	for _, element := range []string{"subject", "patient"} {
+		if reference, ok := resource[element].(map[string]any); ok {
+			if patientID := patientIDFromReference(reference); patientID != "" {
+				return patientID
+			}
+		}
+	}
+	return ""
+}
+
+// patientIDFromReference returns the ID of a relative or absolute Patient reference, or ""
+func patientIDFromReference(reference map[string]any) string {
+	value, _ := reference["reference"].(string)
+	index := strings.LastIndex(value, "Patient/")
+	if index < 0 || (index > 0 && value[index-1] != '/') {
+		return ""
+	}
+	id, _, _ := strings.Cut(value[index+len("Patient/"):], "/")
+	return id
+}
+
+// writeConsentReport writes the consent filter summary as indented JSON
+func writeConsentReport(path string, report ConsentReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal consent report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write consent report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// ExecuteCSVConversionStep flattens the job's FHIR resources into CSV tables in-process
+// Used when services.csv_conversion.url is empty. Reads the output of the last completed
+// imaging, DIMP or import step and writes one <ResourceType>.csv per mapped resource type,
+// plus data_dictionary.csv, to csv/
+func ExecuteCSVConversionStep(ctx context.Context, job *models.PipelineJob, jobDir string, logger *lib.Logger) error {
+	stepName := models.StepCSVConversion
+
+	if !isStepEnabled(job.Config, stepName) {
+		logger.Info("CSV conversion step not enabled, skipping", "job_id", job.JobID)
+		return nil
+	}
+
+	return RunStep(ctx, job, stepName, logger, func(ctx context.Context) error {
+		return executePackagingStep(job, jobDir, csvPackaging, logger)
+	})
+}
+
+// csvPackaging writes flattened tables as <ResourceType>.csv
+var csvPackaging = packagingFormat{
+	step:      models.StepCSVConversion,
+	dir:       "csv",
+	label:     "CSV",
+	extension: ".csv",
+	newSink: func(outputDir string, config models.ProjectConfig) services.TableSink {
+		return services.NewCSVTableSink(outputDir)
+	},
+	newTableSink: func(outputDir string, config models.ProjectConfig) services.TableSink {
+		return services.NewCSVTableSink(outputDir)
+	},
+	removeTable: func(outputDir, table string) error {
+		return os.Remove(filepath.Join(outputDir, table+".csv"))
+	},
+}
@@ -2,23 +2,28 @@ package pipeline
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/trobanga/aether/internal/lib"
 	"github.com/trobanga/aether/internal/models"
 	"github.com/trobanga/aether/internal/services"
-	"github.com/trobanga/aether/internal/ui"
 )
 
 // ExecuteDIMPStep processes FHIR resources through the DIMP pseudonymization service
 // Reads from import/ directory, writes to pseudonymized/ directory
 // Orchestrates Bundle splitting and oversized resource detection before pseudonymization
-func ExecuteDIMPStep(job *models.PipelineJob, jobDir string, logger *lib.Logger) error {
+func ExecuteDIMPStep(ctx context.Context, job *models.PipelineJob, jobDir string, logger *lib.Logger, progress lib.ProgressReporter) error {
 	stepName := models.StepDIMP
 
 	// Check if DIMP step is enabled
@@ -27,34 +32,61 @@ func ExecuteDIMPStep(job *models.PipelineJob, jobDir string, logger *lib.Logger)
 		return nil
 	}
 
+	return RunStep(ctx, job, stepName, logger, func(ctx context.Context) error {
+		return executeDIMPStep(ctx, job, jobDir, logger, progress)
+	})
+}
+
+// executeDIMPStep runs pseudonymization itself; ExecuteDIMPStep wraps it with the step middleware
+// ctx carries the step's time budget deadline: it bounds DIMP calls and is checked between files
+func executeDIMPStep(ctx context.Context, job *models.PipelineJob, jobDir string, logger *lib.Logger, progress lib.ProgressReporter) error {
+	stepName := models.StepDIMP
+
 	// Log step start (DEBUG level to avoid polluting progress bar display)
 	logger.Debug("DIMP step starting", "job_id", job.JobID)
 
 	// Get or create DIMP step in job
 	step := getOrCreateStep(job, stepName)
 	step.Status = models.StepStatusInProgress
-	now := time.Now()
+	now := lib.Now()
 	step.StartedAt = &now
 
 	// Validate DIMP service URL is configured
 	if job.Config.Services.DIMP.URL == "" {
 		err := fmt.Errorf("DIMP service URL not configured")
-		lib.LogStepFailed(logger, string(stepName), job.JobID, err, false)
 		recordStepError(step, err, models.ErrorTypeNonTransient)
 		return err
 	}
 
 	// Create DIMP client
 	httpClient := services.DefaultHTTPClient()
+	httpClient.SetEventSink(jobEventSink(job, logger))
+	httpClient.SetJobID(job.JobID)
+	httpClient.SetWaitSink(jobWaitSink(job, logger))
+	httpClient.SetContext(ctx)
 	dimpClient := services.NewDIMPClient(job.Config.Services.DIMP.URL, httpClient, logger)
 
+	// Usage is recorded even if the step fails: requests that reached DIMP are billed
+	recordUsage := sync.OnceFunc(func() { recordDIMPUsage(job, dimpClient.Usage(), logger) })
+	defer recordUsage()
+
+	// Sample per-resource debug lines, so debug logging a job with millions of resources stays readable
+	debugLogFirst, debugLogEvery := job.Config.Services.DIMP.GetDebugLogSampling()
+	debugLog := lib.NewLogSampler(logger, debugLogFirst, debugLogEvery)
+	dimpClient.SetDebugLogSampler(debugLog)
+
+	// With adaptive_split, the split threshold is tuned across all files of the step
+	tuner := newChunkTuner(ctx, job, logger, dimpSplitThresholdBytes(job))
+	if tuner != nil {
+		defer tuner.LogSummary()
+	}
+
 	// Setup directories
 	importDir := filepath.Join(jobDir, "import")
 	outputDir := filepath.Join(jobDir, "pseudonymized")
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		lib.LogStepFailed(logger, string(stepName), job.JobID, err, false)
 		recordStepError(step, err, models.ErrorTypeNonTransient)
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
@@ -62,87 +94,234 @@ func ExecuteDIMPStep(job *models.PipelineJob, jobDir string, logger *lib.Logger)
 	// Find all NDJSON files in import directory
 	files, err := filepath.Glob(filepath.Join(importDir, "*.ndjson"))
 	if err != nil {
-		lib.LogStepFailed(logger, string(stepName), job.JobID, err, false)
 		recordStepError(step, err, models.ErrorTypeNonTransient)
 		return fmt.Errorf("failed to list import files: %w", err)
 	}
 
 	if len(files) == 0 {
 		err := fmt.Errorf("no FHIR NDJSON files found in import directory")
-		lib.LogStepFailed(logger, string(stepName), job.JobID, err, false)
 		recordStepError(step, err, models.ErrorTypeNonTransient)
 		return err
 	}
 
+	// When splitting by resourceType, per-file output is staged and partitioned at the end
+	fileOutputDir := outputDir
+	splitByResourceType := job.Config.Services.DIMP.SplitByResourceType
+	if splitByResourceType {
+		fileOutputDir = filepath.Join(outputDir, unsplitDirName)
+		if err := os.MkdirAll(fileOutputDir, 0755); err != nil {
+			recordStepError(step, err, models.ErrorTypeNonTransient)
+			return fmt.Errorf("failed to create staging directory: %w", err)
+		}
+	}
+
 	// Print user-friendly message instead of logger (logger pollutes progress bar)
 	fmt.Printf("Processing %d FHIR file(s) through DIMP...\n\n", len(files))
 
 	// Clean up any stale .part files from previous interrupted runs
 	partFiles, _ := filepath.Glob(filepath.Join(outputDir, "*.part"))
+	if splitByResourceType {
+		stagedPartFiles, _ := filepath.Glob(filepath.Join(fileOutputDir, "*.part"))
+		partFiles = append(partFiles, stagedPartFiles...)
+	}
 	for _, partFile := range partFiles {
 		logger.Debug("Removing stale partial file from previous run", "file", filepath.Base(partFile))
 		_ = os.Remove(partFile)
 	}
 
-	// Process each file
-	totalResourcesProcessed := 0
-	filesProcessed := 0
-	for fileIdx, inputFile := range files {
+	quarantineDir := filepath.Join(jobDir, "quarantine")
+	deadLetterDir := filepath.Join(jobDir, DeadLetterDirName)
+
+	// Process files, up to pipeline.dimp.parallelism at once
+	parallelism := min(job.Config.Pipeline.DIMP.GetParallelism(), len(files))
+	fileProgress := progress
+	if parallelism > 1 {
+		// Concurrent progress bars would overwrite each other; files report through log and events
+		fileProgress = lib.NoProgress
+		fmt.Printf("Pseudonymizing up to %d files at once\n", parallelism)
+	}
+	workers := startDIMPFileWorkers(len(files), parallelism, func(fileIdx int, result *dimpFileResult) {
+		inputFile := files[fileIdx]
+		if err := ctx.Err(); err != nil {
+			result.stopped = true
+			result.err = fmt.Errorf("stopped before %s: %w", filepath.Base(inputFile), err)
+			return
+		}
+
 		// Create output filename: dimped_<original-filename>
 		baseName := filepath.Base(inputFile)
-		outputFile := filepath.Join(outputDir, "dimped_"+baseName)
+		outputFile := filepath.Join(fileOutputDir, "dimped_"+baseName)
 
 		// Check if output file already exists (resume support)
 		if _, err := os.Stat(outputFile); err == nil {
-			// Output file exists - skip processing
-			fmt.Printf("  ⊙ %s (already processed, skipping)\n", baseName)
 			logger.Debug("Skipping already processed file",
 				"filename", baseName,
 				"output_file", outputFile,
 				"job_id", job.JobID)
-			filesProcessed++
+			result.skipped = true
 
 			// Count resources in existing file for accurate totals
 			if lineCount, err := lib.CountResourcesInFile(outputFile); err == nil {
-				totalResourcesProcessed += lineCount
+				result.stats.Resources = lineCount
+			}
+			return
+		}
+
+		// Reuse the output of an earlier run on an identical file (content-addressed)
+		if ttl := job.Config.Services.DIMP.GetOutputCacheTTL(); ttl > 0 {
+			key, err := services.DIMPCacheKey(inputFile, job.Config.Services.DIMP)
+			if err != nil {
+				logger.Warn("Failed to hash input file for the DIMP output cache", "file", baseName, "error", err)
+			} else {
+				if stats, ok := reuseCachedDIMPOutput(job, key, ttl, baseName, outputFile, logger); ok {
+					result.cached = true
+					result.stats = stats
+					return
+				}
+				result.cacheKey = key
 			}
-			continue
 		}
 
 		// Process file through DIMP using atomic write (writes to .part first)
-		resourcesProcessed, err := processDIMPFile(inputFile, outputFile, dimpClient, logger, job)
-		if err != nil {
+		result.stats, result.err = processDIMPFile(ctx, inputFile, outputFile, quarantineDir, deadLetterDir, dimpClient, tuner, logger, debugLog, fileProgress, job)
+	})
+
+	// Report results in file order. Warnings are recorded on the step once no worker reads the
+	// job anymore, including when a file fails
+	totalResourcesProcessed := 0
+	totalPassedThrough := 0
+	totalQuarantined := 0
+	filesProcessed := 0
+	finished := make([]*dimpFileResult, 0, len(files))
+	recordWarnings := func() {
+		workers.Wait()
+		for fileIdx, result := range finished {
+			if !result.skipped {
+				recordDIMPFileWarnings(job, logger, filepath.Base(files[fileIdx]), result.stats)
+			}
+		}
+	}
+	for fileIdx, inputFile := range files {
+		result := workers.Result(fileIdx)
+		baseName := filepath.Base(inputFile)
+		resourcesProcessed := result.stats.Resources
+
+		if result.err != nil {
+			recordWarnings()
+			if result.stopped {
+				recordStepError(step, result.err, models.ErrorTypeNonTransient)
+				return result.err
+			}
 			logger.Error("Failed to process FHIR file",
 				"filename", baseName,
 				"file_number", fileIdx+1,
 				"total_files", len(files),
 				"resources_processed_so_far", totalResourcesProcessed,
-				"error", err,
+				"error", result.err,
 				"job_id", job.JobID)
-			lib.LogStepFailed(logger, string(stepName), job.JobID, err, isDIMPErrorRetryable(err))
-			recordStepError(step, err, classifyDIMPError(err))
-			return fmt.Errorf("failed to process %s: %w", baseName, err)
+			recordStepError(step, result.err, classifyDIMPError(result.err))
+			return fmt.Errorf("failed to process %s: %w", baseName, result.err)
 		}
+		finished = append(finished, result)
+
+		switch {
+		case result.skipped:
+			fmt.Printf("  ⊙ %s (already processed, skipping)\n", baseName)
+			recordJobEvent(job, logger, models.EventFileProcessed, string(stepName),
+				fmt.Sprintf("file %d/%d already processed: %s", fileIdx+1, len(files), baseName),
+				map[string]any{"file": baseName, "index": fileIdx + 1, "total": len(files), "skipped": true})
+		case result.cached:
+			fmt.Printf("  ⊙ %s (%d resources, reused cached output)\n", baseName, resourcesProcessed)
+			recordJobEvent(job, logger, models.EventFileProcessed, string(stepName),
+				fmt.Sprintf("file %d/%d reused from cache: %s", fileIdx+1, len(files), baseName),
+				map[string]any{"file": baseName, "index": fileIdx + 1, "total": len(files), "resources": resourcesProcessed, "cached": true})
+		default:
+			// Log completion for this file
+			stats := result.stats
+			switch {
+			case stats.PassedThrough > 0:
+				fmt.Printf("  ✓ %s (%d resources, %d non-FHIR lines passed through)\n", baseName, resourcesProcessed, stats.PassedThrough)
+			case stats.Quarantined > 0:
+				fmt.Printf("  ✓ %s (%d resources, %d non-FHIR lines quarantined)\n", baseName, resourcesProcessed, stats.Quarantined)
+			default:
+				fmt.Printf("  ✓ %s (%d resources)\n", baseName, resourcesProcessed)
+			}
 
-		// Log completion for this file
-		fmt.Printf("  ✓ %s (%d resources)\n", baseName, resourcesProcessed)
+			recordJobEvent(job, logger, models.EventFileProcessed, string(stepName),
+				fmt.Sprintf("file %d/%d processed: %s", fileIdx+1, len(files), baseName),
+				map[string]any{"file": baseName, "index": fileIdx + 1, "total": len(files), "resources": resourcesProcessed})
+			if result.cacheKey != "" {
+				cacheDIMPOutput(job, result.cacheKey, baseName, filepath.Join(fileOutputDir, "dimped_"+baseName), stats, logger)
+			}
+			totalQuarantined += stats.Quarantined
+		}
 
 		totalResourcesProcessed += resourcesProcessed
+		totalPassedThrough += result.stats.PassedThrough
 		filesProcessed++
 	}
+	recordWarnings()
+
+	// Partition staged output into one file per resourceType
+	if splitByResourceType {
+		counts, err := PartitionByResourceType(fileOutputDir, outputDir, "dimped_", job.Config.Limits.GetMaxLineBytes(), logger)
+		if err != nil {
+			recordStepError(step, err, models.ErrorTypeNonTransient)
+			return fmt.Errorf("failed to split output by resource type: %w", err)
+		}
+
+		resourceTypes := make([]string, 0, len(counts))
+		for resourceType := range counts {
+			resourceTypes = append(resourceTypes, resourceType)
+		}
+		sort.Strings(resourceTypes)
+
+		fmt.Printf("\nSplit output by resource type:\n")
+		for _, resourceType := range resourceTypes {
+			fmt.Printf("  ✓ dimped_%s.ndjson (%d resources)\n", resourceType, counts[resourceType])
+		}
+
+		// Staged files are no longer needed once all partitions are in place
+		if err := os.RemoveAll(fileOutputDir); err != nil {
+			logger.Warn("Failed to remove staging directory", "dir", fileOutputDir, "error", err)
+		}
+	}
+
+	recordUsage()
+
+	// pipeline.strict turns selected warnings (e.g. unknown resource types) into a failure
+	if err := checkStrictWarnings(job, stepName); err != nil {
+		recordStepError(step, err, models.ErrorTypeNonTransient)
+		return err
+	}
+
+	// A successful run can still produce unusable output (e.g. resources dropped by DIMP)
+	if err := checkStepPostCondition(job, stepName, importDir, outputDir, logger); err != nil {
+		recordStepError(step, err, models.ErrorTypeNonTransient)
+		return err
+	}
 
 	// Update step status
 	step.Status = models.StepStatusCompleted
 	step.FilesProcessed = len(files)
-	completedAt := time.Now()
+	completedAt := lib.Now()
 	step.CompletedAt = &completedAt
 
 	duration := completedAt.Sub(*step.StartedAt)
 
+	if totalPassedThrough > 0 || totalQuarantined > 0 {
+		logger.Warn("Non-FHIR lines were not pseudonymized",
+			"passed_through", totalPassedThrough,
+			"quarantined", totalQuarantined,
+			"job_id", job.JobID)
+	}
+
 	// Log to structured logger at DEBUG level
 	logger.Debug("DIMP step completed",
 		"files_processed", len(files),
 		"resources_processed", totalResourcesProcessed,
+		"non_fhir_passed_through", totalPassedThrough,
+		"non_fhir_quarantined", totalQuarantined,
 		"duration", duration,
 		"job_id", job.JobID,
 	)
@@ -150,158 +329,511 @@ func ExecuteDIMPStep(job *models.PipelineJob, jobDir string, logger *lib.Logger)
 	return nil
 }
 
+// recordDIMPUsage adds what a step run sent to DIMP to the job and to the monthly counters of the
+// jobs directory, and records a dimp_quota warning once the month approaches services.dimp.usage_quota
+func recordDIMPUsage(job *models.PipelineJob, usage models.DIMPUsage, logger *lib.Logger) {
+	if usage.IsZero() {
+		return
+	}
+	if job.DIMPUsage == nil {
+		job.DIMPUsage = &models.DIMPUsage{}
+	}
+	job.DIMPUsage.Add(usage)
+
+	month := services.DIMPUsageMonth(time.Now())
+	monthly, err := services.RecordDIMPUsage(job.Config.JobsDir, month, usage)
+	if err != nil {
+		logger.Warn("Failed to record monthly DIMP usage", "error", err, "job_id", job.JobID)
+		return
+	}
+	logger.Info("DIMP usage",
+		"requests", usage.Requests,
+		"resources", usage.Resources,
+		"bytes", usage.Bytes,
+		"month", month,
+		"month_resources", monthly.Resources,
+		"month_bytes", monthly.Bytes,
+		"job_id", job.JobID)
+
+	if message := job.Config.Services.DIMP.UsageQuota.Check(monthly); message != "" {
+		recordStepWarning(job, logger, models.StepDIMP, models.WarningDIMPQuota, "", int(monthly.Resources), message)
+	}
+}
+
+// reuseCachedDIMPOutput copies the cached output of an identical input file to outputFile
+// Returns false if there is no usable entry; the caller then pseudonymizes the file
+func reuseCachedDIMPOutput(job *models.PipelineJob, key string, ttl time.Duration, file string, outputFile string, logger *lib.Logger) (dimpFileStats, bool) {
+	entry, found := services.LoadDIMPCacheEntry(job.Config.JobsDir, key, ttl, time.Now())
+	if !found {
+		return dimpFileStats{}, false
+	}
+	if err := services.RestoreDIMPCacheOutput(job.Config.JobsDir, key, outputFile); err != nil {
+		logger.Info("Not reusing cached DIMP output", "file", file, "error", err, "cached_job", entry.JobID)
+		if err := services.RemoveDIMPCacheEntry(job.Config.JobsDir, key); err != nil {
+			logger.Warn("Failed to remove DIMP cache entry", "error", err)
+		}
+		return dimpFileStats{}, false
+	}
+
+	age := time.Since(entry.CreatedAt).Round(time.Second)
+	logger.Info("Reused cached DIMP output", "file", file, "cached_job", entry.JobID, "cached_file", entry.File, "age", age)
+	recordJobEvent(job, logger, models.EventDIMPCacheHit, string(models.StepDIMP),
+		fmt.Sprintf("reused DIMP output of %s from job %s (%s ago)", file, entry.JobID, age),
+		map[string]any{"file": file, "cached_job": entry.JobID, "cached_file": entry.File, "resources": entry.Resources, "age_seconds": int(age.Seconds())})
+
+	return dimpFileStats{
+		Resources:     entry.Resources,
+		PassedThrough: entry.PassedThrough,
+		EmptyLines:    entry.EmptyLines,
+		UnknownTypes:  entry.UnknownTypes,
+	}, true
+}
+
+// cacheDIMPOutput stores the output of a pseudonymized file for reuse by later runs
+// Files with quarantined lines are not cached: their quarantine file is output too
+func cacheDIMPOutput(job *models.PipelineJob, key string, file string, outputFile string, stats dimpFileStats, logger *lib.Logger) {
+	if stats.Quarantined > 0 {
+		return
+	}
+	entry := services.DIMPCacheEntry{
+		Key:           key,
+		JobID:         job.JobID,
+		File:          file,
+		CreatedAt:     lib.Now(),
+		Resources:     stats.Resources,
+		PassedThrough: stats.PassedThrough,
+		EmptyLines:    stats.EmptyLines,
+		UnknownTypes:  stats.UnknownTypes,
+	}
+	if err := services.SaveDIMPCacheEntry(job.Config.JobsDir, outputFile, entry); err != nil {
+		logger.Warn("Failed to cache DIMP output", "file", file, "error", err)
+	}
+}
+
+// dimpFileStats summarizes the outcome of processing a single NDJSON file through DIMP
+type dimpFileStats struct {
+	Resources     int // Resources pseudonymized
+	PassedThrough int // Non-FHIR lines copied unchanged
+	Quarantined   int // Non-FHIR lines moved to quarantine
+	EmptyLines    int // Blank lines skipped
+
+	UnknownTypes map[string]int // Resources per resourceType that is not a FHIR R4 resource type
+}
+
+// recordDIMPFileWarnings records the non-fatal issues found in one file on the DIMP step
+// Warnings of an earlier run of the file are replaced, so a fixed file no longer shows them
+func recordDIMPFileWarnings(job *models.PipelineJob, logger *lib.Logger, file string, stats dimpFileStats) {
+	getOrCreateStep(job, models.StepDIMP).ClearFileWarnings(file)
+	if stats.EmptyLines > 0 {
+		recordStepWarning(job, logger, models.StepDIMP, models.WarningEmptyLines, file, stats.EmptyLines,
+			fmt.Sprintf("%d empty lines skipped in %s", stats.EmptyLines, file))
+	}
+	if nonFHIR := stats.PassedThrough + stats.Quarantined; nonFHIR > 0 {
+		action := "passed through unpseudonymized"
+		if stats.Quarantined > 0 {
+			action = "quarantined"
+		}
+		recordStepWarning(job, logger, models.StepDIMP, models.WarningNonFHIRLines, file, nonFHIR,
+			fmt.Sprintf("%d non-FHIR lines in %s %s", nonFHIR, file, action))
+	}
+	if len(stats.UnknownTypes) > 0 {
+		types := make([]string, 0, len(stats.UnknownTypes))
+		count := 0
+		for resourceType, n := range stats.UnknownTypes {
+			types = append(types, resourceType)
+			count += n
+		}
+		sort.Strings(types)
+		recordStepWarning(job, logger, models.StepDIMP, models.WarningUnknownResourceType, file, count,
+			fmt.Sprintf("%d resources in %s have an unknown resourceType (%s)", count, file, strings.Join(types, ", ")))
+	}
+}
+
 // processDIMPFile processes a single NDJSON file through DIMP
-// Returns the number of resources processed
+// Returns the number of resources processed and non-FHIR lines handled
 // Uses atomic write pattern: writes to .part file, renames on success
 // Implements Bundle splitting for large Bundles to prevent HTTP 413 errors
-func processDIMPFile(inputFile, outputFile string, dimpClient *services.DIMPClient, logger *lib.Logger, job *models.PipelineJob) (int, error) {
+// A transient failure (e.g. a DIMP outage outlasting the HTTP retries) is retried up to
+// services.dimp.file_retry_attempts times; a retry resumes after the last line fully handled
+func processDIMPFile(ctx context.Context, inputFile, outputFile, quarantineDir, deadLetterDir string, dimpClient *services.DIMPClient, tuner *chunkTuner, logger *lib.Logger, debugLog *lib.LogSampler, progressReporter lib.ProgressReporter, job *models.PipelineJob) (dimpFileStats, error) {
 	// Setup file I/O with atomic write pattern
 	fileCtx, err := SetupFileProcessing(inputFile, outputFile)
 	if err != nil {
-		return 0, err
+		return dimpFileStats{}, err
 	}
 
-	// Count resources for progress tracking
-	totalResources := countResourcesInFile(inputFile)
+	// Create resource processor for Bundle and non-Bundle processing
+	processor := NewResourceProcessor(dimpClient, logger, dimpSplitThresholdBytes(job), inputFile)
+	processor.SetEntryCountCheck(job.Config.Retry.MaxAttempts, deadLetterDir)
+	processor.setChunkTuner(tuner)
+
+	run := &dimpFileRun{
+		inputFile:  inputFile,
+		fileCtx:    fileCtx,
+		quarantine: newQuarantineWriter(quarantineDir, filepath.Base(inputFile)),
+		processor:  processor,
+	}
+	defer run.quarantine.Abort()
+
+	maxRetries := job.Config.Services.DIMP.FileRetryAttempts
+	for attempt := 0; ; attempt++ {
+		err = processDIMPFileAttempt(run, logger, debugLog, progressReporter, job)
+		run.stats.Resources = processor.GetResourceCount()
+		if err == nil {
+			break
+		}
 
-	// Create progress bar if we know total count
-	var progressBar *ui.ProgressBar
-	if totalResources > 0 {
-		progressBar = ui.NewProgressBar(int64(totalResources), fmt.Sprintf("Pseudonymizing %s", filepath.Base(inputFile)))
-	} else {
-		// Use spinner for unknown count
-		logger.Info("Processing FHIR resources (unknown count)", "file", filepath.Base(inputFile))
+		if attempt >= maxRetries || classifyDIMPError(err) != models.ErrorTypeTransient || ctx.Err() != nil {
+			_ = FinalizeFileProcessing(fileCtx, outputFile, false)
+			return run.stats, err
+		}
+		if waitErr := waitForDIMPFileRetry(ctx, job, logger, run, attempt, err); waitErr != nil {
+			_ = FinalizeFileProcessing(fileCtx, outputFile, false)
+			return run.stats, fmt.Errorf("%w (retry of %s cancelled: %v)", err, filepath.Base(inputFile), waitErr)
+		}
 	}
 
-	// Get Bundle split threshold from config (convert MB to bytes)
+	// Move quarantined lines into place (overwrites leftovers from an interrupted run)
+	if err := run.quarantine.Commit(); err != nil {
+		return run.stats, err
+	}
+
+	// Finalize file processing with atomic rename
+	if err := FinalizeFileProcessing(fileCtx, outputFile, true); err != nil {
+		return run.stats, err
+	}
+
+	return run.stats, nil
+}
+
+// dimpSplitThresholdBytes returns the configured Bundle split threshold in bytes
+func dimpSplitThresholdBytes(job *models.PipelineJob) int {
 	thresholdMB := job.Config.Services.DIMP.BundleSplitThresholdMB
 	if thresholdMB <= 0 {
 		thresholdMB = 10 // Default to 10MB if not configured
 	}
-	thresholdBytes := thresholdMB * 1024 * 1024
+	return thresholdMB * 1024 * 1024
+}
 
-	// Create resource processor for Bundle and non-Bundle processing
-	processor := NewResourceProcessor(dimpClient, logger, thresholdBytes, inputFile)
+// dimpFileRun is the state of pseudonymizing one file, kept across in-step retries
+// Output and quarantined lines are only ever appended, so a retry continues after the last
+// line an earlier attempt fully handled instead of sending the whole file again
+type dimpFileRun struct {
+	inputFile  string
+	fileCtx    *FileContext
+	quarantine *QuarantineWriter
+	processor  *ResourceProcessor
+	stats      dimpFileStats
+	linesDone  int // Input lines fully handled (written, passed through, quarantined or skipped)
+}
+
+// waitForDIMPFileRetry records a scheduled file retry and waits out its backoff
+// Returns an error if the step is stopped (e.g. its time budget runs out) while waiting
+func waitForDIMPFileRetry(ctx context.Context, job *models.PipelineJob, logger *lib.Logger, run *dimpFileRun, attempt int, cause error) error {
+	file := filepath.Base(run.inputFile)
+	maxRetries := job.Config.Services.DIMP.FileRetryAttempts
+	backoff := job.Config.Services.DIMP.GetFileRetryBackoff(attempt)
+	resumeLine := run.linesDone + 1
+
+	fmt.Printf("  ↻ %s failed at line %d, retrying in %s (%d/%d)\n", file, resumeLine, backoff, attempt+1, maxRetries)
+	logger.Warn("Retrying file after transient DIMP failure",
+		"file", file,
+		"attempt", attempt+1,
+		"max_attempts", maxRetries,
+		"resume_line", resumeLine,
+		"backoff", backoff,
+		"error", cause,
+		"job_id", job.JobID)
+	recordJobEvent(job, logger, models.EventRetryScheduled, string(models.StepDIMP),
+		fmt.Sprintf("retry %d/%d of %s from line %d in %s", attempt+1, maxRetries, file, resumeLine, backoff),
+		map[string]any{
+			"file":        file,
+			"attempt":     attempt + 1,
+			"resume_line": resumeLine,
+			"backoff_ms":  backoff.Milliseconds(),
+			"error":       cause.Error(),
+		})
+
+	waits := jobWaitSink(job, logger)
+	waits(&models.WaitState{
+		Reason:      models.WaitRetry,
+		Target:      "DIMP " + file,
+		Attempt:     attempt + 1,
+		MaxAttempts: maxRetries,
+		NextAt:      time.Now().Add(backoff),
+	})
+	defer waits(nil)
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// processDIMPFileAttempt reads the input file from the start, skips the lines handled by
+// earlier attempts and pseudonymizes the rest into the run's output
+func processDIMPFileAttempt(run *dimpFileRun, logger *lib.Logger, debugLog *lib.LogSampler, progressReporter lib.ProgressReporter, job *models.PipelineJob) (err error) {
+	inputFile := run.inputFile
+	fileCtx := run.fileCtx
+	quarantine := run.quarantine
+	processor := run.processor
+	stats := &run.stats
+
+	if _, err := fileCtx.InFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind input file: %w", err)
+	}
+
+	nonFHIRLines := job.Config.Services.DIMP.NonFHIRLines
+
+	// Estimate resources from a quick pre-scan for the progress bar and periodic progress reports
+	maxLineBytes := job.Config.Limits.GetMaxLineBytes()
+	progress := newDIMPProgress(job, logger, progressReporter, inputFile)
+	defer func() { progress.Finish(err) }()
 
 	// Process line by line with large buffer to handle very large FHIR resources
-	scanner := newLargeBufferScanner(fileCtx.InFile)
+	scanner := newLargeBufferScanner(fileCtx.InFile, maxLineBytes)
+
+	reportFailure := func(line int, resource string, err error) {
+		progress.Clear()
+		fmt.Printf("\n✗ DIMP pseudonymization failed\n")
+		fmt.Printf("  File: %s (line %d)\n", filepath.Base(inputFile), line)
+		fmt.Printf("  Resource: %s\n", resource)
+		fmt.Printf("  Error: %v\n\n", err)
+	}
+
+	// Non-Bundle resources are held back and sent together, up to services.dimp.batch_size
+	// resources and the split threshold per request. Every other line flushes the batch first,
+	// so the output keeps the input order and a file retry resumes after the last written line
+	batchSize := job.Config.Services.DIMP.GetBatchSize()
+	var batch []batchedResource
+	batchBytes := 0
+	flushBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		pending := batch
+		batch, batchBytes = nil, 0
 
+		var results []map[string]any
+		batched := false
+		if len(pending) > 1 {
+			results, batched = processor.PseudonymizeBatch(pending)
+		}
+		for i, item := range pending {
+			var pseudonymized map[string]any
+			if batched {
+				pseudonymized = results[i]
+			} else {
+				var err error
+				pseudonymized, err = processor.pseudonymizeNonBundleResource(item.resource, item.resourceType, item.resourceID)
+				if err != nil {
+					reportFailure(processor.GetResourceCount()+1, item.resourceType+"/"+item.resourceID, err)
+					return err
+				}
+			}
+
+			// Write pseudonymized resource to output
+			if err := WriteProcessedResource(pseudonymized, fileCtx.OutFile); err != nil {
+				return err
+			}
+			processor.IncrementResourceCount()
+			run.linesDone = item.lineNumber
+			progress.Line(item.lineBytes)
+		}
+		return nil
+	}
+
+	lineNumber := 0
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+		lineNumber++
+		if lineNumber <= run.linesDone {
+			// Handled by an earlier attempt
+			if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+				progress.Line(len(scanner.Bytes()))
+			}
+			continue
+		}
+
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			if err := flushBatch(); err != nil {
+				return err
+			}
+			stats.EmptyLines++
+			run.linesDone = lineNumber
 			continue
 		}
 
+		// Bundles that need splitting anyway are split while reading, never decoded as a whole
+		if len(raw) > processor.splitThreshold() {
+			if err := flushBatch(); err != nil {
+				return err
+			}
+			bundleID, err := processor.ProcessBundleStream(raw, fileCtx.OutFile, func(entries int) error {
+				return job.Config.Limits.CheckBundleEntries(entries)
+			})
+			switch {
+			case err == nil:
+				processor.IncrementResourceCount()
+				run.linesDone = lineNumber
+				progress.Line(len(scanner.Bytes()))
+				continue
+			case !errors.Is(err, services.ErrNotBundle):
+				reportFailure(lineNumber, "Bundle/"+bundleID, err)
+				return err
+			}
+			// Not a Bundle: an oversized resource, handled like any other line
+		}
+
+		line := string(raw)
 		// Parse FHIR resource
 		var resource map[string]any
 		if err := json.Unmarshal([]byte(line), &resource); err != nil {
 			// Clear progress bar before logging error
-			if progressBar != nil {
-				_ = progressBar.Clear()
-			}
+			progress.Clear()
 			logger.Error("Failed to parse FHIR resource",
 				"file", filepath.Base(inputFile),
 				"line_number", processor.GetResourceCount()+1,
 				"error", err)
-			return processor.GetResourceCount(), fmt.Errorf("failed to parse resource at line %d: %w", processor.GetResourceCount()+1, err)
+			return fmt.Errorf("failed to parse resource at line %d: %w", processor.GetResourceCount()+1, err)
 		}
 
 		resourceType, _ := resource["resourceType"].(string)
 		resourceID, _ := resource["id"].(string)
 
-		// Only log individual resources at DEBUG level to avoid interfering with progress bar
-		logger.Debug("Processing FHIR resource",
-			"file", filepath.Base(inputFile),
-			"line_number", processor.GetResourceCount()+1,
-			"resourceType", resourceType,
-			"id", resourceID)
+		// Lines without resourceType (e.g. TORCH metadata) are not FHIR resources
+		if resourceType == "" && nonFHIRLines != "" && nonFHIRLines != models.NonFHIRLinesFail {
+			if err := flushBatch(); err != nil {
+				return err
+			}
+			if debugLog.Allow("Skipping non-FHIR line") {
+				logger.Debug("Skipping non-FHIR line",
+					"file", filepath.Base(inputFile),
+					"line_number", lineNumber,
+					"mode", nonFHIRLines)
+			}
 
-		var pseudonymized map[string]any
+			if nonFHIRLines == models.NonFHIRLinesQuarantine {
+				if err := quarantine.WriteLine(line); err != nil {
+					return err
+				}
+				stats.Quarantined++
+			} else {
+				if _, err := fileCtx.OutFile.WriteString(line + "\n"); err != nil {
+					return fmt.Errorf("failed to write output: %w", err)
+				}
+				stats.PassedThrough++
+			}
 
-		// Process resource based on type
-		if resourceType == "Bundle" {
-			pseudonymized, err = processor.ProcessBundle(resource, resourceID)
-		} else {
-			pseudonymized, err = processor.ProcessNonBundle(resource, resourceType, resourceID)
+			run.linesDone = lineNumber
+			progress.Line(len(scanner.Bytes()))
+			continue
 		}
 
-		if err != nil {
-			// Clear progress bar before logging error
-			if progressBar != nil {
-				_ = progressBar.Clear()
+		if resourceType != "" && !models.IsKnownFHIRResourceType(resourceType) {
+			if stats.UnknownTypes == nil {
+				stats.UnknownTypes = make(map[string]int)
 			}
+			stats.UnknownTypes[resourceType]++
+		}
+
+		// Only log individual resources at DEBUG level to avoid interfering with progress bar
+		if debugLog.Allow("Processing FHIR resource") {
+			logger.Debug("Processing FHIR resource",
+				"file", filepath.Base(inputFile),
+				"line_number", processor.GetResourceCount()+1,
+				"resourceType", resourceType,
+				"id", resourceID)
+		}
 
-			// Print user-friendly error message
-			fmt.Printf("\n✗ DIMP pseudonymization failed\n")
-			fmt.Printf("  File: %s (line %d)\n", filepath.Base(inputFile), processor.GetResourceCount()+1)
-			fmt.Printf("  Resource: %s/%s\n", resourceType, resourceID)
-			fmt.Printf("  Error: %v\n\n", err)
+		// Non-Bundle resources go into the batch, after the oversized check
+		if resourceType != "Bundle" {
+			if err := processor.checkOversizedResource(resource, resourceType, resourceID); err != nil {
+				reportFailure(processor.GetResourceCount()+1, resourceType+"/"+resourceID, err)
+				return err
+			}
+			if len(batch) > 0 && batchBytes+len(raw) > processor.splitThreshold() {
+				if err := flushBatch(); err != nil {
+					return err
+				}
+			}
+			batch = append(batch, batchedResource{
+				resource:     resource,
+				resourceType: resourceType,
+				resourceID:   resourceID,
+				lineNumber:   lineNumber,
+				lineBytes:    len(scanner.Bytes()),
+			})
+			batchBytes += len(raw)
+			if len(batch) >= batchSize || !processor.batchingEnabled() {
+				if err := flushBatch(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
 
-			return processor.GetResourceCount(), err
+		if err := flushBatch(); err != nil {
+			return err
+		}
+		entries, _ := resource["entry"].([]any)
+		if err := job.Config.Limits.CheckBundleEntries(len(entries)); err != nil {
+			progress.Clear()
+			return fmt.Errorf("%s line %d (Bundle/%s): %w", filepath.Base(inputFile), lineNumber, resourceID, err)
+		}
+		pseudonymized, err := processor.ProcessBundle(resource, resourceID)
+		if err != nil {
+			reportFailure(processor.GetResourceCount()+1, resourceType+"/"+resourceID, err)
+			return err
 		}
 
 		// Write pseudonymized resource to output
 		if err := WriteProcessedResource(pseudonymized, fileCtx.OutFile); err != nil {
-			return processor.GetResourceCount(), err
+			return err
 		}
 
 		processor.IncrementResourceCount()
+		run.linesDone = lineNumber
 
 		// Update progress
-		if progressBar != nil {
-			_ = progressBar.Add(1)
-		}
+		progress.Line(len(scanner.Bytes()))
 	}
 
 	if err := scanner.Err(); err != nil {
-		return processor.GetResourceCount(), fmt.Errorf("error reading file: %w", err)
-	}
-
-	// Finish progress bar
-	if progressBar != nil {
-		_ = progressBar.Finish()
+		return wrapScanError(err, filepath.Base(inputFile), lineNumber+1, maxLineBytes)
 	}
 
-	// Finalize file processing with atomic rename
-	if err := FinalizeFileProcessing(fileCtx, outputFile, true); err != nil {
-		return processor.GetResourceCount(), err
-	}
-
-	return processor.GetResourceCount(), nil
+	return flushBatch()
 }
 
-// newLargeBufferScanner creates a bufio.Scanner with a 100MB buffer to handle very large FHIR resources
-// Default bufio.Scanner buffer is 64KB which can cause "token too long" errors with complex queries
-func newLargeBufferScanner(r interface{ Read([]byte) (int, error) }) *bufio.Scanner {
+// newLargeBufferScanner creates a bufio.Scanner that accepts lines up to maxLineBytes
+// Default bufio.Scanner buffer is 64KB which can cause "token too long" errors with complex queries.
+// The buffer starts small and only grows as needed, so a limit of 100MB doesn't allocate 100MB
+// per file; lines beyond the limit fail with bufio.ErrTooLong instead of exhausting memory
+func newLargeBufferScanner(r interface{ Read([]byte) (int, error) }, maxLineBytes int) *bufio.Scanner {
 	scanner := bufio.NewScanner(r)
-	// Use 100MB buffer for very large FHIR resources (default is 64KB)
-	buf := make([]byte, 0, 100*1024*1024)
-	scanner.Buffer(buf, 100*1024*1024)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
 	return scanner
 }
 
-// countResourcesInFile counts the number of non-empty lines in an NDJSON file
-func countResourcesInFile(filename string) int {
-	file, err := os.Open(filename)
-	if err != nil {
-		return 0
-	}
-	defer func() { _ = file.Close() }()
-
-	count := 0
-	scanner := newLargeBufferScanner(file)
-	for scanner.Scan() {
-		if strings.TrimSpace(scanner.Text()) != "" {
-			count++
-		}
+// wrapScanError adds line and limit context to scanner errors
+// An over-long line is reported with the configured limit so operators know what to change
+func wrapScanError(err error, filename string, lineNumber int, maxLineBytes int) error {
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("line %d of %s exceeds limits.max_line_size_mb (%d MB): %w",
+			lineNumber, filename, maxLineBytes/(1024*1024), err)
 	}
-
-	return count
+	return fmt.Errorf("error reading file: %w", err)
 }
 
 // isDIMPErrorRetryable checks if a DIMP error should be retried
 func isDIMPErrorRetryable(err error) bool {
-	if dimpErr, ok := err.(*services.DIMPError); ok {
+	var dimpErr *services.DIMPError
+	if errors.As(err, &dimpErr) {
 		return dimpErr.IsRetryable()
 	}
 	// Network errors are retryable
@@ -310,7 +842,8 @@ func isDIMPErrorRetryable(err error) bool {
 
 // classifyDIMPError classifies a DIMP error as transient or non-transient
 func classifyDIMPError(err error) models.ErrorType {
-	if dimpErr, ok := err.(*services.DIMPError); ok {
+	var dimpErr *services.DIMPError
+	if errors.As(err, &dimpErr) {
 		return dimpErr.ErrorType
 	}
 	// Network errors are transient
@@ -352,6 +885,7 @@ func recordStepError(step *models.PipelineStep, err error, errorType models.Erro
 	step.LastError = &models.StepError{
 		Type:      errorType,
 		Message:   err.Error(),
-		Timestamp: time.Now(),
+		RequestID: services.RequestIDOf(err),
+		Timestamp: lib.Now(),
 	}
 }
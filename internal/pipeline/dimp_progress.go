@@ -0,0 +1,194 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// lineEstimateSampleBytes is how much of a file the pre-scan reads to estimate its line count
+const lineEstimateSampleBytes = 4 * 1024 * 1024
+
+// LineEstimate is the result of a quick pre-scan of an NDJSON file
+type LineEstimate struct {
+	Lines int64 // Non-empty lines, extrapolated from the sample unless Exact
+	Bytes int64 // File size
+	Exact bool  // The whole file was scanned
+}
+
+// EstimateLineCount estimates the number of non-empty lines in an NDJSON file
+// Only the first few MB are read; the line count of larger files is extrapolated from
+// the average line length of that sample, so multi-GB files are not read twice
+func EstimateLineCount(filename string) (LineEstimate, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return LineEstimate{}, err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return LineEstimate{}, err
+	}
+	estimate := LineEstimate{Bytes: info.Size()}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var sampled int64
+	var lines int64
+	lineHasContent := false
+	for sampled < lineEstimateSampleBytes || lineHasContent {
+		chunk, err := reader.ReadSlice('\n')
+		sampled += int64(len(chunk))
+		if len(bytes.TrimSpace(chunk)) > 0 {
+			lineHasContent = true
+		}
+		if err == nil || errors.Is(err, io.EOF) {
+			if lineHasContent {
+				lines++
+			}
+			lineHasContent = false
+		}
+		if errors.Is(err, io.EOF) {
+			estimate.Lines = lines
+			estimate.Exact = true
+			return estimate, nil
+		}
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return LineEstimate{}, err
+		}
+	}
+
+	estimate.Lines = lines * estimate.Bytes / sampled
+	return estimate, nil
+}
+
+// cachedLineCount returns the resource count recorded for inputFile at import, so the file
+// is not sampled again. The count is only trusted while the file still has its imported size
+func cachedLineCount(job *models.PipelineJob, inputFile string) (LineEstimate, bool) {
+	info, err := os.Stat(inputFile)
+	if err != nil {
+		return LineEstimate{}, false
+	}
+	name := filepath.Base(inputFile)
+	for _, file := range job.ImportedFiles {
+		if file.FileName == name && file.FileSize == info.Size() && file.LineCount > 0 {
+			return LineEstimate{Lines: int64(file.LineCount), Bytes: file.FileSize, Exact: true}, true
+		}
+	}
+	return LineEstimate{}, false
+}
+
+// dimpProgress tracks progress within one file and reports it every progress interval
+// Reports go to the log and, as dimp_progress events, to the job timeline, so operators
+// can follow a long-running file with `aether pipeline status --events`
+type dimpProgress struct {
+	job      *models.PipelineJob
+	logger   *lib.Logger
+	task     lib.ProgressTask
+	file     string
+	estimate LineEstimate
+	interval time.Duration
+	started  time.Time
+	reported time.Time
+	lines    int64
+	bytes    int64
+}
+
+// newDIMPProgress starts tracking a file as a task of progress
+// Without a usable estimate the task's total is unknown; periodic reports still run
+func newDIMPProgress(job *models.PipelineJob, logger *lib.Logger, progress lib.ProgressReporter, inputFile string) *dimpProgress {
+	p := &dimpProgress{
+		job:      job,
+		logger:   logger,
+		file:     filepath.Base(inputFile),
+		interval: job.Config.Services.DIMP.GetProgressInterval(),
+		started:  time.Now(),
+	}
+	p.reported = p.started
+
+	estimate, ok := cachedLineCount(job, inputFile)
+	if !ok {
+		var err error
+		estimate, err = EstimateLineCount(inputFile)
+		if err != nil {
+			logger.Debug("Failed to estimate line count", "file", p.file, "error", err)
+		}
+	}
+	p.estimate = estimate
+	if estimate.Lines == 0 {
+		logger.Info("Processing FHIR resources (unknown count)", "file", p.file)
+	}
+	p.task = progress.Start(fmt.Sprintf("Pseudonymizing %s", p.file), estimate.Lines)
+	return p
+}
+
+// Line records one processed line of lineBytes bytes (without the newline)
+func (p *dimpProgress) Line(lineBytes int) {
+	p.lines++
+	p.bytes += int64(lineBytes) + 1
+	// An estimate can be too low; grow the total instead of overflowing it
+	if p.estimate.Lines > 0 && p.lines > p.estimate.Lines {
+		p.estimate.Lines = p.lines
+		p.task.SetTotal(p.lines)
+	}
+	p.task.Add(1)
+	if p.interval > 0 && time.Since(p.reported) >= p.interval {
+		p.report()
+	}
+}
+
+// report logs and records the current progress with an ETA based on bytes processed
+func (p *dimpProgress) report() {
+	p.reported = time.Now()
+	processed := min(p.bytes, p.estimate.Bytes)
+
+	percent := 0.0
+	if p.estimate.Bytes > 0 {
+		percent = float64(processed) * 100 / float64(p.estimate.Bytes)
+	}
+	fields := map[string]any{
+		"file":            p.file,
+		"lines":           p.lines,
+		"estimated_lines": p.estimate.Lines,
+		"bytes":           processed,
+		"total_bytes":     p.estimate.Bytes,
+		"percent":         int(percent),
+	}
+
+	linesText := fmt.Sprintf("~%d", p.estimate.Lines)
+	if p.estimate.Exact {
+		linesText = fmt.Sprintf("%d", p.estimate.Lines)
+	}
+	message := fmt.Sprintf("%s: %.0f%% (%d/%s lines, %s/%s)", p.file, percent, p.lines, linesText,
+		lib.FormatBytes(processed), lib.FormatBytes(p.estimate.Bytes))
+
+	if processed > 0 && processed < p.estimate.Bytes {
+		elapsed := p.reported.Sub(p.started)
+		eta := time.Duration(float64(elapsed) * float64(p.estimate.Bytes-processed) / float64(processed)).Round(time.Second)
+		fields["eta_seconds"] = int(eta.Seconds())
+		message += fmt.Sprintf(", ETA %s", eta)
+	}
+
+	p.task.Clear()
+	p.logger.Info("DIMP progress", "file", p.file, "lines", p.lines, "estimated_lines", p.estimate.Lines,
+		"bytes", processed, "total_bytes", p.estimate.Bytes, "percent", int(percent))
+	recordJobEvent(p.job, p.logger, models.EventDIMPProgress, string(models.StepDIMP), message, fields)
+}
+
+// Clear removes the task from the terminal before an error is printed
+func (p *dimpProgress) Clear() {
+	p.task.Clear()
+}
+
+// Finish ends the task as succeeded or failed
+func (p *dimpProgress) Finish(err error) {
+	p.task.Done(err)
+}
@@ -0,0 +1,89 @@
+package pipeline
+
+import "sync"
+
+// dimpFileResult is the outcome of one input file of the DIMP step
+type dimpFileResult struct {
+	done     chan struct{} // Closed once the fields below are set
+	skipped  bool          // Output already existed from an earlier run
+	cached   bool          // Output was restored from the DIMP output cache
+	stopped  bool          // The step was stopped before the file was started
+	cacheKey string        // Key to cache the output under; empty if the output cache is off
+	stats    dimpFileStats
+	err      error
+}
+
+// dimpFileWorkers processes the files of a DIMP step with a fixed number of workers
+// Files are started in order and each file is written by a single worker, so the output of
+// every file keeps its input order and resume (skipping finished outputs) works as in a
+// serial run. After a file fails no further file is started. Results are read in file order
+// through Result
+type dimpFileWorkers struct {
+	results  []*dimpFileResult
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// startDIMPFileWorkers starts parallelism workers calling process for each of count files
+func startDIMPFileWorkers(count, parallelism int, process func(index int, result *dimpFileResult)) *dimpFileWorkers {
+	w := &dimpFileWorkers{
+		results: make([]*dimpFileResult, count),
+		stop:    make(chan struct{}),
+	}
+	for i := range w.results {
+		w.results[i] = &dimpFileResult{done: make(chan struct{})}
+	}
+
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for i := range count {
+			select {
+			case indices <- i:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+
+	for range min(parallelism, count) {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for i := range indices {
+				if w.stopped() {
+					continue
+				}
+				process(i, w.results[i])
+				if w.results[i].err != nil {
+					// Files already started run to completion; their output is kept for a resumed run
+					w.stopOnce.Do(func() { close(w.stop) })
+				}
+				close(w.results[i].done)
+			}
+		}()
+	}
+	return w
+}
+
+// Result waits for the file at index to finish and returns its result
+func (w *dimpFileWorkers) Result(index int) *dimpFileResult {
+	<-w.results[index].done
+	return w.results[index]
+}
+
+// stopped reports whether a file failed, after which no further file is started
+func (w *dimpFileWorkers) stopped() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until all started files have finished
+func (w *dimpFileWorkers) Wait() {
+	w.wg.Wait()
+}
@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// recordJobEvent appends an event to the job's timeline
+// Failures are logged but never fail the pipeline - the timeline is diagnostic only
+func recordJobEvent(job *models.PipelineJob, logger *lib.Logger, eventType models.JobEventType, step string, message string, fields map[string]any) {
+	message, fields = redactEventMetadata(job, message, fields)
+	event := models.JobEvent{
+		Timestamp: lib.Now(),
+		Type:      eventType,
+		Step:      step,
+		Message:   message,
+		Fields:    fields,
+	}
+	if err := services.AppendJobEvent(job.Config.JobsDir, job.JobID, event); err != nil && logger != nil {
+		logger.Debug("Failed to record job event", "type", eventType, "error", err)
+	}
+}
+
+// redactEventMetadata replaces input sources and TORCH URLs in an event per job_metadata policy
+func redactEventMetadata(job *models.PipelineJob, message string, fields map[string]any) (string, map[string]any) {
+	policy := job.Config.JobMetadata
+	if !policy.IsActive() {
+		return message, fields
+	}
+
+	message = policy.RedactText(*job, message)
+	if len(fields) == 0 {
+		return message, fields
+	}
+	redacted := make(map[string]any, len(fields))
+	for key, value := range fields {
+		if text, ok := value.(string); ok {
+			value = policy.RedactText(*job, text)
+		}
+		redacted[key] = value
+	}
+	return message, redacted
+}
+
+// jobEventSink returns a sink that records service events against the job's current step
+func jobEventSink(job *models.PipelineJob, logger *lib.Logger) services.JobEventSink {
+	return func(event models.JobEvent) {
+		if event.Step == "" {
+			event.Step = job.CurrentStep
+		}
+		recordJobEvent(job, logger, event.Type, event.Step, event.Message, event.Fields)
+	}
+}
+
+// jobWaitSink returns a sink that publishes pending retries and polls to jobs/<id>/wait.json
+func jobWaitSink(job *models.PipelineJob, logger *lib.Logger) services.WaitSink {
+	return func(state *models.WaitState) {
+		if err := services.SaveWaitState(job.Config.JobsDir, job.JobID, state); err != nil && logger != nil {
+			logger.Debug("Failed to record wait state", "error", err)
+		}
+	}
+}
+
+// attachLogContext stores the log lines written since mark on the step's last error
+func attachLogContext(step *models.PipelineStep, logger *lib.Logger, mark lib.LogMark) {
+	if step == nil || step.LastError == nil || logger == nil {
+		return
+	}
+	step.LastError.Context = logger.LinesSince(mark, models.MaxErrorContextLines)
+}
+
+// recordStepWarning records a non-fatal issue on the step, logs it and adds it to the timeline
+// count is the number of affected lines, files or resources; file is empty for step-wide warnings
+func recordStepWarning(job *models.PipelineJob, logger *lib.Logger, stepName models.StepName, code models.WarningCode, file string, count int, message string) {
+	getOrCreateStep(job, stepName).SetWarning(models.StepWarning{
+		Code:      code,
+		File:      file,
+		Count:     count,
+		Message:   message,
+		Timestamp: lib.Now(),
+	})
+	if logger != nil {
+		logger.Warn(message, "code", code, "file", file, "count", count, "job_id", job.JobID)
+	}
+	recordJobEvent(job, logger, models.EventStepWarning, string(stepName), message,
+		map[string]any{"code": string(code), "file": file, "count": count})
+}
+
+// maxListedFileNames is how many file names a warning message lists
+const maxListedFileNames = 5
+
+// listFileNames joins the base names of paths for a warning message, shortening long lists
+func listFileNames(paths []string) string {
+	names := make([]string, 0, maxListedFileNames)
+	for i, path := range paths {
+		if i == maxListedFileNames {
+			break
+		}
+		names = append(names, filepath.Base(path))
+	}
+	list := strings.Join(names, ", ")
+	if len(paths) > maxListedFileNames {
+		list += fmt.Sprintf(" (+%d more)", len(paths)-maxListedFileNames)
+	}
+	return list
+}
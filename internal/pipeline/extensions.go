@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// applyExtensionFilter drops extensions rejected by the allow/deny lists from imported files
+// modifierExtension is never filtered, since dropping it would change the meaning of the resource.
+// Returns the number of extensions dropped; file sizes are refreshed for rewritten files
+func applyExtensionFilter(importDir string, files []models.FHIRDataFile, filter models.ExtensionFilterConfig, maxLineBytes int, logger *lib.Logger) (int, error) {
+	if !filter.IsActive() {
+		return 0, nil
+	}
+
+	total := 0
+	for i := range files {
+		path := filepath.Join(importDir, files[i].FileName)
+		dropped := 0
+		changed, err := rewriteResourcesInPlace(path, maxLineBytes, `"extension"`, func(resource map[string]any) (bool, error) {
+			count := filterExtensions(resource, filter)
+			dropped += count
+			return count > 0, nil
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to filter extensions in %s: %w", files[i].FileName, err)
+		}
+		total += dropped
+
+		if changed {
+			files[i].FileSize = lib.GetFileSize(path)
+			logger.Debug("Filtered extensions", "file", files[i].FileName, "dropped", dropped)
+		}
+	}
+
+	if total > 0 {
+		logger.Info("Extension filter applied", "dropped", total)
+	}
+	return total, nil
+}
+
+// filterExtensions walks a JSON value and removes rejected entries from every extension array
+// Kept extensions are not descended into: their sub-extensions use relative URLs and belong to them.
+// Empty extension arrays and primitive-extension objects (_birthDate etc.) left empty are removed
+func filterExtensions(value any, filter models.ExtensionFilterConfig) int {
+	count := 0
+
+	switch v := value.(type) {
+	case map[string]any:
+		if extensions, ok := v["extension"].([]any); ok {
+			kept := extensions[:0]
+			for _, extension := range extensions {
+				extensionMap, isMap := extension.(map[string]any)
+				url, hasURL := extensionMap["url"].(string)
+				if isMap && hasURL && !filter.Keeps(url) {
+					count++
+					continue
+				}
+				kept = append(kept, extension)
+			}
+			if len(kept) == 0 {
+				delete(v, "extension")
+			} else {
+				v["extension"] = kept
+			}
+		}
+
+		for key, child := range v {
+			if key == "extension" {
+				continue
+			}
+			count += filterExtensions(child, filter)
+			if childMap, ok := child.(map[string]any); ok && strings.HasPrefix(key, "_") && len(childMap) == 0 {
+				delete(v, key)
+			}
+		}
+
+	case []any:
+		for _, child := range v {
+			count += filterExtensions(child, filter)
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// cohortSizePrompt asks the operator whether to extract a cohort outside the configured bounds
+// Only set by interactive commands; without it, cohort_size_action "prompt" aborts like "abort"
+var cohortSizePrompt func(question string) bool
+
+// SetCohortSizePrompt installs the question asked for an empty or too large cohort
+// (services.torch.cohort_size_action: prompt). Passing nil restores aborting
+func SetCohortSizePrompt(prompt func(question string) bool) {
+	cohortSizePrompt = prompt
+}
+
+// CohortSizeError reports a cohort that is empty or exceeds services.torch.max_cohort_size
+type CohortSizeError struct {
+	Count int
+	Max   int
+}
+
+func (e *CohortSizeError) Error() string {
+	if e.Count == 0 {
+		return "cohort is empty - check the inclusion and exclusion criteria of the CRTDL"
+	}
+	return fmt.Sprintf("cohort has %d patients, exceeding services.torch.max_cohort_size (%d)", e.Count, e.Max)
+}
+
+// checkCohortSize counts the cohort of the job's CRTDL at services.torch.feasibility_url
+// before anything is submitted to TORCH. Empty cohorts and cohorts above max_cohort_size
+// fail the step with a CohortSizeError unless the operator confirms them when prompted
+// The accepted size is kept in the job, so retries of the step do not ask again
+func checkCohortSize(job *models.PipelineJob, torchClient *services.TORCHClient, logger *lib.Logger) error {
+	config := job.Config.Services.TORCH
+	if config.FeasibilityURL == "" || job.CohortSize != nil {
+		return nil
+	}
+
+	count, err := torchClient.CountCohort(job.InputSource)
+	if err != nil {
+		return err
+	}
+	logger.Info("Cohort counted", "patients", count, "max_cohort_size", config.MaxCohortSize)
+
+	var sizeErr *CohortSizeError
+	if count == 0 || (config.MaxCohortSize > 0 && count > config.MaxCohortSize) {
+		sizeErr = &CohortSizeError{Count: count, Max: config.MaxCohortSize}
+	}
+	confirmed := sizeErr != nil && config.CohortSizeAction == models.CohortSizeActionPrompt &&
+		cohortSizePrompt != nil && cohortSizePrompt(sizeErr.Error())
+
+	recordJobEvent(job, logger, models.EventCohortSize, job.CurrentStep,
+		fmt.Sprintf("cohort has %d patients", count),
+		map[string]any{"patients": count, "max_cohort_size": config.MaxCohortSize, "within_bounds": sizeErr == nil, "confirmed": confirmed})
+
+	if sizeErr != nil && !confirmed {
+		return sizeErr
+	}
+	if confirmed {
+		logger.Warn("Extracting cohort outside the configured bounds as confirmed", "patients", count)
+	}
+	job.CohortSize = &count
+	return nil
+}
@@ -0,0 +1,56 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// executeFHIRExport runs the FHIR Bulk Data $export kicked off by job.InputSource and downloads
+// its output files into importDir. Error files of the manifest are not imported; they are
+// recorded as an export_errors warning
+func executeFHIRExport(job *models.PipelineJob, importDir string, httpClient *services.HTTPClient, logger *lib.Logger, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
+	config := job.Config.Services.FHIRExport
+	client := services.NewFHIRExportClient(config, httpClient, logger)
+	client.SetEventSink(jobEventSink(job, logger))
+
+	statusURL, err := client.KickOff(job.InputSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start FHIR export: %w", err)
+	}
+	logger.Info("FHIR export started", "status_url", statusURL)
+
+	manifest, err := client.PollStatus(statusURL, progress)
+	if err != nil {
+		// Nobody will collect the files of an export that timed out or was cancelled
+		if deleteErr := client.Delete(statusURL); deleteErr != nil {
+			logger.Warn("Failed to cancel FHIR export", "url", statusURL, "error", deleteErr)
+		}
+		return nil, fmt.Errorf("FHIR export failed: %w", err)
+	}
+
+	files, err := client.DownloadFiles(manifest, manifest.Output, importDir, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	step := models.StepName(job.CurrentStep)
+	if len(manifest.Error) > 0 {
+		recordStepWarning(job, logger, step, models.WarningExportErrors, "", len(manifest.Error),
+			fmt.Sprintf("FHIR export listed %d error file(s) with OperationOutcomes for resources it could not export", len(manifest.Error)))
+	}
+	if len(files) == 0 {
+		recordStepWarning(job, logger, step, models.WarningEmptyResult, "", 0, "FHIR export returned no files")
+	}
+
+	if config.CleanupAfterDownload {
+		if err := client.Delete(statusURL); err != nil {
+			logger.Warn("Failed to delete FHIR export files on the server", "url", statusURL, "error", err)
+		} else {
+			logger.Info("Deleted FHIR export files on the server", "url", statusURL)
+		}
+	}
+	return files, nil
+}
@@ -1,9 +1,11 @@
 package pipeline
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 // FileContext holds file handles and cleanup logic for atomic file writing
@@ -90,3 +92,179 @@ func WriteProcessedResource(resource map[string]any, outFile *os.File) error {
 
 	return nil
 }
+
+// rewriteResourcesInPlace applies transform to every resource of an NDJSON file (via .part and rename)
+// Only lines containing marker are parsed; all other lines are copied unchanged.
+// transform reports whether it modified the resource. The file is left untouched if nothing changed
+func rewriteResourcesInPlace(path string, maxLineBytes int, marker string, transform func(resource map[string]any) (bool, error)) (bool, error) {
+	fileCtx, err := SetupFileProcessing(path, path)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	markerBytes := []byte(marker)
+	scanner := newLargeBufferScanner(fileCtx.InFile, maxLineBytes)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		output := line
+
+		if bytes.Contains(line, markerBytes) {
+			var resource map[string]any
+			if err := json.Unmarshal(line, &resource); err != nil {
+				_ = FinalizeFileProcessing(fileCtx, path, false)
+				return false, fmt.Errorf("failed to parse resource at line %d: %w", lineNumber, err)
+			}
+
+			modified, err := transform(resource)
+			if err != nil {
+				_ = FinalizeFileProcessing(fileCtx, path, false)
+				return false, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+
+			if modified {
+				if output, err = json.Marshal(resource); err != nil {
+					_ = FinalizeFileProcessing(fileCtx, path, false)
+					return false, fmt.Errorf("failed to marshal resource at line %d: %w", lineNumber, err)
+				}
+				changed = true
+			}
+		}
+
+		// output may alias the scanner's buffer, so the newline is written separately
+		if _, err := fileCtx.OutFile.Write(output); err != nil {
+			_ = FinalizeFileProcessing(fileCtx, path, false)
+			return false, fmt.Errorf("failed to write output: %w", err)
+		}
+		if _, err := fileCtx.OutFile.Write([]byte("\n")); err != nil {
+			_ = FinalizeFileProcessing(fileCtx, path, false)
+			return false, fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		_ = FinalizeFileProcessing(fileCtx, path, false)
+		return false, wrapScanError(err, filepath.Base(path), lineNumber+1, maxLineBytes)
+	}
+
+	return changed, FinalizeFileProcessing(fileCtx, path, changed)
+}
+
+// removeResourcesInPlace drops every resource of an NDJSON file that keep rejects (via .part and rename)
+// Blank lines are copied unchanged. Returns the number of removed resources; the file is left
+// untouched if none was removed
+func removeResourcesInPlace(path string, maxLineBytes int, keep func(resource map[string]any) (bool, error)) (int, error) {
+	fileCtx, err := SetupFileProcessing(path, path)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	scanner := newLargeBufferScanner(fileCtx.InFile, maxLineBytes)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			var resource map[string]any
+			if err := json.Unmarshal(line, &resource); err != nil {
+				_ = FinalizeFileProcessing(fileCtx, path, false)
+				return 0, fmt.Errorf("failed to parse resource at line %d: %w", lineNumber, err)
+			}
+
+			kept, err := keep(resource)
+			if err != nil {
+				_ = FinalizeFileProcessing(fileCtx, path, false)
+				return 0, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			if !kept {
+				removed++
+				continue
+			}
+		}
+
+		if _, err := fileCtx.OutFile.Write(line); err != nil {
+			_ = FinalizeFileProcessing(fileCtx, path, false)
+			return 0, fmt.Errorf("failed to write output: %w", err)
+		}
+		if _, err := fileCtx.OutFile.Write([]byte("\n")); err != nil {
+			_ = FinalizeFileProcessing(fileCtx, path, false)
+			return 0, fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		_ = FinalizeFileProcessing(fileCtx, path, false)
+		return 0, wrapScanError(err, filepath.Base(path), lineNumber+1, maxLineBytes)
+	}
+
+	return removed, FinalizeFileProcessing(fileCtx, path, removed > 0)
+}
+
+// QuarantineWriter collects lines that can't be processed into quarantine/<filename>
+// The quarantine file is created lazily on the first line and follows the same
+// .part + rename pattern as regular output files
+type QuarantineWriter struct {
+	dir      string
+	filename string
+	file     *os.File
+}
+
+// newQuarantineWriter creates a writer for lines quarantined from the given input file
+func newQuarantineWriter(dir, filename string) *QuarantineWriter {
+	return &QuarantineWriter{dir: dir, filename: filename}
+}
+
+// WriteLine appends a single line to the quarantine file
+func (q *QuarantineWriter) WriteLine(line string) error {
+	if q.file == nil {
+		if err := os.MkdirAll(q.dir, 0755); err != nil {
+			return fmt.Errorf("failed to create quarantine directory: %w", err)
+		}
+		f, err := os.Create(q.path() + ".part")
+		if err != nil {
+			return fmt.Errorf("failed to create quarantine file: %w", err)
+		}
+		q.file = f
+	}
+
+	if _, err := q.file.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to write quarantined line: %w", err)
+	}
+	return nil
+}
+
+// Commit closes the quarantine file and renames it to its final name
+// No-op if nothing was quarantined
+func (q *QuarantineWriter) Commit() error {
+	if q.file == nil {
+		return nil
+	}
+
+	if err := q.file.Close(); err != nil {
+		return fmt.Errorf("failed to close quarantine file: %w", err)
+	}
+	q.file = nil
+
+	if err := os.Rename(q.path()+".part", q.path()); err != nil {
+		return fmt.Errorf("failed to rename quarantine file: %w", err)
+	}
+	return nil
+}
+
+// Abort discards any uncommitted quarantined lines
+func (q *QuarantineWriter) Abort() {
+	if q.file == nil {
+		return
+	}
+	_ = q.file.Close()
+	_ = os.Remove(q.path() + ".part")
+	q.file = nil
+}
+
+func (q *QuarantineWriter) path() string {
+	return filepath.Join(q.dir, q.filename)
+}
@@ -0,0 +1,349 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const (
+	// ImagingReportFileName is the summary written to the job's imaging/ directory
+	ImagingReportFileName = "imaging_report.json"
+
+	// dicomMetadataDirName holds one <StudyInstanceUID>.json per retrieved study
+	dicomMetadataDirName = "dicom"
+
+	// dicomUIDSystem identifies ImagingStudy identifiers carrying the Study Instance UID
+	dicomUIDSystem = "urn:dicom:uid"
+)
+
+// ImagingReport summarizes an imaging step run
+type ImagingReport struct {
+	Studies            int      `json:"studies"`                   // Distinct Study Instance UIDs found
+	StudiesWithoutUID  int      `json:"studies_without_uid"`       // ImagingStudy resources lacking a urn:dicom:uid identifier
+	MetadataRetrieved  int      `json:"metadata_retrieved"`        // Studies whose DICOM metadata is in imaging/dicom/
+	MissingStudies     []string `json:"missing_studies,omitempty"` // Studies unknown to the DICOMweb server
+	EndpointsRewritten int      `json:"endpoints_rewritten"`       // Endpoint addresses changed by wado_rewrite
+}
+
+// ExecuteImagingStep passes imaging references through the pipeline
+// Reads from pseudonymized/ (if DIMP ran before this step) or import/, writes to imaging/:
+// all files with Endpoint addresses rewritten, DICOM metadata per study and imaging_report.json
+func ExecuteImagingStep(ctx context.Context, job *models.PipelineJob, jobDir string, logger *lib.Logger) error {
+	stepName := models.StepImaging
+
+	if !isStepEnabled(job.Config, stepName) {
+		logger.Info("Imaging step not enabled, skipping", "job_id", job.JobID)
+		return nil
+	}
+
+	return RunStep(ctx, job, stepName, logger, func(ctx context.Context) error {
+		return executeImagingStep(ctx, job, jobDir, logger)
+	})
+}
+
+// executeImagingStep does the work; ExecuteImagingStep wraps it with the step middleware
+// ctx carries the step's time budget deadline and bounds the DICOMweb calls
+func executeImagingStep(ctx context.Context, job *models.PipelineJob, jobDir string, logger *lib.Logger) error {
+	stepName := models.StepImaging
+	config := job.Config.Services.Imaging
+
+	step := getOrCreateStep(job, stepName)
+	step.Status = models.StepStatusInProgress
+	now := lib.Now()
+	step.StartedAt = &now
+
+	fail := func(err error, errorType models.ErrorType) error {
+		recordStepError(step, err, errorType)
+		return err
+	}
+
+	inputDir := imagingInputDir(job, jobDir)
+	outputDir := filepath.Join(jobDir, "imaging")
+	metadataDir := filepath.Join(outputDir, dicomMetadataDirName)
+	if err := os.MkdirAll(metadataDir, 0755); err != nil {
+		return fail(fmt.Errorf("failed to create output directory: %w", err), models.ErrorTypeNonTransient)
+	}
+
+	files, err := filepath.Glob(filepath.Join(inputDir, "*.ndjson"))
+	if err != nil {
+		return fail(fmt.Errorf("failed to list input files: %w", err), models.ErrorTypeNonTransient)
+	}
+	if len(files) == 0 {
+		return fail(fmt.Errorf("no FHIR NDJSON files found in %s", filepath.Base(inputDir)), models.ErrorTypeNonTransient)
+	}
+
+	fmt.Printf("Scanning %d FHIR file(s) for imaging references...\n\n", len(files))
+
+	maxLineBytes := job.Config.Limits.GetMaxLineBytes()
+	report := ImagingReport{}
+	studyUIDs := make(map[string]bool)
+	var bytesProcessed int64
+
+	for fileIdx, inputFile := range files {
+		baseName := filepath.Base(inputFile)
+		stats, err := processImagingFile(inputFile, filepath.Join(outputDir, baseName), config, maxLineBytes)
+		if err != nil {
+			return fail(fmt.Errorf("failed to process %s: %w", baseName, err), models.ErrorTypeNonTransient)
+		}
+
+		for _, uid := range stats.StudyUIDs {
+			studyUIDs[uid] = true
+		}
+		report.StudiesWithoutUID += stats.StudiesWithoutUID
+		report.EndpointsRewritten += stats.EndpointsRewritten
+		bytesProcessed += lib.GetFileSize(inputFile)
+
+		fmt.Printf("  ✓ %s (%d studies, %d endpoints rewritten)\n", baseName, len(stats.StudyUIDs), stats.EndpointsRewritten)
+		recordJobEvent(job, logger, models.EventFileProcessed, string(stepName),
+			fmt.Sprintf("file %d/%d processed: %s", fileIdx+1, len(files), baseName),
+			map[string]any{"file": baseName, "index": fileIdx + 1, "total": len(files), "studies": len(stats.StudyUIDs)})
+	}
+
+	uids := make([]string, 0, len(studyUIDs))
+	for uid := range studyUIDs {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	report.Studies = len(uids)
+
+	if config.DICOMwebURL != "" && len(uids) > 0 {
+		httpClient := services.NewHTTPClient(30*time.Second, job.Config.Retry, logger)
+		httpClient.SetEventSink(jobEventSink(job, logger))
+		httpClient.SetJobID(job.JobID)
+		httpClient.SetWaitSink(jobWaitSink(job, logger))
+		httpClient.SetContext(ctx)
+		dicomClient := services.NewDICOMwebClient(config.DICOMwebURL, httpClient, logger)
+
+		fmt.Printf("\nRetrieving DICOM metadata for %d studies...\n", len(uids))
+		for _, uid := range uids {
+			metadataFile := filepath.Join(metadataDir, uid+".json")
+
+			// Resume support: metadata retrieved by an earlier run is kept
+			if _, err := os.Stat(metadataFile); err == nil {
+				report.MetadataRetrieved++
+				continue
+			}
+
+			metadata, err := dicomClient.FetchStudyMetadata(uid)
+			if errors.Is(err, services.ErrStudyNotFound) {
+				logger.Warn("Study not found on DICOMweb server", "study_uid", uid)
+				report.MissingStudies = append(report.MissingStudies, uid)
+				continue
+			}
+			if err != nil {
+				errorType := models.ErrorTypeNonTransient
+				if errors.Is(err, services.ErrDICOMwebUnavailable) {
+					errorType = models.ErrorTypeTransient
+				}
+				return fail(fmt.Errorf("failed to retrieve metadata for study %s: %w", uid, err), errorType)
+			}
+
+			if err := os.WriteFile(metadataFile, metadata, 0644); err != nil {
+				return fail(fmt.Errorf("failed to write metadata for study %s: %w", uid, err), models.ErrorTypeNonTransient)
+			}
+			report.MetadataRetrieved++
+		}
+	}
+
+	if err := writeImagingReport(filepath.Join(outputDir, ImagingReportFileName), report); err != nil {
+		return fail(err, models.ErrorTypeNonTransient)
+	}
+
+	fmt.Printf("\nImaging: %d studies, %d with metadata, %d missing, %d endpoints rewritten\n",
+		report.Studies, report.MetadataRetrieved, len(report.MissingStudies), report.EndpointsRewritten)
+
+	if report.StudiesWithoutUID > 0 {
+		recordStepWarning(job, logger, stepName, models.WarningStudiesWithoutUID, "", report.StudiesWithoutUID,
+			fmt.Sprintf("%d ImagingStudy resources have no Study Instance UID", report.StudiesWithoutUID))
+	}
+	if len(report.MissingStudies) > 0 && !config.FailOnMissingMetadata {
+		recordStepWarning(job, logger, stepName, models.WarningStudiesMissing, "", len(report.MissingStudies),
+			fmt.Sprintf("DICOM metadata missing for %d of %d studies (see %s)", len(report.MissingStudies), report.Studies, ImagingReportFileName))
+	}
+
+	if len(report.MissingStudies) > 0 && config.FailOnMissingMetadata {
+		return fail(fmt.Errorf("DICOM metadata missing for %d of %d studies (see %s)",
+			len(report.MissingStudies), report.Studies, ImagingReportFileName), models.ErrorTypeNonTransient)
+	}
+
+	if err := checkStrictWarnings(job, stepName); err != nil {
+		return fail(err, models.ErrorTypeNonTransient)
+	}
+
+	if err := checkStepPostCondition(job, stepName, inputDir, outputDir, logger); err != nil {
+		return fail(err, models.ErrorTypeNonTransient)
+	}
+
+	step.Status = models.StepStatusCompleted
+	step.FilesProcessed = len(files)
+	step.BytesProcessed = bytesProcessed
+	completedAt := lib.Now()
+	step.CompletedAt = &completedAt
+
+	logger.Debug("Imaging step completed",
+		"files_processed", len(files),
+		"studies", report.Studies,
+		"metadata_retrieved", report.MetadataRetrieved,
+		"missing_studies", len(report.MissingStudies),
+		"endpoints_rewritten", report.EndpointsRewritten,
+		"duration", completedAt.Sub(*step.StartedAt),
+		"job_id", job.JobID)
+
+	return nil
+}
+
+// imagingInputDir returns pseudonymized/ when DIMP runs before the imaging step, import/ otherwise
+func imagingInputDir(job *models.PipelineJob, jobDir string) string {
+	for _, step := range job.Config.Pipeline.EnabledSteps {
+		switch step {
+		case models.StepDIMP:
+			return filepath.Join(jobDir, "pseudonymized")
+		case models.StepImaging:
+			return filepath.Join(jobDir, "import")
+		}
+	}
+	return filepath.Join(jobDir, "import")
+}
+
+// imagingFileStats summarizes the imaging references found in one NDJSON file
+type imagingFileStats struct {
+	StudyUIDs          []string
+	StudiesWithoutUID  int
+	EndpointsRewritten int
+}
+
+// processImagingFile copies an NDJSON file, rewriting Endpoint addresses and collecting Study Instance UIDs
+// Lines without changes are written byte-for-byte; the output uses the .part + rename pattern
+func processImagingFile(inputFile, outputFile string, config models.ImagingConfig, maxLineBytes int) (imagingFileStats, error) {
+	stats := imagingFileStats{}
+
+	fileCtx, err := SetupFileProcessing(inputFile, outputFile)
+	if err != nil {
+		return stats, err
+	}
+
+	scanner := newLargeBufferScanner(fileCtx.InFile, maxLineBytes)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		output := []byte(line)
+
+		// Only lines mentioning imaging resources need to be parsed
+		if strings.Contains(line, `"ImagingStudy"`) || strings.Contains(line, `"Endpoint"`) {
+			var resource map[string]any
+			if err := json.Unmarshal([]byte(line), &resource); err != nil {
+				_ = FinalizeFileProcessing(fileCtx, outputFile, false)
+				return stats, fmt.Errorf("failed to parse resource at line %d: %w", lineNumber, err)
+			}
+
+			rewritten := 0
+			walkResources(resource, func(r map[string]any) {
+				switch r["resourceType"] {
+				case "ImagingStudy":
+					if uid := studyInstanceUID(r); uid != "" {
+						stats.StudyUIDs = append(stats.StudyUIDs, uid)
+					} else {
+						stats.StudiesWithoutUID++
+					}
+				case "Endpoint":
+					if address, ok := r["address"].(string); ok {
+						if newAddress, changed := config.RewriteAddress(address); changed {
+							r["address"] = newAddress
+							rewritten++
+						}
+					}
+				}
+			})
+
+			if rewritten > 0 {
+				stats.EndpointsRewritten += rewritten
+				if output, err = json.Marshal(resource); err != nil {
+					_ = FinalizeFileProcessing(fileCtx, outputFile, false)
+					return stats, fmt.Errorf("failed to marshal resource at line %d: %w", lineNumber, err)
+				}
+			}
+		}
+
+		if _, err := fileCtx.OutFile.Write(append(output, '\n')); err != nil {
+			_ = FinalizeFileProcessing(fileCtx, outputFile, false)
+			return stats, fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		_ = FinalizeFileProcessing(fileCtx, outputFile, false)
+		return stats, wrapScanError(err, filepath.Base(inputFile), lineNumber+1, maxLineBytes)
+	}
+
+	return stats, FinalizeFileProcessing(fileCtx, outputFile, true)
+}
+
+// walkResources calls fn for a resource, its contained resources and the resources of Bundle entries
+func walkResources(resource map[string]any, fn func(map[string]any)) {
+	fn(resource)
+
+	if contained, ok := resource["contained"].([]any); ok {
+		for _, item := range contained {
+			if r, ok := item.(map[string]any); ok {
+				walkResources(r, fn)
+			}
+		}
+	}
+
+	if entries, ok := resource["entry"].([]any); ok {
+		for _, item := range entries {
+			if entry, ok := item.(map[string]any); ok {
+				if r, ok := entry["resource"].(map[string]any); ok {
+					walkResources(r, fn)
+				}
+			}
+		}
+	}
+}
+
+// studyInstanceUID returns the DICOM Study Instance UID of an ImagingStudy
+// FHIR carries it as identifier {system: urn:dicom:uid, value: urn:oid:<uid>}
+func studyInstanceUID(imagingStudy map[string]any) string {
+	identifiers, _ := imagingStudy["identifier"].([]any)
+	for _, item := range identifiers {
+		identifier, ok := item.(map[string]any)
+		if !ok || identifier["system"] != dicomUIDSystem {
+			continue
+		}
+		value, _ := identifier["value"].(string)
+		uid := strings.TrimPrefix(value, "urn:oid:")
+		// UIDs are dotted digits; anything else is rejected since the UID becomes a file name
+		if uid != "" && strings.Trim(uid, "0123456789.") == "" {
+			return uid
+		}
+	}
+	return ""
+}
+
+// writeImagingReport writes the imaging summary as indented JSON
+func writeImagingReport(path string, report ImagingReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal imaging report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write imaging report: %w", err)
+	}
+	return nil
+}
@@ -1,7 +1,12 @@
 package pipeline
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/trobanga/aether/internal/lib"
@@ -11,80 +16,314 @@ import (
 
 // ExecuteImportStep performs the import step of the pipeline
 // Detects input type (local vs HTTP) and delegates to appropriate importer
-// Updates job state with progress and imported files
-func ExecuteImportStep(job *models.PipelineJob, logger *lib.Logger, httpClient *services.HTTPClient, showProgress bool) (*models.PipelineJob, error) {
-	startTime := time.Now()
+// Updates job state with progress and imported files. Cancelling ctx stops the HTTP calls of
+// the import and leaves the step cancelled
+func ExecuteImportStep(ctx context.Context, job *models.PipelineJob, logger *lib.Logger, httpClient *services.HTTPClient, progress lib.ProgressReporter) (*models.PipelineJob, error) {
+	run := &stepRun{
+		ctx:         ctx,
+		job:         job,
+		step:        models.StepName(job.CurrentStep),
+		logger:      logger,
+		startFields: map[string]any{"source": job.InputSource},
+		fail: func(run *stepRun, err error, errorType models.ErrorType) {
+			*run.job = failImportStep(run.job, err, errorType, 0)
+		},
+		result: func(run *stepRun) (int, int64) {
+			return run.job.TotalFiles, run.job.TotalBytes
+		},
+	}
+	err := runStep(run, func(run *stepRun) error {
+		// Network calls of the import are bounded by the step's share of the time budget
+		if httpClient != nil {
+			httpClient.SetEventSink(jobEventSink(run.job, logger))
+			httpClient.SetJobID(run.job.JobID)
+			httpClient.SetWaitSink(jobWaitSink(run.job, logger))
+			httpClient.SetContext(run.ctx)
+		}
+
+		updatedJob, err := executeImportStep(run.job, logger, httpClient, progress)
+		run.job = updatedJob
+		return err
+	})
+	return run.job, err
+}
 
+// executeImportStep runs the import itself; ExecuteImportStep wraps it with the step middleware
+func executeImportStep(job *models.PipelineJob, logger *lib.Logger, httpClient *services.HTTPClient, progress lib.ProgressReporter) (*models.PipelineJob, error) {
 	currentStep := models.StepName(job.CurrentStep)
-	lib.LogStepStart(logger, string(currentStep), job.JobID)
 
 	// Get import output directory
 	importDir := services.GetJobOutputDir(job.Config.JobsDir, job.JobID, currentStep)
 
-	// Validate input source
+	// Sources redacted by the job_metadata policy cannot be imported again after a reload
+	if err := checkSourcesNotRedacted(job); err != nil {
+		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+		return &updatedJob, err
+	}
+
+	// Validate all input sources before importing anything
 	if err := services.ValidateImportSource(job.InputSource, job.InputType); err != nil {
 		// Failed with non-transient error
 		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
-		lib.LogStepFailed(logger, string(currentStep), job.JobID, err, false)
 		return &updatedJob, err
 	}
+	for _, extra := range job.ExtraSources {
+		if err := services.ValidateImportSource(extra.Source, extra.Type); err != nil {
+			updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+			return &updatedJob, err
+		}
+	}
+
+	// Import the primary source, then any additional sources into the same directory
+	var torchResults []torchResult
+	importedFiles, err := importFromSource(job, importDir, &torchResults, httpClient, logger, progress)
+	failedType := job.InputType
+	if err == nil {
+		claimed := make(map[string]bool, len(importedFiles))
+		for _, file := range importedFiles {
+			claimed[file.FileName] = true
+		}
+
+		for i, extra := range job.ExtraSources {
+			var extraFiles []models.FHIRDataFile
+			extraFiles, err = importExtraSource(job, extra, i+2, importDir, claimed, &torchResults, httpClient, logger, progress)
+			if err != nil {
+				failedType = extra.Type
+				break
+			}
+			importedFiles = append(importedFiles, extraFiles...)
+		}
+	}
+
+	// Handle errors
+	if err != nil {
+		// Classify error type
+		errorType := classifyImportError(err, failedType)
+		updatedJob := failImportStep(job, err, errorType, 0)
+		return &updatedJob, err
+	}
+
+	// Normalize encoding so downstream JSON parsing never sees BOMs, UTF-16 or CRLF
+	if err := normalizeImportedFiles(importDir, importedFiles, logger); err != nil {
+		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+		return &updatedJob, err
+	}
+
+	// TORCH and other exports do not always name files after their resource type
+	detectResourceTypes(importDir, importedFiles, job.Config.Import, logger)
+
+	// Drop resources outside each patient's consent while the IDs still match the consent windows
+	jobDir := services.GetJobDir(job.Config.JobsDir, job.JobID)
+	if _, err := applyConsentFilter(importDir, jobDir, importedFiles, job.Config.Consent, job.Config.Limits.GetMaxLineBytes(), logger); err != nil {
+		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+		return &updatedJob, err
+	}
+
+	// Keep, strip or externalize Binary and Attachment data before anything leaves the job directory
+	if _, err := applyAttachmentPolicy(importDir, jobDir, importedFiles, job.Config.Attachments, job.Config.Limits.GetMaxLineBytes(), logger); err != nil {
+		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+		return &updatedJob, err
+	}
+
+	// Scrub free-text narratives locally; DIMP does not reliably catch identifiers in XHTML
+	if _, err := applyNarrativePolicy(importDir, importedFiles, job.Config.Narrative, job.Config.Limits.GetMaxLineBytes(), logger); err != nil {
+		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+		return &updatedJob, err
+	}
+
+	// Drop vendor-specific extensions so flattened output is not cluttered with them
+	if _, err := applyExtensionFilter(importDir, importedFiles, job.Config.Extensions, job.Config.Limits.GetMaxLineBytes(), logger); err != nil {
+		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+		return &updatedJob, err
+	}
+
+	// Warnings escalated by pipeline.strict and output that violates pipeline.post_conditions
+	// fail the step before TORCH results are deleted
+	if err := checkStrictWarnings(job, currentStep); err != nil {
+		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+		return &updatedJob, err
+	}
+	if err := checkStepPostCondition(job, currentStep, "", importDir, logger); err != nil {
+		updatedJob := failImportStep(job, err, models.ErrorTypeNonTransient, 0)
+		return &updatedJob, err
+	}
+
+	// Calculate total bytes imported
+	var totalBytes int64
+	for _, file := range importedFiles {
+		totalBytes += file.FileSize
+	}
+
+	// Update job with imported file metrics and inventory
+	updatedJob := models.UpdateJobMetrics(*job, len(importedFiles), totalBytes)
+	updatedJob.ImportedFiles = importedFiles
+
+	// Complete the import step
+	importStep, _ := models.GetStepByName(updatedJob, currentStep)
+	completedStep := models.CompleteStep(importStep, len(importedFiles), totalBytes)
+	updatedJob = models.ReplaceStep(updatedJob, completedStep)
+
+	cleanupTORCHResults(&updatedJob, torchResults, httpClient, logger)
+
+	return &updatedJob, nil
+}
 
-	// Execute import based on input type
+// checkSourcesNotRedacted fails if an input source was only persisted as hash or placeholder
+func checkSourcesNotRedacted(job *models.PipelineJob) error {
+	sources := []string{job.InputSource}
+	for _, extra := range job.ExtraSources {
+		sources = append(sources, extra.Source)
+	}
+	for _, source := range sources {
+		if models.IsRedactedJobMetadata(source) {
+			return fmt.Errorf("input source was not persisted (job_metadata.mode: %s); start a new job to import it again", job.Config.JobMetadata.Mode)
+		}
+	}
+	return nil
+}
+
+// importFromSource imports job.InputSource into importDir based on job.InputType
+// Every returned file records the source it came from
+// TORCH results are appended to torchResults for cleanup once the import step has completed
+func importFromSource(job *models.PipelineJob, importDir string, torchResults *[]torchResult, httpClient *services.HTTPClient, logger *lib.Logger, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
 	var importedFiles []models.FHIRDataFile
 	var err error
 
 	switch job.InputType {
 	case models.InputTypeLocal:
 		logger.Info("Importing from local directory", "source", job.InputSource)
-		importedFiles, err = services.ImportFromLocalDirectory(job.InputSource, importDir, logger)
+		importedFiles, err = executeLocalImport(job, importDir, logger)
 
 	case models.InputTypeHTTP:
 		logger.Info("Downloading from URL", "source", job.InputSource)
-		if showProgress {
-			importedFiles, err = services.DownloadFromURLWithProgress(job.InputSource, importDir, httpClient, logger)
-		} else {
-			importedFiles, err = services.DownloadFromURL(job.InputSource, importDir, httpClient, logger, false)
-		}
+		importedFiles, err = services.DownloadFromURL(job.InputSource, importDir, httpClient, logger, progress)
+
+	case models.InputTypeFHIRSearch:
+		logger.Info("Importing FHIR search results", "source", job.InputSource)
+		importedFiles, err = services.ImportFromFHIRSearch(job.InputSource, importDir, httpClient, logger)
+
+	case models.InputTypeBulkExport:
+		logger.Info("Importing FHIR bulk export", "source", job.InputSource)
+		importedFiles, err = executeFHIRExport(job, importDir, httpClient, logger, progress)
+
+	case models.InputTypeS3:
+		logger.Info("Importing from object storage", "source", job.InputSource)
+		importedFiles, err = services.ImportFromS3(job.InputSource, importDir, job.Config, httpClient, logger, progress)
 
 	case models.InputTypeCRTDL:
 		logger.Info("Extracting data from TORCH using CRTDL", "source", job.InputSource)
-		importedFiles, err = executeTORCHExtraction(job, importDir, httpClient, logger, showProgress)
+		importedFiles, err = executeTORCHExtraction(job, importDir, torchResults, httpClient, logger, progress)
 
 	case models.InputTypeTORCHURL:
 		logger.Info("Downloading from TORCH result URL", "source", job.InputSource)
-		importedFiles, err = executeTORCHDownload(job, importDir, httpClient, logger, showProgress)
+		importedFiles, err = executeTORCHDownload(job, importDir, torchResults, httpClient, logger, progress)
 
 	default:
 		err = fmt.Errorf("unsupported input type: %s", job.InputType)
 	}
 
-	// Handle errors
 	if err != nil {
-		// Classify error type
-		errorType := classifyImportError(err, job.InputType)
-		updatedJob := failImportStep(job, err, errorType, 0)
-		lib.LogStepFailed(logger, string(currentStep), job.JobID, err, errorType == models.ErrorTypeTransient)
-		return &updatedJob, err
+		return nil, err
 	}
 
-	// Calculate total bytes imported
-	var totalBytes int64
-	for _, file := range importedFiles {
-		totalBytes += file.FileSize
+	for i := range importedFiles {
+		importedFiles[i].Source = job.InputSource
+		importedFiles[i].SourceStep = models.StepName(job.CurrentStep)
 	}
+	return importedFiles, nil
+}
 
-	// Update job with imported file metrics
-	updatedJob := models.UpdateJobMetrics(*job, len(importedFiles), totalBytes)
+// importExtraSource imports an additional source via a staging directory and moves its files
+// into importDir. Names already claimed by earlier sources get a ".src<N>" suffix
+// (Patient.ndjson -> Patient.src2.ndjson), so re-running the import is deterministic
+func importExtraSource(job *models.PipelineJob, extra models.InputSource, sourceNumber int, importDir string, claimed map[string]bool, torchResults *[]torchResult, httpClient *services.HTTPClient, logger *lib.Logger, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
+	stagingDir := filepath.Join(importDir, fmt.Sprintf(".source-%d", sourceNumber))
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
 
-	// Complete the import step
-	importStep, _ := models.GetStepByName(updatedJob, currentStep)
-	completedStep := models.CompleteStep(importStep, len(importedFiles), totalBytes)
-	updatedJob = models.ReplaceStep(updatedJob, completedStep)
+	sourceJob := *job
+	sourceJob.InputSource = extra.Source
+	sourceJob.InputType = extra.Type
 
-	duration := time.Since(startTime)
-	lib.LogStepComplete(logger, string(currentStep), job.JobID, len(importedFiles), duration)
+	files, err := importFromSource(&sourceJob, stagingDir, torchResults, httpClient, logger, progress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import %s: %w", extra.Source, err)
+	}
 
-	return &updatedJob, nil
+	for i := range files {
+		name := files[i].FileName
+		if claimed[name] {
+			name = fmt.Sprintf("%s.src%d.ndjson", strings.TrimSuffix(name, ".ndjson"), sourceNumber)
+		}
+		if err := os.Rename(filepath.Join(stagingDir, files[i].FileName), filepath.Join(importDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to move %s into import directory: %w", files[i].FileName, err)
+		}
+		claimed[name] = true
+		files[i].FileName = name
+		files[i].FilePath = name
+	}
+
+	logger.Info("Imported additional source", "source", extra.Source, "files", len(files))
+	return files, nil
+}
+
+// normalizeImportedFiles rewrites imported files as BOM-less UTF-8 with LF line endings
+// File sizes and resource counts are refreshed for files that were rewritten
+func normalizeImportedFiles(importDir string, files []models.FHIRDataFile, logger *lib.Logger) error {
+	for i := range files {
+		path := filepath.Join(importDir, files[i].FileName)
+
+		result, err := lib.NormalizeNDJSONEncoding(path)
+		if err != nil {
+			return fmt.Errorf("failed to normalize encoding of %s: %w", files[i].FileName, err)
+		}
+		if !result.Changed {
+			continue
+		}
+
+		logger.Info("Normalized file encoding",
+			"file", files[i].FileName,
+			"encoding", result.Encoding,
+			"bom_stripped", result.HadBOM,
+			"line_endings_fixed", result.FixedNewline)
+
+		files[i].FileSize = result.NewSize
+		if lineCount, err := lib.CountResourcesInFile(path); err == nil {
+			files[i].LineCount = lineCount
+		}
+	}
+	return nil
+}
+
+// detectResourceTypes sets the resource type of imported files from a sample of their content
+// (import.resource_type_detection: content). A file with several types becomes "mixed" with the
+// types listed; files without readable resources keep the type derived from their name
+func detectResourceTypes(importDir string, files []models.FHIRDataFile, config models.ImportConfig, logger *lib.Logger) {
+	if config.ResourceTypeDetection == models.ResourceTypeFromFilename {
+		return
+	}
+	for i := range files {
+		types, err := lib.SampleResourceTypes(filepath.Join(importDir, files[i].FileName), config.GetResourceTypeSampleLines())
+		if err != nil || len(types) == 0 {
+			logger.Debug("Resource type not detected from content, using file name",
+				"file", files[i].FileName, "resource_type", files[i].ResourceType, "error", err)
+			continue
+		}
+
+		detected := types[0]
+		files[i].ResourceTypes = nil
+		if len(types) > 1 {
+			detected = models.ResourceTypeMixed
+			files[i].ResourceTypes = types
+		}
+		if detected != files[i].ResourceType {
+			logger.Debug("Resource type detected from content",
+				"file", files[i].FileName, "resource_type", detected, "from_name", files[i].ResourceType)
+		}
+		files[i].ResourceType = detected
+	}
 }
 
 // failImportStep marks the import step as failed
@@ -97,74 +336,274 @@ func failImportStep(job *models.PipelineJob, err error, errorType models.ErrorTy
 	}
 
 	failedStep := models.FailStep(importStep, errorType, err.Error(), httpStatus)
+	failedStep.LastError.RequestID = services.RequestIDOf(err)
 	updatedJob := models.ReplaceStep(*job, failedStep)
 	updatedJob = models.AddError(updatedJob, err.Error())
 
 	return updatedJob
 }
 
+// executeLocalImport copies NDJSON files from a local directory after enforcing the file count limit
+// Files excluded by import.include/import.exclude are neither counted nor copied; they and other
+// files left out are recorded as step warnings
+func executeLocalImport(job *models.PipelineJob, importDir string, logger *lib.Logger) ([]models.FHIRDataFile, error) {
+	scan, err := services.ScanLocalSource(job.InputSource, job.Config.Import)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan source directory: %w", err)
+	}
+	if err := job.Config.Limits.CheckFileCount(len(scan.Files)); err != nil {
+		return nil, err
+	}
+
+	stepName := models.StepName(job.CurrentStep)
+	if len(scan.Excluded) > 0 {
+		recordStepWarning(job, logger, stepName, models.WarningFilesExcluded, "", len(scan.Excluded),
+			fmt.Sprintf("%d files excluded by import.include/import.exclude: %s", len(scan.Excluded), listFileNames(scan.Excluded)))
+	}
+	if len(scan.Ignored) > 0 {
+		recordStepWarning(job, logger, stepName, models.WarningFilesIgnored, "", len(scan.Ignored),
+			fmt.Sprintf("%d files ignored because they are not NDJSON: %s", len(scan.Ignored), listFileNames(scan.Ignored)))
+	}
+
+	return services.ImportFromLocalSource(job.InputSource, importDir, job.Config.Import, logger)
+}
+
 // executeTORCHExtraction performs CRTDL-based data extraction from TORCH server
 // Submits CRTDL, polls for completion, and downloads resulting NDJSON files
-func executeTORCHExtraction(job *models.PipelineJob, importDir string, httpClient *services.HTTPClient, logger *lib.Logger, showProgress bool) ([]models.FHIRDataFile, error) {
+// With services.torch.split_period, the extraction is split into one per sub-period
+func executeTORCHExtraction(job *models.PipelineJob, importDir string, torchResults *[]torchResult, httpClient *services.HTTPClient, logger *lib.Logger, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
+	// Refuse empty or unexpectedly large cohorts before TORCH spends hours on them
+	feasibilityClient := services.NewTORCHClient(job.Config.Services.TORCH, httpClient, logger)
+	if err := checkCohortSize(job, feasibilityClient, logger); err != nil {
+		return nil, err
+	}
+
+	if period := job.Config.Services.TORCH.SplitPeriod; period != "" {
+		data, err := os.ReadFile(job.InputSource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CRTDL file: %w", err)
+		}
+		chunks, err := services.SplitCRTDL(data, period)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split CRTDL by %s: %w", period, err)
+		}
+		if chunks != nil {
+			return executeSplitTORCHExtraction(job, chunks, importDir, torchResults, httpClient, logger)
+		}
+		logger.Info("CRTDL date window fits into one period, extracting without split", "split_period", period)
+	}
+	return runTORCHExtraction(job, job.InputSource, "", importDir, torchResults, httpClient, logger, progress)
+}
+
+// runTORCHExtraction extracts one CRTDL into destDir. label names the sub-period of a split
+// extraction; only unsplit extractions ("") store their URL in the job for resumption,
+// since sub-period extractions run concurrently
+func runTORCHExtraction(job *models.PipelineJob, crtdlPath string, label string, destDir string, torchResults *[]torchResult, httpClient *services.HTTPClient, logger *lib.Logger, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
 	// Create TORCH client
 	torchClient := services.NewTORCHClient(job.Config.Services.TORCH, httpClient, logger)
+	torchClient.SetEventSink(jobEventSink(job, logger))
+	torchClient.SetResponseCache(services.NewTORCHResponseCache(job.Config.JobsDir, job.Config.Services.TORCH))
+
+	// Reuse the result of an earlier extraction of the same CRTDL if TORCH still serves it
+	crtdlHash := ""
+	if job.Config.Services.TORCH.GetResultCacheTTL() > 0 {
+		hash, err := services.HashCRTDL(crtdlPath)
+		if err != nil {
+			return nil, err
+		}
+		crtdlHash = hash
+		if entry, files, ok := reuseCachedExtraction(job, destDir, torchClient, crtdlHash, logger, progress); ok {
+			age := time.Since(entry.CreatedAt).Round(time.Second)
+			fields := map[string]any{"extraction_job": entry.JobID, "files": len(entry.FileURLs), "age_seconds": int(age.Seconds())}
+			if label == "" {
+				job.TORCHExtractionURL = entry.StatusURL
+				fields["url"] = entry.StatusURL // Redacted per job_metadata like the job's own URL
+			} else {
+				fields["period"] = label
+			}
+			recordJobEvent(job, logger, models.EventTORCHCacheHit, job.CurrentStep,
+				fmt.Sprintf("reused TORCH extraction of job %s from %s ago", entry.JobID, age), fields)
+			return files, nil
+		}
+	}
+
+	// Queue behind other jobs' extractions if the server's extraction limit is reached
+	// The slot is held until the files are downloaded
+	slot, err := torchClient.AcquireExtractionSlot(job.Config.BaseJobsDir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for a TORCH extraction slot: %w", err)
+	}
+	defer func() {
+		if err := slot.Release(); err != nil {
+			logger.Warn("Failed to release TORCH extraction slot", "error", err)
+		}
+	}()
 
 	// Submit extraction
-	extractionURL, err := torchClient.SubmitExtraction(job.InputSource)
+	extractionURL, err := torchClient.SubmitExtraction(crtdlPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to submit TORCH extraction: %w", err)
 	}
 
 	// Store extraction URL in job for resumption capability
-	job.TORCHExtractionURL = extractionURL
-	logger.Info("TORCH extraction URL stored for resumption", "url", extractionURL)
+	if label == "" {
+		job.TORCHExtractionURL = extractionURL
+		logger.Info("TORCH extraction URL stored for resumption", "url", extractionURL)
+	}
+
+	// Let the runtime watchdog cancel the extraction if the run is aborted
+	defer trackExtraction(job.JobID, extractionURL)()
 
 	// Poll extraction status until complete
-	fileURLs, err := torchClient.PollExtractionStatus(extractionURL, showProgress)
+	fileURLs, err := torchClient.PollExtractionStatus(extractionURL, progress)
 	if err != nil {
 		return nil, fmt.Errorf("TORCH extraction failed: %w", err)
 	}
 
+	if crtdlHash != "" {
+		entry := services.TORCHCacheEntry{
+			CRTDLHash: crtdlHash,
+			StatusURL: extractionURL,
+			FileURLs:  fileURLs,
+			JobID:     job.JobID,
+			CreatedAt: lib.Now(),
+		}
+		if err := services.SaveTORCHCacheEntry(job.Config.BaseJobsDir(), job.Config.Services.TORCH.BaseURL, entry); err != nil {
+			logger.Warn("Failed to cache TORCH extraction result", "error", err)
+		}
+	}
+
 	if len(fileURLs) == 0 {
-		logger.Warn("TORCH extraction returned no files (empty cohort)")
+		// Sub-period extractions run concurrently; their empty results are recorded once merged
+		if label == "" {
+			recordStepWarning(job, logger, models.StepName(job.CurrentStep), models.WarningEmptyResult, "", 0,
+				"TORCH extraction returned no files (empty cohort)")
+		} else {
+			logger.Warn("TORCH extraction returned no files (empty cohort)", "period", label)
+		}
 		return []models.FHIRDataFile{}, nil
 	}
 
+	if err := job.Config.Limits.CheckFileCount(len(fileURLs)); err != nil {
+		return nil, err
+	}
+
 	// Download extraction files
-	files, err := torchClient.DownloadExtractionFiles(fileURLs, importDir, showProgress)
+	files, err := torchClient.DownloadExtractionFiles(fileURLs, destDir, progress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download TORCH files: %w", err)
 	}
+	*torchResults = append(*torchResults, torchResult{statusURL: extractionURL, fileURLs: fileURLs})
 
 	return files, nil
 }
 
+// reuseCachedExtraction downloads the cached extraction result of a CRTDL into destDir
+// Returns false if there is no cached result, or it is gone or fails to download; the
+// caller then runs a new extraction
+func reuseCachedExtraction(job *models.PipelineJob, destDir string, torchClient *services.TORCHClient, crtdlHash string, logger *lib.Logger, progress lib.ProgressReporter) (*services.TORCHCacheEntry, []models.FHIRDataFile, bool) {
+	config := job.Config.Services.TORCH
+	entry, found := services.LoadTORCHCacheEntry(job.Config.BaseJobsDir(), config.BaseURL, crtdlHash, config.GetResultCacheTTL(), time.Now())
+	if !found {
+		return nil, nil, false
+	}
+
+	forget := func(reason string, err error) {
+		logger.Info("Not reusing cached TORCH extraction: "+reason, "error", err, "extraction_job", entry.JobID)
+		if err := services.RemoveTORCHCacheEntry(job.Config.BaseJobsDir(), config.BaseURL, crtdlHash); err != nil {
+			logger.Warn("Failed to remove TORCH cache entry", "error", err)
+		}
+	}
+	if err := torchClient.CheckExtractionResult(*entry); err != nil {
+		forget("result unavailable", err)
+		return nil, nil, false
+	}
+	if err := job.Config.Limits.CheckFileCount(len(entry.FileURLs)); err != nil {
+		forget("file limit exceeded", err)
+		return nil, nil, false
+	}
+
+	files, err := torchClient.DownloadExtractionFiles(entry.FileURLs, destDir, progress)
+	if err != nil {
+		forget("download failed", err)
+		return nil, nil, false
+	}
+
+	logger.Info("Reused cached TORCH extraction", "extraction_job", entry.JobID, "age", time.Since(entry.CreatedAt).Round(time.Second), "files", len(files))
+	return entry, files, true
+}
+
 // executeTORCHDownload downloads files from a direct TORCH result URL
 // This bypasses extraction submission and directly downloads from an existing result
-func executeTORCHDownload(job *models.PipelineJob, importDir string, httpClient *services.HTTPClient, logger *lib.Logger, showProgress bool) ([]models.FHIRDataFile, error) {
+func executeTORCHDownload(job *models.PipelineJob, importDir string, torchResults *[]torchResult, httpClient *services.HTTPClient, logger *lib.Logger, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
 	// Create TORCH client
 	torchClient := services.NewTORCHClient(job.Config.Services.TORCH, httpClient, logger)
+	torchClient.SetEventSink(jobEventSink(job, logger))
+	torchClient.SetResponseCache(services.NewTORCHResponseCache(job.Config.JobsDir, job.Config.Services.TORCH))
 
 	// Poll the URL directly (it should return 200 immediately if extraction is complete)
-	fileURLs, err := torchClient.PollExtractionStatus(job.InputSource, showProgress)
+	fileURLs, err := torchClient.PollExtractionStatus(job.InputSource, progress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get TORCH result: %w", err)
 	}
 
 	if len(fileURLs) == 0 {
-		logger.Warn("TORCH result URL returned no files")
+		recordStepWarning(job, logger, models.StepName(job.CurrentStep), models.WarningEmptyResult, "", 0,
+			"TORCH result URL returned no files")
 		return []models.FHIRDataFile{}, nil
 	}
 
+	if err := job.Config.Limits.CheckFileCount(len(fileURLs)); err != nil {
+		return nil, err
+	}
+
 	// Download files
-	files, err := torchClient.DownloadExtractionFiles(fileURLs, importDir, showProgress)
+	files, err := torchClient.DownloadExtractionFiles(fileURLs, importDir, progress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download TORCH files: %w", err)
 	}
+	*torchResults = append(*torchResults, torchResult{statusURL: job.InputSource, fileURLs: fileURLs})
 
 	return files, nil
 }
 
+// torchResult is a TORCH extraction result downloaded by the import step
+type torchResult struct {
+	statusURL string // Content-Location URL of the extraction
+	fileURLs  []string
+}
+
+// cleanupTORCHResults deletes the downloaded extraction results on the TORCH server if
+// services.torch.cleanup_after_download is set. It runs after the import step completed, so a
+// failed import can still download them again; cleanup failures are logged, not step failures
+func cleanupTORCHResults(job *models.PipelineJob, results []torchResult, httpClient *services.HTTPClient, logger *lib.Logger) {
+	if !job.Config.Services.TORCH.CleanupAfterDownload || len(results) == 0 {
+		return
+	}
+
+	torchClient := services.NewTORCHClient(job.Config.Services.TORCH, httpClient, logger)
+	torchClient.SetResponseCache(services.NewTORCHResponseCache(job.Config.JobsDir, job.Config.Services.TORCH))
+	for _, result := range results {
+		err := torchClient.DeleteExtractionResult(result.statusURL, result.fileURLs)
+		fields := map[string]any{"url": result.statusURL, "files": len(result.fileURLs), "deleted": err == nil}
+		message := "deleted TORCH extraction result"
+		switch {
+		case errors.Is(err, services.ErrTORCHCleanupUnsupported):
+			logger.Warn("TORCH server does not support deleting extraction results, disable services.torch.cleanup_after_download",
+				"url", result.statusURL)
+		case err != nil:
+			logger.Warn("Failed to clean up TORCH extraction result", "url", result.statusURL, "error", err)
+		default:
+			logger.Info("Deleted TORCH extraction result", "url", result.statusURL, "files", len(result.fileURLs))
+		}
+		if err != nil {
+			message = "TORCH cleanup failed: " + err.Error()
+			fields["error"] = err.Error()
+		}
+		recordJobEvent(job, logger, models.EventTORCHCleanup, job.CurrentStep, message, fields)
+	}
+}
+
 // classifyImportError determines if an import error is transient or non-transient
 func classifyImportError(err error, inputType models.InputType) models.ErrorType {
 	if err == nil {
@@ -175,9 +614,16 @@ func classifyImportError(err error, inputType models.InputType) models.ErrorType
 	if torchErr, ok := err.(*services.TORCHError); ok {
 		return torchErr.ErrorType
 	}
+	var exportErr *services.FHIRExportError
+	if errors.As(err, &exportErr) {
+		return exportErr.ErrorType
+	}
+	if inputType == models.InputTypeS3 {
+		return classifyS3Error(err)
+	}
 
 	// For HTTP downloads and TORCH operations, network errors are transient
-	if inputType == models.InputTypeHTTP || inputType == models.InputTypeCRTDL || inputType == models.InputTypeTORCHURL {
+	if inputType == models.InputTypeHTTP || inputType == models.InputTypeFHIRSearch || inputType == models.InputTypeBulkExport || inputType == models.InputTypeCRTDL || inputType == models.InputTypeTORCHURL {
 		if lib.IsNetworkError(err) {
 			return models.ErrorTypeTransient
 		}
@@ -189,8 +635,8 @@ func classifyImportError(err error, inputType models.InputType) models.ErrorType
 }
 
 // RetryImportStep attempts to retry a failed import step
-// Should only be called if the error was transient
-func RetryImportStep(job *models.PipelineJob, logger *lib.Logger, httpClient *services.HTTPClient, showProgress bool) (*models.PipelineJob, error) {
+// Should only be called if the error was transient. Cancelling ctx ends the backoff wait
+func RetryImportStep(ctx context.Context, job *models.PipelineJob, logger *lib.Logger, httpClient *services.HTTPClient, progress lib.ProgressReporter) (*models.PipelineJob, error) {
 	// Get current import step
 	currentStep := models.StepName(job.CurrentStep)
 	importStep, found := models.GetStepByName(*job, currentStep)
@@ -215,9 +661,16 @@ func RetryImportStep(job *models.PipelineJob, logger *lib.Logger, httpClient *se
 
 	// Calculate backoff
 	backoff := lib.CalculateBackoff(retriedStep.RetryCount-1, job.Config.Retry.InitialBackoffMs, job.Config.Retry.MaxBackoffMs)
+	recordJobEvent(job, logger, models.EventRetryScheduled, string(currentStep),
+		fmt.Sprintf("retry %d/%d of step in %s", retriedStep.RetryCount, job.Config.Retry.MaxAttempts, backoff),
+		map[string]any{"attempt": retriedStep.RetryCount, "backoff_ms": backoff.Milliseconds(), "error": importStep.LastError.Message})
 	logger.Info("Waiting before retry", "backoff", backoff)
-	time.Sleep(backoff)
+	select {
+	case <-time.After(backoff):
+	case <-ctx.Done():
+		return job, fmt.Errorf("%w: %v", ErrStepCancelled, context.Cause(ctx))
+	}
 
 	// Retry the import
-	return ExecuteImportStep(&updatedJob, logger, httpClient, showProgress)
+	return ExecuteImportStep(ctx, &updatedJob, logger, httpClient, progress)
 }
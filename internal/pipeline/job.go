@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"fmt"
+	"maps"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +14,68 @@ import (
 // CreateJob initializes a new pipeline job
 // Returns the created job with generated UUID and initialized steps
 func CreateJob(inputSource string, config models.ProjectConfig, logger *lib.Logger) (*models.PipelineJob, error) {
+	return CreateMultiSourceJob([]string{inputSource}, config, logger)
+}
+
+// CreateMultiSourceJob initializes a new pipeline job that imports several sources into one job
+// The first source determines the import step; the others (local directories, HTTP URLs,
+// TORCH result URLs) are imported into the same import directory during that step
+func CreateMultiSourceJob(inputSources []string, config models.ProjectConfig, logger *lib.Logger) (*models.PipelineJob, error) {
+	return createJob(inputSources, nil, nil, config, logger)
+}
+
+// CreateDerivedJob initializes a new pipeline job from the output of other jobs
+// inputSources must include the parents' output directories (see ParentJobSources);
+// the parent job IDs are recorded in the job state for 'aether lineage'
+func CreateDerivedJob(inputSources []string, parentJobs []string, config models.ProjectConfig, logger *lib.Logger) (*models.PipelineJob, error) {
+	return createJob(inputSources, parentJobs, nil, config, logger)
+}
+
+// CreateTemplateJob initializes a new pipeline job from a job template
+// config must already have the template applied (see JobTemplate.Apply). The first input
+// must be of the template's input type; the template and its labels are stored with the job
+func CreateTemplateJob(inputSources []string, template *models.JobTemplate, config models.ProjectConfig, logger *lib.Logger) (*models.PipelineJob, error) {
+	return createJob(inputSources, nil, template, config, logger)
+}
+
+// ParentJobSources returns the FHIR output directories of completed parent jobs, to be
+// imported by a job created from them. Duplicate IDs are dropped
+func ParentJobSources(jobsDir string, parentJobs []string) (sources []string, parents []string, err error) {
+	seen := map[string]bool{}
+	for _, parentID := range parentJobs {
+		if seen[parentID] {
+			continue
+		}
+		seen[parentID] = true
+
+		parent, err := services.LoadJobState(jobsDir, parentID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parent job %s: %w", parentID, err)
+		}
+		if parent.Status != models.JobStatusCompleted {
+			return nil, nil, fmt.Errorf("parent job %s is %s; only completed jobs can be used as input", parentID, parent.Status)
+		}
+		outputDir, ok := services.JobFHIROutputDir(jobsDir, parent)
+		if !ok {
+			return nil, nil, fmt.Errorf("parent job %s has no FHIR output (no completed import, dimp or imaging step)", parentID)
+		}
+		sources = append(sources, outputDir)
+		parents = append(parents, parentID)
+	}
+	return sources, parents, nil
+}
+
+func createJob(inputSources []string, parentJobs []string, template *models.JobTemplate, config models.ProjectConfig, logger *lib.Logger) (*models.PipelineJob, error) {
+	if len(inputSources) == 0 {
+		return nil, fmt.Errorf("at least one input source is required")
+	}
+	inputSource := inputSources[0]
+
+	// Enforce the quota of the (tenant's) jobs directory
+	if err := services.CheckJobQuota(config.JobsDir, config.Quota); err != nil {
+		return nil, err
+	}
+
 	// Generate unique job ID
 	jobID := uuid.New().String()
 
@@ -23,6 +86,9 @@ func CreateJob(inputSource string, config models.ProjectConfig, logger *lib.Logg
 	}
 
 	logger.Info("Detected input type", "type", inputType, "source", inputSource)
+	if template != nil && template.InputType != "" && inputType != template.InputType {
+		return nil, fmt.Errorf("input %s is %s, but template %s expects %s", inputSource, inputType, template.Name, template.InputType)
+	}
 
 	// Validate CRTDL syntax if input is CRTDL file
 	if inputType == models.InputTypeCRTDL {
@@ -32,27 +98,42 @@ func CreateJob(inputSource string, config models.ProjectConfig, logger *lib.Logg
 		logger.Info("CRTDL syntax validation passed")
 	}
 
+	// Name the job after the study of its CRTDL; a CRTDL without display or version is fine
+	var annotations *models.JobAnnotations
+	if inputType == models.InputTypeCRTDL {
+		if annotations, err = lib.ReadCRTDLAnnotations(inputSource); err != nil {
+			logger.Warn("Failed to read CRTDL annotations", "error", err)
+		}
+	}
+
 	// Determine initial step based on input type
-	var initialStep models.StepName
-	switch inputType {
-	case models.InputTypeCRTDL, models.InputTypeTORCHURL:
-		initialStep = models.StepTorchImport
-	case models.InputTypeLocal:
-		initialStep = models.StepLocalImport
-	case models.InputTypeHTTP:
-		initialStep = models.StepHttpImport
-	default:
+	initialStep := models.ImportStepForInputType(inputType)
+	if initialStep == "" {
 		return nil, fmt.Errorf("unknown input type: %s", inputType)
 	}
 
+	// Detect types of additional sources
+	var extraSources []models.InputSource
+	for _, source := range inputSources[1:] {
+		sourceType, err := lib.DetectInputType(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect input type of %s: %w", source, err)
+		}
+		if !models.IsValidExtraSourceType(sourceType) {
+			return nil, fmt.Errorf("%s (%s) can only be used as the first input source", source, sourceType)
+		}
+		logger.Info("Detected additional input source", "type", sourceType, "source", source)
+		extraSources = append(extraSources, models.InputSource{Source: source, Type: sourceType})
+	}
+
 	// Initialize steps from config
 	steps := models.InitializeSteps(config.Pipeline.EnabledSteps)
 
 	// Create job
 	job := &models.PipelineJob{
 		JobID:              jobID,
-		CreatedAt:          time.Now(),
-		UpdatedAt:          time.Now(),
+		CreatedAt:          lib.Now(),
+		UpdatedAt:          lib.Now(),
 		InputSource:        inputSource,
 		InputType:          inputType,
 		TORCHExtractionURL: "",                  // Will be set during TORCH extraction if applicable
@@ -63,6 +144,13 @@ func CreateJob(inputSource string, config models.ProjectConfig, logger *lib.Logg
 		TotalFiles:         0,
 		TotalBytes:         0,
 		ErrorMessage:       "",
+		ExtraSources:       extraSources,
+		ParentJobs:         parentJobs,
+		Annotations:        annotations,
+	}
+	if template != nil {
+		job.Labels = maps.Clone(template.Labels)
+		job.Template = template
 	}
 
 	// Validate the job
@@ -80,6 +168,18 @@ func CreateJob(inputSource string, config models.ProjectConfig, logger *lib.Logg
 		return nil, fmt.Errorf("failed to save initial job state: %w", err)
 	}
 
+	fields := map[string]any{"input_source": inputSource, "input_type": string(inputType)}
+	if len(parentJobs) > 0 {
+		fields["parent_jobs"] = parentJobs
+	}
+	if study := annotations.Label(); study != "" {
+		fields["study"] = study
+	}
+	if template != nil {
+		fields["template"] = template.Name
+	}
+	recordJobEvent(job, logger, models.EventJobCreated, "", "job created", fields)
+
 	return job, nil
 }
 
@@ -91,7 +191,7 @@ func LoadJob(jobsDir string, jobID string) (*models.PipelineJob, error) {
 // UpdateJob updates job state on disk
 // Uses pure functions to create new job instance before saving
 func UpdateJob(jobsDir string, job *models.PipelineJob) error {
-	job.UpdatedAt = time.Now()
+	job.UpdatedAt = lib.Now()
 	return services.SaveJobState(jobsDir, job)
 }
 
@@ -114,12 +214,14 @@ func StartJob(job *models.PipelineJob) *models.PipelineJob {
 func CompleteJob(job *models.PipelineJob) *models.PipelineJob {
 	updatedJob := models.UpdateJobStatus(*job, models.JobStatusCompleted)
 	updatedJob.CurrentStep = "" // No current step when complete
+	recordJobEvent(&updatedJob, nil, models.EventJobCompleted, "", "job completed", nil)
 	return &updatedJob
 }
 
 // FailJob marks job as failed with error message
 func FailJob(job *models.PipelineJob, errorMsg string) *models.PipelineJob {
 	updatedJob := models.AddError(*job, errorMsg)
+	recordJobEvent(&updatedJob, nil, models.EventJobFailed, job.CurrentStep, errorMsg, nil)
 	return &updatedJob
 }
 
@@ -184,10 +286,19 @@ func GetJobSummary(job *models.PipelineJob) string {
 	duration := time.Since(job.CreatedAt)
 
 	summary := fmt.Sprintf("Job %s\n", job.JobID)
+	if study := job.Annotations.Label(); study != "" {
+		summary += fmt.Sprintf("Study: %s\n", study)
+	}
 	summary += fmt.Sprintf("Status: %s\n", job.Status)
 	summary += fmt.Sprintf("Current Step: %s\n", job.CurrentStep)
 	summary += fmt.Sprintf("Files: %d\n", job.TotalFiles)
+	if job.CohortSize != nil {
+		summary += fmt.Sprintf("Cohort Size: %d patients\n", *job.CohortSize)
+	}
 	summary += fmt.Sprintf("Duration: %v\n", duration.Round(time.Second))
+	if warnings := job.WarningCount(); warnings > 0 {
+		summary += fmt.Sprintf("Warnings: %d\n", warnings)
+	}
 
 	if job.ErrorMessage != "" {
 		summary += fmt.Sprintf("Error: %s\n", job.ErrorMessage)
@@ -0,0 +1,86 @@
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// SanitizedNarrativeDiv replaces narratives in sanitize mode
+const SanitizedNarrativeDiv = `<div xmlns="http://www.w3.org/1999/xhtml">Narrative removed</div>`
+
+// applyNarrativePolicy removes or sanitizes resource.text in imported files
+// Applies to top-level resources, contained resources and Bundle entries alike.
+// Returns the number of narratives scrubbed; file sizes are refreshed for rewritten files
+func applyNarrativePolicy(importDir string, files []models.FHIRDataFile, policy models.NarrativeConfig, maxLineBytes int, logger *lib.Logger) (int, error) {
+	if !policy.IsActive() {
+		return 0, nil
+	}
+
+	total := 0
+	for i := range files {
+		path := filepath.Join(importDir, files[i].FileName)
+		scrubbed := 0
+		changed, err := rewriteResourcesInPlace(path, maxLineBytes, `"div"`, func(resource map[string]any) (bool, error) {
+			count := scrubNarratives(resource, policy.Mode)
+			scrubbed += count
+			return count > 0, nil
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to apply narrative policy to %s: %w", files[i].FileName, err)
+		}
+		total += scrubbed
+
+		if changed {
+			files[i].FileSize = lib.GetFileSize(path)
+			logger.Debug("Applied narrative policy", "file", files[i].FileName, "narratives", scrubbed)
+		}
+	}
+
+	if total > 0 {
+		logger.Info("Narrative policy applied", "mode", policy.Mode, "narratives", total)
+	}
+	return total, nil
+}
+
+// scrubNarratives walks a JSON value and removes or sanitizes the narrative of every resource in it
+// Only objects with a resourceType and an object-valued text are touched; CodeableConcept.text
+// and other string-valued text elements are left alone
+func scrubNarratives(value any, mode models.NarrativeMode) int {
+	count := 0
+
+	switch v := value.(type) {
+	case map[string]any:
+		if _, isResource := v["resourceType"].(string); isResource {
+			if _, hasNarrative := v["text"].(map[string]any); hasNarrative && !isSanitizedNarrative(v["text"]) {
+				if mode == models.NarrativeRemove {
+					delete(v, "text")
+				} else {
+					v["text"] = map[string]any{"status": "empty", "div": SanitizedNarrativeDiv}
+				}
+				count++
+			}
+		}
+		for key, child := range v {
+			if key == "text" {
+				continue
+			}
+			count += scrubNarratives(child, mode)
+		}
+
+	case []any:
+		for _, child := range v {
+			count += scrubNarratives(child, mode)
+		}
+	}
+
+	return count
+}
+
+// isSanitizedNarrative returns true for narratives already replaced by sanitize mode, so re-runs are no-ops
+func isSanitizedNarrative(text any) bool {
+	narrative, ok := text.(map[string]any)
+	return ok && narrative["status"] == "empty" && narrative["div"] == SanitizedNarrativeDiv
+}
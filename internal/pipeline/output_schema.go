@@ -0,0 +1,25 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// CheckOutputSchemas validates the tables produced by the flatten mapping against the schemas
+// registered in pipeline.packaging.expected_schema. Returns nil if no registry is configured.
+// The columns of a wide Observation table depend on the data and are not known up front; the
+// mapping's Observation columns are checked instead
+func CheckOutputSchemas(config models.ProjectConfig, httpClient *services.HTTPClient, logger *lib.Logger) error {
+	expected := config.Pipeline.Packaging.ExpectedSchema
+	if !expected.IsEnabled() {
+		return nil
+	}
+	mapping, err := services.LoadFlattenMapping(config.Flatten)
+	if err != nil {
+		return fmt.Errorf("flatten mapping: %w", err)
+	}
+	return services.ValidateTableSchemas(expected, services.ProducedTableSchemas(mapping), httpClient, logger)
+}
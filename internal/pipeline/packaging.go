@@ -0,0 +1,321 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// WideObservationTable is the table holding Observations pivoted by code (flatten.observations.format)
+const WideObservationTable = "Observation_wide"
+
+// observationPivotDir holds the Observations spilled for the wide pivot in the job directory while packaging
+const observationPivotDir = "observation_pivot"
+
+// packagingFormat is an output format of the in-process packaging steps
+type packagingFormat struct {
+	step      models.StepName
+	dir       string // Output directory in the job directory
+	label     string // Shown in progress output
+	extension string // Appended to table names in progress output and events
+	newSink   func(outputDir string, config models.ProjectConfig) services.TableSink
+	// newTableSink creates an unpartitioned sink for tables computed after the pass, such as the wide Observation table
+	newTableSink func(outputDir string, config models.ProjectConfig) services.TableSink
+	removeTable  func(outputDir, table string) error
+	// prepare clears output of earlier runs before the pass (optional)
+	prepare func(outputDir string, config models.ProjectConfig) error
+	// finish completes the written tables, given their columns (optional)
+	finish func(outputDir string, tables map[string][]string, config models.ProjectConfig) error
+}
+
+// PackagingPassFileName records the results of a shared flattening pass for the packaging steps
+// that have not run yet, in the job directory
+const PackagingPassFileName = "packaging_pass.json"
+
+// packagingResult summarizes the output a flattening pass wrote for one format
+type packagingResult struct {
+	Files     int            `json:"files"`
+	Bytes     int64          `json:"bytes"`
+	Resources int            `json:"resources"`
+	Unmapped  int            `json:"unmapped"`
+	Rows      map[string]int `json:"rows"`
+}
+
+// executePackagingStep flattens the job's FHIR resources into the tables of a format
+// Reads the output of the last completed imaging, DIMP or import step and writes one table per
+// mapped resource type, plus data_dictionary.csv, to the format's output directory.
+// The first in-process packaging step of a job writes the tables of the other pending in-process
+// formats in the same pass, so the data is read and flattened once; their steps complete from
+// packaging_pass.json. The step middleware (RunStep) wraps it
+func executePackagingStep(job *models.PipelineJob, jobDir string, format packagingFormat, logger *lib.Logger) error {
+	stepName := format.step
+
+	step := getOrCreateStep(job, stepName)
+	step.Status = models.StepStatusInProgress
+	now := lib.Now()
+	step.StartedAt = &now
+
+	fail := func(err error, errorType models.ErrorType) error {
+		recordStepError(step, err, errorType)
+		return err
+	}
+
+	passPath := filepath.Join(jobDir, PackagingPassFileName)
+	pending, err := readPackagingPass(passPath)
+	if err != nil {
+		return fail(err, models.ErrorTypeNonTransient)
+	}
+
+	result, shared := pending[stepName]
+	if shared {
+		fmt.Printf("%s tables were written by the shared flattening pass\n\n", format.label)
+		delete(pending, stepName)
+	} else {
+		formats := []packagingFormat{format}
+		for _, other := range inProcessPackagingFormats(job.Config) {
+			if other.step != stepName && packagingStepPending(job, other.step) {
+				formats = append(formats, other)
+			}
+		}
+		results, err := writePackagingOutput(job, jobDir, formats)
+		if err != nil {
+			return fail(err, models.ErrorTypeNonTransient)
+		}
+		result = results[0]
+		pending = map[models.StepName]packagingResult{}
+		for i, other := range formats[1:] {
+			pending[other.step] = results[i+1]
+		}
+	}
+	if err := writePackagingPass(passPath, pending); err != nil {
+		return fail(err, models.ErrorTypeNonTransient)
+	}
+
+	tables := make([]string, 0, len(result.Rows))
+	for table := range result.Rows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Printf("  ✓ %s%s (%d rows)\n", table, format.extension, result.Rows[table])
+		recordJobEvent(job, logger, models.EventFileProcessed, string(stepName),
+			fmt.Sprintf("table written: %s%s", table, format.extension),
+			map[string]any{"table": table, "rows": result.Rows[table]})
+	}
+	fmt.Printf("\n%s conversion: %d resources, %d tables, %d resources without mapping\n",
+		format.label, result.Resources, len(result.Rows), result.Unmapped)
+
+	if result.Unmapped > 0 {
+		logger.Info("Resources without column mapping were not converted", "resources", result.Unmapped)
+	}
+
+	if err := checkStrictWarnings(job, stepName); err != nil {
+		return fail(err, models.ErrorTypeNonTransient)
+	}
+
+	step.Status = models.StepStatusCompleted
+	step.FilesProcessed = result.Files
+	step.BytesProcessed = result.Bytes
+	completedAt := lib.Now()
+	step.CompletedAt = &completedAt
+
+	logger.Debug(format.label+" conversion step completed",
+		"files_processed", result.Files,
+		"resources", result.Resources,
+		"tables", len(result.Rows),
+		"unmapped", result.Unmapped,
+		"shared_pass", shared,
+		"duration", completedAt.Sub(*step.StartedAt),
+		"job_id", job.JobID)
+
+	return nil
+}
+
+// inProcessPackagingFormats returns the packaging formats the job writes in-process
+// CSV runs in-process without services.csv_conversion.url, Parquet with pipeline.packaging.mode local
+func inProcessPackagingFormats(config models.ProjectConfig) []packagingFormat {
+	var formats []packagingFormat
+	if isStepEnabled(config, models.StepCSVConversion) && config.Services.CSVConversion.URL == "" {
+		formats = append(formats, csvPackaging)
+	}
+	if isStepEnabled(config, models.StepParquetConversion) && config.Pipeline.Packaging.WritesParquetLocally() {
+		formats = append(formats, parquetPackaging)
+	}
+	return formats
+}
+
+// packagingStepPending reports whether a packaging step of the job has yet to complete
+func packagingStepPending(job *models.PipelineJob, stepName models.StepName) bool {
+	step, found := models.GetStepByName(*job, stepName)
+	return !found || step.Status != models.StepStatusCompleted
+}
+
+// writePackagingOutput flattens the job's FHIR resources once and writes the tables of every format
+// Returns one result per format, in order
+func writePackagingOutput(job *models.PipelineJob, jobDir string, formats []packagingFormat) ([]packagingResult, error) {
+	inputDir, ok := services.JobFHIROutputDir(job.Config.JobsDir, job)
+	if !ok {
+		return nil, fmt.Errorf("no completed import, DIMP or imaging step to convert")
+	}
+	outputDirs := make([]string, len(formats))
+	for i, format := range formats {
+		outputDirs[i] = filepath.Join(jobDir, format.dir)
+		if err := os.MkdirAll(outputDirs[i], 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(inputDir, "*.ndjson"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list input files: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no FHIR NDJSON files found in %s", filepath.Base(inputDir))
+	}
+
+	mapping, err := services.LoadFlattenMapping(job.Config.Flatten)
+	if err != nil {
+		return nil, fmt.Errorf("flatten mapping: %w", err)
+	}
+	flattener, err := services.NewFlattener(mapping)
+	if err != nil {
+		return nil, fmt.Errorf("flatten mapping: %w", err)
+	}
+
+	// Errors of the formats written along with the step's own are attributed to them
+	labeled := func(i int, err error) error {
+		if i == 0 {
+			return err
+		}
+		return fmt.Errorf("%s: %w", formats[i].label, err)
+	}
+
+	labels := make([]string, len(formats))
+	for i, format := range formats {
+		labels[i] = format.label
+		if format.prepare != nil {
+			if err := format.prepare(outputDirs[i], job.Config); err != nil {
+				return nil, labeled(i, err)
+			}
+		}
+	}
+
+	fmt.Printf("Flattening %d FHIR file(s) into %s tables...\n\n", len(files), strings.Join(labels, " and "))
+
+	pivot := job.Config.Flatten.Observations
+	var sinks []services.TableSink
+	for i, format := range formats {
+		sinks = append(sinks, format.newSink(outputDirs[i], job.Config))
+	}
+	var observations *services.ObservationPivotSink
+	if pivot.WantsWide() {
+		observations, err = services.NewObservationPivotSink(filepath.Join(jobDir, observationPivotDir), flattener.Columns("Observation"), pivot)
+		if err != nil {
+			return nil, fmt.Errorf("observation pivot: %w", err)
+		}
+		defer observations.Abort()
+		sinks = append(sinks, observations)
+	}
+
+	stats, err := services.FlattenFiles(files, flattener, sinks...)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string][]string, len(stats.Rows))
+	for table := range stats.Rows {
+		columns[table] = flattener.Columns(table)
+	}
+
+	if pivot.WantsWide() {
+		wideSinks := make([]services.TableSink, len(formats))
+		for i, format := range formats {
+			wideSinks[i] = format.newTableSink(outputDirs[i], job.Config)
+		}
+		rows, err := observations.WriteTo(WideObservationTable, wideSinks...)
+		if err != nil {
+			return nil, err
+		}
+		// Like the flattened tables, the wide table is only written if it has rows
+		if rows > 0 {
+			stats.Rows[WideObservationTable] = rows
+			columns[WideObservationTable] = observations.Columns()
+		}
+	}
+	if !pivot.WantsLong() {
+		delete(stats.Rows, "Observation")
+		delete(columns, "Observation")
+	}
+
+	var bytesProcessed int64
+	for _, file := range files {
+		bytesProcessed += lib.GetFileSize(file)
+	}
+	pseudonymized := filepath.Base(inputDir) == "pseudonymized"
+	dictionary := services.BuildDataDictionary(mapping, pseudonymized)
+
+	results := make([]packagingResult, len(formats))
+	for i, format := range formats {
+		if !pivot.WantsLong() {
+			_ = format.removeTable(outputDirs[i], "Observation")
+		}
+		if format.finish != nil {
+			if err := format.finish(outputDirs[i], columns, job.Config); err != nil {
+				return nil, labeled(i, err)
+			}
+		}
+		if err := services.WriteDataDictionary(outputDirs[i], dictionary); err != nil {
+			return nil, labeled(i, err)
+		}
+		results[i] = packagingResult{
+			Files:     len(files),
+			Bytes:     bytesProcessed,
+			Resources: stats.Resources,
+			Unmapped:  stats.Unmapped,
+			Rows:      maps.Clone(stats.Rows),
+		}
+	}
+	return results, nil
+}
+
+// readPackagingPass loads the pending results of a shared flattening pass; a missing file means none
+func readPackagingPass(path string) (map[models.StepName]packagingResult, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[models.StepName]packagingResult{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PackagingPassFileName, err)
+	}
+	pending := map[models.StepName]packagingResult{}
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", PackagingPassFileName, err)
+	}
+	return pending, nil
+}
+
+// writePackagingPass stores the results still to be picked up by packaging steps, removing the file
+// once none are left
+func writePackagingPass(path string, pending map[models.StepName]packagingResult) error {
+	if len(pending) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", PackagingPassFileName, err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", PackagingPassFileName, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", PackagingPassFileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// ExecuteParquetConversionStep flattens the job's FHIR resources into Parquet tables in-process
+// Used with pipeline.packaging.mode local. Reads the output of the last completed imaging, DIMP
+// or import step and writes one table directory per mapped resource type
+// (<ResourceType>/part-00000.parquet), plus data_dictionary.csv, to parquet/
+func ExecuteParquetConversionStep(ctx context.Context, job *models.PipelineJob, jobDir string, logger *lib.Logger) error {
+	stepName := models.StepParquetConversion
+
+	if !isStepEnabled(job.Config, stepName) {
+		logger.Info("Parquet conversion step not enabled, skipping", "job_id", job.JobID)
+		return nil
+	}
+
+	return RunStep(ctx, job, stepName, logger, func(ctx context.Context) error {
+		return executePackagingStep(job, jobDir, parquetPackaging, logger)
+	})
+}
+
+// parquetPackaging writes flattened tables as <ResourceType>/part-00000.parquet
+// With table_format delta, file names carry a run tag and every table gets a Delta commit
+var parquetPackaging = packagingFormat{
+	step:      models.StepParquetConversion,
+	dir:       "parquet",
+	label:     "Parquet",
+	extension: "/",
+	newSink: func(outputDir string, config models.ProjectConfig) services.TableSink {
+		return services.NewParquetTableSink(outputDir, config.Pipeline.Packaging.Parquet, parquetFileTag(config))
+	},
+	newTableSink: func(outputDir string, config models.ProjectConfig) services.TableSink {
+		options := config.Pipeline.Packaging.Parquet
+		options.PartitionBy = nil
+		return services.NewParquetTableSink(outputDir, options, parquetFileTag(config))
+	},
+	removeTable: func(outputDir, table string) error {
+		return os.RemoveAll(filepath.Join(outputDir, table))
+	},
+	prepare: prepareParquetOutput,
+	finish:  commitDeltaTables,
+}
+
+// parquetFileTag returns the tag of the Parquet files of a run: unique for Delta tables, which keep
+// the files of earlier versions, and empty otherwise
+func parquetFileTag(config models.ProjectConfig) string {
+	if config.Pipeline.Packaging.TableFormat != models.TableFormatDelta {
+		return ""
+	}
+	return uuid.NewString()
+}
+
+// prepareParquetOutput removes the tables of earlier runs
+// Delta tables keep their log and committed files, so re-packaging adds a new version; only files
+// no commit added (e.g. of a run that failed before committing) are removed
+func prepareParquetOutput(outputDir string, config models.ProjectConfig) error {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to read Parquet output directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		tableDir := filepath.Join(outputDir, entry.Name())
+		if config.Pipeline.Packaging.TableFormat == models.TableFormatDelta && lib.DirExists(filepath.Join(tableDir, services.DeltaLogDirName)) {
+			err = services.RemoveUncommittedDeltaFiles(tableDir)
+		} else {
+			err = os.RemoveAll(tableDir)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to clear previous Parquet table %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// commitDeltaTables records the files of every written table as the next version of its Delta table
+// The schema holds the table's columns followed by its partition columns, whose values come from the
+// partition directories of the files
+func commitDeltaTables(outputDir string, tables map[string][]string, config models.ProjectConfig) error {
+	if config.Pipeline.Packaging.TableFormat != models.TableFormatDelta {
+		return nil
+	}
+
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	for _, table := range names {
+		columns := make([]services.DeltaColumn, 0, len(tables[table]))
+		for _, column := range tables[table] {
+			columns = append(columns, services.DeltaColumn{Name: column})
+		}
+		var partitionColumns []string
+		if table != WideObservationTable {
+			for _, partition := range config.Pipeline.Packaging.Parquet.PartitionBy {
+				partitionColumns = append(partitionColumns, string(partition))
+				columns = append(columns, services.DeltaColumn{Name: string(partition)})
+			}
+		}
+
+		version, err := services.CommitDeltaTable(filepath.Join(outputDir, table), columns, partitionColumns, lib.Now())
+		if err != nil {
+			return fmt.Errorf("delta table %s: %w", table, err)
+		}
+		fmt.Printf("  ✓ %s/ committed as Delta version %d\n", table, version)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+)
+
+// unsplitDirName is the staging directory (inside the DIMP output directory) holding
+// per-input DIMP output before it is partitioned by resourceType
+const unsplitDirName = ".unsplit"
+
+// unknownResourceType is used for lines without a valid resourceType
+const unknownResourceType = "Unknown"
+
+// resourceTypePattern matches valid FHIR resource type names (safe to use in filenames)
+var resourceTypePattern = regexp.MustCompile(`^[A-Z][A-Za-z]*$`)
+
+// PartitionByResourceType rewrites all NDJSON files in srcDir into one file per
+// resourceType in outputDir, named <prefix><ResourceType>.ndjson
+// Each output is written to a .part file first and renamed once all input has been
+// read, so an interrupted partition leaves no half-written type files behind.
+// Returns the number of resources written per resourceType.
+func PartitionByResourceType(srcDir, outputDir, prefix string, maxLineBytes int, logger *lib.Logger) (map[string]int, error) {
+	files, err := filepath.Glob(filepath.Join(srcDir, "*.ndjson"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files to partition: %w", err)
+	}
+	sort.Strings(files)
+
+	type partition struct {
+		file   *os.File
+		writer *bufio.Writer
+	}
+	partitions := make(map[string]*partition)
+	counts := make(map[string]int)
+
+	closeAll := func() {
+		for _, p := range partitions {
+			_ = p.file.Close()
+		}
+	}
+	removeParts := func() {
+		for resourceType := range partitions {
+			_ = os.Remove(partitionPath(outputDir, prefix, resourceType) + ".part")
+		}
+	}
+
+	for _, inputFile := range files {
+		if err := func() error {
+			inFile, err := os.Open(inputFile)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", filepath.Base(inputFile), err)
+			}
+			defer func() { _ = inFile.Close() }()
+
+			scanner := newLargeBufferScanner(inFile, maxLineBytes)
+			lineNumber := 0
+			for scanner.Scan() {
+				lineNumber++
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" {
+					continue
+				}
+
+				resourceType := resourceTypeOfLine(line)
+				p, ok := partitions[resourceType]
+				if !ok {
+					f, err := os.Create(partitionPath(outputDir, prefix, resourceType) + ".part")
+					if err != nil {
+						return fmt.Errorf("failed to create partition for %s: %w", resourceType, err)
+					}
+					p = &partition{file: f, writer: bufio.NewWriter(f)}
+					partitions[resourceType] = p
+				}
+
+				if _, err := p.writer.WriteString(line + "\n"); err != nil {
+					return fmt.Errorf("failed to write %s resource: %w", resourceType, err)
+				}
+				counts[resourceType]++
+			}
+
+			if err := scanner.Err(); err != nil {
+				return wrapScanError(err, filepath.Base(inputFile), lineNumber+1, maxLineBytes)
+			}
+			return nil
+		}(); err != nil {
+			closeAll()
+			removeParts()
+			return nil, err
+		}
+	}
+
+	// Flush and close all partitions before renaming
+	for resourceType, p := range partitions {
+		if err := p.writer.Flush(); err != nil {
+			closeAll()
+			removeParts()
+			return nil, fmt.Errorf("failed to flush partition for %s: %w", resourceType, err)
+		}
+	}
+	closeAll()
+
+	for resourceType := range partitions {
+		finalPath := partitionPath(outputDir, prefix, resourceType)
+		if err := os.Rename(finalPath+".part", finalPath); err != nil {
+			removeParts()
+			return nil, fmt.Errorf("failed to finalize partition for %s: %w", resourceType, err)
+		}
+		logger.Debug("Wrote resource type partition",
+			"resourceType", resourceType,
+			"resources", counts[resourceType],
+			"file", filepath.Base(finalPath))
+	}
+
+	return counts, nil
+}
+
+// partitionPath returns the output path for a resourceType partition
+func partitionPath(outputDir, prefix, resourceType string) string {
+	return filepath.Join(outputDir, prefix+resourceType+".ndjson")
+}
+
+// resourceTypeOfLine extracts the resourceType from a single NDJSON line
+// Returns unknownResourceType if the line can't be parsed or the type isn't a valid FHIR name
+func resourceTypeOfLine(line string) string {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if err := json.Unmarshal([]byte(line), &header); err != nil {
+		return unknownResourceType
+	}
+	if !resourceTypePattern.MatchString(header.ResourceType) {
+		return unknownResourceType
+	}
+	return header.ResourceType
+}
@@ -0,0 +1,115 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// PostConditionError reports step output that violates pipeline.post_conditions
+type PostConditionError struct {
+	Step       models.StepName
+	Violations []string
+}
+
+func (e *PostConditionError) Error() string {
+	return fmt.Sprintf("post-conditions of step %s violated: %s", e.Step, strings.Join(e.Violations, "; "))
+}
+
+// NDJSONOutputStats counts the NDJSON files and resources (non-empty lines) in a directory
+type NDJSONOutputStats struct {
+	Files     int
+	Resources int
+}
+
+// CountNDJSONOutput counts the NDJSON files in dir and the resources in them
+func CountNDJSONOutput(dir string, maxLineBytes int) (NDJSONOutputStats, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	if err != nil {
+		return NDJSONOutputStats{}, fmt.Errorf("failed to list files: %w", err)
+	}
+	stats := NDJSONOutputStats{Files: len(files)}
+	for _, path := range files {
+		count, err := countNDJSONLines(path, maxLineBytes)
+		if err != nil {
+			return NDJSONOutputStats{}, err
+		}
+		stats.Resources += count
+	}
+	return stats, nil
+}
+
+// CheckPostCondition compares a step's output in outputDir against condition
+// inputDir is only read for max_shrinkage_percent; all violations are reported together
+func CheckPostCondition(step models.StepName, condition models.StepPostCondition, inputDir, outputDir string, maxLineBytes int) error {
+	output, err := CountNDJSONOutput(outputDir, maxLineBytes)
+	if err != nil {
+		return fmt.Errorf("failed to check post-conditions of step %s: %w", step, err)
+	}
+
+	var violations []string
+	if output.Files < condition.MinOutputFiles {
+		violations = append(violations, fmt.Sprintf("%d output files, expected at least %d", output.Files, condition.MinOutputFiles))
+	}
+	if output.Resources < condition.MinResources {
+		violations = append(violations, fmt.Sprintf("%d resources, expected at least %d", output.Resources, condition.MinResources))
+	}
+	if condition.MaxShrinkagePercent != nil {
+		input, err := CountNDJSONOutput(inputDir, maxLineBytes)
+		if err != nil {
+			return fmt.Errorf("failed to check post-conditions of step %s: %w", step, err)
+		}
+		if input.Resources > 0 && output.Resources < input.Resources {
+			shrinkage := float64(input.Resources-output.Resources) * 100 / float64(input.Resources)
+			if shrinkage > *condition.MaxShrinkagePercent {
+				violations = append(violations, fmt.Sprintf("output has %d of %d input resources (%.1f%% lost, at most %g%% allowed)",
+					output.Resources, input.Resources, shrinkage, *condition.MaxShrinkagePercent))
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PostConditionError{Step: step, Violations: violations}
+	}
+	return nil
+}
+
+// checkStepPostCondition evaluates the job's post-condition for step, if one is configured
+func checkStepPostCondition(job *models.PipelineJob, step models.StepName, inputDir, outputDir string, logger *lib.Logger) error {
+	condition, ok := job.Config.Pipeline.PostConditions[step]
+	if !ok || !condition.IsActive() {
+		return nil
+	}
+	if err := CheckPostCondition(step, condition, inputDir, outputDir, job.Config.Limits.GetMaxLineBytes()); err != nil {
+		return err
+	}
+	logger.Debug("Post-conditions met", "step", step, "job_id", job.JobID)
+	return nil
+}
+
+// countNDJSONLines counts the non-empty lines of an NDJSON file without parsing them
+func countNDJSONLines(path string, maxLineBytes int) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer func() { _ = file.Close() }()
+
+	count := 0
+	lineNumber := 0
+	scanner := newLargeBufferScanner(file, maxLineBytes)
+	for scanner.Scan() {
+		lineNumber++
+		if strings.TrimSpace(scanner.Text()) != "" {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, wrapScanError(err, filepath.Base(path), lineNumber+1, maxLineBytes)
+	}
+	return count, nil
+}
@@ -0,0 +1,221 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// PreflightStatus is the outcome of a single preflight check
+type PreflightStatus string
+
+const (
+	PreflightPassed  PreflightStatus = "passed"
+	PreflightFailed  PreflightStatus = "failed"
+	PreflightSkipped PreflightStatus = "skipped"
+)
+
+// PreflightCheck records the result of exercising one enabled step with synthetic data
+type PreflightCheck struct {
+	Step     models.StepName
+	Status   PreflightStatus
+	Message  string
+	Duration time.Duration
+}
+
+// PreflightReport summarizes all preflight checks
+type PreflightReport struct {
+	Checks []PreflightCheck
+}
+
+// IsGo returns true if no check failed
+func (r PreflightReport) IsGo() bool {
+	for _, check := range r.Checks {
+		if check.Status == PreflightFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// preflightPatient is the synthetic resource sent through DIMP
+// Contains no real data - safe to send to production services
+func preflightPatient() map[string]any {
+	return map[string]any{
+		"resourceType": "Patient",
+		"id":           "aether-preflight-patient",
+		"identifier": []any{
+			map[string]any{
+				"system": "urn:aether:preflight",
+				"value":  "aether-preflight-0001",
+			},
+		},
+		"gender":    "unknown",
+		"birthDate": "1970-01-01",
+	}
+}
+
+// preflightNDJSON is the synthetic 3-line NDJSON sent to conversion services
+const preflightNDJSON = `{"resourceType":"Patient","id":"aether-preflight-1","gender":"female","birthDate":"1970-01-01"}
+{"resourceType":"Patient","id":"aether-preflight-2","gender":"male","birthDate":"1980-01-01"}
+{"resourceType":"Patient","id":"aether-preflight-3","gender":"unknown","birthDate":"1990-01-01"}
+`
+
+// RunPreflight exercises every enabled step with a tiny synthetic payload
+// Returns a report with one check per enabled step; never aborts early so
+// operators see the full go/no-go picture in a single run
+func RunPreflight(config *models.ProjectConfig, httpClient *services.HTTPClient, logger *lib.Logger) PreflightReport {
+	report := PreflightReport{}
+
+	for _, step := range config.Pipeline.EnabledSteps {
+		start := time.Now()
+		check := runPreflightCheck(config, step, httpClient, logger)
+		check.Step = step
+		check.Duration = time.Since(start)
+
+		logger.Debug("Preflight check finished", "step", step, "status", check.Status, "duration", check.Duration)
+		report.Checks = append(report.Checks, check)
+	}
+
+	return report
+}
+
+// runPreflightCheck runs the check for a single step
+func runPreflightCheck(config *models.ProjectConfig, step models.StepName, httpClient *services.HTTPClient, logger *lib.Logger) PreflightCheck {
+	switch step {
+	case models.StepTorchImport:
+		if config.Services.TORCH.BaseURL == "" {
+			return PreflightCheck{Status: PreflightFailed, Message: "services.torch.base_url is not configured"}
+		}
+		torchClient := services.NewTORCHClient(config.Services.TORCH, httpClient, logger)
+		if err := torchClient.CheckAuth(); err != nil {
+			return PreflightCheck{Status: PreflightFailed, Message: err.Error()}
+		}
+		return PreflightCheck{Status: PreflightPassed, Message: "TORCH reachable and credentials accepted"}
+
+	case models.StepLocalImport, models.StepHttpImport:
+		return PreflightCheck{Status: PreflightPassed, Message: "no external service required"}
+
+	case models.StepDIMP:
+		return preflightDIMP(config, httpClient, logger)
+
+	case models.StepImaging:
+		if config.Services.Imaging.DICOMwebURL == "" {
+			return PreflightCheck{Status: PreflightPassed, Message: "no DICOMweb server configured (endpoint rewriting only)"}
+		}
+		return preflightDICOMweb(config, httpClient, logger)
+
+	case models.StepCSVConversion, models.StepParquetConversion:
+		if step == models.StepCSVConversion && config.Services.CSVConversion.URL == "" ||
+			step == models.StepParquetConversion && config.Pipeline.Packaging.WritesParquetLocally() {
+			return preflightNativeConversion(config, httpClient, logger)
+		}
+		check := preflightConversion(config.Services.GetServiceURL(step), httpClient)
+		if check.Status == PreflightPassed && config.Pipeline.Packaging.ExpectedSchema.IsEnabled() {
+			return preflightExpectedSchema(config, httpClient, logger)
+		}
+		return check
+
+	case models.StepValidation:
+		return PreflightCheck{Status: PreflightSkipped, Message: "validation step not yet implemented"}
+
+	default:
+		return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("unknown step: %s", step)}
+	}
+}
+
+// preflightDIMP round-trips a dummy Patient through the DIMP service
+func preflightDIMP(config *models.ProjectConfig, httpClient *services.HTTPClient, logger *lib.Logger) PreflightCheck {
+	if config.Services.DIMP.URL == "" {
+		return PreflightCheck{Status: PreflightFailed, Message: "services.dimp.url is not configured"}
+	}
+
+	dimpClient := services.NewDIMPClient(config.Services.DIMP.URL, httpClient, logger)
+	pseudonymized, err := dimpClient.Pseudonymize(preflightPatient())
+	if err != nil {
+		return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("DIMP round-trip failed: %v", err)}
+	}
+
+	if resourceType, _ := pseudonymized["resourceType"].(string); resourceType != "Patient" {
+		return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("DIMP returned unexpected resourceType %q", resourceType)}
+	}
+
+	return PreflightCheck{Status: PreflightPassed, Message: "dummy Patient pseudonymized successfully"}
+}
+
+// preflightDICOMweb asks the DICOMweb server for the metadata of a study that cannot exist
+// Any answer other than a server or network error shows the server is reachable
+func preflightDICOMweb(config *models.ProjectConfig, httpClient *services.HTTPClient, logger *lib.Logger) PreflightCheck {
+	dicomClient := services.NewDICOMwebClient(config.Services.Imaging.DICOMwebURL, httpClient, logger)
+	_, err := dicomClient.FetchStudyMetadata("2.25.0")
+	if err != nil && !errors.Is(err, services.ErrStudyNotFound) {
+		return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("DICOMweb metadata request failed: %v", err)}
+	}
+	return PreflightCheck{Status: PreflightPassed, Message: "DICOMweb server reachable"}
+}
+
+// preflightExpectedSchema checks the output tables against the schema registry, so a mapping
+// change that breaks downstream ETL is reported before a job is started
+func preflightExpectedSchema(config *models.ProjectConfig, httpClient *services.HTTPClient, logger *lib.Logger) PreflightCheck {
+	if err := CheckOutputSchemas(*config, httpClient, logger); err != nil {
+		return PreflightCheck{Status: PreflightFailed, Message: err.Error()}
+	}
+	return PreflightCheck{Status: PreflightPassed, Message: "conversion service reachable; output tables compatible with the registered schemas"}
+}
+
+// preflightNativeConversion flattens the synthetic NDJSON in-process, as CSV conversion does without
+// a service URL and Parquet conversion does with packaging mode local
+// This catches mapping files that fail to load or compile before any data is processed
+func preflightNativeConversion(config *models.ProjectConfig, httpClient *services.HTTPClient, logger *lib.Logger) PreflightCheck {
+	mapping, err := services.LoadFlattenMapping(config.Flatten)
+	if err != nil {
+		return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("flatten mapping: %v", err)}
+	}
+	flattener, err := services.NewFlattener(mapping)
+	if err != nil {
+		return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("flatten mapping: %v", err)}
+	}
+
+	rows := 0
+	for _, line := range bytes.Split(bytes.TrimSpace([]byte(preflightNDJSON)), []byte("\n")) {
+		var resource map[string]any
+		if err := json.Unmarshal(line, &resource); err != nil {
+			return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("invalid preflight resource: %v", err)}
+		}
+		if _, _, ok := flattener.Flatten(resource); ok {
+			rows++
+		}
+	}
+
+	if config.Pipeline.Packaging.ExpectedSchema.IsEnabled() {
+		return preflightExpectedSchema(config, httpClient, logger)
+	}
+	return PreflightCheck{Status: PreflightPassed, Message: fmt.Sprintf("in-process conversion: %d of 3 Patients flattened", rows)}
+}
+
+// preflightConversion posts a 3-line NDJSON file to a conversion service
+func preflightConversion(serviceURL string, httpClient *services.HTTPClient) PreflightCheck {
+	if serviceURL == "" {
+		return PreflightCheck{Status: PreflightFailed, Message: "service URL is not configured"}
+	}
+
+	resp, err := httpClient.Post(serviceURL, "application/fhir+ndjson", []byte(preflightNDJSON))
+	if err != nil {
+		return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("conversion request failed: %v", err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return PreflightCheck{Status: PreflightFailed, Message: fmt.Sprintf("conversion service returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))}
+	}
+
+	return PreflightCheck{Status: PreflightPassed, Message: "3-line NDJSON converted successfully"}
+}
@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// interruptedMessage is recorded on a job whose process ended while it was running
+const interruptedMessage = "interrupted: the process running the job ended unexpectedly"
+
+// JobRecovery summarizes what RecoverJob changed in a job
+type JobRecovery struct {
+	JobID           string   `json:"job_id"`
+	RemovedFiles    []string `json:"removed_files,omitempty"`    // Partial downloads and outputs (*.part) and temporary state files, relative to the job directory
+	InterruptedStep string   `json:"interrupted_step,omitempty"` // Step that was running when the process ended; failed so that 'pipeline continue' re-runs it
+}
+
+// HasActions reports whether anything was cleaned up or reconciled
+func (r JobRecovery) HasActions() bool {
+	return len(r.RemovedFiles) > 0 || r.InterruptedStep != ""
+}
+
+// RecoverJob reconciles a job left behind by a process that ended unexpectedly (crash,
+// kill, power loss). Partial files are removed, and a job still marked in_progress is
+// failed like a cancelled one: the running step fails (transient), later steps stay pending.
+// The job must not be running; a locked job is skipped and reported without actions
+func RecoverJob(jobsDir string, jobID string, logger *lib.Logger) (JobRecovery, error) {
+	recovery := JobRecovery{JobID: jobID}
+	if services.IsJobLocked(jobsDir, jobID) {
+		return recovery, nil
+	}
+
+	err := services.WithJobLock(jobsDir, jobID, logger, func() error {
+		removed, err := services.RemovePartialFiles(jobsDir, jobID)
+		recovery.RemovedFiles = removed
+		if err != nil {
+			return err
+		}
+
+		job, err := services.LoadJobState(jobsDir, jobID)
+		if err != nil {
+			return err
+		}
+		if job.Status != models.JobStatusInProgress {
+			return nil
+		}
+
+		recovery.InterruptedStep = job.CurrentStep
+		recordJobEvent(job, logger, models.EventJobInterrupted, job.CurrentStep, interruptedMessage,
+			map[string]any{"removed_files": len(removed)})
+		if step, found := models.GetStepByName(*job, models.StepName(job.CurrentStep)); found && step.Status != models.StepStatusCompleted {
+			updated := models.ReplaceStep(*job, models.FailStep(step, models.ErrorTypeTransient, interruptedMessage, 0))
+			job = &updated
+		}
+		return services.SaveJobState(jobsDir, FailJob(job, interruptedMessage))
+	})
+	if err != nil {
+		return recovery, err
+	}
+
+	if recovery.HasActions() {
+		logger.Info("Recovered interrupted job", "job_id", jobID,
+			"removed_files", len(recovery.RemovedFiles), "interrupted_step", recovery.InterruptedStep)
+	}
+	return recovery, nil
+}
+
+// RecoverJobs runs RecoverJob on every job in the jobs directory that is not running
+// Returns the jobs that were changed; jobs that fail to recover are logged and skipped
+func RecoverJobs(jobsDir string, logger *lib.Logger) ([]JobRecovery, error) {
+	jobIDs, err := services.ListAllJobs(jobsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var recovered []JobRecovery
+	for _, jobID := range jobIDs {
+		recovery, err := RecoverJob(jobsDir, jobID, logger)
+		if err != nil {
+			logger.Warn("Failed to recover job", "job_id", jobID, "error", err)
+			continue
+		}
+		if recovery.HasActions() {
+			recovered = append(recovered, recovery)
+		}
+	}
+	return recovered, nil
+}
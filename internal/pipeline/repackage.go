@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// RepackageFormats maps the formats accepted by `aether repackage` to their packaging steps
+var RepackageFormats = map[string]models.StepName{
+	"csv":     models.StepCSVConversion,
+	"parquet": models.StepParquetConversion,
+}
+
+// ParseRepackageFormats converts a comma-separated format list (e.g. "csv,parquet") to packaging steps
+func ParseRepackageFormats(formats string) ([]models.StepName, error) {
+	var steps []models.StepName
+	seen := map[models.StepName]bool{}
+	for _, format := range strings.Split(formats, ",") {
+		format = strings.TrimSpace(format)
+		if format == "" {
+			continue
+		}
+		step, ok := RepackageFormats[format]
+		if !ok {
+			return nil, fmt.Errorf("unknown format '%s' (must be csv or parquet)", format)
+		}
+		if !seen[step] {
+			steps = append(steps, step)
+			seen[step] = true
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no formats given")
+	}
+	return steps, nil
+}
+
+// DefaultRepackageSteps returns the packaging steps the configuration can run: csv_conversion
+// unless it is set to use a conversion service, and parquet_conversion with pipeline.packaging.mode local
+func DefaultRepackageSteps(config models.ProjectConfig) []models.StepName {
+	var steps []models.StepName
+	if config.Services.CSVConversion.URL == "" {
+		steps = append(steps, models.StepCSVConversion)
+	}
+	if config.Pipeline.Packaging.WritesParquetLocally() {
+		steps = append(steps, models.StepParquetConversion)
+	}
+	return steps
+}
+
+// checkRepackageSteps fails for packaging steps that cannot produce output with the configuration:
+// conversion via an external service is not yet implemented
+func checkRepackageSteps(steps []models.StepName, config models.ProjectConfig) error {
+	for _, step := range steps {
+		switch step {
+		case models.StepCSVConversion:
+			if config.Services.CSVConversion.URL != "" {
+				return fmt.Errorf("csv cannot be regenerated: conversion via services.csv_conversion.url is not yet implemented (remove the URL to convert in-process)")
+			}
+		case models.StepParquetConversion:
+			if !config.Pipeline.Packaging.WritesParquetLocally() {
+				return fmt.Errorf("parquet cannot be regenerated: conversion via an external service is not yet implemented (set pipeline.packaging.mode: local)")
+			}
+		}
+	}
+	return nil
+}
+
+// PrepareRepackage resets the packaging steps of a job so they can run again on its pseudonymized output
+// The job picks up the current flattening and packaging settings, so an updated column mapping takes effect.
+// Previous output of the steps is removed, except Delta tables, which get a new version; import and
+// DIMP are left untouched. Steps that cannot produce output with the configuration are refused first
+func PrepareRepackage(job *models.PipelineJob, steps []models.StepName, config models.ProjectConfig, logger *lib.Logger) (*models.PipelineJob, error) {
+	dimpStep, found := models.GetStepByName(*job, models.StepDIMP)
+	if !found || dimpStep.Status != models.StepStatusCompleted {
+		return nil, fmt.Errorf("job %s has no completed dimp step; repackage works on pseudonymized output only", job.JobID)
+	}
+	pseudonymizedDir := services.GetJobOutputDir(job.Config.JobsDir, job.JobID, models.StepDIMP)
+	if entries, err := os.ReadDir(pseudonymizedDir); err != nil || len(entries) == 0 {
+		return nil, fmt.Errorf("pseudonymized output of job %s is missing or empty: %s", job.JobID, pseudonymizedDir)
+	}
+
+	updated := *job
+	updated.Config.Flatten = config.Flatten
+	updated.Config.Pipeline.Packaging = config.Pipeline.Packaging
+	if config.Services.CSVConversion.URL != "" {
+		updated.Config.Services.CSVConversion = config.Services.CSVConversion
+	}
+	if config.Services.ParquetConversion.URL != "" {
+		updated.Config.Services.ParquetConversion = config.Services.ParquetConversion
+	}
+
+	if err := checkRepackageSteps(steps, updated.Config); err != nil {
+		return nil, err
+	}
+
+	// Results of an earlier shared flattening pass no longer match the output
+	passPath := filepath.Join(services.GetJobDir(job.Config.JobsDir, job.JobID), PackagingPassFileName)
+	if err := os.Remove(passPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove %s: %w", PackagingPassFileName, err)
+	}
+
+	for _, stepName := range steps {
+		// Delta tables are kept: the step commits the new output as their next version
+		keepDelta := stepName == models.StepParquetConversion && updated.Config.Pipeline.Packaging.TableFormat == models.TableFormatDelta
+		outputDir := services.GetJobOutputDir(job.Config.JobsDir, job.JobID, stepName)
+		if !keepDelta {
+			if err := os.RemoveAll(outputDir); err != nil {
+				return nil, fmt.Errorf("failed to remove previous %s output: %w", stepName, err)
+			}
+		}
+
+		pending := models.InitializeSteps([]models.StepName{stepName})[0]
+		if _, exists := models.GetStepByName(updated, stepName); exists {
+			updated = models.ReplaceStep(updated, pending)
+		} else {
+			// The format was not enabled when the job was created
+			updated.Steps = append(append([]models.PipelineStep{}, updated.Steps...), pending)
+			updated.Config.Pipeline.EnabledSteps = append(append([]models.StepName{}, updated.Config.Pipeline.EnabledSteps...), stepName)
+		}
+		logger.Info("Reset packaging step for repackaging", "job_id", job.JobID, "step", stepName)
+	}
+
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = string(step)
+	}
+	recordJobEvent(&updated, logger, models.EventRepackage, "", "Packaging steps reset for repackaging",
+		map[string]any{"steps": names})
+	return &updated, nil
+}
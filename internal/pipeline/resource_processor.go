@@ -1,8 +1,14 @@
 package pipeline
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/trobanga/aether/internal/lib"
 	"github.com/trobanga/aether/internal/models"
@@ -17,6 +23,10 @@ type ResourceProcessor struct {
 	thresholdBytes     int
 	inputFile          string
 	resourcesProcessed int
+	entryCheckAttempts int         // How often a Bundle is sent when DIMP returns a different number of entries
+	deadLetterDir      string      // Where rejected requests are kept (empty = not kept)
+	tuner              *chunkTuner // Adjusts the split threshold (services.dimp.adaptive_split); nil = fixed threshold
+	batchingOff        bool        // DIMP rejected a batch; the rest of the file is sent one resource per request
 }
 
 // NewResourceProcessor creates a new resource processor
@@ -30,7 +40,40 @@ func NewResourceProcessor(dimpClient *services.DIMPClient, logger *lib.Logger, t
 	}
 }
 
+// SetEntryCountCheck configures how Bundles whose DIMP response has a different number of
+// entries than the request are handled: they are sent up to attempts times, then the request
+// and last response are kept in deadLetterDir and processing fails
+func (rp *ResourceProcessor) SetEntryCountCheck(attempts int, deadLetterDir string) {
+	rp.entryCheckAttempts = attempts
+	rp.deadLetterDir = deadLetterDir
+}
+
+// setChunkTuner makes the processor split Bundles at the tuner's threshold instead of the
+// configured one; oversized non-Bundle resources are still detected with the configured threshold
+func (rp *ResourceProcessor) setChunkTuner(tuner *chunkTuner) {
+	rp.tuner = tuner
+}
+
+// splitThreshold returns the size above which Bundles are split
+func (rp *ResourceProcessor) splitThreshold() int {
+	if rp.tuner != nil {
+		return rp.tuner.Threshold()
+	}
+	return rp.thresholdBytes
+}
+
+// splitAgainError is returned for a Bundle request that failed with a 413 or a timeout after
+// the chunk tuner lowered the threshold; the Bundle is split again and resent
+type splitAgainError struct {
+	err error
+}
+
+func (e *splitAgainError) Error() string { return e.err.Error() }
+func (e *splitAgainError) Unwrap() error { return e.err }
+
 // ProcessBundle handles Bundle resources with automatic splitting for large Bundles
+// In adaptive mode, a Bundle whose request DIMP rejects as too large or too slow is split again
+// at the lowered threshold
 func (rp *ResourceProcessor) ProcessBundle(resource map[string]any, resourceID string) (map[string]any, error) {
 	// Calculate Bundle size
 	bundleSize, err := models.CalculateJSONSize(resource)
@@ -43,23 +86,38 @@ func (rp *ResourceProcessor) ProcessBundle(resource map[string]any, resourceID s
 		return nil, fmt.Errorf("failed to calculate Bundle size at line %d: %w", rp.resourcesProcessed+1, err)
 	}
 
+	for {
+		pseudonymized, err := rp.processBundleAtThreshold(resource, resourceID, bundleSize, rp.splitThreshold())
+		var again *splitAgainError
+		if !errors.As(err, &again) {
+			return pseudonymized, err
+		}
+		rp.logger.Info("Splitting Bundle again with a lower threshold",
+			"bundle_id", resourceID,
+			"threshold_bytes", rp.splitThreshold(),
+			"error", again.err)
+	}
+}
+
+// processBundleAtThreshold sends a Bundle through DIMP, split into chunks of at most threshold bytes
+func (rp *ResourceProcessor) processBundleAtThreshold(resource map[string]any, resourceID string, bundleSize int, threshold int) (map[string]any, error) {
 	// Check if splitting is needed
-	if services.ShouldSplit(bundleSize, rp.thresholdBytes) {
-		return rp.processLargeBundle(resource, resourceID, bundleSize)
+	if services.ShouldSplit(bundleSize, threshold) {
+		return rp.processLargeBundle(resource, resourceID, bundleSize, threshold)
 	}
 
 	// Bundle is small enough - use direct DIMP path
-	return rp.processSmallBundle(resource, resourceID, bundleSize)
+	return rp.processSmallBundle(resource, resourceID, bundleSize, threshold)
 }
 
 // processSmallBundle processes a Bundle without splitting
-func (rp *ResourceProcessor) processSmallBundle(resource map[string]any, resourceID string, bundleSize int) (map[string]any, error) {
+func (rp *ResourceProcessor) processSmallBundle(resource map[string]any, resourceID string, bundleSize int, threshold int) (map[string]any, error) {
 	rp.logger.Debug("Bundle size below threshold, processing directly",
 		"bundle_id", resourceID,
 		"size_bytes", bundleSize,
-		"threshold_bytes", rp.thresholdBytes)
+		"threshold_bytes", threshold)
 
-	pseudonymized, err := rp.dimpClient.Pseudonymize(resource)
+	pseudonymized, err := rp.sendBundle(resource, bundleSize, resourceID, "Bundle")
 	if err != nil {
 		rp.logger.Error("Failed to pseudonymize Bundle",
 			"file", filepath.Base(rp.inputFile),
@@ -73,9 +131,9 @@ func (rp *ResourceProcessor) processSmallBundle(resource map[string]any, resourc
 }
 
 // processLargeBundle orchestrates Bundle splitting and chunk processing
-func (rp *ResourceProcessor) processLargeBundle(resource map[string]any, resourceID string, bundleSize int) (map[string]any, error) {
+func (rp *ResourceProcessor) processLargeBundle(resource map[string]any, resourceID string, bundleSize int, threshold int) (map[string]any, error) {
 	// Split the Bundle into chunks
-	splitResult, err := rp.splitLargeBundle(resource, resourceID, bundleSize)
+	splitResult, err := rp.splitLargeBundle(resource, resourceID, bundleSize, threshold)
 	if err != nil {
 		return nil, err
 	}
@@ -85,19 +143,23 @@ func (rp *ResourceProcessor) processLargeBundle(resource map[string]any, resourc
 }
 
 // splitLargeBundle splits a large Bundle into smaller chunks based on threshold
-func (rp *ResourceProcessor) splitLargeBundle(resource map[string]any, resourceID string, bundleSize int) (*models.SplitResult, error) {
-	thresholdMB := rp.thresholdBytes / (1024 * 1024)
+func (rp *ResourceProcessor) splitLargeBundle(resource map[string]any, resourceID string, bundleSize int, threshold int) (*models.SplitResult, error) {
+	if rp.tuner != nil {
+		// A tuned threshold never splits below single entries, which cannot be split further
+		threshold = max(threshold, largestEntrySize(resource)+bundleChunkOverheadBytes)
+	}
+	thresholdMB := threshold / (1024 * 1024)
 
 	// Log Bundle splitting operation
 	rp.logger.Info("Bundle size exceeds threshold, splitting",
 		"bundle_id", resourceID,
 		"size_bytes", bundleSize,
-		"threshold_bytes", rp.thresholdBytes,
+		"threshold_bytes", threshold,
 		"size_mb", float64(bundleSize)/(1024*1024),
 		"threshold_mb", thresholdMB)
 
 	// Split the Bundle
-	splitResult, err := services.SplitBundle(resource, rp.thresholdBytes)
+	splitResult, err := services.SplitBundle(resource, threshold)
 	if err != nil {
 		rp.logger.Error("Failed to split Bundle",
 			"file", filepath.Base(rp.inputFile),
@@ -132,6 +194,132 @@ func (rp *ResourceProcessor) ProcessBundleChunks(splitResult models.SplitResult,
 	return reassembledBundle, nil
 }
 
+// ProcessBundleStream pseudonymizes a Bundle line larger than the split threshold without
+// decoding it as a whole: chunks are cut from data one at a time (services.SplitBundleStream),
+// sent through DIMP, and their entries are written to out as they come back, followed by a newline
+// checkEntries is called with the number of entries read so far after each chunk
+// Returns the Bundle's id. If data is not a Bundle, services.ErrNotBundle is returned before
+// anything is sent; on any other error, out is truncated to where the Bundle started
+func (rp *ResourceProcessor) ProcessBundleStream(data []byte, out *os.File, checkEntries func(entries int) error) (string, error) {
+	start, err := out.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate output position: %w", err)
+	}
+
+	for {
+		bundleID, err := rp.streamBundleAtThreshold(data, out, checkEntries, rp.splitThreshold())
+		if err == nil {
+			return bundleID, nil
+		}
+
+		// Drop the partial Bundle, so a retry or resume continues from a complete line
+		if truncErr := out.Truncate(start); truncErr != nil {
+			return bundleID, fmt.Errorf("%w (failed to discard partial output: %v)", err, truncErr)
+		}
+		if _, seekErr := out.Seek(start, io.SeekStart); seekErr != nil {
+			return bundleID, fmt.Errorf("%w (failed to discard partial output: %v)", err, seekErr)
+		}
+
+		var again *splitAgainError
+		if !errors.As(err, &again) {
+			return bundleID, err
+		}
+		rp.logger.Info("Splitting Bundle again with a lower threshold",
+			"bundle_id", bundleID,
+			"threshold_bytes", rp.splitThreshold(),
+			"error", again.err)
+	}
+}
+
+// streamBundleAtThreshold makes one pass of ProcessBundleStream with chunks of at most threshold bytes
+func (rp *ResourceProcessor) streamBundleAtThreshold(data []byte, out *os.File, checkEntries func(entries int) error, threshold int) (string, error) {
+	rp.logger.Info("Bundle size exceeds threshold, splitting while reading",
+		"file", filepath.Base(rp.inputFile),
+		"line_number", rp.resourcesProcessed+1,
+		"size_bytes", len(data),
+		"threshold_bytes", threshold,
+		"size_mb", float64(len(data))/(1024*1024))
+
+	buffered := bufio.NewWriter(out)
+	writer := services.NewBundleStreamWriter(buffered)
+	bundleID := ""
+	entries := 0
+	var chunkErr error // Failure of a chunk, as opposed to a failure to read the Bundle
+
+	// A tuned threshold never fails on single entries, which cannot be split further
+	result, err := services.SplitBundleStream(bytes.NewReader(data), threshold, rp.tuner != nil, func(chunk models.BundleChunk) error {
+		bundleID = chunk.OriginalID
+		chunkErr = rp.streamBundleChunk(chunk, writer, checkEntries, &entries)
+		return chunkErr
+	})
+	switch {
+	case chunkErr != nil:
+		return bundleID, chunkErr
+	case errors.Is(err, services.ErrNotBundle):
+		return bundleID, err
+	case err != nil:
+		rp.logger.Error("Failed to split Bundle",
+			"file", filepath.Base(rp.inputFile),
+			"line_number", rp.resourcesProcessed+1,
+			"bundle_id", bundleID,
+			"error", err)
+		return bundleID, fmt.Errorf("failed to split Bundle at line %d: %w", rp.resourcesProcessed+1, err)
+	}
+
+	written, err := writer.Finish(result.Metadata)
+	if err != nil {
+		return result.Metadata.ID, fmt.Errorf("failed to reassemble Bundle at line %d: %w", rp.resourcesProcessed+1, err)
+	}
+	if err := buffered.WriteByte('\n'); err != nil {
+		return result.Metadata.ID, fmt.Errorf("failed to write newline: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return result.Metadata.ID, fmt.Errorf("failed to write output: %w", err)
+	}
+
+	rp.logger.Info("Reassembled Bundle from chunks",
+		"bundle_id", result.Metadata.ID,
+		"entries", written,
+		"chunks", result.Chunks)
+	return result.Metadata.ID, nil
+}
+
+// streamBundleChunk sends one chunk of a streamed Bundle through DIMP and writes its entries
+// entries counts the entries of the Bundle read so far
+func (rp *ResourceProcessor) streamBundleChunk(chunk models.BundleChunk, writer *services.BundleStreamWriter, checkEntries func(entries int) error, entries *int) error {
+	*entries += len(chunk.Entries)
+	if err := checkEntries(*entries); err != nil {
+		return err
+	}
+
+	label := fmt.Sprintf("chunk %d", chunk.Index+1)
+	rp.logger.Debug("Processing Bundle chunk",
+		"bundle_id", chunk.OriginalID,
+		"chunk", label,
+		"entries", len(chunk.Entries),
+		"estimated_bytes", chunk.EstimatedSize)
+
+	pseudonymized, err := rp.sendBundle(models.ConvertChunkToBundle(chunk), chunk.EstimatedSize, chunk.OriginalID, label)
+	if err != nil {
+		var again *splitAgainError
+		if errors.As(err, &again) {
+			return err
+		}
+		rp.logger.Error("Failed to pseudonymize Bundle chunk",
+			"file", filepath.Base(rp.inputFile),
+			"line_number", rp.resourcesProcessed+1,
+			"bundle_id", chunk.OriginalID,
+			"chunk_id", chunk.ChunkID,
+			"chunk", label,
+			"error", err)
+		return fmt.Errorf("failed to pseudonymize Bundle %s at line %d: %w", label, rp.resourcesProcessed+1, err)
+	}
+	if err := writer.WriteChunk(pseudonymized); err != nil {
+		return fmt.Errorf("failed to reassemble Bundle at line %d: %w", rp.resourcesProcessed+1, err)
+	}
+	return nil
+}
+
 // pseudonymizeBundleChunks sends each Bundle chunk through DIMP for pseudonymization
 func (rp *ResourceProcessor) pseudonymizeBundleChunks(chunks []models.BundleChunk, resourceID string) ([]map[string]any, error) {
 	pseudonymizedChunks := make([]map[string]any, 0, len(chunks))
@@ -146,7 +334,7 @@ func (rp *ResourceProcessor) pseudonymizeBundleChunks(chunks []models.BundleChun
 		chunkBundle := models.ConvertChunkToBundle(chunk)
 
 		// Send chunk to DIMP
-		pseudonymizedChunk, err := rp.dimpClient.Pseudonymize(chunkBundle)
+		pseudonymizedChunk, err := rp.sendBundle(chunkBundle, chunk.EstimatedSize, resourceID, fmt.Sprintf("chunk %d/%d", chunk.Index+1, chunk.TotalChunks))
 		if err != nil {
 			rp.logger.Error("Failed to pseudonymize Bundle chunk",
 				"file", filepath.Base(rp.inputFile),
@@ -165,6 +353,88 @@ func (rp *ResourceProcessor) pseudonymizeBundleChunks(chunks []models.BundleChun
 	return pseudonymizedChunks, nil
 }
 
+// sendBundle sends a Bundle or Bundle chunk of size bytes through DIMP and, in adaptive mode,
+// tunes the split threshold from the response time or a 413/timeout failure
+func (rp *ResourceProcessor) sendBundle(bundle map[string]any, size int, bundleID string, chunk string) (map[string]any, error) {
+	started := time.Now()
+	pseudonymized, err := rp.pseudonymizeBundle(bundle, bundleID, chunk)
+	if rp.tuner == nil {
+		return pseudonymized, err
+	}
+	if err != nil {
+		if bundleEntryCount(bundle) > 1 && rp.tuner.Shrink(size, err) {
+			return nil, &splitAgainError{err: err}
+		}
+		return nil, err
+	}
+	rp.tuner.Observe(size, time.Since(started))
+	return pseudonymized, nil
+}
+
+// bundleChunkOverheadBytes is headroom for the Bundle wrapper of a chunk around its entries
+const bundleChunkOverheadBytes = 1024
+
+// largestEntrySize returns the JSON size of the largest entry of a Bundle
+func largestEntrySize(bundle map[string]any) int {
+	entries, _ := models.ExtractEntriesFromBundle(bundle)
+	largest := 0
+	for _, entry := range entries {
+		if size, err := models.CalculateJSONSize(entry); err == nil {
+			largest = max(largest, size)
+		}
+	}
+	return largest
+}
+
+// pseudonymizeBundle sends a Bundle or Bundle chunk through DIMP and checks that the response
+// has as many entries as the request, so a truncating service cannot silently drop resources
+func (rp *ResourceProcessor) pseudonymizeBundle(bundle map[string]any, bundleID string, chunk string) (map[string]any, error) {
+	sent := bundleEntryCount(bundle)
+	attempts := max(rp.entryCheckAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		pseudonymized, err := rp.dimpClient.Pseudonymize(bundle)
+		if err != nil {
+			return nil, err
+		}
+		received := bundleEntryCount(pseudonymized)
+		if received == sent {
+			return pseudonymized, nil
+		}
+
+		rp.logger.Warn("DIMP response has a different number of Bundle entries",
+			"file", filepath.Base(rp.inputFile),
+			"bundle_id", bundleID,
+			"chunk", chunk,
+			"sent", sent,
+			"received", received,
+			"attempt", attempt,
+			"max_attempts", attempts)
+		if attempt < attempts {
+			continue
+		}
+
+		mismatch := &ChunkEntryMismatchError{Chunk: chunk, Sent: sent, Received: received, Attempts: attempt}
+		if rp.deadLetterDir != "" {
+			path, err := writeDeadLetter(rp.deadLetterDir, deadLetterRecord{
+				File:            filepath.Base(rp.inputFile),
+				Resource:        rp.resourcesProcessed + 1,
+				BundleID:        bundleID,
+				Chunk:           chunk,
+				SentEntries:     sent,
+				ReceivedEntries: received,
+				Timestamp:       lib.Now(),
+				Request:         bundle,
+				Response:        pseudonymized,
+			})
+			if err != nil {
+				rp.logger.Warn("Failed to keep rejected DIMP request", "error", err)
+			}
+			mismatch.DeadLetterFile = path
+		}
+		return nil, mismatch
+	}
+}
+
 // reassembleBundleChunks combines pseudonymized chunks back into a single Bundle
 func (rp *ResourceProcessor) reassembleBundleChunks(metadata models.BundleMetadata, pseudonymizedChunks []map[string]any, resourceID string) (map[string]any, error) {
 	reassembled, err := services.ReassembleBundle(metadata, pseudonymizedChunks)
@@ -233,6 +503,69 @@ func (rp *ResourceProcessor) pseudonymizeNonBundleResource(resource map[string]a
 	return pseudonymized, nil
 }
 
+// batchedResource is a non-Bundle resource held back to be sent together with others (services.dimp.batch_size)
+type batchedResource struct {
+	resource     map[string]any
+	resourceType string
+	resourceID   string
+	lineNumber   int // Line in the input file
+	lineBytes    int // Size of the line, for progress reporting and the batch size limit
+}
+
+// batchingEnabled reports whether resources are still sent in batches
+func (rp *ResourceProcessor) batchingEnabled() bool {
+	return !rp.batchingOff
+}
+
+// PseudonymizeBatch sends resources as the entries of one transaction Bundle and returns the
+// pseudonymized resources in input order. Returns false if DIMP failed the request or answered with
+// a different number of entries; the caller then sends the resources one by one. A batch DIMP
+// rejects (as opposed to a transient failure) turns batching off for the rest of the file
+func (rp *ResourceProcessor) PseudonymizeBatch(batch []batchedResource) ([]map[string]any, bool) {
+	entries := make([]any, len(batch))
+	for i, item := range batch {
+		request := map[string]any{"method": "POST", "url": item.resourceType}
+		if item.resourceID != "" {
+			request = map[string]any{"method": "PUT", "url": item.resourceType + "/" + item.resourceID}
+		}
+		entries[i] = map[string]any{"resource": item.resource, "request": request}
+	}
+	bundle := map[string]any{"resourceType": "Bundle", "type": "transaction", "entry": entries}
+
+	fallBack := func(reason string, err error) ([]map[string]any, bool) {
+		if err == nil || !isDIMPErrorRetryable(err) {
+			rp.batchingOff = true
+		}
+		rp.logger.Warn("DIMP batch request failed, sending its resources one by one",
+			"file", filepath.Base(rp.inputFile),
+			"first_line", batch[0].lineNumber,
+			"resources", len(batch),
+			"reason", reason,
+			"batching_disabled", rp.batchingOff)
+		return nil, false
+	}
+
+	response, err := rp.dimpClient.Pseudonymize(bundle)
+	if err != nil {
+		return fallBack(err.Error(), err)
+	}
+	responseEntries, _ := response["entry"].([]any)
+	if len(responseEntries) != len(batch) {
+		return fallBack(fmt.Sprintf("sent %d entries, received %d", len(batch), len(responseEntries)), nil)
+	}
+
+	pseudonymized := make([]map[string]any, len(batch))
+	for i, entry := range responseEntries {
+		entryMap, _ := entry.(map[string]any)
+		resource, ok := entryMap["resource"].(map[string]any)
+		if !ok {
+			return fallBack(fmt.Sprintf("entry %d has no resource", i), nil)
+		}
+		pseudonymized[i] = resource
+	}
+	return pseudonymized, true
+}
+
 // IncrementResourceCount increments the processed resource counter
 func (rp *ResourceProcessor) IncrementResourceCount() {
 	rp.resourcesProcessed++
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// RuntimeExceededNotification is the JSON payload posted to sla.webhook_url when a run is aborted
+type RuntimeExceededNotification struct {
+	JobID             string    `json:"job_id"`
+	Step              string    `json:"step"`
+	MaxRuntimeMinutes float64   `json:"max_runtime_minutes"`
+	StartedAt         time.Time `json:"started_at"`
+	Message           string    `json:"message"`
+}
+
+// activeExtractions maps the URLs of TORCH extractions currently being polled to their job IDs
+// A job polls several extractions at once when its CRTDL is split by time period.
+// The runtime watchdog reads it from its own goroutine to cancel the extractions
+var activeExtractions sync.Map
+
+// trackExtraction records a running TORCH extraction; the returned function forgets it
+func trackExtraction(jobID string, extractionURL string) func() {
+	activeExtractions.Store(extractionURL, jobID)
+	return func() { activeExtractions.Delete(extractionURL) }
+}
+
+// WatchJobRuntime aborts a run that is still going after maxRuntime
+// On expiry a running TORCH extraction is cancelled, the persisted current step is marked failed,
+// a runtime_exceeded event and notification are emitted, and abort is called (which should end the process).
+// The job's ID and config are read when the watch starts; progress is taken from state.json.
+// A zero maxRuntime disables the watch. Returns a stop function that must be called when the run ends
+func WatchJobRuntime(job *models.PipelineJob, maxRuntime time.Duration, logger *lib.Logger, abort func()) func() {
+	if maxRuntime <= 0 {
+		return func() {}
+	}
+
+	jobID := job.JobID
+	config := job.Config
+	startedAt := time.Now()
+	timer := time.AfterFunc(maxRuntime, func() {
+		AbortJobForRuntime(jobID, config, startedAt, maxRuntime, logger)
+		abort()
+	})
+
+	return func() { timer.Stop() }
+}
+
+// AbortJobForRuntime cancels and fails a job that exceeded its maximum runtime
+// Every action is best-effort: failures are logged so the abort itself always proceeds
+func AbortJobForRuntime(jobID string, config models.ProjectConfig, startedAt time.Time, maxRuntime time.Duration, logger *lib.Logger) {
+	message := fmt.Sprintf("job exceeded its maximum runtime of %s (running since %s)",
+		maxRuntime, startedAt.Format(time.RFC3339))
+	logger.Error("Aborting job: maximum runtime exceeded",
+		"job_id", jobID,
+		"max_runtime", maxRuntime,
+		"started_at", startedAt)
+
+	// Stop the server-side work first, so TORCH does not keep extracting for nobody
+	activeExtractions.Range(func(extractionURL, owner any) bool {
+		if owner != jobID {
+			return true
+		}
+		httpClient := services.NewHTTPClient(30*time.Second, config.Retry, logger)
+		httpClient.SetJobID(jobID)
+		torchClient := services.NewTORCHClient(config.Services.TORCH, httpClient, logger)
+		if err := torchClient.CancelExtraction(extractionURL.(string)); err != nil {
+			logger.Warn("Failed to cancel TORCH extraction", "job_id", jobID, "error", err)
+		}
+		return true
+	})
+
+	stepName := ""
+	job, err := services.LoadJobState(config.JobsDir, jobID)
+	if err != nil {
+		logger.Error("Failed to load job state", "job_id", jobID, "error", err)
+	} else {
+		stepName = job.CurrentStep
+		recordJobEvent(job, logger, models.EventRuntimeExceeded, stepName, message,
+			map[string]any{"max_runtime_minutes": maxRuntime.Minutes()})
+
+		// The interrupted step is failed (non-transient), later steps stay pending;
+		// 'pipeline continue' re-runs the step from the start
+		if step, found := models.GetStepByName(*job, models.StepName(stepName)); found && step.Status != models.StepStatusCompleted {
+			updated := models.ReplaceStep(*job, models.FailStep(step, models.ErrorTypeNonTransient, message, 0))
+			job = &updated
+		}
+		job = FailJob(job, message)
+		if err := services.SaveJobState(config.JobsDir, job); err != nil {
+			logger.Error("Failed to save job state", "job_id", jobID, "error", err)
+		}
+	}
+
+	if webhookURL := config.SLA.WebhookURL; webhookURL != "" {
+		notification := RuntimeExceededNotification{
+			JobID:             jobID,
+			Step:              stepName,
+			MaxRuntimeMinutes: maxRuntime.Minutes(),
+			StartedAt:         startedAt,
+			Message:           message,
+		}
+		data := services.WebhookTemplateData{Event: "runtime_exceeded", Notification: notification, Job: job}
+		if err := services.NotifyWebhook(config.SLA, data); err != nil {
+			logger.Warn("Failed to send runtime notification", "url", webhookURL, "error", err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// s3RequestTimeout bounds a single S3 request; parts of a multipart upload are up to
+// services.s3.part_size_mb large, so it is longer than for service calls
+const s3RequestTimeout = 5 * time.Minute
+
+// uploadStepOutput uploads the output directory of a completed step to output.s3, as
+// <prefix>/<job-id>/<output directory>/<relative path>. Objects of an earlier attempt are
+// overwritten, so a re-run step replaces its upload
+func uploadStepOutput(ctx context.Context, job *models.PipelineJob, step models.StepName, logger *lib.Logger) error {
+	bucket, prefix, err := models.ParseS3URL(job.Config.Output.S3.URL)
+	if err != nil {
+		return err
+	}
+	outputDir := services.GetJobOutputDir(job.Config.JobsDir, job.JobID, step)
+	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
+		logger.Debug("Step has no output to upload", "step", step, "dir", outputDir)
+		return nil
+	}
+
+	httpClient := services.NewHTTPClient(s3RequestTimeout, job.Config.Retry, logger)
+	httpClient.SetEventSink(jobEventSink(job, logger))
+	httpClient.SetJobID(job.JobID)
+	httpClient.SetWaitSink(jobWaitSink(job, logger))
+	httpClient.SetContext(ctx)
+	client, err := services.NewS3Client(job.Config.Services.S3, httpClient, logger)
+	if err != nil {
+		return err
+	}
+
+	keyPrefix := path.Join(prefix, job.JobID, filepath.Base(outputDir))
+	target := fmt.Sprintf("s3://%s/%s", bucket, keyPrefix)
+	files, bytes, err := services.UploadDirToS3(client, outputDir, bucket, keyPrefix, logger)
+	if err != nil {
+		return fmt.Errorf("failed to upload output of step %s to %s: %w", step, target, err)
+	}
+
+	logger.Info("Uploaded step output", "step", step, "target", target, "files", files, "bytes", bytes)
+	recordJobEvent(job, logger, models.EventOutputUploaded, string(step),
+		fmt.Sprintf("uploaded %d files to %s", files, target),
+		map[string]any{"target": target, "files": files, "bytes": bytes})
+	return nil
+}
+
+// classifyS3Error classifies an S3 error as transient or non-transient
+func classifyS3Error(err error) models.ErrorType {
+	var s3Err *services.S3Error
+	if errors.As(err, &s3Err) {
+		return s3Err.ErrorType
+	}
+	// Network errors are transient
+	if lib.IsNetworkError(err) {
+		return models.ErrorTypeTransient
+	}
+	return models.ErrorTypeNonTransient
+}
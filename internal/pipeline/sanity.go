@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// SanityCheckResult is the outcome of a single sanity check
+type SanityCheckResult struct {
+	Check  models.SanityCheck
+	Count  int
+	Passed bool
+}
+
+// SanityCheckDir returns the directory whose NDJSON files sanity checks should run against
+// Uses pseudonymized output if present (what gets delivered), otherwise the imported data
+func SanityCheckDir(jobDir string) string {
+	pseudonymizedDir := filepath.Join(jobDir, "pseudonymized")
+	if files, _ := filepath.Glob(filepath.Join(pseudonymizedDir, "*.ndjson")); len(files) > 0 {
+		return pseudonymizedDir
+	}
+	return filepath.Join(jobDir, "import")
+}
+
+// RunSanityChecks counts matching resources across all NDJSON files in dataDir
+// and compares each count against the check's expected range
+// All checks are evaluated in a single pass over the data
+func RunSanityChecks(dataDir string, checks []models.SanityCheck, maxLineBytes int, logger *lib.Logger) ([]SanityCheckResult, error) {
+	filters := make([]lib.ResourceFilter, len(checks))
+	for i, check := range checks {
+		filters[i] = lib.ResourceFilter{ResourceType: check.ResourceType}
+		if check.Where != "" {
+			compiled, err := lib.CompileFHIRPath(check.Where)
+			if err != nil {
+				return nil, fmt.Errorf("sanity check '%s': %w", check.Name, err)
+			}
+			filters[i].Where = compiled
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dataDir, "*.ndjson"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no NDJSON files found in %s", dataDir)
+	}
+
+	counts := make([]int, len(checks))
+	for _, path := range files {
+		if err := countSanityMatches(path, filters, counts, maxLineBytes); err != nil {
+			return nil, err
+		}
+		logger.Debug("Sanity checks scanned file", "file", filepath.Base(path))
+	}
+
+	results := make([]SanityCheckResult, len(checks))
+	for i, check := range checks {
+		results[i] = SanityCheckResult{
+			Check:  check,
+			Count:  counts[i],
+			Passed: check.InRange(counts[i]),
+		}
+	}
+	return results, nil
+}
+
+// countSanityMatches adds the number of resources matching each filter in one file to counts
+func countSanityMatches(path string, filters []lib.ResourceFilter, counts []int, maxLineBytes int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := newLargeBufferScanner(file, maxLineBytes)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var resource map[string]any
+		if err := json.Unmarshal([]byte(line), &resource); err != nil {
+			return fmt.Errorf("failed to parse %s line %d: %w", filepath.Base(path), lineNumber, err)
+		}
+
+		for i, filter := range filters {
+			if filter.Matches(resource) {
+				counts[i]++
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return wrapScanError(err, filepath.Base(path), lineNumber+1, maxLineBytes)
+	}
+	return nil
+}
+
+// AllSanityChecksPassed returns true if every result is within its expected range
+func AllSanityChecksPassed(results []SanityCheckResult) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// SLAExceededNotification is the JSON payload posted to sla.webhook_url
+type SLAExceededNotification struct {
+	JobID            string    `json:"job_id"`
+	Step             string    `json:"step"`
+	ThresholdMinutes float64   `json:"threshold_minutes"`
+	StartedAt        time.Time `json:"started_at"`
+	Message          string    `json:"message"`
+}
+
+// WatchStepSLA warns once if a step is still running after threshold
+// The step is never interrupted. A zero threshold disables the watch
+// Returns a stop function that must be called when the step finishes
+func WatchStepSLA(job *models.PipelineJob, stepName models.StepName, threshold time.Duration, logger *lib.Logger) func() {
+	if threshold <= 0 {
+		return func() {}
+	}
+
+	startedAt := time.Now()
+	timer := time.AfterFunc(threshold, func() {
+		message := fmt.Sprintf("step %s still running after %s (SLA %s)", stepName, time.Since(startedAt).Round(time.Second), threshold)
+		logger.Warn("Step exceeded its expected duration",
+			"job_id", job.JobID,
+			"step", stepName,
+			"sla", threshold,
+			"started_at", startedAt)
+		recordJobEvent(job, logger, models.EventSLAExceeded, string(stepName), message,
+			map[string]any{"threshold_minutes": threshold.Minutes()})
+
+		if webhookURL := job.Config.SLA.WebhookURL; webhookURL != "" {
+			notification := SLAExceededNotification{
+				JobID:            job.JobID,
+				Step:             string(stepName),
+				ThresholdMinutes: threshold.Minutes(),
+				StartedAt:        startedAt,
+				Message:          message,
+			}
+			data := services.WebhookTemplateData{Event: "sla_exceeded", Notification: notification, Job: job}
+			if err := services.NotifyWebhook(job.Config.SLA, data); err != nil {
+				logger.Warn("Failed to send SLA notification", "url", webhookURL, "error", err)
+			}
+		}
+	})
+
+	return func() { timer.Stop() }
+}
@@ -0,0 +1,231 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime/debug"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// stepRun is one execution of a step as seen by the step middleware
+type stepRun struct {
+	job    *models.PipelineJob // Replaced by handlers that return an updated copy (import)
+	step   models.StepName
+	logger *lib.Logger
+	ctx    context.Context // The run's context; bounded by the step's share of the time budget inside withStepDeadline
+
+	startFields map[string]any                                            // Added to the step_started event
+	fail        func(run *stepRun, err error, errorType models.ErrorType) // Records a failure on the step; recordStepError by default
+	result      func(run *stepRun) (files int, bytes int64)               // Output of a completed step, for the step_completed event and log
+	duration    time.Duration
+}
+
+// stepHandler does the work of a step
+type stepHandler func(run *stepRun) error
+
+// stepMiddleware wraps a step handler with behavior shared by all steps
+type stepMiddleware func(next stepHandler) stepHandler
+
+// stepMiddlewareChain is applied to every step, outermost first: trace span, timeline events,
+// logs with timing, cancellation, output upload, SLA watch, time budget, and recovery from
+// panics closest to the step's own code
+var stepMiddlewareChain = []stepMiddleware{withStepSpan, withStepEvents, withStepLogging, withCancellation, withOutputUpload, withStepSLA, withStepDeadline, withPanicRecovery}
+
+// ErrStepCancelled is returned by a step that stopped because the run's context was cancelled
+var ErrStepCancelled = errors.New("step cancelled")
+
+// RunStep runs work as stepName of job wrapped in the step middleware: timeline events, start
+// and finish logs with the duration, cancellation with ctx, SLA watch, the step's time budget
+// (passed to work as a context derived from ctx) and panic recovery. work records its own failures
+// with their error type on the job's step; a panic is recorded as a non-transient error
+func RunStep(ctx context.Context, job *models.PipelineJob, stepName models.StepName, logger *lib.Logger, work func(ctx context.Context) error) error {
+	return runStep(&stepRun{job: job, step: stepName, logger: logger, ctx: ctx}, func(run *stepRun) error {
+		return work(run.ctx)
+	})
+}
+
+// runStep runs handler for run.step wrapped in stepMiddlewareChain
+func runStep(run *stepRun, handler stepHandler) error {
+	if run.ctx == nil {
+		run.ctx = context.Background()
+	}
+	if run.fail == nil {
+		run.fail = func(run *stepRun, err error, errorType models.ErrorType) {
+			recordStepError(getOrCreateStep(run.job, run.step), err, errorType)
+		}
+	}
+	if run.result == nil {
+		run.result = func(run *stepRun) (int, int64) {
+			step := getOrCreateStep(run.job, run.step)
+			return step.FilesProcessed, step.BytesProcessed
+		}
+	}
+
+	for i := len(stepMiddlewareChain) - 1; i >= 0; i-- {
+		handler = stepMiddlewareChain[i](handler)
+	}
+	return handler(run)
+}
+
+// withStepSpan traces the step as a span; service calls made with the step's context become
+// its children. The trace ID is added to the step_started event to find the trace of a job
+func withStepSpan(next stepHandler) stepHandler {
+	return func(run *stepRun) error {
+		ctx, span := lib.StartSpan(run.ctx, "step "+string(run.step),
+			attribute.String("aether.job_id", run.job.JobID),
+			attribute.String("aether.step", string(run.step)))
+		run.ctx = ctx
+		if traceID := lib.TraceID(ctx); traceID != "" {
+			fields := map[string]any{"trace_id": traceID}
+			maps.Copy(fields, run.startFields)
+			run.startFields = fields
+		}
+
+		err := next(run)
+		lib.EndSpan(span, err)
+		return err
+	}
+}
+
+// withStepEvents records step_started, step_completed and step_failed on the job timeline
+// with the step's duration and output, and attaches the log lines of a failure to its error
+func withStepEvents(next stepHandler) stepHandler {
+	return func(run *stepRun) error {
+		recordJobEvent(run.job, run.logger, models.EventStepStarted, string(run.step), "step started", run.startFields)
+
+		logMark := run.logger.Mark()
+		err := next(run)
+		if err != nil {
+			eventType := models.EventStepFailed
+			if errors.Is(err, ErrStepCancelled) {
+				eventType = models.EventStepCancelled
+			}
+			recordJobEvent(run.job, run.logger, eventType, string(run.step), err.Error(),
+				map[string]any{"duration_seconds": run.duration.Seconds()})
+			step := getOrCreateStep(run.job, run.step)
+			attachLogContext(step, run.logger, logMark)
+			return err
+		}
+
+		files, bytes := run.result(run)
+		recordJobEvent(run.job, run.logger, models.EventStepCompleted, string(run.step),
+			fmt.Sprintf("step completed (%d files)", files),
+			map[string]any{"files": files, "bytes": bytes, "duration_seconds": run.duration.Seconds()})
+		return nil
+	}
+}
+
+// withStepLogging logs the start and the outcome of a step and measures its duration
+func withStepLogging(next stepHandler) stepHandler {
+	return func(run *stepRun) error {
+		lib.LogStepStart(run.logger, string(run.step), run.job.JobID)
+		started := time.Now()
+
+		err := next(run)
+		run.duration = time.Since(started)
+		if errors.Is(err, ErrStepCancelled) {
+			run.logger.Warn("Step cancelled", "step", run.step, "job_id", run.job.JobID, "duration", run.duration, "reason", err)
+			return err
+		}
+		if err != nil {
+			retryable := false
+			if step, found := models.GetStepByName(*run.job, run.step); found && step.LastError != nil {
+				retryable = step.LastError.Type == models.ErrorTypeTransient
+			}
+			lib.LogStepFailed(run.logger, string(run.step), run.job.JobID, err, retryable)
+			return err
+		}
+
+		files, _ := run.result(run)
+		lib.LogStepComplete(run.logger, string(run.step), run.job.JobID, files, run.duration)
+		return nil
+	}
+}
+
+// withCancellation marks the step cancelled when it ends because the run's context was cancelled
+// (SIGINT, SIGTERM), whatever error the interrupted work returned. The step stays resumable:
+// 'pipeline continue' runs it again
+func withCancellation(next stepHandler) stepHandler {
+	return func(run *stepRun) error {
+		parent := run.ctx
+		err := next(run)
+		if err == nil || parent.Err() == nil {
+			return err
+		}
+
+		cancelErr := fmt.Errorf("%w: %v", ErrStepCancelled, context.Cause(parent))
+		step := getOrCreateStep(run.job, run.step)
+		*step = models.CancelStep(*step, cancelErr.Error())
+		return cancelErr
+	}
+}
+
+// withOutputUpload uploads the output of a completed step to output.s3. A failed upload fails
+// the step, so the output is uploaded again when 'pipeline continue' re-runs it. The upload is
+// bounded by the run's context, not by the step's share of the time budget
+func withOutputUpload(next stepHandler) stepHandler {
+	return func(run *stepRun) error {
+		parent := run.ctx
+		err := next(run)
+		if err != nil || !run.job.Config.Output.S3.UploadsStep(run.step) {
+			return err
+		}
+
+		if err := uploadStepOutput(parent, run.job, run.step, run.logger); err != nil {
+			run.fail(run, err, classifyS3Error(err))
+			return err
+		}
+		return nil
+	}
+}
+
+// withStepSLA warns while the step runs longer than its configured SLA
+func withStepSLA(next stepHandler) stepHandler {
+	return func(run *stepRun) error {
+		stopSLAWatch := WatchStepSLA(run.job, run.step, run.job.Config.SLA.Threshold(run.step), run.logger)
+		defer stopSLAWatch()
+		return next(run)
+	}
+}
+
+// withStepDeadline bounds the step by its share of the time budget; a step that fails after
+// the share is used up fails (non-transient) with the budget error instead of its own
+func withStepDeadline(next stepHandler) stepHandler {
+	return func(run *stepRun) error {
+		deadline := startStepDeadline(run.ctx, run.job, run.step, run.logger)
+		defer deadline.Stop()
+		run.ctx = deadline.ctx
+
+		err := next(run)
+		if err != nil {
+			if budgetErr := deadline.Err(); budgetErr != nil {
+				run.fail(run, budgetErr, models.ErrorTypeNonTransient)
+				return budgetErr
+			}
+		}
+		return err
+	}
+}
+
+// withPanicRecovery turns a panic in the step into a non-transient step error, so a crash
+// fails the step like any other error instead of ending the process with the job in_progress
+func withPanicRecovery(next stepHandler) stepHandler {
+	return func(run *stepRun) (err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("step %s panicked: %v", run.step, recovered)
+				run.logger.Error("Step panicked", "step", run.step, "job_id", run.job.JobID,
+					"panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+				run.fail(run, err, models.ErrorTypeNonTransient)
+			}
+		}()
+		return next(run)
+	}
+}
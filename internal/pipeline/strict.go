@@ -0,0 +1,43 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// StrictModeError reports warnings that pipeline.strict escalated to a step failure
+type StrictModeError struct {
+	Step     models.StepName
+	Warnings []models.StepWarning
+}
+
+func (e *StrictModeError) Error() string {
+	messages := make([]string, len(e.Warnings))
+	for i, warning := range e.Warnings {
+		messages[i] = warning.Message
+	}
+	return fmt.Sprintf("strict mode: step %s has %d warnings that fail the step: %s",
+		e.Step, len(e.Warnings), strings.Join(messages, "; "))
+}
+
+// checkStrictWarnings fails a step whose warnings include a class escalated by pipeline.strict
+// Called once the step's work is done, so the error lists every offending warning at once
+func checkStrictWarnings(job *models.PipelineJob, stepName models.StepName) error {
+	step, found := models.GetStepByName(*job, stepName)
+	if !found {
+		return nil
+	}
+
+	var escalated []models.StepWarning
+	for _, warning := range step.Warnings {
+		if job.Config.Pipeline.IsStrictWarning(warning.Code) {
+			escalated = append(escalated, warning)
+		}
+	}
+	if len(escalated) == 0 {
+		return nil
+	}
+	return &StrictModeError{Step: stepName, Warnings: escalated}
+}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// splitCRTDLDirName is the directory (inside the job directory) holding the CRTDLs of a split extraction
+const splitCRTDLDirName = "crtdl-periods"
+
+// executeSplitTORCHExtraction runs one TORCH extraction per sub-period of the job's CRTDL
+// (services.torch.split_period), at most split_concurrency at a time. Each period is
+// downloaded into its own staging directory; its files are then moved into importDir with
+// the period appended (Patient.ndjson -> Patient.2023-Q1.ndjson)
+func executeSplitTORCHExtraction(job *models.PipelineJob, chunks []services.CRTDLChunk, importDir string, torchResults *[]torchResult, httpClient *services.HTTPClient, logger *lib.Logger) ([]models.FHIRDataFile, error) {
+	config := job.Config.Services.TORCH
+	crtdlDir := filepath.Join(services.GetJobDir(job.Config.JobsDir, job.JobID), splitCRTDLDirName)
+	if err := os.MkdirAll(crtdlDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create CRTDL period directory: %w", err)
+	}
+
+	logger.Info("Splitting TORCH extraction by period", "split_period", config.SplitPeriod, "periods", len(chunks), "concurrency", config.GetSplitConcurrency())
+	recordJobEvent(job, logger, models.EventTORCHSplit, job.CurrentStep,
+		fmt.Sprintf("split TORCH extraction into %d %s periods (%s to %s)", len(chunks), config.SplitPeriod, chunks[0].Label, chunks[len(chunks)-1].Label),
+		map[string]any{"split_period": config.SplitPeriod, "periods": len(chunks), "concurrency": config.GetSplitConcurrency()})
+
+	files := make([][]models.FHIRDataFile, len(chunks))
+	results := make([][]torchResult, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, config.GetSplitConcurrency())
+	for i, chunk := range chunks {
+		crtdlPath := filepath.Join(crtdlDir, chunk.Label+".crtdl")
+		if err := os.WriteFile(crtdlPath, chunk.CRTDL, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write CRTDL of period %s: %w", chunk.Label, err)
+		}
+		stagingDir := filepath.Join(importDir, ".period-"+chunk.Label)
+		if err := os.RemoveAll(stagingDir); err != nil {
+			return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(stagingDir) }()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			// Spinners of concurrent extractions would overwrite each other
+			files[i], errs[i] = runTORCHExtraction(job, crtdlPath, chunk.Label, stagingDir, &results[i], httpClient, logger, lib.NoProgress)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("period %s: %w", chunk.Label, errs[i])
+				return
+			}
+			logger.Info("TORCH period extracted", "period", chunk.Label, "files", len(files[i]))
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var merged []models.FHIRDataFile
+	var emptyPeriods []string
+	for i, chunk := range chunks {
+		if len(files[i]) == 0 {
+			emptyPeriods = append(emptyPeriods, chunk.Label)
+		}
+		stagingDir := filepath.Join(importDir, ".period-"+chunk.Label)
+		for _, file := range files[i] {
+			name := fmt.Sprintf("%s.%s.ndjson", strings.TrimSuffix(file.FileName, ".ndjson"), chunk.Label)
+			if err := os.Rename(filepath.Join(stagingDir, file.FileName), filepath.Join(importDir, name)); err != nil {
+				return nil, fmt.Errorf("failed to move %s into import directory: %w", file.FileName, err)
+			}
+			file.FileName = name
+			file.FilePath = name
+			merged = append(merged, file)
+		}
+		*torchResults = append(*torchResults, results[i]...)
+	}
+
+	if len(emptyPeriods) > 0 {
+		recordStepWarning(job, logger, models.StepName(job.CurrentStep), models.WarningEmptyResult, "", len(emptyPeriods),
+			fmt.Sprintf("%d of %d TORCH periods returned no files: %s", len(emptyPeriods), len(chunks), strings.Join(emptyPeriods, ", ")))
+	}
+
+	logger.Info("Merged TORCH period extractions", "periods", len(chunks), "files", len(merged))
+	return merged, nil
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// principal is the authenticated caller of a request
+type principal struct {
+	name   string
+	role   models.APIRole
+	tenant string // Tenant the caller is restricted to (empty = all)
+}
+
+// Principals used when no API token identifies the caller
+// Without server.tokens callers are anonymous viewers: the API is read-only, so nobody who can
+// reach the port can submit jobs, import server-local paths or cancel runs
+var (
+	anonymousPrincipal = principal{name: "anonymous", role: models.RoleViewer}
+	sharePrincipal     = principal{name: "share-link"}
+)
+
+// statusRecorder remembers the status code written by a handler for the audit log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// handle registers a handler that requires permission
+// Without server.tokens every caller is treated as an anonymous viewer
+func (s *Server) handle(pattern string, permission models.APIPermission, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		caller, err := s.authenticate(r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="aether"`)
+			s.writeError(recorder, http.StatusUnauthorized, err)
+		} else if err := caller.authorize(permission, r.PathValue("tenant")); err != nil {
+			s.writeError(recorder, http.StatusForbidden, err)
+		} else {
+			handler(recorder, r)
+		}
+		s.audit(r, caller, recorder.status, r.URL.Path)
+	})
+}
+
+// handleShared registers a share-link handler, authorized by the signed token in the path
+// The audit log records the route instead of the path so tokens are not written to disk
+func (s *Server) handleShared(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler(recorder, r)
+		route := r.Pattern
+		if _, path, found := strings.Cut(route, " "); found {
+			route = path
+		}
+		s.audit(r, sharePrincipal, recorder.status, route)
+	})
+}
+
+// authorize checks that the caller may use a permission on a tenant's endpoints
+func (p principal) authorize(permission models.APIPermission, tenant string) error {
+	if p == anonymousPrincipal && permission != models.PermissionRead {
+		return errors.New("the API is read-only without server.tokens; configure a token to " + string(permission))
+	}
+	if !p.role.Allows(permission) {
+		return errors.New("role " + string(p.role) + " may not " + string(permission))
+	}
+	if p.tenant != "" && p.tenant != tenant {
+		return errors.New("token is restricted to tenant " + p.tenant)
+	}
+	return nil
+}
+
+// authenticate identifies the caller from an "Authorization: Bearer <token>" value
+func (s *Server) authenticate(authorization string) (principal, error) {
+	if !s.config.Server.AuthEnabled() {
+		return anonymousPrincipal, nil
+	}
+	given, found := strings.CutPrefix(authorization, "Bearer ")
+	if !found || given == "" {
+		return principal{name: "anonymous"}, errors.New("missing bearer token")
+	}
+
+	// Compare fixed-length digests in constant time so token lengths and prefixes do not leak
+	givenSum := sha256.Sum256([]byte(given))
+	for _, token := range s.config.Server.Tokens {
+		tokenSum := sha256.Sum256([]byte(token.Token))
+		if subtle.ConstantTimeCompare(givenSum[:], tokenSum[:]) == 1 {
+			return principal{name: token.Name, role: token.Role, tenant: token.Tenant}, nil
+		}
+	}
+	return principal{name: "anonymous"}, errors.New("invalid bearer token")
+}
+
+// audit appends the outcome of an HTTP request to <jobs_dir>/audit.ndjson
+func (s *Server) audit(r *http.Request, caller principal, status int, path string) {
+	s.writeAudit(models.AuditEntry{
+		Timestamp: s.now(),
+		Principal: caller.name,
+		Role:      caller.role,
+		Method:    r.Method,
+		Path:      path,
+		Tenant:    r.PathValue("tenant"),
+		JobID:     r.PathValue("id"),
+		Status:    status,
+		Remote:    r.RemoteAddr,
+	})
+}
+
+// writeAudit appends an entry to the audit log
+// Failures are logged but never fail the request
+func (s *Server) writeAudit(entry models.AuditEntry) {
+	if err := services.AppendAuditEntry(s.config.JobsDir, entry); err != nil {
+		s.logger.Warn("Failed to write audit log", "error", err)
+	}
+	s.logger.Debug("API request", "principal", entry.Principal, "method", entry.Method, "path", entry.Path, "status", entry.Status)
+}
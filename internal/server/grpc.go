@@ -0,0 +1,400 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/server/aetherv1"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// statusPollInterval is how often GetStatus checks a job's state and events for changes
+// Jobs run in other processes, so their files on disk are the only source of progress
+const statusPollInterval = time.Second
+
+// Launcher runs a created job through its pipeline in the background
+// `aether serve` starts each job in a child process, so a failing run cannot take the server down
+type Launcher func(config models.ProjectConfig, jobID string) error
+
+// SetLauncher enables job submission through the gRPC API
+func (s *Server) SetLauncher(launcher Launcher) {
+	s.launcher = launcher
+}
+
+// GRPCServer returns a gRPC server exposing the JobService of aether/v1/aether.proto
+// With server.grpc_tls_cert and server.grpc_tls_key it only accepts TLS connections
+func (s *Server) GRPCServer() (*grpc.Server, error) {
+	var options []grpc.ServerOption
+	if s.config.Server.GRPCTLSEnabled() {
+		creds, err := credentials.NewServerTLSFromFile(s.config.Server.GRPCTLSCert, s.config.Server.GRPCTLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load server.grpc_tls_cert/grpc_tls_key: %w", err)
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(options...)
+	aetherv1.RegisterJobServiceServer(grpcServer, &jobService{server: s})
+	return grpcServer, nil
+}
+
+// jobService implements the gRPC JobService with the same tokens, tenants and audit log as the REST API
+type jobService struct {
+	aetherv1.UnimplementedJobServiceServer
+	server *Server
+}
+
+func (g *jobService) SubmitJob(ctx context.Context, req *aetherv1.SubmitJobRequest) (*aetherv1.Job, error) {
+	done, err := g.authorize(ctx, models.PermissionSubmit, req.GetTenant(), "")
+	if err != nil {
+		return nil, err
+	}
+	if g.server.launcher == nil {
+		return nil, done(status.Error(codes.Unimplemented, "job submission is not enabled on this server"))
+	}
+	if len(req.GetInputs()) == 0 {
+		return nil, done(status.Error(codes.InvalidArgument, "at least one input is required"))
+	}
+	config, err := g.scope(req.GetTenant())
+	if err != nil {
+		return nil, done(err)
+	}
+	if err := checkSubmittedInputs(req.GetInputs(), g.server.config.Server.AllowedInputDirs); err != nil {
+		return nil, done(status.Error(codes.PermissionDenied, err.Error()))
+	}
+
+	job, err := pipeline.CreateMultiSourceJob(req.GetInputs(), config, g.server.logger)
+	switch {
+	case errors.Is(err, services.ErrQuotaReached):
+		return nil, done(status.Error(codes.ResourceExhausted, err.Error()))
+	case err != nil:
+		return nil, done(status.Error(codes.InvalidArgument, err.Error()))
+	}
+	if err := g.server.launcher(config, job.JobID); err != nil {
+		failed := pipeline.FailJob(job, "failed to start job: "+err.Error())
+		if saveErr := services.SaveJobState(config.JobsDir, failed); saveErr != nil {
+			g.server.logger.Error("Failed to save job state", "job_id", job.JobID, "error", saveErr)
+		}
+		return nil, done(status.Errorf(codes.Internal, "failed to start job %s: %v", job.JobID, err))
+	}
+	g.server.logger.Info("Submitted job", "job_id", job.JobID, "tenant", config.Tenant)
+	return jobToProto(models.NewJobStatusView(*job)), done(nil)
+}
+
+// remoteInputTypes can be submitted by API clients without server.allowed_input_dirs
+// URLs and S3 prefixes are fetched from other services, and a CRTDL only defines the extraction sent to TORCH
+var remoteInputTypes = map[models.InputType]bool{
+	models.InputTypeHTTP:       true,
+	models.InputTypeTORCHURL:   true,
+	models.InputTypeFHIRSearch: true,
+	models.InputTypeBulkExport: true,
+	models.InputTypeS3:         true,
+	models.InputTypeCRTDL:      true,
+}
+
+// checkSubmittedInputs rejects server paths outside allowedDirs, so API clients cannot import
+// arbitrary files of the server host (e.g. /etc) into a job whose output they can read
+func checkSubmittedInputs(inputs []string, allowedDirs []string) error {
+	for _, input := range inputs {
+		inputType, err := lib.DetectInputType(input)
+		if err != nil {
+			return err
+		}
+		if remoteInputTypes[inputType] || isInAllowedDir(input, allowedDirs) {
+			continue
+		}
+		return fmt.Errorf("input '%s' is not under server.allowed_input_dirs; submit URLs, S3 prefixes or CRTDL files instead", input)
+	}
+	return nil
+}
+
+// isInAllowedDir returns true if path, with symlinks resolved, is one of allowedDirs or below one
+func isInAllowedDir(path string, allowedDirs []string) bool {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range allowedDirs {
+		allowed, err := resolvePath(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(allowed, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns the absolute path of an existing file with symlinks resolved
+func resolvePath(path string) (string, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(absolute)
+}
+
+func (g *jobService) GetStatus(req *aetherv1.GetStatusRequest, stream grpc.ServerStreamingServer[aetherv1.StatusUpdate]) error {
+	done, err := g.authorize(stream.Context(), models.PermissionRead, req.GetTenant(), req.GetJobId())
+	if err != nil {
+		return err
+	}
+	config, err := g.scope(req.GetTenant())
+	if err != nil {
+		return done(err)
+	}
+	job, err := g.loadJob(config, req.GetJobId())
+	if err != nil {
+		return done(err)
+	}
+	return done(g.streamStatus(stream, config, job, req.GetReplayEvents()))
+}
+
+// streamStatus sends the job, then new events and state changes until the job has finished
+func (g *jobService) streamStatus(stream grpc.ServerStreamingServer[aetherv1.StatusUpdate], config models.ProjectConfig, job *models.PipelineJob, replay bool) error {
+	sent := 0
+	if !replay {
+		events, err := services.LoadJobEvents(config.JobsDir, job.JobID)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		sent = len(events)
+	}
+	if err := stream.Send(&aetherv1.StatusUpdate{Update: &aetherv1.StatusUpdate_Job{Job: jobToProto(models.NewJobStatusView(*job))}}); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+	for {
+		// A partially written event is skipped by LoadJobEvents and sent on a later poll
+		if events, err := services.LoadJobEvents(config.JobsDir, job.JobID); err == nil {
+			for _, event := range events[min(sent, len(events)):] {
+				if err := stream.Send(&aetherv1.StatusUpdate{Update: &aetherv1.StatusUpdate_Event{Event: eventToProto(event)}}); err != nil {
+					return err
+				}
+			}
+			sent = max(sent, len(events))
+		}
+		if job.Status == models.JobStatusCompleted || job.Status == models.JobStatusFailed {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+
+		current, err := services.LoadJobState(config.JobsDir, job.JobID)
+		if err != nil {
+			g.server.logger.Debug("Failed to reload job state", "job_id", job.JobID, "error", err)
+			continue
+		}
+		if current.Status != job.Status || !current.UpdatedAt.Equal(job.UpdatedAt) {
+			if err := stream.Send(&aetherv1.StatusUpdate{Update: &aetherv1.StatusUpdate_Job{Job: jobToProto(models.NewJobStatusView(*current))}}); err != nil {
+				return err
+			}
+		}
+		job = current
+	}
+}
+
+func (g *jobService) CancelJob(ctx context.Context, req *aetherv1.CancelJobRequest) (*aetherv1.Job, error) {
+	done, err := g.authorize(ctx, models.PermissionControl, req.GetTenant(), req.GetJobId())
+	if err != nil {
+		return nil, err
+	}
+	config, err := g.scope(req.GetTenant())
+	if err != nil {
+		return nil, done(err)
+	}
+	if _, err := g.loadJob(config, req.GetJobId()); err != nil {
+		return nil, done(err)
+	}
+
+	job, err := pipeline.CancelJob(config, req.GetJobId(), req.GetReason(), g.server.logger)
+	switch {
+	case errors.Is(err, pipeline.ErrJobFinished):
+		return nil, done(status.Errorf(codes.FailedPrecondition, "job %s has already %s", req.GetJobId(), job.Status))
+	case err != nil:
+		return nil, done(status.Error(codes.Internal, err.Error()))
+	}
+	return jobToProto(models.NewJobStatusView(*job)), done(nil)
+}
+
+func (g *jobService) ListJobs(ctx context.Context, req *aetherv1.ListJobsRequest) (*aetherv1.ListJobsResponse, error) {
+	done, err := g.authorize(ctx, models.PermissionRead, req.GetTenant(), "")
+	if err != nil {
+		return nil, err
+	}
+	config, err := g.scope(req.GetTenant())
+	if err != nil {
+		return nil, done(err)
+	}
+	views, err := services.ListJobViews(config.JobsDir, g.server.logger)
+	if err != nil {
+		return nil, done(status.Error(codes.Internal, err.Error()))
+	}
+
+	response := &aetherv1.ListJobsResponse{Jobs: make([]*aetherv1.Job, len(views))}
+	for i, view := range views {
+		response.Jobs[i] = jobToProto(view)
+	}
+	return response, done(nil)
+}
+
+// authorize authenticates an RPC from its "authorization" metadata and checks permission and tenant
+// The returned function records the outcome of the call in the audit log and returns its error
+func (g *jobService) authorize(ctx context.Context, permission models.APIPermission, tenant string, jobID string) (func(error) error, error) {
+	authorization := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	method, _ := grpc.Method(ctx)
+	remote := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	caller, err := g.server.authenticate(authorization)
+	done := func(result error) error {
+		g.server.writeAudit(models.AuditEntry{
+			Timestamp: g.server.now(),
+			Principal: caller.name,
+			Role:      caller.role,
+			Method:    "GRPC",
+			Path:      method,
+			Tenant:    tenant,
+			JobID:     jobID,
+			Status:    httpStatus(status.Code(result)),
+			Remote:    remote,
+		})
+		return result
+	}
+
+	if err != nil {
+		return nil, done(status.Error(codes.Unauthenticated, err.Error()))
+	}
+	if err := caller.authorize(permission, tenant); err != nil {
+		return nil, done(status.Error(codes.PermissionDenied, err.Error()))
+	}
+	return done, nil
+}
+
+// scope returns the configuration of a tenant, or NotFound for unknown tenants
+func (g *jobService) scope(tenant string) (models.ProjectConfig, error) {
+	config, err := g.server.config.ForTenant(tenant)
+	if err != nil {
+		return models.ProjectConfig{}, status.Error(codes.NotFound, err.Error())
+	}
+	return config, nil
+}
+
+// loadJob loads a job of a (tenant's) jobs directory, or returns NotFound
+func (g *jobService) loadJob(config models.ProjectConfig, jobID string) (*models.PipelineJob, error) {
+	if _, err := uuid.Parse(jobID); err != nil {
+		return nil, status.Error(codes.NotFound, "job not found: "+jobID)
+	}
+	job, err := services.LoadJobState(config.JobsDir, jobID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return job, nil
+}
+
+// httpStatus maps gRPC codes to the HTTP status recorded in the audit log
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.FailedPrecondition:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499 // Client closed request
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// jobToProto converts the job view shared with the REST API
+func jobToProto(view models.JobStatusView) *aetherv1.Job {
+	job := &aetherv1.Job{
+		JobId:        view.JobID,
+		Status:       string(view.Status),
+		CurrentStep:  view.CurrentStep,
+		CreatedAt:    timestamppb.New(view.CreatedAt),
+		UpdatedAt:    timestamppb.New(view.UpdatedAt),
+		TotalFiles:   int64(view.TotalFiles),
+		TotalBytes:   view.TotalBytes,
+		ErrorMessage: view.ErrorMessage,
+		Steps:        make([]*aetherv1.Step, len(view.Steps)),
+	}
+	for i, step := range view.Steps {
+		job.Steps[i] = &aetherv1.Step{
+			Name:           string(step.Name),
+			Status:         string(step.Status),
+			StartedAt:      optionalTimestamp(step.StartedAt),
+			CompletedAt:    optionalTimestamp(step.CompletedAt),
+			FilesProcessed: int64(step.FilesProcessed),
+			BytesProcessed: step.BytesProcessed,
+			RetryCount:     int64(step.RetryCount),
+			LastError:      step.LastError,
+		}
+	}
+	return job
+}
+
+func eventToProto(event models.JobEvent) *aetherv1.JobEvent {
+	converted := &aetherv1.JobEvent{
+		Timestamp: timestamppb.New(event.Timestamp),
+		Type:      string(event.Type),
+		Step:      event.Step,
+		Message:   event.Message,
+	}
+	// Fields decoded from events.ndjson only hold JSON types, which Struct always accepts
+	if fields, err := structpb.NewStruct(event.Fields); err == nil && len(event.Fields) > 0 {
+		converted.Fields = fields
+	}
+	return converted
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
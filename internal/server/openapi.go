@@ -0,0 +1,212 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// APIVersion is the version of the REST API contract in /openapi.json
+// Bump it when endpoints or response schemas change
+const APIVersion = "1.0.0"
+
+// pathParamPattern matches {name} and {name...} wildcards of mux patterns
+var pathParamPattern = regexp.MustCompile(`\{([a-z]+)(\.\.\.)?\}`)
+
+// enumValues lists the values of string types that are enums in the API
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeFor[models.JobStatus](): {
+		string(models.JobStatusPending), string(models.JobStatusInProgress),
+		string(models.JobStatusCompleted), string(models.JobStatusFailed),
+	},
+	reflect.TypeFor[models.StepStatus](): {
+		string(models.StepStatusPending), string(models.StepStatusInProgress),
+		string(models.StepStatusCompleted), string(models.StepStatusFailed),
+		string(models.StepStatusCancelled),
+	},
+	reflect.TypeFor[models.StepName](): stepNameValues(),
+}
+
+// schemaNames renames types whose Go name is not a good schema name
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeFor[errorResponse](): "Error",
+}
+
+func stepNameValues() []string {
+	values := make([]string, len(models.AllStepNames))
+	for i, name := range models.AllStepNames {
+		values[i] = string(name)
+	}
+	return values
+}
+
+// buildOpenAPI describes the route table as an OpenAPI 3 document
+// Response schemas are derived from the Go types the handlers encode, so the document
+// cannot drift from what the API returns
+func buildOpenAPI(routes []route, publicURL string) map[string]any {
+	schemas := schemaBuilder{components: map[string]any{}}
+	errorRef := schemas.schema(reflect.TypeFor[errorResponse]())
+
+	paths := map[string]any{}
+	for _, r := range routes {
+		path := pathParamPattern.ReplaceAllString(r.path, "{$1}")
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[path] = item
+		}
+
+		var parameters []any
+		for _, match := range pathParamPattern.FindAllStringSubmatch(r.path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, q := range r.query {
+			parameters = append(parameters, map[string]any{
+				"name": q.name, "in": "query", "description": q.description, "schema": map[string]any{"type": "string"},
+			})
+		}
+
+		success := map[string]any{"description": http.StatusText(r.status)}
+		if r.response != nil {
+			success["content"] = jsonContent(schemas.schema(reflect.TypeOf(r.response)))
+		} else {
+			success["content"] = map[string]any{
+				"application/octet-stream": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+			}
+		}
+
+		operation := map[string]any{
+			"operationId": r.operationID,
+			"summary":     r.summary,
+			"responses": map[string]any{
+				strconv.Itoa(r.status): success,
+				"default":              map[string]any{"description": "Error", "content": jsonContent(errorRef)},
+			},
+		}
+		if parameters != nil {
+			operation["parameters"] = parameters
+		}
+		if r.permission == "" {
+			operation["tags"] = []string{"share"}
+			operation["security"] = []any{}
+		} else {
+			operation["tags"] = []string{"jobs"}
+			operation["description"] = "Requires a token with role " + string(minimumRole(r.permission)) + " or higher."
+			operation["x-aether-permission"] = string(r.permission)
+		}
+		item[strings.ToLower(r.method)] = operation
+	}
+
+	document := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Aether API",
+			"version":     APIVersion,
+			"description": "Job status and share links of an aether jobs directory (aether serve).",
+		},
+		"paths":    paths,
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	if publicURL != "" {
+		document["servers"] = []any{map[string]any{"url": strings.TrimSuffix(publicURL, "/")}}
+	}
+	return document
+}
+
+// minimumRole returns the least privileged role holding a permission
+func minimumRole(permission models.APIPermission) models.APIRole {
+	for _, role := range []models.APIRole{models.RoleViewer, models.RoleSubmitter, models.RoleOperator} {
+		if role.Allows(permission) {
+			return role
+		}
+	}
+	return models.RoleOperator
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// schemaBuilder derives JSON schemas from Go types, collecting named structs as components
+type schemaBuilder struct {
+	components map[string]any
+}
+
+// schema returns the schema of t; structs are referenced by name
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if values, ok := enumValues[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Struct:
+		name := schemaNames[t]
+		if name == "" {
+			name = t.Name()
+		}
+		if _, done := b.components[name]; !done {
+			b.components[name] = nil // Reserve the name for recursive types
+			b.components[name] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+// object returns the schema of a struct from its exported fields and json tags
+// Fields without omitempty are required
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
@@ -0,0 +1,340 @@
+// Package server implements `aether serve`, a REST API over the jobs directory
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// Server serves job status over HTTP
+// Jobs are read from disk on every request, so the API reflects jobs run by any aether process
+type Server struct {
+	config   models.ProjectConfig
+	logger   *lib.Logger
+	now      func() time.Time
+	mux      *http.ServeMux
+	openAPI  map[string]any
+	launcher Launcher // Runs jobs submitted through the gRPC API (nil = submission disabled)
+}
+
+// New creates a server for the jobs directory of config
+func New(config models.ProjectConfig, logger *lib.Logger) *Server {
+	s := &Server{
+		config: config,
+		logger: logger,
+		now:    time.Now,
+		mux:    http.NewServeMux(),
+	}
+	s.routes()
+	return s
+}
+
+// route describes one endpoint; the route table drives both the mux and /openapi.json
+type route struct {
+	method      string
+	path        string
+	permission  models.APIPermission // Empty for share-link routes, which are authorized by their token
+	operationID string
+	summary     string
+	query       []queryParam
+	status      int // Status of a successful response
+	response    any // Body of a successful response (nil = file download)
+	handler     http.HandlerFunc
+}
+
+// queryParam documents an optional query parameter of a route
+type queryParam struct {
+	name        string
+	description string
+}
+
+// routeTable lists all endpoints with the permission they require
+// Job endpoints exist once for the default jobs directory and once per tenant
+func (s *Server) routeTable() []route {
+	var routes []route
+	for _, scope := range []struct{ prefix, suffix, summary string }{
+		{"/api/v1", "", ""},
+		{"/api/v1/tenants/{tenant}", "ForTenant", " of a tenant"},
+	} {
+		routes = append(routes,
+			route{method: "GET", path: scope.prefix + "/jobs", permission: models.PermissionRead,
+				operationID: "listJobs" + scope.suffix, summary: "List jobs" + scope.summary + ", newest first",
+				status: http.StatusOK, response: []models.JobStatusView{}, handler: s.handleListJobs},
+			route{method: "GET", path: scope.prefix + "/jobs/{id}", permission: models.PermissionRead,
+				operationID: "getJob" + scope.suffix, summary: "Status of one job" + scope.summary,
+				status: http.StatusOK, response: models.JobStatusView{}, handler: s.handleGetJob},
+			route{method: "POST", path: scope.prefix + "/jobs/{id}/share", permission: models.PermissionShare,
+				operationID: "createShareLink" + scope.suffix, summary: "Create a read-only share link to a job" + scope.summary,
+				query:  []queryParam{{"ttl", "Validity of the link, e.g. 24h (default: server.share_ttl_hours)"}},
+				status: http.StatusCreated, response: ShareLink{}, handler: s.handleCreateShare},
+		)
+	}
+	return append(routes,
+		route{method: "GET", path: "/api/v1/tenants", permission: models.PermissionRead,
+			operationID: "listTenants", summary: "Names of the configured tenants",
+			status: http.StatusOK, response: []string{}, handler: s.handleListTenants},
+		route{method: "GET", path: "/share/{token}",
+			operationID: "getSharedJob", summary: "Job status via share link",
+			status: http.StatusOK, response: models.JobStatusView{}, handler: s.handleSharedStatus},
+		route{method: "GET", path: "/share/{token}/artifacts/{name...}",
+			operationID: "getSharedArtifact", summary: "Report file via share link, e.g. csv/data_dictionary.csv",
+			status: http.StatusOK, handler: s.handleSharedArtifact},
+	)
+}
+
+// routes registers the route table and the OpenAPI document describing it
+func (s *Server) routes() {
+	table := s.routeTable()
+	for _, r := range table {
+		pattern := r.method + " " + r.path
+		if r.permission == "" {
+			s.handleShared(pattern, r.handler)
+		} else {
+			s.handle(pattern, r.permission, r.handler)
+		}
+	}
+	s.openAPI = buildOpenAPI(table, s.config.Server.PublicURL)
+
+	// The API description is public, like the API's existence itself
+	s.mux.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+}
+
+// Handler returns the HTTP handler of the server
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe serves the REST API on server.listen, and the gRPC API on server.grpc_listen
+// if configured, until a listener fails
+func (s *Server) ListenAndServe() error {
+	httpServer := &http.Server{
+		Addr:              s.config.Server.GetListen(),
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if !s.config.Server.AuthEnabled() {
+		s.logger.Warn("No server.tokens configured - the API is read-only: jobs cannot be submitted, cancelled or shared")
+	}
+
+	errs := make(chan error, 2)
+	if s.config.Server.GRPCEnabled() {
+		if err := s.config.Server.CheckGRPCTransport(); err != nil {
+			return err
+		}
+		grpcServer, err := s.GRPCServer()
+		if err != nil {
+			return err
+		}
+		listener, err := net.Listen("tcp", s.config.Server.GRPCListen)
+		if err != nil {
+			return fmt.Errorf("failed to listen on server.grpc_listen: %w", err)
+		}
+		s.logger.Info("Serving aether gRPC API", "listen", listener.Addr().String(), "tls", s.config.Server.GRPCTLSEnabled())
+		go func() { errs <- grpcServer.Serve(listener) }()
+	}
+
+	s.logger.Info("Serving aether API", "listen", httpServer.Addr, "jobs_dir", s.config.JobsDir)
+	go func() { errs <- httpServer.ListenAndServe() }()
+	return <-errs
+}
+
+// ShareLink is the response of the share endpoint
+type ShareLink struct {
+	JobID     string    `json:"job_id"`
+	URL       string    `json:"url"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateShareLink signs a read-only link to a job's status that expires after ttl
+// A ttl of 0 uses server.share_ttl_hours
+func CreateShareLink(config models.ProjectConfig, jobID string, ttl time.Duration, now time.Time) (ShareLink, error) {
+	if !config.Server.SharingEnabled() {
+		return ShareLink{}, errors.New("sharing is disabled: server.share_secret is not configured")
+	}
+	if config.Server.PublicURL == "" {
+		return ShareLink{}, errors.New("server.public_url is required to create share links")
+	}
+	if ttl <= 0 {
+		ttl = config.Server.GetShareTTL()
+	}
+	expires := now.Add(ttl).Truncate(time.Second)
+	token, err := services.SignShareToken(config.Server.ShareSecret, config.Tenant, jobID, expires)
+	if err != nil {
+		return ShareLink{}, err
+	}
+	return ShareLink{
+		JobID:     jobID,
+		URL:       services.ShareURL(config.Server.PublicURL, token),
+		Token:     token,
+		ExpiresAt: expires,
+	}, nil
+}
+
+// OpenAPI returns the OpenAPI 3 document of the server's endpoints
+func (s *Server) OpenAPI() map[string]any {
+	return s.openAPI
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.openAPI)
+}
+
+func (s *Server) handleListTenants(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.config.Tenants))
+	for _, tenant := range s.config.Tenants {
+		names = append(names, tenant.Name)
+	}
+	s.writeJSON(w, http.StatusOK, names)
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	config, ok := s.scope(w, r.PathValue("tenant"))
+	if !ok {
+		return
+	}
+	views, err := services.ListJobViews(config.JobsDir, s.logger)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	config, ok := s.scope(w, r.PathValue("tenant"))
+	if !ok {
+		return
+	}
+	job, ok := s.loadJob(w, config, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, models.NewJobStatusView(*job))
+}
+
+func (s *Server) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	config, ok := s.scope(w, r.PathValue("tenant"))
+	if !ok {
+		return
+	}
+	job, ok := s.loadJob(w, config, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	var ttl time.Duration
+	if value := r.URL.Query().Get("ttl"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			s.writeError(w, http.StatusBadRequest, errors.New("ttl must be a positive duration (e.g. 24h)"))
+			return
+		}
+		ttl = parsed
+	}
+
+	link, err := CreateShareLink(config, job.JobID, ttl, s.now())
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.logger.Info("Created share link", "job_id", job.JobID, "tenant", config.Tenant, "expires_at", link.ExpiresAt)
+	s.writeJSON(w, http.StatusCreated, link)
+}
+
+func (s *Server) handleSharedStatus(w http.ResponseWriter, r *http.Request) {
+	config, jobID, ok := s.verifyShare(w, r.PathValue("token"))
+	if !ok {
+		return
+	}
+	job, ok := s.loadJob(w, config, jobID)
+	if !ok {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, models.NewJobStatusView(*job))
+}
+
+func (s *Server) handleSharedArtifact(w http.ResponseWriter, r *http.Request) {
+	config, jobID, ok := s.verifyShare(w, r.PathValue("token"))
+	if !ok {
+		return
+	}
+	path, err := services.ResolveShareArtifact(config.JobsDir, jobID, r.PathValue("name"))
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		s.writeError(w, http.StatusNotFound, errors.New("artifact not available yet"))
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// scope returns the configuration of a tenant (or the default one for an empty name)
+// and writes 404 for unknown tenants
+func (s *Server) scope(w http.ResponseWriter, tenant string) (models.ProjectConfig, bool) {
+	config, err := s.config.ForTenant(tenant)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return models.ProjectConfig{}, false
+	}
+	return config, true
+}
+
+// verifyShare checks a share token and writes 403/410 if it does not grant access
+// Returns the configuration of the job's tenant and the job ID
+func (s *Server) verifyShare(w http.ResponseWriter, token string) (models.ProjectConfig, string, bool) {
+	grant, err := services.VerifyShareToken(s.config.Server.ShareSecret, token, s.now())
+	switch {
+	case errors.Is(err, services.ErrShareExpired):
+		s.writeError(w, http.StatusGone, err)
+		return models.ProjectConfig{}, "", false
+	case err != nil:
+		s.writeError(w, http.StatusForbidden, err)
+		return models.ProjectConfig{}, "", false
+	}
+	config, ok := s.scope(w, grant.Tenant)
+	return config, grant.JobID, ok
+}
+
+// loadJob loads a job by ID from a (tenant's) jobs directory and writes 404 if it does not exist
+func (s *Server) loadJob(w http.ResponseWriter, config models.ProjectConfig, jobID string) (*models.PipelineJob, bool) {
+	if _, err := uuid.Parse(jobID); err != nil {
+		s.writeError(w, http.StatusNotFound, errors.New("job not found: "+jobID))
+		return nil, false
+	}
+	job, err := services.LoadJobState(config.JobsDir, jobID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	return job, true
+}
+
+// errorResponse is the JSON body of all error responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.Debug("Failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+const (
+	// AuditFileName is the append-only log of REST API actions in jobs_dir
+	AuditFileName = "audit.ndjson"
+)
+
+// GetAuditFilePath returns the full path to the API audit log
+func GetAuditFilePath(jobsBaseDir string) string {
+	return filepath.Join(jobsBaseDir, AuditFileName)
+}
+
+// AppendAuditEntry appends a single API action to <jobs_dir>/audit.ndjson
+func AppendAuditEntry(jobsBaseDir string, entry models.AuditEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = lib.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	// O_APPEND keeps each entry on its own line even if requests are handled concurrently
+	file, err := os.OpenFile(GetAuditFilePath(jobsBaseDir), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return file.Close()
+}
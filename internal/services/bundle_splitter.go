@@ -25,6 +25,8 @@
 //   - Partitioning: O(m) where m = number of entries (single pass greedy scan)
 //   - Reassembly: O(m) concatenation of entry arrays
 //   - Memory: O(Bundle size) - all data structures fit in memory for chunks <10MB
+//   - Streaming (bundle_stream.go): O(largest chunk) beyond the input itself, for Bundles too
+//     large to decode as a whole
 //
 // Example Usage:
 //
@@ -51,6 +53,10 @@ import (
 	"github.com/trobanga/aether/internal/models"
 )
 
+// bundleOverheadBytes is the Bundle wrapper overhead (approximate fixed cost per chunk)
+// Includes: resourceType, id, type, timestamp, total fields
+const bundleOverheadBytes = 200
+
 // ShouldSplit determines if a Bundle exceeds the threshold and requires splitting
 // Pure function: Takes bundle size and threshold, returns boolean decision
 //
@@ -98,10 +104,6 @@ func PartitionEntries(entries []map[string]any, thresholdBytes int) ([][]map[str
 	currentPartition := []map[string]any{}
 	currentSize := 0
 
-	// Bundle wrapper overhead (approximate fixed cost per chunk)
-	// Includes: resourceType, id, type, timestamp, total fields
-	const bundleOverheadBytes = 200
-
 	for i, entry := range entries {
 		// Calculate size of this entry
 		entrySize, err := models.CalculateJSONSize(entry)
@@ -111,33 +113,7 @@ func PartitionEntries(entries []map[string]any, thresholdBytes int) ([][]map[str
 
 		// Check if single entry exceeds threshold (cannot be split)
 		if entrySize+bundleOverheadBytes > thresholdBytes {
-			// Extract resource info for error message
-			resourceType := "Unknown"
-			resourceID := "unknown"
-
-			if resource, ok := entry["resource"].(map[string]any); ok {
-				if rt, ok := resource["resourceType"].(string); ok {
-					resourceType = rt
-				}
-				if id, ok := resource["id"].(string); ok {
-					resourceID = id
-				}
-			}
-
-			guidance := fmt.Sprintf(
-				"This entry contains a %s resource that cannot be split. "+
-					"Solutions: (1) Review data quality - resource may contain unnecessary data; "+
-					"(2) Increase DIMP server payload limit; (3) Increase bundle_split_threshold_mb configuration.",
-				resourceType,
-			)
-
-			return nil, &models.OversizedResourceError{
-				ResourceType: resourceType,
-				ResourceID:   resourceID,
-				Size:         entrySize,
-				Threshold:    thresholdBytes,
-				Guidance:     guidance,
-			}
+			return nil, oversizedEntryError(entry, entrySize, thresholdBytes)
 		}
 
 		// Check if adding this entry would exceed threshold
@@ -161,6 +137,38 @@ func PartitionEntries(entries []map[string]any, thresholdBytes int) ([][]map[str
 	return partitions, nil
 }
 
+// oversizedEntryError describes a Bundle entry of entrySize bytes that does not fit into a
+// chunk of thresholdBytes on its own
+func oversizedEntryError(entry map[string]any, entrySize int, thresholdBytes int) *models.OversizedResourceError {
+	// Extract resource info for error message
+	resourceType := "Unknown"
+	resourceID := "unknown"
+
+	if resource, ok := entry["resource"].(map[string]any); ok {
+		if rt, ok := resource["resourceType"].(string); ok {
+			resourceType = rt
+		}
+		if id, ok := resource["id"].(string); ok {
+			resourceID = id
+		}
+	}
+
+	guidance := fmt.Sprintf(
+		"This entry contains a %s resource that cannot be split. "+
+			"Solutions: (1) Review data quality - resource may contain unnecessary data; "+
+			"(2) Increase DIMP server payload limit; (3) Increase bundle_split_threshold_mb configuration.",
+		resourceType,
+	)
+
+	return &models.OversizedResourceError{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Size:         entrySize,
+		Threshold:    thresholdBytes,
+		Guidance:     guidance,
+	}
+}
+
 // SplitBundle splits a large FHIR Bundle into smaller chunks for processing
 // Pure function: Takes Bundle and threshold, returns SplitResult (no side effects)
 //
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// Streaming Bundle splitting:
+// SplitBundleStream reads a Bundle with a json.Decoder token by token and hands out one chunk
+// at a time, so only the current chunk is held as decoded JSON instead of the whole Bundle.
+// BundleStreamWriter writes the entries of the pseudonymized chunks to the output as they come
+// back, instead of reassembling the Bundle in memory first. Chunks and the reassembled Bundle
+// have the same shape as with SplitBundle and ReassembleBundle; only the order of the
+// Bundle-level fields differs
+
+// ErrNotBundle is returned by SplitBundleStream for JSON objects that are not a FHIR Bundle
+var ErrNotBundle = errors.New("resource is not a Bundle")
+
+// BundleStreamResult summarizes a Bundle split by SplitBundleStream
+type BundleStreamResult struct {
+	Metadata models.BundleMetadata // Bundle id, type and timestamp
+	Entries  int                   // Entries read
+	Chunks   int                   // Chunks emitted
+}
+
+// bundleStreamHeader holds the Bundle-level fields needed to build chunks
+type bundleStreamHeader struct {
+	resourceType string
+	id           string
+	bundleType   string
+	timestamp    string
+}
+
+// complete reports whether chunks can be built, i.e. whether the fields read so far identify a Bundle
+func (h bundleStreamHeader) complete() bool {
+	return h.resourceType == "Bundle" && h.id != "" && h.bundleType != ""
+}
+
+// metadata returns the Bundle metadata (see models.ExtractBundleMetadata)
+func (h bundleStreamHeader) metadata() models.BundleMetadata {
+	metadata := models.BundleMetadata{ID: h.id, Type: h.bundleType}
+	if parsed, err := time.Parse(time.RFC3339, h.timestamp); err == nil {
+		metadata.Timestamp = parsed
+	}
+	return metadata
+}
+
+// SplitBundleStream reads a FHIR Bundle from r and calls emit for each chunk of its entries, in
+// order, as soon as the chunk is complete. Chunks are partitioned greedily like SplitBundle;
+// their TotalChunks is 0, as the number of chunks is only known at the end
+// An entry that does not fit into a chunk on its own fails with models.OversizedResourceError,
+// unless allowOversized is set, which sends such an entry as a chunk of its own
+// Returns ErrNotBundle (before emit is called) if r holds another resource. If the Bundle lists
+// its entries before its id and type, r is read twice
+func SplitBundleStream(r io.ReadSeeker, thresholdBytes int, allowOversized bool, emit func(models.BundleChunk) error) (BundleStreamResult, error) {
+	var header bundleStreamHeader
+	s := &bundleStreamSplitter{threshold: thresholdBytes, allowOversized: allowOversized, emit: emit}
+
+	streamed, err := s.read(json.NewDecoder(r), &header, false)
+	if err != nil {
+		return BundleStreamResult{}, err
+	}
+	if !streamed {
+		// The entries came before the fields the chunks need; read them again
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return BundleStreamResult{}, fmt.Errorf("failed to rewind Bundle: %w", err)
+		}
+		if _, err := s.read(json.NewDecoder(r), &header, true); err != nil {
+			return BundleStreamResult{}, err
+		}
+	}
+
+	if s.entries == 0 {
+		return BundleStreamResult{}, fmt.Errorf("cannot partition empty entry array")
+	}
+	return BundleStreamResult{Metadata: header.metadata(), Entries: s.entries, Chunks: s.index}, nil
+}
+
+// bundleStreamSplitter partitions the entries of one Bundle into chunks
+type bundleStreamSplitter struct {
+	threshold      int
+	allowOversized bool
+	emit           func(models.BundleChunk) error
+
+	metadata models.BundleMetadata
+	pending  []json.RawMessage // Entries of the chunk being filled
+	size     int               // Bytes of the pending entries
+	entries  int               // Entries read
+	index    int               // Chunks emitted
+}
+
+// read walks the top-level fields of the Bundle. The entry array is streamed into chunks once
+// resourceType, id and type are known, and skipped otherwise; returns whether it was streamed
+// With headerKnown, the header was read by an earlier pass and its fields are skipped
+func (s *bundleStreamSplitter) read(dec *json.Decoder, header *bundleStreamHeader, headerKnown bool) (bool, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return false, err
+	}
+
+	streamed := false
+	entrySeen := false
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return false, fmt.Errorf("invalid Bundle: %w", err)
+		}
+		key, _ := token.(string)
+
+		switch {
+		case key == "entry":
+			entrySeen = true
+			if !header.complete() {
+				if header.resourceType != "" && header.resourceType != "Bundle" {
+					return false, ErrNotBundle
+				}
+				if err := skipValue(dec); err != nil {
+					return false, err
+				}
+				continue
+			}
+			if err := s.streamEntries(dec, header.metadata()); err != nil {
+				return false, err
+			}
+			streamed = true
+		case headerKnown:
+			if err := skipValue(dec); err != nil {
+				return false, err
+			}
+		default:
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return false, fmt.Errorf("invalid Bundle: %w", err)
+			}
+			var text string
+			_ = json.Unmarshal(value, &text)
+			switch key {
+			case "resourceType":
+				if text != "Bundle" {
+					return false, ErrNotBundle
+				}
+				header.resourceType = text
+			case "id":
+				header.id = text
+			case "type":
+				header.bundleType = text
+			case "timestamp":
+				header.timestamp = text
+			}
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return false, err
+	}
+
+	switch {
+	case header.resourceType != "Bundle":
+		return false, ErrNotBundle
+	case header.id == "":
+		return false, fmt.Errorf("invalid Bundle structure: bundle.id must be present and a string")
+	case header.bundleType == "":
+		return false, fmt.Errorf("invalid Bundle structure: bundle.type must be present and a string")
+	case !entrySeen:
+		return false, fmt.Errorf("failed to extract entries: bundle.entry must be an array")
+	}
+	return streamed || headerKnown, nil
+}
+
+// streamEntries reads the entry array and emits its entries in chunks
+func (s *bundleStreamSplitter) streamEntries(dec *json.Decoder, metadata models.BundleMetadata) error {
+	s.metadata = metadata
+	if err := expectDelim(dec, '['); err != nil {
+		return fmt.Errorf("failed to extract entries: bundle.entry must be an array")
+	}
+
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("invalid Bundle entry %d: %w", s.entries, err)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return fmt.Errorf("invalid Bundle entry %d: %w", s.entries, err)
+		}
+		entry := json.RawMessage(compact.Bytes())
+		if len(entry) == 0 || entry[0] != '{' {
+			return fmt.Errorf("failed to extract entries: bundle.entry[%d] must be an object", s.entries)
+		}
+		if err := s.add(entry); err != nil {
+			return err
+		}
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// add appends an entry to the pending chunk, emitting the chunk first if the entry does not fit
+func (s *bundleStreamSplitter) add(entry json.RawMessage) error {
+	s.entries++
+	entrySize := len(entry)
+
+	if entrySize+bundleOverheadBytes > s.threshold {
+		if !s.allowOversized {
+			var decoded map[string]any
+			_ = json.Unmarshal(entry, &decoded)
+			return fmt.Errorf("failed to partition entries: %w", oversizedEntryError(decoded, entrySize, s.threshold))
+		}
+		// Sent on its own: it cannot be split any further
+		if err := s.flush(); err != nil {
+			return err
+		}
+		s.pending = append(s.pending, entry)
+		s.size = entrySize
+		return s.flush()
+	}
+
+	if len(s.pending) > 0 && s.size+entrySize+bundleOverheadBytes > s.threshold {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	s.pending = append(s.pending, entry)
+	s.size += entrySize
+	return nil
+}
+
+// flush emits the pending entries as a chunk
+func (s *bundleStreamSplitter) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	entries := make([]map[string]any, 0, len(s.pending))
+	for _, raw := range s.pending {
+		var entry map[string]any
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return fmt.Errorf("invalid Bundle entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	chunk := models.BundleChunk{
+		ChunkID:       fmt.Sprintf("%s-chunk-%d", s.metadata.ID, s.index),
+		Index:         s.index,
+		OriginalID:    s.metadata.ID,
+		Metadata:      s.metadata,
+		Entries:       entries,
+		EstimatedSize: s.size + len(s.pending) - 1 + bundleOverheadBytes,
+	}
+	s.pending = s.pending[:0]
+	s.size = 0
+	s.index++
+	return s.emit(chunk)
+}
+
+// expectDelim reads the next token and checks that it is delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid Bundle: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("invalid Bundle: expected '%s', got %v", delim, token)
+	}
+	return nil
+}
+
+// skipValue reads past the next JSON value token by token, without holding it in memory
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("invalid Bundle: %w", err)
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// BundleStreamWriter reassembles pseudonymized Bundle chunks into one Bundle written straight to
+// w: the Bundle-level fields of the first chunk, then the entries of every chunk as they are
+// written. The result matches ReassembleBundle (type, timestamp and total as in the original)
+type BundleStreamWriter struct {
+	w         io.Writer
+	chunks    int
+	entries   int
+	timestamp json.RawMessage // Timestamp of the first chunk, kept if the original has none
+}
+
+// NewBundleStreamWriter returns a writer reassembling a Bundle into w
+func NewBundleStreamWriter(w io.Writer) *BundleStreamWriter {
+	return &BundleStreamWriter{w: w}
+}
+
+// WriteChunk appends the entries of the next pseudonymized chunk
+func (bw *BundleStreamWriter) WriteChunk(chunk map[string]any) error {
+	if resourceType, ok := chunk["resourceType"].(string); !ok || resourceType != "Bundle" {
+		if bw.chunks == 0 {
+			return fmt.Errorf("first chunk is not a Bundle")
+		}
+		return fmt.Errorf("chunk %d is not a Bundle", bw.chunks)
+	}
+	entries, err := models.ExtractEntriesFromBundle(chunk)
+	if err != nil {
+		return fmt.Errorf("failed to extract entries from chunk %d: %w", bw.chunks, err)
+	}
+
+	if bw.chunks == 0 {
+		if err := bw.writeHeader(chunk); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal entry: %w", err)
+		}
+		if bw.entries > 0 {
+			data = append([]byte{','}, data...)
+		}
+		if _, err := bw.w.Write(data); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		bw.entries++
+	}
+	bw.chunks++
+	return nil
+}
+
+// writeHeader writes the Bundle-level fields of the first chunk and opens the entry array
+func (bw *BundleStreamWriter) writeHeader(chunk map[string]any) error {
+	keys := make([]string, 0, len(chunk))
+	for key := range chunk {
+		switch key {
+		case "entry", "type", "total":
+		case "timestamp":
+			bw.timestamp, _ = json.Marshal(chunk[key])
+		default:
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, key := range keys {
+		value, err := json.Marshal(chunk[key])
+		if err != nil {
+			return fmt.Errorf("failed to marshal Bundle field %s: %w", key, err)
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+		buf.WriteByte(',')
+	}
+	buf.WriteString(`"entry":[`)
+	if _, err := bw.w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// Finish closes the Bundle with the original's type and timestamp and, for searchset and
+// history Bundles, the total; returns the number of entries written
+func (bw *BundleStreamWriter) Finish(metadata models.BundleMetadata) (int, error) {
+	if bw.chunks == 0 {
+		return 0, fmt.Errorf("cannot reassemble from empty chunk array")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`],"type":`)
+	bundleType, _ := json.Marshal(metadata.Type)
+	buf.Write(bundleType)
+	switch {
+	case !metadata.Timestamp.IsZero():
+		buf.WriteString(`,"timestamp":"` + metadata.Timestamp.Format("2006-01-02T15:04:05Z07:00") + `"`)
+	case bw.timestamp != nil:
+		buf.WriteString(`,"timestamp":`)
+		buf.Write(bw.timestamp)
+	}
+	if metadata.Type == "searchset" || metadata.Type == "history" {
+		fmt.Fprintf(&buf, `,"total":%d`, bw.entries)
+	}
+	buf.WriteByte('}')
+	if _, err := bw.w.Write(buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to write output: %w", err)
+	}
+	return bw.entries, nil
+}
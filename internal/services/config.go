@@ -3,8 +3,11 @@ package services
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode"
 
 	"github.com/spf13/viper"
 	"github.com/trobanga/aether/internal/models"
@@ -23,38 +26,127 @@ func ExpandEnvVars(s string) string {
 	return expanded
 }
 
+// ConfigEnvVar names the environment variable that selects the configuration file
+const ConfigEnvVar = "AETHER_CONFIG"
+
+// ChaosEnvVar names the environment variable that enables fault injection (see models.ParseChaosSpec)
+// It overrides http_client.chaos, so CI can inject faults without changing the configuration file
+const ChaosEnvVar = "AETHER_CHAOS"
+
+// ConfigSource tells where the path of the loaded configuration file came from
+type ConfigSource string
+
+const (
+	ConfigSourceFlag   ConfigSource = "--config"
+	ConfigSourceEnv    ConfigSource = ConfigEnvVar
+	ConfigSourceSearch ConfigSource = "search path"
+	ConfigSourceNone   ConfigSource = "defaults"
+)
+
+// ConfigSearchPaths returns the files tried, in order, when neither --config nor AETHER_CONFIG is set
+// ~/.config/aether/aether.yaml and /etc/aether/aether.yaml are kept for existing installations
+func ConfigSearchPaths() []string {
+	paths := []string{"aether.yaml"}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		paths = append(paths,
+			filepath.Join(configHome, "aether", "config.yaml"),
+			filepath.Join(configHome, "aether", "aether.yaml"))
+	}
+	return append(paths, filepath.Join("/etc", "aether", "aether.yaml"))
+}
+
+// ResolveConfigFile picks the configuration file to load
+// Discovery order: the --config flag, then AETHER_CONFIG, then the first existing file of
+// ConfigSearchPaths. An explicitly given file is returned even if it does not exist, so
+// loading it fails instead of silently falling back. Returns "" and ConfigSourceNone if
+// no file is found
+func ResolveConfigFile(flagValue string) (string, ConfigSource) {
+	if flagValue != "" {
+		return flagValue, ConfigSourceFlag
+	}
+	if envValue := os.Getenv(ConfigEnvVar); envValue != "" {
+		return envValue, ConfigSourceEnv
+	}
+	for _, path := range ConfigSearchPaths() {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, ConfigSourceSearch
+		}
+	}
+	return "", ConfigSourceNone
+}
+
+// EnvPrefix is the prefix of the environment variables that override configuration keys
+const EnvPrefix = "AETHER"
+
+// envKeyReplacer maps a configuration key to its environment variable name (after the prefix)
+var envKeyReplacer = strings.NewReplacer(".", "_")
+
+// EnvKey returns the environment variable that overrides a configuration key
+// e.g. services.dimp.url is overridden by AETHER_SERVICES_DIMP_URL
+func EnvKey(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// getStringSlice reads a list setting; a list from the environment may be separated by
+// commas or whitespace (AETHER_PIPELINE_ENABLED_STEPS="torch,dimp")
+func getStringSlice(key string) []string {
+	value, ok := os.LookupEnv(EnvKey(key))
+	if !ok || value == "" {
+		return viper.GetStringSlice(key)
+	}
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// stepKeys resolves a step name used as a settings key (sla.step_minutes, ...) to the steps it
+// applies to; "import" sets all import steps. Unknown names are kept for validation to report
+func stepKeys(name string) []models.StepName {
+	if steps, ok := models.ResolveStepName(name); ok {
+		return steps
+	}
+	return []models.StepName{models.StepName(name)}
+}
+
 // LoadConfig loads configuration from file and merges with CLI flags
-// Priority order (highest to lowest):
-//  1. CLI flags (via viper bindings)
+// The file is located with ResolveConfigFile. Priority order (highest to lowest):
+//  1. CLI flags (via SetConfigValue)
 //  2. Environment variables
 //  3. Configuration file
 //  4. Default values
 func LoadConfig(configFile string) (*models.ProjectConfig, error) {
-	// Set config file path if provided
-	if configFile != "" {
-		viper.SetConfigFile(configFile)
-	} else {
-		// Search for config in standard locations
-		viper.SetConfigName("aether")
-		viper.SetConfigType("yaml")
-		viper.AddConfigPath(".")
-		viper.AddConfigPath("$HOME/.config/aether")
-		viper.AddConfigPath("/etc/aether")
-	}
+	configFile, _ = ResolveConfigFile(configFile)
 
-	// Enable environment variable override with AETHER_ prefix
-	viper.SetEnvPrefix("AETHER")
+	// Enable environment variable override with AETHER_ prefix; nested keys use
+	// underscores, e.g. services.torch.password is AETHER_SERVICES_TORCH_PASSWORD
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer)
 	viper.AutomaticEnv()
 
-	// Read config file (optional - don't fail if not found)
-	configFound := true
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			// Config file was found but couldn't be read
+	// Read config file (optional - don't fail if none was found)
+	// "-" reads it from stdin and an http(s) URL fetches it, see readConfigSource
+	configFound := configFile != ""
+	switch {
+	case !configFound:
+	case configFile == ConfigStdin || IsRemoteConfig(configFile):
+		if err := readConfigSource(configFile); err != nil {
+			return nil, err
+		}
+	default:
+		// The type set for stdin or a URL would otherwise stick to viper
+		if ext := strings.TrimPrefix(filepath.Ext(configFile), "."); ext != "" {
+			viper.SetConfigType(ext)
+		}
+		viper.SetConfigFile(configFile)
+		if err := viper.ReadInConfig(); err != nil {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
-		// Config file not found - use defaults only
-		configFound = false
 	}
 
 	// Build config manually from viper values
@@ -66,13 +158,64 @@ func LoadConfig(configFile string) (*models.ProjectConfig, error) {
 				BaseURL:                   ExpandEnvVars(viper.GetString("services.torch.base_url")),
 				Username:                  ExpandEnvVars(viper.GetString("services.torch.username")),
 				Password:                  ExpandEnvVars(viper.GetString("services.torch.password")),
+				AuthMode:                  viper.GetString("services.torch.auth_mode"),
+				TokenURL:                  ExpandEnvVars(viper.GetString("services.torch.token_url")),
+				ClientID:                  ExpandEnvVars(viper.GetString("services.torch.client_id")),
+				ClientSecret:              ExpandEnvVars(viper.GetString("services.torch.client_secret")),
+				Scope:                     viper.GetString("services.torch.scope"),
+				BearerToken:               ExpandEnvVars(viper.GetString("services.torch.bearer_token")),
 				ExtractionTimeoutMinutes:  viper.GetInt("services.torch.extraction_timeout_minutes"),
 				PollingIntervalSeconds:    viper.GetInt("services.torch.polling_interval_seconds"),
 				MaxPollingIntervalSeconds: viper.GetInt("services.torch.max_polling_interval_seconds"),
+				CleanupAfterDownload:      viper.GetBool("services.torch.cleanup_after_download"),
+				MaxActiveExtractions:      viper.GetInt("services.torch.max_active_extractions"),
+				ResultCacheTTLMinutes:     viper.GetInt("services.torch.result_cache_ttl_minutes"),
+				ResponseCacheSeconds:      viper.GetInt("services.torch.response_cache_seconds"),
+				ResponseCacheMaxMB:        viper.GetInt("services.torch.response_cache_max_mb"),
+				SplitPeriod:               viper.GetString("services.torch.split_period"),
+				SplitConcurrency:          viper.GetInt("services.torch.split_concurrency"),
+				FeasibilityURL:            ExpandEnvVars(viper.GetString("services.torch.feasibility_url")),
+				MaxCohortSize:             viper.GetInt("services.torch.max_cohort_size"),
+				CohortSizeAction:          viper.GetString("services.torch.cohort_size_action"),
 			},
 			DIMP: models.DIMPConfig{
-				URL:                    ExpandEnvVars(viper.GetString("services.dimp.url")),
-				BundleSplitThresholdMB: viper.GetInt("services.dimp.bundle_split_threshold_mb"),
+				URL:                     ExpandEnvVars(viper.GetString("services.dimp.url")),
+				BundleSplitThresholdMB:  viper.GetInt("services.dimp.bundle_split_threshold_mb"),
+				SplitByResourceType:     viper.GetBool("services.dimp.split_by_resource_type"),
+				NonFHIRLines:            models.NonFHIRLineMode(viper.GetString("services.dimp.non_fhir_lines")),
+				ProgressIntervalSeconds: viper.GetInt("services.dimp.progress_interval_seconds"),
+				DebugLogFirst:           viper.GetInt("services.dimp.debug_log_first"),
+				DebugLogEvery:           viper.GetInt("services.dimp.debug_log_every"),
+				FileRetryAttempts:       viper.GetInt("services.dimp.file_retry_attempts"),
+				FileRetryBackoffSeconds: viper.GetInt("services.dimp.file_retry_backoff_seconds"),
+				OutputCacheTTLMinutes:   viper.GetInt("services.dimp.output_cache_ttl_minutes"),
+				AdaptiveSplit:           viper.GetBool("services.dimp.adaptive_split"),
+				SplitTargetSeconds:      viper.GetInt("services.dimp.split_target_seconds"),
+				BatchSize:               viper.GetInt("services.dimp.batch_size"),
+				UsageQuota: models.DIMPQuotaConfig{
+					MonthlyResources: viper.GetInt64("services.dimp.usage_quota.monthly_resources"),
+					MonthlyMB:        viper.GetInt64("services.dimp.usage_quota.monthly_mb"),
+					WarnPercent:      viper.GetInt("services.dimp.usage_quota.warn_percent"),
+				},
+			},
+			FHIRExport: models.FHIRExportConfig{
+				Username:                  ExpandEnvVars(viper.GetString("services.fhir_export.username")),
+				Password:                  ExpandEnvVars(viper.GetString("services.fhir_export.password")),
+				BearerToken:               ExpandEnvVars(viper.GetString("services.fhir_export.bearer_token")),
+				Types:                     viper.GetStringSlice("services.fhir_export.types"),
+				TimeoutMinutes:            viper.GetInt("services.fhir_export.timeout_minutes"),
+				PollingIntervalSeconds:    viper.GetInt("services.fhir_export.polling_interval_seconds"),
+				MaxPollingIntervalSeconds: viper.GetInt("services.fhir_export.max_polling_interval_seconds"),
+				CleanupAfterDownload:      viper.GetBool("services.fhir_export.cleanup_after_download"),
+			},
+			S3: models.S3Config{
+				Endpoint:           ExpandEnvVars(viper.GetString("services.s3.endpoint")),
+				Region:             viper.GetString("services.s3.region"),
+				AccessKeyID:        ExpandEnvVars(viper.GetString("services.s3.access_key_id")),
+				SecretAccessKey:    ExpandEnvVars(viper.GetString("services.s3.secret_access_key")),
+				SessionToken:       ExpandEnvVars(viper.GetString("services.s3.session_token")),
+				VirtualHostedStyle: viper.GetBool("services.s3.virtual_hosted_style"),
+				PartSizeMB:         viper.GetInt("services.s3.part_size_mb"),
 			},
 			CSVConversion: models.CSVConversionConfig{
 				URL: ExpandEnvVars(viper.GetString("services.csv_conversion.url")),
@@ -80,19 +223,262 @@ func LoadConfig(configFile string) (*models.ProjectConfig, error) {
 			ParquetConversion: models.ParquetConversionConfig{
 				URL: ExpandEnvVars(viper.GetString("services.parquet_conversion.url")),
 			},
+			HealthCheck: models.HealthCheckConfig{
+				TimeoutSeconds:  viper.GetInt("services.healthcheck.timeout_seconds"),
+				CacheTTLSeconds: viper.GetInt("services.healthcheck.cache_ttl_seconds"),
+			},
+			Imaging: models.ImagingConfig{
+				DICOMwebURL:           ExpandEnvVars(viper.GetString("services.imaging.dicomweb_url")),
+				FailOnMissingMetadata: viper.GetBool("services.imaging.fail_on_missing_metadata"),
+			},
 		},
 		Retry: models.RetryConfig{
 			MaxAttempts:      viper.GetInt("retry.max_attempts"),
 			InitialBackoffMs: viper.GetInt64("retry.initial_backoff_ms"),
 			MaxBackoffMs:     viper.GetInt64("retry.max_backoff_ms"),
 		},
-		JobsDir: ExpandEnvVars(viper.GetString("jobs_dir")),
+		Import: models.ImportConfig{
+			LinkMode: models.ImportLinkMode(viper.GetString("import.link_mode")),
+			Include:  getStringSlice("import.include"),
+			Exclude:  getStringSlice("import.exclude"),
+
+			ResourceTypeDetection:   models.ResourceTypeDetection(viper.GetString("import.resource_type_detection")),
+			ResourceTypeSampleLines: viper.GetInt("import.resource_type_sample_lines"),
+		},
+		Attachments: models.AttachmentConfig{
+			Mode:      models.AttachmentMode(viper.GetString("attachments.mode")),
+			MaxSizeKB: viper.GetInt("attachments.max_size_kb"),
+		},
+		Narrative: models.NarrativeConfig{
+			Mode: models.NarrativeMode(viper.GetString("narrative.mode")),
+		},
+		Extensions: models.ExtensionFilterConfig{
+			Allow: getStringSlice("extensions.allow"),
+			Deny:  getStringSlice("extensions.deny"),
+		},
+		Consent: models.ConsentFilterConfig{
+			Source:  models.ConsentSource(viper.GetString("consent.source")),
+			CSVPath: ExpandEnvVars(viper.GetString("consent.csv_path")),
+			Missing: models.ConsentMissingPolicy(viper.GetString("consent.missing")),
+		},
+		Flatten: models.FlattenConfig{
+			MappingFile: ExpandEnvVars(viper.GetString("flatten.mapping_file")),
+			Observations: models.ObservationPivotConfig{
+				Format:      models.ObservationFormat(viper.GetString("flatten.observations.format")),
+				GroupBy:     getStringSlice("flatten.observations.group_by"),
+				CodeColumn:  viper.GetString("flatten.observations.code_column"),
+				ValueColumn: viper.GetString("flatten.observations.value_column"),
+			},
+		},
+		Limits: models.LimitsConfig{
+			MaxLineSizeMB:    viper.GetInt("limits.max_line_size_mb"),
+			MaxBundleEntries: viper.GetInt("limits.max_bundle_entries"),
+			MaxFiles:         viper.GetInt("limits.max_files"),
+		},
+		Storage: models.StorageConfig{
+			MinFreeMB:              viper.GetInt("storage.min_free_mb"),
+			MinFreeInodes:          viper.GetInt("storage.min_free_inodes"),
+			AllowNetworkFilesystem: viper.GetBool("storage.allow_network_filesystem"),
+		},
+		Heartbeat: models.HeartbeatConfig{
+			IntervalSeconds: viper.GetInt("heartbeat.interval_seconds"),
+			Global:          viper.GetBool("heartbeat.global"),
+		},
+		HTTPClient: models.HTTPClientConfig{
+			UserAgent:  viper.GetString("http_client.user_agent"),
+			RequestIDs: viper.GetBool("http_client.request_ids"),
+			Chaos: models.ChaosConfig{
+				Enabled:      viper.GetBool("http_client.chaos.enabled"),
+				ErrorRate:    viper.GetFloat64("http_client.chaos.error_rate"),
+				TimeoutRate:  viper.GetFloat64("http_client.chaos.timeout_rate"),
+				TruncateRate: viper.GetFloat64("http_client.chaos.truncate_rate"),
+				SlowRate:     viper.GetFloat64("http_client.chaos.slow_rate"),
+				SlowMs:       viper.GetInt("http_client.chaos.slow_ms"),
+				Hosts:        getStringSlice("http_client.chaos.hosts"),
+				Seed:         viper.GetUint64("http_client.chaos.seed"),
+			},
+		},
+		Tracing: models.TracingConfig{
+			Endpoint:    viper.GetString("tracing.endpoint"),
+			Protocol:    models.TracingProtocol(viper.GetString("tracing.protocol")),
+			ServiceName: viper.GetString("tracing.service_name"),
+			SampleRatio: viper.GetFloat64("tracing.sample_ratio"),
+		},
+		JobMetadata: models.JobMetadataConfig{
+			Mode:    models.JobMetadataMode(viper.GetString("job_metadata.mode")),
+			HashKey: ExpandEnvVars(viper.GetString("job_metadata.hash_key")),
+		},
+		Server: models.ServerConfig{
+			Listen:           viper.GetString("server.listen"),
+			PublicURL:        ExpandEnvVars(viper.GetString("server.public_url")),
+			ShareSecret:      ExpandEnvVars(viper.GetString("server.share_secret")),
+			ShareTTLHours:    viper.GetInt("server.share_ttl_hours"),
+			GRPCListen:       viper.GetString("server.grpc_listen"),
+			GRPCTLSCert:      viper.GetString("server.grpc_tls_cert"),
+			GRPCTLSKey:       viper.GetString("server.grpc_tls_key"),
+			AllowedInputDirs: viper.GetStringSlice("server.allowed_input_dirs"),
+		},
+		Output: models.OutputConfig{
+			S3: models.S3OutputConfig{
+				URL: ExpandEnvVars(viper.GetString("output.s3.url")),
+			},
+		},
+		Locale:   viper.GetString("locale"),
+		TimeZone: viper.GetString("time_zone"),
+		JobsDir:  ExpandEnvVars(viper.GetString("jobs_dir")),
+	}
+
+	// Sanity checks are a list of flat structs - safe to unmarshal directly
+	if err := viper.UnmarshalKey("sanity_checks", &config.SanityChecks); err != nil {
+		return nil, fmt.Errorf("invalid sanity_checks: %w", err)
+	}
+
+	// Tenants are a list of flat structs with nested service overrides
+	if err := viper.UnmarshalKey("tenants", &config.Tenants); err != nil {
+		return nil, fmt.Errorf("invalid tenants: %w", err)
+	}
+	for i := range config.Tenants {
+		torch := &config.Tenants[i].Services.TORCH
+		torch.BaseURL = ExpandEnvVars(torch.BaseURL)
+		torch.Username = ExpandEnvVars(torch.Username)
+		torch.Password = ExpandEnvVars(torch.Password)
+		config.Tenants[i].Services.DIMP.URL = ExpandEnvVars(config.Tenants[i].Services.DIMP.URL)
+	}
+	config.Quota = models.QuotaConfig{
+		MaxJobs:       viper.GetInt("quota.max_jobs"),
+		MaxActiveJobs: viper.GetInt("quota.max_active_jobs"),
+	}
+
+	// API tokens are a list of flat structs; token values usually come from the environment
+	if err := viper.UnmarshalKey("server.tokens", &config.Server.Tokens); err != nil {
+		return nil, fmt.Errorf("invalid server.tokens: %w", err)
+	}
+	for i := range config.Server.Tokens {
+		config.Server.Tokens[i].Token = ExpandEnvVars(config.Server.Tokens[i].Token)
 	}
 
-	// Get enabled steps
-	enabledSteps := viper.GetStringSlice("pipeline.enabled_steps")
-	for _, stepStr := range enabledSteps {
-		config.Pipeline.EnabledSteps = append(config.Pipeline.EnabledSteps, models.StepName(stepStr))
+	// WADO rewrites are a list of flat from/to pairs
+	if err := viper.UnmarshalKey("services.imaging.wado_rewrite", &config.Services.Imaging.WADORewrites); err != nil {
+		return nil, fmt.Errorf("invalid services.imaging.wado_rewrite: %w", err)
+	}
+
+	// Pivot codes are a list of flat code/column pairs
+	if err := viper.UnmarshalKey("flatten.observations.codes", &config.Flatten.Observations.Codes); err != nil {
+		return nil, fmt.Errorf("invalid flatten.observations.codes: %w", err)
+	}
+
+	// SLA thresholds are a flat map of step name to minutes
+	var slaMinutes map[string]int
+	if err := viper.UnmarshalKey("sla.step_minutes", &slaMinutes); err != nil {
+		return nil, fmt.Errorf("invalid sla.step_minutes: %w", err)
+	}
+	for name, minutes := range slaMinutes {
+		if config.SLA.StepMinutes == nil {
+			config.SLA.StepMinutes = map[models.StepName]int{}
+		}
+		for _, step := range stepKeys(name) {
+			config.SLA.StepMinutes[step] = minutes
+		}
+	}
+	config.SLA.WebhookURL = ExpandEnvVars(viper.GetString("sla.webhook_url"))
+	config.SLA.WebhookTemplate = viper.GetString("sla.webhook_template")
+	if templateFile := ExpandEnvVars(viper.GetString("sla.webhook_template_file")); templateFile != "" {
+		if config.SLA.WebhookTemplate != "" {
+			return nil, fmt.Errorf("sla.webhook_template and sla.webhook_template_file are mutually exclusive")
+		}
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sla.webhook_template_file: %w", err)
+		}
+		config.SLA.WebhookTemplate = string(data)
+	}
+
+	// Get enabled steps; aliases such as "import" are resolved to canonical step names
+	enabledSteps := getStringSlice("pipeline.enabled_steps")
+	steps, err := models.CanonicalStepNames(enabledSteps)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pipeline.enabled_steps: %w", err)
+	}
+	// "import" only enables TORCH where TORCH is configured, so a local or HTTP setup is not
+	// asked for TORCH settings
+	if slices.Contains(enabledSteps, "import") && config.Services.TORCH.BaseURL == "" {
+		steps = slices.DeleteFunc(steps, func(step models.StepName) bool { return step == models.StepTorchImport })
+	}
+	config.Pipeline.EnabledSteps = steps
+
+	config.Pipeline.MaxRuntimeMinutes = viper.GetInt("pipeline.max_runtime_minutes")
+
+	// Time budget weights are a flat map of step name to relative share
+	config.Pipeline.TimeBudget.TotalMinutes = viper.GetInt("pipeline.time_budget.total_minutes")
+	var budgetWeights map[string]int
+	if err := viper.UnmarshalKey("pipeline.time_budget.weights", &budgetWeights); err != nil {
+		return nil, fmt.Errorf("invalid pipeline.time_budget.weights: %w", err)
+	}
+	for name, weight := range budgetWeights {
+		if config.Pipeline.TimeBudget.Weights == nil {
+			config.Pipeline.TimeBudget.Weights = map[models.StepName]int{}
+		}
+		for _, step := range stepKeys(name) {
+			config.Pipeline.TimeBudget.Weights[step] = weight
+		}
+	}
+
+	// Uploaded steps accept aliases such as "import"
+	for _, name := range getStringSlice("output.s3.steps") {
+		config.Output.S3.Steps = append(config.Output.S3.Steps, stepKeys(name)...)
+	}
+
+	// Post-conditions are a map of step name to output checks
+	var postConditions map[string]models.StepPostCondition
+	if err := viper.UnmarshalKey("pipeline.post_conditions", &postConditions); err != nil {
+		return nil, fmt.Errorf("invalid pipeline.post_conditions: %w", err)
+	}
+	for name, condition := range postConditions {
+		if config.Pipeline.PostConditions == nil {
+			config.Pipeline.PostConditions = map[models.StepName]models.StepPostCondition{}
+		}
+		for _, step := range stepKeys(name) {
+			config.Pipeline.PostConditions[step] = condition
+		}
+	}
+
+	config.Pipeline.DIMP.Parallelism = viper.GetInt("pipeline.dimp.parallelism")
+	config.Pipeline.Strict = viper.GetBool("pipeline.strict")
+	for _, code := range getStringSlice("pipeline.strict_warnings") {
+		config.Pipeline.StrictWarnings = append(config.Pipeline.StrictWarnings, models.WarningCode(code))
+	}
+
+	// Parquet writer options (dictionary encoding is on unless explicitly disabled)
+	config.Pipeline.Packaging.Parquet = models.ParquetOptions{
+		Compression:        models.ParquetCompression(viper.GetString("pipeline.packaging.parquet.compression")),
+		DictionaryEncoding: !viper.IsSet("pipeline.packaging.parquet.dictionary_encoding") || viper.GetBool("pipeline.packaging.parquet.dictionary_encoding"),
+		TargetFileSizeMB:   viper.GetInt("pipeline.packaging.parquet.target_file_size_mb"),
+	}
+	config.Pipeline.Packaging.Mode = models.PackagingMode(viper.GetString("pipeline.packaging.mode"))
+	config.Pipeline.Packaging.TableFormat = models.TableFormat(viper.GetString("pipeline.packaging.table_format"))
+	config.Pipeline.Packaging.ExpectedSchema = models.ExpectedSchemaConfig{
+		URL:    viper.GetString("pipeline.packaging.expected_schema.url"),
+		Format: models.SchemaFormat(viper.GetString("pipeline.packaging.expected_schema.format")),
+	}
+	for _, partition := range getStringSlice("pipeline.packaging.parquet.partition_by") {
+		config.Pipeline.Packaging.Parquet.PartitionBy = append(config.Pipeline.Packaging.Parquet.PartitionBy, models.ParquetPartition(partition))
+	}
+
+	// Tracing headers usually carry an API key, so values may reference environment variables
+	for name, value := range viper.GetStringMapString("tracing.headers") {
+		if config.Tracing.Headers == nil {
+			config.Tracing.Headers = map[string]string{}
+		}
+		config.Tracing.Headers[name] = ExpandEnvVars(value)
+	}
+
+	// AETHER_CHAOS replaces the configured fault injection
+	if spec := os.Getenv(ChaosEnvVar); spec != "" {
+		chaos, err := models.ParseChaosSpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ChaosEnvVar, err)
+		}
+		config.HTTPClient.Chaos = chaos
 	}
 
 	// Apply defaults for missing fields only if config wasn't found
@@ -107,12 +493,33 @@ func LoadConfig(configFile string) (*models.ProjectConfig, error) {
 		if config.Services.DIMP.BundleSplitThresholdMB == 0 {
 			config.Services.DIMP.BundleSplitThresholdMB = defaults.Services.DIMP.BundleSplitThresholdMB
 		}
+		if config.Services.TORCH.ExtractionTimeoutMinutes == 0 {
+			config.Services.TORCH.ExtractionTimeoutMinutes = defaults.Services.TORCH.ExtractionTimeoutMinutes
+		}
+		if config.Services.TORCH.PollingIntervalSeconds == 0 {
+			config.Services.TORCH.PollingIntervalSeconds = defaults.Services.TORCH.PollingIntervalSeconds
+		}
+		if config.Services.TORCH.MaxPollingIntervalSeconds == 0 {
+			config.Services.TORCH.MaxPollingIntervalSeconds = defaults.Services.TORCH.MaxPollingIntervalSeconds
+		}
 		if config.Retry.MaxAttempts == 0 {
 			config.Retry = defaults.Retry
 		}
 		if config.JobsDir == "" {
 			config.JobsDir = defaults.JobsDir
 		}
+		if config.Services.HealthCheck.TimeoutSeconds == 0 {
+			config.Services.HealthCheck.TimeoutSeconds = defaults.Services.HealthCheck.TimeoutSeconds
+		}
+		if !viper.IsSet("services.healthcheck.cache_ttl_seconds") {
+			config.Services.HealthCheck.CacheTTLSeconds = defaults.Services.HealthCheck.CacheTTLSeconds
+		}
+		if config.Limits.MaxLineSizeMB == 0 {
+			config.Limits.MaxLineSizeMB = defaults.Limits.MaxLineSizeMB
+		}
+		if !viper.IsSet("heartbeat.interval_seconds") {
+			config.Heartbeat.IntervalSeconds = defaults.Heartbeat.IntervalSeconds
+		}
 	} else {
 		// Config was loaded, apply defaults only for truly missing values
 		if config.Retry.MaxAttempts == 0 {
@@ -141,6 +548,21 @@ func LoadConfig(configFile string) (*models.ProjectConfig, error) {
 		if config.Services.DIMP.BundleSplitThresholdMB == 0 {
 			config.Services.DIMP.BundleSplitThresholdMB = 10 // 10MB default
 		}
+		// Apply health check defaults (an explicit cache_ttl_seconds: 0 disables caching)
+		if config.Services.HealthCheck.TimeoutSeconds == 0 {
+			config.Services.HealthCheck.TimeoutSeconds = 5
+		}
+		if !viper.IsSet("services.healthcheck.cache_ttl_seconds") {
+			config.Services.HealthCheck.CacheTTLSeconds = 60
+		}
+		// Apply input limit defaults (other limits default to unlimited)
+		if config.Limits.MaxLineSizeMB == 0 {
+			config.Limits.MaxLineSizeMB = models.DefaultMaxLineSizeMB
+		}
+		// Apply heartbeat default (an explicit interval_seconds: 0 disables heartbeats)
+		if !viper.IsSet("heartbeat.interval_seconds") {
+			config.Heartbeat.IntervalSeconds = models.DefaultHeartbeatIntervalSeconds
+		}
 	}
 
 	// Validate the configuration
@@ -148,6 +570,19 @@ func LoadConfig(configFile string) (*models.ProjectConfig, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	// Report mapping file and pivot errors now rather than when the flattening step runs
+	if config.Flatten.MappingFile != "" || config.Flatten.Observations.WantsWide() {
+		mapping, err := LoadFlattenMapping(config.Flatten)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: flatten.mapping_file: %w", err)
+		}
+		if config.Flatten.Observations.WantsWide() {
+			if err := config.Flatten.Observations.ValidateAgainst(mapping.ColumnNames("Observation")); err != nil {
+				return nil, fmt.Errorf("invalid configuration: %w", err)
+			}
+		}
+	}
+
 	// Validate jobs directory exists and is writable
 	if err := models.ValidateJobsDir(config.JobsDir); err != nil {
 		// Try to create it if it doesn't exist
@@ -160,9 +595,30 @@ func LoadConfig(configFile string) (*models.ProjectConfig, error) {
 		}
 	}
 
+	// Hash mode without a configured key uses the installation's key file in jobs_dir
+	if err := EnsureJobMetadataKey(&config.JobMetadata, config.BaseJobsDir()); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// TenantEnvVar names the environment variable that selects a tenant when --tenant is not given
+const TenantEnvVar = "AETHER_TENANT"
+
+// ScopeConfigToTenant returns the configuration of a tenant (see ProjectConfig.ForTenant)
+// and creates the tenant's jobs directory. An empty tenant returns config unchanged
+func ScopeConfigToTenant(config *models.ProjectConfig, tenant string) (*models.ProjectConfig, error) {
+	scoped, err := config.ForTenant(tenant)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.ValidateJobsDir(scoped.JobsDir); err != nil {
+		return nil, err
+	}
+	return &scoped, nil
+}
+
 // GetConfigFilePath returns the path to the config file that was loaded
 func GetConfigFilePath() string {
 	return viper.ConfigFileUsed()
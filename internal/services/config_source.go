@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/trobanga/aether/internal/lib"
+)
+
+// ConfigStdin is the --config value that reads the configuration from standard input
+const ConfigStdin = "-"
+
+// maxRemoteConfigBytes limits the size of a configuration read from stdin or a URL
+const maxRemoteConfigBytes = 1024 * 1024
+
+// remoteConfigTimeout bounds fetching a configuration URL
+const remoteConfigTimeout = 30 * time.Second
+
+// maxConfigRedirects is the number of redirects followed when fetching a configuration URL
+const maxConfigRedirects = 10
+
+// IsRemoteConfig reports whether a --config value is an HTTP(S) URL rather than a file
+func IsRemoteConfig(configFile string) bool {
+	return strings.HasPrefix(configFile, "https://") || strings.HasPrefix(configFile, "http://")
+}
+
+// readConfigSource reads a configuration from stdin ("-") or a URL into viper
+// A URL may pin the content with a #sha256=<hex> fragment; plain http:// URLs must be pinned,
+// since anyone on the network path could otherwise change the configuration. For the same
+// reason an unpinned https:// URL may not redirect to http://
+func readConfigSource(configFile string) error {
+	var data []byte
+	var err error
+	configType := "yaml"
+
+	if configFile == ConfigStdin {
+		data, err = readLimited(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read config from stdin: %w", err)
+		}
+	} else {
+		var location string
+		location, data, err = fetchRemoteConfig(configFile)
+		if err != nil {
+			return err
+		}
+		if ext := strings.TrimPrefix(path.Ext(location), "."); ext == "json" || ext == "toml" {
+			configType = ext
+		}
+	}
+
+	viper.SetConfigType(configType)
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	return nil
+}
+
+// fetchRemoteConfig downloads a configuration URL and verifies its checksum pin
+// Returns the URL path (for the file type) and the content
+func fetchRemoteConfig(rawURL string) (string, []byte, error) {
+	configURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid config URL: %w", err)
+	}
+
+	pin, err := parseConfigPin(configURL.Fragment)
+	if err != nil {
+		return "", nil, err
+	}
+	if configURL.Scheme == "http" && pin == "" {
+		return "", nil, fmt.Errorf("config URL %s uses plain HTTP: pin its content with #sha256=<hex> or use https", configURL.Redacted())
+	}
+	configURL.Fragment = ""
+
+	req, err := http.NewRequest(http.MethodGet, configURL.String(), nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid config URL: %w", err)
+	}
+	req.Header.Set("User-Agent", lib.UserAgent(""))
+
+	client := &http.Client{
+		Timeout: remoteConfigTimeout,
+		// Without a pin only TLS protects the content, so redirects must stay on https
+		CheckRedirect: func(next *http.Request, via []*http.Request) error {
+			if pin == "" && next.URL.Scheme != "https" {
+				return fmt.Errorf("config URL redirects to plain HTTP (%s): pin its content with #sha256=<hex>", next.URL.Redacted())
+			}
+			if len(via) >= maxConfigRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxConfigRedirects)
+			}
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch config: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("failed to fetch config from %s: HTTP %d", configURL.Redacted(), resp.StatusCode)
+	}
+	data, err := readLimited(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch config: %w", err)
+	}
+
+	if pin != "" {
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); actual != pin {
+			return "", nil, fmt.Errorf("config from %s does not match its pinned checksum (sha256 %s, expected %s)", configURL.Redacted(), actual, pin)
+		}
+	}
+	return configURL.Path, data, nil
+}
+
+// parseConfigPin extracts the hex SHA-256 digest of a "sha256=<hex>" URL fragment
+func parseConfigPin(fragment string) (string, error) {
+	if fragment == "" {
+		return "", nil
+	}
+	digest, found := strings.CutPrefix(fragment, "sha256=")
+	if !found {
+		return "", fmt.Errorf("invalid config URL fragment %q: expected sha256=<hex>", fragment)
+	}
+	digest = strings.ToLower(digest)
+	if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid config checksum %q: expected 64 hex characters", digest)
+	}
+	return digest, nil
+}
+
+// readLimited reads r completely, failing if it exceeds maxRemoteConfigBytes
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxRemoteConfigBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRemoteConfigBytes {
+		return nil, fmt.Errorf("config exceeds %d bytes", maxRemoteConfigBytes)
+	}
+	return data, nil
+}
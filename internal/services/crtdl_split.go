@@ -0,0 +1,231 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// crtdlDateLayout is the date format of CRTDL date filters
+const crtdlDateLayout = "2006-01-02"
+
+// CRTDLChunk is the CRTDL of one sub-period of a split extraction
+type CRTDLChunk struct {
+	Label string    // Period, e.g. "2023-Q1", "2023-04" or "2023"
+	Start time.Time // First day of the period within the CRTDL's window
+	End   time.Time // Last day (inclusive)
+	CRTDL []byte
+}
+
+// dateWindow is the date filter of an attribute group
+type dateWindow struct {
+	start, end time.Time
+}
+
+// SplitCRTDL splits the date filters of a CRTDL's attribute groups into calendar periods
+// (services.torch.split_period). Each chunk keeps the cohort definition and the dated
+// attribute groups overlapping its period, with their date filters narrowed to it.
+// Attribute groups without a bounded date filter are extracted once, with the first chunk.
+// Returns nil if the CRTDL has no bounded date filter or its window fits into one period
+func SplitCRTDL(data []byte, period string) ([]CRTDLChunk, error) {
+	windows, err := attributeGroupWindows(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var first, last time.Time
+	for _, window := range windows {
+		if window == nil {
+			continue
+		}
+		if first.IsZero() || window.start.Before(first) {
+			first = window.start
+		}
+		if window.end.After(last) {
+			last = window.end
+		}
+	}
+	if first.IsZero() {
+		return nil, nil
+	}
+
+	var chunks []CRTDLChunk
+	for start := periodStart(first, period); !start.After(last); start = nextPeriod(start, period) {
+		chunk := CRTDLChunk{
+			Label: periodLabel(start, period),
+			Start: maxTime(start, first),
+			End:   minTime(nextPeriod(start, period).AddDate(0, 0, -1), last),
+		}
+		crtdl, empty, err := narrowCRTDL(data, windows, chunk.Start, chunk.End, len(chunks) == 0)
+		if err != nil {
+			return nil, err
+		}
+		if empty {
+			continue // Gap between the date filters of different attribute groups
+		}
+		chunk.CRTDL = crtdl
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) <= 1 {
+		return nil, nil
+	}
+	return chunks, nil
+}
+
+// attributeGroupWindows returns the date filter of every attribute group (nil if unbounded)
+func attributeGroupWindows(data []byte) ([]*dateWindow, error) {
+	groups, _, err := parseAttributeGroups(data)
+	if err != nil {
+		return nil, err
+	}
+
+	windows := make([]*dateWindow, len(groups))
+	for i, group := range groups {
+		filter := dateFilter(group)
+		if filter == nil {
+			continue
+		}
+		startValue, hasStart := filter["start"].(string)
+		endValue, hasEnd := filter["end"].(string)
+		if !hasStart || !hasEnd {
+			continue
+		}
+		start, err := time.Parse(crtdlDateLayout, startValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date filter start '%s' in attribute group %d: %w", startValue, i+1, err)
+		}
+		end, err := time.Parse(crtdlDateLayout, endValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date filter end '%s' in attribute group %d: %w", endValue, i+1, err)
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("date filter of attribute group %d ends before it starts", i+1)
+		}
+		windows[i] = &dateWindow{start: start, end: end}
+	}
+	return windows, nil
+}
+
+// narrowCRTDL returns the CRTDL with its attribute groups restricted to [start, end]
+// Undated groups are kept if withUndated is set. Reports empty if no group remains
+func narrowCRTDL(data []byte, windows []*dateWindow, start, end time.Time, withUndated bool) ([]byte, bool, error) {
+	groups, crtdl, err := parseAttributeGroups(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	kept := make([]any, 0, len(groups))
+	for i, group := range groups {
+		window := windows[i]
+		if window == nil {
+			if withUndated {
+				kept = append(kept, group)
+			}
+			continue
+		}
+		from, to := maxTime(window.start, start), minTime(window.end, end)
+		if from.After(to) {
+			continue
+		}
+		filter := dateFilter(group)
+		filter["start"] = from.Format(crtdlDateLayout)
+		filter["end"] = to.Format(crtdlDateLayout)
+		kept = append(kept, group)
+	}
+	if len(kept) == 0 {
+		return nil, true, nil
+	}
+
+	crtdl["dataExtraction"].(map[string]any)["attributeGroups"] = kept
+	narrowed, err := json.MarshalIndent(crtdl, "", "  ")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal CRTDL: %w", err)
+	}
+	return narrowed, false, nil
+}
+
+// parseAttributeGroups decodes a CRTDL and returns its attribute groups
+func parseAttributeGroups(data []byte) ([]map[string]any, map[string]any, error) {
+	var crtdl map[string]any
+	if err := json.Unmarshal(data, &crtdl); err != nil {
+		return nil, nil, fmt.Errorf("invalid CRTDL JSON: %w", err)
+	}
+	extraction, ok := crtdl["dataExtraction"].(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("CRTDL has no dataExtraction")
+	}
+	rawGroups, _ := extraction["attributeGroups"].([]any)
+
+	groups := make([]map[string]any, 0, len(rawGroups))
+	for i, raw := range rawGroups {
+		group, ok := raw.(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("attribute group %d is not an object", i+1)
+		}
+		groups = append(groups, group)
+	}
+	return groups, crtdl, nil
+}
+
+// dateFilter returns the first filter of type "date" of an attribute group
+func dateFilter(group map[string]any) map[string]any {
+	filters, _ := group["filter"].([]any)
+	for _, raw := range filters {
+		if filter, ok := raw.(map[string]any); ok && filter["type"] == "date" {
+			return filter
+		}
+	}
+	return nil
+}
+
+// periodStart returns the first day of the calendar period containing t
+func periodStart(t time.Time, period string) time.Time {
+	switch period {
+	case models.SplitPeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case models.SplitPeriodQuarter:
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// nextPeriod returns the first day of the period after the one starting at start
+func nextPeriod(start time.Time, period string) time.Time {
+	switch period {
+	case models.SplitPeriodMonth:
+		return start.AddDate(0, 1, 0)
+	case models.SplitPeriodQuarter:
+		return start.AddDate(0, 3, 0)
+	default:
+		return start.AddDate(1, 0, 0)
+	}
+}
+
+// periodLabel names the period starting at start, for file names and logs
+func periodLabel(start time.Time, period string) string {
+	switch period {
+	case models.SplitPeriodMonth:
+		return start.Format("2006-01")
+	case models.SplitPeriodQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	default:
+		return start.Format("2006")
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
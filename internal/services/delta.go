@@ -0,0 +1,449 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeltaLogDirName is the transaction log directory of a Delta table
+const DeltaLogDirName = "_delta_log"
+
+// DeltaColumn is one column of a Delta table schema
+type DeltaColumn struct {
+	Name string
+	Type string // Delta primitive type, e.g. "string", "long", "double", "date"
+}
+
+// DeltaDataFile is a Parquet file to be added to a Delta table
+type DeltaDataFile struct {
+	Path             string            // Relative to the table directory, with forward slashes
+	PartitionValues  map[string]string // Partition column to value
+	Size             int64
+	ModificationTime time.Time
+}
+
+// CollectDeltaDataFiles lists the Parquet files under a table directory
+// Partition values are taken from Hive-style key=value directories
+func CollectDeltaDataFiles(tableDir string) ([]DeltaDataFile, error) {
+	var files []DeltaDataFile
+	err := filepath.WalkDir(tableDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == DeltaLogDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(entry.Name(), ".parquet") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(tableDir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		partitionValues := map[string]string{}
+		for _, segment := range strings.Split(filepath.ToSlash(filepath.Dir(relPath)), "/") {
+			if key, value, ok := strings.Cut(segment, "="); ok {
+				partitionValues[key] = value
+			}
+		}
+
+		files = append(files, DeltaDataFile{
+			Path:             relPath,
+			PartitionValues:  partitionValues,
+			Size:             info.Size(),
+			ModificationTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect Parquet files: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// WriteDeltaCommit records files as the next commit of a Delta table
+// The first commit also writes the protocol and table metadata. A later commit whose columns
+// differ from the table schema evolves it (see MergeDeltaColumns). Commits are created with
+// put-if-absent semantics (hard link), so two concurrent writers cannot produce the same version
+func WriteDeltaCommit(tableDir string, columns []DeltaColumn, partitionColumns []string, files []DeltaDataFile, now time.Time) (int64, error) {
+	return writeDeltaCommit(tableDir, columns, partitionColumns, files, nil, now)
+}
+
+// CommitDeltaTable records the Parquet files of a packaging run as the next version of a table
+// Files that are not part of the table yet are added, and the files of the previous version are
+// removed from it (not from disk), so the new version holds exactly one run while older versions
+// stay readable until they are vacuumed
+func CommitDeltaTable(tableDir string, columns []DeltaColumn, partitionColumns []string, now time.Time) (int64, error) {
+	files, err := CollectDeltaDataFiles(tableDir)
+	if err != nil {
+		return 0, err
+	}
+	live, _, err := readDeltaLog(filepath.Join(tableDir, DeltaLogDirName))
+	if err != nil {
+		return 0, err
+	}
+
+	var added []DeltaDataFile
+	for _, file := range files {
+		if !live[file.Path] {
+			added = append(added, file)
+		}
+	}
+	removed := make([]string, 0, len(live))
+	for path := range live {
+		removed = append(removed, path)
+	}
+	sort.Strings(removed)
+	return writeDeltaCommit(tableDir, columns, partitionColumns, added, removed, now)
+}
+
+// RemoveUncommittedDeltaFiles deletes the Parquet files under a table directory that no commit added,
+// e.g. files of a run that failed before its commit. Files of older versions are kept
+func RemoveUncommittedDeltaFiles(tableDir string) error {
+	files, err := CollectDeltaDataFiles(tableDir)
+	if err != nil {
+		return err
+	}
+	_, committed, err := readDeltaLog(filepath.Join(tableDir, DeltaLogDirName))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if committed[file.Path] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(tableDir, filepath.FromSlash(file.Path))); err != nil {
+			return fmt.Errorf("failed to remove uncommitted Parquet file: %w", err)
+		}
+	}
+	return nil
+}
+
+// readDeltaLog replays the commits of a Delta log
+// live holds the files of the latest version, committed every file any commit added.
+// A missing log is an empty table
+func readDeltaLog(logDir string) (live, committed map[string]bool, err error) {
+	live, committed = map[string]bool{}, map[string]bool{}
+	if _, err := os.Stat(logDir); errors.Is(err, fs.ErrNotExist) {
+		return live, committed, nil
+	}
+	next, err := nextDeltaVersion(logDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for version := int64(0); version < next; version++ {
+		data, err := os.ReadFile(filepath.Join(logDir, fmt.Sprintf("%020d.json", version)))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read Delta log: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			var action struct {
+				Add    *struct{ Path string } `json:"add"`
+				Remove *struct{ Path string } `json:"remove"`
+			}
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if err := json.Unmarshal([]byte(line), &action); err != nil {
+				return nil, nil, fmt.Errorf("invalid Delta commit %d: %w", version, err)
+			}
+			if action.Add != nil {
+				live[action.Add.Path] = true
+				committed[action.Add.Path] = true
+			}
+			if action.Remove != nil {
+				delete(live, action.Remove.Path)
+			}
+		}
+	}
+	return live, committed, nil
+}
+
+// writeDeltaCommit writes the next commit, adding files and removing the paths in removed
+func writeDeltaCommit(tableDir string, columns []DeltaColumn, partitionColumns []string, files []DeltaDataFile, removed []string, now time.Time) (int64, error) {
+	logDir := filepath.Join(tableDir, DeltaLogDirName)
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create Delta log directory: %w", err)
+	}
+
+	version, err := nextDeltaVersion(logDir)
+	if err != nil {
+		return 0, err
+	}
+
+	partitionBy, _ := json.Marshal(partitionColumns)
+	mode := "Append"
+	if version == 0 || len(removed) > 0 {
+		mode = "Overwrite"
+	}
+	actions := []any{
+		map[string]any{"commitInfo": map[string]any{
+			"timestamp":           now.UnixMilli(),
+			"operation":           "WRITE",
+			"operationParameters": map[string]any{"mode": mode, "partitionBy": string(partitionBy)},
+			"engineInfo":          "aether",
+			"isBlindAppend":       len(removed) == 0,
+		}},
+	}
+
+	if version == 0 {
+		schema, err := deltaSchemaString(columns)
+		if err != nil {
+			return 0, err
+		}
+		if partitionColumns == nil {
+			partitionColumns = []string{}
+		}
+		actions = append(actions,
+			map[string]any{"protocol": map[string]any{"minReaderVersion": 1, "minWriterVersion": 2}},
+			map[string]any{"metaData": map[string]any{
+				"id":               uuid.New().String(),
+				"format":           map[string]any{"provider": "parquet", "options": map[string]any{}},
+				"schemaString":     schema,
+				"partitionColumns": partitionColumns,
+				"configuration":    map[string]any{},
+				"createdTime":      now.UnixMilli(),
+			}},
+		)
+	} else {
+		metadata, err := evolveDeltaMetadata(logDir, version, columns, partitionColumns)
+		if err != nil {
+			return 0, err
+		}
+		if metadata != nil {
+			actions = append(actions, map[string]any{"metaData": metadata})
+		}
+	}
+
+	for _, file := range files {
+		partitionValues := file.PartitionValues
+		if partitionValues == nil {
+			partitionValues = map[string]string{}
+		}
+		actions = append(actions, map[string]any{"add": map[string]any{
+			"path":             file.Path,
+			"partitionValues":  partitionValues,
+			"size":             file.Size,
+			"modificationTime": file.ModificationTime.UnixMilli(),
+			"dataChange":       true,
+		}})
+	}
+
+	for _, path := range removed {
+		actions = append(actions, map[string]any{"remove": map[string]any{
+			"path":              path,
+			"deletionTimestamp": now.UnixMilli(),
+			"dataChange":        true,
+		}})
+	}
+
+	var commit strings.Builder
+	for _, action := range actions {
+		line, err := json.Marshal(action)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode Delta action: %w", err)
+		}
+		commit.Write(line)
+		commit.WriteByte('\n')
+	}
+
+	commitFile := filepath.Join(logDir, fmt.Sprintf("%020d.json", version))
+	tempFile := commitFile + ".part"
+	if err := os.WriteFile(tempFile, []byte(commit.String()), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write Delta commit: %w", err)
+	}
+	defer func() { _ = os.Remove(tempFile) }()
+
+	if err := os.Link(tempFile, commitFile); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return 0, fmt.Errorf("commit %d of the Delta table already exists (concurrent writer?)", version)
+		}
+		return 0, fmt.Errorf("failed to write Delta commit: %w", err)
+	}
+	return version, nil
+}
+
+// nextDeltaVersion returns the version after the highest existing commit, or 0 for a new table
+func nextDeltaVersion(logDir string) (int64, error) {
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Delta log: %w", err)
+	}
+
+	next := int64(0)
+	for _, entry := range entries {
+		var version int64
+		if _, err := fmt.Sscanf(entry.Name(), "%020d.json", &version); err != nil || entry.Name() != fmt.Sprintf("%020d.json", version) {
+			continue
+		}
+		if version >= next {
+			next = version + 1
+		}
+	}
+	return next, nil
+}
+
+// MergeDeltaColumns returns the union of a table's columns and the columns of new data files
+// Existing columns keep their position and type and new columns are appended, so files written
+// before read the added columns as null. changed reports whether columns were added. A column
+// whose type differs is an error, because files already written cannot be converted
+func MergeDeltaColumns(existing, incoming []DeltaColumn) (merged []DeltaColumn, changed bool, err error) {
+	types := make(map[string]string, len(existing))
+	merged = make([]DeltaColumn, 0, len(existing)+len(incoming))
+	for _, column := range existing {
+		types[column.Name] = deltaColumnType(column)
+		merged = append(merged, column)
+	}
+	for _, column := range incoming {
+		existingType, ok := types[column.Name]
+		if !ok {
+			types[column.Name] = deltaColumnType(column)
+			merged = append(merged, column)
+			changed = true
+			continue
+		}
+		if existingType != deltaColumnType(column) {
+			return nil, false, fmt.Errorf("column '%s' changes type from %s to %s; the Delta table must be rewritten", column.Name, existingType, deltaColumnType(column))
+		}
+	}
+	return merged, changed, nil
+}
+
+// evolveDeltaMetadata returns the metaData action widening the table schema to columns,
+// or nil if the schema already covers them or the log holds no metaData to evolve
+func evolveDeltaMetadata(logDir string, version int64, columns []DeltaColumn, partitionColumns []string) (map[string]any, error) {
+	metadata, err := latestDeltaMetadata(logDir, version)
+	if err != nil || metadata == nil {
+		return nil, err
+	}
+
+	var existingPartitions []string
+	if values, ok := metadata["partitionColumns"].([]any); ok {
+		for _, value := range values {
+			name, _ := value.(string)
+			existingPartitions = append(existingPartitions, name)
+		}
+	}
+	if partitionColumns != nil && !slices.Equal(existingPartitions, partitionColumns) {
+		return nil, fmt.Errorf("partition columns change from %v to %v; the Delta table must be rewritten", existingPartitions, partitionColumns)
+	}
+
+	schemaString, _ := metadata["schemaString"].(string)
+	existing, err := parseDeltaSchema(schemaString)
+	if err != nil {
+		return nil, err
+	}
+	merged, changed, err := MergeDeltaColumns(existing, columns)
+	if err != nil || !changed {
+		return nil, err
+	}
+	if metadata["schemaString"], err = deltaSchemaString(merged); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// latestDeltaMetadata returns the last metaData action of the commits before version
+// Returns nil if none is found, e.g. when older commits were replaced by a checkpoint
+func latestDeltaMetadata(logDir string, version int64) (map[string]any, error) {
+	for v := version - 1; v >= 0; v-- {
+		data, err := os.ReadFile(filepath.Join(logDir, fmt.Sprintf("%020d.json", v)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Delta log: %w", err)
+		}
+
+		var metadata map[string]any
+		for _, line := range strings.Split(string(data), "\n") {
+			var action struct {
+				MetaData map[string]any `json:"metaData"`
+			}
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if err := json.Unmarshal([]byte(line), &action); err != nil {
+				return nil, fmt.Errorf("invalid Delta commit %d: %w", v, err)
+			}
+			if action.MetaData != nil {
+				metadata = action.MetaData
+			}
+		}
+		if metadata != nil {
+			return metadata, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseDeltaSchema reads the columns of a flat Delta/Spark JSON schema
+func parseDeltaSchema(schemaString string) ([]DeltaColumn, error) {
+	var schema struct {
+		Fields []struct {
+			Name string `json:"name"`
+			Type any    `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(schemaString), &schema); err != nil {
+		return nil, fmt.Errorf("invalid Delta schema: %w", err)
+	}
+
+	columns := make([]DeltaColumn, len(schema.Fields))
+	for i, field := range schema.Fields {
+		columnType, ok := field.Type.(string)
+		if !ok {
+			return nil, fmt.Errorf("column '%s' of the Delta schema has a nested type, which cannot be evolved", field.Name)
+		}
+		columns[i] = DeltaColumn{Name: field.Name, Type: columnType}
+	}
+	return columns, nil
+}
+
+func deltaColumnType(column DeltaColumn) string {
+	if column.Type == "" {
+		return "string"
+	}
+	return column.Type
+}
+
+// deltaSchemaString renders a flat, nullable struct schema in the Delta/Spark JSON schema format
+func deltaSchemaString(columns []DeltaColumn) (string, error) {
+	fields := make([]map[string]any, len(columns))
+	for i, column := range columns {
+		fields[i] = map[string]any{
+			"name":     column.Name,
+			"type":     deltaColumnType(column),
+			"nullable": true,
+			"metadata": map[string]any{},
+		}
+	}
+	schema, err := json.Marshal(map[string]any{"type": "struct", "fields": fields})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode Delta schema: %w", err)
+	}
+	return string(schema), nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+)
+
+var (
+	// ErrStudyNotFound is returned when the DICOMweb server does not know a study
+	ErrStudyNotFound = errors.New("study not found")
+
+	// ErrDICOMwebUnavailable wraps network errors and exhausted retries (transient)
+	ErrDICOMwebUnavailable = errors.New("DICOMweb server unavailable")
+)
+
+// DICOMwebClient retrieves study metadata from a DICOMweb (WADO-RS) server
+// Only the metadata resource is requested, so no pixel data is transferred
+type DICOMwebClient struct {
+	baseURL    string
+	httpClient *HTTPClient
+	logger     *lib.Logger
+}
+
+// NewDICOMwebClient creates a new DICOMweb client with the given WADO-RS base URL
+func NewDICOMwebClient(baseURL string, httpClient *HTTPClient, logger *lib.Logger) *DICOMwebClient {
+	return &DICOMwebClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+		logger:     logger,
+	}
+}
+
+// FetchStudyMetadata retrieves the DICOM JSON metadata of all instances in a study
+// Per DICOM PS3.18: GET {base}/studies/{StudyInstanceUID}/metadata
+// Returns the raw JSON array, ErrStudyNotFound for unknown studies, or an error
+func (c *DICOMwebClient) FetchStudyMetadata(studyUID string) ([]byte, error) {
+	metadataURL := fmt.Sprintf("%s/studies/%s/metadata", c.baseURL, url.PathEscape(studyUID))
+
+	req, err := http.NewRequest(http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/dicom+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDICOMwebUnavailable, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNoContent {
+		return nil, ErrStudyNotFound
+	}
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("DICOMweb server returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	// DICOM JSON model: an array with one dataset per instance
+	var instances []map[string]any
+	if err := json.Unmarshal(body, &instances); err != nil {
+		return nil, fmt.Errorf("invalid DICOM JSON metadata: %w", err)
+	}
+	if len(instances) == 0 {
+		return nil, ErrStudyNotFound
+	}
+
+	c.logger.Debug("Fetched study metadata", "study_uid", studyUID, "instances", len(instances))
+	return body, nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// dimpCacheDirName is the directory below the jobs directory holding cached DIMP output
+const dimpCacheDirName = ".dimp-cache"
+
+// dimpCacheVersion is part of every cache key; bump it when the output format of the DIMP step changes
+const dimpCacheVersion = 1
+
+// DIMPCacheEntry describes the cached pseudonymized output of one input file (services.dimp.output_cache_ttl_minutes)
+// The output itself is stored next to the entry as <key>.ndjson
+type DIMPCacheEntry struct {
+	Key           string         `json:"key"`
+	JobID         string         `json:"job_id"` // Job that pseudonymized the file
+	File          string         `json:"file"`   // Input file name in that job
+	CreatedAt     time.Time      `json:"created_at"`
+	Resources     int            `json:"resources"`
+	PassedThrough int            `json:"passed_through,omitempty"`
+	EmptyLines    int            `json:"empty_lines,omitempty"`
+	UnknownTypes  map[string]int `json:"unknown_types,omitempty"`
+}
+
+// DIMPCacheKey returns the content address of an input file's DIMP output
+// The key covers the file content and every setting that changes the output of the DIMP step,
+// so output is only reused for an identical file pseudonymized the same way
+func DIMPCacheKey(inputFile string, config models.DIMPConfig) (string, error) {
+	file, err := os.Open(inputFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash input file: %w", err)
+	}
+	contentHash := hex.EncodeToString(hash.Sum(nil))
+
+	nonFHIRLines := config.NonFHIRLines
+	if nonFHIRLines == "" {
+		nonFHIRLines = models.NonFHIRLinesFail
+	}
+	thresholdMB := config.BundleSplitThresholdMB
+	if thresholdMB <= 0 {
+		thresholdMB = 10
+	}
+	stepConfig := fmt.Sprintf("v%d|%s|%s|%d", dimpCacheVersion, strings.TrimSuffix(config.URL, "/"), nonFHIRLines, thresholdMB)
+
+	sum := sha256.Sum256([]byte(contentHash + "|" + stepConfig))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// getDIMPCachePath returns the cache entry file of a key
+// Entries are kept per tenant, since pseudonymized output must not cross tenant boundaries
+func getDIMPCachePath(jobsDir string, key string) string {
+	return filepath.Join(jobsDir, dimpCacheDirName, key[:2], key+".json")
+}
+
+// LoadDIMPCacheEntry returns the cached output of a key if it is younger than ttl
+// Expired and incomplete entries are removed and reported as not found
+func LoadDIMPCacheEntry(jobsDir string, key string, ttl time.Duration, now time.Time) (*DIMPCacheEntry, bool) {
+	path := getDIMPCachePath(jobsDir, key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry DIMPCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || now.Sub(entry.CreatedAt) > ttl {
+		_ = RemoveDIMPCacheEntry(jobsDir, key)
+		return nil, false
+	}
+	if _, err := os.Stat(dimpCacheOutputPath(path)); err != nil {
+		_ = RemoveDIMPCacheEntry(jobsDir, key)
+		return nil, false
+	}
+	return &entry, true
+}
+
+// SaveDIMPCacheEntry stores a copy of a pseudonymized output file under the entry's key
+// The output is written before the entry, so an entry is only found once its output is complete
+func SaveDIMPCacheEntry(jobsDir string, outputFile string, entry DIMPCacheEntry) error {
+	path := getDIMPCachePath(jobsDir, entry.Key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create DIMP cache directory: %w", err)
+	}
+
+	if err := copyFileAtomic(outputFile, dimpCacheOutputPath(path)); err != nil {
+		return fmt.Errorf("failed to cache DIMP output: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal DIMP cache entry: %w", err)
+	}
+	tempFile := filepath.Join(filepath.Dir(path), fmt.Sprintf(".cache.tmp.%s", uuid.New().String()))
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write DIMP cache entry: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to save DIMP cache entry: %w", err)
+	}
+	return nil
+}
+
+// RestoreDIMPCacheOutput copies the cached output of a key to outputFile (atomic write)
+func RestoreDIMPCacheOutput(jobsDir string, key string, outputFile string) error {
+	if err := copyFileAtomic(dimpCacheOutputPath(getDIMPCachePath(jobsDir, key)), outputFile); err != nil {
+		return fmt.Errorf("failed to restore cached DIMP output: %w", err)
+	}
+	return nil
+}
+
+// RemoveDIMPCacheEntry forgets the cached output of a key
+func RemoveDIMPCacheEntry(jobsDir string, key string) error {
+	path := getDIMPCachePath(jobsDir, key)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(dimpCacheOutputPath(path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func dimpCacheOutputPath(entryPath string) string {
+	return strings.TrimSuffix(entryPath, ".json") + ".ndjson"
+}
+
+// copyFileAtomic copies src to dst via a temporary file in dst's directory and a rename
+func copyFileAtomic(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	tempFile := filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%s.tmp.%s", filepath.Base(dst), uuid.New().String()))
+	out, err := os.Create(tempFile)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tempFile)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tempFile)
+		return err
+	}
+	if err := os.Rename(tempFile, dst); err != nil {
+		_ = os.Remove(tempFile)
+		return err
+	}
+	return nil
+}
@@ -1,9 +1,15 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/trobanga/aether/internal/lib"
 	"github.com/trobanga/aether/internal/models"
@@ -15,6 +21,12 @@ type DIMPClient struct {
 	baseURL    string
 	httpClient *HTTPClient
 	logger     *lib.Logger
+	debugLog   *lib.LogSampler // Samples per-resource debug lines; nil logs all
+
+	// Usage counters, safe for concurrent requests
+	requests  atomic.Int64
+	resources atomic.Int64
+	bytes     atomic.Int64
 }
 
 // NewDIMPClient creates a new DIMP client with the given base URL
@@ -26,18 +38,50 @@ func NewDIMPClient(baseURL string, httpClient *HTTPClient, logger *lib.Logger) *
 	}
 }
 
+// SetDebugLogSampler samples the client's per-resource debug lines, including those of its HTTP client
+func (c *DIMPClient) SetDebugLogSampler(sampler *lib.LogSampler) {
+	c.debugLog = sampler
+	c.httpClient.SetDebugLogSampler(sampler)
+}
+
+// Usage returns the requests, resources and request bytes sent to DIMP by this client
+// Failed requests are included: they reached the service
+func (c *DIMPClient) Usage() models.DIMPUsage {
+	return models.DIMPUsage{
+		Requests:  c.requests.Load(),
+		Resources: c.resources.Load(),
+		Bytes:     c.bytes.Load(),
+	}
+}
+
 // Pseudonymize sends a FHIR resource to the DIMP service for pseudonymization
 // Returns the pseudonymized resource or an error
 // Per contract: POST /$de-identify with single FHIR resource
+// Each request (a resource or a chunk of a split Bundle) is traced as a dimp.pseudonymize span
 func (c *DIMPClient) Pseudonymize(resource map[string]any) (map[string]any, error) {
+	resourceType, _ := resource["resourceType"].(string)
+	ctx, span := lib.StartSpan(c.httpClient.Context(), "dimp.pseudonymize", attribute.String("fhir.resource_type", resourceType))
+	if entries, ok := resource["entry"].([]any); ok {
+		span.SetAttributes(attribute.Int("fhir.bundle.entries", len(entries)))
+	}
+
+	pseudonymized, err := c.pseudonymize(ctx, resource)
+	lib.EndSpan(span, err)
+	return pseudonymized, err
+}
+
+// pseudonymize sends a resource to DIMP with a request bound to ctx
+func (c *DIMPClient) pseudonymize(ctx context.Context, resource map[string]any) (map[string]any, error) {
 	// Extract resource info for logging
 	resourceType, _ := resource["resourceType"].(string)
 	resourceID, _ := resource["id"].(string)
 
-	c.logger.Debug("Sending resource to DIMP",
-		"resourceType", resourceType,
-		"id", resourceID,
-		"url", c.baseURL+"/$de-identify")
+	if c.debugLog.Allow("Sending resource to DIMP") {
+		c.logger.Debug("Sending resource to DIMP",
+			"resourceType", resourceType,
+			"id", resourceID,
+			"url", c.baseURL+"/$de-identify")
+	}
 
 	// Marshal resource to JSON
 	jsonBody, err := json.Marshal(resource)
@@ -45,18 +89,27 @@ func (c *DIMPClient) Pseudonymize(resource map[string]any) (map[string]any, erro
 		return nil, fmt.Errorf("failed to marshal resource: %w", err)
 	}
 
-	c.logger.Debug("Request body size", "bytes", len(jsonBody))
+	if c.debugLog.Allow("Request body size") {
+		c.logger.Debug("Request body size", "bytes", len(jsonBody))
+	}
 
 	// Construct endpoint URL
 	url := c.baseURL + "/$de-identify"
 
 	// Send POST request
-	resp, err := c.httpClient.PostJSON(url, jsonBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.countRequest(resource, len(jsonBody))
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		c.logger.Error("DIMP HTTP request failed",
 			"resourceType", resourceType,
 			"id", resourceID,
-			"error", err)
+			"error", err,
+			"request_id", RequestIDOf(err))
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer func() {
@@ -70,6 +123,7 @@ func (c *DIMPClient) Pseudonymize(resource map[string]any) (map[string]any, erro
 		// Read error response body
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		errorType := lib.ClassifyHTTPError(resp.StatusCode)
+		requestID := ResponseRequestID(resp)
 
 		c.logger.Error("DIMP service returned error",
 			"status_code", resp.StatusCode,
@@ -77,7 +131,8 @@ func (c *DIMPClient) Pseudonymize(resource map[string]any) (map[string]any, erro
 			"resourceType", resourceType,
 			"id", resourceID,
 			"error_body", string(bodyBytes),
-			"retryable", errorType == models.ErrorTypeTransient)
+			"retryable", errorType == models.ErrorTypeTransient,
+			"request_id", requestID)
 
 		// Create error with classification
 		err := &DIMPError{
@@ -85,15 +140,19 @@ func (c *DIMPClient) Pseudonymize(resource map[string]any) (map[string]any, erro
 			Status:     resp.Status,
 			ErrorType:  errorType,
 			Body:       string(bodyBytes),
+			RequestID:  requestID,
 		}
 
 		return nil, err
 	}
 
-	c.logger.Debug("DIMP service responded successfully",
-		"status_code", resp.StatusCode,
-		"resourceType", resourceType,
-		"id", resourceID)
+	if c.debugLog.Allow("DIMP service responded successfully") {
+		c.logger.Debug("DIMP service responded successfully",
+			"status_code", resp.StatusCode,
+			"resourceType", resourceType,
+			"id", resourceID,
+			"request_id", ResponseRequestID(resp))
+	}
 
 	// Success - parse pseudonymized resource
 	var pseudonymized map[string]any
@@ -108,7 +167,7 @@ func (c *DIMPClient) Pseudonymize(resource map[string]any) (map[string]any, erro
 	// Log what changed
 	originalID, _ := resource["id"].(string)
 	newID, _ := pseudonymized["id"].(string)
-	if originalID != newID {
+	if originalID != newID && c.debugLog.Allow("Resource ID pseudonymized") {
 		c.logger.Debug("Resource ID pseudonymized",
 			"resourceType", resourceType,
 			"original_id", originalID,
@@ -118,12 +177,24 @@ func (c *DIMPClient) Pseudonymize(resource map[string]any) (map[string]any, erro
 	return pseudonymized, nil
 }
 
+// countRequest adds a request to the usage counters; a Bundle counts as its entries
+func (c *DIMPClient) countRequest(resource map[string]any, bodyBytes int) {
+	resources := int64(1)
+	if entries, ok := resource["entry"].([]any); ok && resource["resourceType"] == "Bundle" {
+		resources = int64(len(entries))
+	}
+	c.requests.Add(1)
+	c.resources.Add(resources)
+	c.bytes.Add(int64(bodyBytes))
+}
+
 // DIMPError represents an error response from the DIMP service
 type DIMPError struct {
 	StatusCode int
 	Status     string
 	ErrorType  models.ErrorType
 	Body       string
+	RequestID  string // X-Request-ID of the failed request, for locating it in DIMP logs
 }
 
 func (e *DIMPError) Error() string {
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// dimpUsageFileName is the file below the jobs directory holding the monthly DIMP usage counters
+const dimpUsageFileName = ".dimp-usage.json"
+
+// dimpUsageLockWait bounds how long RecordDIMPUsage waits for another process updating the counters
+const dimpUsageLockWait = 10 * time.Second
+
+// DIMPMonthUsage is the DIMP usage of all jobs in one calendar month (UTC)
+type DIMPMonthUsage struct {
+	Month string `json:"month"` // YYYY-MM
+	models.DIMPUsage
+}
+
+// DIMPUsageMonth returns the counter month of t, e.g. "2026-10"
+func DIMPUsageMonth(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// LoadDIMPUsage reads the monthly DIMP usage counters of a jobs directory, oldest month first
+// Returns no months if nothing was sent to DIMP yet
+func LoadDIMPUsage(jobsDir string) ([]DIMPMonthUsage, error) {
+	months, err := readDIMPUsage(jobsDir)
+	if err != nil {
+		return nil, err
+	}
+	usage := make([]DIMPMonthUsage, 0, len(months))
+	for month, counters := range months {
+		usage = append(usage, DIMPMonthUsage{Month: month, DIMPUsage: counters})
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Month < usage[j].Month })
+	return usage, nil
+}
+
+// RecordDIMPUsage adds usage to the counter of month and returns the month's new total
+// The counters are shared by all jobs of the jobs directory; a lock file serializes updates
+// from concurrent aether processes
+func RecordDIMPUsage(jobsDir, month string, usage models.DIMPUsage) (models.DIMPUsage, error) {
+	if err := os.MkdirAll(jobsDir, 0755); err != nil {
+		return models.DIMPUsage{}, fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+
+	lockPath := filepath.Join(jobsDir, ".dimp-usage.lock")
+	deadline := time.Now().Add(dimpUsageLockWait)
+	var lock *os.File
+	for {
+		file, err := tryLockFile(lockPath)
+		if err != nil {
+			return models.DIMPUsage{}, fmt.Errorf("failed to lock DIMP usage counters: %w", err)
+		}
+		if file != nil {
+			lock = file
+			break
+		}
+		if time.Now().After(deadline) {
+			return models.DIMPUsage{}, errors.New("DIMP usage counters are locked by another process")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	defer func() { _ = lock.Close() }()
+
+	months, err := readDIMPUsage(jobsDir)
+	if err != nil {
+		return models.DIMPUsage{}, err
+	}
+	total := months[month]
+	total.Add(usage)
+	months[month] = total
+
+	data, err := json.MarshalIndent(months, "", "  ")
+	if err != nil {
+		return models.DIMPUsage{}, fmt.Errorf("failed to marshal DIMP usage counters: %w", err)
+	}
+	path := filepath.Join(jobsDir, dimpUsageFileName)
+	if err := os.WriteFile(path+".part", data, 0644); err != nil {
+		return models.DIMPUsage{}, fmt.Errorf("failed to write DIMP usage counters: %w", err)
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		_ = os.Remove(path + ".part")
+		return models.DIMPUsage{}, fmt.Errorf("failed to write DIMP usage counters: %w", err)
+	}
+	return total, nil
+}
+
+// readDIMPUsage reads the counters keyed by month; a missing file means no usage
+func readDIMPUsage(jobsDir string) (map[string]models.DIMPUsage, error) {
+	months := map[string]models.DIMPUsage{}
+	data, err := os.ReadFile(filepath.Join(jobsDir, dimpUsageFileName))
+	if os.IsNotExist(err) {
+		return months, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DIMP usage counters: %w", err)
+	}
+	if err := json.Unmarshal(data, &months); err != nil {
+		return nil, fmt.Errorf("failed to parse DIMP usage counters: %w", err)
+	}
+	return months, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+const (
+	// EventsFileName is the name of the append-only event timeline in each job directory
+	EventsFileName = "events.ndjson"
+)
+
+// JobEventSink receives events emitted by long-running service calls (downloads, retries)
+// A nil sink discards events
+type JobEventSink func(event models.JobEvent)
+
+// emit forwards an event to the sink if one is configured
+func (s JobEventSink) emit(eventType models.JobEventType, message string, fields map[string]any) {
+	if s == nil {
+		return
+	}
+	s(models.JobEvent{
+		Timestamp: lib.Now(),
+		Type:      eventType,
+		Message:   message,
+		Fields:    fields,
+	})
+}
+
+// GetEventsFilePath returns the full path to a job's event timeline
+func GetEventsFilePath(jobsBaseDir string, jobID string) string {
+	return filepath.Join(GetJobDir(jobsBaseDir, jobID), EventsFileName)
+}
+
+// AppendJobEvent appends a single event to the job's events.ndjson
+// The job directory must already exist; events are never written for unknown jobs
+func AppendJobEvent(jobsBaseDir string, jobID string, event models.JobEvent) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = lib.Now()
+	}
+	event.Timestamp = event.Timestamp.In(lib.TimeZone())
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job event: %w", err)
+	}
+	data = append(data, '\n')
+
+	// O_APPEND keeps each event on its own line even if several writers race
+	file, err := os.OpenFile(GetEventsFilePath(jobsBaseDir, jobID), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open events file: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write job event: %w", err)
+	}
+	return file.Close()
+}
+
+// LoadJobEvents reads a job's event timeline in the order events were recorded
+// Returns an empty slice if the job has no events yet
+// A truncated trailing line (e.g., from a crash mid-write) is ignored
+func LoadJobEvents(jobsBaseDir string, jobID string) ([]models.JobEvent, error) {
+	file, err := os.Open(GetEventsFilePath(jobsBaseDir, jobID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []models.JobEvent{}, nil
+		}
+		return nil, fmt.Errorf("failed to open events file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	events := []models.JobEvent{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var event models.JobEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read events file: %w", err)
+	}
+	return events, nil
+}
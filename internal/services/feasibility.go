@@ -0,0 +1,67 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+)
+
+// CountCohort asks the feasibility endpoint (services.torch.feasibility_url) how many
+// patients match the cohort definition of a CRTDL. The cohortDefinition is posted as a
+// structured query, as FLARE's /query/execute expects, and the answer is a plain count
+// The request uses the TORCH credentials and retries transient errors
+func (c *TORCHClient) CountCohort(crtdlPath string) (int, error) {
+	data, err := os.ReadFile(crtdlPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CRTDL file: %w", err)
+	}
+	var crtdl struct {
+		CohortDefinition json.RawMessage `json:"cohortDefinition"`
+	}
+	if err := json.Unmarshal(data, &crtdl); err != nil {
+		return 0, fmt.Errorf("invalid CRTDL JSON: %w", err)
+	}
+	if len(crtdl.CohortDefinition) == 0 {
+		return 0, fmt.Errorf("CRTDL has no cohortDefinition")
+	}
+
+	req, err := http.NewRequestWithContext(c.httpClient.Context(), http.MethodPost, c.config.FeasibilityURL, bytes.NewReader(crtdl.CohortDefinition))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create feasibility request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/sq+json")
+	req.Header.Set("Accept", "text/plain, application/json")
+
+	c.logger.Debug("Counting cohort", "url", c.config.FeasibilityURL)
+	resp, err := c.sendAuthorized(req, c.httpClient.Do)
+	if err != nil {
+		return 0, fmt.Errorf("feasibility endpoint unreachable: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read feasibility response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return 0, &TORCHError{
+			Operation:  "feasibility",
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(body)),
+			ErrorType:  lib.ClassifyHTTPError(resp.StatusCode),
+		}
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(string(body)))
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("feasibility endpoint returned no cohort size: %q", strings.TrimSpace(string(body)))
+	}
+	return count, nil
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// FHIRExportClient runs FHIR Bulk Data $export operations
+// (https://hl7.org/fhir/uv/bulkdata/export.html): kick-off, status polling and file download
+type FHIRExportClient struct {
+	config     models.FHIRExportConfig
+	httpClient *HTTPClient
+	logger     *lib.Logger
+	events     JobEventSink
+}
+
+// FHIRExportManifest is the completion response of an export's status endpoint
+type FHIRExportManifest struct {
+	TransactionTime     string               `json:"transactionTime"`
+	Request             string               `json:"request"`
+	RequiresAccessToken bool                 `json:"requiresAccessToken"`
+	Output              []FHIRExportFileItem `json:"output"`
+	Error               []FHIRExportFileItem `json:"error"`
+}
+
+// FHIRExportFileItem is one NDJSON file listed in the manifest
+type FHIRExportFileItem struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Count int    `json:"count,omitempty"`
+}
+
+// FHIRExportError represents a failed request of a FHIR export
+type FHIRExportError struct {
+	Operation  string // "kick-off", "status", "download"
+	StatusCode int
+	Message    string
+	ErrorType  models.ErrorType
+}
+
+func (e *FHIRExportError) Error() string {
+	return fmt.Sprintf("FHIR export %s error: HTTP %d: %s", e.Operation, e.StatusCode, e.Message)
+}
+
+// ErrFHIRExportTimeout is returned when an export does not complete within services.fhir_export.timeout_minutes
+var ErrFHIRExportTimeout = fmt.Errorf("FHIR export timeout exceeded")
+
+// NewFHIRExportClient creates a client for $export operations with the given configuration
+func NewFHIRExportClient(config models.FHIRExportConfig, httpClient *HTTPClient, logger *lib.Logger) *FHIRExportClient {
+	return &FHIRExportClient{
+		config:     config,
+		httpClient: httpClient,
+		logger:     logger,
+	}
+}
+
+// SetEventSink routes download progress events to a job's event timeline
+func (c *FHIRExportClient) SetEventSink(sink JobEventSink) {
+	c.events = sink
+}
+
+// KickOff starts the export and returns the URL of its status endpoint
+// services.fhir_export.types is sent as _type unless the kick-off URL names types itself
+func (c *FHIRExportClient) KickOff(kickOffURL string) (string, error) {
+	parsed, err := url.Parse(kickOffURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid FHIR export URL: %w", err)
+	}
+	if query := parsed.Query(); len(c.config.Types) > 0 && query.Get("_type") == "" {
+		query.Set("_type", strings.Join(c.config.Types, ","))
+		parsed.RawQuery = query.Encode()
+	}
+
+	req, err := c.newRequest(http.MethodGet, parsed.String(), "application/fhir+json")
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Prefer", "respond-async")
+
+	c.logger.Info("Starting FHIR export", "url", parsed.String())
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return "", exportResponseError("kick-off", resp)
+	}
+	location := resp.Header.Get("Content-Location")
+	if location == "" {
+		return "", fmt.Errorf("FHIR export kick-off response has no Content-Location header")
+	}
+	statusURL, err := parsed.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid FHIR export status URL '%s': %w", location, err)
+	}
+	return statusURL.String(), nil
+}
+
+// PollStatus polls the status endpoint until the export is complete and returns its manifest
+// Waits double from polling_interval_seconds up to max_polling_interval_seconds; a Retry-After
+// sent by the server replaces the wait, capped at max_polling_interval_seconds
+func (c *FHIRExportClient) PollStatus(statusURL string, progress lib.ProgressReporter) (manifest *FHIRExportManifest, err error) {
+	interval, maxInterval := c.config.GetPollingIntervals()
+	timeout := c.config.GetTimeout()
+	start := time.Now()
+
+	task := progress.Start("Waiting for FHIR export to complete", 0)
+	defer func() { task.Done(err) }()
+
+	for attempt := 1; ; attempt++ {
+		if time.Since(start) > timeout {
+			c.logger.Error("FHIR export timeout", "duration", time.Since(start), "timeout", timeout, "polls", attempt-1)
+			return nil, ErrFHIRExportTimeout
+		}
+
+		req, err := c.newRequest(http.MethodGet, statusURL, "application/json")
+		if err != nil {
+			return nil, err
+		}
+		// Sent once: an error status ends the export, and its OperationOutcome says why
+		resp, err := c.httpClient.send(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			manifest, err := decodeExportManifest(resp, req.URL)
+			if err != nil {
+				return nil, err
+			}
+			c.logger.Info("FHIR export completed", "polls", attempt, "files", len(manifest.Output), "error_files", len(manifest.Error))
+			return manifest, nil
+
+		case http.StatusAccepted:
+			status := resp.Header.Get("X-Progress")
+			wait := interval
+			if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+				wait = min(retryAfter, maxInterval)
+			}
+			_ = resp.Body.Close()
+			c.logger.Debug("FHIR export in progress", "attempt", attempt, "progress", status, "next_poll", wait)
+
+			state := models.WaitState{
+				Reason:    models.WaitPoll,
+				Target:    "FHIR export",
+				Attempt:   attempt + 1,
+				NextAt:    time.Now().Add(wait),
+				GivesUpAt: start.Add(timeout),
+			}
+			description := state.Describe(lib.Now())
+			if status != "" {
+				description = status + ", " + description
+			}
+			task.SetStatus(description)
+			c.httpClient.waits.report(&state)
+			err = c.httpClient.Wait(wait)
+			c.httpClient.waits.report(nil)
+			if err != nil {
+				return nil, err
+			}
+			interval = CalculateNextPollInterval(interval, maxInterval)
+
+		default:
+			err := exportResponseError("status", resp)
+			_ = resp.Body.Close()
+			return nil, err
+		}
+	}
+}
+
+// DownloadFiles downloads the files of a manifest section into destinationDir
+// Files are named after their type (Patient.ndjson); further files of the same type get a
+// counter (Patient-2.ndjson). The access token is only sent if the manifest requires it
+func (c *FHIRExportClient) DownloadFiles(manifest *FHIRExportManifest, items []FHIRExportFileItem, destinationDir string, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
+	if err := os.MkdirAll(destinationDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	files := make([]models.FHIRDataFile, 0, len(items))
+	perType := make(map[string]int)
+	for i, item := range items {
+		resourceType := item.Type
+		if !fhirResourceTypeName(resourceType) {
+			resourceType = "Unknown"
+		}
+		perType[resourceType]++
+		fileName := resourceType + ".ndjson"
+		if n := perType[resourceType]; n > 1 {
+			fileName = fmt.Sprintf("%s-%d.ndjson", resourceType, n)
+		}
+
+		task := progress.Start(fmt.Sprintf("Downloading file %d/%d: %s", i+1, len(items), fileName), 0)
+		file, err := c.downloadFile(item.URL, filepath.Join(destinationDir, fileName), manifest.RequiresAccessToken)
+		task.Done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download file %s: %w", item.URL, err)
+		}
+		file.ResourceType = resourceType
+
+		files = append(files, file)
+		c.events.emit(models.EventDownloadFinished,
+			fmt.Sprintf("download %d/%d finished: %s", i+1, len(items), fileName),
+			map[string]any{"file": fileName, "index": i + 1, "total": len(items), "bytes": file.FileSize})
+		c.logger.Info("Downloaded FHIR export file", "file", fileName, "size", file.FileSize, "resources", file.LineCount)
+	}
+	return files, nil
+}
+
+// Delete asks the server to cancel a running export or to remove the files of a completed one
+// An export that is already gone (HTTP 404) counts as deleted
+func (c *FHIRExportClient) Delete(statusURL string) error {
+	req, err := c.newRequest(http.MethodDelete, statusURL, "application/fhir+json")
+	if err != nil {
+		return err
+	}
+	// Sent once and without the client's context: exports are also cancelled after the run was
+	resp, err := c.httpClient.send(req.WithContext(context.Background()))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+		return exportResponseError("delete", resp)
+	}
+	return nil
+}
+
+// newRequest creates a request with the configured credentials
+func (c *FHIRExportClient) newRequest(method, requestURL, accept string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(c.httpClient.Context(), method, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FHIR export request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	c.authorize(req)
+	return req, nil
+}
+
+// authorize sets the Authorization header if credentials are configured
+func (c *FHIRExportClient) authorize(req *http.Request) {
+	switch {
+	case c.config.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.config.BearerToken)
+	case c.config.Username != "":
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+}
+
+// downloadFile downloads one NDJSON file to destPath via a .part file
+func (c *FHIRExportClient) downloadFile(fileURL, destPath string, withToken bool) (models.FHIRDataFile, error) {
+	req, err := http.NewRequestWithContext(c.httpClient.Context(), http.MethodGet, fileURL, nil)
+	if err != nil {
+		return models.FHIRDataFile{}, fmt.Errorf("failed to create download request: %w", err)
+	}
+	req.Header.Set("Accept", "application/fhir+ndjson")
+	if withToken {
+		c.authorize(req)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return models.FHIRDataFile{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 400 {
+		return models.FHIRDataFile{}, exportResponseError("download", resp)
+	}
+
+	partPath := destPath + PartialFileSuffix
+	destFile, err := os.Create(partPath)
+	if err != nil {
+		return models.FHIRDataFile{}, fmt.Errorf("failed to create destination file: %w", err)
+	}
+	var lines lib.LineCounter
+	bytesWritten, err := io.Copy(io.MultiWriter(destFile, &lines), resp.Body)
+	if closeErr := destFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partPath, destPath)
+	}
+	if err != nil {
+		_ = os.Remove(partPath)
+		return models.FHIRDataFile{}, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	fileName := filepath.Base(destPath)
+	return models.FHIRDataFile{
+		FileName:   fileName,
+		FilePath:   fileName, // Relative to job import directory
+		FileSize:   bytesWritten,
+		SourceStep: models.StepHttpImport,
+		LineCount:  lines.Count(),
+		CreatedAt:  lib.GetFileModTime(destPath),
+	}, nil
+}
+
+// decodeExportManifest reads the manifest of a completed export, resolving file URLs against
+// the status URL
+func decodeExportManifest(resp *http.Response, statusURL *url.URL) (*FHIRExportManifest, error) {
+	defer func() { _ = resp.Body.Close() }()
+	var manifest FHIRExportManifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid FHIR export manifest: %w", err)
+	}
+	for _, items := range [][]FHIRExportFileItem{manifest.Output, manifest.Error} {
+		for i := range items {
+			if items[i].URL == "" {
+				return nil, fmt.Errorf("FHIR export manifest lists a %s file without url", items[i].Type)
+			}
+			fileURL, err := statusURL.Parse(items[i].URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid file URL '%s' in FHIR export manifest: %w", items[i].URL, err)
+			}
+			items[i].URL = fileURL.String()
+		}
+	}
+	return &manifest, nil
+}
+
+// exportResponseError describes an unexpected response, keeping the start of its body
+// (usually an OperationOutcome)
+func exportResponseError(operation string, resp *http.Response) *FHIRExportError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &FHIRExportError{
+		Operation:  operation,
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+		ErrorType:  lib.ClassifyHTTPError(resp.StatusCode),
+	}
+}
+
+// parseRetryAfter returns the wait requested by a Retry-After header (seconds or HTTP date),
+// or 0 if there is none
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// searchBundle is the part of a FHIR searchset Bundle needed to page through results
+type searchBundle struct {
+	ResourceType string `json:"resourceType"`
+	Link         []struct {
+		Relation string `json:"relation"`
+		URL      string `json:"url"`
+	} `json:"link"`
+	Entry []struct {
+		Resource json.RawMessage `json:"resource"`
+		Search   struct {
+			Mode string `json:"mode"`
+		} `json:"search"`
+	} `json:"entry"`
+}
+
+// nextLink returns the URL of the next result page, resolved against the current page
+func (b searchBundle) nextLink(pageURL *url.URL) (*url.URL, error) {
+	for _, link := range b.Link {
+		if link.Relation == "next" && link.URL != "" {
+			next, err := url.Parse(link.URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid next link '%s': %w", link.URL, err)
+			}
+			return pageURL.ResolveReference(next), nil
+		}
+	}
+	return nil, nil
+}
+
+// ImportFromFHIRSearch runs a FHIR search and writes the results as NDJSON into destinationDir
+// Follows the Bundle's next links until the last page. Matches and included resources are
+// written to one file per resource type (Observation.ndjson, Patient.ndjson); a resource
+// returned on several pages is written once. OperationOutcome entries are logged, not written
+func ImportFromFHIRSearch(searchURL string, destinationDir string, httpClient *HTTPClient, logger *lib.Logger) ([]models.FHIRDataFile, error) {
+	if err := os.MkdirAll(destinationDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	pageURL, err := url.Parse(searchURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FHIR search URL: %w", err)
+	}
+
+	writers := make(map[string]*os.File)
+	counts := make(map[string]int)
+	defer func() {
+		for _, file := range writers {
+			_ = file.Close()
+		}
+	}()
+
+	seenPages := make(map[string]bool)
+	seenResources := make(map[string]bool)
+	for page := 1; pageURL != nil; page++ {
+		if seenPages[pageURL.String()] {
+			return nil, fmt.Errorf("FHIR search page %d links back to an earlier page: %s", page, pageURL)
+		}
+		seenPages[pageURL.String()] = true
+
+		bundle, err := fetchSearchPage(pageURL.String(), httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("FHIR search page %d: %w", page, err)
+		}
+
+		written := 0
+		for _, entry := range bundle.Entry {
+			var header struct {
+				ResourceType string `json:"resourceType"`
+				ID           string `json:"id"`
+			}
+			if len(entry.Resource) == 0 || json.Unmarshal(entry.Resource, &header) != nil || header.ResourceType == "" {
+				continue
+			}
+			if entry.Search.Mode == "outcome" || (header.ResourceType == "OperationOutcome" && entry.Search.Mode != "match") {
+				logger.Warn("FHIR search returned an OperationOutcome", "page", page, "outcome", string(entry.Resource))
+				continue
+			}
+			if !fhirResourceTypeName(header.ResourceType) {
+				return nil, fmt.Errorf("FHIR search returned invalid resource type '%s'", header.ResourceType)
+			}
+
+			key := header.ResourceType + "/" + header.ID
+			if header.ID != "" && seenResources[key] {
+				continue
+			}
+			seenResources[key] = true
+
+			file, ok := writers[header.ResourceType]
+			if !ok {
+				file, err = os.Create(filepath.Join(destinationDir, header.ResourceType+".ndjson"))
+				if err != nil {
+					return nil, fmt.Errorf("failed to create output file: %w", err)
+				}
+				writers[header.ResourceType] = file
+			}
+			line, err := compactJSON(entry.Resource)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s resource on page %d: %w", header.ResourceType, page, err)
+			}
+			if _, err := file.Write(append(line, '\n')); err != nil {
+				return nil, fmt.Errorf("failed to write %s.ndjson: %w", header.ResourceType, err)
+			}
+			counts[header.ResourceType]++
+			written++
+		}
+		logger.Debug("Fetched FHIR search page", "page", page, "entries", len(bundle.Entry), "written", written)
+
+		pageURL, err = bundle.nextLink(pageURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resourceTypes := make([]string, 0, len(writers))
+	for resourceType, file := range writers {
+		if err := file.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write %s.ndjson: %w", resourceType, err)
+		}
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	clear(writers)
+	sort.Strings(resourceTypes)
+
+	files := make([]models.FHIRDataFile, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		fileName := resourceType + ".ndjson"
+		path := filepath.Join(destinationDir, fileName)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", fileName, err)
+		}
+		files = append(files, models.FHIRDataFile{
+			FileName:     fileName,
+			FilePath:     fileName, // Relative to job import directory
+			ResourceType: resourceType,
+			FileSize:     info.Size(),
+			SourceStep:   models.StepHttpImport,
+			LineCount:    counts[resourceType],
+			CreatedAt:    info.ModTime(),
+		})
+	}
+
+	logger.Info("FHIR search imported", "pages", len(seenPages), "files", len(files), "resources", len(seenResources))
+	return files, nil
+}
+
+// fetchSearchPage GETs one page of search results and decodes the searchset Bundle
+func fetchSearchPage(pageURL string, httpClient *HTTPClient) (*searchBundle, error) {
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/fhir+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("FHIR server returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var bundle searchBundle
+	if err := json.NewDecoder(resp.Body).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("invalid search response: %w", err)
+	}
+	if bundle.ResourceType != "Bundle" {
+		return nil, fmt.Errorf("search response is a %s, not a Bundle", bundle.ResourceType)
+	}
+	return &bundle, nil
+}
+
+// fhirResourceTypeName reports whether name can be a resource type (and so a safe file name)
+func fhirResourceTypeName(name string) bool {
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// compactJSON removes insignificant whitespace so a resource fits on one NDJSON line
+func compactJSON(raw json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// FlattenValueSeparator joins multiple values of one column (e.g. several given names)
+const FlattenValueSeparator = "|"
+
+//go:embed mappings/mii-kds.yaml
+var defaultFlattenMappingYAML []byte
+
+// DefaultFlattenMapping returns the shipped column mappings for common MII KDS profiles
+func DefaultFlattenMapping() models.FlattenMapping {
+	mapping, err := ParseFlattenMapping(defaultFlattenMappingYAML)
+	if err != nil {
+		panic(fmt.Sprintf("embedded default flatten mapping is invalid: %v", err))
+	}
+	return mapping
+}
+
+// ParseFlattenMapping parses a mapping document (resourceType → [column: FHIRPath])
+func ParseFlattenMapping(data []byte) (models.FlattenMapping, error) {
+	var mapping models.FlattenMapping
+	if err := yaml.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("invalid column mapping: %w", err)
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// LoadFlattenMapping returns the default mappings overridden by the project mapping file, if any
+// The result is compiled once to report FHIRPath errors when the config is loaded
+func LoadFlattenMapping(config models.FlattenConfig) (models.FlattenMapping, error) {
+	mapping := DefaultFlattenMapping()
+
+	if config.MappingFile != "" {
+		data, err := os.ReadFile(config.MappingFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mapping file: %w", err)
+		}
+		override, err := ParseFlattenMapping(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", config.MappingFile, err)
+		}
+		mapping = mapping.Merge(override)
+	}
+
+	if _, err := NewFlattener(mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// Flattener turns FHIR resources into table rows according to a column mapping
+type Flattener struct {
+	mapping models.FlattenMapping
+	paths   map[string][]*lib.FHIRPath
+}
+
+// NewFlattener compiles the FHIRPath expressions of a mapping
+func NewFlattener(mapping models.FlattenMapping) (*Flattener, error) {
+	f := &Flattener{mapping: mapping, paths: make(map[string][]*lib.FHIRPath, len(mapping))}
+	for resourceType, columns := range mapping {
+		compiled := make([]*lib.FHIRPath, len(columns))
+		for i, column := range columns {
+			path, err := lib.CompileFHIRPath(column.Path)
+			if err != nil {
+				return nil, fmt.Errorf("mapping %s column '%s': %w", resourceType, column.Name, err)
+			}
+			compiled[i] = path
+		}
+		f.paths[resourceType] = compiled
+	}
+	return f, nil
+}
+
+// ResourceTypes returns the resource types that have a table, in sorted order
+func (f *Flattener) ResourceTypes() []string {
+	return f.mapping.ResourceTypes()
+}
+
+// Columns returns the column names of a resource type's table, or nil if it has none
+func (f *Flattener) Columns(resourceType string) []string {
+	return f.mapping.ColumnNames(resourceType)
+}
+
+// Flatten evaluates every column for a resource
+// Returns the resource type and the row, or false if the resource type has no table
+func (f *Flattener) Flatten(resource map[string]any) (string, []string, bool) {
+	resourceType, _ := resource["resourceType"].(string)
+	paths, ok := f.paths[resourceType]
+	if !ok {
+		return resourceType, nil, false
+	}
+
+	row := make([]string, len(paths))
+	for i, path := range paths {
+		row[i] = FormatFlattenValue(path.Evaluate(resource))
+	}
+	return resourceType, row, true
+}
+
+// FormatFlattenValue renders a FHIRPath result as a single cell
+// Empty results become "", multiple values are joined with FlattenValueSeparator,
+// numbers use the shortest exact representation and complex values are written as JSON
+func FormatFlattenValue(collection []any) string {
+	values := make([]string, 0, len(collection))
+	for _, item := range collection {
+		switch v := item.(type) {
+		case string:
+			values = append(values, v)
+		case float64:
+			values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			values = append(values, strconv.FormatBool(v))
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			values = append(values, string(encoded))
+		}
+	}
+	return strings.Join(values, FlattenValueSeparator)
+}
+
+// DataDictionaryFileName is the data dictionary written next to flattened tables
+const DataDictionaryFileName = "data_dictionary.csv"
+
+// DataDictionaryEntry describes one column of a flattened table
+type DataDictionaryEntry struct {
+	Table          string
+	Column         string
+	Path           string
+	Classification models.ColumnClassification
+	Pseudonymized  bool // Identifier column whose values were pseudonymized by DIMP
+}
+
+// BuildDataDictionary lists every column of a mapping with its classification, sorted by table
+// pseudonymized reports whether the data went through DIMP, so identifier columns hold pseudonyms
+func BuildDataDictionary(mapping models.FlattenMapping, pseudonymized bool) []DataDictionaryEntry {
+	var entries []DataDictionaryEntry
+	for _, resourceType := range mapping.ResourceTypes() {
+		for _, column := range mapping[resourceType] {
+			classification := column.GetClassification()
+			entries = append(entries, DataDictionaryEntry{
+				Table:          resourceType,
+				Column:         column.Name,
+				Path:           column.Path,
+				Classification: classification,
+				Pseudonymized:  pseudonymized && classification == models.ColumnIdentifier,
+			})
+		}
+	}
+	return entries
+}
+
+// WriteDataDictionary writes the data dictionary as CSV (table, column, fhirpath, classification, pseudonymized)
+func WriteDataDictionary(outputDir string, entries []DataDictionaryEntry) error {
+	var buf strings.Builder
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write([]string{"table", "column", "fhirpath", "classification", "pseudonymized"})
+	for _, entry := range entries {
+		_ = writer.Write([]string{entry.Table, entry.Column, entry.Path, string(entry.Classification), strconv.FormatBool(entry.Pseudonymized)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to encode data dictionary: %w", err)
+	}
+
+	path := filepath.Join(outputDir, DataDictionaryFileName)
+	if err := os.WriteFile(path+".part", []byte(buf.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write data dictionary: %w", err)
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		_ = os.Remove(path + ".part")
+		return fmt.Errorf("failed to write data dictionary: %w", err)
+	}
+	return nil
+}
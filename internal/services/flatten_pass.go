@@ -0,0 +1,252 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/trobanga/aether/internal/lib"
+)
+
+// TableSink receives the rows of a flattening pass
+// Each row comes with its columns. A row with columns its table has not had before evolves the
+// table's schema: the columns are appended, and the table's other rows hold null (empty) for them.
+// The source resource is passed along so a sink can partition rows by its content
+type TableSink interface {
+	WriteRow(table string, columns []string, row []string, resource map[string]any) error
+	Close() error // Completes the output after a successful pass
+	Abort()       // Discards partial output after a failed pass
+}
+
+// FlattenPassStats summarizes a flattening pass
+type FlattenPassStats struct {
+	Resources int            // Resources read
+	Unmapped  int            // Resources whose type has no table
+	Rows      map[string]int // Rows per table
+}
+
+// FlattenFiles flattens the resources of NDJSON files in a single pass and writes each row to every sink
+// With CSV and Parquet both written in-process, the packaging steps pass a CSVTableSink and a ParquetTableSink,
+// so the data is read and its FHIRPath columns are evaluated once. Sinks are closed after the pass, or aborted if it fails
+func FlattenFiles(files []string, flattener *Flattener, sinks ...TableSink) (FlattenPassStats, error) {
+	stats := FlattenPassStats{Rows: map[string]int{}}
+	columns := map[string][]string{}
+	abort := func() {
+		for _, sink := range sinks {
+			sink.Abort()
+		}
+	}
+
+	for _, file := range files {
+		_, err := lib.ReadNDJSONFile(file, func(resource lib.FHIRResource) error {
+			stats.Resources++
+			table, row, ok := flattener.Flatten(resource)
+			if !ok {
+				stats.Unmapped++
+				return nil
+			}
+			if _, ok := columns[table]; !ok {
+				columns[table] = flattener.Columns(table)
+			}
+			for _, sink := range sinks {
+				if err := sink.WriteRow(table, columns[table], row, resource); err != nil {
+					return err
+				}
+			}
+			stats.Rows[table]++
+			return nil
+		})
+		if err != nil {
+			abort()
+			return stats, fmt.Errorf("failed to flatten %s: %w", filepath.Base(file), err)
+		}
+	}
+
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		abort()
+		return stats, err
+	}
+	return stats, nil
+}
+
+// tableColumns is the evolving schema of a table: the union of the columns of its rows
+// Columns keep their position and new ones are appended, as MergeDeltaColumns does for Delta tables
+type tableColumns struct {
+	names []string
+	index map[string]int
+}
+
+func newTableColumns(columns []string) *tableColumns {
+	c := &tableColumns{index: map[string]int{}}
+	c.add(columns)
+	return c
+}
+
+// add appends the columns not yet in the table and reports whether there were any
+func (c *tableColumns) add(columns []string) bool {
+	added := false
+	for _, column := range columns {
+		if _, ok := c.index[column]; !ok {
+			c.index[column] = len(c.names)
+			c.names = append(c.names, column)
+			added = true
+		}
+	}
+	return added
+}
+
+// place arranges a row with the given columns in the table's column order; missing columns are empty
+func (c *tableColumns) place(columns, row []string) []string {
+	if slices.Equal(columns, c.names) {
+		return row
+	}
+	placed := make([]string, len(c.names))
+	for i, column := range columns {
+		if i < len(row) {
+			placed[c.index[column]] = row[i]
+		}
+	}
+	return placed
+}
+
+// CSVTableSink writes one CSV file per table (<dir>/<ResourceType>.csv) with the table's columns as header
+// Files are written as .part and renamed when the pass completes. A table whose columns grew during
+// the pass is rewritten once at the end, with the full header and earlier rows padded with empty cells
+type CSVTableSink struct {
+	dir    string
+	tables map[string]*csvTableFile
+}
+
+// csvTableFile is a CSV table being written as <path>.part
+type csvTableFile struct {
+	file        *os.File
+	writer      *csv.Writer
+	columns     *tableColumns
+	headerWidth int // Columns in the header written at the start of the file
+}
+
+// NewCSVTableSink creates a sink writing CSV tables into dir
+func NewCSVTableSink(dir string) *CSVTableSink {
+	return &CSVTableSink{dir: dir, tables: map[string]*csvTableFile{}}
+}
+
+// WriteRow appends a row to the table's CSV file, creating it with a header on first use
+func (s *CSVTableSink) WriteRow(table string, columns []string, row []string, resource map[string]any) error {
+	t, ok := s.tables[table]
+	if !ok {
+		if err := os.MkdirAll(s.dir, 0755); err != nil {
+			return fmt.Errorf("failed to create CSV directory: %w", err)
+		}
+		file, err := os.Create(s.tablePath(table) + ".part")
+		if err != nil {
+			return fmt.Errorf("failed to create CSV table %s: %w", table, err)
+		}
+		t = &csvTableFile{file: file, writer: csv.NewWriter(file), columns: newTableColumns(columns)}
+		t.headerWidth = len(t.columns.names)
+		s.tables[table] = t
+		if err := t.writer.Write(t.columns.names); err != nil {
+			return fmt.Errorf("failed to write CSV table %s: %w", table, err)
+		}
+	} else {
+		t.columns.add(columns)
+	}
+	if err := t.writer.Write(t.columns.place(columns, row)); err != nil {
+		return fmt.Errorf("failed to write CSV table %s: %w", table, err)
+	}
+	return nil
+}
+
+// Close flushes every table, widens those whose columns grew and moves them into place
+func (s *CSVTableSink) Close() error {
+	for table, t := range s.tables {
+		t.writer.Flush()
+		err := t.writer.Error()
+		if closeErr := t.file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil && len(t.columns.names) > t.headerWidth {
+			err = widenCSVTable(s.tablePath(table)+".part", t.columns.names)
+		}
+		if err == nil {
+			err = os.Rename(s.tablePath(table)+".part", s.tablePath(table))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write CSV table %s: %w", table, err)
+		}
+		delete(s.tables, table)
+	}
+	return nil
+}
+
+// Abort removes the tables that were not completed
+func (s *CSVTableSink) Abort() {
+	for table, t := range s.tables {
+		_ = t.file.Close()
+		_ = os.Remove(s.tablePath(table) + ".part")
+	}
+	s.tables = map[string]*csvTableFile{}
+}
+
+func (s *CSVTableSink) tablePath(table string) string {
+	return filepath.Join(s.dir, table+".csv")
+}
+
+// widenCSVTable replaces the header of a CSV file and pads its shorter rows to the header's width
+func widenCSVTable(path string, header []string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := os.Create(path + ".widened")
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	writer := csv.NewWriter(dst)
+	err = writer.Write(header)
+	if _, readErr := reader.Read(); err == nil && readErr != nil {
+		err = readErr
+	}
+	padded := make([]string, len(header))
+	for err == nil {
+		var record []string
+		record, err = reader.Read()
+		if err != nil {
+			break
+		}
+		clear(padded)
+		copy(padded, record)
+		err = writer.Write(padded)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(path+".widened", path)
+	}
+	if err != nil {
+		_ = os.Remove(path + ".widened")
+	}
+	return err
+}
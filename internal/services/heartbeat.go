@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+const (
+	// HeartbeatFileName is the name of the heartbeat file in job directories and jobs_dir
+	HeartbeatFileName = "heartbeat"
+)
+
+// Heartbeat periodically refreshes heartbeat files while a job executes
+type Heartbeat struct {
+	paths  []string
+	jobID  string
+	stop   chan struct{}
+	done   chan struct{}
+	logger *lib.Logger
+}
+
+// StartHeartbeat writes jobs/<id>/heartbeat immediately and then every configured interval
+// until Stop is called. With config.Global, <jobs_dir>/heartbeat is refreshed as well
+// Returns nil if heartbeats are disabled; Stop is safe to call on a nil Heartbeat
+func StartHeartbeat(jobsBaseDir string, jobID string, config models.HeartbeatConfig, logger *lib.Logger) *Heartbeat {
+	interval := config.GetInterval()
+	if interval == 0 {
+		return nil
+	}
+
+	paths := []string{filepath.Join(GetJobDir(jobsBaseDir, jobID), HeartbeatFileName)}
+	if config.Global {
+		paths = append(paths, filepath.Join(jobsBaseDir, HeartbeatFileName))
+	}
+
+	hb := &Heartbeat{
+		paths:  paths,
+		jobID:  jobID,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+
+	hb.beat()
+	go hb.run(interval)
+	return hb
+}
+
+// Stop ends the heartbeat loop
+// The files are left in place so monitors see when the process last made progress
+func (hb *Heartbeat) Stop() {
+	if hb == nil {
+		return
+	}
+	close(hb.stop)
+	<-hb.done
+}
+
+// run refreshes the heartbeat until stopped
+func (hb *Heartbeat) run(interval time.Duration) {
+	defer close(hb.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hb.stop:
+			return
+		case <-ticker.C:
+			hb.beat()
+		}
+	}
+}
+
+// beat writes the current timestamp to every heartbeat file
+// Files are replaced atomically so monitors never read a partial line
+func (hb *Heartbeat) beat() {
+	content := fmt.Sprintf("%s pid=%d job=%s\n", lib.FormatTimestamp(time.Now()), os.Getpid(), hb.jobID)
+
+	for _, path := range hb.paths {
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			hb.logger.Debug("Failed to write heartbeat", "path", path, "error", err)
+		}
+	}
+}
+
+// writeFileAtomic replaces path with data via a uniquely named temp file and rename
+// Unique temp names keep concurrent writers (e.g., several jobs sharing the global heartbeat) apart
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// ReadHeartbeat returns the time of the last heartbeat for a job
+// Returns an error if the job has never written one
+func ReadHeartbeat(jobsBaseDir string, jobID string) (time.Time, error) {
+	data, err := os.ReadFile(filepath.Join(GetJobDir(jobsBaseDir, jobID), HeartbeatFileName))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var timestamp string
+	if _, err := fmt.Sscanf(string(data), "%s", &timestamp); err != nil {
+		return time.Time{}, fmt.Errorf("malformed heartbeat file: %w", err)
+	}
+	return time.Parse(time.RFC3339, timestamp)
+}
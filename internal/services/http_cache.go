@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// httpCacheDirName is the directory below the jobs directory holding cached HTTP responses
+const httpCacheDirName = ".http-cache"
+
+// HTTPResponseCache keeps answers to idempotent requests (GET, HEAD) on disk for a short time,
+// so several processes observing the same job (e.g. `aether pipeline status` and the server
+// UI) share one request to the upstream server instead of each sending their own
+// Entries older than the TTL are ignored; when the cache grows beyond its size limit, the
+// oldest entries are removed
+type HTTPResponseCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+}
+
+// httpCacheEntry is one cached response
+type httpCacheEntry struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     []byte      `json:"body,omitempty"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// NewHTTPResponseCache returns a cache below the jobs directory, or nil if ttl is not positive
+// A nil cache stores nothing and never hits
+func NewHTTPResponseCache(jobsBaseDir string, ttl time.Duration, maxBytes int64) *HTTPResponseCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &HTTPResponseCache{
+		dir:      filepath.Join(jobsBaseDir, httpCacheDirName),
+		ttl:      ttl,
+		maxBytes: maxBytes,
+	}
+}
+
+// NewTORCHResponseCache returns the response cache configured for a TORCH server
+// (services.torch.response_cache_seconds), or nil if it is disabled
+func NewTORCHResponseCache(jobsBaseDir string, config models.TORCHConfig) *HTTPResponseCache {
+	return NewHTTPResponseCache(jobsBaseDir, config.GetResponseCacheTTL(), config.GetResponseCacheMaxBytes())
+}
+
+// isCacheableStatus reports whether an answer is worth sharing: final results, progress
+// and gone resources; errors and auth failures are always sent again
+func isCacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// path returns the cache file of a request
+func (c *HTTPResponseCache) path(method, url string) string {
+	sum := sha256.Sum256([]byte(method + " " + url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the cached answer to req if one younger than the TTL exists
+func (c *HTTPResponseCache) Get(req *http.Request) (*http.Response, bool) {
+	if c == nil || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return nil, false
+	}
+	url := req.URL.String()
+	data, err := os.ReadFile(c.path(req.Method, url))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry httpCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Method != req.Method || entry.URL != url {
+		return nil, false
+	}
+	if age := time.Since(entry.StoredAt); age < 0 || age > c.ttl {
+		return nil, false
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}, true
+}
+
+// Store caches resp as the answer to req and returns a response with the same content
+// resp's body is read and closed; responses that are not cacheable are returned unchanged
+func (c *HTTPResponseCache) Store(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if c == nil || (req.Method != http.MethodGet && req.Method != http.MethodHead) || !isCacheableStatus(resp.StatusCode) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if int64(len(body)) > c.maxBytes/4 {
+		// Large answers (e.g. downloads) would evict everything else
+		return resp, nil
+	}
+
+	entry := httpCacheEntry{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Status:   resp.StatusCode,
+		Header:   resp.Header.Clone(),
+		Body:     body,
+		StoredAt: time.Now(),
+	}
+	if err := c.write(entry); err != nil {
+		return resp, err
+	}
+	c.evict()
+	return resp, nil
+}
+
+// Invalidate forgets the cached answers for url, e.g. after it was deleted
+func (c *HTTPResponseCache) Invalidate(url string) {
+	if c == nil {
+		return
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		_ = os.Remove(c.path(method, url))
+	}
+}
+
+// write stores an entry (atomic write, so concurrent readers never see a partial entry)
+func (c *HTTPResponseCache) write(entry httpCacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create HTTP cache directory: %w", err)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal HTTP cache entry: %w", err)
+	}
+	tempFile := filepath.Join(c.dir, fmt.Sprintf(".cache.tmp.%s", uuid.New().String()))
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write HTTP cache entry: %w", err)
+	}
+	if err := os.Rename(tempFile, c.path(entry.Method, entry.URL)); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to save HTTP cache entry: %w", err)
+	}
+	return nil
+}
+
+// evict removes expired entries, then the oldest ones until the cache fits its size limit
+func (c *HTTPResponseCache) evict() {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	type cached struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []cached
+	var total int64
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(c.dir, dirEntry.Name())
+		if time.Since(info.ModTime()) > c.ttl {
+			_ = os.Remove(path)
+			continue
+		}
+		entries = append(entries, cached{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	for _, entry := range entries {
+		if total <= c.maxBytes {
+			break
+		}
+		if os.Remove(entry.path) == nil {
+			total -= entry.size
+		}
+	}
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/trobanga/aether/internal/lib"
@@ -16,13 +19,21 @@ type HTTPClient struct {
 	client      *http.Client
 	retryConfig lib.RetryConfig
 	logger      *lib.Logger
+	events      JobEventSink
+	waits       WaitSink
+	ctx         context.Context // Step deadline; nil means no deadline
+	jobID       string          // Job identified in the User-Agent; empty for requests outside a job
+	debugLog    *lib.LogSampler // Samples the per-request debug lines; nil logs all
 }
 
 // NewHTTPClient creates an HTTP client with timeout and retry configuration
+// Requests are subject to fault injection while it is enabled (see lib.ConfigureChaos) and are
+// traced as client spans while tracing is (see lib.ConfigureTracing)
 func NewHTTPClient(timeout time.Duration, retryConfig models.RetryConfig, logger *lib.Logger) *HTTPClient {
 	return &HTTPClient{
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: lib.NewTracingTransport(lib.NewChaosTransport(nil)),
 		},
 		retryConfig: lib.NewRetryConfigFromModel(retryConfig),
 		logger:      logger,
@@ -42,6 +53,57 @@ func DefaultHTTPClient() *HTTPClient {
 	)
 }
 
+// SetEventSink routes retry events to a job's event timeline
+func (c *HTTPClient) SetEventSink(sink JobEventSink) {
+	c.events = sink
+}
+
+// SetWaitSink reports retry backoffs (and TORCH poll intervals) while they are pending
+func (c *HTTPClient) SetWaitSink(sink WaitSink) {
+	c.waits = sink
+}
+
+// SetJobID identifies the job in the User-Agent of all requests (see lib.UserAgent)
+func (c *HTTPClient) SetJobID(jobID string) {
+	c.jobID = jobID
+}
+
+// SetDebugLogSampler samples the debug lines logged for each request (see lib.LogSampler)
+// Failed requests and error responses are always logged
+func (c *HTTPClient) SetDebugLogSampler(sampler *lib.LogSampler) {
+	c.debugLog = sampler
+}
+
+// SetContext bounds all requests and retry waits by ctx (e.g., a step's time budget)
+// Once ctx is done, requests fail immediately and are not retried
+func (c *HTTPClient) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Context returns the context requests are bound to (background if none was set)
+func (c *HTTPClient) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Wait sleeps for d, returning early with an error if the client's context is done
+func (c *HTTPClient) Wait(d time.Duration) error {
+	if c.ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.ctx.Done():
+		return fmt.Errorf("request cancelled: %w", c.ctx.Err())
+	}
+}
+
 // Get performs an HTTP GET request with retry logic
 func (c *HTTPClient) Get(url string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
@@ -69,8 +131,70 @@ func (c *HTTPClient) PostJSON(url string, jsonBody []byte) (*http.Response, erro
 	return c.Post(url, "application/json", jsonBody)
 }
 
+// RequestError attaches the ID of a failed request (see http_client.request_ids) to its error
+type RequestError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// RequestIDOf returns the ID of the request that caused err, or "" if unknown
+func RequestIDOf(err error) string {
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.RequestID
+	}
+	var dimpErr *DIMPError
+	if errors.As(err, &dimpErr) {
+		return dimpErr.RequestID
+	}
+	return ""
+}
+
+// ResponseRequestID returns the request ID sent with the request of resp ("" if none was sent)
+func ResponseRequestID(resp *http.Response) string {
+	if resp == nil || resp.Request == nil {
+		return ""
+	}
+	return resp.Request.Header.Get(lib.RequestIDHeader)
+}
+
 // Do executes an HTTP request with retry logic for transient errors
+// The request keeps one request ID across retries; errors carry it as *RequestError
+// A request made with a context derived from Context(), e.g. to run inside a span, keeps it
 func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if c.ctx != nil && req.Context() == context.Background() {
+		req = req.WithContext(c.ctx)
+	}
+
+	// Identify the request once, so all retries carry the same request ID
+	requestID := lib.ApplyClientIdentity(req, c.jobID)
+	logFields := requestLogFields(requestID)
+
+	resp, err := c.doWithRetry(req, logFields)
+	if err != nil {
+		c.logger.Warn("Service call failed", append([]any{
+			"service", req.URL.Host,
+			"endpoint", req.URL.Path,
+			"method", req.Method,
+			"error", err,
+		}, logFields...)...)
+		if requestID != "" {
+			return nil, &RequestError{RequestID: requestID, Err: err}
+		}
+	}
+	return resp, err
+}
+
+// doWithRetry executes an identified request, retrying transient errors
+func (c *HTTPClient) doWithRetry(req *http.Request, logFields []any) (*http.Response, error) {
 	var resp *http.Response
 	var lastErr error
 
@@ -89,12 +213,21 @@ func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 		duration := time.Since(startTime)
 
 		// Log the request
-		lib.LogServiceCall(c.logger, req.URL.Host, req.URL.Path, req.Method)
+		if c.debugLog.Allow("Service call") {
+			lib.LogServiceCall(c.logger, req.URL.Host, req.URL.Path, req.Method, logFields...)
+		}
+
+		// Requests failing because the context finished (e.g., exhausted time budget) are not retried
+		if lastErr != nil && c.ctx != nil && c.ctx.Err() != nil {
+			return nil, fmt.Errorf("request cancelled: %w", c.ctx.Err())
+		}
 
 		// Success
 		if lastErr == nil {
 			// Log response
-			lib.LogServiceResponse(c.logger, req.URL.Host, resp.StatusCode, duration)
+			if resp.StatusCode >= 400 || c.debugLog.Allow("Service response") {
+				lib.LogServiceResponse(c.logger, req.URL.Host, resp.StatusCode, duration, logFields...)
+			}
 
 			// Check if HTTP status indicates error
 			if resp.StatusCode >= 400 {
@@ -111,7 +244,7 @@ func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 
 				// For transient errors, retry
 				if lib.ShouldRetry(errorType, attempt, c.retryConfig.MaxAttempts) {
-					lib.LogRetry(c.logger, req.URL.String(), attempt, c.retryConfig.MaxAttempts, statusErr)
+					lib.LogRetry(c.logger, req.URL.String(), attempt, c.retryConfig.MaxAttempts, statusErr, logFields...)
 
 					// Store the error in case this is the last attempt
 					lastErr = statusErr
@@ -122,7 +255,10 @@ func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 					// Wait before retry
 					if attempt < c.retryConfig.MaxAttempts-1 {
 						backoff := lib.CalculateBackoff(attempt, c.retryConfig.InitialBackoffMs, c.retryConfig.MaxBackoffMs)
-						time.Sleep(backoff)
+						c.emitRetryScheduled(req, attempt, backoff, statusErr)
+						if err := c.waitForRetry(req, attempt, backoff); err != nil {
+							return nil, err
+						}
 					}
 
 					// Reset request body for retry
@@ -142,12 +278,15 @@ func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 		if lib.IsNetworkError(lastErr) {
 			errorType := models.ErrorTypeTransient
 			if lib.ShouldRetry(errorType, attempt, c.retryConfig.MaxAttempts) {
-				lib.LogRetry(c.logger, req.URL.String(), attempt, c.retryConfig.MaxAttempts, lastErr)
+				lib.LogRetry(c.logger, req.URL.String(), attempt, c.retryConfig.MaxAttempts, lastErr, logFields...)
 
 				// Wait before retry
 				if attempt < c.retryConfig.MaxAttempts-1 {
 					backoff := lib.CalculateBackoff(attempt, c.retryConfig.InitialBackoffMs, c.retryConfig.MaxBackoffMs)
-					time.Sleep(backoff)
+					c.emitRetryScheduled(req, attempt, backoff, lastErr)
+					if err := c.waitForRetry(req, attempt, backoff); err != nil {
+						return nil, err
+					}
 				}
 
 				// Reset request body for retry
@@ -166,6 +305,22 @@ func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 	return nil, fmt.Errorf("request failed after %d attempts: %w", c.retryConfig.MaxAttempts, lastErr)
 }
 
+// send executes a request once, without retries, identified like requests of Do
+// Used for calls whose status codes the caller interprets itself (e.g., TORCH polling)
+func (c *HTTPClient) send(req *http.Request) (*http.Response, error) {
+	logFields := requestLogFields(lib.ApplyClientIdentity(req, c.jobID))
+	lib.LogServiceCall(c.logger, req.URL.Host, req.URL.Path, req.Method, logFields...)
+	return c.client.Do(req)
+}
+
+// requestLogFields returns the log fields identifying a request
+func requestLogFields(requestID string) []any {
+	if requestID == "" {
+		return nil
+	}
+	return []any{"request_id", requestID}
+}
+
 // Download downloads a file from a URL and writes it to a writer
 // Returns the number of bytes downloaded
 func (c *HTTPClient) Download(url string, writer io.Writer) (int64, error) {
@@ -216,6 +371,39 @@ func (c *HTTPClient) DownloadWithProgress(url string, writer io.Writer, progress
 	return bytesWritten, nil
 }
 
+// DownloadToFile downloads a file into file, resuming a broken transfer with Range requests
+// observer sees every byte written; restart is called if the download has to start over
+// Returns the number of bytes in the file
+func (c *HTTPClient) DownloadToFile(url string, file *os.File, observer io.Writer, restart func()) (int64, error) {
+	req, err := http.NewRequestWithContext(c.Context(), "GET", url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	statusError := func(resp *http.Response) error {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return 0, statusError(resp)
+	}
+
+	download := &resumableDownload{
+		client:      c,
+		send:        c.send,
+		statusError: statusError,
+		file:        file,
+		observer:    observer,
+		restart:     restart,
+		logger:      c.logger,
+	}
+	return download.run(req, resp)
+}
+
 // ProgressReader wraps an io.Reader and calls a callback with bytes read
 type ProgressReader struct {
 	Reader   io.Reader
@@ -231,3 +419,28 @@ func (r *ProgressReader) Read(p []byte) (int, error) {
 	}
 	return n, err
 }
+
+// waitForRetry sleeps for a retry backoff, reporting the pending retry to the wait sink
+func (c *HTTPClient) waitForRetry(req *http.Request, attempt int, backoff time.Duration) error {
+	c.waits.report(&models.WaitState{
+		Reason:      models.WaitRetry,
+		Target:      req.Method + " " + req.URL.Path,
+		Attempt:     attempt + 1,
+		MaxAttempts: c.retryConfig.MaxAttempts - 1,
+		NextAt:      time.Now().Add(backoff),
+	})
+	defer c.waits.report(nil)
+	return c.Wait(backoff)
+}
+
+// emitRetryScheduled records a retry_scheduled event for a request about to be retried
+func (c *HTTPClient) emitRetryScheduled(req *http.Request, attempt int, backoff time.Duration, cause error) {
+	c.events.emit(models.EventRetryScheduled,
+		fmt.Sprintf("retry %d/%d of %s %s in %s", attempt+1, c.retryConfig.MaxAttempts-1, req.Method, req.URL.Path, backoff),
+		map[string]any{
+			"url":        req.URL.String(),
+			"attempt":    attempt + 1,
+			"backoff_ms": backoff.Milliseconds(),
+			"error":      cause.Error(),
+		})
+}
@@ -5,31 +5,42 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/trobanga/aether/internal/i18n"
 	"github.com/trobanga/aether/internal/lib"
 	"github.com/trobanga/aether/internal/models"
 )
 
-// ImportFromLocalDirectory copies FHIR NDJSON files from a local directory to the job's import directory
+// ImportFromLocalDirectory copies FHIR NDJSON files from a local source to the job's import directory
+// The source is a directory, a glob pattern (/data/exports/2024-*/*.ndjson) or a file list (@paths.txt)
 // Returns list of imported files and any error
 func ImportFromLocalDirectory(sourcePath string, destinationDir string, logger *lib.Logger) ([]models.FHIRDataFile, error) {
-	// Validate source directory exists
-	sourceInfo, err := os.Stat(sourcePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("source directory does not exist: %s", sourcePath)
+	return ImportFromLocalSource(sourcePath, destinationDir, models.ImportConfig{}, logger)
+}
+
+// ImportFromLocalSource places FHIR NDJSON files from a local source into the job's import directory
+// Files are filtered by the config's include/exclude patterns and placed according to its link mode
+func ImportFromLocalSource(sourcePath string, destinationDir string, config models.ImportConfig, logger *lib.Logger) ([]models.FHIRDataFile, error) {
+	if !IsGlobSource(sourcePath) && !IsFileListSource(sourcePath) {
+		// Validate source directory exists
+		sourceInfo, err := os.Stat(sourcePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("source directory does not exist: %s", sourcePath)
+			}
+			return nil, fmt.Errorf("cannot access source directory: %w", err)
 		}
-		return nil, fmt.Errorf("cannot access source directory: %w", err)
-	}
 
-	if !sourceInfo.IsDir() {
-		fileExt := strings.ToLower(filepath.Ext(sourcePath))
-		switch fileExt {
-		case ".json", ".crtdl":
-			return nil, fmt.Errorf("source path is a JSON/CRTDL file, not a directory: %s. For CRTDL input, the file should have been detected as InputTypeCRTDL. This suggests the CRTDL file is invalid or missing required fields", sourcePath)
-		default:
-			return nil, fmt.Errorf("source path is not a directory: %s", sourcePath)
+		if !sourceInfo.IsDir() {
+			fileExt := strings.ToLower(filepath.Ext(sourcePath))
+			switch fileExt {
+			case ".json", ".crtdl":
+				return nil, fmt.Errorf("source path is a JSON/CRTDL file, not a directory: %s. For CRTDL input, the file should have been detected as InputTypeCRTDL. This suggests the CRTDL file is invalid or missing required fields", sourcePath)
+			default:
+				return nil, fmt.Errorf("source path is not a directory: %s", sourcePath)
+			}
 		}
 	}
 
@@ -38,32 +49,321 @@ func ImportFromLocalDirectory(sourcePath string, destinationDir string, logger *
 		return nil, fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Find all NDJSON files in source directory
-	ndjsonFiles, err := findNDJSONFiles(sourcePath)
+	// Find all NDJSON files the source refers to
+	ndjsonFiles, err := ResolveLocalSource(sourcePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan source directory: %w", err)
+		return nil, fmt.Errorf("failed to scan source: %w", err)
+	}
+
+	ndjsonFiles, skipped := FilterImportFiles(ndjsonFiles, config)
+	for _, path := range skipped {
+		logger.Debug("Skipping file excluded by import patterns", "file", path)
 	}
 
 	if len(ndjsonFiles) == 0 {
+		if len(skipped) > 0 {
+			return nil, fmt.Errorf("no FHIR NDJSON files found in %s (%d excluded by import.include/import.exclude)", sourcePath, len(skipped))
+		}
 		return nil, fmt.Errorf("no FHIR NDJSON files found in %s", sourcePath)
 	}
 
-	logger.Info("Found FHIR files", "count", len(ndjsonFiles), "source", sourcePath)
+	logger.Info("Found FHIR files", "count", len(ndjsonFiles), "excluded", len(skipped), "source", sourcePath)
 
-	// Import each file
+	// Import each file under a unique name (sources may contain the same file name in several directories)
+	destNames := uniqueImportNames(ndjsonFiles)
 	var importedFiles []models.FHIRDataFile
-	for _, srcFile := range ndjsonFiles {
-		imported, err := copyFile(srcFile, destinationDir, logger)
+	for i, srcFile := range ndjsonFiles {
+		imported, err := placeFile(srcFile, destinationDir, destNames[i], config.LinkMode, logger)
 		if err != nil {
 			return importedFiles, fmt.Errorf("failed to import %s: %w", srcFile, err)
 		}
 		importedFiles = append(importedFiles, imported)
 	}
 
-	logger.Info("Import completed", "files", len(importedFiles))
+	logger.Info("Import completed", "files", len(importedFiles), "mode", linkModeOrDefault(config.LinkMode))
 	return importedFiles, nil
 }
 
+// linkModeOrDefault maps the empty mode to copy
+func linkModeOrDefault(mode models.ImportLinkMode) models.ImportLinkMode {
+	if mode == "" {
+		return models.ImportLinkCopy
+	}
+	return mode
+}
+
+// placeFile puts a source file into destDir under fileName according to mode
+// Any existing destination is removed first, so a copy never writes through a link
+// left by an earlier run into the original source file
+func placeFile(sourcePath string, destDir string, fileName string, mode models.ImportLinkMode, logger *lib.Logger) (models.FHIRDataFile, error) {
+	destPath := filepath.Join(destDir, fileName)
+	if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
+		return models.FHIRDataFile{}, fmt.Errorf("failed to replace existing file: %w", err)
+	}
+
+	switch linkModeOrDefault(mode) {
+	case models.ImportLinkHardlink:
+		if err := os.Link(sourcePath, destPath); err != nil {
+			// Hardlinks cannot cross filesystems (EXDEV) - fall back to copying
+			logger.Info("Hardlink not possible, copying instead", "file", fileName, "reason", err)
+			return copyFile(sourcePath, destDir, fileName, logger)
+		}
+		return linkedFileInfo(sourcePath, destPath, fileName, logger)
+
+	case models.ImportLinkSymlink:
+		target, err := filepath.Abs(sourcePath)
+		if err != nil {
+			return models.FHIRDataFile{}, fmt.Errorf("failed to resolve source path: %w", err)
+		}
+		if err := os.Symlink(target, destPath); err != nil {
+			return models.FHIRDataFile{}, fmt.Errorf("failed to create symlink: %w", err)
+		}
+		return linkedFileInfo(sourcePath, destPath, fileName, logger)
+
+	default:
+		return copyFile(sourcePath, destDir, fileName, logger)
+	}
+}
+
+// linkedFileInfo builds FHIRDataFile metadata for a hardlinked or symlinked file
+// Sizes are taken from the source file, so byte accounting matches a copy
+func linkedFileInfo(sourcePath string, destPath string, fileName string, logger *lib.Logger) (models.FHIRDataFile, error) {
+	srcInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return models.FHIRDataFile{}, fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	lineCount, err := lib.CountResourcesInFile(destPath)
+	if err != nil {
+		logger.Warn("Failed to count resources", "file", fileName, "error", err)
+		lineCount = 0
+	}
+
+	logger.Debug("File linked", "file", fileName, "source", sourcePath, "size", srcInfo.Size(), "resources", lineCount)
+
+	return models.FHIRDataFile{
+		FileName:     fileName,
+		FilePath:     fileName, // Relative to job import directory
+		ResourceType: models.GetResourceTypeFromFilename(fileName),
+		FileSize:     srcInfo.Size(),
+		SourceStep:   models.StepLocalImport,
+		LineCount:    lineCount,
+		CreatedAt:    srcInfo.ModTime(),
+	}, nil
+}
+
+// CountLocalFHIRFiles returns the number of NDJSON files an import of sourcePath would copy
+// Used to enforce file count limits before any data is copied
+func CountLocalFHIRFiles(sourcePath string, config models.ImportConfig) (int, error) {
+	files, err := ResolveLocalSource(sourcePath)
+	if err != nil {
+		return 0, err
+	}
+	files, _ = FilterImportFiles(files, config)
+	return len(files), nil
+}
+
+// LocalSourceScan lists what an import of a local source picks up and leaves out
+type LocalSourceScan struct {
+	Files    []string // NDJSON files to import
+	Excluded []string // NDJSON files skipped by import.include/import.exclude
+	Ignored  []string // Other files found in scanned directories or matched by a glob (hidden files are not listed)
+}
+
+// ScanLocalSource resolves a local source like ResolveLocalSource and also returns the files left out
+// File lists are not scanned for ignored files: listing a non-NDJSON file is an error
+func ScanLocalSource(sourcePath string, config models.ImportConfig) (LocalSourceScan, error) {
+	files, err := ResolveLocalSource(sourcePath)
+	if err != nil {
+		return LocalSourceScan{}, err
+	}
+	scan := LocalSourceScan{}
+	scan.Files, scan.Excluded = FilterImportFiles(files, config)
+
+	switch {
+	case IsFileListSource(sourcePath):
+	case IsGlobSource(sourcePath):
+		matches, _ := filepath.Glob(sourcePath)
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				ignored, err := findIgnoredFiles(path)
+				if err != nil {
+					return LocalSourceScan{}, err
+				}
+				scan.Ignored = append(scan.Ignored, ignored...)
+			} else if !models.IsValidFHIRFile(info.Name()) && !strings.HasPrefix(info.Name(), ".") {
+				scan.Ignored = append(scan.Ignored, path)
+			}
+		}
+	default:
+		if scan.Ignored, err = findIgnoredFiles(sourcePath); err != nil {
+			return LocalSourceScan{}, err
+		}
+	}
+	return scan, nil
+}
+
+// findIgnoredFiles returns the non-NDJSON files below rootPath, skipping hidden files
+func findIgnoredFiles(rootPath string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") || models.IsValidFHIRFile(info.Name()) {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// FilterImportFiles splits files into those passing the import include/exclude patterns and those skipped
+// Patterns are matched against each file's base name, so the result does not depend on where the source lives
+func FilterImportFiles(files []string, config models.ImportConfig) (kept []string, skipped []string) {
+	if !config.HasFilters() {
+		return files, nil
+	}
+	for _, path := range files {
+		if config.Includes(filepath.Base(path)) {
+			kept = append(kept, path)
+		} else {
+			skipped = append(skipped, path)
+		}
+	}
+	return kept, skipped
+}
+
+// IsGlobSource reports whether a local source is a glob pattern rather than a directory
+func IsGlobSource(source string) bool {
+	return strings.ContainsAny(source, "*?[")
+}
+
+// IsFileListSource reports whether a local source names a text file listing paths (@paths.txt)
+func IsFileListSource(source string) bool {
+	return strings.HasPrefix(source, "@")
+}
+
+// ResolveLocalSource returns the NDJSON files a local source refers to, sorted and de-duplicated
+//   - directory: all .ndjson files below it (recursive)
+//   - glob pattern: matching .ndjson files; matching directories are scanned recursively
+//   - @file: one path per line (blank lines and # comments ignored), relative to the list file;
+//     every listed path must exist
+func ResolveLocalSource(source string) ([]string, error) {
+	var files []string
+
+	switch {
+	case IsFileListSource(source):
+		listed, err := readFileList(strings.TrimPrefix(source, "@"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range listed {
+			found, err := resolveLocalPath(path, true)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, found...)
+		}
+
+	case IsGlobSource(source):
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %s: %w", source, err)
+		}
+		for _, path := range matches {
+			found, err := resolveLocalPath(path, false)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, found...)
+		}
+
+	default:
+		return findNDJSONFiles(source)
+	}
+
+	sort.Strings(files)
+	unique := files[:0]
+	for i, path := range files {
+		if i == 0 || path != files[i-1] {
+			unique = append(unique, path)
+		}
+	}
+	return unique, nil
+}
+
+// resolveLocalPath expands a directory to its NDJSON files and filters plain files by extension
+// With explicit set (file lists), non-NDJSON files are an error instead of being skipped
+func resolveLocalPath(path string, explicit bool) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return findNDJSONFiles(path)
+	}
+	if !models.IsValidFHIRFile(info.Name()) {
+		if explicit {
+			return nil, fmt.Errorf("listed file is not an NDJSON file: %s", path)
+		}
+		return nil, nil
+	}
+	return []string{path}, nil
+}
+
+// readFileList reads a file list, resolving relative entries against the list's directory
+func readFileList(listPath string) ([]string, error) {
+	data, err := os.ReadFile(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file list: %w", err)
+	}
+
+	baseDir := filepath.Dir(listPath)
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(baseDir, line)
+		}
+		paths = append(paths, line)
+	}
+	return paths, nil
+}
+
+// uniqueImportNames assigns each source file a distinct name in the import directory
+// The first file with a given base name keeps it; later ones get a numeric suffix
+// (Patient.ndjson, Patient.2.ndjson, ...). Files are expected in sorted order, so names are stable across runs
+func uniqueImportNames(paths []string) []string {
+	names := make([]string, len(paths))
+	used := make(map[string]bool, len(paths))
+	for i, path := range paths {
+		name := filepath.Base(path)
+		if used[name] {
+			stem := strings.TrimSuffix(name, filepath.Ext(name))
+			ext := filepath.Ext(name)
+			for n := 2; ; n++ {
+				candidate := fmt.Sprintf("%s.%d%s", stem, n, ext)
+				if !used[candidate] {
+					name = candidate
+					break
+				}
+			}
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
 // findNDJSONFiles recursively finds all .ndjson files in a directory
 func findNDJSONFiles(rootPath string) ([]string, error) {
 	var files []string
@@ -89,9 +389,9 @@ func findNDJSONFiles(rootPath string) ([]string, error) {
 	return files, err
 }
 
-// copyFile copies a single file to the destination directory
+// copyFile copies a single file to the destination directory under fileName
 // Returns FHIRDataFile metadata
-func copyFile(sourcePath string, destDir string, logger *lib.Logger) (models.FHIRDataFile, error) {
+func copyFile(sourcePath string, destDir string, fileName string, logger *lib.Logger) (models.FHIRDataFile, error) {
 	// Open source file
 	srcFile, err := os.Open(sourcePath)
 	if err != nil {
@@ -110,7 +410,6 @@ func copyFile(sourcePath string, destDir string, logger *lib.Logger) (models.FHI
 	}
 
 	// Create destination file path
-	fileName := filepath.Base(sourcePath)
 	destPath := filepath.Join(destDir, fileName)
 
 	// Create destination file
@@ -124,18 +423,13 @@ func copyFile(sourcePath string, destDir string, logger *lib.Logger) (models.FHI
 		}
 	}()
 
-	// Copy file contents
-	bytesWritten, err := io.Copy(destFile, srcFile)
+	// Copy file contents, counting resources (lines) on the way so the file is read once
+	var lines lib.LineCounter
+	bytesWritten, err := io.Copy(io.MultiWriter(destFile, &lines), srcFile)
 	if err != nil {
 		return models.FHIRDataFile{}, fmt.Errorf("failed to copy file: %w", err)
 	}
-
-	// Count lines (FHIR resources)
-	lineCount, err := lib.CountResourcesInFile(destPath)
-	if err != nil {
-		logger.Warn("Failed to count resources", "file", fileName, "error", err)
-		lineCount = 0
-	}
+	lineCount := lines.Count()
 
 	// Extract resource type from filename
 	resourceType := models.GetResourceTypeFromFilename(fileName)
@@ -157,11 +451,23 @@ func copyFile(sourcePath string, destDir string, logger *lib.Logger) (models.FHI
 func ValidateImportSource(sourcePath string, inputType models.InputType) error {
 	switch inputType {
 	case models.InputTypeLocal:
+		// Glob patterns and file lists must resolve to at least one NDJSON file
+		if IsGlobSource(sourcePath) || IsFileListSource(sourcePath) {
+			files, err := ResolveLocalSource(sourcePath)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				return fmt.Errorf("no FHIR NDJSON files match %s\n\nExpected files with extensions: .ndjson", sourcePath)
+			}
+			return nil
+		}
+
 		// Check if directory exists and is accessible
 		info, err := os.Stat(sourcePath)
 		if err != nil {
 			if os.IsNotExist(err) {
-				return fmt.Errorf("directory does not exist: %s", sourcePath)
+				return i18n.Errorf(i18n.MsgDirectoryNotExist, sourcePath)
 			}
 			return fmt.Errorf("cannot access directory: %w", err)
 		}
@@ -172,11 +478,11 @@ func ValidateImportSource(sourcePath string, inputType models.InputType) error {
 			var hint string
 			switch fileExt {
 			case ".json", ".crtdl":
-				hint = "\n\nThis appears to be a JSON/CRTDL file. Possible issues:\n  - File may not have valid CRTDL structure (missing cohortDefinition or dataExtraction)\n  - File may be using FHIR Parameters format instead of flat CRTDL format\n\nRun with verbose logging to see detailed validation errors."
+				hint = i18n.T(i18n.MsgHintCRTDLFile)
 			case ".ndjson":
-				hint = "\n\nThis is an NDJSON file. Please provide the directory containing it, not the file itself."
+				hint = i18n.T(i18n.MsgHintNDJSONFile)
 			}
-			return fmt.Errorf("expected directory but got file: %s%s", sourcePath, hint)
+			return i18n.Errorf(i18n.MsgExpectedDirectory, sourcePath, hint)
 		}
 
 		// Check if directory contains NDJSON files
@@ -191,7 +497,7 @@ func ValidateImportSource(sourcePath string, inputType models.InputType) error {
 
 		return nil
 
-	case models.InputTypeHTTP:
+	case models.InputTypeHTTP, models.InputTypeFHIRSearch, models.InputTypeBulkExport:
 		// URL validation already done in models.Validate()
 		// Just check format
 		if sourcePath == "" {
@@ -199,6 +505,12 @@ func ValidateImportSource(sourcePath string, inputType models.InputType) error {
 		}
 		return nil
 
+	case models.InputTypeS3:
+		if _, _, err := models.ParseS3URL(sourcePath); err != nil {
+			return err
+		}
+		return nil
+
 	case models.InputTypeCRTDL:
 		// CRTDL file validation
 		if sourcePath == "" {
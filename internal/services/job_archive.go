@@ -0,0 +1,328 @@
+package services
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// ArchiveCompression is the compression of a job archive
+type ArchiveCompression string
+
+const (
+	ArchiveZstd ArchiveCompression = "zstd" // .tar.zst (default): faster and smaller than gzip for NDJSON
+	ArchiveGzip ArchiveCompression = "gzip" // .tar.gz, for tools without zstd support
+)
+
+// Magic numbers at the start of compressed archives
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ArchiveCompressionForPath selects the compression from an archive file name
+// .tar.gz and .tgz are gzip-compressed; everything else, including .tar.zst, uses zstd
+func ArchiveCompressionForPath(name string) ArchiveCompression {
+	if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") {
+		return ArchiveGzip
+	}
+	return ArchiveZstd
+}
+
+// jobRuntimeFiles are per-host files of a job directory that are never archived
+// They describe a process on the exporting machine (lock holder, liveness, pending waits)
+var jobRuntimeFiles = map[string]bool{
+	".lock":           true,
+	HeartbeatFileName: true,
+	WaitStateFileName: true,
+}
+
+// IsJobDataDir reports whether name is a step output directory of a job (import, pseudonymized, csv, ...)
+func IsJobDataDir(name string) bool {
+	switch name {
+	case "import", "pseudonymized", "imaging", "quarantine", "dead-letter", "csv", "parquet":
+		return true
+	}
+	return false
+}
+
+// ExportJobArchive writes a job as a tar archive with the given compression to w
+// The archive holds state.json, the event timeline and the step output directories
+// listed in dataDirs (all of them if dataDirs is empty), below a top-level <job-id>/ directory.
+// Lock, heartbeat and wait files are skipped, symlinked inputs are archived by content and
+// the TORCH password is removed from the configuration snapshot
+func ExportJobArchive(jobsBaseDir string, jobID string, dataDirs []string, compression ArchiveCompression, w io.Writer) error {
+	job, err := LoadJobState(jobsBaseDir, jobID)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dataDirs {
+		if !IsJobDataDir(dir) {
+			return fmt.Errorf("unknown job output directory '%s'", dir)
+		}
+	}
+	selected := func(dir string) bool {
+		if len(dataDirs) == 0 {
+			return true
+		}
+		for _, d := range dataDirs {
+			if d == dir {
+				return true
+			}
+		}
+		return false
+	}
+
+	var compressed io.WriteCloser
+	switch compression {
+	case ArchiveGzip:
+		compressed = gzip.NewWriter(w)
+	case ArchiveZstd:
+		if compressed, err = zstd.NewWriter(w); err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown archive compression '%s'", compression)
+	}
+	tw := tar.NewWriter(compressed)
+
+	// state.json comes first so that an import can identify the job early
+	exported := *job
+	exported.Config.Services.TORCH.Password = ""
+	exported.Config.Services.TORCH.ClientSecret = ""
+	exported.Config.Services.TORCH.BearerToken = ""
+	exported.Config.Services.FHIRExport.Password = ""
+	exported.Config.Services.FHIRExport.BearerToken = ""
+	exported.Config.Services.S3.SecretAccessKey = ""
+	exported.Config.Services.S3.SessionToken = ""
+	state, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job state: %w", err)
+	}
+	if err := writeArchiveFile(tw, path.Join(jobID, StateFileName), state); err != nil {
+		return err
+	}
+
+	jobDir := GetJobDir(jobsBaseDir, jobID)
+	err = filepath.WalkDir(jobDir, func(filePath string, entry os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(jobDir, filePath)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		top := strings.SplitN(rel, "/", 2)[0]
+
+		if entry.IsDir() {
+			if IsJobDataDir(top) && !selected(top) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel == StateFileName || jobRuntimeFiles[rel] || strings.HasPrefix(entry.Name(), ".state.tmp.") {
+			return nil
+		}
+		if IsJobDataDir(top) && !selected(top) {
+			return nil
+		}
+		return copyFileToArchive(tw, path.Join(jobID, rel), filePath)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive job %s: %w", jobID, err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return compressed.Close()
+}
+
+// writeArchiveFile adds an in-memory file to the archive
+func writeArchiveFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	return nil
+}
+
+// copyFileToArchive adds a file from disk to the archive, following symlinks
+func copyFileToArchive(tw *tar.Writer, name string, filePath string) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", filePath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	return nil
+}
+
+// ImportJobArchive unpacks an archive created by ExportJobArchive into the jobs directory
+// The compression (zstd or gzip) is detected from the archive's first bytes.
+// The job keeps its ID. Its configuration snapshot is rebound to the importing host:
+// jobs_dir and service endpoints/credentials are taken from config, so the job can be
+// resumed against this machine's services. Fails if a job with the same ID exists
+// Returns the imported job
+func ImportJobArchive(jobsBaseDir string, r io.Reader, config models.ProjectConfig) (*models.PipelineJob, error) {
+	if err := os.MkdirAll(jobsBaseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+
+	// Unpack into a staging directory first so a broken archive leaves no partial job behind
+	stagingDir := filepath.Join(jobsBaseDir, fmt.Sprintf(".import.%s", uuid.New().String()))
+	if err := os.Mkdir(stagingDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
+
+	jobID, err := extractJobArchive(r, stagingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := LoadJobState(stagingDir, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("archive contains no valid job state: %w", err)
+	}
+	if job.JobID != jobID {
+		return nil, fmt.Errorf("archive directory %s does not match job ID %s", jobID, job.JobID)
+	}
+
+	targetDir := GetJobDir(jobsBaseDir, jobID)
+	if _, err := os.Stat(targetDir); err == nil {
+		return nil, fmt.Errorf("job %s already exists in %s", jobID, jobsBaseDir)
+	}
+
+	job.Config.JobsDir = jobsBaseDir
+	job.Config.Services = config.Services
+	if err := SaveJobState(stagingDir, job); err != nil {
+		return nil, err
+	}
+	// SaveJobState wrote below the staging directory; the config must point to the real one
+	if err := os.Rename(GetJobDir(stagingDir, jobID), targetDir); err != nil {
+		return nil, fmt.Errorf("failed to move imported job into place: %w", err)
+	}
+
+	return job, nil
+}
+
+// extractJobArchive unpacks a job archive below destDir and returns the job ID (the top-level directory)
+// Only regular files and directories inside a single top-level directory are accepted
+func extractJobArchive(r io.Reader, destDir string) (string, error) {
+	decompressed, err := decompressArchive(r)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = decompressed.Close() }()
+
+	tr := tar.NewReader(decompressed)
+	jobID := ""
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := filepath.FromSlash(strings.TrimSuffix(header.Name, "/"))
+		if !models.IsSafePath(name) {
+			return "", fmt.Errorf("archive entry escapes the job directory: %s", header.Name)
+		}
+		top := strings.SplitN(filepath.ToSlash(filepath.Clean(name)), "/", 2)[0]
+		if jobID == "" {
+			jobID = top
+		} else if top != jobID {
+			return "", fmt.Errorf("archive contains more than one job (%s, %s)", jobID, top)
+		}
+
+		target := filepath.Join(destDir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", fmt.Errorf("failed to create %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+			}
+			if err := extractArchiveFile(tr, target); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("unsupported archive entry type for %s", header.Name)
+		}
+	}
+
+	if jobID == "" {
+		return "", fmt.Errorf("archive is empty")
+	}
+	return jobID, nil
+}
+
+// decompressArchive detects the compression of an archive from its magic number
+func decompressArchive(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, zstdMagic):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		return decoder.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		return gz, nil
+	}
+	return nil, fmt.Errorf("not a job archive (expected .tar.zst or .tar.gz)")
+}
+
+// extractArchiveFile writes the current archive entry to target
+func extractArchiveFile(tr *tar.Reader, target string) error {
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(file, tr); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to extract %s: %w", target, err)
+	}
+	return file.Close()
+}
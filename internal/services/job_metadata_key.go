@@ -0,0 +1,86 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// JobMetadataKeyFileName is the file in jobs_dir holding the generated job_metadata hash key
+const JobMetadataKeyFileName = ".job_metadata_key"
+
+// EnsureJobMetadataKey sets the hash key of a job_metadata policy in hash mode if none is configured
+// The key is read from <jobsDir>/.job_metadata_key, which is generated on first use, so
+// all jobs of an installation hash equal sources to equal values
+func EnsureJobMetadataKey(policy *models.JobMetadataConfig, jobsDir string) error {
+	if policy.Mode != models.JobMetadataHash || policy.HashKey != "" {
+		return nil
+	}
+	key, err := LoadJobMetadataKey(jobsDir)
+	if err != nil {
+		return err
+	}
+	policy.HashKey = key
+	return nil
+}
+
+// LoadJobMetadataKey returns the key in <jobsDir>/.job_metadata_key, generating it if the file does not exist
+func LoadJobMetadataKey(jobsDir string) (string, error) {
+	keyPath := filepath.Join(jobsDir, JobMetadataKeyFileName)
+	key, err := readJobMetadataKey(keyPath)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return key, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate job metadata key: %w", err)
+	}
+	if err := os.MkdirAll(jobsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+
+	// Written to a temp file and linked into place, so concurrent processes never read
+	// a partial key and agree on whichever key was linked first
+	tempFile := filepath.Join(jobsDir, JobMetadataKeyFileName+"."+uuid.New().String())
+	if err := os.WriteFile(tempFile, []byte(hex.EncodeToString(secret)+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write job metadata key: %w", err)
+	}
+	defer func() { _ = os.Remove(tempFile) }()
+	if err := os.Link(tempFile, keyPath); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("failed to write job metadata key: %w", err)
+	}
+	return readJobMetadataKey(keyPath)
+}
+
+// readJobMetadataKey reads a key file, rejecting keys that are too short
+func readJobMetadataKey(keyPath string) (string, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to read job metadata key: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if len(key) < models.MinJobMetadataHashKeyLength {
+		return "", fmt.Errorf("job metadata key %s is shorter than %d characters", keyPath, models.MinJobMetadataHashKeyLength)
+	}
+	return key, nil
+}
+
+// jobMetadataKeyDir returns the directory holding the key file for a job: its configured jobs_dir
+// before tenant scoping, or jobsBaseDir for jobs without a configuration snapshot
+func jobMetadataKeyDir(job *models.PipelineJob, jobsBaseDir string) string {
+	if job.Config.JobsDir == "" {
+		return jobsBaseDir
+	}
+	return job.Config.BaseJobsDir()
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// LoadJobTemplate reads and validates a job template file
+// Unknown keys are an error, so a misspelled setting cannot silently fall back to the
+// configuration. Relative local paths (crtdl, inputs) are resolved against the template's
+// directory, so a template works from any working directory. Environment variables in
+// paths and the webhook URL are expanded
+func LoadJobTemplate(path string) (*models.JobTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job template: %w", err)
+	}
+
+	var template models.JobTemplate
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&template); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid job template %s: %w", path, err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve job template path: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	template.Path = absPath
+	template.SHA256 = hex.EncodeToString(sum[:])
+	if template.Name == "" {
+		template.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	baseDir := filepath.Dir(absPath)
+	template.CRTDL = resolveTemplatePath(baseDir, ExpandEnvVars(template.CRTDL))
+	for i, input := range template.Inputs {
+		template.Inputs[i] = resolveTemplatePath(baseDir, ExpandEnvVars(input))
+	}
+	template.Notifications.WebhookURL = ExpandEnvVars(template.Notifications.WebhookURL)
+
+	if err := template.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid job template %s: %w", path, err)
+	}
+	return &template, nil
+}
+
+// resolveTemplatePath makes a relative local path absolute against dir; URLs are returned unchanged
+func resolveTemplatePath(dir, source string) string {
+	if source == "" || strings.Contains(source, "://") || filepath.IsAbs(source) {
+		return source
+	}
+	return filepath.Join(dir, source)
+}
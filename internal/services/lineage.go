@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// fhirOutputSteps lists the steps writing FHIR NDJSON, latest in the pipeline first
+var fhirOutputSteps = []models.StepName{
+	models.StepImaging, models.StepDIMP, models.StepTorchImport, models.StepLocalImport, models.StepHttpImport,
+}
+
+// JobFHIROutputDir returns the directory with the final FHIR NDJSON of a job: the output of
+// its last completed imaging, DIMP or import step. Returns false if none of them completed
+func JobFHIROutputDir(jobsBaseDir string, job *models.PipelineJob) (string, bool) {
+	for _, name := range fhirOutputSteps {
+		if step, found := models.GetStepByName(*job, name); found && step.Status == models.StepStatusCompleted {
+			return GetJobOutputDir(jobsBaseDir, job.JobID, name), true
+		}
+	}
+	return "", false
+}
+
+// Lineage is the provenance of a job: the jobs it was created from and the jobs created from it
+type Lineage struct {
+	Ancestors   models.LineageNode `json:"ancestors"`   // The job, with Parents filled recursively
+	Descendants models.LineageNode `json:"descendants"` // The job, with Children filled recursively
+}
+
+// BuildLineage reads the parent_jobs of all jobs in the jobs directory and returns the
+// lineage of one job. Deleted parents are included as missing nodes
+func BuildLineage(jobsBaseDir string, jobID string, logger *lib.Logger) (*Lineage, error) {
+	jobIDs, err := ListAllJobs(jobsBaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(map[string]*models.PipelineJob, len(jobIDs))
+	children := map[string][]string{}
+	for _, id := range jobIDs {
+		job, err := LoadJobState(jobsBaseDir, id)
+		if err != nil {
+			logger.Warn("Failed to load job", "job_id", id, "error", err)
+			continue
+		}
+		jobs[id] = job
+		for _, parent := range job.ParentJobs {
+			children[parent] = append(children[parent], id)
+		}
+	}
+	if _, ok := jobs[jobID]; !ok {
+		return nil, fmt.Errorf("job not found: %s", jobID)
+	}
+
+	node := func(id string) models.LineageNode {
+		if job, ok := jobs[id]; ok {
+			return models.NewLineageNode(*job)
+		}
+		return models.LineageNode{JobID: id, Missing: true}
+	}
+
+	// A job can only name jobs that existed before it as parents, so the graph has no cycles;
+	// the path check only guards against hand-edited state files
+	var ancestors func(id string, path map[string]bool) models.LineageNode
+	ancestors = func(id string, path map[string]bool) models.LineageNode {
+		current := node(id)
+		if job, ok := jobs[id]; ok && !path[id] {
+			path[id] = true
+			for _, parent := range job.ParentJobs {
+				current.Parents = append(current.Parents, ancestors(parent, path))
+			}
+			delete(path, id)
+		}
+		return current
+	}
+	var descendants func(id string, path map[string]bool) models.LineageNode
+	descendants = func(id string, path map[string]bool) models.LineageNode {
+		current := node(id)
+		if !path[id] {
+			path[id] = true
+			for _, child := range sortedByCreation(children[id], jobs) {
+				current.Children = append(current.Children, descendants(child, path))
+			}
+			delete(path, id)
+		}
+		return current
+	}
+
+	return &Lineage{
+		Ancestors:   ancestors(jobID, map[string]bool{}),
+		Descendants: descendants(jobID, map[string]bool{}),
+	}, nil
+}
+
+// sortedByCreation orders job IDs oldest first
+func sortedByCreation(ids []string, jobs map[string]*models.PipelineJob) []string {
+	sorted := append([]string(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return jobs[sorted[i]].CreatedAt.Before(jobs[sorted[j]].CreatedAt)
+	})
+	return sorted
+}
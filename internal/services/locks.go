@@ -3,6 +3,9 @@ package services
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/trobanga/aether/internal/lib"
@@ -38,9 +41,25 @@ func WithJobLock(jobsDir string, jobID string, logger *lib.Logger, fn func() err
 
 // writeLockInfo writes debug information to the lock file
 func (jl *JobLock) writeLockInfo() error {
-	lockInfo := fmt.Sprintf("pid=%d\ntime=%s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+	lockInfo := fmt.Sprintf("pid=%d\ntime=%s\n", os.Getpid(), lib.FormatTimestamp(time.Now()))
 	_ = jl.lockFile.Truncate(0)
 	_, _ = jl.lockFile.Seek(0, 0)
 	_, _ = jl.lockFile.WriteString(lockInfo)
 	return jl.lockFile.Sync()
 }
+
+// LockHolderPID returns the process ID recorded in a job's lock file
+// The PID is only meaningful while IsJobLocked reports the job as locked
+func LockHolderPID(jobsDir string, jobID string) (int, bool) {
+	data, err := os.ReadFile(filepath.Join(GetJobDir(jobsDir, jobID), ".lock"))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, found := strings.CutPrefix(line, "pid="); found {
+			pid, err := strconv.Atoi(strings.TrimSpace(value))
+			return pid, err == nil && pid > 0
+		}
+	}
+	return 0, false
+}
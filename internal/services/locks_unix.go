@@ -114,3 +114,20 @@ func IsJobLocked(jobsDir string, jobID string) bool {
 	_ = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
 	return false
 }
+
+// tryLockFile opens path and takes an exclusive lock on it without blocking (Unix implementation)
+// Returns a nil file if another process holds the lock; closing the file releases it
+func tryLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return file, nil
+}
@@ -169,3 +169,29 @@ func IsJobLocked(jobsDir string, jobID string) bool {
 	)
 	return false
 }
+
+// tryLockFile opens path and takes an exclusive lock on it without blocking (Windows implementation)
+// Returns a nil file if another process holds the lock; closing the file releases it
+func tryLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	overlapped := syscall.Overlapped{}
+	r1, _, err := procLockFileEx.Call(
+		uintptr(syscall.Handle(file.Fd())),
+		uintptr(LOCKFILE_EXCLUSIVE_LOCK|LOCKFILE_FAIL_IMMEDIATELY),
+		0,
+		uintptr(1),
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if r1 == 0 {
+		_ = file.Close()
+		if err == ERROR_LOCK_VIOLATION {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return file, nil
+}
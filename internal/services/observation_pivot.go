@@ -0,0 +1,388 @@
+package services
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// observationPivotBuckets is the number of spill files the long rows are hashed into by group
+// Only one bucket's groups are held in memory while pivoting
+const observationPivotBuckets = 64
+
+// observationPivot locates the pivot's columns in the long Observation table
+type observationPivot struct {
+	header      []string       // Group-by columns followed by one column per code
+	groupBy     []int          // Long column of each group-by column
+	code        int            // Long column holding the code
+	value       int            // Long column holding the value
+	codeColumns map[string]int // Wide column per configured code
+}
+
+func newObservationPivot(longColumns []string, pivot models.ObservationPivotConfig) (*observationPivot, error) {
+	if err := pivot.ValidateAgainst(longColumns); err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(longColumns))
+	for i, column := range longColumns {
+		index[column] = i
+	}
+	p := &observationPivot{
+		code:        index[pivot.GetCodeColumn()],
+		value:       index[pivot.GetValueColumn()],
+		codeColumns: make(map[string]int, len(pivot.Codes)),
+	}
+	for _, name := range pivot.GetGroupBy() {
+		p.groupBy = append(p.groupBy, index[name])
+		p.header = append(p.header, name)
+	}
+	for _, code := range pivot.Codes {
+		p.codeColumns[code.Code] = len(p.header)
+		p.header = append(p.header, code.ColumnName())
+	}
+	return p, nil
+}
+
+// entry returns the group values, wide column and value of a long row, or false if its code is not pivoted
+func (p *observationPivot) entry(longRow []string) ([]string, int, string, bool) {
+	column, ok := p.codeColumns[longRow[p.code]]
+	if !ok {
+		return nil, 0, "", false
+	}
+	groupValues := make([]string, len(p.groupBy))
+	for i, index := range p.groupBy {
+		groupValues[i] = longRow[index]
+	}
+	return groupValues, column, longRow[p.value], true
+}
+
+// observationGroups collects wide rows in order of their group's first appearance
+type observationGroups struct {
+	width int
+	rows  [][]string
+	first []int64 // Sequence number of the first long row of each wide row
+	index map[string]int
+}
+
+func newObservationGroups(width int) *observationGroups {
+	return &observationGroups{width: width, index: map[string]int{}}
+}
+
+// add merges a value into the wide row of its group; several values are joined with FlattenValueSeparator
+func (g *observationGroups) add(seq int64, groupValues []string, column int, value string) {
+	groupKey := strings.Join(groupValues, "\x00")
+	i, exists := g.index[groupKey]
+	if !exists {
+		wideRow := make([]string, g.width)
+		copy(wideRow, groupValues)
+		i = len(g.rows)
+		g.index[groupKey] = i
+		g.rows = append(g.rows, wideRow)
+		g.first = append(g.first, seq)
+	}
+
+	wideRow := g.rows[i]
+	if wideRow[column] == "" {
+		wideRow[column] = value
+	} else if value != "" {
+		wideRow[column] += FlattenValueSeparator + value
+	}
+}
+
+// PivotObservations turns long Observation rows into a wide table with one column per configured code
+// Rows are grouped by the group-by columns in order of first appearance. Observations with other codes
+// are ignored; several values for the same group and code are joined with FlattenValueSeparator.
+// All rows are held in memory; a flattening pass uses ObservationPivotSink instead
+func PivotObservations(longColumns []string, longRows [][]string, pivot models.ObservationPivotConfig) ([]string, [][]string, error) {
+	p, err := newObservationPivot(longColumns, pivot)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := newObservationGroups(len(p.header))
+	for seq, longRow := range longRows {
+		if groupValues, column, value, ok := p.entry(longRow); ok {
+			groups.add(int64(seq), groupValues, column, value)
+		}
+	}
+	return p.header, groups.rows, nil
+}
+
+// ObservationPivotSink pivots the Observation rows of a flattening pass with bounded memory
+// Rows with a pivoted code are spilled to files in dir, hashed by group. WriteTo then pivots one
+// bucket at a time and merges the buckets back into the order in which the groups first appeared,
+// so the result matches PivotObservations without holding all Observations in memory
+type ObservationPivotSink struct {
+	dir     string
+	pivot   *observationPivot
+	seq     int64
+	files   []*os.File
+	writers []*csv.Writer
+}
+
+// NewObservationPivotSink creates a pivot sink spilling into dir, which it creates and removes again
+// longColumns are the columns of the long Observation table, which the pivot's columns must be among
+func NewObservationPivotSink(dir string, longColumns []string, pivot models.ObservationPivotConfig) (*ObservationPivotSink, error) {
+	p, err := newObservationPivot(longColumns, pivot)
+	if err != nil {
+		return nil, err
+	}
+	return &ObservationPivotSink{
+		dir:     dir,
+		pivot:   p,
+		files:   make([]*os.File, observationPivotBuckets),
+		writers: make([]*csv.Writer, observationPivotBuckets),
+	}, nil
+}
+
+// Columns returns the columns of the wide table
+func (s *ObservationPivotSink) Columns() []string {
+	return s.pivot.header
+}
+
+// WriteRow spills an Observation row with a pivoted code to the bucket of its group
+// Rows of other tables are ignored. The long table's columns are fixed by the mapping, so columns is not consulted
+func (s *ObservationPivotSink) WriteRow(table string, columns []string, row []string, resource map[string]any) error {
+	if table != "Observation" {
+		return nil
+	}
+	groupValues, column, value, ok := s.pivot.entry(row)
+	if !ok {
+		return nil
+	}
+
+	hash := fnv.New32a()
+	for _, groupValue := range groupValues {
+		_, _ = hash.Write([]byte(groupValue))
+		_, _ = hash.Write([]byte{0})
+	}
+	bucket := int(hash.Sum32() % observationPivotBuckets)
+	if s.writers[bucket] == nil {
+		if err := os.MkdirAll(s.dir, 0755); err != nil {
+			return fmt.Errorf("failed to create observation pivot directory: %w", err)
+		}
+		file, err := os.Create(s.bucketPath(bucket))
+		if err != nil {
+			return fmt.Errorf("failed to spill observations: %w", err)
+		}
+		s.files[bucket] = file
+		s.writers[bucket] = csv.NewWriter(file)
+	}
+
+	record := append([]string{strconv.FormatInt(s.seq, 10), strconv.Itoa(column), value}, groupValues...)
+	s.seq++
+	if err := s.writers[bucket].Write(record); err != nil {
+		return fmt.Errorf("failed to spill observations: %w", err)
+	}
+	return nil
+}
+
+// Close flushes the spilled rows; WriteTo reads them
+func (s *ObservationPivotSink) Close() error {
+	var errs []error
+	for bucket, writer := range s.writers {
+		if writer == nil {
+			continue
+		}
+		writer.Flush()
+		errs = append(errs, writer.Error(), s.files[bucket].Close())
+		s.writers[bucket] = nil
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to spill observations: %w", err)
+	}
+	return nil
+}
+
+// Abort removes the spilled rows; it is also used to clean up after WriteTo
+func (s *ObservationPivotSink) Abort() {
+	for bucket, writer := range s.writers {
+		if writer != nil {
+			_ = s.files[bucket].Close()
+			s.writers[bucket] = nil
+		}
+	}
+	_ = os.RemoveAll(s.dir)
+}
+
+// WriteTo pivots the spilled rows and writes the wide rows as table to every sink, then closes them
+// Returns the number of wide rows. The sinks are aborted if writing fails
+func (s *ObservationPivotSink) WriteTo(table string, sinks ...TableSink) (int, error) {
+	rows, err := s.writeTo(table, sinks)
+	if err == nil {
+		var errs []error
+		for _, sink := range sinks {
+			errs = append(errs, sink.Close())
+		}
+		err = errors.Join(errs...)
+	}
+	if err != nil {
+		for _, sink := range sinks {
+			sink.Abort()
+		}
+		return 0, fmt.Errorf("observation pivot: %w", err)
+	}
+	return rows, nil
+}
+
+func (s *ObservationPivotSink) writeTo(table string, sinks []TableSink) (int, error) {
+	// Pivot every bucket into a run of wide rows ordered by first appearance
+	var runs []*observationRun
+	defer func() {
+		for _, run := range runs {
+			_ = run.file.Close()
+		}
+	}()
+	for bucket, file := range s.files {
+		if file == nil {
+			continue
+		}
+		run, err := s.pivotBucket(bucket)
+		if err != nil {
+			return 0, err
+		}
+		if run != nil {
+			runs = append(runs, run)
+		}
+	}
+
+	// Merge the runs by the sequence number of each row's first long row
+	merge := observationRunHeap{}
+	for _, run := range runs {
+		ok, err := run.next()
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			merge = append(merge, run)
+		}
+	}
+	heap.Init(&merge)
+
+	rows := 0
+	for merge.Len() > 0 {
+		run := merge[0]
+		for _, sink := range sinks {
+			if err := sink.WriteRow(table, s.pivot.header, run.row, nil); err != nil {
+				return rows, err
+			}
+		}
+		rows++
+
+		ok, err := run.next()
+		if err != nil {
+			return rows, err
+		}
+		if ok {
+			heap.Fix(&merge, 0)
+		} else {
+			heap.Pop(&merge)
+		}
+	}
+	return rows, nil
+}
+
+// pivotBucket groups the rows of one bucket and writes the wide rows, prefixed with the sequence
+// number of their first long row, to a run file
+func (s *ObservationPivotSink) pivotBucket(bucket int) (*observationRun, error) {
+	in, err := os.Open(s.bucketPath(bucket))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = in.Close() }()
+
+	groups := newObservationGroups(len(s.pivot.header))
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spilled observations: %w", err)
+		}
+		seq, _ := strconv.ParseInt(record[0], 10, 64)
+		column, _ := strconv.Atoi(record[1])
+		groups.add(seq, record[3:], column, record[2])
+	}
+	if len(groups.rows) == 0 {
+		return nil, nil
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("run-%02d.csv", bucket))
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := csv.NewWriter(out)
+	record := make([]string, 1+len(s.pivot.header))
+	for i, wideRow := range groups.rows {
+		record[0] = strconv.FormatInt(groups.first[i], 10)
+		copy(record[1:], wideRow)
+		_ = writer.Write(record)
+	}
+	writer.Flush()
+	err = writer.Error()
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write pivoted observations: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &observationRun{file: file, reader: csv.NewReader(file)}, nil
+}
+
+func (s *ObservationPivotSink) bucketPath(bucket int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("bucket-%02d.csv", bucket))
+}
+
+// observationRun reads the wide rows of one pivoted bucket in order
+type observationRun struct {
+	file   *os.File
+	reader *csv.Reader
+	first  int64
+	row    []string
+}
+
+// next reads the run's next row, returning false at its end
+func (r *observationRun) next() (bool, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read pivoted observations: %w", err)
+	}
+	r.first, _ = strconv.ParseInt(record[0], 10, 64)
+	r.row = record[1:]
+	return true, nil
+}
+
+// observationRunHeap orders runs by the first long row of their current wide row
+type observationRunHeap []*observationRun
+
+func (h observationRunHeap) Len() int           { return len(h) }
+func (h observationRunHeap) Less(i, j int) bool { return h[i].first < h[j].first }
+func (h observationRunHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *observationRunHeap) Push(x any)        { *h = append(*h, x.(*observationRun)) }
+func (h *observationRunHeap) Pop() any {
+	old := *h
+	run := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return run
+}
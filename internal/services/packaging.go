@@ -0,0 +1,98 @@
+package services
+
+import (
+	"path"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// HivePartitionDefault is the partition value for resources without a usable date
+// Spark and Trino read it as NULL
+const HivePartitionDefault = "__HIVE_DEFAULT_PARTITION__"
+
+// resourceDateElements are the elements whose year partitions a resource, in order of preference
+var resourceDateElements = [][]string{
+	{"effectiveDateTime"},
+	{"effectivePeriod", "start"},
+	{"effectiveInstant"},
+	{"onsetDateTime"},
+	{"performedDateTime"},
+	{"performedPeriod", "start"},
+	{"period", "start"},
+	{"authoredOn"},
+	{"recordedDate"},
+	{"collection", "collectedDateTime"},
+	{"issued"},
+	{"date"},
+	{"birthDate"},
+	{"meta", "lastUpdated"},
+}
+
+// ParquetPartitionDir returns the Hive-style partition directory of a resource (e.g. resource_type=Observation/year=2024)
+// Returns "" if no partitioning is configured
+func ParquetPartitionDir(options models.ParquetOptions, resource map[string]any) string {
+	var segments []string
+	for _, partition := range options.PartitionBy {
+		switch partition {
+		case models.PartitionByResourceType:
+			resourceType, _ := resource["resourceType"].(string)
+			if resourceType == "" {
+				resourceType = HivePartitionDefault
+			}
+			segments = append(segments, "resource_type="+resourceType)
+		case models.PartitionByYear:
+			segments = append(segments, "year="+ResourceYear(resource))
+		}
+	}
+	return path.Join(segments...)
+}
+
+// ResourceYear returns the year of a resource's clinically relevant date, or HivePartitionDefault
+func ResourceYear(resource map[string]any) string {
+	for _, element := range resourceDateElements {
+		if date := resourceDateElement(resource, element); isYearPrefix(date) {
+			return date[:4]
+		}
+	}
+	return HivePartitionDefault
+}
+
+// ResourceDate returns the date of the care a resource documents, or "" if it has none
+// Unlike ResourceYear, birthDate and meta.lastUpdated are not used: they do not date any care
+func ResourceDate(resource map[string]any) string {
+	for _, element := range resourceDateElements {
+		if element[0] == "birthDate" || element[0] == "meta" {
+			continue
+		}
+		if date := resourceDateElement(resource, element); isYearPrefix(date) {
+			return date
+		}
+	}
+	return ""
+}
+
+// resourceDateElement returns the string at a path of nested elements, or ""
+func resourceDateElement(resource map[string]any, element []string) string {
+	var value any = resource
+	for _, name := range element {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = object[name]
+	}
+	date, _ := value.(string)
+	return date
+}
+
+func isYearPrefix(date string) bool {
+	if len(date) < 4 {
+		return false
+	}
+	for _, c := range date[:4] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
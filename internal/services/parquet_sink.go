@@ -0,0 +1,315 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// parquetRowGroupRows bounds the rows buffered in memory before a row group is written out
+const parquetRowGroupRows = 64 * 1024
+
+// ParquetTableSink writes one Parquet table directory per resource type (<dir>/<ResourceType>/)
+// The schema is inferred from the flattened columns: every column is an optional string in mapping
+// order, and empty cells are written as null. Rows go to the Hive-style partition directory of their
+// resource (packaging.parquet.partition_by), and a file is rolled over to the next part-NNNNN.parquet
+// once it reaches packaging.parquet.target_file_size_mb. Files are written as .part and renamed when complete.
+// When a table's columns grow during the pass, its open files are completed and the next ones use the
+// evolved schema; at the end, files with fewer columns are rewritten with the added columns as null
+type ParquetTableSink struct {
+	dir     string
+	options models.ParquetOptions
+	fileTag string                       // Appended to file names, e.g. part-00000-<tag>.parquet
+	columns map[string]*tableColumns     // Evolving schema per table
+	open    map[string]*parquetTableFile // Open file per table partition directory
+	parts   map[string]int               // Files started per table partition directory
+	written []*parquetTableFile          // Completed files, removed again if the pass is aborted
+}
+
+// NewParquetTableSink creates a sink writing Parquet tables into dir
+// A non-empty fileTag is appended to the file names (part-00000-<tag>.parquet), so the files of
+// several runs can coexist in a table directory, as Delta tables need
+func NewParquetTableSink(dir string, options models.ParquetOptions, fileTag string) *ParquetTableSink {
+	return &ParquetTableSink{
+		dir:     dir,
+		options: options,
+		fileTag: fileTag,
+		columns: map[string]*tableColumns{},
+		open:    map[string]*parquetTableFile{},
+		parts:   map[string]int{},
+	}
+}
+
+// WriteRow appends a row to the file of the table's partition, starting a new file on first use,
+// after the previous one reached the target size and after the table's columns grew
+func (s *ParquetTableSink) WriteRow(table string, columns []string, row []string, resource map[string]any) error {
+	schema, ok := s.columns[table]
+	if !ok {
+		schema = newTableColumns(columns)
+		s.columns[table] = schema
+	} else if schema.add(columns) {
+		for partitionDir, file := range s.open {
+			if file.table == table {
+				if err := s.complete(partitionDir, file); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	partitionDir := filepath.Join(table, filepath.FromSlash(ParquetPartitionDir(s.options, resource)))
+	file, ok := s.open[partitionDir]
+	if !ok {
+		name := fmt.Sprintf("part-%05d", s.parts[partitionDir])
+		if s.fileTag != "" {
+			name += "-" + s.fileTag
+		}
+		path := filepath.Join(s.dir, partitionDir, name+".parquet")
+		var err error
+		file, err = createParquetTableFile(path, table, slices.Clone(schema.names), s.options)
+		if err != nil {
+			return err
+		}
+		s.open[partitionDir] = file
+		s.parts[partitionDir]++
+	}
+
+	if err := file.write(schema.place(columns, row)); err != nil {
+		return err
+	}
+	if file.writer.Size() >= s.options.GetTargetFileSizeBytes() {
+		return s.complete(partitionDir, file)
+	}
+	return nil
+}
+
+// complete closes the open file of a partition directory
+func (s *ParquetTableSink) complete(partitionDir string, file *parquetTableFile) error {
+	delete(s.open, partitionDir)
+	if err := file.close(); err != nil {
+		file.abort()
+		return err
+	}
+	s.written = append(s.written, file)
+	return nil
+}
+
+// Close completes every open file and moves it into place, then rewrites files written before
+// their table's columns grew
+func (s *ParquetTableSink) Close() error {
+	for partitionDir, file := range s.open {
+		if err := s.complete(partitionDir, file); err != nil {
+			return err
+		}
+	}
+	for _, file := range s.written {
+		if columns := s.columns[file.table].names; len(file.columns) < len(columns) {
+			if err := widenParquetFile(file.path, file.table, columns, s.options); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Abort removes the files of the pass, so no partial tables are left behind
+func (s *ParquetTableSink) Abort() {
+	for _, file := range s.open {
+		file.abort()
+	}
+	for _, file := range s.written {
+		_ = os.Remove(file.path)
+		_ = os.Remove(file.path + ".part")
+	}
+	s.open = map[string]*parquetTableFile{}
+	s.written = nil
+}
+
+// WriteParquetTable writes a complete table with the given columns to <dir>/<table>/
+// Such tables are computed from several resources (e.g. the wide Observation table), so they are
+// not partitioned; files are still rolled over at the target size
+func WriteParquetTable(dir, table string, columns []string, rows [][]string, options models.ParquetOptions, fileTag string) error {
+	options.PartitionBy = nil
+	sink := NewParquetTableSink(dir, options, fileTag)
+	for _, row := range rows {
+		if err := sink.WriteRow(table, columns, row, nil); err != nil {
+			sink.Abort()
+			return err
+		}
+	}
+	if err := sink.Close(); err != nil {
+		sink.Abort()
+		return err
+	}
+	return nil
+}
+
+// widenParquetFile rewrites a completed file of a table with the table's evolved columns
+// Columns only ever get appended, so the file's rows keep their values and get nulls for the new columns
+func widenParquetFile(path, table string, columns []string, options models.ParquetOptions) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to widen Parquet table %s: %w", table, err)
+	}
+	defer func() { _ = src.Close() }()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to widen Parquet table %s: %w", table, err)
+	}
+	parquetFile, err := parquet.OpenFile(src, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to widen Parquet table %s: %w", table, err)
+	}
+
+	dst, err := createParquetTableFile(path, table, columns, options)
+	if err != nil {
+		return err
+	}
+	reader := parquet.NewReader(parquetFile)
+	defer func() { _ = reader.Close() }()
+	width := len(parquetFile.Schema().Fields())
+	buffer := make([]parquet.Row, 1024)
+	for {
+		n, readErr := reader.ReadRows(buffer)
+		for i := range buffer[:n] {
+			for column := width; column < len(columns); column++ {
+				buffer[i] = append(buffer[i], parquet.NullValue().Level(0, 0, column))
+			}
+		}
+		if _, err := dst.writer.WriteRows(buffer[:n]); err != nil {
+			dst.abort()
+			return fmt.Errorf("failed to widen Parquet table %s: %w", table, err)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			dst.abort()
+			return fmt.Errorf("failed to widen Parquet table %s: %w", table, readErr)
+		}
+	}
+	if err := dst.close(); err != nil {
+		dst.abort()
+		return err
+	}
+	return nil
+}
+
+// parquetTableFile is a Parquet file being written as <path>.part
+type parquetTableFile struct {
+	table   string
+	path    string
+	columns []string
+	file    *os.File
+	writer  *parquet.Writer
+	row     parquet.Row
+}
+
+func createParquetTableFile(path, table string, columns []string, options models.ParquetOptions) (*parquetTableFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create Parquet directory: %w", err)
+	}
+	file, err := os.Create(path + ".part")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet table %s: %w", table, err)
+	}
+	schema := ParquetTableSchema(table, columns, options.DictionaryEncoding)
+	writer := parquet.NewWriter(file, schema,
+		parquet.Compression(parquetCodec(options.GetCompression())),
+		parquet.MaxRowsPerRowGroup(parquetRowGroupRows))
+	return &parquetTableFile{
+		table:   table,
+		path:    path,
+		columns: columns,
+		file:    file,
+		writer:  writer,
+		row:     make(parquet.Row, len(columns)),
+	}, nil
+}
+
+func (f *parquetTableFile) write(row []string) error {
+	for i := range f.row {
+		if i < len(row) && row[i] != "" {
+			f.row[i] = parquet.ByteArrayValue([]byte(row[i])).Level(0, 1, i)
+		} else {
+			f.row[i] = parquet.NullValue().Level(0, 0, i)
+		}
+	}
+	if _, err := f.writer.WriteRows([]parquet.Row{f.row}); err != nil {
+		return fmt.Errorf("failed to write Parquet table %s: %w", f.table, err)
+	}
+	return nil
+}
+
+func (f *parquetTableFile) close() error {
+	err := f.writer.Close()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.path+".part", f.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write Parquet table %s: %w", f.table, err)
+	}
+	return nil
+}
+
+func (f *parquetTableFile) abort() {
+	_ = f.file.Close()
+	_ = os.Remove(f.path + ".part")
+}
+
+// ParquetTableSchema returns the schema of a flattened table: optional string columns in the given order
+// With dictionary encoding, repeated values such as codes and units are stored once per column chunk
+func ParquetTableSchema(table string, columns []string, dictionaryEncoding bool) *parquet.Schema {
+	fields := make([]parquet.Field, len(columns))
+	for i, column := range columns {
+		node := parquet.Optional(parquet.String())
+		if dictionaryEncoding {
+			node = parquet.Encoded(node, &parquet.RLEDictionary)
+		}
+		fields[i] = parquetField{Node: node, name: column}
+	}
+	return parquet.NewSchema(table, orderedGroup{Group: parquet.Group{}, fields: fields})
+}
+
+// orderedGroup is a group node that keeps its fields in the given order
+// parquet.Group sorts fields by name, which would lose the column order of the mapping
+type orderedGroup struct {
+	parquet.Group
+	fields []parquet.Field
+}
+
+func (g orderedGroup) Fields() []parquet.Field { return g.fields }
+
+// parquetField names a column node within an orderedGroup
+type parquetField struct {
+	parquet.Node
+	name string
+}
+
+func (f parquetField) Name() string { return f.name }
+
+// Value is only used when writing Go structs; rows are written as parquet.Row
+func (f parquetField) Value(base reflect.Value) reflect.Value { return reflect.Value{} }
+
+func parquetCodec(compression models.ParquetCompression) compress.Codec {
+	switch compression {
+	case models.ParquetZstd:
+		return &parquet.Zstd
+	case models.ParquetGzip:
+		return &parquet.Gzip
+	case models.ParquetNone:
+		return &parquet.Uncompressed
+	default:
+		return &parquet.Snappy
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// ErrQuotaReached is wrapped by the errors of CheckJobQuota
+var ErrQuotaReached = errors.New("quota reached")
+
+// CheckJobQuota returns an error if creating another job in jobsBaseDir would exceed quota
+// Jobs whose state cannot be loaded count towards max_jobs but not towards max_active_jobs
+func CheckJobQuota(jobsBaseDir string, quota models.QuotaConfig) error {
+	if quota.MaxJobs == 0 && quota.MaxActiveJobs == 0 {
+		return nil
+	}
+
+	jobIDs, err := ListAllJobs(jobsBaseDir)
+	if err != nil {
+		return err
+	}
+	if quota.MaxJobs > 0 && len(jobIDs) >= quota.MaxJobs {
+		return fmt.Errorf("job %w: %d of %d jobs exist in %s (delete or export old jobs first)", ErrQuotaReached, len(jobIDs), quota.MaxJobs, jobsBaseDir)
+	}
+
+	if quota.MaxActiveJobs > 0 {
+		active := 0
+		for _, jobID := range jobIDs {
+			job, err := LoadJobState(jobsBaseDir, jobID)
+			if err != nil {
+				continue
+			}
+			if job.Status == models.JobStatusPending || job.Status == models.JobStatusInProgress {
+				active++
+			}
+		}
+		if active >= quota.MaxActiveJobs {
+			return fmt.Errorf("active job %w: %d of %d jobs are pending or in progress", ErrQuotaReached, active, quota.MaxActiveJobs)
+		}
+	}
+	return nil
+}
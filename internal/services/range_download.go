@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// resumableDownload copies the body of a file download into a file. When the transfer breaks,
+// the missing bytes are requested with a Range header instead of downloading the file again.
+// If-Range carries the ETag (or Last-Modified) of the first response, so a file that changed on
+// the server is sent in full and the download starts over
+type resumableDownload struct {
+	client      *HTTPClient
+	send        func(*http.Request) (*http.Response, error) // Sends one request, e.g. with TORCH authorization
+	statusError func(*http.Response) error                  // Error for an unexpected status of a resume request
+	file        *os.File
+	observer    io.Writer // Sees every byte written, e.g. a line counter or progress
+	restart     func()    // Resets the observer when the download starts over
+	logger      *lib.Logger
+
+	written   int64
+	size      int64  // Total size from Content-Length or Content-Range, -1 if unknown
+	etag      string // Strong ETag of the file, "" if none
+	validator string // If-Range value; "" if the transfer cannot be resumed
+}
+
+// interruptedTransferError marks a download whose connection broke, so it is resumed
+type interruptedTransferError struct {
+	Err error
+}
+
+func (e *interruptedTransferError) Error() string { return e.Err.Error() }
+func (e *interruptedTransferError) Unwrap() error { return e.Err }
+
+// run copies resp, the 200 response to req, resuming it until the file is complete or the
+// retry attempts are used up. The final size is checked against the size the server announced
+func (d *resumableDownload) run(req *http.Request, resp *http.Response) (int64, error) {
+	d.begin(resp)
+	err := d.copy(resp)
+	for attempt := 0; err != nil; attempt++ {
+		var interrupted *interruptedTransferError
+		if !errors.As(err, &interrupted) {
+			return d.written, err
+		}
+		if attempt+1 >= d.client.retryConfig.MaxAttempts {
+			return d.written, fmt.Errorf("download interrupted after %d bytes: %w", d.written, interrupted.Err)
+		}
+
+		backoff := lib.CalculateBackoff(attempt, d.client.retryConfig.InitialBackoffMs, d.client.retryConfig.MaxBackoffMs)
+		lib.LogRetry(d.logger, req.URL.String(), attempt, d.client.retryConfig.MaxAttempts, interrupted.Err)
+		d.client.emitRetryScheduled(req, attempt, backoff, interrupted.Err)
+		if err := d.client.waitForRetry(req, attempt, backoff); err != nil {
+			return d.written, err
+		}
+		err = d.resume(req)
+	}
+
+	if d.size >= 0 && d.written != d.size {
+		return d.written, fmt.Errorf("incomplete download: received %d of %d bytes", d.written, d.size)
+	}
+	return d.written, nil
+}
+
+// begin records size and validator of a response carrying the whole file
+func (d *resumableDownload) begin(resp *http.Response) {
+	d.size = resp.ContentLength
+	d.etag = resp.Header.Get("ETag")
+	if strings.HasPrefix(d.etag, "W/") {
+		d.etag = "" // Weak ETags must not be used in If-Range
+	}
+
+	d.validator = d.etag
+	if d.validator == "" {
+		d.validator = resp.Header.Get("Last-Modified")
+	}
+	// Ranges address the encoded body, which the transport already decompressed
+	if resp.Uncompressed || resp.Header.Get("Accept-Ranges") == "none" {
+		d.validator = ""
+	}
+}
+
+// copy appends the body of resp to the file
+// A failed read returns *interruptedTransferError; a failed write is returned as is
+func (d *resumableDownload) copy(resp *http.Response) error {
+	defer func() { _ = resp.Body.Close() }()
+	body := &readErrorRecorder{reader: resp.Body}
+	n, err := io.Copy(io.MultiWriter(d.file, d.observer), body)
+	d.written += n
+	if body.err != nil {
+		return &interruptedTransferError{Err: body.err}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// resume requests the bytes not received yet and copies them
+// A server that ignores the range or sends a changed file restarts the download from byte 0
+func (d *resumableDownload) resume(req *http.Request) error {
+	retry := req.Clone(req.Context())
+	resuming := d.validator != "" && d.written > 0
+	if resuming {
+		retry.Header.Set("Range", fmt.Sprintf("bytes=%d-", d.written))
+		retry.Header.Set("If-Range", d.validator)
+	}
+
+	resp, err := d.send(retry)
+	if err != nil {
+		if lib.IsNetworkError(err) {
+			return &interruptedTransferError{Err: err}
+		}
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && resuming:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		etag := resp.Header.Get("ETag")
+		if !ok || start != d.written || (d.etag != "" && etag != "" && etag != d.etag) {
+			_ = resp.Body.Close()
+			d.validator = "" // Start over with the next attempt
+			return &interruptedTransferError{Err: fmt.Errorf("unexpected range %q (ETag %s) in answer to bytes=%d-", resp.Header.Get("Content-Range"), etag, d.written)}
+		}
+		if total >= 0 {
+			d.size = total
+		}
+		d.logger.Info("Resuming download", "url", req.URL.String(), "offset", d.written, "size", d.size)
+		return d.copy(resp)
+
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && resuming:
+		_ = resp.Body.Close()
+		if d.size >= 0 && d.written == d.size {
+			return nil // The connection broke after the last byte
+		}
+		d.validator = ""
+		return &interruptedTransferError{Err: fmt.Errorf("server cannot resume at byte %d", d.written)}
+
+	case resp.StatusCode == http.StatusOK:
+		if resuming {
+			d.logger.Info("Server sent the whole file, restarting download", "url", req.URL.String(), "discarded_bytes", d.written)
+		}
+		if err := d.reset(); err != nil {
+			_ = resp.Body.Close()
+			return err
+		}
+		d.begin(resp)
+		return d.copy(resp)
+
+	default:
+		err := d.statusError(resp)
+		_ = resp.Body.Close()
+		if lib.ClassifyHTTPError(resp.StatusCode) == models.ErrorTypeTransient {
+			return &interruptedTransferError{Err: err}
+		}
+		return err
+	}
+}
+
+// reset empties the file to download it from the start
+func (d *resumableDownload) reset() error {
+	if err := d.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	if _, err := d.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	d.written = 0
+	if d.restart != nil {
+		d.restart()
+	}
+	return nil
+}
+
+// readErrorRecorder remembers the error of the underlying reader, to tell a broken
+// connection from a failed write after io.Copy
+type readErrorRecorder struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// parseContentRange parses "bytes <start>-<end>/<total>"; total is -1 if given as *
+func parseContentRange(value string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	byteRange, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, total, true
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PartialFileSuffix marks a file that is still being written: downloads and step outputs
+// are written to <name>.part and renamed when complete. A .part file in a job that is not
+// running was left behind by an interrupted process
+const PartialFileSuffix = ".part"
+
+// stateTempPrefix is the prefix of the temporary files SaveJobState renames to state.json
+const stateTempPrefix = ".state.tmp."
+
+// RemovePartialFiles deletes the .part files and temporary state files of a job
+// Only call it while holding the job lock: a running job writes these files.
+// Returns the removed paths relative to the job directory, sorted
+func RemovePartialFiles(jobsBaseDir string, jobID string) ([]string, error) {
+	jobDir := GetJobDir(jobsBaseDir, jobID)
+
+	var removed []string
+	err := filepath.WalkDir(jobDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, PartialFileSuffix) && !strings.HasPrefix(name, stateTempPrefix)) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		rel, _ := filepath.Rel(jobDir, path)
+		removed = append(removed, rel)
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	slices.Sort(removed)
+	return removed, nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// StepReport summarizes one step of a run for `aether report diff`
+type StepReport struct {
+	Status          models.StepStatus          `json:"status"`
+	DurationSeconds float64                    `json:"duration_seconds,omitempty"` // Zero while the step has not completed
+	Files           int                        `json:"files"`
+	Resources       *int                       `json:"resources,omitempty"` // Resources in the step's NDJSON output; nil for steps without FHIR output or deleted output
+	Warnings        map[models.WarningCode]int `json:"warnings,omitempty"`  // Affected lines, files or resources per warning class
+}
+
+// StepDiff compares a step of two runs; A or B is nil if the step did not run in that job
+type StepDiff struct {
+	Step models.StepName `json:"step"`
+	A    *StepReport     `json:"a,omitempty"`
+	B    *StepReport     `json:"b,omitempty"`
+}
+
+// JobDiff compares two runs, typically of the same CRTDL before and after an upgrade
+type JobDiff struct {
+	JobA      string     `json:"job_a"`
+	JobB      string     `json:"job_b"`
+	SameInput bool       `json:"same_input"`
+	InputNote string     `json:"input_note"`        // How the inputs were compared, e.g. the CRTDL checksum
+	StudyA    string     `json:"study_a,omitempty"` // Study of job A from its CRTDL (see models.JobAnnotations)
+	StudyB    string     `json:"study_b,omitempty"`
+	Steps     []StepDiff `json:"steps"`
+}
+
+// DiffJobs compares the step durations, resource counts and warnings of two jobs
+func DiffJobs(jobsBaseDir string, jobIDA string, jobIDB string) (*JobDiff, error) {
+	jobA, err := LoadJobState(jobsBaseDir, jobIDA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job %s: %w", jobIDA, err)
+	}
+	jobB, err := LoadJobState(jobsBaseDir, jobIDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job %s: %w", jobIDB, err)
+	}
+
+	diff := &JobDiff{JobA: jobA.JobID, JobB: jobB.JobID, StudyA: jobA.Annotations.Label(), StudyB: jobB.Annotations.Label()}
+	diff.SameInput, diff.InputNote = compareJobInputs(jobA, jobB)
+
+	for _, name := range models.AllStepNames {
+		stepDiff := StepDiff{
+			Step: name,
+			A:    newStepReport(jobsBaseDir, jobA, name),
+			B:    newStepReport(jobsBaseDir, jobB, name),
+		}
+		if stepDiff.A != nil || stepDiff.B != nil {
+			diff.Steps = append(diff.Steps, stepDiff)
+		}
+	}
+	return diff, nil
+}
+
+// compareJobInputs reports whether two jobs ran on the same input
+// CRTDL inputs are compared by content, since the same CRTDL is often passed from different paths
+func compareJobInputs(a *models.PipelineJob, b *models.PipelineJob) (bool, string) {
+	if a.InputType != b.InputType {
+		return false, fmt.Sprintf("different input types: %s and %s", a.InputType, b.InputType)
+	}
+	if a.InputType == models.InputTypeCRTDL {
+		hashA, errA := HashCRTDL(a.InputSource)
+		hashB, errB := HashCRTDL(b.InputSource)
+		if errA == nil && errB == nil {
+			if hashA != hashB {
+				return false, fmt.Sprintf("different CRTDLs (sha256 %s and %s)", hashA[:12], hashB[:12])
+			}
+			return true, fmt.Sprintf("same CRTDL (sha256 %s)", hashA[:12])
+		}
+	}
+	if a.InputSource != b.InputSource {
+		return false, fmt.Sprintf("different inputs: %s and %s", a.InputSource, b.InputSource)
+	}
+	return true, "same input: " + a.InputSource
+}
+
+// newStepReport summarizes a step of a job, or returns nil if the job has no such step
+func newStepReport(jobsBaseDir string, job *models.PipelineJob, name models.StepName) *StepReport {
+	step, found := models.GetStepByName(*job, name)
+	if !found {
+		return nil
+	}
+
+	report := &StepReport{
+		Status: step.Status,
+		Files:  step.FilesProcessed,
+	}
+	if step.StartedAt != nil && step.CompletedAt != nil {
+		report.DurationSeconds = step.CompletedAt.Sub(*step.StartedAt).Round(time.Millisecond).Seconds()
+	}
+	if slices.Contains(fhirOutputSteps, name) {
+		if count, err := countNDJSONResources(GetJobOutputDir(jobsBaseDir, job.JobID, name)); err == nil {
+			report.Resources = &count
+		}
+	}
+	for _, warning := range step.Warnings {
+		if report.Warnings == nil {
+			report.Warnings = make(map[models.WarningCode]int)
+		}
+		report.Warnings[warning.Code] += max(warning.Count, 1)
+	}
+	return report
+}
+
+// countNDJSONResources counts the non-empty lines of the NDJSON files in dir
+// Lines are not parsed, so counting stays fast for large outputs
+func countNDJSONResources(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for _, path := range files {
+		lines, err := lib.CountResourcesInFile(path)
+		if err != nil {
+			return 0, err
+		}
+		count += lines
+	}
+	return count, nil
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// RunbookFileName is the operator guide written into the directory of a failed job
+const RunbookFileName = "RUNBOOK.md"
+
+// runbookContextLines is how many log lines of the failed step attempt the runbook shows
+const runbookContextLines = 15
+
+// Failure codes of a runbook, derived from the failed step's error by ClassifyFailure
+const (
+	FailureInterrupted     = "job_interrupted"
+	FailureCancelled       = "job_cancelled"
+	FailureRuntimeExceeded = "runtime_exceeded"
+	FailureStrictWarnings  = "strict_warnings"
+	FailurePostCondition   = "post_condition_failed"
+	FailureAuth            = "auth_failed"
+	FailurePayloadTooLarge = "payload_too_large"
+	FailureOversizedEntry  = "oversized_resource"
+	FailureLineTooLong     = "line_too_long"
+	FailureNotFound        = "not_found"
+	FailureRateLimited     = "rate_limited"
+	FailureServiceError    = "service_error"
+	FailureTimeout         = "timeout"
+	FailureNetwork         = "network_error"
+	FailureDiskFull        = "disk_full"
+	FailureTransient       = "transient_error"
+	FailureStepFailed      = "step_failed"
+)
+
+// failureGuidance is the remediation shown for each failure code
+var failureGuidance = map[string]string{
+	FailureInterrupted: "The process running the job ended unexpectedly (crash, `kill -9`, reboot or OOM kill). " +
+		"`continue` removes partial files and re-runs the step. If it happens again, check the system log for the OOM killer " +
+		"and lower `pipeline.dimp.parallelism`.",
+	FailureCancelled: "The job was cancelled through the API or `aether serve`. Resume it once the reason for cancelling is resolved.",
+	FailureRuntimeExceeded: "The run exceeded `pipeline.max_runtime_minutes`. Raise the limit or resume outside peak hours; " +
+		"completed steps are not repeated.",
+	FailureStrictWarnings: "`pipeline.strict` escalated warnings of the step. List them with `aether pipeline status`, fix the input, " +
+		"or remove the warning class from `pipeline.strict_warnings` if the data is acceptable.",
+	FailurePostCondition: "The step's output violates `pipeline.post_conditions`. Check whether the input was incomplete; " +
+		"if the output is expected, adjust the thresholds.",
+	FailureAuth: "The service rejected the credentials. Check `username`/`password`, tokens and OAuth2 client settings of the " +
+		"service (`services.torch`, `services.fhir_export`, `services.s3`); static tokens may have expired.",
+	FailurePayloadTooLarge: "DIMP or a proxy in front of it rejected a request as too large (HTTP 413). Lower " +
+		"`services.dimp.bundle_split_threshold_mb`, enable `services.dimp.adaptive_split`, or raise the proxy's body size limit.",
+	FailureOversizedEntry: "A single resource is larger than the Bundle split threshold and cannot be split further. Raise " +
+		"`services.dimp.bundle_split_threshold_mb` and the body size limit of the DIMP proxy.",
+	FailureLineTooLong: "An NDJSON line exceeds `limits.max_line_size_mb`. Raise the limit if the machine has the memory, " +
+		"or have the source split its Bundles.",
+	FailureNotFound: "A URL, extraction or export no longer exists (HTTP 404/410); results may have expired on the server. " +
+		"Check the input source. If it is gone, start a new job instead of resuming.",
+	FailureRateLimited: "The service throttled requests (HTTP 429). Wait, reduce `pipeline.dimp.parallelism` or " +
+		"`services.torch.max_active_extractions`, then resume.",
+	FailureServiceError: "The service failed or was unavailable (HTTP 5xx) through all retries. Check its health with " +
+		"`aether preflight` and its logs (the request ID below identifies the call), then resume.",
+	FailureTimeout: "An operation did not finish in time. Check the service's load, then raise the timeout that applies " +
+		"(`services.torch.extraction_timeout_minutes`, `services.fhir_export.timeout_minutes`, `pipeline.time_budget`) and resume.",
+	FailureNetwork: "The service could not be reached. Check its URL, DNS, proxies and firewalls (`aether preflight` tests " +
+		"all configured services), then resume.",
+	FailureDiskFull: "The disk holding the jobs directory is full. Free space (e.g. delete old jobs with their outputs), then resume.",
+	FailureTransient: "A temporary failure persisted through all retries (`retry.max_attempts`). Resume once the cause is resolved; " +
+		"the error below names the failing call.",
+	FailureStepFailed: "The step failed with an error that retrying will not fix. Read the error and log lines below, correct " +
+		"the input or configuration, then resume.",
+}
+
+// ClassifyFailure returns the failure code of a failed step from its error (nil if the step
+// recorded none) and the job's error message
+func ClassifyFailure(stepErr *models.StepError, jobMessage string) string {
+	message := jobMessage
+	status := 0
+	transient := false
+	if stepErr != nil {
+		message = stepErr.Message
+		status = stepErr.HTTPStatus
+		transient = stepErr.Type == models.ErrorTypeTransient
+	}
+	lower := strings.ToLower(message)
+
+	switch {
+	case strings.HasPrefix(lower, "interrupted:"):
+		return FailureInterrupted
+	case lower == "job cancelled":
+		return FailureCancelled
+	case strings.Contains(lower, "exceeded its maximum runtime"):
+		return FailureRuntimeExceeded
+	case strings.HasPrefix(lower, "strict mode:"):
+		return FailureStrictWarnings
+	case strings.Contains(lower, "post-conditions of step"):
+		return FailurePostCondition
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || strings.Contains(lower, "http 401") || strings.Contains(lower, "http 403"):
+		return FailureAuth
+	case status == http.StatusRequestEntityTooLarge || strings.Contains(lower, "http 413"):
+		return FailurePayloadTooLarge
+	case strings.Contains(lower, "exceeds threshold"):
+		return FailureOversizedEntry
+	case strings.Contains(lower, "limits.max_line_size_mb"):
+		return FailureLineTooLong
+	case status == http.StatusNotFound || status == http.StatusGone || strings.Contains(lower, "http 404") || strings.Contains(lower, "http 410"):
+		return FailureNotFound
+	case status == http.StatusTooManyRequests || strings.Contains(lower, "http 429"):
+		return FailureRateLimited
+	case status >= 500 || strings.Contains(lower, "http 5"):
+		return FailureServiceError
+	case strings.Contains(lower, "no space left on device"):
+		return FailureDiskFull
+	case strings.Contains(lower, "timeout") || strings.Contains(lower, "deadline exceeded"):
+		return FailureTimeout
+	case strings.Contains(lower, "connection refused") || strings.Contains(lower, "no such host") || strings.Contains(lower, "connection reset"):
+		return FailureNetwork
+	case transient:
+		return FailureTransient
+	}
+	return FailureStepFailed
+}
+
+// WriteRunbook writes RUNBOOK.md into the directory of a failed job: the failing step, its
+// failure code with remediation and the commands resuming the job. The runbook of a job that
+// is no longer failed is removed, so a stale one never outlives the resume
+func WriteRunbook(jobsBaseDir string, job *models.PipelineJob) error {
+	path := filepath.Join(GetJobDir(jobsBaseDir, job.JobID), RunbookFileName)
+	if job.Status != models.JobStatusFailed {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale runbook: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(RenderRunbook(job)), 0644); err != nil {
+		return fmt.Errorf("failed to write runbook: %w", err)
+	}
+	return nil
+}
+
+// RenderRunbook returns the runbook of a failed job as Markdown
+func RenderRunbook(job *models.PipelineJob) string {
+	stepName, stepErr := failedStep(job)
+	code := ClassifyFailure(stepErr, job.ErrorMessage)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Runbook: job %s\n\n", job.JobID)
+	fmt.Fprintf(&b, "Generated when the job failed at %s. It is removed when the job is resumed.\n\n",
+		job.UpdatedAt.Format(time.RFC3339))
+
+	b.WriteString("## Failure\n\n")
+	if stepName != "" {
+		fmt.Fprintf(&b, "- **Step:** `%s`\n", stepName)
+	}
+	fmt.Fprintf(&b, "- **Error code:** `%s`\n", code)
+	if stepErr != nil {
+		fmt.Fprintf(&b, "- **Error type:** %s\n", stepErr.Type)
+		if stepErr.HTTPStatus > 0 {
+			fmt.Fprintf(&b, "- **HTTP status:** %d\n", stepErr.HTTPStatus)
+		}
+		if stepErr.RequestID != "" {
+			fmt.Fprintf(&b, "- **Request ID:** `%s`\n", stepErr.RequestID)
+		}
+	}
+	message := job.ErrorMessage
+	if stepErr != nil && stepErr.Message != "" {
+		message = stepErr.Message
+	}
+	if message != "" {
+		fmt.Fprintf(&b, "\n```\n%s\n```\n", strings.TrimSpace(message))
+	}
+
+	b.WriteString("\n## Remediation\n\n")
+	b.WriteString(failureGuidance[code] + "\n")
+
+	b.WriteString("\n## Commands\n\n")
+	flags := runbookFlags(job)
+	b.WriteString("Inspect the job, including warnings and the log lines of the failed attempt:\n\n")
+	fmt.Fprintf(&b, "```bash\naether pipeline status %s%s --json\n```\n\n", job.JobID, flags)
+	b.WriteString("Resume the job; the failed step runs again and later steps follow:\n\n")
+	fmt.Fprintf(&b, "```bash\naether pipeline continue %s%s\n```\n", job.JobID, flags)
+	if stepName != "" {
+		b.WriteString("\nRetry only the failed step:\n\n")
+		fmt.Fprintf(&b, "```bash\naether job run %s --step %s%s\n```\n", job.JobID, stepName, flags)
+	}
+	b.WriteString("\nAdd `--config <file>` if the job was started with a configuration file other than the default.\n")
+
+	if stepErr != nil && len(stepErr.Context) > 0 {
+		lines := stepErr.Context
+		if len(lines) > runbookContextLines {
+			lines = lines[len(lines)-runbookContextLines:]
+		}
+		fmt.Fprintf(&b, "\n## Last Log Lines\n\n```\n%s\n```\n", strings.Join(lines, "\n"))
+	}
+	return b.String()
+}
+
+// failedStep returns the step the job failed at and its error: the last failed step, or the
+// current step if none recorded a failure (e.g. the job failed between steps)
+func failedStep(job *models.PipelineJob) (models.StepName, *models.StepError) {
+	for i := len(job.Steps) - 1; i >= 0; i-- {
+		if job.Steps[i].Status == models.StepStatusFailed {
+			return job.Steps[i].Name, job.Steps[i].LastError
+		}
+	}
+	return models.StepName(job.CurrentStep), nil
+}
+
+// runbookFlags returns the global flags that select the job's jobs directory and tenant
+func runbookFlags(job *models.PipelineJob) string {
+	var flags string
+	if baseDir := job.Config.BaseJobsDir(); baseDir != "" {
+		if abs, err := filepath.Abs(baseDir); err == nil {
+			baseDir = abs
+		}
+		flags += " --jobs-dir " + shellQuote(baseDir)
+	}
+	if job.Config.Tenant != "" {
+		flags += " --tenant " + shellQuote(job.Config.Tenant)
+	}
+	return flags
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything but safe characters
+func shellQuote(s string) string {
+	safe := s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@", r))
+	}) < 0
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
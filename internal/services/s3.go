@@ -0,0 +1,577 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// S3Client reads and writes objects of S3-compatible storage (AWS S3, MinIO) with AWS Signature
+// Version 4. Requests go through the HTTPClient, so they share its retries, request IDs and the
+// step deadline. Without credentials requests are sent unsigned (public buckets)
+type S3Client struct {
+	config     models.S3Config // With environment fallbacks applied
+	endpoint   *url.URL
+	httpClient *HTTPClient
+	logger     *lib.Logger
+}
+
+// S3Object is an object listed under a prefix
+type S3Object struct {
+	Key  string
+	Size int64
+}
+
+// S3Error represents a failed S3 request with the error code from the response body
+type S3Error struct {
+	Operation  string // "list", "get", "put", "create-multipart", "upload-part", "complete-multipart"
+	StatusCode int
+	Code       string // e.g. NoSuchBucket, AccessDenied, SignatureDoesNotMatch
+	Message    string
+	ErrorType  models.ErrorType
+}
+
+func (e *S3Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("S3 %s error: HTTP %d: %s: %s", e.Operation, e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("S3 %s error: HTTP %d: %s", e.Operation, e.StatusCode, e.Message)
+}
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// NewS3Client creates a client for the storage in config. Unset credentials, region and endpoint
+// are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION
+// (AWS_DEFAULT_REGION) and AWS_ENDPOINT_URL_S3 (AWS_ENDPOINT_URL); they are resolved here so
+// that they are never persisted with the job
+func NewS3Client(config models.S3Config, httpClient *HTTPClient, logger *lib.Logger) (*S3Client, error) {
+	config = resolveS3Config(config)
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint '%s'", config.Endpoint)
+	}
+	return &S3Client{
+		config:     config,
+		endpoint:   endpoint,
+		httpClient: httpClient,
+		logger:     logger,
+	}, nil
+}
+
+// resolveS3Config fills unset connection settings from the standard AWS environment variables
+func resolveS3Config(config models.S3Config) models.S3Config {
+	if config.AccessKeyID == "" && config.SecretAccessKey == "" {
+		config.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		config.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if config.SessionToken == "" {
+			config.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+	if config.Region == "" {
+		config.Region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), models.DefaultS3Region)
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL"),
+			"https://s3."+config.Region+".amazonaws.com")
+	}
+	return config
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ListObjects returns all objects whose key starts with prefix, following continuation tokens
+func (c *S3Client) ListObjects(bucket, prefix string) ([]S3Object, error) {
+	var objects []S3Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.do(http.MethodGet, bucket, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, s3ResponseError("list", resp)
+		}
+
+		var result struct {
+			Contents []struct {
+				Key  string `xml:"Key"`
+				Size int64  `xml:"Size"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 list response: %w", err)
+		}
+		for _, content := range result.Contents {
+			objects = append(objects, S3Object{Key: content.Key, Size: content.Size})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// GetObject streams an object into w and returns the number of bytes written
+func (c *S3Client) GetObject(bucket, key string, w io.Writer) (int64, error) {
+	resp, err := c.do(http.MethodGet, bucket, key, nil, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return 0, s3ResponseError("get", resp)
+	}
+	return io.Copy(w, resp.Body)
+}
+
+// PutObject uploads body as one object
+func (c *S3Client) PutObject(bucket, key string, body []byte) error {
+	resp, err := c.do(http.MethodPut, bucket, key, nil, body, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError("put", resp)
+	}
+	return nil
+}
+
+// UploadFile uploads a local file to key. Files larger than the configured part size are sent
+// as a multipart upload, holding one part in memory at a time; a failed multipart upload is
+// aborted so the storage does not keep its parts
+func (c *S3Client) UploadFile(bucket, key, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", filePath, err)
+	}
+
+	partSize := c.config.GetPartSize()
+	if info.Size() <= partSize {
+		body, err := io.ReadAll(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		return c.PutObject(bucket, key, body)
+	}
+
+	uploadID, err := c.createMultipartUpload(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := c.uploadParts(bucket, key, uploadID, file, partSize); err != nil {
+		if abortErr := c.abortMultipartUpload(bucket, key, uploadID); abortErr != nil {
+			c.logger.Warn("Failed to abort S3 multipart upload", "bucket", bucket, "key", key, "error", abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// s3CompletedPart identifies an uploaded part in CompleteMultipartUpload
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// uploadParts sends file in parts of partSize and completes the upload
+func (c *S3Client) uploadParts(bucket, key, uploadID string, file io.Reader, partSize int64) error {
+	var parts []s3CompletedPart
+	buffer := make([]byte, partSize)
+	for partNumber := 1; ; partNumber++ {
+		n, err := io.ReadFull(file, buffer)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read part %d: %w", partNumber, err)
+		}
+
+		query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+		resp, err := c.do(http.MethodPut, bucket, key, query, buffer[:n], nil)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return s3ResponseError("upload-part", resp)
+		}
+		parts = append(parts, s3CompletedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+		if n < len(buffer) {
+			break
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("failed to encode multipart completion: %w", err)
+	}
+	resp, err := c.do(http.MethodPost, bucket, key, url.Values{"uploadId": {uploadID}}, body,
+		map[string]string{"Content-Type": "application/xml"})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// A completion can fail after the 200 status was sent; the error is then in the body
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || bytes.Contains(data, []byte("<Error>")) {
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		return s3ResponseError("complete-multipart", resp)
+	}
+	return nil
+}
+
+// createMultipartUpload starts a multipart upload and returns its upload ID
+func (c *S3Client) createMultipartUpload(bucket, key string) (string, error) {
+	resp, err := c.do(http.MethodPost, bucket, key, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", s3ResponseError("create-multipart", resp)
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("invalid S3 multipart upload response: %v", err)
+	}
+	return result.UploadID, nil
+}
+
+// abortMultipartUpload discards the parts of an unfinished upload
+func (c *S3Client) abortMultipartUpload(bucket, key, uploadID string) error {
+	resp, err := c.do(http.MethodDelete, bucket, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3ResponseError("abort-multipart", resp)
+	}
+	return nil
+}
+
+// do sends a signed request for key in bucket (the bucket itself if key is empty)
+func (c *S3Client) do(method, bucket, key string, query url.Values, body []byte, headers map[string]string) (*http.Response, error) {
+	req, err := c.NewSignedRequest(method, bucket, key, query, body, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return c.httpClient.Do(req)
+}
+
+// NewSignedRequest builds a request for key in bucket (the bucket itself if key is empty), signed at now
+// Only host, x-amz-content-sha256, x-amz-date and x-amz-security-token are signed, so further
+// headers can be added to the returned request
+func (c *S3Client) NewSignedRequest(method, bucket, key string, query url.Values, body []byte, now time.Time) (*http.Request, error) {
+	requestURL := *c.endpoint
+	objectPath := "/" + key
+	if c.config.VirtualHostedStyle {
+		requestURL.Host = bucket + "." + requestURL.Host
+	} else {
+		objectPath = "/" + bucket + objectPath
+		if key == "" {
+			objectPath = "/" + bucket
+		}
+	}
+	requestURL.Path = strings.TrimSuffix(c.endpoint.Path, "/") + objectPath
+	requestURL.RawPath = s3EscapePath(requestURL.Path)
+	requestURL.RawQuery = s3CanonicalQuery(query)
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(c.httpClient.Context(), method, requestURL.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	c.sign(req, body, now)
+	return req, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req
+// (https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html)
+func (c *S3Client) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.config.SessionToken)
+	}
+	if c.config.AccessKeyID == "" {
+		return
+	}
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if c.config.SessionToken != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	date := now.Format("20060102")
+	scope := date + "/" + c.config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.config.SecretAccessKey), date)
+	for _, part := range []string{c.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.config.AccessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes everything but unreserved characters, as SigV4 requires
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || (keepSlash && ch == '/') {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// s3EscapePath encodes an object path for the request line and the canonical request
+func s3EscapePath(p string) string {
+	return s3Escape(p, true)
+}
+
+// s3CanonicalQuery encodes query parameters sorted by name, as SigV4 requires
+func s3CanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			pairs = append(pairs, s3Escape(name, false)+"="+s3Escape(value, false))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// s3ResponseError describes an unexpected response with the code and message of its XML body
+func s3ResponseError(operation string, resp *http.Response) *S3Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	s3Err := &S3Error{
+		Operation:  operation,
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+		ErrorType:  lib.ClassifyHTTPError(resp.StatusCode),
+	}
+	var parsed struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &parsed) == nil && parsed.Code != "" {
+		s3Err.Code = parsed.Code
+		s3Err.Message = parsed.Message
+	}
+	// Throttling and internal errors reported with 200 (completion) are worth retrying
+	if s3Err.Code == "SlowDown" || s3Err.Code == "InternalError" {
+		s3Err.ErrorType = models.ErrorTypeTransient
+	}
+	return s3Err
+}
+
+// ImportFromS3 downloads the NDJSON objects under an s3://bucket/prefix source into destDir
+// Objects are filtered by import.include/import.exclude on their base name; objects with the
+// same base name under different "directories" get numbered names (Patient.2.ndjson)
+func ImportFromS3(source string, destDir string, config models.ProjectConfig, httpClient *HTTPClient, logger *lib.Logger, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
+	bucket, prefix, err := models.ParseS3URL(source)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewS3Client(config.Services.S3, httpClient, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	objects, err := client.ListObjects(bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	sizes := make(map[string]int64, len(objects))
+	for _, object := range objects {
+		if models.IsValidFHIRFile(object.Key) {
+			keys = append(keys, object.Key)
+			sizes[object.Key] = object.Size
+		}
+	}
+	keys, skipped := FilterImportFiles(keys, config.Import)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no FHIR NDJSON objects found under %s (%d excluded by import.include/import.exclude)", source, len(skipped))
+	}
+	logger.Info("Found FHIR objects", "count", len(keys), "excluded", len(skipped), "source", source)
+
+	destNames := uniqueImportNames(keys)
+	var importedFiles []models.FHIRDataFile
+	for i, key := range keys {
+		task := progress.Start(fmt.Sprintf("Downloading object %d/%d: %s", i+1, len(keys), destNames[i]), sizes[key])
+		file, err := downloadS3Object(client, bucket, key, filepath.Join(destDir, destNames[i]), task)
+		task.Done(err)
+		if err != nil {
+			return importedFiles, fmt.Errorf("failed to download s3://%s/%s: %w", bucket, key, err)
+		}
+		importedFiles = append(importedFiles, file)
+	}
+	logger.Info("S3 import completed", "files", len(importedFiles), "source", source)
+	return importedFiles, nil
+}
+
+// downloadS3Object downloads one object to destPath via a .part file
+func downloadS3Object(client *S3Client, bucket, key, destPath string, task lib.ProgressTask) (models.FHIRDataFile, error) {
+	partPath := destPath + PartialFileSuffix
+	destFile, err := os.Create(partPath)
+	if err != nil {
+		return models.FHIRDataFile{}, fmt.Errorf("failed to create destination file: %w", err)
+	}
+	var lines lib.LineCounter
+	bytesWritten, err := client.GetObject(bucket, key, io.MultiWriter(destFile, &lines, progressTaskWriter{task}))
+	if closeErr := destFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partPath, destPath)
+	}
+	if err != nil {
+		_ = os.Remove(partPath)
+		return models.FHIRDataFile{}, err
+	}
+
+	fileName := filepath.Base(destPath)
+	return models.FHIRDataFile{
+		FileName:     fileName,
+		FilePath:     fileName, // Relative to job import directory
+		ResourceType: models.GetResourceTypeFromFilename(fileName),
+		FileSize:     bytesWritten,
+		SourceStep:   models.StepHttpImport,
+		LineCount:    lines.Count(),
+		CreatedAt:    lib.GetFileModTime(destPath),
+	}, nil
+}
+
+// progressTaskWriter records the bytes written through it on a progress task
+type progressTaskWriter struct {
+	task lib.ProgressTask
+}
+
+func (w progressTaskWriter) Write(p []byte) (int, error) {
+	w.task.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// UploadDirToS3 uploads all files below dir to <prefix>/<relative path> in bucket and returns
+// the number of files and bytes uploaded. Partial files (.part) are skipped
+func UploadDirToS3(client *S3Client, dir, bucket, prefix string, logger *lib.Logger) (int, int64, error) {
+	var files int
+	var totalBytes int64
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(info.Name(), PartialFileSuffix) {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		key := path.Join(prefix, filepath.ToSlash(relPath))
+		if err := client.UploadFile(bucket, key, filePath); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", relPath, err)
+		}
+		logger.Debug("Uploaded file to S3", "file", relPath, "bucket", bucket, "key", key, "size", info.Size())
+		files++
+		totalBytes += info.Size()
+		return nil
+	})
+	return files, totalBytes, err
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// ErrSchemaNotRegistered is returned when the registry has no schema for a table
+var ErrSchemaNotRegistered = errors.New("no schema registered")
+
+// ExpectedField is one column of a registered table schema
+type ExpectedField struct {
+	Name     string
+	Types    []string // Accepted JSON Schema types or Avro types (the logical type if set); empty accepts any
+	Required bool     // The column must be present
+}
+
+// ExpectedTableSchema is the schema downstream ETL expects for a table
+type ExpectedTableSchema struct {
+	Format       models.SchemaFormat
+	Fields       []ExpectedField
+	ClosedFields bool // Columns not in Fields are incompatible (JSON Schema additionalProperties: false)
+}
+
+// SchemaIncompatibleError lists the differences between produced and registered table schemas
+type SchemaIncompatibleError struct {
+	Problems []string // "<table>: <difference>"
+}
+
+func (e *SchemaIncompatibleError) Error() string {
+	return fmt.Sprintf("output schema is incompatible with the registered schema: %s", strings.Join(e.Problems, "; "))
+}
+
+// ProducedTableSchemas returns the columns of every table of a flatten mapping
+// The flattener writes every value as text (see FormatFlattenValue), so all columns are strings
+func ProducedTableSchemas(mapping models.FlattenMapping) map[string][]DeltaColumn {
+	tables := make(map[string][]DeltaColumn, len(mapping))
+	for _, resourceType := range mapping.ResourceTypes() {
+		for _, name := range mapping.ColumnNames(resourceType) {
+			tables[resourceType] = append(tables[resourceType], DeltaColumn{Name: name, Type: "string"})
+		}
+	}
+	return tables
+}
+
+// ValidateTableSchemas checks each table against the schema registered for it
+// Tables without a registered schema are skipped; all differences are collected into a
+// *SchemaIncompatibleError so one run shows everything that breaks downstream ETL
+func ValidateTableSchemas(config models.ExpectedSchemaConfig, tables map[string][]DeltaColumn, httpClient *HTTPClient, logger *lib.Logger) error {
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, table := range names {
+		expected, err := FetchExpectedSchema(config.TableURL(table), config.Format, httpClient)
+		if errors.Is(err, ErrSchemaNotRegistered) {
+			logger.Info("No schema registered for table, skipping schema check", "table", table)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
+		for _, problem := range CheckTableSchema(expected, tables[table]) {
+			problems = append(problems, table+": "+problem)
+		}
+		logger.Debug("Checked table schema", "table", table, "format", expected.Format)
+	}
+
+	if len(problems) > 0 {
+		return &SchemaIncompatibleError{Problems: problems}
+	}
+	return nil
+}
+
+// FetchExpectedSchema downloads and parses the schema registered at schemaURL
+// Returns ErrSchemaNotRegistered if the registry answers 404
+func FetchExpectedSchema(schemaURL string, format models.SchemaFormat, httpClient *HTTPClient) (*ExpectedTableSchema, error) {
+	req, err := http.NewRequest(http.MethodGet, schemaURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/schema+json, application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("schema registry request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSchemaNotRegistered
+	}
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("schema registry returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	return ParseExpectedSchema(data, format)
+}
+
+// ParseExpectedSchema parses a JSON Schema or an Avro record schema, either as is or wrapped in a
+// Confluent-style registry response ({"schema": "<schema as string>", "schemaType": "JSON"})
+// An empty format is detected: a record with fields is Avro, anything else JSON Schema
+func ParseExpectedSchema(data []byte, format models.SchemaFormat) (*ExpectedTableSchema, error) {
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid schema document: %w", err)
+	}
+
+	if wrapped, ok := document["schema"].(string); ok {
+		if format == "" {
+			// Registries without schemaType only hold Avro
+			switch schemaType, _ := document["schemaType"].(string); strings.ToUpper(schemaType) {
+			case "", "AVRO":
+				format = models.SchemaFormatAvro
+			case "JSON":
+				format = models.SchemaFormatJSON
+			default:
+				return nil, fmt.Errorf("unsupported schema type %s (must be JSON or AVRO)", schemaType)
+			}
+		}
+		document = nil
+		if err := json.Unmarshal([]byte(wrapped), &document); err != nil {
+			return nil, fmt.Errorf("invalid schema in registry response: %w", err)
+		}
+	}
+
+	if format == "" {
+		format = models.SchemaFormatJSON
+		if _, hasFields := document["fields"]; document["type"] == "record" && hasFields {
+			format = models.SchemaFormatAvro
+		}
+	}
+	if format == models.SchemaFormatAvro {
+		return parseAvroSchema(document)
+	}
+	return parseJSONSchema(document)
+}
+
+// parseJSONSchema reads the properties of a JSON Schema describing one row
+func parseJSONSchema(document map[string]any) (*ExpectedTableSchema, error) {
+	if types := jsonSchemaTypes(document["type"]); len(types) > 0 && !slices.Contains(types, "object") {
+		return nil, fmt.Errorf("JSON Schema must describe an object (one row), got type %v", document["type"])
+	}
+
+	required := map[string]bool{}
+	if names, ok := document["required"].([]any); ok {
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	properties, _ := document["properties"].(map[string]any)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schema := &ExpectedTableSchema{Format: models.SchemaFormatJSON}
+	for _, name := range names {
+		property, _ := properties[name].(map[string]any)
+		schema.Fields = append(schema.Fields, ExpectedField{Name: name, Types: jsonSchemaTypes(property["type"]), Required: required[name]})
+		delete(required, name)
+	}
+	// Required names without a property accept any type
+	for name := range required {
+		schema.Fields = append(schema.Fields, ExpectedField{Name: name, Required: true})
+	}
+
+	schema.ClosedFields = document["additionalProperties"] == false
+	return schema, nil
+}
+
+// jsonSchemaTypes returns the types of a JSON Schema "type", given as string or list
+func jsonSchemaTypes(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var types []string
+		for _, t := range v {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// parseAvroSchema reads the fields of an Avro record
+// A field is required unless it has a default or its union contains null
+func parseAvroSchema(document map[string]any) (*ExpectedTableSchema, error) {
+	if document["type"] != "record" {
+		return nil, fmt.Errorf("Avro schema must be a record, got type %v", document["type"])
+	}
+	fields, _ := document["fields"].([]any)
+
+	schema := &ExpectedTableSchema{Format: models.SchemaFormatAvro}
+	for _, field := range fields {
+		field, _ := field.(map[string]any)
+		name, _ := field["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("Avro record field without a name")
+		}
+		types := avroTypes(field["type"])
+		_, hasDefault := field["default"]
+		schema.Fields = append(schema.Fields, ExpectedField{
+			Name:     name,
+			Types:    types,
+			Required: !hasDefault && !slices.Contains(types, "null"),
+		})
+	}
+	return schema, nil
+}
+
+// avroTypes returns the type names of an Avro type: a name, a union or a complex type
+// For a logical type such as {"type": "int", "logicalType": "date"} the logical type is returned
+func avroTypes(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var types []string
+		for _, member := range v {
+			types = append(types, avroTypes(member)...)
+		}
+		return types
+	case map[string]any:
+		if logicalType, ok := v["logicalType"].(string); ok {
+			return []string{logicalType}
+		}
+		return avroTypes(v["type"])
+	}
+	return nil
+}
+
+// CheckTableSchema compares produced columns with an expected schema and describes every difference
+// that breaks a reader of the expected schema: a missing required column, a column whose type the
+// expected one cannot hold, or an extra column where the schema allows none
+func CheckTableSchema(expected *ExpectedTableSchema, columns []DeltaColumn) []string {
+	produced := make(map[string]string, len(columns))
+	for _, column := range columns {
+		produced[column.Name] = column.Type
+	}
+
+	var problems []string
+	known := map[string]bool{}
+	for _, field := range expected.Fields {
+		known[field.Name] = true
+		columnType, ok := produced[field.Name]
+		if !ok {
+			if field.Required {
+				problems = append(problems, fmt.Sprintf("required column %s is missing", field.Name))
+			}
+			continue
+		}
+		if len(field.Types) > 0 && !acceptsColumnType(expected.Format, field.Types, columnType) {
+			problems = append(problems, fmt.Sprintf("column %s is %s, the registered schema expects %s", field.Name, columnType, strings.Join(field.Types, " | ")))
+		}
+	}
+
+	if expected.ClosedFields {
+		for _, column := range columns {
+			if !known[column.Name] {
+				problems = append(problems, fmt.Sprintf("column %s is not in the registered schema", column.Name))
+			}
+		}
+	}
+	return problems
+}
+
+// acceptsColumnType reports whether one of the expected types can hold values of a Delta column type
+// Avro types follow Avro's promotion rules (int to long, float or double, ...)
+func acceptsColumnType(format models.SchemaFormat, expectedTypes []string, columnType string) bool {
+	var accepted []string
+	if format == models.SchemaFormatAvro {
+		switch columnType {
+		case "string":
+			accepted = []string{"string", "bytes"}
+		case "integer", "short", "byte":
+			accepted = []string{"int", "long", "float", "double"}
+		case "long":
+			accepted = []string{"long", "float", "double"}
+		case "float":
+			accepted = []string{"float", "double"}
+		case "double":
+			accepted = []string{"double"}
+		case "boolean":
+			accepted = []string{"boolean"}
+		case "binary":
+			accepted = []string{"bytes", "string"}
+		case "date":
+			accepted = []string{"date"}
+		case "timestamp":
+			accepted = []string{"timestamp-micros", "timestamp-millis"}
+		}
+	} else {
+		switch columnType {
+		case "string", "date", "timestamp", "binary":
+			accepted = []string{"string"}
+		case "integer", "short", "byte", "long":
+			accepted = []string{"integer", "number"}
+		case "float", "double":
+			accepted = []string{"number"}
+		case "boolean":
+			accepted = []string{"boolean"}
+		}
+	}
+
+	for _, expectedType := range expectedTypes {
+		if slices.Contains(accepted, expectedType) {
+			return true
+		}
+	}
+	return false
+}
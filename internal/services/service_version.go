@@ -0,0 +1,92 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ServiceVersion identifies the software behind a service endpoint
+// FHIR servers report it in the software element of their CapabilityStatement; other
+// services are identified by their Server response header, if any
+type ServiceVersion struct {
+	Software    string `json:"software,omitempty"`
+	Version     string `json:"version,omitempty"`
+	FHIRVersion string `json:"fhir_version,omitempty"`
+	Server      string `json:"server,omitempty"` // Server response header
+}
+
+// String formats the version for reports, e.g. "TORCH 1.2.0 (FHIR 4.0.1)"
+func (v ServiceVersion) String() string {
+	name := strings.TrimSpace(v.Software + " " + v.Version)
+	if name == "" {
+		name = v.Server
+	}
+	if name == "" {
+		name = "unknown version"
+	}
+	if v.FHIRVersion != "" {
+		name += " (FHIR " + v.FHIRVersion + ")"
+	}
+	return name
+}
+
+// capabilityStatement is the part of a FHIR CapabilityStatement identifying the server
+type capabilityStatement struct {
+	ResourceType string `json:"resourceType"`
+	FHIRVersion  string `json:"fhirVersion"`
+	Software     struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"software"`
+}
+
+// FetchServiceVersion asks a FHIR endpoint for its CapabilityStatement (GET <baseURL>/metadata)
+// The request is sent once, without retries. An endpoint answering without a CapabilityStatement
+// is identified by its Server header only; an error is returned if neither is available
+func FetchServiceVersion(httpClient *HTTPClient, baseURL string, authorization string) (ServiceVersion, error) {
+	req, err := http.NewRequestWithContext(httpClient.Context(), http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/metadata", nil)
+	if err != nil {
+		return ServiceVersion{}, fmt.Errorf("failed to create metadata request: %w", err)
+	}
+	req.Header.Set("Accept", "application/fhir+json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := httpClient.send(req)
+	if err != nil {
+		return ServiceVersion{}, fmt.Errorf("metadata request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	version := ServiceVersion{Server: resp.Header.Get("Server")}
+	var capabilities capabilityStatement
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if resp.StatusCode < 400 && json.Unmarshal(body, &capabilities) == nil && capabilities.ResourceType == "CapabilityStatement" {
+		version.Software = capabilities.Software.Name
+		version.Version = capabilities.Software.Version
+		version.FHIRVersion = capabilities.FHIRVersion
+		return version, nil
+	}
+	if version.Server != "" {
+		return version, nil
+	}
+	return version, fmt.Errorf("no CapabilityStatement at %s (HTTP %d)", req.URL, resp.StatusCode)
+}
+
+// FetchVersion reports the TORCH version from its FHIR CapabilityStatement
+func (c *TORCHClient) FetchVersion() (ServiceVersion, error) {
+	authorization, err := c.auth.header()
+	if err != nil {
+		return ServiceVersion{}, err
+	}
+	return FetchServiceVersion(c.httpClient, c.config.BaseURL+"/fhir", authorization)
+}
+
+// FetchVersion reports the DIMP version from its FHIR CapabilityStatement
+func (c *DIMPClient) FetchVersion() (ServiceVersion, error) {
+	return FetchServiceVersion(c.httpClient, c.baseURL, "")
+}
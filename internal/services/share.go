@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ShareArtifacts lists the report files (relative to the job directory) that a share link exposes
+// Data files are never shared; only summaries meant for requesters are
+var ShareArtifacts = []string{
+	"imaging/imaging_report.json",
+	"csv/" + DataDictionaryFileName,
+	"parquet/" + DataDictionaryFileName,
+}
+
+// ErrShareExpired is returned for a share token past its expiry time
+var ErrShareExpired = errors.New("share link has expired")
+
+// ErrShareInvalid is returned for a malformed or tampered share token
+var ErrShareInvalid = errors.New("invalid share link")
+
+// shareClaims is the signed payload of a share token
+type shareClaims struct {
+	JobID   string `json:"job"`
+	Tenant  string `json:"tenant,omitempty"`
+	Expires int64  `json:"exp"` // Unix seconds
+}
+
+// ShareGrant is what a valid share token grants access to
+type ShareGrant struct {
+	JobID     string
+	Tenant    string // Empty for jobs outside any tenant
+	ExpiresAt time.Time
+}
+
+// SignShareToken creates a token granting read-only access to one job of a tenant until expires
+// The token is base64url(claims) "." base64url(HMAC-SHA256(secret, claims))
+func SignShareToken(secret string, tenant string, jobID string, expires time.Time) (string, error) {
+	if secret == "" {
+		return "", errors.New("sharing is disabled: server.share_secret is not configured")
+	}
+	payload, err := json.Marshal(shareClaims{JobID: jobID, Tenant: tenant, Expires: expires.Unix()})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode share token: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signSharePayload(secret, encoded), nil
+}
+
+// VerifyShareToken checks a token's signature and expiry and returns what it grants access to
+func VerifyShareToken(secret string, token string, now time.Time) (ShareGrant, error) {
+	if secret == "" {
+		return ShareGrant{}, ErrShareInvalid
+	}
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(signSharePayload(secret, encoded))) {
+		return ShareGrant{}, ErrShareInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ShareGrant{}, ErrShareInvalid
+	}
+	var claims shareClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.JobID == "" {
+		return ShareGrant{}, ErrShareInvalid
+	}
+
+	grant := ShareGrant{JobID: claims.JobID, Tenant: claims.Tenant, ExpiresAt: time.Unix(claims.Expires, 0)}
+	if !now.Before(grant.ExpiresAt) {
+		return grant, ErrShareExpired
+	}
+	return grant, nil
+}
+
+// signSharePayload returns the base64url HMAC-SHA256 of an encoded payload
+func signSharePayload(secret string, encoded string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ShareURL returns the public status URL for a share token
+func ShareURL(publicURL string, token string) string {
+	return strings.TrimSuffix(publicURL, "/") + "/share/" + url.PathEscape(token)
+}
+
+// ResolveShareArtifact returns the path of a shareable report file of a job
+// Only files listed in ShareArtifacts are resolved
+func ResolveShareArtifact(jobsBaseDir string, jobID string, name string) (string, error) {
+	for _, artifact := range ShareArtifacts {
+		if artifact == name {
+			return filepath.Join(GetJobDir(jobsBaseDir, jobID), filepath.FromSlash(artifact)), nil
+		}
+	}
+	return "", fmt.Errorf("not a shareable artifact: %s", name)
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 
 	"github.com/google/uuid"
+	"github.com/trobanga/aether/internal/lib"
 	"github.com/trobanga/aether/internal/models"
 )
 
@@ -44,6 +47,14 @@ func LoadJobState(jobsBaseDir string, jobID string) (*models.PipelineJob, error)
 		return nil, fmt.Errorf("failed to parse job state: %w", err)
 	}
 
+	// Step name aliases (e.g. torch_import) are migrated to canonical names before validation
+	models.CanonicalizeJobSteps(&job)
+
+	// The hash key is not part of state.json
+	if err := EnsureJobMetadataKey(&job.Config.JobMetadata, jobMetadataKeyDir(&job, jobsBaseDir)); err != nil {
+		return nil, err
+	}
+
 	// Validate loaded job
 	if err := job.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid job state loaded from disk: %w", err)
@@ -66,14 +77,22 @@ func SaveJobState(jobsBaseDir string, job *models.PipelineJob) error {
 		return fmt.Errorf("failed to create job directory: %w", err)
 	}
 
+	// Redact sensitive metadata (input sources, TORCH URLs) per job_metadata policy
+	if err := EnsureJobMetadataKey(&job.Config.JobMetadata, jobMetadataKeyDir(job, jobsBaseDir)); err != nil {
+		return err
+	}
+	// The in-memory job keeps the raw values for the rest of the run
+	// Timestamps are written in the configured time zone
+	persisted := job.Config.JobMetadata.RedactJob(*job).InLocation(lib.TimeZone())
+
 	// Marshal to JSON with indentation for human readability
-	data, err := json.MarshalIndent(job, "", "  ")
+	data, err := json.MarshalIndent(persisted, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal job state: %w", err)
 	}
 
 	// Write to temporary file first (atomic write pattern)
-	tempFile := filepath.Join(jobDir, fmt.Sprintf(".state.tmp.%s", uuid.New().String()))
+	tempFile := filepath.Join(jobDir, stateTempPrefix+uuid.New().String())
 	if err := os.WriteFile(tempFile, data, 0644); err != nil {
 		return fmt.Errorf("failed to write temp state file: %w", err)
 	}
@@ -86,6 +105,10 @@ func SaveJobState(jobsBaseDir string, job *models.PipelineJob) error {
 		return fmt.Errorf("failed to save job state: %w", err)
 	}
 
+	// RUNBOOK.md follows the persisted state; it is a convenience, so failing to write it
+	// does not fail the save
+	_ = WriteRunbook(jobsBaseDir, &persisted)
+
 	return nil
 }
 
@@ -117,6 +140,83 @@ func ListAllJobs(jobsBaseDir string) ([]string, error) {
 	return jobIDs, nil
 }
 
+// ListJobs loads the jobs in the jobs directory, newest first
+// With statuses given, only jobs in one of these states are returned.
+// Jobs whose state cannot be loaded are logged and skipped
+func ListJobs(jobsBaseDir string, statuses []models.JobStatus, logger *lib.Logger) ([]*models.PipelineJob, error) {
+	jobIDs, err := ListAllJobs(jobsBaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*models.PipelineJob, 0, len(jobIDs))
+	for _, jobID := range jobIDs {
+		job, err := LoadJobState(jobsBaseDir, jobID)
+		if err != nil {
+			logger.Warn("Failed to load job", "job_id", jobID, "error", err)
+			continue
+		}
+		if len(statuses) > 0 && !slices.Contains(statuses, job.Status) {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// ListJobViews returns the status of all jobs in the jobs directory, newest first
+// This is the stable job schema shared by the REST API, gRPC and `job list --json`.
+// Jobs whose state cannot be loaded are logged and skipped
+func ListJobViews(jobsBaseDir string, logger *lib.Logger) ([]models.JobStatusView, error) {
+	jobs, err := ListJobs(jobsBaseDir, nil, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]models.JobStatusView, 0, len(jobs))
+	for _, job := range jobs {
+		views = append(views, models.NewJobStatusView(*job))
+	}
+	return views, nil
+}
+
+// JobDataDirs lists the job subdirectories holding NDJSON data, in pipeline order
+var JobDataDirs = []string{"import", "pseudonymized", "imaging", "quarantine"}
+
+// ResolveJobFile locates a data file inside a job directory
+// name may be a path relative to the job directory (e.g. "pseudonymized/dimped_Patient.ndjson")
+// or a bare filename, which is searched for in JobDataDirs in order
+func ResolveJobFile(jobsBaseDir string, jobID string, name string) (string, error) {
+	jobDir := GetJobDir(jobsBaseDir, jobID)
+	if _, err := os.Stat(GetStateFilePath(jobsBaseDir, jobID)); err != nil {
+		return "", fmt.Errorf("job not found: %s", jobID)
+	}
+
+	if !models.IsSafePath(name) {
+		return "", fmt.Errorf("invalid file path (must be relative to the job directory): %s", name)
+	}
+
+	if filepath.Base(name) != name {
+		path := filepath.Join(jobDir, name)
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("file not found in job %s: %s", jobID, name)
+		}
+		return path, nil
+	}
+
+	for _, dir := range JobDataDirs {
+		path := filepath.Join(jobDir, dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("file not found in job %s: %s (searched %v)", jobID, name, JobDataDirs)
+}
+
 // DeleteJob removes a job's directory and all its data
 // WARNING: This is destructive and cannot be undone
 func DeleteJob(jobsBaseDir string, jobID string) error {
@@ -145,6 +245,7 @@ func EnsureJobDirs(jobsBaseDir string, jobID string) (map[models.StepName]string
 		models.StepLocalImport:       filepath.Join(jobDir, "import"),
 		models.StepHttpImport:        filepath.Join(jobDir, "import"),
 		models.StepDIMP:              filepath.Join(jobDir, "pseudonymized"),
+		models.StepImaging:           filepath.Join(jobDir, "imaging"),
 		models.StepCSVConversion:     filepath.Join(jobDir, "csv"),
 		models.StepParquetConversion: filepath.Join(jobDir, "parquet"),
 	}
@@ -168,6 +269,8 @@ func GetJobOutputDir(jobsBaseDir string, jobID string, step models.StepName) str
 		return filepath.Join(jobDir, "import")
 	case models.StepDIMP:
 		return filepath.Join(jobDir, "pseudonymized")
+	case models.StepImaging:
+		return filepath.Join(jobDir, "imaging")
 	case models.StepCSVConversion:
 		return filepath.Join(jobDir, "csv")
 	case models.StepParquetConversion:
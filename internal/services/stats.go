@@ -0,0 +1,240 @@
+package services
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// stepServices names the external service each step talks to; steps without one are local
+var stepServices = map[models.StepName]string{
+	models.StepTorchImport:       "torch",
+	models.StepHttpImport:        "http_source",
+	models.StepDIMP:              "dimp",
+	models.StepImaging:           "dicomweb",
+	models.StepCSVConversion:     "csv_conversion",
+	models.StepParquetConversion: "parquet_conversion",
+}
+
+// DurationStats summarizes the durations of completed runs in seconds
+type DurationStats struct {
+	Count  int     `json:"count"`
+	Median float64 `json:"median_seconds"`
+	P95    float64 `json:"p95_seconds"`
+	Max    float64 `json:"max_seconds"`
+	Total  float64 `json:"total_seconds"`
+}
+
+// newDurationStats computes the summary of a set of durations (nearest-rank percentiles)
+func newDurationStats(seconds []float64) DurationStats {
+	if len(seconds) == 0 {
+		return DurationStats{}
+	}
+	sorted := slices.Clone(seconds)
+	slices.Sort(sorted)
+
+	stats := DurationStats{Count: len(sorted), Max: sorted[len(sorted)-1]}
+	for _, s := range sorted {
+		stats.Total += s
+	}
+	stats.Median = percentile(sorted, 50)
+	stats.P95 = percentile(sorted, 95)
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// StepStats aggregates one pipeline step across jobs
+type StepStats struct {
+	Step        models.StepName `json:"step"`
+	Service     string          `json:"service,omitempty"` // External service of the step; empty for local steps
+	Runs        int             `json:"runs"`              // Jobs in which the step started
+	Completed   int             `json:"completed"`
+	Failed      int             `json:"failed"`
+	FailureRate float64         `json:"failure_rate"` // Failed / (Completed + Failed)
+	Retries     int             `json:"retries"`
+	Files       int             `json:"files"`
+	Bytes       int64           `json:"bytes"`
+	Durations   DurationStats   `json:"durations"` // Completed runs only
+
+	durations []float64
+}
+
+// ServiceStats aggregates the steps talking to one external service
+type ServiceStats struct {
+	Service           string        `json:"service"`
+	Runs              int           `json:"runs"`
+	Failed            int           `json:"failed"`
+	TransientFailures int           `json:"transient_failures"` // Failures after retries were exhausted (network, 5xx, timeout)
+	FailureRate       float64       `json:"failure_rate"`
+	Retries           int           `json:"retries"`
+	Durations         DurationStats `json:"durations"`
+
+	completed int
+	durations []float64
+}
+
+// JobStats is the historical summary of `aether stats`
+type JobStats struct {
+	Since      *time.Time       `json:"since,omitempty"` // Jobs created before are not included; nil = all jobs
+	Jobs       int              `json:"jobs"`
+	Completed  int              `json:"completed"`
+	Failed     int              `json:"failed"`
+	Other      int              `json:"other"` // Pending, in progress or cancelled
+	Files      int              `json:"files"`
+	Bytes      int64            `json:"bytes"`
+	Durations  DurationStats    `json:"durations"` // Completed jobs, from creation to last update
+	Steps      []StepStats      `json:"steps"`     // In pipeline order, steps that never ran are omitted
+	Services   []ServiceStats   `json:"services"`
+	DIMPUsage  models.DIMPUsage `json:"dimp_usage"`            // Sent to DIMP by the jobs included
+	DIMPMonths []DIMPMonthUsage `json:"dimp_months,omitempty"` // Sent to DIMP per calendar month by all jobs, including deleted ones
+	Unreadable int              `json:"unreadable,omitempty"`  // Job directories whose state could not be loaded
+}
+
+// AggregateJobStats summarizes the persisted state of all jobs created at or after since
+// A zero since includes every job. Only job state is read, so the statistics also cover
+// jobs whose data was cleaned up
+func AggregateJobStats(jobsBaseDir string, since time.Time, logger *lib.Logger) (*JobStats, error) {
+	jobIDs, err := ListAllJobs(jobsBaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &JobStats{Steps: []StepStats{}, Services: []ServiceStats{}}
+	if !since.IsZero() {
+		stats.Since = &since
+	}
+	steps := make(map[models.StepName]*StepStats)
+	services := make(map[string]*ServiceStats)
+	var jobDurations []float64
+
+	for _, jobID := range jobIDs {
+		job, err := LoadJobState(jobsBaseDir, jobID)
+		if err != nil {
+			logger.Warn("Failed to load job", "job_id", jobID, "error", err)
+			stats.Unreadable++
+			continue
+		}
+		if job.CreatedAt.Before(since) {
+			continue
+		}
+
+		stats.Jobs++
+		stats.Files += job.TotalFiles
+		stats.Bytes += job.TotalBytes
+		if job.DIMPUsage != nil {
+			stats.DIMPUsage.Add(*job.DIMPUsage)
+		}
+		switch job.Status {
+		case models.JobStatusCompleted:
+			stats.Completed++
+			jobDurations = append(jobDurations, job.UpdatedAt.Sub(job.CreatedAt).Seconds())
+		case models.JobStatusFailed:
+			stats.Failed++
+		default:
+			stats.Other++
+		}
+
+		for _, step := range job.Steps {
+			if step.StartedAt == nil {
+				continue
+			}
+			addStepRun(steps, services, step)
+		}
+	}
+
+	for _, name := range models.AllStepNames {
+		step, ok := steps[name]
+		if !ok {
+			continue
+		}
+		step.FailureRate = failureRate(step.Failed, step.Completed)
+		step.Durations = newDurationStats(step.durations)
+		stats.Steps = append(stats.Steps, *step)
+	}
+	for _, name := range models.AllStepNames {
+		service, ok := services[stepServices[name]]
+		if !ok {
+			continue
+		}
+		delete(services, service.Service) // each service once, in the order of its first step
+		service.FailureRate = failureRate(service.Failed, service.completed)
+		service.Durations = newDurationStats(service.durations)
+		stats.Services = append(stats.Services, *service)
+	}
+	stats.Durations = newDurationStats(jobDurations)
+
+	months, err := LoadDIMPUsage(jobsBaseDir)
+	if err != nil {
+		logger.Warn("Failed to load monthly DIMP usage", "error", err)
+	}
+	for _, month := range months {
+		if since.IsZero() || month.Month >= DIMPUsageMonth(since) {
+			stats.DIMPMonths = append(stats.DIMPMonths, month)
+		}
+	}
+
+	return stats, nil
+}
+
+// addStepRun adds a started step to the statistics of the step and its service
+func addStepRun(steps map[models.StepName]*StepStats, services map[string]*ServiceStats, step models.PipelineStep) {
+	serviceName := stepServices[step.Name]
+	stepStats, ok := steps[step.Name]
+	if !ok {
+		stepStats = &StepStats{Step: step.Name, Service: serviceName}
+		steps[step.Name] = stepStats
+	}
+	var service *ServiceStats
+	if serviceName != "" {
+		if service, ok = services[serviceName]; !ok {
+			service = &ServiceStats{Service: serviceName}
+			services[serviceName] = service
+		}
+		service.Runs++
+		service.Retries += step.RetryCount
+	}
+
+	stepStats.Runs++
+	stepStats.Retries += step.RetryCount
+	stepStats.Files += step.FilesProcessed
+	stepStats.Bytes += step.BytesProcessed
+
+	switch step.Status {
+	case models.StepStatusCompleted:
+		stepStats.Completed++
+		if service != nil {
+			service.completed++
+		}
+		if step.CompletedAt != nil {
+			seconds := step.CompletedAt.Sub(*step.StartedAt).Seconds()
+			stepStats.durations = append(stepStats.durations, seconds)
+			if service != nil {
+				service.durations = append(service.durations, seconds)
+			}
+		}
+	case models.StepStatusFailed:
+		stepStats.Failed++
+		if service != nil {
+			service.Failed++
+			if step.LastError != nil && step.LastError.Type == models.ErrorTypeTransient {
+				service.TransientFailures++
+			}
+		}
+	}
+}
+
+// failureRate returns failed / (failed + completed), 0 without finished runs
+func failureRate(failed, completed int) float64 {
+	if failed+completed == 0 {
+		return 0
+	}
+	return float64(failed) / float64(failed+completed)
+}
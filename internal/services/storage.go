@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// filesystemStats describes the filesystem holding a directory
+type filesystemStats struct {
+	Type        string // Filesystem name, e.g. ext4 or nfs
+	Network     bool   // Known network filesystem whose locks may not reach other hosts
+	FreeBytes   uint64 // Space available to unprivileged users
+	FreeInodes  uint64
+	TotalInodes uint64 // 0 if the filesystem allocates inodes dynamically
+}
+
+// CheckJobsDir verifies that jobsDir can hold jobs before any job starts: it is created if
+// missing and must be writable, support file locks, not be on a network filesystem unless
+// storage.allow_network_filesystem is set, and have the free space and inodes required by
+// storage.min_free_mb and storage.min_free_inodes
+func CheckJobsDir(jobsDir string, config models.StorageConfig) error {
+	if err := os.MkdirAll(jobsDir, 0755); err != nil {
+		return fmt.Errorf("cannot create jobs directory %s: %w (set jobs_dir, --jobs-dir or AETHER_JOBS_DIR to a writable location)", jobsDir, err)
+	}
+
+	probe, err := os.CreateTemp(jobsDir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("jobs directory %s is not writable: %w (check its owner and permissions with 'ls -ld %s', or set jobs_dir, --jobs-dir or AETHER_JOBS_DIR to a writable location)", jobsDir, err, jobsDir)
+	}
+	probePath := probe.Name()
+	defer func() {
+		_ = os.Remove(probePath)
+	}()
+	_, err = probe.WriteString("aether write check\n")
+	if err == nil {
+		err = probe.Sync()
+	}
+	if closeErr := probe.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("cannot write to jobs directory %s: %w (the filesystem may be full or read-only)", jobsDir, err)
+	}
+
+	lockFile, err := tryLockFile(probePath)
+	if err != nil {
+		return fmt.Errorf("file locking does not work in jobs directory %s: %w (jobs are locked to prevent concurrent runs; on NFS make sure the lock service is running, or use a local disk)", jobsDir, err)
+	}
+	if lockFile != nil {
+		_ = lockFile.Close()
+	}
+
+	stats, ok, err := statFilesystem(jobsDir)
+	if err != nil {
+		return fmt.Errorf("failed to inspect filesystem of jobs directory %s: %w", jobsDir, err)
+	}
+	if !ok {
+		return nil
+	}
+
+	if stats.Network && !config.AllowNetworkFilesystem {
+		return fmt.Errorf("jobs directory %s is on a %s network filesystem, where job locks may not be seen by other hosts and two machines could run the same job (use a local disk, or set storage.allow_network_filesystem: true if only one host uses this jobs directory)", jobsDir, stats.Type)
+	}
+	if minBytes := config.GetMinFreeBytes(); minBytes > 0 && stats.FreeBytes < minBytes {
+		return fmt.Errorf("only %s free on the filesystem of jobs directory %s, %s required by storage.min_free_mb (free space, e.g. delete finished jobs, or lower storage.min_free_mb; -1 disables the check)",
+			lib.FormatBytes(int64(stats.FreeBytes)), jobsDir, lib.FormatBytes(int64(minBytes)))
+	}
+	if minInodes := config.GetMinFreeInodes(); minInodes > 0 && stats.TotalInodes > 0 && stats.FreeInodes < minInodes {
+		return fmt.Errorf("only %d free inodes on the filesystem of jobs directory %s, %d required by storage.min_free_inodes (remove files, e.g. finished jobs, or lower storage.min_free_inodes; -1 disables the check)",
+			stats.FreeInodes, jobsDir, minInodes)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build darwin
+
+package services
+
+import (
+	"syscall"
+)
+
+// darwinNetworkFilesystems lists filesystems whose advisory locks are not reliably shared
+// between hosts
+var darwinNetworkFilesystems = map[string]bool{
+	"nfs": true, "smbfs": true, "afpfs": true, "webdav": true,
+}
+
+// statFilesystem reports the filesystem holding path (macOS implementation)
+func statFilesystem(path string) (filesystemStats, bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return filesystemStats{}, false, err
+	}
+	var name []byte
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return filesystemStats{
+		Type:        string(name),
+		Network:     darwinNetworkFilesystems[string(name)],
+		FreeBytes:   st.Bavail * uint64(st.Bsize),
+		FreeInodes:  st.Ffree,
+		TotalInodes: st.Files,
+	}, true, nil
+}
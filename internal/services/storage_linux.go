@@ -0,0 +1,51 @@
+//go:build linux
+
+package services
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// linuxFilesystems names filesystem magic numbers from statfs(2)
+var linuxFilesystems = map[int64]string{
+	0xEF53:     "ext4",
+	0x58465342: "xfs",
+	0x9123683E: "btrfs",
+	0x2FC12FC1: "zfs",
+	0x01021994: "tmpfs",
+	0x794C7630: "overlayfs",
+	0x65735546: "fuse",
+	0x00C36400: "ceph",
+	0x6969:     "nfs",
+	0x517B:     "smb",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x5346414F: "afs",
+	0x01021997: "9p",
+}
+
+// linuxNetworkFilesystems lists filesystems whose advisory locks are not reliably shared
+// between hosts; CephFS is left out because it coordinates flock across clients
+var linuxNetworkFilesystems = map[string]bool{
+	"nfs": true, "smb": true, "cifs": true, "smb2": true, "afs": true, "9p": true,
+}
+
+// statFilesystem reports the filesystem holding path (Linux implementation)
+func statFilesystem(path string) (filesystemStats, bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return filesystemStats{}, false, err
+	}
+	name, known := linuxFilesystems[int64(st.Type)]
+	if !known {
+		name = fmt.Sprintf("0x%x", st.Type)
+	}
+	return filesystemStats{
+		Type:        name,
+		Network:     linuxNetworkFilesystems[name],
+		FreeBytes:   uint64(st.Bavail) * uint64(st.Bsize),
+		FreeInodes:  uint64(st.Ffree),
+		TotalInodes: uint64(st.Files),
+	}, true, nil
+}
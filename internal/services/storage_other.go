@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package services
+
+// statFilesystem is not implemented on this platform; the filesystem type, space and
+// inode checks are skipped
+func statFilesystem(path string) (filesystemStats, bool, error) {
+	return filesystemStats{}, false, nil
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MII core data set profiles of the generated resources
+const (
+	synthProfilePatient     = "https://www.medizininformatik-initiative.de/fhir/core/modul-person/StructureDefinition/Patient"
+	synthProfileEncounter   = "https://www.medizininformatik-initiative.de/fhir/core/modul-fall/StructureDefinition/KontaktGesundheitseinrichtung"
+	synthProfileCondition   = "https://www.medizininformatik-initiative.de/fhir/core/modul-diagnose/StructureDefinition/Diagnose"
+	synthProfileObservation = "https://www.medizininformatik-initiative.de/fhir/core/modul-labor/StructureDefinition/ObservationLab"
+)
+
+// SynthTagSystem marks every generated resource, so synthetic data is never mistaken for real data
+const SynthTagSystem = "urn:aether:synth"
+
+// synthResourceTypes are the generated resource types, one NDJSON file each
+var synthResourceTypes = []string{"Patient", "Encounter", "Condition", "Observation"}
+
+// synthEpoch anchors generated dates, so a seed always produces the same output
+var synthEpoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type synthCoding struct {
+	code    string
+	display string
+}
+
+var synthDiagnoses = []synthCoding{
+	{"I10.90", "Essentielle Hypertonie, nicht näher bezeichnet"},
+	{"E11.90", "Diabetes mellitus, Typ 2: Ohne Komplikationen: Nicht als entgleist bezeichnet"},
+	{"J18.9", "Pneumonie, nicht näher bezeichnet"},
+	{"I21.9", "Akuter Myokardinfarkt, nicht näher bezeichnet"},
+	{"I50.9", "Herzinsuffizienz, nicht näher bezeichnet"},
+	{"N18.3", "Chronische Nierenkrankheit, Stadium 3"},
+	{"C34.9", "Bösartige Neubildung: Bronchus oder Lunge, nicht näher bezeichnet"},
+	{"F32.9", "Depressive Episode, nicht näher bezeichnet"},
+	{"K35.8", "Akute Appendizitis, nicht näher bezeichnet"},
+	{"S72.00", "Schenkelhalsfraktur: Teil nicht näher bezeichnet"},
+}
+
+type synthLabTest struct {
+	synthCoding
+	unit     string
+	low      float64
+	high     float64
+	decimals int
+}
+
+var synthLabTests = []synthLabTest{
+	{synthCoding{"718-7", "Hemoglobin [Mass/volume] in Blood"}, "g/dL", 10, 17.5, 1},
+	{synthCoding{"2160-0", "Creatinine [Mass/volume] in Serum or Plasma"}, "mg/dL", 0.5, 2.5, 2},
+	{synthCoding{"2345-7", "Glucose [Mass/volume] in Serum or Plasma"}, "mg/dL", 65, 220, 0},
+	{synthCoding{"6690-2", "Leukocytes [#/volume] in Blood by Automated count"}, "10*3/uL", 3, 16, 1},
+	{synthCoding{"2823-3", "Potassium [Moles/volume] in Serum or Plasma"}, "mmol/L", 3.2, 5.6, 1},
+	{synthCoding{"2951-2", "Sodium [Moles/volume] in Serum or Plasma"}, "mmol/L", 130, 148, 0},
+	{synthCoding{"1988-5", "C reactive protein [Mass/volume] in Serum or Plasma"}, "mg/L", 0.5, 120, 1},
+}
+
+var (
+	synthFamilyNames = []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann"}
+	synthFemaleNames = []string{"Anna", "Maria", "Ursula", "Sabine", "Julia", "Lena", "Monika", "Petra"}
+	synthMaleNames   = []string{"Peter", "Klaus", "Thomas", "Michael", "Jonas", "Lukas", "Hans", "Stefan"}
+	synthCities      = []struct{ city, postalCode string }{
+		{"Berlin", "10117"}, {"Hamburg", "20095"}, {"München", "80331"}, {"Köln", "50667"},
+		{"Leipzig", "04109"}, {"Erlangen", "91054"}, {"Mainz", "55116"}, {"Kiel", "24103"},
+	}
+)
+
+// SynthOptions configures `aether synth`
+type SynthOptions struct {
+	Patients int
+	Seed     uint64
+}
+
+// SynthStats counts the generated resources per type
+type SynthStats map[string]int
+
+// GenerateSyntheticData writes MII-KDS-like Patient, Encounter, Condition and Observation NDJSON
+// files to outDir. Encounters reference their patient and diagnoses; conditions and observations
+// reference patient and encounter. The same seed always produces the same files.
+// Existing NDJSON files of these types are not overwritten.
+func GenerateSyntheticData(outDir string, opts SynthOptions) (SynthStats, error) {
+	if opts.Patients <= 0 {
+		return nil, fmt.Errorf("number of patients must be positive, got %d", opts.Patients)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	writers := make(map[string]*synthWriter, len(synthResourceTypes))
+	// On failure, remove the files created so far instead of leaving a partial data set behind
+	abort := func() {
+		for _, w := range writers {
+			_ = w.file.Close()
+			_ = os.Remove(w.path)
+		}
+	}
+	for _, resourceType := range synthResourceTypes {
+		w, err := newSynthWriter(filepath.Join(outDir, resourceType+".ndjson"))
+		if err != nil {
+			abort()
+			return nil, err
+		}
+		writers[resourceType] = w
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x5eed))
+	for i := 1; i <= opts.Patients; i++ {
+		for _, resource := range synthPatientRecord(rng, i) {
+			if err := writers[resource["resourceType"].(string)].write(resource); err != nil {
+				abort()
+				return nil, err
+			}
+		}
+	}
+
+	stats := make(SynthStats, len(writers))
+	for resourceType, w := range writers {
+		if err := w.close(); err != nil {
+			abort()
+			return nil, err
+		}
+		stats[resourceType] = w.count
+	}
+	return stats, nil
+}
+
+type synthWriter struct {
+	path  string
+	file  *os.File
+	buf   *bufio.Writer
+	count int
+}
+
+func newSynthWriter(path string) (*synthWriter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("%s already exists; choose an empty output directory", path)
+		}
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return &synthWriter{path: path, file: file, buf: bufio.NewWriterSize(file, 256*1024)}, nil
+}
+
+func (w *synthWriter) write(resource map[string]any) error {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", resource["resourceType"], err)
+	}
+	data = append(data, '\n')
+	if _, err := w.buf.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.path, err)
+	}
+	w.count++
+	return nil
+}
+
+func (w *synthWriter) close() error {
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.path, err)
+	}
+	return w.file.Close()
+}
+
+// synthPatientRecord generates a patient with 1-3 encounters, each with 1-2 diagnoses and 2-5 lab results
+func synthPatientRecord(rng *rand.Rand, index int) []map[string]any {
+	patientID := fmt.Sprintf("synth-pat-%06d", index)
+	patientRef := map[string]any{"reference": "Patient/" + patientID}
+	birthDate := synthEpoch.AddDate(-18-rng.IntN(72), 0, -rng.IntN(365))
+
+	resources := []map[string]any{synthPatient(rng, patientID, birthDate)}
+
+	encounters := 1 + rng.IntN(3)
+	for e := 1; e <= encounters; e++ {
+		encounterID := fmt.Sprintf("synth-enc-%06d-%d", index, e)
+		encounterRef := map[string]any{"reference": "Encounter/" + encounterID}
+		admitted := synthEpoch.AddDate(0, 0, -1-rng.IntN(5*365)).Add(time.Duration(6+rng.IntN(14)) * time.Hour)
+		inpatient := rng.IntN(3) > 0
+		discharged := admitted.Add(time.Duration(1+rng.IntN(4)) * time.Hour)
+		if inpatient {
+			discharged = admitted.AddDate(0, 0, 1+rng.IntN(14))
+		}
+
+		var conditions []map[string]any
+		var diagnoses []any
+		conditionCount := 1 + rng.IntN(2)
+		for c := 1; c <= conditionCount; c++ {
+			conditionID := fmt.Sprintf("synth-cond-%06d-%d-%d", index, e, c)
+			conditions = append(conditions, synthCondition(rng, conditionID, patientRef, encounterRef, admitted))
+			diagnoses = append(diagnoses, map[string]any{
+				"condition": map[string]any{"reference": "Condition/" + conditionID},
+				"rank":      c,
+			})
+		}
+		resources = append(resources, synthEncounter(encounterID, patientRef, admitted, discharged, inpatient, diagnoses))
+		resources = append(resources, conditions...)
+
+		observationCount := 2 + rng.IntN(4)
+		for o := 1; o <= observationCount; o++ {
+			observationID := fmt.Sprintf("synth-obs-%06d-%d-%d", index, e, o)
+			effective := admitted.Add(time.Duration(rng.Int64N(int64(discharged.Sub(admitted)))))
+			resources = append(resources, synthObservation(rng, observationID, patientRef, encounterRef, effective))
+		}
+	}
+	return resources
+}
+
+func synthMeta(profile string) map[string]any {
+	return map[string]any{
+		"profile": []any{profile},
+		"tag":     []any{map[string]any{"system": SynthTagSystem, "code": "synthetic", "display": "Synthetic test data"}},
+	}
+}
+
+func synthPatient(rng *rand.Rand, id string, birthDate time.Time) map[string]any {
+	gender, given := "female", synthFemaleNames[rng.IntN(len(synthFemaleNames))]
+	if rng.IntN(2) == 0 {
+		gender, given = "male", synthMaleNames[rng.IntN(len(synthMaleNames))]
+	}
+	city := synthCities[rng.IntN(len(synthCities))]
+
+	return map[string]any{
+		"resourceType": "Patient",
+		"id":           id,
+		"meta":         synthMeta(synthProfilePatient),
+		"identifier": []any{map[string]any{
+			"use": "usual",
+			"type": map[string]any{"coding": []any{map[string]any{
+				"system": "http://terminology.hl7.org/CodeSystem/v2-0203",
+				"code":   "MR",
+			}}},
+			"system": SynthTagSystem + ":patient-id",
+			"value":  id,
+		}},
+		"name": []any{map[string]any{
+			"use":    "official",
+			"family": synthFamilyNames[rng.IntN(len(synthFamilyNames))],
+			"given":  []any{given},
+		}},
+		"gender":    gender,
+		"birthDate": birthDate.Format("2006-01-02"),
+		"address": []any{map[string]any{
+			"type":       "both",
+			"city":       city.city,
+			"postalCode": city.postalCode,
+			"country":    "DE",
+		}},
+	}
+}
+
+func synthEncounter(id string, patientRef map[string]any, admitted, discharged time.Time, inpatient bool, diagnoses []any) map[string]any {
+	class := map[string]any{"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "AMB", "display": "ambulatory"}
+	if inpatient {
+		class = map[string]any{"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "IMP", "display": "inpatient encounter"}
+	}
+
+	return map[string]any{
+		"resourceType": "Encounter",
+		"id":           id,
+		"meta":         synthMeta(synthProfileEncounter),
+		"identifier": []any{map[string]any{
+			"type": map[string]any{"coding": []any{map[string]any{
+				"system": "http://terminology.hl7.org/CodeSystem/v2-0203",
+				"code":   "VN",
+			}}},
+			"system": SynthTagSystem + ":encounter-id",
+			"value":  id,
+		}},
+		"status": "finished",
+		"class":  class,
+		"type": []any{map[string]any{"coding": []any{map[string]any{
+			"system": "http://fhir.de/CodeSystem/Kontaktebene",
+			"code":   "einrichtungskontakt",
+		}}}},
+		"subject": patientRef,
+		"period": map[string]any{
+			"start": admitted.Format(time.RFC3339),
+			"end":   discharged.Format(time.RFC3339),
+		},
+		"diagnosis": diagnoses,
+	}
+}
+
+func synthCondition(rng *rand.Rand, id string, patientRef, encounterRef map[string]any, recorded time.Time) map[string]any {
+	diagnosis := synthDiagnoses[rng.IntN(len(synthDiagnoses))]
+
+	return map[string]any{
+		"resourceType": "Condition",
+		"id":           id,
+		"meta":         synthMeta(synthProfileCondition),
+		"clinicalStatus": map[string]any{"coding": []any{map[string]any{
+			"system": "http://terminology.hl7.org/CodeSystem/condition-clinical",
+			"code":   "active",
+		}}},
+		"code": map[string]any{"coding": []any{map[string]any{
+			"system":  "http://fhir.de/CodeSystem/bfarm/icd-10-gm",
+			"version": "2025",
+			"code":    diagnosis.code,
+			"display": diagnosis.display,
+		}}},
+		"subject":      patientRef,
+		"encounter":    encounterRef,
+		"recordedDate": recorded.Format(time.RFC3339),
+	}
+}
+
+func synthObservation(rng *rand.Rand, id string, patientRef, encounterRef map[string]any, effective time.Time) map[string]any {
+	test := synthLabTests[rng.IntN(len(synthLabTests))]
+	value := test.low + rng.Float64()*(test.high-test.low)
+	scale := math.Pow10(test.decimals)
+	value = math.Round(value*scale) / scale
+
+	return map[string]any{
+		"resourceType": "Observation",
+		"id":           id,
+		"meta":         synthMeta(synthProfileObservation),
+		"identifier": []any{map[string]any{
+			"type": map[string]any{"coding": []any{map[string]any{
+				"system": "http://terminology.hl7.org/CodeSystem/v2-0203",
+				"code":   "OBI",
+			}}},
+			"system": SynthTagSystem + ":observation-id",
+			"value":  id,
+		}},
+		"status": "final",
+		"category": []any{map[string]any{"coding": []any{
+			map[string]any{"system": "http://loinc.org", "code": "26436-6"},
+			map[string]any{"system": "http://terminology.hl7.org/CodeSystem/observation-category", "code": "laboratory"},
+		}}},
+		"code": map[string]any{"coding": []any{map[string]any{
+			"system":  "http://loinc.org",
+			"code":    test.code,
+			"display": test.display,
+		}}},
+		"subject":           patientRef,
+		"encounter":         encounterRef,
+		"effectiveDateTime": effective.Format(time.RFC3339),
+		"valueQuantity": map[string]any{
+			"value":  value,
+			"unit":   test.unit,
+			"system": "http://unitsofmeasure.org",
+			"code":   test.unit,
+		},
+	}
+}
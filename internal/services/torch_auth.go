@@ -0,0 +1,218 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// tokenExpiryMargin renews an access token this long before it expires, so no request
+// starts with a token that runs out in flight
+const tokenExpiryMargin = 30 * time.Second
+
+// defaultTokenLifetime is assumed for access tokens answered without expires_in
+const defaultTokenLifetime = 5 * time.Minute
+
+// torchAuth authorizes TORCH requests per services.torch.auth_mode
+// OAuth2 access tokens are cached until shortly before they expire
+type torchAuth struct {
+	config     models.TORCHConfig
+	httpClient *HTTPClient
+	logger     *lib.Logger
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// tokenResponse is the answer of an OAuth2 token endpoint (RFC 6749 section 5.1)
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// header returns the Authorization header value for a TORCH request
+func (a *torchAuth) header() (string, error) {
+	switch a.config.GetAuthMode() {
+	case models.TORCHAuthBearerToken:
+		return "Bearer " + a.config.BearerToken, nil
+	case models.TORCHAuthOAuth2ClientCredentials:
+		token, err := a.accessToken()
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		credentials := a.config.Username + ":" + a.config.Password
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)), nil
+	}
+}
+
+// credentialKeys names the configuration keys holding the credentials of the auth mode
+func (a *torchAuth) credentialKeys() string {
+	switch a.config.GetAuthMode() {
+	case models.TORCHAuthBearerToken:
+		return "services.torch.bearer_token"
+	case models.TORCHAuthOAuth2ClientCredentials:
+		return "the roles granted to services.torch.client_id (and services.torch.scope)"
+	default:
+		return "services.torch.username and services.torch.password"
+	}
+}
+
+// invalidate drops the cached access token after TORCH rejected it
+// Returns true if the next request gets a new token, i.e. a rejected request is worth repeating
+func (a *torchAuth) invalidate() bool {
+	if a.config.GetAuthMode() != models.TORCHAuthOAuth2ClientCredentials {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+	return true
+}
+
+// accessToken returns the cached access token, requesting a new one if there is none or it
+// is about to expire
+func (a *torchAuth) accessToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Before(a.expiresAt) {
+		return a.token, nil
+	}
+
+	token, lifetime, err := a.requestToken()
+	if err != nil {
+		return "", err
+	}
+	a.token = token
+	a.expiresAt = time.Now().Add(lifetime - tokenExpiryMargin)
+	if lifetime <= 2*tokenExpiryMargin {
+		// Short-lived tokens are used for half their lifetime instead
+		a.expiresAt = time.Now().Add(lifetime / 2)
+	}
+	a.logger.Debug("Obtained TORCH access token", "token_url", a.config.TokenURL, "expires_in", lifetime)
+	return token, nil
+}
+
+// requestToken asks the token endpoint for an access token with the client credentials grant
+func (a *torchAuth) requestToken() (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if a.config.Scope != "" {
+		form.Set("scope", a.config.Scope)
+	}
+
+	req, err := http.NewRequestWithContext(a.httpClient.Context(), http.MethodPost, a.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", 0, &TORCHError{Operation: "auth", Message: "token endpoint unreachable: " + err.Error(), ErrorType: models.ErrorTypeTransient}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 400 {
+		return "", 0, &TORCHError{
+			Operation:  "auth",
+			StatusCode: resp.StatusCode,
+			Message:    "token request rejected - check services.torch.client_id and services.torch.client_secret: " + strings.TrimSpace(string(body)),
+			ErrorType:  lib.ClassifyHTTPError(resp.StatusCode),
+		}
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", 0, &TORCHError{
+			Operation:  "auth",
+			StatusCode: resp.StatusCode,
+			Message:    "token endpoint returned no access_token",
+			ErrorType:  models.ErrorTypeNonTransient,
+		}
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", 0, &TORCHError{
+			Operation:  "auth",
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("unsupported token_type '%s' from token endpoint (expected Bearer)", token.TokenType),
+			ErrorType:  models.ErrorTypeNonTransient,
+		}
+	}
+
+	lifetime := defaultTokenLifetime
+	if token.ExpiresIn > 0 {
+		lifetime = time.Duration(token.ExpiresIn) * time.Second
+	}
+	return token.AccessToken, lifetime, nil
+}
+
+// authorize sets the Authorization header of a TORCH request
+func (c *TORCHClient) authorize(req *http.Request) error {
+	header, err := c.auth.header()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", header)
+	return nil
+}
+
+// send authorizes a TORCH request and sends it once (see HTTPClient.send)
+func (c *TORCHClient) send(req *http.Request) (*http.Response, error) {
+	return c.sendAuthorized(req, c.httpClient.send)
+}
+
+// sendAuthorized authorizes a TORCH request and sends it with do. If TORCH answers 401 to an
+// OAuth2 token, the cached token is dropped and the request is repeated once with a new token
+func (c *TORCHClient) sendAuthorized(req *http.Request, do func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	resp, err := do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !c.auth.invalidate() {
+		return resp, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil // The body cannot be sent again
+		}
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_ = resp.Body.Close()
+
+	c.logger.Debug("TORCH rejected the access token, requesting a new one", "url", req.URL.String())
+	if err := c.authorize(retry); err != nil {
+		return nil, err
+	}
+	return do(retry)
+}
+
+// requestError is the error of a TORCH request that got no response. Failures to obtain an
+// access token keep their own error type; anything else is transient
+func requestError(operation string, err error) *TORCHError {
+	var authErr *TORCHError
+	if errors.As(err, &authErr) && authErr.Operation == "auth" {
+		return authErr
+	}
+	return &TORCHError{Operation: operation, Message: err.Error(), ErrorType: models.ErrorTypeTransient}
+}
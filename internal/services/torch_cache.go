@@ -0,0 +1,137 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// torchCacheDirName is the directory below the jobs directory holding cached extraction results
+const torchCacheDirName = ".torch-cache"
+
+// ErrExtractionResultGone is returned when a cached extraction result is no longer served by TORCH
+var ErrExtractionResultGone = errors.New("TORCH extraction result is no longer available")
+
+// TORCHCacheEntry maps a CRTDL to the result of its extraction (services.torch.result_cache_ttl_minutes)
+type TORCHCacheEntry struct {
+	CRTDLHash string    `json:"crtdl_hash"`
+	StatusURL string    `json:"status_url"`
+	FileURLs  []string  `json:"file_urls"`
+	JobID     string    `json:"job_id"` // Job that ran the extraction
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HashCRTDL returns the SHA-256 of a CRTDL file's content
+func HashCRTDL(crtdlPath string) (string, error) {
+	data, err := os.ReadFile(crtdlPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CRTDL file: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetTORCHCachePath returns the cache file of a CRTDL's extraction on a TORCH server
+// Like extraction slots, entries are kept per server in the jobs directory shared by all tenants
+func GetTORCHCachePath(jobsBaseDir string, baseURL string, crtdlHash string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(baseURL, "/")))
+	return filepath.Join(jobsBaseDir, torchCacheDirName, hex.EncodeToString(sum[:])[:16], crtdlHash+".json")
+}
+
+// LoadTORCHCacheEntry returns the cached extraction of a CRTDL if it is younger than ttl
+// Expired and unreadable entries are removed and reported as not found
+func LoadTORCHCacheEntry(jobsBaseDir string, baseURL string, crtdlHash string, ttl time.Duration, now time.Time) (*TORCHCacheEntry, bool) {
+	path := GetTORCHCachePath(jobsBaseDir, baseURL, crtdlHash)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry TORCHCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.StatusURL == "" || now.Sub(entry.CreatedAt) > ttl {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	return &entry, true
+}
+
+// SaveTORCHCacheEntry stores the extraction result of a CRTDL (atomic write)
+func SaveTORCHCacheEntry(jobsBaseDir string, baseURL string, entry TORCHCacheEntry) error {
+	path := GetTORCHCachePath(jobsBaseDir, baseURL, entry.CRTDLHash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create TORCH cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal TORCH cache entry: %w", err)
+	}
+	tempFile := filepath.Join(filepath.Dir(path), fmt.Sprintf(".cache.tmp.%s", uuid.New().String()))
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write TORCH cache entry: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to save TORCH cache entry: %w", err)
+	}
+	return nil
+}
+
+// RemoveTORCHCacheEntry forgets the cached extraction of a CRTDL
+func RemoveTORCHCacheEntry(jobsBaseDir string, baseURL string, crtdlHash string) error {
+	err := os.Remove(GetTORCHCachePath(jobsBaseDir, baseURL, crtdlHash))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CheckExtractionResult verifies that TORCH still serves a completed extraction
+// The status URL must answer 200 with the same files, and every file must answer HEAD with 2xx
+// Returns ErrExtractionResultGone (wrapped) if the result cannot be reused
+func (c *TORCHClient) CheckExtractionResult(entry TORCHCacheEntry) error {
+	req, err := createPollRequest(c.httpClient.Context(), entry.StatusURL, c)
+	if err != nil {
+		return err
+	}
+	resp, err := c.sendCached(req)
+	if err != nil {
+		return fmt.Errorf("failed to check cached TORCH result: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return fmt.Errorf("%w: status URL answers HTTP %d", ErrExtractionResultGone, resp.StatusCode)
+	}
+	complete, fileURLs, err := handlePollResponse(resp, c)
+	if err != nil || !complete {
+		return fmt.Errorf("%w: status URL does not list a result", ErrExtractionResultGone)
+	}
+	if len(fileURLs) != len(entry.FileURLs) {
+		return fmt.Errorf("%w: result lists %d instead of %d files", ErrExtractionResultGone, len(fileURLs), len(entry.FileURLs))
+	}
+
+	for _, fileURL := range entry.FileURLs {
+		req, err := http.NewRequestWithContext(c.httpClient.Context(), http.MethodHead, fileURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create file check request: %w", err)
+		}
+		resp, err := c.sendCached(req)
+		if err != nil {
+			return fmt.Errorf("failed to check cached TORCH file: %w", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("%w: %s answers HTTP %d", ErrExtractionResultGone, fileURL, resp.StatusCode)
+		}
+	}
+	return nil
+}
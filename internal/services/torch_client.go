@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,9 +14,10 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/trobanga/aether/internal/lib"
 	"github.com/trobanga/aether/internal/models"
-	"github.com/trobanga/aether/internal/ui"
 )
 
 // TORCHClient handles communication with TORCH server for CRTDL-based data extraction
@@ -23,6 +26,9 @@ type TORCHClient struct {
 	config     models.TORCHConfig
 	httpClient *HTTPClient
 	logger     *lib.Logger
+	events     JobEventSink
+	auth       *torchAuth
+	responses  *HTTPResponseCache // Shares status GETs and file HEADs between processes; nil = off
 }
 
 // TORCHExtractionRequest represents the FHIR Parameters resource for extraction submission
@@ -91,13 +97,50 @@ var ErrExtractionTimeout = fmt.Errorf("TORCH extraction timeout exceeded")
 // ErrInvalidCRTDL is returned when CRTDL file is malformed
 var ErrInvalidCRTDL = fmt.Errorf("invalid CRTDL file")
 
+// ErrTORCHCleanupUnsupported is returned when the TORCH server does not allow deleting
+// extraction results (DELETE answered with HTTP 405 or 501)
+var ErrTORCHCleanupUnsupported = fmt.Errorf("TORCH server does not support deleting extraction results")
+
 // NewTORCHClient creates a new TORCH client with the given configuration
 func NewTORCHClient(config models.TORCHConfig, httpClient *HTTPClient, logger *lib.Logger) *TORCHClient {
 	return &TORCHClient{
 		config:     config,
 		httpClient: httpClient,
 		logger:     logger,
+		auth:       &torchAuth{config: config, httpClient: httpClient, logger: logger},
+	}
+}
+
+// SetEventSink routes download progress events to a job's event timeline
+func (c *TORCHClient) SetEventSink(sink JobEventSink) {
+	c.events = sink
+}
+
+// SetResponseCache shares the answers to status polls and file checks through cache
+// (services.torch.response_cache_seconds); nil sends every request
+func (c *TORCHClient) SetResponseCache(cache *HTTPResponseCache) {
+	c.responses = cache
+}
+
+// sendCached answers an idempotent TORCH request from the response cache if possible, and
+// otherwise sends it and stores the answer for other processes polling the same URL
+func (c *TORCHClient) sendCached(req *http.Request) (*http.Response, error) {
+	if resp, ok := c.responses.Get(req); ok {
+		c.logger.Debug("Reusing cached TORCH response", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode)
+		return resp, nil
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := c.responses.Store(req, resp)
+	if err != nil {
+		c.logger.Debug("Failed to cache TORCH response", "url", req.URL.String(), "error", err)
+		if cached == nil {
+			return nil, err
+		}
 	}
+	return cached, nil
 }
 
 // SubmitExtraction submits a CRTDL file for extraction to TORCH server
@@ -134,24 +177,18 @@ func (c *TORCHClient) SubmitExtraction(crtdlPath string) (string, error) {
 	url := c.config.BaseURL + "/fhir/$extract-data"
 
 	// Create HTTP request with authentication
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(jsonBody)))
+	req, err := http.NewRequestWithContext(c.httpClient.Context(), "POST", url, strings.NewReader(string(jsonBody)))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", c.buildBasicAuthHeader())
 
 	// Send request
-	resp, err := c.httpClient.client.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		c.logger.Error("TORCH submission failed", "error", err)
-		return "", &TORCHError{
-			Operation:  "submit",
-			StatusCode: 0,
-			Message:    err.Error(),
-			ErrorType:  models.ErrorTypeTransient,
-		}
+		return "", requestError("submit", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
@@ -189,24 +226,15 @@ func (c *TORCHClient) SubmitExtraction(crtdlPath string) (string, error) {
 // PollExtractionStatus polls the extraction status URL until completion or timeout
 // Returns the list of file URLs when extraction is complete
 // Per TORCH API: GET Content-Location URL until HTTP 200, handle HTTP 202 as in-progress
-// Uses spinner for polling (duration unknown until extraction completes)
-func (c *TORCHClient) PollExtractionStatus(extractionURL string, showProgress bool) ([]string, error) {
+// Reports polling as a task of unknown duration, with the next poll time as its status
+func (c *TORCHClient) PollExtractionStatus(extractionURL string, progress lib.ProgressReporter) (fileURLs []string, err error) {
 	c.logger.Info("Polling TORCH extraction status", "url", extractionURL)
 
 	// Setup polling configuration
 	pollConfig := NewPollConfig(c.config.ExtractionTimeoutMinutes, c.config.PollingIntervalSeconds, c.config.MaxPollingIntervalSeconds)
 
-	// Start spinner for polling (duration unknown)
-	var spinner *ui.Spinner
-	if showProgress {
-		spinner = ui.NewSpinner("Waiting for TORCH extraction to complete")
-		spinner.Start()
-		defer func() {
-			if spinner != nil {
-				spinner.Stop(true)
-			}
-		}()
-	}
+	task := progress.Start("Waiting for TORCH extraction to complete", 0)
+	defer func() { task.Done(err) }()
 
 	for {
 		// Check timeout
@@ -221,26 +249,7 @@ func (c *TORCHClient) PollExtractionStatus(extractionURL string, showProgress bo
 		pollConfig.IncrementPollCount()
 		c.logger.Debug("Polling TORCH extraction", "attempt", pollConfig.PollCount, "interval", pollConfig.PollInterval)
 
-		// Create poll request with authentication
-		req, err := createPollRequest(extractionURL, c)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create poll request: %w", err)
-		}
-
-		// Send request
-		resp, err := c.httpClient.client.Do(req)
-		if err != nil {
-			c.logger.Error("TORCH polling failed", "error", err, "attempt", pollConfig.PollCount)
-			return nil, &TORCHError{
-				Operation:  "poll",
-				StatusCode: 0,
-				Message:    err.Error(),
-				ErrorType:  models.ErrorTypeTransient,
-			}
-		}
-
-		// Handle response
-		complete, fileURLs, err := handlePollResponse(resp, c)
+		complete, fileURLs, err := c.pollOnce(extractionURL, pollConfig.PollCount)
 		if err != nil {
 			return nil, err
 		}
@@ -250,16 +259,50 @@ func (c *TORCHClient) PollExtractionStatus(extractionURL string, showProgress bo
 			return fileURLs, nil
 		}
 
-		// Still in progress - wait with exponential backoff
-		time.Sleep(pollConfig.PollInterval)
+		// Still in progress - wait with exponential backoff (cut short if the step's deadline passes)
+		// The next poll is shown as the task's status and, via the wait sink, in 'pipeline status'
+		wait := models.WaitState{
+			Reason:    models.WaitPoll,
+			Target:    "TORCH extraction",
+			Attempt:   pollConfig.PollCount + 1,
+			NextAt:    time.Now().Add(pollConfig.PollInterval),
+			GivesUpAt: pollConfig.StartTime.Add(pollConfig.Timeout),
+		}
+		task.SetStatus(wait.Describe(lib.Now()))
+		c.httpClient.waits.report(&wait)
+		err = c.httpClient.Wait(pollConfig.PollInterval)
+		c.httpClient.waits.report(nil)
+		if err != nil {
+			return nil, err
+		}
 		pollConfig.UpdateInterval()
 	}
 }
 
+// pollOnce sends one status request, traced as a torch.poll span
+func (c *TORCHClient) pollOnce(extractionURL string, attempt int) (complete bool, fileURLs []string, err error) {
+	ctx, span := lib.StartSpan(c.httpClient.Context(), "torch.poll", attribute.Int("torch.poll.attempt", attempt))
+	defer func() { lib.EndSpan(span, err) }()
+
+	// Create poll request with authentication
+	req, err := createPollRequest(ctx, extractionURL, c)
+	if err != nil {
+		return false, nil, err
+	}
+
+	// Send request (or reuse an answer another process got moments ago)
+	resp, err := c.sendCached(req)
+	if err != nil {
+		c.logger.Error("TORCH polling failed", "error", err, "attempt", attempt)
+		return false, nil, requestError("poll", err)
+	}
+	return handlePollResponse(resp, c)
+}
+
 // DownloadExtractionFiles downloads all NDJSON files from the extraction result
 // Returns list of downloaded files with metadata
-// Uses spinner for each file download (file size is unknown)
-func (c *TORCHClient) DownloadExtractionFiles(fileURLs []string, destinationDir string, showProgress bool) ([]models.FHIRDataFile, error) {
+// Each file download is reported as a task of unknown size
+func (c *TORCHClient) DownloadExtractionFiles(fileURLs []string, destinationDir string, progress lib.ProgressReporter) ([]models.FHIRDataFile, error) {
 	c.logger.Info("Downloading TORCH extraction files",
 		"file_count", len(fileURLs),
 		"destination", destinationDir)
@@ -292,21 +335,12 @@ func (c *TORCHClient) DownloadExtractionFiles(fileURLs []string, destinationDir
 
 		destPath := filepath.Join(destinationDir, fileName)
 
-		// Start spinner for this download (file size unknown)
-		var spinner *ui.Spinner
-		if showProgress {
-			spinnerMsg := fmt.Sprintf("Downloading file %d/%d: %s", i+1, len(fileURLs), fileName)
-			spinner = ui.NewSpinner(spinnerMsg)
-			spinner.Start()
-		}
-
-		// Download file
-		file, err := c.downloadFile(fileURL, destPath)
-
-		// Stop spinner
-		if spinner != nil {
-			spinner.Stop(err == nil)
-		}
+		task := progress.Start(fmt.Sprintf("Downloading file %d/%d: %s", i+1, len(fileURLs), fileName), 0)
+		ctx, span := lib.StartSpan(c.httpClient.Context(), "torch.download", attribute.String("torch.file", fileName))
+		file, err := c.downloadFile(ctx, fileURL, destPath)
+		span.SetAttributes(attribute.Int64("torch.download.bytes", file.FileSize))
+		lib.EndSpan(span, err)
+		task.Done(err)
 
 		if err != nil {
 			c.logger.Error("Failed to download TORCH file", "url", fileURL, "error", err)
@@ -314,6 +348,9 @@ func (c *TORCHClient) DownloadExtractionFiles(fileURLs []string, destinationDir
 		}
 
 		downloadedFiles = append(downloadedFiles, file)
+		c.events.emit(models.EventDownloadFinished,
+			fmt.Sprintf("download %d/%d finished: %s", i+1, len(fileURLs), fileName),
+			map[string]any{"file": fileName, "index": i + 1, "total": len(fileURLs), "bytes": file.FileSize})
 		c.logger.Info("Downloaded TORCH file",
 			"file", fileName,
 			"size", file.FileSize,
@@ -325,60 +362,59 @@ func (c *TORCHClient) DownloadExtractionFiles(fileURLs []string, destinationDir
 	return downloadedFiles, nil
 }
 
-// downloadFile downloads a single file from URL to destination path
-func (c *TORCHClient) downloadFile(fileURL, destPath string) (models.FHIRDataFile, error) {
+// downloadFile downloads a single file from URL to destination path with requests bound to ctx
+func (c *TORCHClient) downloadFile(ctx context.Context, fileURL, destPath string) (models.FHIRDataFile, error) {
 	// Create request
-	req, err := http.NewRequest("GET", fileURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
 	if err != nil {
 		return models.FHIRDataFile{}, fmt.Errorf("failed to create download request: %w", err)
 	}
 
-	// Add authentication header for TORCH requests
-	req.Header.Set("Authorization", c.buildBasicAuthHeader())
 	req.Header.Set("Accept", "application/fhir+ndjson")
 
 	// Send request
-	resp, err := c.httpClient.client.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
-		return models.FHIRDataFile{}, &TORCHError{
-			Operation:  "download",
-			StatusCode: 0,
-			Message:    err.Error(),
-			ErrorType:  models.ErrorTypeTransient,
-		}
+		return models.FHIRDataFile{}, requestError("download", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	// Check for errors
 	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		errorType := lib.ClassifyHTTPError(resp.StatusCode)
-
-		return models.FHIRDataFile{}, &TORCHError{
-			Operation:  "download",
-			StatusCode: resp.StatusCode,
-			Message:    string(bodyBytes),
-			ErrorType:  errorType,
-		}
+		return models.FHIRDataFile{}, downloadStatusError(resp)
 	}
 
-	// Create destination file
-	destFile, err := os.Create(destPath)
+	// Download to a .part file, renamed on success (see PartialFileSuffix)
+	partPath := destPath + PartialFileSuffix
+	destFile, err := os.Create(partPath)
 	if err != nil {
 		return models.FHIRDataFile{}, fmt.Errorf("failed to create destination file: %w", err)
 	}
-	defer func() { _ = destFile.Close() }()
 
-	// Copy content
-	bytesWritten, err := io.Copy(destFile, resp.Body)
+	// Copy content, counting resources on the way so the file is not read again.
+	// A broken transfer is resumed with a Range request
+	var lines lib.LineCounter
+	download := &resumableDownload{
+		client:      c.httpClient,
+		send:        c.send,
+		statusError: downloadStatusError,
+		file:        destFile,
+		observer:    &lines,
+		restart:     func() { lines = lib.LineCounter{} },
+		logger:      c.logger,
+	}
+	bytesWritten, err := download.run(req, resp)
+	if closeErr := destFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partPath, destPath)
+	}
 	if err != nil {
-		_ = os.Remove(destPath)
-		return models.FHIRDataFile{}, fmt.Errorf("failed to write file: %w", err)
+		_ = os.Remove(partPath)
+		return models.FHIRDataFile{}, err
 	}
 
-	// Count lines (FHIR resources)
-	lineCount, _ := lib.CountResourcesInFile(destPath)
-
 	// Extract resource type from filename
 	fileName := filepath.Base(destPath)
 	resourceType := models.GetResourceTypeFromFilename(fileName)
@@ -389,11 +425,112 @@ func (c *TORCHClient) downloadFile(fileURL, destPath string) (models.FHIRDataFil
 		ResourceType: resourceType,
 		FileSize:     bytesWritten,
 		SourceStep:   models.StepTorchImport,
-		LineCount:    lineCount,
+		LineCount:    lines.Count(),
 		CreatedAt:    lib.GetFileModTime(destPath),
 	}, nil
 }
 
+// downloadStatusError returns the TORCHError for a failed file download
+func downloadStatusError(resp *http.Response) error {
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	return &TORCHError{
+		Operation:  "download",
+		StatusCode: resp.StatusCode,
+		Message:    string(bodyBytes),
+		ErrorType:  lib.ClassifyHTTPError(resp.StatusCode),
+	}
+}
+
+// CancelExtraction asks TORCH to stop a running extraction
+// Per the FHIR asynchronous request pattern: DELETE on the Content-Location URL
+// An extraction that is already gone (HTTP 404) counts as cancelled
+func (c *TORCHClient) CancelExtraction(extractionURL string) error {
+	c.logger.Info("Cancelling TORCH extraction", "url", extractionURL)
+
+	req, err := http.NewRequest(http.MethodDelete, extractionURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create cancel request: %w", err)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return requestError("cancel", err)
+	}
+	c.responses.Invalidate(extractionURL)
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &TORCHError{
+			Operation:  "cancel",
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(body)),
+			ErrorType:  models.ErrorTypeNonTransient,
+		}
+	}
+	return nil
+}
+
+// DeleteExtractionResult removes a downloaded extraction result from the TORCH server and
+// verifies that it is gone: afterwards the status URL and all file URLs must answer 404 or 410
+// Per the FHIR asynchronous request pattern the result is deleted via DELETE on the status URL
+func (c *TORCHClient) DeleteExtractionResult(statusURL string, fileURLs []string) error {
+	c.logger.Debug("Deleting TORCH extraction result", "url", statusURL, "files", len(fileURLs))
+
+	req, err := http.NewRequestWithContext(c.httpClient.Context(), http.MethodDelete, statusURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create cleanup request: %w", err)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return requestError("cleanup", err)
+	}
+	for _, resourceURL := range append([]string{statusURL}, fileURLs...) {
+		c.responses.Invalidate(resourceURL)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		return ErrTORCHCleanupUnsupported
+	case resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound:
+		return &TORCHError{
+			Operation:  "cleanup",
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(body)),
+			ErrorType:  lib.ClassifyHTTPError(resp.StatusCode),
+		}
+	}
+
+	for _, resourceURL := range append([]string{statusURL}, fileURLs...) {
+		if err := c.verifyDeleted(resourceURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyDeleted checks that a URL of a deleted extraction result is no longer served
+func (c *TORCHClient) verifyDeleted(resourceURL string) error {
+	req, err := http.NewRequestWithContext(c.httpClient.Context(), http.MethodGet, resourceURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create cleanup verification request: %w", err)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return &TORCHError{Operation: "cleanup", Message: "verification failed: " + err.Error(), ErrorType: models.ErrorTypeTransient}
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone {
+		return fmt.Errorf("cleanup not verified: %s still answers HTTP %d after DELETE", resourceURL, resp.StatusCode)
+	}
+	return nil
+}
+
 // Ping checks connectivity to TORCH server
 // Used by ValidateServiceConnectivity()
 func (c *TORCHClient) Ping() error {
@@ -405,9 +542,7 @@ func (c *TORCHClient) Ping() error {
 		return fmt.Errorf("failed to create ping request: %w", err)
 	}
 
-	req.Header.Set("Authorization", c.buildBasicAuthHeader())
-
-	resp, err := c.httpClient.client.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		c.logger.Error("TORCH ping failed", "error", err)
 		return fmt.Errorf("TORCH server unreachable: %w", err)
@@ -423,6 +558,42 @@ func (c *TORCHClient) Ping() error {
 	return nil
 }
 
+// CheckAuth verifies that TORCH accepts the configured credentials
+// Unlike Ping, authentication failures (HTTP 401/403) are reported as errors
+func (c *TORCHClient) CheckAuth() error {
+	c.logger.Debug("Checking TORCH authentication", "url", c.config.BaseURL)
+
+	req, err := http.NewRequest("GET", c.config.BaseURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create auth check request: %w", err)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		var authErr *TORCHError
+		if errors.As(err, &authErr) {
+			return authErr // No access token from the token endpoint
+		}
+		return fmt.Errorf("TORCH server unreachable: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &TORCHError{
+			Operation:  "auth",
+			StatusCode: resp.StatusCode,
+			Message:    "credentials rejected - check " + c.auth.credentialKeys(),
+			ErrorType:  models.ErrorTypeNonTransient,
+		}
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("TORCH server error: HTTP %d", resp.StatusCode)
+	}
+
+	c.logger.Debug("TORCH authentication check successful", "status", resp.StatusCode)
+	return nil
+}
+
 // encodeCRTDLToBase64 reads CRTDL file and encodes it to base64
 func (c *TORCHClient) encodeCRTDLToBase64(crtdlPath string) (string, error) {
 	// Read CRTDL file
@@ -547,13 +718,6 @@ func (c *TORCHClient) extractURLsFromFHIRFormat(result TORCHExtractionResult) []
 	return fileURLs
 }
 
-// buildBasicAuthHeader creates Basic authentication header
-func (c *TORCHClient) buildBasicAuthHeader() string {
-	credentials := c.config.Username + ":" + c.config.Password
-	encoded := base64.StdEncoding.EncodeToString([]byte(credentials))
-	return "Basic " + encoded
-}
-
 // makeAbsoluteURL ensures a URL is absolute and uses the configured baseURL
 // This handles two cases:
 // 1. Relative URLs from TORCH - prepends baseURL (scheme + host)
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -29,14 +30,13 @@ func NewPollConfig(timeoutMinutes, pollIntervalSeconds, maxPollIntervalSeconds i
 	}
 }
 
-// createPollRequest creates an HTTP GET request with authentication for polling
-func createPollRequest(extractionURL string, c *TORCHClient) (*http.Request, error) {
-	req, err := http.NewRequest("GET", extractionURL, nil)
+// createPollRequest creates an HTTP GET request with authentication for polling, bound to ctx
+func createPollRequest(ctx context.Context, extractionURL string, c *TORCHClient) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", extractionURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create poll request: %w", err)
 	}
 
-	req.Header.Set("Authorization", c.buildBasicAuthHeader())
 	req.Header.Set("Accept", "application/json")
 
 	return req, nil
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// torchSlotsDirName is the directory below the jobs directory holding extraction slot locks
+const torchSlotsDirName = ".torch-slots"
+
+// ExtractionSlot is one of the services.torch.max_active_extractions slots of a TORCH server
+// A slot is a file lock, so the limit holds across all aether processes sharing a jobs
+// directory, and the slot of a crashed process is freed by the operating system
+type ExtractionSlot struct {
+	file *os.File
+	Slot int
+}
+
+// Release frees the slot for the next queued extraction; releasing a nil slot is a no-op
+func (s *ExtractionSlot) Release() error {
+	if s == nil || s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// GetExtractionSlotsDir returns the slot lock directory of a TORCH server
+// Servers are told apart by a hash of their base URL
+func GetExtractionSlotsDir(jobsBaseDir string, baseURL string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(baseURL, "/")))
+	return filepath.Join(jobsBaseDir, torchSlotsDirName, hex.EncodeToString(sum[:])[:16])
+}
+
+// AcquireExtractionSlot waits until fewer than max_active_extractions extractions run on the
+// TORCH server and takes a slot. jobsBaseDir is the jobs directory shared by all tenants
+// Returns a nil slot if the number of extractions is unlimited. While queued, the wait is
+// reported to the wait sink and bounded by the HTTP client's context (the step deadline)
+func (c *TORCHClient) AcquireExtractionSlot(jobsBaseDir string) (*ExtractionSlot, error) {
+	limit := c.config.MaxActiveExtractions
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	dir := GetExtractionSlotsDir(jobsBaseDir, c.config.BaseURL)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create TORCH slot directory: %w", err)
+	}
+
+	interval := time.Duration(max(c.config.PollingIntervalSeconds, 1)) * time.Second
+	queuedAt := time.Now()
+	for attempt := 1; ; attempt++ {
+		for i := 1; i <= limit; i++ {
+			file, err := tryLockFile(filepath.Join(dir, fmt.Sprintf("slot-%d.lock", i)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to lock TORCH slot: %w", err)
+			}
+			if file == nil {
+				continue
+			}
+			if attempt > 1 {
+				c.logger.Info("TORCH extraction slot acquired", "slot", i, "queued", time.Since(queuedAt).Round(time.Second))
+			}
+			return &ExtractionSlot{file: file, Slot: i}, nil
+		}
+
+		if attempt == 1 {
+			c.logger.Info("TORCH server busy, queueing extraction", "max_active_extractions", limit)
+			c.events.emit(models.EventTORCHQueued,
+				fmt.Sprintf("waiting for one of %d TORCH extraction slots", limit),
+				map[string]any{"max_active_extractions": limit})
+		}
+		c.httpClient.waits.report(&models.WaitState{
+			Reason:  models.WaitSlot,
+			Target:  "TORCH extraction",
+			Attempt: attempt,
+			NextAt:  time.Now().Add(interval),
+		})
+		err := c.httpClient.Wait(interval)
+		c.httpClient.waits.report(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+)
+
+// Exit codes of runs with --emit-trace, for workflow engines deciding whether to retry a task
+const (
+	ExitCodeFailed    = 1  // Non-transient failure: fix input or configuration before re-running
+	ExitCodeTransient = 75 // Transient failure (EX_TEMPFAIL): a later retry may succeed
+)
+
+// traceColumns is the header of a step trace file
+var traceColumns = []string{
+	"job_id", "step", "status", "exit", "start", "complete", "duration_ms", "files", "bytes", "retries", "error",
+}
+
+// StepTrace is one executed step in the trace format of --emit-trace
+type StepTrace struct {
+	JobID    string
+	Step     models.StepName
+	Status   models.StepStatus
+	Exit     int
+	Start    *time.Time
+	Complete *time.Time
+	Files    int
+	Bytes    int64
+	Retries  int
+	Error    string
+}
+
+// NewStepTrace builds the trace of a step from the job state
+func NewStepTrace(jobID string, step models.PipelineStep) StepTrace {
+	trace := StepTrace{
+		JobID:    jobID,
+		Step:     step.Name,
+		Status:   step.Status,
+		Exit:     StepExitCode(step),
+		Start:    step.StartedAt,
+		Complete: step.CompletedAt,
+		Files:    step.FilesProcessed,
+		Bytes:    step.BytesProcessed,
+		Retries:  step.RetryCount,
+	}
+	if step.LastError != nil && step.Status == models.StepStatusFailed {
+		trace.Error = step.LastError.Message
+		if trace.Complete == nil {
+			trace.Complete = &step.LastError.Timestamp
+		}
+	}
+	return trace
+}
+
+// StepExitCode maps a step's outcome to the exit code convention of --emit-trace
+// Completed steps exit 0, transient failures 75 and everything else 1
+func StepExitCode(step models.PipelineStep) int {
+	switch {
+	case step.Status == models.StepStatusCompleted:
+		return 0
+	case step.LastError != nil && step.LastError.Type == models.ErrorTypeTransient:
+		return ExitCodeTransient
+	default:
+		return ExitCodeFailed
+	}
+}
+
+// GetStepTracePath returns the trace file of a step in a trace directory
+func GetStepTracePath(traceDir string, step models.StepName) string {
+	return filepath.Join(traceDir, string(step)+".trace.tsv")
+}
+
+// WriteStepTrace writes a step's trace as a tab-separated file with a header line
+// Tabs and newlines in the error message are replaced so every trace is exactly two lines
+func WriteStepTrace(traceDir string, trace StepTrace) error {
+	if err := os.MkdirAll(traceDir, 0755); err != nil {
+		return fmt.Errorf("failed to create trace directory: %w", err)
+	}
+
+	duration := ""
+	if trace.Start != nil && trace.Complete != nil {
+		duration = strconv.FormatInt(trace.Complete.Sub(*trace.Start).Milliseconds(), 10)
+	}
+	row := []string{
+		trace.JobID,
+		string(trace.Step),
+		string(trace.Status),
+		strconv.Itoa(trace.Exit),
+		formatTraceTime(trace.Start),
+		formatTraceTime(trace.Complete),
+		duration,
+		strconv.Itoa(trace.Files),
+		strconv.FormatInt(trace.Bytes, 10),
+		strconv.Itoa(trace.Retries),
+		strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(trace.Error),
+	}
+
+	content := strings.Join(traceColumns, "\t") + "\n" + strings.Join(row, "\t") + "\n"
+	if err := os.WriteFile(GetStepTracePath(traceDir, trace.Step), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	return nil
+}
+
+func formatTraceTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(lib.TimeZone()).Format(time.RFC3339Nano)
+}
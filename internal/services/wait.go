@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+const (
+	// WaitStateFileName holds the current retry/poll wait of a running job
+	WaitStateFileName = "wait.json"
+)
+
+// WaitSink receives the wait state of long-running service calls; nil means the wait is over
+// A nil sink discards wait states
+type WaitSink func(state *models.WaitState)
+
+// report forwards a wait state to the sink if one is configured
+func (s WaitSink) report(state *models.WaitState) {
+	if s != nil {
+		s(state)
+	}
+}
+
+// SaveWaitState writes jobs/<id>/wait.json, or removes it for a nil state
+func SaveWaitState(jobsBaseDir string, jobID string, state *models.WaitState) error {
+	path := filepath.Join(GetJobDir(jobsBaseDir, jobID), WaitStateFileName)
+	if state == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal wait state: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// LoadWaitState returns the current wait of a job, or nil if it is not waiting
+func LoadWaitState(jobsBaseDir string, jobID string) (*models.WaitState, error) {
+	data, err := os.ReadFile(filepath.Join(GetJobDir(jobsBaseDir, jobID), WaitStateFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state models.WaitState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("malformed wait state: %w", err)
+	}
+	return &state, nil
+}
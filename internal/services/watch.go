@@ -0,0 +1,177 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// DropWatcher detects complete filesets in a drop directory
+// A fileset is a subdirectory containing NDJSON files. It is complete once its
+// file names, sizes and modification times have not changed for the stability window
+// Hidden subdirectories (".incoming") are ignored, so uploaders can write there and rename
+type DropWatcher struct {
+	dir       string
+	stableFor time.Duration
+	metadata  models.JobMetadataConfig
+	observed  map[string]filesetObservation
+	processed map[string]bool
+}
+
+// filesetObservation remembers when a fileset's current fingerprint was first seen
+type filesetObservation struct {
+	fingerprint string
+	since       time.Time
+}
+
+// NewDropWatcher creates a watcher for dir with the given stability window
+// metadata is the job_metadata policy the jobs were persisted with, used to match hashed sources
+func NewDropWatcher(dir string, stableFor time.Duration, metadata models.JobMetadataConfig) (*DropWatcher, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve drop directory: %w", err)
+	}
+	info, err := os.Stat(absDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access drop directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("drop directory is not a directory: %s", dir)
+	}
+
+	return &DropWatcher{
+		dir:       absDir,
+		stableFor: stableFor,
+		metadata:  metadata,
+		observed:  make(map[string]filesetObservation),
+		processed: make(map[string]bool),
+	}, nil
+}
+
+// Dir returns the absolute path of the watched directory
+func (w *DropWatcher) Dir() string {
+	return w.dir
+}
+
+// MarkProcessed excludes a fileset from future polls (e.g., because a job already imported it)
+// Hashed paths (job_metadata.mode: hash) are matched against the hash of each fileset path
+func (w *DropWatcher) MarkProcessed(path string) {
+	if models.IsRedactedJobMetadata(path) {
+		w.processed[path] = true
+		return
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		w.processed[absPath] = true
+	}
+}
+
+// Poll scans the drop directory and returns the filesets that became complete, sorted by path
+// Returned filesets are marked processed and never returned again
+func (w *DropWatcher) Poll(now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read drop directory: %w", err)
+	}
+
+	var ready []string
+	present := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(w.dir, entry.Name())
+		if w.processed[path] || w.processed[w.metadata.Hash(path)] {
+			continue
+		}
+		present[path] = true
+
+		fingerprint, err := filesetFingerprint(path)
+		if err != nil {
+			// The fileset may be moving or being written - look again on the next poll
+			delete(w.observed, path)
+			continue
+		}
+		if fingerprint == "" {
+			continue // No NDJSON files yet
+		}
+
+		observation, seen := w.observed[path]
+		if !seen || observation.fingerprint != fingerprint {
+			w.observed[path] = filesetObservation{fingerprint: fingerprint, since: now}
+			continue
+		}
+		if now.Sub(observation.since) >= w.stableFor {
+			ready = append(ready, path)
+			w.processed[path] = true
+			delete(w.observed, path)
+		}
+	}
+
+	// Forget filesets that disappeared before becoming complete
+	for path := range w.observed {
+		if !present[path] {
+			delete(w.observed, path)
+		}
+	}
+
+	sort.Strings(ready)
+	return ready, nil
+}
+
+// filesetFingerprint summarizes the NDJSON files below path (name, size, modification time)
+// Returns an empty string if the directory contains no NDJSON files
+func filesetFingerprint(path string) (string, error) {
+	files, err := findNDJSONFiles(path)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s|%d|%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// ProcessedJobSources returns the absolute paths of all local inputs used by existing jobs
+// The watch command uses it so filesets imported before a restart are not imported again
+// Sources persisted as hashes are returned as-is; omitted sources cannot be matched
+func ProcessedJobSources(jobsBaseDir string) ([]string, error) {
+	jobIDs, err := ListAllJobs(jobsBaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []string
+	for _, jobID := range jobIDs {
+		job, err := LoadJobState(jobsBaseDir, jobID)
+		if err != nil {
+			continue // Corrupt or partially written jobs are reported by 'job list'
+		}
+		inputs := []models.InputSource{{Source: job.InputSource, Type: job.InputType}}
+		inputs = append(inputs, job.ExtraSources...)
+		for _, input := range inputs {
+			if input.Type != models.InputTypeLocal || input.Source == models.OmittedJobMetadata {
+				continue
+			}
+			if models.IsRedactedJobMetadata(input.Source) {
+				sources = append(sources, input.Source)
+				continue
+			}
+			if absPath, err := filepath.Abs(input.Source); err == nil {
+				sources = append(sources, absPath)
+			}
+		}
+	}
+	return sources, nil
+}
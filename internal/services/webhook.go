@@ -0,0 +1,76 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trobanga/aether/internal/models"
+)
+
+// webhookTimeout bounds how long a notification may delay the pipeline
+const webhookTimeout = 10 * time.Second
+
+// WebhookTemplateData is the data a webhook template is executed with
+type WebhookTemplateData struct {
+	Event        string              // sla_exceeded or runtime_exceeded
+	Notification any                 // the default payload of the event
+	Job          *models.PipelineJob // the job the event belongs to; nil if its state could not be loaded
+}
+
+// RenderWebhookPayload executes a webhook template and checks that the result is valid JSON
+func RenderWebhookPayload(templateText string, data WebhookTemplateData) ([]byte, error) {
+	tmpl, err := models.ParseWebhookTemplate(templateText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, fmt.Errorf("webhook template did not render valid JSON: %s", body.String())
+	}
+	return body.Bytes(), nil
+}
+
+// NotifyWebhook posts a notification to the configured SLA webhook
+// The payload is rendered with sla.webhook_template if set, else the default notification is sent
+func NotifyWebhook(sla models.SLAConfig, data WebhookTemplateData) error {
+	if sla.WebhookTemplate == "" {
+		return PostWebhook(sla.WebhookURL, data.Notification)
+	}
+	body, err := RenderWebhookPayload(sla.WebhookTemplate, data)
+	if err != nil {
+		return err
+	}
+	return postWebhookBody(sla.WebhookURL, body)
+}
+
+// PostWebhook sends payload as a JSON POST to url
+// Notifications are best-effort: no retries, short timeout
+func PostWebhook(url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	return postWebhookBody(url, body)
+}
+
+// postWebhookBody sends an encoded JSON payload to url
+func postWebhookBody(url string, body []byte) error {
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
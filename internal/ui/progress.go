@@ -80,6 +80,12 @@ func (p *ProgressBar) Set(value int64) error {
 	return p.bar.Set64(value)
 }
 
+// SetTotal changes the total, e.g. when an estimated total turns out too small
+func (p *ProgressBar) SetTotal(total int64) {
+	p.total = total
+	p.bar.ChangeMax64(total)
+}
+
 // Finish completes the progress bar
 func (p *ProgressBar) Finish() error {
 	return p.bar.Finish()
@@ -109,6 +115,7 @@ type Spinner struct {
 	description string
 	startTime   time.Time
 	active      bool
+	midLine     bool // A status line without trailing newline was printed
 }
 
 // NewSpinner creates a spinner for unknown-duration operations
@@ -131,6 +138,10 @@ func (s *Spinner) Start() {
 func (s *Spinner) Stop(success bool) {
 	s.active = false
 	elapsed := time.Since(s.startTime)
+	if s.midLine {
+		fmt.Println()
+		s.midLine = false
+	}
 
 	if success {
 		fmt.Printf("✓ %s (completed in %v)\n", s.description, elapsed.Round(time.Millisecond))
@@ -144,6 +155,16 @@ func (s *Spinner) UpdateMessage(message string) {
 	s.description = message
 	if s.active {
 		fmt.Printf("\r%s... (%v elapsed)", message, time.Since(s.startTime).Round(time.Second))
+		s.midLine = true
+	}
+}
+
+// SetStatus shows a transient status (e.g., the next poll time) after the description
+// Unlike UpdateMessage, the description used by Stop is kept
+func (s *Spinner) SetStatus(status string) {
+	if s.active {
+		fmt.Printf("\r%s... %s (%v elapsed)", s.description, status, time.Since(s.startTime).Round(time.Second))
+		s.midLine = true
 	}
 }
 
@@ -0,0 +1,57 @@
+package ui
+
+import "github.com/trobanga/aether/internal/lib"
+
+// TerminalProgress renders progress tasks in the terminal: a progress bar for tasks with a
+// known total and a spinner for tasks whose duration is unknown (polling, downloads)
+type TerminalProgress struct{}
+
+// NewTerminalProgress returns the reporter used by interactive commands
+func NewTerminalProgress() lib.ProgressReporter {
+	return TerminalProgress{}
+}
+
+// Start begins a bar if total is known, otherwise a spinner
+func (TerminalProgress) Start(description string, total int64) lib.ProgressTask {
+	if total > 0 {
+		return &barTask{bar: NewProgressBar(total, description)}
+	}
+	spinner := NewSpinner(description)
+	spinner.Start()
+	return &spinnerTask{spinner: spinner}
+}
+
+// spinnerTask adapts a Spinner to lib.ProgressTask; amounts are not shown
+type spinnerTask struct {
+	spinner *Spinner
+}
+
+func (t *spinnerTask) SetStatus(status string) { t.spinner.SetStatus(status) }
+func (t *spinnerTask) SetTotal(int64)          {}
+func (t *spinnerTask) Add(int64)               {}
+func (t *spinnerTask) Clear()                  {}
+func (t *spinnerTask) Done(err error)          { t.spinner.Stop(err == nil) }
+
+// barTask adapts a ProgressBar to lib.ProgressTask; the bar grows when its total is exceeded
+type barTask struct {
+	bar *ProgressBar
+}
+
+func (t *barTask) SetStatus(string)     {}
+func (t *barTask) SetTotal(total int64) { t.bar.SetTotal(total) }
+func (t *barTask) Clear()               { _ = t.bar.Clear() }
+
+func (t *barTask) Add(amount int64) {
+	if t.bar.current+amount > t.bar.total {
+		t.bar.SetTotal(t.bar.current + amount)
+	}
+	_ = t.bar.Add(amount)
+}
+
+func (t *barTask) Done(err error) {
+	if err != nil {
+		_ = t.bar.Clear()
+		return
+	}
+	_ = t.bar.Finish()
+}
@@ -3,6 +3,8 @@ package ui
 import (
 	"fmt"
 	"time"
+
+	"github.com/trobanga/aether/internal/lib"
 )
 
 // ThroughputCalculator tracks and calculates data processing rates
@@ -113,25 +115,7 @@ func FormatBytesPerSecond(bytesPerSec float64) string {
 
 // FormatBytes formats bytes as human-readable size
 func FormatBytes(bytes int64) string {
-	const (
-		KB = 1024
-		MB = 1024 * KB
-		GB = 1024 * MB
-		TB = 1024 * GB
-	)
-
-	fbytes := float64(bytes)
-
-	if bytes >= TB {
-		return fmt.Sprintf("%.2f TB", fbytes/TB)
-	} else if bytes >= GB {
-		return fmt.Sprintf("%.2f GB", fbytes/GB)
-	} else if bytes >= MB {
-		return fmt.Sprintf("%.2f MB", fbytes/MB)
-	} else if bytes >= KB {
-		return fmt.Sprintf("%.2f KB", fbytes/KB)
-	}
-	return fmt.Sprintf("%d B", bytes)
+	return lib.FormatBytes(bytes)
 }
 
 // Reset resets the throughput calculator
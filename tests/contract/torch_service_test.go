@@ -200,7 +200,7 @@ func TestTORCHService_PollStatus_InProgress(t *testing.T) {
 	}
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
 
-	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 	assert.Error(t, err)
 	assert.Equal(t, services.ErrExtractionTimeout, err)
 }
@@ -252,7 +252,7 @@ func TestTORCHService_PollStatus_Complete(t *testing.T) {
 	}
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
 
-	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 	assert.NoError(t, err)
 	require.Len(t, urls, 2)
 	assert.Equal(t, server.URL+"/output/batch-1.ndjson", urls[0])
@@ -290,7 +290,7 @@ func TestTORCHService_PollStatus_Failed(t *testing.T) {
 	}
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
 
-	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "500")
 }
@@ -330,7 +330,7 @@ func TestTORCHService_DownloadFile_Success(t *testing.T) {
 	// Create temp destination directory
 	tempDir := t.TempDir()
 
-	files, err := client.DownloadExtractionFiles([]string{server.URL + "/output/batch-1.ndjson"}, tempDir, false)
+	files, err := client.DownloadExtractionFiles([]string{server.URL + "/output/batch-1.ndjson"}, tempDir, lib.NoProgress)
 	assert.NoError(t, err)
 	require.Len(t, files, 1)
 
@@ -361,7 +361,7 @@ func TestTORCHService_DownloadFile_NotFound(t *testing.T) {
 	// Create temp destination directory
 	tempDir := t.TempDir()
 
-	_, err := client.DownloadExtractionFiles([]string{server.URL + "/output/missing.ndjson"}, tempDir, false)
+	_, err := client.DownloadExtractionFiles([]string{server.URL + "/output/missing.ndjson"}, tempDir, lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "404")
 }
@@ -390,7 +390,7 @@ func TestTORCHService_DownloadFile_ServerError(t *testing.T) {
 	// Create temp destination directory
 	tempDir := t.TempDir()
 
-	_, err := client.DownloadExtractionFiles([]string{server.URL + "/output/batch-1.ndjson"}, tempDir, false)
+	_, err := client.DownloadExtractionFiles([]string{server.URL + "/output/batch-1.ndjson"}, tempDir, lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "500")
 	assert.Equal(t, 1, callCount, "Should call once (no retry in current implementation)")
@@ -489,13 +489,13 @@ func TestTORCHService_EndToEnd_SubmitPollDownload(t *testing.T) {
 	assert.Equal(t, server.URL+extractionJobPath, extractionURL)
 
 	// Poll until complete
-	urls, err := client.PollExtractionStatus(extractionURL, false)
+	urls, err := client.PollExtractionStatus(extractionURL, lib.NoProgress)
 	require.NoError(t, err)
 	require.Len(t, urls, 1)
 
 	// Download files
 	downloadDir := filepath.Join(tempDir, "downloads")
-	files, err := client.DownloadExtractionFiles(urls, downloadDir, false)
+	files, err := client.DownloadExtractionFiles(urls, downloadDir, lib.NoProgress)
 	require.NoError(t, err)
 	require.Len(t, files, 1)
 
@@ -206,6 +206,36 @@ func TestJobList_FilterByStatus(t *testing.T) {
 	assert.Contains(t, failedJobs, failedJob.JobID)
 }
 
+// TestJobList_ListJobs tests the job registry's status filter and ordering
+func TestJobList_ListJobs(t *testing.T) {
+	jobsDir := filepath.Join(t.TempDir(), "jobs")
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	failedJob := createTestJobWithState(t, jobsDir, models.JobStatusFailed, models.StepDIMP)
+	time.Sleep(10 * time.Millisecond)
+	_ = createTestJobWithState(t, jobsDir, models.JobStatusCompleted, models.StepLocalImport)
+	time.Sleep(10 * time.Millisecond)
+	runningJob := createTestJobWithState(t, jobsDir, models.JobStatusInProgress, models.StepDIMP)
+
+	// A directory without state.json is not a job
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, "not-a-job"), 0755))
+
+	all, err := services.ListJobs(jobsDir, nil, logger)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, runningJob.JobID, all[0].JobID, "newest job first")
+
+	open, err := services.ListJobs(jobsDir, []models.JobStatus{models.JobStatusFailed, models.JobStatusInProgress}, logger)
+	require.NoError(t, err)
+	require.Len(t, open, 2)
+	assert.Equal(t, runningJob.JobID, open[0].JobID)
+	assert.Equal(t, failedJob.JobID, open[1].JobID)
+
+	pending, err := services.ListJobs(jobsDir, []models.JobStatus{models.JobStatusPending}, logger)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
 // Helper: Create a test job with specific state
 func createTestJobWithState(t *testing.T, jobsDir string, status models.JobStatus, currentStep models.StepName) *models.PipelineJob {
 	config := models.ProjectConfig{
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -67,7 +68,7 @@ func TestDIMPConsistency_SplitVsUnsplit(t *testing.T) {
 		}
 
 		logger := lib.NewLogger(lib.LogLevelDebug)
-		err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+		err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 		require.NoError(t, err, "DIMP step should succeed without splitting")
 
 		// Read output
@@ -130,7 +131,7 @@ func TestDIMPConsistency_SplitVsUnsplit(t *testing.T) {
 		}
 
 		logger := lib.NewLogger(lib.LogLevelDebug)
-		err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+		err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 		require.NoError(t, err, "DIMP step should succeed with splitting")
 
 		// Read output
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -106,7 +107,7 @@ func TestDIMPResumeAfterInterrupt(t *testing.T) {
 
 	// Execute DIMP step (this should skip patient.ndjson since it's already processed)
 	jobDir := filepath.Join(jobsDir, job.JobID)
-	err = pipeline.ExecuteDIMPStep(reloadedJob, jobDir, logger)
+	err = pipeline.ExecuteDIMPStep(context.Background(), reloadedJob, jobDir, logger, lib.NoProgress)
 
 	// The bug: ExecuteDIMPStep currently processes ALL files, including patient.ndjson
 	// Expected: Should only process observation.ndjson and condition.ndjson
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -72,7 +73,7 @@ func TestDIMPStepWithLargeBundle(t *testing.T) {
 	logger := lib.NewLogger(lib.LogLevelDebug)
 
 	// Execute DIMP step
-	err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 	require.NoError(t, err, "DIMP step should complete without error")
 
 	// Verify output file exists
@@ -179,7 +180,7 @@ func TestDIMPStepWithLargeBundleAndChunks(t *testing.T) {
 
 	// Execute DIMP step
 	startTime := time.Now()
-	err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 	duration := time.Since(startTime)
 	require.NoError(t, err, "DIMP step should complete without error")
 
@@ -255,7 +256,7 @@ func TestDIMPStepWithSmallBundleNoSplit(t *testing.T) {
 
 	logger := lib.NewLogger(lib.LogLevelDebug)
 
-	err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 	require.NoError(t, err, "DIMP step should complete without error")
 
 	// Verify output exists
@@ -501,7 +502,7 @@ func TestDIMPStepWithOversizedResource(t *testing.T) {
 
 	// Execute DIMP step - should handle oversized resource gracefully
 	logger := lib.NewLogger(lib.LogLevelInfo)
-	err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 
 	// Expect error due to oversized resource
 	assert.Error(t, err, "Step should error when oversized resource is encountered")
@@ -558,7 +559,7 @@ func TestDIMPStepWithCustomThreshold(t *testing.T) {
 		}
 
 		logger := lib.NewLogger(lib.LogLevelDebug)
-		err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+		err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 		require.NoError(t, err, "DIMP step should succeed with valid threshold")
 
 		// Verify output file exists
@@ -609,7 +610,7 @@ func TestDIMPStepWithCustomThreshold(t *testing.T) {
 		}
 
 		logger := lib.NewLogger(lib.LogLevelDebug)
-		err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+		err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 		require.NoError(t, err, "DIMP step should succeed with valid threshold")
 
 		// Verify output file exists
@@ -660,7 +661,7 @@ func TestDIMPStepWithCustomThreshold(t *testing.T) {
 		}
 
 		logger := lib.NewLogger(lib.LogLevelDebug)
-		err := pipeline.ExecuteDIMPStep(job, jobDir, logger)
+		err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, logger, lib.NoProgress)
 		require.NoError(t, err, "DIMP step should succeed with valid threshold")
 
 		// Verify output file exists
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -49,7 +50,7 @@ func TestPipelineImportError_UnreachableURL(t *testing.T) {
 	startedJob := pipeline.StartJob(job)
 
 	// Execute import - should fail
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	// Verify error
 	assert.Error(t, err, "Import should fail for unreachable URL")
@@ -110,7 +111,7 @@ func TestPipelineImportError_HTTP404(t *testing.T) {
 	// Execute import
 	job, _ := pipeline.CreateJob(server.URL+"/missing.ndjson", config, logger)
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	// Verify error
 	assert.Error(t, err, "Import should fail with 404")
@@ -161,7 +162,7 @@ func TestPipelineImportError_HTTP500WithRetry(t *testing.T) {
 	// Execute import
 	job, _ := pipeline.CreateJob(server.URL+"/error.ndjson", config, logger)
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	// Verify error after retries
 	assert.Error(t, err, "Import should fail after max retries")
@@ -207,7 +208,7 @@ func TestPipelineImportError_InvalidLocalPath(t *testing.T) {
 
 	// Start and execute
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	// Verify error
 	assert.Error(t, err, "Import should fail for nonexistent directory")
@@ -248,7 +249,7 @@ func TestPipelineImportError_EmptyDirectory(t *testing.T) {
 	// Execute import
 	job, _ := pipeline.CreateJob(emptyDir, config, logger)
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	// Verify error
 	assert.Error(t, err, "Import should fail for empty directory")
@@ -287,7 +288,7 @@ func TestPipelineImportError_PathIsFile(t *testing.T) {
 	// Execute import
 	job, _ := pipeline.CreateJob(filePath, config, logger)
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	// Verify error
 	assert.Error(t, err, "Import should fail when path is a file")
@@ -331,7 +332,7 @@ func TestPipelineImportError_NetworkTimeout(t *testing.T) {
 	// Execute import
 	job, _ := pipeline.CreateJob(server.URL+"/slow.ndjson", config, logger)
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	// Verify timeout error
 	assert.Error(t, err, "Import should fail with timeout")
@@ -374,7 +375,7 @@ func TestPipelineImportError_StatePersistence(t *testing.T) {
 	// Execute import
 	job, _ := pipeline.CreateJob(server.URL+"/bad.ndjson", config, logger)
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	assert.Error(t, err)
 
@@ -432,7 +433,7 @@ func TestPipelineImportError_PartialDownloadCleanup(t *testing.T) {
 	// we test the cleanup mechanism with a different error scenario
 	job, _ := pipeline.CreateJob("http://localhost:99999/unreachable.ndjson", config, logger)
 	startedJob := pipeline.StartJob(job)
-	_, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	_, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	assert.Error(t, err)
 
@@ -1,11 +1,15 @@
 package integration
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -65,7 +69,7 @@ func TestPipelineImportURL_EndToEnd(t *testing.T) {
 	assert.Equal(t, models.JobStatusInProgress, startedJob.Status)
 
 	// Step 3: Execute import with progress display
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, true)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 	require.NoError(t, err, "Import should succeed")
 
 	// Verify import step completed
@@ -139,7 +143,7 @@ func TestPipelineImportURL_WithRetry(t *testing.T) {
 	// Create and execute job
 	job, _ := pipeline.CreateJob(server.URL+"/test.ndjson", config, logger)
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	// Verify retry succeeded
 	require.NoError(t, err, "Import should succeed after retries")
@@ -187,7 +191,7 @@ func TestPipelineImportURL_LargeFile(t *testing.T) {
 	// Execute import with progress
 	job, _ := pipeline.CreateJob(server.URL+"/large.ndjson", config, logger)
 	startedJob := pipeline.StartJob(job)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, true)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	require.NoError(t, err, "Import should succeed")
 
@@ -243,7 +247,7 @@ func TestPipelineImportURL_ProgressDisplay(t *testing.T) {
 	startedJob := pipeline.StartJob(job)
 
 	// This should use progress bar/spinner internally (progress indicator requirementsc, Progress indicators must update at least every 2 seconds)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, true)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 	require.NoError(t, err, "Import should succeed")
 
@@ -290,7 +294,7 @@ func TestPipelineImportURL_MultipleURLs(t *testing.T) {
 	for _, url := range urls {
 		job, _ := pipeline.CreateJob(url, config, logger)
 		startedJob := pipeline.StartJob(job)
-		importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+		importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 		require.NoError(t, err, "Import should succeed for URL %s", url)
 		jobs = append(jobs, importedJob)
 	}
@@ -365,7 +369,7 @@ func TestPipelineImportURL_FilenameFromURL(t *testing.T) {
 			url := server.URL + tt.urlPath
 			job, _ := pipeline.CreateJob(url, config, logger)
 			startedJob := pipeline.StartJob(job)
-			_, _ = pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+			_, _ = pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 
 			// Verify filename
 			importDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepHttpImport)
@@ -412,8 +416,8 @@ func TestPipelineImportURL_ConcurrentDownloads(t *testing.T) {
 	startedJob1 := pipeline.StartJob(job1)
 	startedJob2 := pipeline.StartJob(job2)
 
-	_, err1 := pipeline.ExecuteImportStep(startedJob1, logger, httpClient, false)
-	_, err2 := pipeline.ExecuteImportStep(startedJob2, logger, httpClient, false)
+	_, err1 := pipeline.ExecuteImportStep(context.Background(), startedJob1, logger, httpClient, lib.NoProgress)
+	_, err2 := pipeline.ExecuteImportStep(context.Background(), startedJob2, logger, httpClient, lib.NoProgress)
 
 	// Verify both succeeded independently
 	require.NoError(t, err1, "Job1 import should succeed")
@@ -433,3 +437,140 @@ func TestPipelineImportURL_ConcurrentDownloads(t *testing.T) {
 	assert.FileExists(t, file1, "Job1 file should exist")
 	assert.FileExists(t, file2, "Job2 file should exist")
 }
+
+// TestPipelineImportFHIRSearch_EndToEnd verifies that a FHIR search URL is paged through by the http_import step
+func TestPipelineImportFHIRSearch_EndToEnd(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		if r.URL.Query().Get("_page") == "" {
+			_, _ = fmt.Fprintf(w, `{"resourceType":"Bundle","type":"searchset","link":[{"relation":"next","url":"%s/fhir/Observation?category=laboratory&_page=2"}],"entry":[{"resource":{"resourceType":"Observation","id":"o1"},"search":{"mode":"match"}}]}`, "http://"+r.Host)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset","entry":[{"resource":{"resourceType":"Observation","id":"o2"},"search":{"mode":"match"}}]}`)
+	}))
+	defer server.Close()
+
+	config := models.ProjectConfig{
+		JobsDir:  filepath.Join(t.TempDir(), "jobs"),
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepHttpImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	job, err := pipeline.CreateJob(server.URL+"/fhir/Observation?category=laboratory", config, logger)
+	require.NoError(t, err)
+	assert.Equal(t, models.InputTypeFHIRSearch, job.InputType)
+
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), pipeline.StartJob(job), logger, services.DefaultHTTPClient(), lib.NoProgress)
+	require.NoError(t, err)
+	assert.Equal(t, 1, importedJob.TotalFiles)
+	require.Len(t, importedJob.ImportedFiles, 1)
+	assert.Equal(t, 2, importedJob.ImportedFiles[0].LineCount)
+	assert.Equal(t, job.InputSource, importedJob.ImportedFiles[0].Source)
+
+	importDir := services.GetJobOutputDir(config.JobsDir, job.JobID, models.StepHttpImport)
+	assert.FileExists(t, filepath.Join(importDir, "Observation.ndjson"))
+}
+
+// TestPipelineImportFHIRBulkExport_EndToEnd verifies that a $export URL is kicked off, polled and downloaded by the http_import step
+func TestPipelineImportFHIRBulkExport_EndToEnd(t *testing.T) {
+	var polls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/fhir/Group/g1/$export":
+			assert.Equal(t, "respond-async", r.Header.Get("Prefer"))
+			w.Header().Set("Content-Location", base+"/status/1")
+			w.WriteHeader(http.StatusAccepted)
+		case "/status/1":
+			if polls++; polls == 1 {
+				w.Header().Set("X-Progress", "50%")
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"transactionTime":"2026-01-01T00:00:00Z","request":"%[1]s/fhir/Group/g1/$export","requiresAccessToken":false,
+			  "output":[{"type":"Patient","url":"%[1]s/files/1"},{"type":"Observation","url":"%[1]s/files/2"}],"error":[]}`, base)
+		case "/files/1":
+			_, _ = fmt.Fprint(w, "{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n")
+		case "/files/2":
+			_, _ = fmt.Fprint(w, "{\"resourceType\":\"Observation\",\"id\":\"o1\"}\n{\"resourceType\":\"Observation\",\"id\":\"o2\"}\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := models.ProjectConfig{
+		JobsDir:  filepath.Join(t.TempDir(), "jobs"),
+		Services: models.ServiceConfig{FHIRExport: models.FHIRExportConfig{PollingIntervalSeconds: 1}},
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepHttpImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	job, err := pipeline.CreateJob(server.URL+"/fhir/Group/g1/$export", config, logger)
+	require.NoError(t, err)
+	assert.Equal(t, models.InputTypeBulkExport, job.InputType)
+
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), pipeline.StartJob(job), logger, services.DefaultHTTPClient(), lib.NoProgress)
+	require.NoError(t, err)
+	assert.Equal(t, 2, polls)
+	require.Len(t, importedJob.ImportedFiles, 2)
+	assert.Equal(t, "Patient.ndjson", importedJob.ImportedFiles[0].FileName)
+	assert.Equal(t, "Observation.ndjson", importedJob.ImportedFiles[1].FileName)
+	assert.Equal(t, 2, importedJob.ImportedFiles[1].LineCount)
+	assert.Equal(t, job.InputSource, importedJob.ImportedFiles[0].Source)
+
+	importDir := services.GetJobOutputDir(config.JobsDir, job.JobID, models.StepHttpImport)
+	assert.FileExists(t, filepath.Join(importDir, "Patient.ndjson"))
+	assert.FileExists(t, filepath.Join(importDir, "Observation.ndjson"))
+}
+
+// TestPipelineImportS3_UploadsOutput verifies import from an s3:// prefix and the output.s3 sink
+// uploading the step's output under <prefix>/<job-id>/
+func TestPipelineImportS3_UploadsOutput(t *testing.T) {
+	var mu sync.Mutex
+	uploaded := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=minio/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/staging":
+			assert.Equal(t, "study-42/", r.URL.Query().Get("prefix"))
+			_, _ = fmt.Fprint(w, `<ListBucketResult><Contents><Key>study-42/Patient.ndjson</Key><Size>38</Size></Contents></ListBucketResult>`)
+		case r.Method == http.MethodGet && r.URL.Path == "/staging/study-42/Patient.ndjson":
+			_, _ = fmt.Fprint(w, "{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n")
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/results/"):
+			body, _ := io.ReadAll(r.Body)
+			uploaded[strings.TrimPrefix(r.URL.Path, "/results/")] = string(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := models.ProjectConfig{
+		JobsDir:  filepath.Join(t.TempDir(), "jobs"),
+		Services: models.ServiceConfig{S3: models.S3Config{Endpoint: server.URL, AccessKeyID: "minio", SecretAccessKey: "minio-secret"}},
+		Output:   models.OutputConfig{S3: models.S3OutputConfig{URL: "s3://results/aether", Steps: []models.StepName{models.StepHttpImport}}},
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepHttpImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	job, err := pipeline.CreateJob("s3://staging/study-42/", config, logger)
+	require.NoError(t, err)
+	assert.Equal(t, models.InputTypeS3, job.InputType)
+
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), pipeline.StartJob(job), logger, services.DefaultHTTPClient(), lib.NoProgress)
+	require.NoError(t, err)
+	require.Len(t, importedJob.ImportedFiles, 1)
+	assert.Equal(t, "Patient.ndjson", importedJob.ImportedFiles[0].FileName)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n", uploaded["aether/"+job.JobID+"/import/Patient.ndjson"])
+	step, _ := models.GetStepByName(*importedJob, models.StepHttpImport)
+	assert.Equal(t, models.StepStatusCompleted, step.Status)
+}
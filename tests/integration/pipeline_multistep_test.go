@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -83,7 +84,7 @@ func TestPipelineMultiStep_AutomaticExecution(t *testing.T) {
 	// Execute import step
 	logger = lib.NewLogger(lib.LogLevelError) // Suppress logs in tests
 	httpClient := services.NewHTTPClient(30*time.Second, config.Retry, logger)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 	require.NoError(t, err)
 	require.NoError(t, pipeline.UpdateJob(jobsDir, importedJob))
 
@@ -108,7 +109,7 @@ func TestPipelineMultiStep_AutomaticExecution(t *testing.T) {
 
 	// Execute DIMP step
 	jobDir := services.GetJobDir(jobsDir, job.JobID)
-	err = pipeline.ExecuteDIMPStep(advancedJob, jobDir, logger)
+	err = pipeline.ExecuteDIMPStep(context.Background(), advancedJob, jobDir, logger, lib.NoProgress)
 	require.NoError(t, err, "DIMP step should execute successfully")
 
 	// Verify DIMP step completed
@@ -198,7 +199,7 @@ func TestPipelineMultiStep_OnlyImportEnabled(t *testing.T) {
 	// Execute import
 	logger = lib.NewLogger(lib.LogLevelError)
 	httpClient := services.NewHTTPClient(30*time.Second, config.Retry, logger)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 	require.NoError(t, err)
 
 	// Try to get next step - should be empty
@@ -319,7 +320,7 @@ func TestPipelineMultiStep_JobStatePersistedBetweenSteps(t *testing.T) {
 
 	logger = lib.NewLogger(lib.LogLevelError)
 	httpClient := services.NewHTTPClient(30*time.Second, config.Retry, logger)
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, httpClient, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, httpClient, lib.NoProgress)
 	require.NoError(t, err)
 	require.NoError(t, pipeline.UpdateJob(jobsDir, importedJob))
 
@@ -337,7 +338,7 @@ func TestPipelineMultiStep_JobStatePersistedBetweenSteps(t *testing.T) {
 
 	// Execute DIMP
 	jobDir := services.GetJobDir(jobsDir, jobID)
-	err = pipeline.ExecuteDIMPStep(advancedJob, jobDir, logger)
+	err = pipeline.ExecuteDIMPStep(context.Background(), advancedJob, jobDir, logger, lib.NoProgress)
 	require.NoError(t, err)
 	require.NoError(t, pipeline.UpdateJob(jobsDir, advancedJob))
 
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 	"time"
@@ -61,7 +62,7 @@ func TestPipelineRetry_TransientHTTPError(t *testing.T) {
 	}
 
 	// Test RetryImportStep - should be allowed
-	retriedJob, retryErr := pipeline.RetryImportStep(job, logger, httpClient, false)
+	retriedJob, retryErr := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Retry should be attempted (will fail with empty directory, but retry was allowed)
 	assert.Error(t, retryErr)
@@ -118,7 +119,7 @@ func TestPipelineRetry_MaxRetriesExhausted(t *testing.T) {
 	}
 
 	// First retry - should be allowed (retry count 0 -> 1)
-	job2, err := pipeline.RetryImportStep(job1, logger, httpClient, false)
+	job2, err := pipeline.RetryImportStep(context.Background(), job1, logger, httpClient, lib.NoProgress)
 	assert.Error(t, err)
 	assert.NotNil(t, job2)
 	assert.NotContains(t, err.Error(), "retry not allowed", "First retry should be allowed")
@@ -137,7 +138,7 @@ func TestPipelineRetry_MaxRetriesExhausted(t *testing.T) {
 	}
 
 	// Second retry - should be allowed (retry count 1 -> 2)
-	job3, err := pipeline.RetryImportStep(job2, logger, httpClient, false)
+	job3, err := pipeline.RetryImportStep(context.Background(), job2, logger, httpClient, lib.NoProgress)
 	assert.Error(t, err)
 	assert.NotNil(t, job3)
 	assert.NotContains(t, err.Error(), "retry not allowed", "Second retry should be allowed")
@@ -156,7 +157,7 @@ func TestPipelineRetry_MaxRetriesExhausted(t *testing.T) {
 		Config:      job3.Config,
 	}
 
-	job4, err := pipeline.RetryImportStep(job3, logger, httpClient, false)
+	job4, err := pipeline.RetryImportStep(context.Background(), job3, logger, httpClient, lib.NoProgress)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "retry not allowed", "Third retry should be rejected")
 	assert.Nil(t, job4, "Should return nil when retry not allowed")
@@ -209,7 +210,7 @@ func TestPipelineRetry_EventualSuccess(t *testing.T) {
 
 	// Verify multiple retries can be attempted
 	for i := 0; i < config.Retry.MaxAttempts-1; i++ {
-		retriedJob, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+		retriedJob, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 		assert.Error(t, err, "Expected error from empty directory")
 		assert.NotNil(t, retriedJob, "Should return updated job")
 		assert.NotContains(t, err.Error(), "retry not allowed", "Retry %d should be allowed", i+1)
@@ -230,7 +231,7 @@ func TestPipelineRetry_EventualSuccess(t *testing.T) {
 
 	// After MaxAttempts-1 retries, we're at retry count (MaxAttempts-1)
 	// One more retry should still be allowed
-	retriedJob, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+	retriedJob, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 	assert.Error(t, err, "Expected error from empty directory")
 	assert.NotNil(t, retriedJob, "Should return updated job")
 	assert.NotContains(t, err.Error(), "retry not allowed", "Last retry should still be allowed")
@@ -249,7 +250,7 @@ func TestPipelineRetry_EventualSuccess(t *testing.T) {
 	}
 
 	// NOW the retry count should be at max, and next retry should be rejected
-	_, err = pipeline.RetryImportStep(job, logger, httpClient, false)
+	_, err = pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "retry not allowed", "Should reject after max retries")
 }
@@ -298,7 +299,7 @@ func TestPipelineRetry_NonTransientNoRetry(t *testing.T) {
 	}
 
 	// Attempt retry - should be rejected immediately
-	retriedJob, retryErr := pipeline.RetryImportStep(job, logger, httpClient, false)
+	retriedJob, retryErr := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Verify retry was rejected
 	require.Error(t, retryErr)
@@ -355,7 +356,7 @@ func TestPipelineRetry_StateTransitions(t *testing.T) {
 	assert.Equal(t, 0, step1.RetryCount)
 
 	// First retry
-	job2, err := pipeline.RetryImportStep(job1, logger, httpClient, false)
+	job2, err := pipeline.RetryImportStep(context.Background(), job1, logger, httpClient, lib.NoProgress)
 	assert.Error(t, err) // Will fail with empty directory
 	assert.NotNil(t, job2)
 
@@ -414,7 +415,7 @@ func TestPipelineRetry_BackoffBehavior(t *testing.T) {
 
 	// Measure time for first retry
 	start := time.Now()
-	job2, err := pipeline.RetryImportStep(job1, logger, httpClient, false)
+	job2, err := pipeline.RetryImportStep(context.Background(), job1, logger, httpClient, lib.NoProgress)
 	duration := time.Since(start)
 
 	assert.Error(t, err) // Will fail with empty directory
@@ -475,7 +476,7 @@ func TestPipelineRetry_ImmutabilityVerification(t *testing.T) {
 	originalRetryCount := originalStep.RetryCount
 
 	// Call RetryImportStep
-	_, err := pipeline.RetryImportStep(originalJob, logger, httpClient, false)
+	_, err := pipeline.RetryImportStep(context.Background(), originalJob, logger, httpClient, lib.NoProgress)
 	assert.Error(t, err) // Expected to fail with empty directory
 
 	// Verify original job is unchanged
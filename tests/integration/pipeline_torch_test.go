@@ -1,11 +1,16 @@
 package integration
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -161,7 +166,7 @@ func TestPipeline_TORCHExtraction_EndToEnd(t *testing.T) {
 
 	// Execute import step (which should trigger TORCH extraction)
 	httpClient := services.NewHTTPClient(2*time.Second, config.Retry, logger)
-	updatedJob, err := pipeline.ExecuteImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Verify successful execution
 	require.NoError(t, err)
@@ -262,7 +267,7 @@ func TestPipeline_TORCHExtraction_EmptyResult(t *testing.T) {
 	require.NoError(t, err)
 
 	httpClient := services.NewHTTPClient(2*time.Second, config.Retry, logger)
-	updatedJob, err := pipeline.ExecuteImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Empty result should be handled gracefully
 	require.NoError(t, err)
@@ -310,7 +315,7 @@ func TestPipeline_TORCHExtraction_ServerUnavailable(t *testing.T) {
 	require.NoError(t, err)
 
 	httpClient := services.NewHTTPClient(2*time.Second, config.Retry, logger)
-	_, err = pipeline.ExecuteImportStep(job, logger, httpClient, false)
+	_, err = pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Should fail with network error
 	assert.Error(t, err)
@@ -410,7 +415,7 @@ func TestPipeline_DirectTORCHURL_Download(t *testing.T) {
 
 	// Execute import step (should download directly without extraction submission)
 	httpClient := services.NewHTTPClient(2*time.Second, config.Retry, logger)
-	updatedJob, err := pipeline.ExecuteImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Verify successful execution
 	require.NoError(t, err)
@@ -497,7 +502,7 @@ func TestPipeline_DirectTORCHURL_EmptyResult(t *testing.T) {
 	require.NoError(t, err)
 
 	httpClient := services.NewHTTPClient(2*time.Second, config.Retry, logger)
-	updatedJob, err := pipeline.ExecuteImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Empty result should be handled gracefully
 	require.NoError(t, err)
@@ -758,14 +763,14 @@ func TestPipeline_TORCHExtraction_JobResumption(t *testing.T) {
 	t.Logf("Phase 2: Job reloaded from disk with extraction URL: %s", reloadedJob.TORCHExtractionURL)
 
 	// Resume polling using the saved extraction URL
-	urls, err := torchClient.PollExtractionStatus(reloadedJob.TORCHExtractionURL, false)
+	urls, err := torchClient.PollExtractionStatus(reloadedJob.TORCHExtractionURL, lib.NoProgress)
 	require.NoError(t, err)
 	require.Len(t, urls, 1)
 
 	t.Logf("Phase 2: Polling resumed and completed, got %d file URL(s)", len(urls))
 
 	// Download files
-	files, err := torchClient.DownloadExtractionFiles(urls, services.GetJobOutputDir(jobsDir, reloadedJob.JobID, models.StepTorchImport), false)
+	files, err := torchClient.DownloadExtractionFiles(urls, services.GetJobOutputDir(jobsDir, reloadedJob.JobID, models.StepTorchImport), lib.NoProgress)
 	require.NoError(t, err)
 	require.Len(t, files, 1)
 
@@ -789,3 +794,361 @@ func TestPipeline_TORCHExtraction_JobResumption(t *testing.T) {
 
 	t.Logf("Job resumption test passed: extraction completed successfully after simulated restart")
 }
+
+func TestPipeline_DirectTORCHURL_CleanupAfterDownload(t *testing.T) {
+	jobsDir := filepath.Join(t.TempDir(), "jobs")
+	resultPath := "/fhir/extraction/result-cleanup"
+	deleted := false
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == resultPath:
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		case deleted:
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == resultPath:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"resourceType": "Parameters",
+				"parameter": []map[string]any{{
+					"name": "output",
+					"part": []map[string]any{{"name": "url", "valueUrl": server.URL + "/output/Patient.ndjson"}},
+				}},
+			})
+		case r.URL.Path == "/output/Patient.ndjson":
+			w.Header().Set("Content-Type", "application/fhir+ndjson")
+			_, _ = w.Write([]byte(`{"resourceType":"Patient","id":"p1"}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := models.ProjectConfig{
+		Services: models.ServiceConfig{
+			TORCH: models.TORCHConfig{
+				BaseURL:                   server.URL,
+				Username:                  "testuser",
+				Password:                  "testpass",
+				ExtractionTimeoutMinutes:  1,
+				PollingIntervalSeconds:    1,
+				MaxPollingIntervalSeconds: 5,
+				CleanupAfterDownload:      true,
+			},
+		},
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepTorchImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		JobsDir:  jobsDir,
+	}
+
+	logger := lib.NewLogger(lib.LogLevelError)
+	job, err := pipeline.CreateJob(server.URL+resultPath, config, logger)
+	require.NoError(t, err)
+
+	httpClient := services.NewHTTPClient(2*time.Second, config.Retry, logger)
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updatedJob.TotalFiles)
+	assert.True(t, deleted, "extraction result should be deleted on the TORCH server")
+
+	events, err := services.LoadJobEvents(jobsDir, job.JobID)
+	require.NoError(t, err)
+	var cleanup *models.JobEvent
+	for i := range events {
+		if events[i].Type == models.EventTORCHCleanup {
+			cleanup = &events[i]
+		}
+	}
+	require.NotNil(t, cleanup, "expected a torch_cleanup event")
+	assert.Equal(t, true, cleanup.Fields["deleted"])
+}
+
+// TestPipeline_TORCHExtraction_ResultCache verifies that a second job with the same CRTDL
+// reuses the first job's extraction instead of submitting a new one
+func TestPipeline_TORCHExtraction_ResultCache(t *testing.T) {
+	tempDir := t.TempDir()
+	jobsDir := filepath.Join(tempDir, "jobs")
+	crtdlPath := filepath.Join(tempDir, "cohort.crtdl")
+	require.NoError(t, os.WriteFile(crtdlPath, []byte(`{"cohortDefinition":{"version":"1.0.0","inclusionCriteria":[[{"termCodes":[{"code":"424144002","system":"http://snomed.info/sct"}]}]]},"dataExtraction":{"attributeGroups":[]}}`), 0644))
+
+	statusPath := "/fhir/extraction/cached"
+	submissions := 0
+	resultGone := false
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/fhir/$extract-data":
+			submissions++
+			resultGone = false
+			w.Header().Set("Content-Location", server.URL+statusPath)
+			w.WriteHeader(http.StatusAccepted)
+		case resultGone:
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == statusPath:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"resourceType": "Parameters",
+				"parameter": []map[string]any{{
+					"name": "output",
+					"part": []map[string]any{{"name": "url", "valueUrl": server.URL + "/output/Patient.ndjson"}},
+				}},
+			})
+		case r.URL.Path == "/output/Patient.ndjson":
+			w.Header().Set("Content-Type", "application/fhir+ndjson")
+			_, _ = w.Write([]byte(`{"resourceType":"Patient","id":"p1"}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := models.ProjectConfig{
+		Services: models.ServiceConfig{
+			TORCH: models.TORCHConfig{
+				BaseURL:                   server.URL,
+				ExtractionTimeoutMinutes:  1,
+				PollingIntervalSeconds:    1,
+				MaxPollingIntervalSeconds: 5,
+				ResultCacheTTLMinutes:     60,
+			},
+		},
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepTorchImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		JobsDir:  jobsDir,
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	runJob := func() *models.PipelineJob {
+		job, err := pipeline.CreateJob(crtdlPath, config, logger)
+		require.NoError(t, err)
+		updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, services.NewHTTPClient(2*time.Second, config.Retry, logger), lib.NoProgress)
+		require.NoError(t, err)
+		assert.Equal(t, 1, updatedJob.TotalFiles)
+		return updatedJob
+	}
+	hasCacheHit := func(jobID string) bool {
+		events, err := services.LoadJobEvents(jobsDir, jobID)
+		require.NoError(t, err)
+		for _, event := range events {
+			if event.Type == models.EventTORCHCacheHit {
+				return true
+			}
+		}
+		return false
+	}
+
+	first := runJob()
+	assert.Equal(t, 1, submissions)
+	assert.False(t, hasCacheHit(first.JobID))
+
+	second := runJob()
+	assert.Equal(t, 1, submissions, "second job should reuse the cached extraction")
+	assert.True(t, hasCacheHit(second.JobID))
+	assert.Equal(t, server.URL+statusPath, second.TORCHExtractionURL)
+
+	// Once TORCH has dropped the result, the next job extracts again
+	resultGone = true
+	third := runJob()
+	assert.Equal(t, 2, submissions)
+	assert.False(t, hasCacheHit(third.JobID))
+}
+
+// TestPipeline_TORCHExtraction_SplitByPeriod verifies that a CRTDL spanning several quarters is
+// extracted once per quarter and the results are merged into the import directory
+func TestPipeline_TORCHExtraction_SplitByPeriod(t *testing.T) {
+	tempDir := t.TempDir()
+	jobsDir := filepath.Join(tempDir, "jobs")
+	crtdlPath := filepath.Join(tempDir, "cohort.crtdl")
+	require.NoError(t, os.WriteFile(crtdlPath, []byte(`{"cohortDefinition":{"version":"1.0.0","inclusionCriteria":[[{"termCodes":[{"code":"424144002","system":"http://snomed.info/sct"}]}]]},"dataExtraction":{"attributeGroups":[{"groupReference":"https://www.medizininformatik-initiative.de/fhir/core/modul-labor/StructureDefinition/ObservationLab","filter":[{"type":"date","name":"date","start":"2023-02-15","end":"2023-08-31"}]}]}}`), 0644))
+
+	var mu sync.Mutex
+	var windows []string
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/fhir/$extract-data":
+			var params struct {
+				Parameter []struct {
+					ValueBase64Binary string `json:"valueBase64Binary"`
+				} `json:"parameter"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+			crtdl, err := base64.StdEncoding.DecodeString(params.Parameter[0].ValueBase64Binary)
+			require.NoError(t, err)
+			var parsed struct {
+				DataExtraction struct {
+					AttributeGroups []struct {
+						Filter []struct {
+							Start string `json:"start"`
+							End   string `json:"end"`
+						} `json:"filter"`
+					} `json:"attributeGroups"`
+				} `json:"dataExtraction"`
+			}
+			require.NoError(t, json.Unmarshal(crtdl, &parsed))
+			filter := parsed.DataExtraction.AttributeGroups[0].Filter[0]
+
+			mu.Lock()
+			windows = append(windows, filter.Start+"/"+filter.End)
+			mu.Unlock()
+			w.Header().Set("Content-Location", server.URL+"/fhir/extraction/"+filter.Start)
+			w.WriteHeader(http.StatusAccepted)
+		case strings.HasPrefix(r.URL.Path, "/fhir/extraction/"):
+			start := strings.TrimPrefix(r.URL.Path, "/fhir/extraction/")
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"resourceType": "Parameters",
+				"parameter": []map[string]any{{
+					"name": "output",
+					"part": []map[string]any{{"name": "url", "valueUrl": server.URL + "/output/" + start + "/Observation.ndjson"}},
+				}},
+			})
+		case strings.HasPrefix(r.URL.Path, "/output/"):
+			w.Header().Set("Content-Type", "application/fhir+ndjson")
+			_, _ = fmt.Fprintf(w, `{"resourceType":"Observation","id":"%s"}`+"\n", strings.Split(r.URL.Path, "/")[2])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := models.ProjectConfig{
+		Services: models.ServiceConfig{
+			TORCH: models.TORCHConfig{
+				BaseURL:                   server.URL,
+				ExtractionTimeoutMinutes:  1,
+				PollingIntervalSeconds:    1,
+				MaxPollingIntervalSeconds: 5,
+				SplitPeriod:               models.SplitPeriodQuarter,
+				SplitConcurrency:          2,
+			},
+		},
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepTorchImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		JobsDir:  jobsDir,
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	job, err := pipeline.CreateJob(crtdlPath, config, logger)
+	require.NoError(t, err)
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, services.NewHTTPClient(2*time.Second, config.Retry, logger), lib.NoProgress)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"2023-02-15/2023-03-31", "2023-04-01/2023-06-30", "2023-07-01/2023-08-31"}, windows)
+	assert.Equal(t, 3, updatedJob.TotalFiles)
+	assert.Empty(t, updatedJob.TORCHExtractionURL, "split extractions cannot be resumed by URL")
+
+	importDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepTorchImport)
+	for _, name := range []string{"Observation.2023-Q1.ndjson", "Observation.2023-Q2.ndjson", "Observation.2023-Q3.ndjson"} {
+		assert.FileExists(t, filepath.Join(importDir, name))
+	}
+	entries, err := os.ReadDir(importDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "staging directories should be removed")
+}
+
+// TestPipeline_TORCHExtraction_CohortSizeCheck verifies that empty or too large cohorts are
+// refused before submission unless the operator confirms them
+func TestPipeline_TORCHExtraction_CohortSizeCheck(t *testing.T) {
+	tempDir := t.TempDir()
+	crtdlPath := filepath.Join(tempDir, "cohort.crtdl")
+	require.NoError(t, os.WriteFile(crtdlPath, []byte(`{"cohortDefinition":{"version":"1.0.0","inclusionCriteria":[[{"termCodes":[{"code":"424144002","system":"http://snomed.info/sct"}]}]]},"dataExtraction":{"attributeGroups":[]}}`), 0644))
+
+	cohortSize := "0"
+	submissions := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/query/execute":
+			_, _ = w.Write([]byte(cohortSize))
+		case "/fhir/$extract-data":
+			submissions++
+			w.Header().Set("Content-Location", server.URL+"/fhir/extraction/1")
+			w.WriteHeader(http.StatusAccepted)
+		case "/fhir/extraction/1":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"resourceType": "Parameters",
+				"parameter": []map[string]any{{
+					"name": "output",
+					"part": []map[string]any{{"name": "url", "valueUrl": server.URL + "/output/Patient.ndjson"}},
+				}},
+			})
+		case "/output/Patient.ndjson":
+			w.Header().Set("Content-Type", "application/fhir+ndjson")
+			_, _ = w.Write([]byte(`{"resourceType":"Patient","id":"p1"}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := models.ProjectConfig{
+		Services: models.ServiceConfig{
+			TORCH: models.TORCHConfig{
+				BaseURL:                   server.URL,
+				ExtractionTimeoutMinutes:  1,
+				PollingIntervalSeconds:    1,
+				MaxPollingIntervalSeconds: 5,
+				FeasibilityURL:            server.URL + "/query/execute",
+				MaxCohortSize:             100,
+				CohortSizeAction:          models.CohortSizeActionPrompt,
+			},
+		},
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepTorchImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		JobsDir:  filepath.Join(tempDir, "jobs"),
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+	runJob := func() (*models.PipelineJob, error) {
+		job, err := pipeline.CreateJob(crtdlPath, config, logger)
+		require.NoError(t, err)
+		return pipeline.ExecuteImportStep(context.Background(), job, logger, services.NewHTTPClient(2*time.Second, config.Retry, logger), lib.NoProgress)
+	}
+
+	// Empty cohort without an operator to ask: abort
+	job, err := runJob()
+	var sizeErr *pipeline.CohortSizeError
+	require.ErrorAs(t, err, &sizeErr)
+	assert.Equal(t, 0, sizeErr.Count)
+	assert.Equal(t, models.ErrorTypeNonTransient, job.Steps[0].LastError.Type)
+	assert.Equal(t, 0, submissions)
+
+	// Too large cohort, declined at the prompt
+	cohortSize = "250"
+	var questions []string
+	pipeline.SetCohortSizePrompt(func(question string) bool {
+		questions = append(questions, question)
+		return false
+	})
+	t.Cleanup(func() { pipeline.SetCohortSizePrompt(nil) })
+	_, err = runJob()
+	require.ErrorAs(t, err, &sizeErr)
+	assert.Equal(t, 250, sizeErr.Count)
+	require.Len(t, questions, 1)
+	assert.Contains(t, questions[0], "max_cohort_size (100)")
+	assert.Equal(t, 0, submissions)
+
+	// Confirmed at the prompt: extract and keep the size
+	pipeline.SetCohortSizePrompt(func(string) bool { return true })
+	job, err = runJob()
+	require.NoError(t, err)
+	assert.Equal(t, 1, submissions)
+	require.NotNil(t, job.CohortSize)
+	assert.Equal(t, 250, *job.CohortSize)
+
+	// Within bounds: no question asked
+	cohortSize = "42"
+	pipeline.SetCohortSizePrompt(func(string) bool {
+		t.Error("cohort within bounds should not prompt")
+		return false
+	})
+	job, err = runJob()
+	require.NoError(t, err)
+	assert.Equal(t, 42, *job.CohortSize)
+	assert.Equal(t, 2, submissions)
+}
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -47,7 +48,7 @@ func TestRetryTracking_IncrementRetryCount(t *testing.T) {
 	require.NoError(t, err)
 
 	// Complete import step
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, nil, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, nil, lib.NoProgress)
 	require.NoError(t, err)
 
 	// Advance to DIMP step
@@ -348,7 +349,7 @@ func TestRetryTracking_ResetOnSuccess(t *testing.T) {
 	startedJob := pipeline.StartJob(job)
 
 	// Execute import successfully
-	importedJob, err := pipeline.ExecuteImportStep(startedJob, logger, nil, false)
+	importedJob, err := pipeline.ExecuteImportStep(context.Background(), startedJob, logger, nil, lib.NoProgress)
 	require.NoError(t, err)
 
 	// Verify: Successful step has no retries
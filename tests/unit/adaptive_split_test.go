@@ -0,0 +1,113 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// payloadLimitedDIMPServer echoes requests up to maxBytes and answers 413 to larger ones
+// Returns the server and the sizes of the requests it accepted
+func payloadLimitedDIMPServer(maxBytes int) (*httptest.Server, func() []int) {
+	var mu sync.Mutex
+	var accepted []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if len(body) > maxBytes {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		mu.Lock()
+		accepted = append(accepted, len(body))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/fhir+json")
+		_, _ = w.Write(body)
+	}))
+	return server, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), accepted...)
+	}
+}
+
+// runLargeBundleDIMPJob pseudonymizes one 1.5 MB Bundle with a 1 MB split threshold against a
+// DIMP accepting at most 400 KB per request
+func runLargeBundleDIMPJob(t *testing.T, adaptive bool) (*models.PipelineJob, string, []int, error) {
+	server, accepted := payloadLimitedDIMPServer(400 * 1024)
+	t.Cleanup(server.Close)
+
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = t.TempDir()
+	job.Config.Services.DIMP.BundleSplitThresholdMB = 1
+	job.Config.Services.DIMP.AdaptiveSplit = adaptive
+	jobDir := services.GetJobDir(job.Config.JobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	entries := make([]any, 150)
+	for i := range entries {
+		entries[i] = map[string]any{"resource": map[string]any{
+			"resourceType": "Observation",
+			"id":           fmt.Sprintf("o%d", i),
+			"note":         []any{map[string]any{"text": strings.Repeat("x", 10*1024)}},
+		}}
+	}
+	writeDIMPNDJSON(t, filepath.Join(importDir, "bundles.ndjson"), []map[string]any{
+		{"resourceType": "Bundle", "id": "b1", "type": "collection", "entry": entries},
+	})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress)
+	return job, jobDir, accepted(), err
+}
+
+func TestAdaptiveSplit_ShrinksChunksAfter413(t *testing.T) {
+	job, jobDir, accepted, err := runLargeBundleDIMPJob(t, true)
+	require.NoError(t, err)
+
+	output := readDIMPNDJSON(t, filepath.Join(jobDir, "pseudonymized", "dimped_bundles.ndjson"))
+	require.Len(t, output, 1)
+	entries, _ := output[0]["entry"].([]any)
+	assert.Len(t, entries, 150, "all entries are reassembled")
+	for _, size := range accepted {
+		assert.LessOrEqual(t, size, 400*1024)
+	}
+
+	events, err := services.LoadJobEvents(job.Config.JobsDir, job.JobID)
+	require.NoError(t, err)
+	var adjusted []models.JobEvent
+	for _, event := range events {
+		if event.Type == models.EventSplitAdjusted {
+			adjusted = append(adjusted, event)
+		}
+	}
+	require.NotEmpty(t, adjusted)
+	assert.Equal(t, "HTTP 413", adjusted[0].Fields["reason"])
+	assert.Less(t, adjusted[0].Fields["threshold_bytes"], adjusted[0].Fields["previous_bytes"])
+}
+
+func TestAdaptiveSplit_DisabledKeepsConfiguredThreshold(t *testing.T) {
+	_, _, _, err := runLargeBundleDIMPJob(t, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "413")
+}
+
+func TestDIMPConfig_GetSplitTarget(t *testing.T) {
+	assert.Equal(t, 10*time.Second, models.DIMPConfig{}.GetSplitTarget())
+	assert.Equal(t, 3*time.Second, models.DIMPConfig{SplitTargetSeconds: 3}.GetSplitTarget())
+}
@@ -0,0 +1,229 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var attachmentTestPDF = []byte("%PDF-1.4 scanned referral letter")
+
+// runImportWithAttachments imports the given resources from a local directory with an attachment policy
+// and returns the imported resources and the job directory
+func runImportWithAttachments(t *testing.T, fileName string, resources []map[string]any, policy models.AttachmentConfig) ([]map[string]any, string) {
+	t.Helper()
+	return runLocalImportStep(t, fileName, resources, func(config *models.ProjectConfig) {
+		config.Attachments = policy
+	})
+}
+
+// runLocalImportStep runs the import step on the given resources with a customized config
+// and returns the imported resources and the job directory
+func runLocalImportStep(t *testing.T, fileName string, resources []map[string]any, configure func(config *models.ProjectConfig)) ([]map[string]any, string) {
+	t.Helper()
+
+	sourceDir := t.TempDir()
+	writeDIMPNDJSON(t, filepath.Join(sourceDir, fileName), resources)
+
+	jobsDir := t.TempDir()
+	logger := lib.NewLogger(lib.LogLevelError)
+	job := &models.PipelineJob{
+		JobID:       "test-import-policy-job",
+		InputSource: sourceDir,
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepLocalImport),
+		Status:      models.JobStatusInProgress,
+		Steps:       models.InitializeSteps([]models.StepName{models.StepLocalImport}),
+		Config: models.ProjectConfig{
+			JobsDir:  jobsDir,
+			Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+			Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		},
+	}
+	configure(&job.Config)
+
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, nil, lib.NoProgress)
+	require.NoError(t, err)
+
+	importDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepLocalImport)
+	file, err := os.Open(filepath.Join(importDir, fileName))
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	var imported []map[string]any
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var resource map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &resource))
+		imported = append(imported, resource)
+	}
+	require.NoError(t, scanner.Err())
+
+	info, err := os.Stat(filepath.Join(importDir, fileName))
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), updatedJob.TotalBytes, "total bytes should reflect the rewritten file")
+
+	return imported, services.GetJobDir(jobsDir, job.JobID)
+}
+
+func documentReferenceWithPDF(id string, content []byte) map[string]any {
+	return map[string]any{
+		"resourceType": "DocumentReference",
+		"id":           id,
+		"content": []any{
+			map[string]any{"attachment": map[string]any{
+				"contentType": "application/pdf",
+				"title":       "Referral",
+				"data":        base64.StdEncoding.EncodeToString(content),
+			}},
+		},
+	}
+}
+
+func firstAttachment(t *testing.T, resource map[string]any) map[string]any {
+	t.Helper()
+	content := resource["content"].([]any)
+	return content[0].(map[string]any)["attachment"].(map[string]any)
+}
+
+// TestAttachmentPolicy_Keep verifies the default policy leaves data inline
+func TestAttachmentPolicy_Keep(t *testing.T) {
+	imported, jobDir := runImportWithAttachments(t, "DocumentReference.ndjson",
+		[]map[string]any{documentReferenceWithPDF("d1", attachmentTestPDF)},
+		models.AttachmentConfig{})
+
+	attachment := firstAttachment(t, imported[0])
+	assert.Equal(t, base64.StdEncoding.EncodeToString(attachmentTestPDF), attachment["data"])
+	assert.NoDirExists(t, filepath.Join(jobDir, pipeline.AttachmentsDirName))
+}
+
+// TestAttachmentPolicy_Strip verifies data is removed while metadata is kept and size is recorded
+func TestAttachmentPolicy_Strip(t *testing.T) {
+	imported, jobDir := runImportWithAttachments(t, "DocumentReference.ndjson",
+		[]map[string]any{
+			documentReferenceWithPDF("d1", attachmentTestPDF),
+			{"resourceType": "Binary", "id": "b1", "contentType": "image/png", "data": "iVBORw0KGgo="},
+			{"resourceType": "Patient", "id": "p1"},
+		},
+		models.AttachmentConfig{Mode: models.AttachmentStrip})
+
+	require.Len(t, imported, 3)
+	attachment := firstAttachment(t, imported[0])
+	assert.NotContains(t, attachment, "data")
+	assert.Equal(t, "application/pdf", attachment["contentType"])
+	assert.Equal(t, "Referral", attachment["title"])
+	assert.EqualValues(t, len(attachmentTestPDF), attachment["size"])
+
+	assert.NotContains(t, imported[1], "data")
+	assert.Equal(t, "image/png", imported[1]["contentType"])
+	assert.Equal(t, map[string]any{"resourceType": "Patient", "id": "p1"}, imported[2])
+	assert.NoDirExists(t, filepath.Join(jobDir, pipeline.AttachmentsDirName))
+}
+
+// TestAttachmentPolicy_Externalize verifies data is written to content-addressed files and references are rewritten
+func TestAttachmentPolicy_Externalize(t *testing.T) {
+	binaryContent := []byte{0x89, 'P', 'N', 'G'}
+	imported, jobDir := runImportWithAttachments(t, "DocumentReference.ndjson",
+		[]map[string]any{
+			documentReferenceWithPDF("d1", attachmentTestPDF),
+			documentReferenceWithPDF("d2", attachmentTestPDF),
+			{"resourceType": "Binary", "id": "b1", "contentType": "image/png", "data": base64.StdEncoding.EncodeToString(binaryContent)},
+		},
+		models.AttachmentConfig{Mode: models.AttachmentExternalize})
+
+	pdfSum := sha256.Sum256(attachmentTestPDF)
+	pdfPath := "attachments/" + hex.EncodeToString(pdfSum[:]) + ".pdf"
+
+	for _, resource := range imported[:2] {
+		attachment := firstAttachment(t, resource)
+		assert.NotContains(t, attachment, "data")
+		assert.Equal(t, pdfPath, attachment["url"])
+		assert.EqualValues(t, len(attachmentTestPDF), attachment["size"])
+		assert.NotEmpty(t, attachment["hash"])
+	}
+
+	written, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(pdfPath)))
+	require.NoError(t, err)
+	assert.Equal(t, attachmentTestPDF, written)
+
+	binary := imported[2]
+	assert.NotContains(t, binary, "data")
+	extensions := binary["extension"].([]any)
+	require.Len(t, extensions, 1)
+	extension := extensions[0].(map[string]any)
+	assert.Equal(t, pipeline.ExternalizedBinaryExtensionURL, extension["url"])
+	binaryPath := extension["valueUrl"].(string)
+	assert.True(t, strings.HasSuffix(binaryPath, ".png"), "Binary file should use the content type's extension: %s", binaryPath)
+	written, err = os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(binaryPath)))
+	require.NoError(t, err)
+	assert.Equal(t, binaryContent, written)
+
+	entries, err := os.ReadDir(filepath.Join(jobDir, pipeline.AttachmentsDirName))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "identical attachments should be stored once")
+}
+
+// TestAttachmentPolicy_MaxSizeStripsLargeData verifies max_size_kb strips oversized data even in keep mode
+func TestAttachmentPolicy_MaxSizeStripsLargeData(t *testing.T) {
+	large := []byte(strings.Repeat("x", 2048))
+	imported, _ := runImportWithAttachments(t, "DocumentReference.ndjson",
+		[]map[string]any{
+			documentReferenceWithPDF("small", attachmentTestPDF),
+			documentReferenceWithPDF("large", large),
+		},
+		models.AttachmentConfig{Mode: models.AttachmentKeep, MaxSizeKB: 1})
+
+	assert.Contains(t, firstAttachment(t, imported[0]), "data")
+	largeAttachment := firstAttachment(t, imported[1])
+	assert.NotContains(t, largeAttachment, "data")
+	assert.EqualValues(t, len(large), largeAttachment["size"])
+}
+
+// TestAttachmentConfig_Validate verifies mode and size limit validation
+func TestAttachmentConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.AttachmentConfig{}.Validate())
+	assert.NoError(t, models.AttachmentConfig{Mode: models.AttachmentExternalize, MaxSizeKB: 512}.Validate())
+	assert.Error(t, models.AttachmentConfig{Mode: "delete"}.Validate())
+	assert.Error(t, models.AttachmentConfig{MaxSizeKB: -1}.Validate())
+
+	assert.False(t, models.AttachmentConfig{Mode: models.AttachmentKeep}.IsActive())
+	assert.True(t, models.AttachmentConfig{Mode: models.AttachmentKeep, MaxSizeKB: 1}.IsActive())
+	assert.True(t, models.AttachmentConfig{Mode: models.AttachmentStrip}.IsActive())
+}
+
+// TestConfigLoading_Attachments verifies the attachments section is loaded from YAML
+func TestConfigLoading_Attachments(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+attachments:
+  mode: externalize
+  max_size_kb: 2048
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, models.AttachmentExternalize, config.Attachments.Mode)
+	assert.Equal(t, 2048, config.Attachments.MaxSizeKB)
+}
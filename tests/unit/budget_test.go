@@ -0,0 +1,146 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// budgetTestJob returns a job whose import step completed after the given duration
+func budgetTestJob(budget models.TimeBudgetConfig, importDuration time.Duration) *models.PipelineJob {
+	completedAt := time.Now()
+	startedAt := completedAt.Add(-importDuration)
+	return &models.PipelineJob{
+		JobID:       "budget-job",
+		CurrentStep: string(models.StepDIMP),
+		Status:      models.JobStatusInProgress,
+		Steps: []models.PipelineStep{
+			{Name: models.StepLocalImport, Status: models.StepStatusCompleted, StartedAt: &startedAt, CompletedAt: &completedAt},
+			{Name: models.StepDIMP, Status: models.StepStatusPending},
+			{Name: models.StepImaging, Status: models.StepStatusPending},
+		},
+		Config: models.ProjectConfig{Pipeline: models.PipelineConfig{
+			EnabledSteps: []models.StepName{models.StepLocalImport, models.StepDIMP, models.StepImaging},
+			TimeBudget:   budget,
+		}},
+	}
+}
+
+// TestStepTimeShare_Apportioning verifies the remaining budget is split by weight across unfinished steps
+func TestStepTimeShare_Apportioning(t *testing.T) {
+	budget := models.TimeBudgetConfig{TotalMinutes: 120, Weights: map[models.StepName]int{models.StepDIMP: 2}}
+	job := budgetTestJob(budget, 30*time.Minute)
+
+	share, remaining, ok := pipeline.StepTimeShare(job, models.StepDIMP)
+	require.True(t, ok)
+	assert.Equal(t, 90*time.Minute, remaining)
+	assert.Equal(t, 60*time.Minute, share, "dimp has weight 2 of 3")
+
+	share, _, _ = pipeline.StepTimeShare(job, models.StepImaging)
+	assert.Equal(t, 30*time.Minute, share)
+
+	// A failed attempt consumes budget too; the last step gets everything that is left
+	startedAt := time.Now().Add(-40 * time.Minute)
+	job.Steps[1] = models.PipelineStep{Name: models.StepDIMP, Status: models.StepStatusCompleted, StartedAt: &startedAt, CompletedAt: ptrTime(startedAt.Add(20 * time.Minute))}
+	job.Steps[2] = models.PipelineStep{Name: models.StepImaging, Status: models.StepStatusFailed, StartedAt: &startedAt,
+		LastError: &models.StepError{Type: models.ErrorTypeTransient, Timestamp: startedAt.Add(10 * time.Minute)}}
+	share, remaining, _ = pipeline.StepTimeShare(job, models.StepImaging)
+	assert.Equal(t, 60*time.Minute, remaining)
+	assert.Equal(t, 60*time.Minute, share)
+
+	// Used up budget leaves nothing
+	job = budgetTestJob(models.TimeBudgetConfig{TotalMinutes: 10}, 15*time.Minute)
+	share, _, ok = pipeline.StepTimeShare(job, models.StepDIMP)
+	assert.True(t, ok)
+	assert.Zero(t, share)
+
+	// No budget configured
+	_, _, ok = pipeline.StepTimeShare(budgetTestJob(models.TimeBudgetConfig{}, time.Minute), models.StepDIMP)
+	assert.False(t, ok)
+}
+
+// TestExecuteDIMPStep_TimeBudgetDeadline verifies a step is stopped at its share and fails non-transiently
+func TestExecuteDIMPStep_TimeBudgetDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // DIMP hangs until the test ends
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// One minute budget, of which the import used all but half a second
+	job := budgetTestJob(models.TimeBudgetConfig{TotalMinutes: 1}, time.Minute-500*time.Millisecond)
+	job.Steps = job.Steps[:2]
+	job.Config.Pipeline.EnabledSteps = []models.StepName{models.StepLocalImport, models.StepDIMP}
+	job.Config.Services.DIMP = models.DIMPConfig{URL: server.URL, BundleSplitThresholdMB: 10}
+
+	jobDir := t.TempDir()
+	job.Config.JobsDir = filepath.Dir(jobDir)
+	require.NoError(t, os.MkdirAll(filepath.Join(jobDir, "import"), 0755))
+	writeDIMPNDJSON(t, filepath.Join(jobDir, "import", "Patient.ndjson"), []map[string]any{{"resourceType": "Patient", "id": "p1"}})
+
+	start := time.Now()
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, pipeline.ErrTimeBudgetExceeded), "unexpected error: %v", err)
+	assert.Less(t, time.Since(start), 3*time.Second, "the request should be cancelled at the deadline")
+
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	require.NotNil(t, step.LastError)
+	assert.Equal(t, models.ErrorTypeNonTransient, step.LastError.Type)
+}
+
+// TestConfigLoading_TimeBudget verifies pipeline.time_budget is loaded and validated
+func TestConfigLoading_TimeBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - torch
+    - dimp
+  time_budget:
+    total_minutes: 480
+    weights:
+      torch: 3
+      dimp: 2
+
+services:
+  torch:
+    base_url: "http://localhost:8080"
+  dimp:
+    url: "http://localhost:32861/fhir"
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, 8*time.Hour, config.Pipeline.TimeBudget.Total())
+	assert.Equal(t, 3, config.Pipeline.TimeBudget.Weight(models.StepTorchImport))
+	assert.Equal(t, 1, config.Pipeline.TimeBudget.Weight(models.StepImaging))
+
+	assert.Error(t, models.TimeBudgetConfig{TotalMinutes: -1}.Validate())
+	assert.Error(t, models.TimeBudgetConfig{Weights: map[models.StepName]int{"unknown": 1}}.Validate())
+	assert.Error(t, models.TimeBudgetConfig{Weights: map[models.StepName]int{models.StepDIMP: 0}}.Validate())
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,222 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// streamTestBundle returns a Bundle of count Observations with a note of noteBytes each
+func streamTestBundle(bundleType string, count, noteBytes int) map[string]any {
+	entries := make([]any, count)
+	for i := range entries {
+		entries[i] = map[string]any{"resource": map[string]any{
+			"resourceType": "Observation",
+			"id":           fmt.Sprintf("o%d", i),
+			"note":         []any{map[string]any{"text": strings.Repeat("x", noteBytes)}},
+		}}
+	}
+	return map[string]any{
+		"resourceType": "Bundle",
+		"id":           "b1",
+		"type":         bundleType,
+		"timestamp":    "2024-05-01T10:00:00Z",
+		"entry":        entries,
+	}
+}
+
+// splitStream splits data with SplitBundleStream and returns the emitted chunks
+func splitStream(t *testing.T, data []byte, threshold int, allowOversized bool) ([]models.BundleChunk, services.BundleStreamResult, error) {
+	t.Helper()
+	var chunks []models.BundleChunk
+	result, err := services.SplitBundleStream(bytes.NewReader(data), threshold, allowOversized, func(chunk models.BundleChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	return chunks, result, err
+}
+
+func TestSplitBundleStream_MatchesSplitBundle(t *testing.T) {
+	bundle := streamTestBundle("collection", 50, 1000)
+	data, err := json.Marshal(bundle)
+	require.NoError(t, err)
+
+	expected, err := services.SplitBundle(bundle, 10*1024)
+	require.NoError(t, err)
+	chunks, result, err := splitStream(t, data, 10*1024, false)
+	require.NoError(t, err)
+
+	require.Len(t, chunks, expected.TotalChunks)
+	assert.Equal(t, expected.TotalChunks, result.Chunks)
+	assert.Equal(t, 50, result.Entries)
+	assert.Equal(t, expected.Metadata, result.Metadata)
+	for i, chunk := range chunks {
+		assert.Equal(t, expected.Chunks[i].ChunkID, chunk.ChunkID)
+		assert.Equal(t, expected.Chunks[i].Entries, chunk.Entries)
+		assert.Equal(t, models.ConvertChunkToBundle(expected.Chunks[i]), models.ConvertChunkToBundle(chunk))
+		assert.LessOrEqual(t, chunk.EstimatedSize, 10*1024)
+	}
+}
+
+func TestSplitBundleStream_EntriesBeforeHeader(t *testing.T) {
+	entries := `[{"resource":{"resourceType":"Patient","id":"p1"}},{"resource":{"resourceType":"Patient","id":"p2"}}]`
+	data := []byte(`{"entry":` + entries + `,"type":"collection","id":"b1","resourceType":"Bundle"}`)
+
+	chunks, result, err := splitStream(t, data, 1024, false)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Len(t, chunks[0].Entries, 2)
+	assert.Equal(t, "b1-chunk-0", chunks[0].ChunkID)
+	assert.Equal(t, "collection", result.Metadata.Type)
+}
+
+func TestSplitBundleStream_NotABundle(t *testing.T) {
+	_, _, err := splitStream(t, []byte(`{"resourceType":"Patient","id":"p1"}`), 1024, false)
+	assert.ErrorIs(t, err, services.ErrNotBundle)
+
+	// Nothing is emitted for a non-Bundle, even if its entries come first
+	chunks, _, err := splitStream(t, []byte(`{"entry":[{"resource":{}}],"id":"x","type":"collection"}`), 1024, false)
+	assert.ErrorIs(t, err, services.ErrNotBundle)
+	assert.Empty(t, chunks)
+}
+
+func TestSplitBundleStream_InvalidBundles(t *testing.T) {
+	for name, data := range map[string]string{
+		"no id":         `{"resourceType":"Bundle","type":"collection","entry":[{"resource":{}}]}`,
+		"no entries":    `{"resourceType":"Bundle","id":"b1","type":"collection"}`,
+		"empty entries": `{"resourceType":"Bundle","id":"b1","type":"collection","entry":[]}`,
+		"entry object":  `{"resourceType":"Bundle","id":"b1","type":"collection","entry":{}}`,
+		"entry string":  `{"resourceType":"Bundle","id":"b1","type":"collection","entry":["x"]}`,
+		"truncated":     `{"resourceType":"Bundle","id":"b1","type":"collection","entry":[{"resource":`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := splitStream(t, []byte(data), 1024, false)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSplitBundleStream_OversizedEntry(t *testing.T) {
+	data, err := json.Marshal(streamTestBundle("collection", 3, 2000))
+	require.NoError(t, err)
+
+	_, _, err = splitStream(t, data, 1024, false)
+	var oversized *models.OversizedResourceError
+	require.ErrorAs(t, err, &oversized)
+	assert.Equal(t, "Observation", oversized.ResourceType)
+	assert.Equal(t, "o0", oversized.ResourceID)
+
+	// With allowOversized each entry is sent on its own
+	chunks, _, err := splitStream(t, data, 1024, true)
+	require.NoError(t, err)
+	assert.Len(t, chunks, 3)
+}
+
+func TestBundleStreamWriter_MatchesReassembleBundle(t *testing.T) {
+	for _, bundleType := range []string{"collection", "searchset"} {
+		t.Run(bundleType, func(t *testing.T) {
+			bundle := streamTestBundle(bundleType, 20, 500)
+			split, err := services.SplitBundle(bundle, 4*1024)
+			require.NoError(t, err)
+			require.Greater(t, split.TotalChunks, 1)
+
+			var pseudonymized []map[string]any
+			var out bytes.Buffer
+			writer := services.NewBundleStreamWriter(&out)
+			for _, chunk := range split.Chunks {
+				chunkBundle := models.ConvertChunkToBundle(chunk)
+				chunkBundle["meta"] = map[string]any{"security": []any{map[string]any{"code": "PSEUDED"}}}
+				pseudonymized = append(pseudonymized, chunkBundle)
+				require.NoError(t, writer.WriteChunk(chunkBundle))
+			}
+			written, err := writer.Finish(split.Metadata)
+			require.NoError(t, err)
+			assert.Equal(t, 20, written)
+
+			expected, err := services.ReassembleBundle(split.Metadata, pseudonymized)
+			require.NoError(t, err)
+			expectedJSON, err := json.Marshal(expected.Bundle)
+			require.NoError(t, err)
+
+			var want, got map[string]any
+			require.NoError(t, json.Unmarshal(expectedJSON, &want))
+			require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestBundleStreamWriter_RejectsInvalidChunks(t *testing.T) {
+	writer := services.NewBundleStreamWriter(&bytes.Buffer{})
+	assert.Error(t, writer.WriteChunk(map[string]any{"resourceType": "Patient"}))
+	_, err := writer.Finish(models.BundleMetadata{ID: "b1", Type: "collection"})
+	assert.Error(t, err)
+}
+
+func TestExecuteDIMPStep_StreamsLargeBundles(t *testing.T) {
+	server, received := flakyDIMPServer("b1-chunk-1")
+	defer server.Close()
+
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = t.TempDir()
+	job.Config.Services.DIMP.BundleSplitThresholdMB = 1
+	job.Config.Services.DIMP.FileRetryAttempts = 1
+	job.Config.Services.DIMP.FileRetryBackoffSeconds = 1
+	jobDir := services.GetJobDir(job.Config.JobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	bundle := streamTestBundle("collection", 300, 10*1024)
+	writeDIMPNDJSON(t, filepath.Join(importDir, "bundles.ndjson"), []map[string]any{
+		bundle,
+		{"resourceType": "Patient", "id": "p1"},
+	})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress)
+	require.NoError(t, err)
+
+	// The second chunk failed once; the retry must not leave the partial Bundle in the output
+	output := readDIMPNDJSON(t, filepath.Join(jobDir, "pseudonymized", "dimped_bundles.ndjson"))
+	require.Len(t, output, 2)
+	entries, ok := output[0]["entry"].([]any)
+	require.True(t, ok)
+	require.Len(t, entries, 300)
+	for i, entry := range entries {
+		resource := entry.(map[string]any)["resource"].(map[string]any)
+		assert.Equal(t, fmt.Sprintf("o%d", i), resource["id"])
+	}
+	assert.Equal(t, "pseudo-b1-chunk-0", output[0]["id"])
+	assert.Equal(t, "collection", output[0]["type"])
+	assert.Equal(t, "pseudo-p1", output[1]["id"])
+	assert.Contains(t, received(), "b1-chunk-2")
+}
+
+func TestExecuteDIMPStep_StreamedBundleEntryLimit(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	job := createDIMPTestJob(server.URL)
+	job.Config.Services.DIMP.BundleSplitThresholdMB = 1
+	job.Config.Limits.MaxBundleEntries = 100
+	tmpDir := t.TempDir()
+	importDir := filepath.Join(tmpDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	writeDIMPNDJSON(t, filepath.Join(importDir, "bundles.ndjson"), []map[string]any{streamTestBundle("collection", 300, 10*1024)})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, createDIMPTestLogger(), lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limits.max_bundle_entries")
+}
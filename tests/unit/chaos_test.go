@@ -0,0 +1,185 @@
+package unit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// enableChaos injects faults for the duration of a test
+func enableChaos(t *testing.T, config models.ChaosConfig) {
+	config.Enabled = true
+	lib.ConfigureChaos(config)
+	t.Cleanup(func() { lib.ConfigureChaos(models.ChaosConfig{}) })
+}
+
+// countingServer answers every request with a JSON body and counts the requests it received
+func countingServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"resourceType":"Patient","id":"p1"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func chaosTestClient(maxAttempts int) *services.HTTPClient {
+	return services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: maxAttempts, InitialBackoffMs: 1, MaxBackoffMs: 2}, lib.NewLogger(lib.LogLevelError))
+}
+
+func TestChaos_InjectedErrorIsRetried(t *testing.T) {
+	server, requests := countingServer(t)
+	enableChaos(t, models.ChaosConfig{ErrorRate: 1})
+
+	resp, err := chaosTestClient(3).Get(server.URL)
+	require.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Contains(t, err.Error(), "HTTP 503")
+	assert.Zero(t, requests.Load(), "injected errors must not reach the service")
+}
+
+func TestChaos_InjectedTimeoutIsRetried(t *testing.T) {
+	server, requests := countingServer(t)
+	enableChaos(t, models.ChaosConfig{TimeoutRate: 1})
+
+	_, err := chaosTestClient(2).Get(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "request failed after 2 attempts")
+	assert.Contains(t, err.Error(), "timeout")
+	assert.True(t, lib.IsNetworkError(err))
+	assert.Zero(t, requests.Load())
+}
+
+func TestChaos_TruncatedBody(t *testing.T) {
+	server, requests := countingServer(t)
+	enableChaos(t, models.ChaosConfig{TruncateRate: 1})
+
+	resp, err := chaosTestClient(1).Get(server.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, string(lib.ChaosTruncate), resp.Header.Get(lib.ChaosHeader))
+
+	body, err := io.ReadAll(resp.Body)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, `{"resourceType":"P`, string(body))
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestChaos_SlowResponse(t *testing.T) {
+	server, _ := countingServer(t)
+	enableChaos(t, models.ChaosConfig{SlowRate: 1, SlowMs: 50})
+
+	start := time.Now()
+	resp, err := chaosTestClient(1).Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestChaos_RecoversWithRetries(t *testing.T) {
+	server, requests := countingServer(t)
+	enableChaos(t, models.ChaosConfig{ErrorRate: 0.3, TimeoutRate: 0.3, Seed: 42})
+
+	client := chaosTestClient(10)
+	for range 20 {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	assert.Equal(t, int32(20), requests.Load(), "every request should reach the service exactly once")
+}
+
+func TestChaos_HostFilterAndDisabled(t *testing.T) {
+	server, requests := countingServer(t)
+	enableChaos(t, models.ChaosConfig{ErrorRate: 1, Hosts: []string{"dimp.example.org"}})
+
+	resp, err := chaosTestClient(1).Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	lib.ConfigureChaos(models.ChaosConfig{ErrorRate: 1})
+	assert.False(t, lib.ChaosEnabled())
+	resp, err = chaosTestClient(1).Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestChaosConfig_AppliesTo(t *testing.T) {
+	config := models.ChaosConfig{Enabled: true, Hosts: []string{"dimp", "torch:8080"}}
+	assert.True(t, config.AppliesTo("dimp:32861"))
+	assert.True(t, config.AppliesTo("torch:8080"))
+	assert.False(t, config.AppliesTo("torch:9090"))
+	assert.False(t, config.AppliesTo("fhir"))
+	assert.False(t, models.ChaosConfig{}.AppliesTo("dimp"))
+}
+
+func TestParseChaosSpec(t *testing.T) {
+	config, err := models.ParseChaosSpec("error_rate=0.1, timeout_rate=0.05,truncate_rate=0.05,slow_rate=0.2,slow_ms=500,seed=42,hosts=dimp|torch")
+	require.NoError(t, err)
+	assert.Equal(t, models.ChaosConfig{
+		Enabled:      true,
+		ErrorRate:    0.1,
+		TimeoutRate:  0.05,
+		TruncateRate: 0.05,
+		SlowRate:     0.2,
+		SlowMs:       500,
+		Seed:         42,
+		Hosts:        []string{"dimp", "torch"},
+	}, config)
+	assert.Equal(t, 500*time.Millisecond, config.GetSlowDelay())
+
+	for _, spec := range []string{"error_rate", "error_rate=x", "loss_rate=0.1", "error_rate=1.5", "error_rate=0.6,timeout_rate=0.6", "slow_ms=-1"} {
+		_, err := models.ParseChaosSpec(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestLoadConfig_Chaos(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "aether.yaml")
+	content := remoteConfigContent(t) + `
+http_client:
+  chaos:
+    enabled: true
+    truncate_rate: 0.1
+    hosts: [dimp.example.org]
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0644))
+
+	viper.Reset()
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.True(t, config.HTTPClient.Chaos.Enabled)
+	assert.Equal(t, 0.1, config.HTTPClient.Chaos.TruncateRate)
+	assert.Equal(t, []string{"dimp.example.org"}, config.HTTPClient.Chaos.Hosts)
+
+	// AETHER_CHAOS replaces the configured settings
+	t.Setenv(services.ChaosEnvVar, "error_rate=0.25,seed=7")
+	viper.Reset()
+	config, err = services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, models.ChaosConfig{Enabled: true, ErrorRate: 0.25, Seed: 7}, config.HTTPClient.Chaos)
+
+	t.Setenv(services.ChaosEnvVar, "error_rate=2")
+	viper.Reset()
+	_, err = services.LoadConfig(configFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), services.ChaosEnvVar)
+}
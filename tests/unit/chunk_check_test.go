@@ -0,0 +1,137 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// createTruncatingDIMPServer drops the last entry of the first truncatedCalls Bundles it receives
+func createTruncatingDIMPServer(truncatedCalls int32, calls *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resource map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&resource)
+		if entries, ok := resource["entry"].([]any); ok && calls.Add(1) <= truncatedCalls && len(entries) > 0 {
+			resource["entry"] = entries[:len(entries)-1]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+}
+
+func testBundle(entries int) map[string]any {
+	entry := make([]any, entries)
+	for i := range entry {
+		entry[i] = map[string]any{"resource": map[string]any{"resourceType": "Observation", "id": string(rune('a' + i))}}
+	}
+	return map[string]any{"resourceType": "Bundle", "id": "bundle-1", "type": "collection", "entry": entry}
+}
+
+func newCheckedResourceProcessor(server *httptest.Server, attempts int, deadLetterDir string) *pipeline.ResourceProcessor {
+	logger := lib.NewLogger(lib.LogLevelDebug)
+	dimpClient := services.NewDIMPClient(server.URL, services.DefaultHTTPClient(), logger)
+	processor := pipeline.NewResourceProcessor(dimpClient, logger, 10*1024*1024, "observations.ndjson")
+	processor.SetEntryCountCheck(attempts, deadLetterDir)
+	return processor
+}
+
+func TestResourceProcessor_EntryCountMismatchIsResent(t *testing.T) {
+	var calls atomic.Int32
+	server := createTruncatingDIMPServer(1, &calls)
+	defer server.Close()
+
+	processor := newCheckedResourceProcessor(server, 3, filepath.Join(t.TempDir(), pipeline.DeadLetterDirName))
+	pseudonymized, err := processor.ProcessBundle(testBundle(3), "bundle-1")
+	require.NoError(t, err)
+	assert.Len(t, pseudonymized["entry"], 3)
+	assert.Equal(t, int32(2), calls.Load(), "the truncated response is discarded and the Bundle sent again")
+}
+
+func TestResourceProcessor_EntryCountMismatchGoesToDeadLetter(t *testing.T) {
+	var calls atomic.Int32
+	server := createTruncatingDIMPServer(100, &calls)
+	defer server.Close()
+
+	deadLetterDir := filepath.Join(t.TempDir(), pipeline.DeadLetterDirName)
+	processor := newCheckedResourceProcessor(server, 2, deadLetterDir)
+	_, err := processor.ProcessBundle(testBundle(3), "bundle-1")
+
+	var mismatch *pipeline.ChunkEntryMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, 3, mismatch.Sent)
+	assert.Equal(t, 2, mismatch.Received)
+	assert.Equal(t, 2, mismatch.Attempts)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Contains(t, err.Error(), "DIMP returned 2 of 3 entries for Bundle")
+
+	require.NotEmpty(t, mismatch.DeadLetterFile)
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(deadLetterDir), mismatch.DeadLetterFile))
+	require.NoError(t, err)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, "observations.ndjson", record["file"])
+	assert.EqualValues(t, 3, record["sent_entries"])
+	assert.EqualValues(t, 2, record["received_entries"])
+	assert.Len(t, record["request"].(map[string]any)["entry"], 3)
+}
+
+func TestResourceProcessor_EntryCountCheckedPerChunk(t *testing.T) {
+	var calls atomic.Int32
+	server := createTruncatingDIMPServer(100, &calls)
+	defer server.Close()
+
+	processor := newCheckedResourceProcessor(server, 1, "")
+	bundle := testBundle(12)
+	result, err := services.SplitBundle(bundle, 500)
+	require.NoError(t, err)
+	require.Greater(t, result.TotalChunks, 1)
+
+	_, err = processor.ProcessBundleChunks(result, "bundle-1")
+	var mismatch *pipeline.ChunkEntryMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, fmt.Sprintf("chunk 1/%d", result.TotalChunks), mismatch.Chunk)
+	assert.Empty(t, mismatch.DeadLetterFile, "nothing is kept without a dead-letter directory")
+}
+
+func TestExecuteDIMPStep_EntryCountMismatchFailsStep(t *testing.T) {
+	var calls atomic.Int32
+	server := createTruncatingDIMPServer(100, &calls)
+	defer server.Close()
+
+	jobsDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = jobsDir
+	job.Config.Retry.MaxAttempts = 2
+	jobDir := services.GetJobDir(jobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	writeDIMPNDJSON(t, filepath.Join(importDir, "bundles.ndjson"), []map[string]any{testBundle(2)})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DIMP returned 1 of 2 entries")
+
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	assert.Equal(t, models.ErrorTypeNonTransient, step.LastError.Type)
+
+	deadLetters, _ := filepath.Glob(filepath.Join(jobDir, pipeline.DeadLetterDirName, "*.json"))
+	assert.Len(t, deadLetters, 1)
+	assert.NoFileExists(t, filepath.Join(jobDir, "pseudonymized", "dimped_bundles.ndjson"))
+}
@@ -801,6 +801,82 @@ func TestTORCHConfig_ValidateEdgeCases(t *testing.T) {
 			wantErr: true,
 			errMsg:  "must use http or https scheme",
 		},
+		{
+			name: "Username without password - invalid",
+			config: models.TORCHConfig{
+				BaseURL:                   "http://localhost:8080",
+				Username:                  "researcher",
+				ExtractionTimeoutMinutes:  30,
+				PollingIntervalSeconds:    5,
+				MaxPollingIntervalSeconds: 30,
+			},
+			wantErr: true,
+			errMsg:  "username and password must be set together",
+		},
+		{
+			name: "Password without username - invalid",
+			config: models.TORCHConfig{
+				BaseURL:                   "http://localhost:8080",
+				Password:                  "secret",
+				ExtractionTimeoutMinutes:  30,
+				PollingIntervalSeconds:    5,
+				MaxPollingIntervalSeconds: 30,
+			},
+			wantErr: true,
+			errMsg:  "username and password must be set together",
+		},
+		{
+			name: "OAuth2 client credentials - valid",
+			config: models.TORCHConfig{
+				BaseURL:                   "http://localhost:8080",
+				AuthMode:                  models.TORCHAuthOAuth2ClientCredentials,
+				TokenURL:                  "https://keycloak.example.com/realms/mii/protocol/openid-connect/token",
+				ClientID:                  "aether",
+				ClientSecret:              "secret",
+				ExtractionTimeoutMinutes:  30,
+				PollingIntervalSeconds:    5,
+				MaxPollingIntervalSeconds: 30,
+			},
+			wantErr: false,
+		},
+		{
+			name: "OAuth2 without client secret - invalid",
+			config: models.TORCHConfig{
+				BaseURL:                   "http://localhost:8080",
+				AuthMode:                  models.TORCHAuthOAuth2ClientCredentials,
+				TokenURL:                  "https://keycloak.example.com/token",
+				ClientID:                  "aether",
+				ExtractionTimeoutMinutes:  30,
+				PollingIntervalSeconds:    5,
+				MaxPollingIntervalSeconds: 30,
+			},
+			wantErr: true,
+			errMsg:  "requires token_url, client_id and client_secret",
+		},
+		{
+			name: "Bearer token mode without token - invalid",
+			config: models.TORCHConfig{
+				BaseURL:                   "http://localhost:8080",
+				AuthMode:                  models.TORCHAuthBearerToken,
+				ExtractionTimeoutMinutes:  30,
+				PollingIntervalSeconds:    5,
+				MaxPollingIntervalSeconds: 30,
+			},
+			wantErr: true,
+			errMsg:  "requires bearer_token",
+		},
+		{
+			name: "Unknown auth mode - invalid",
+			config: models.TORCHConfig{
+				BaseURL:                   "http://localhost:8080",
+				AuthMode:                  "kerberos",
+				ExtractionTimeoutMinutes:  30,
+				PollingIntervalSeconds:    5,
+				MaxPollingIntervalSeconds: 30,
+			},
+			wantErr: true,
+			errMsg:  "auth_mode must be",
+		},
 	}
 
 	for _, tt := range tests {
@@ -985,30 +1061,6 @@ func TestConfigValidation_MissingServiceURLForEnabledStep(t *testing.T) {
 			},
 			errorText: "service URL required for enabled step 'dimp'",
 		},
-		{
-			name: "CSV Conversion enabled without URL",
-			config: models.ProjectConfig{
-				Services: models.ServiceConfig{
-					DIMP: models.DIMPConfig{
-						URL: "http://dimp.example.com",
-					},
-					CSVConversion: models.CSVConversionConfig{URL: ""},
-				},
-				Pipeline: models.PipelineConfig{
-					EnabledSteps: []models.StepName{
-						models.StepLocalImport,
-						models.StepCSVConversion,
-					},
-				},
-				Retry: models.RetryConfig{
-					MaxAttempts:      5,
-					InitialBackoffMs: 1000,
-					MaxBackoffMs:     30000,
-				},
-				JobsDir: "/tmp/jobs",
-			},
-			errorText: "service URL required for enabled step 'csv_conversion'",
-		},
 		{
 			name: "Parquet Conversion enabled without URL",
 			config: models.ProjectConfig{
@@ -1041,6 +1093,47 @@ func TestConfigValidation_MissingServiceURLForEnabledStep(t *testing.T) {
 	}
 }
 
+// TestValidateServiceURLs_CSVConversionWithoutURL verifies CSV conversion falls back to in-process flattening
+func TestValidateServiceURLs_CSVConversionWithoutURL(t *testing.T) {
+	config := models.ProjectConfig{
+		Pipeline: models.PipelineConfig{
+			EnabledSteps: []models.StepName{
+				models.StepLocalImport,
+				models.StepCSVConversion,
+			},
+		},
+		Retry: models.RetryConfig{
+			MaxAttempts:      5,
+			InitialBackoffMs: 1000,
+			MaxBackoffMs:     30000,
+		},
+		JobsDir: "/tmp/jobs",
+	}
+
+	assert.NoError(t, config.Validate())
+}
+
+// TestValidateServiceURLs_LocalParquetWithoutURL verifies packaging mode local needs no Parquet conversion service
+func TestValidateServiceURLs_LocalParquetWithoutURL(t *testing.T) {
+	config := models.ProjectConfig{
+		Pipeline: models.PipelineConfig{
+			EnabledSteps: []models.StepName{
+				models.StepLocalImport,
+				models.StepParquetConversion,
+			},
+			Packaging: models.PackagingConfig{Mode: models.PackagingModeLocal},
+		},
+		Retry: models.RetryConfig{
+			MaxAttempts:      5,
+			InitialBackoffMs: 1000,
+			MaxBackoffMs:     30000,
+		},
+		JobsDir: "/tmp/jobs",
+	}
+
+	assert.NoError(t, config.Validate())
+}
+
 // TestValidateServiceConnectivity_AllServicesAvailable verifies connectivity check with all services available
 func TestValidateServiceConnectivity_AllServicesAvailable(t *testing.T) {
 	// Create mock servers for each service
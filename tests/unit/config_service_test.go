@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/trobanga/aether/internal/models"
 	"github.com/trobanga/aether/internal/services"
 )
 
@@ -105,6 +106,9 @@ func TestLoadConfig_ConfigFileNotFound_UsesDefaults(t *testing.T) {
 	assert.True(t, len(config.Pipeline.EnabledSteps) > 0)
 	assert.Greater(t, config.Services.DIMP.BundleSplitThresholdMB, 0)
 	assert.Greater(t, config.Retry.MaxAttempts, 0)
+	assert.Equal(t, 30, config.Services.TORCH.ExtractionTimeoutMinutes, "TORCH polling must not time out at once (aether download)")
+	assert.Equal(t, 5, config.Services.TORCH.PollingIntervalSeconds)
+	assert.Equal(t, 30, config.Services.TORCH.MaxPollingIntervalSeconds)
 }
 
 // TestLoadConfig_CreateJobsDir tests that jobs directory is created if it doesn't exist
@@ -200,6 +204,77 @@ services:
 	assert.Equal(t, overrideJobsDir, config.JobsDir)
 }
 
+// TestLoadConfig_EnvVarOverrideNestedKeys tests that nested keys are overridden by AETHER_<KEY_WITH_UNDERSCORES>
+func TestLoadConfig_EnvVarOverrideNestedKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	jobsDir := filepath.Join(tmpDir, "jobs")
+	require.NoError(t, os.MkdirAll(jobsDir, 0755))
+
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `jobs_dir: ` + jobsDir + `
+pipeline:
+  enabled_steps:
+    - torch
+services:
+  torch:
+    base_url: "http://torch:8080"
+    username: "file-user"
+    password: "file-secret"
+  dimp:
+    url: "http://localhost:8080"
+retry:
+  max_attempts: 3
+  initial_backoff_ms: 100
+  max_backoff_ms: 1000
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	t.Setenv("AETHER_SERVICES_TORCH_PASSWORD", "env-secret")
+	t.Setenv("AETHER_SERVICES_DIMP_URL", "http://dimp:8083/fhir")
+	t.Setenv("AETHER_SERVICES_TORCH_CLEANUP_AFTER_DOWNLOAD", "true")
+	t.Setenv("AETHER_RETRY_MAX_ATTEMPTS", "7")
+	t.Setenv("AETHER_PIPELINE_ENABLED_STEPS", "torch, dimp")
+	t.Setenv("AETHER_IMPORT_EXCLUDE", "Binary.ndjson  *_old.ndjson")
+	t.Setenv("AETHER_SERVICES_TORCH_USERNAME", "") // empty values do not override
+	viper.Reset()
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, "env-secret", config.Services.TORCH.Password)
+	assert.Equal(t, "file-user", config.Services.TORCH.Username)
+	assert.Equal(t, "http://torch:8080", config.Services.TORCH.BaseURL, "keys without an environment variable come from the file")
+	assert.True(t, config.Services.TORCH.CleanupAfterDownload)
+	assert.Equal(t, "http://dimp:8083/fhir", config.Services.DIMP.URL)
+	assert.Equal(t, 7, config.Retry.MaxAttempts)
+	assert.Equal(t, []models.StepName{models.StepTorchImport, models.StepDIMP}, config.Pipeline.EnabledSteps)
+	assert.Equal(t, []string{"Binary.ndjson", "*_old.ndjson"}, config.Import.Exclude)
+}
+
+// TestLoadConfig_FlagOverridesEnv tests that values set from CLI flags win over environment variables
+func TestLoadConfig_FlagOverridesEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	flagJobsDir := filepath.Join(tmpDir, "flag_jobs")
+	envJobsDir := filepath.Join(tmpDir, "env_jobs")
+	require.NoError(t, os.MkdirAll(flagJobsDir, 0755))
+	require.NoError(t, os.MkdirAll(envJobsDir, 0755))
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("pipeline:\n  enabled_steps:\n    - local_import\n"), 0644))
+
+	t.Setenv("AETHER_JOBS_DIR", envJobsDir)
+	viper.Reset()
+	services.SetConfigValue("jobs_dir", flagJobsDir)
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, flagJobsDir, config.JobsDir)
+}
+
+func TestEnvKey(t *testing.T) {
+	assert.Equal(t, "AETHER_SERVICES_TORCH_PASSWORD", services.EnvKey("services.torch.password"))
+	assert.Equal(t, "AETHER_JOBS_DIR", services.EnvKey("jobs_dir"))
+}
+
 // TestGetConfigFilePath tests getting the loaded config file path
 func TestGetConfigFilePath(t *testing.T) {
 	viper.Reset()
@@ -324,3 +399,65 @@ services:
 	assert.Equal(t, "http://my-dimp:9999", config.Services.DIMP.URL)
 	assert.Equal(t, 50, config.Services.DIMP.BundleSplitThresholdMB)
 }
+
+// TestResolveConfigFile_DiscoveryOrder verifies flag > AETHER_CONFIG > ./aether.yaml > ~/.config/aether/config.yaml
+func TestResolveConfigFile_DiscoveryOrder(t *testing.T) {
+	workDir := t.TempDir()
+	configHome := t.TempDir()
+	t.Chdir(workDir)
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv(services.ConfigEnvVar, "")
+
+	path, source := services.ResolveConfigFile("")
+	assert.Empty(t, path)
+	assert.Equal(t, services.ConfigSourceNone, source)
+
+	userConfig := filepath.Join(configHome, "aether", "config.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(userConfig), 0755))
+	require.NoError(t, os.WriteFile(userConfig, []byte("jobs_dir: ./user-jobs\n"), 0644))
+	path, source = services.ResolveConfigFile("")
+	assert.Equal(t, userConfig, path)
+	assert.Equal(t, services.ConfigSourceSearch, source)
+
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "aether.yaml"), []byte("jobs_dir: ./local-jobs\n"), 0644))
+	path, _ = services.ResolveConfigFile("")
+	assert.Equal(t, "aether.yaml", path)
+
+	t.Setenv(services.ConfigEnvVar, "/etc/site/aether.yaml")
+	path, source = services.ResolveConfigFile("")
+	assert.Equal(t, "/etc/site/aether.yaml", path)
+	assert.Equal(t, services.ConfigSourceEnv, source)
+
+	path, source = services.ResolveConfigFile("custom.yaml")
+	assert.Equal(t, "custom.yaml", path)
+	assert.Equal(t, services.ConfigSourceFlag, source)
+}
+
+// TestLoadConfig_EnvConfigAndJobsDirOverride verifies AETHER_CONFIG is loaded and a jobs_dir override wins over it
+func TestLoadConfig_EnvConfigAndJobsDirOverride(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "site.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("pipeline:\n  enabled_steps: [local_import]\njobs_dir: "+filepath.Join(tmpDir, "site-jobs")+"\n"), 0644))
+	t.Setenv(services.ConfigEnvVar, configFile)
+
+	viper.Reset()
+	config, err := services.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, "site-jobs"), config.JobsDir)
+	assert.Equal(t, configFile, services.GetConfigFilePath())
+
+	// --jobs-dir is applied as a runtime override
+	viper.Reset()
+	services.SetConfigValue("jobs_dir", filepath.Join(tmpDir, "flag-jobs"))
+	config, err = services.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, "flag-jobs"), config.JobsDir)
+	assert.DirExists(t, config.JobsDir)
+
+	// A missing explicit file is an error, not a silent fallback to defaults
+	viper.Reset()
+	t.Setenv(services.ConfigEnvVar, filepath.Join(tmpDir, "missing.yaml"))
+	_, err = services.LoadConfig("")
+	assert.Error(t, err)
+	viper.Reset()
+}
@@ -0,0 +1,145 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func remoteConfigContent(t *testing.T) string {
+	return `
+pipeline:
+  enabled_steps:
+    - local_import
+    - dimp
+services:
+  dimp:
+    url: "http://dimp.example.org/fhir"
+jobs_dir: "` + filepath.Join(t.TempDir(), "jobs") + `"
+`
+}
+
+func serveConfig(content string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/aether.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoadConfig_FromPinnedURL(t *testing.T) {
+	content := remoteConfigContent(t)
+	server := serveConfig(content)
+	defer server.Close()
+
+	viper.Reset()
+	config, err := services.LoadConfig(server.URL + "/aether.yaml#sha256=" + sha256Hex(content))
+	require.NoError(t, err)
+	assert.Equal(t, "http://dimp.example.org/fhir", config.Services.DIMP.URL)
+	assert.Equal(t, []models.StepName{models.StepLocalImport, models.StepDIMP}, config.Pipeline.EnabledSteps)
+}
+
+func TestLoadConfig_URLChecksumMismatch(t *testing.T) {
+	server := serveConfig(remoteConfigContent(t))
+	defer server.Close()
+
+	viper.Reset()
+	_, err := services.LoadConfig(server.URL + "/aether.yaml#sha256=" + sha256Hex("something else"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match its pinned checksum")
+}
+
+func TestLoadConfig_PlainHTTPRequiresPin(t *testing.T) {
+	server := serveConfig(remoteConfigContent(t))
+	defer server.Close()
+
+	viper.Reset()
+	_, err := services.LoadConfig(server.URL + "/aether.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plain HTTP")
+}
+
+// TestLoadConfig_HTTPSRedirectToHTTP verifies an unpinned https URL may not redirect to plain HTTP
+func TestLoadConfig_HTTPSRedirectToHTTP(t *testing.T) {
+	content := remoteConfigContent(t)
+	plain := serveConfig(content)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.RedirectHandler(plain.URL+"/aether.yaml", http.StatusFound))
+	defer secure.Close()
+
+	// The configuration client uses the default transport; make it trust the test certificate
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = secure.Client().Transport
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	viper.Reset()
+	_, err := services.LoadConfig(secure.URL + "/aether.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "redirects to plain HTTP")
+
+	// A pinned URL may be served from anywhere, since its content is verified
+	viper.Reset()
+	config, err := services.LoadConfig(secure.URL + "/aether.yaml#sha256=" + sha256Hex(content))
+	require.NoError(t, err)
+	assert.Equal(t, "http://dimp.example.org/fhir", config.Services.DIMP.URL)
+}
+
+func TestLoadConfig_URLErrors(t *testing.T) {
+	server := serveConfig(remoteConfigContent(t))
+	defer server.Close()
+
+	viper.Reset()
+	_, err := services.LoadConfig(server.URL + "/missing.yaml#sha256=" + sha256Hex(""))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 404")
+
+	_, err = services.LoadConfig(server.URL + "/aether.yaml#md5=abc")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected sha256=<hex>")
+
+	_, err = services.LoadConfig(server.URL + "/aether.yaml#sha256=abc")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "64 hex characters")
+}
+
+func TestLoadConfig_FromStdin(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = writer.WriteString(remoteConfigContent(t))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	stdin := os.Stdin
+	os.Stdin = reader
+	defer func() { os.Stdin = stdin }()
+
+	viper.Reset()
+	config, err := services.LoadConfig(services.ConfigStdin)
+	require.NoError(t, err)
+	assert.Equal(t, "http://dimp.example.org/fhir", config.Services.DIMP.URL)
+}
+
+func TestIsRemoteConfig(t *testing.T) {
+	assert.True(t, services.IsRemoteConfig("https://config.example.org/aether.yaml"))
+	assert.True(t, services.IsRemoteConfig("http://config.example.org/aether.yaml#sha256=00"))
+	assert.False(t, services.IsRemoteConfig("aether.yaml"))
+	assert.False(t, services.IsRemoteConfig(services.ConfigStdin))
+}
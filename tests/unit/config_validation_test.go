@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/trobanga/aether/internal/lib"
 	"github.com/trobanga/aether/internal/models"
 )
@@ -151,6 +152,25 @@ func TestProjectConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "at least one pipeline step must be enabled",
 		},
+		{
+			name: "Invalid DIMP non_fhir_lines mode",
+			config: models.ProjectConfig{
+				Services: models.ServiceConfig{
+					DIMP: models.DIMPConfig{NonFHIRLines: "ignore"},
+				},
+				Pipeline: models.PipelineConfig{
+					EnabledSteps: []models.StepName{models.StepLocalImport},
+				},
+				Retry: models.RetryConfig{
+					MaxAttempts:      3,
+					InitialBackoffMs: 500,
+					MaxBackoffMs:     5000,
+				},
+				JobsDir: "/tmp/jobs",
+			},
+			wantErr: true,
+			errMsg:  "invalid dimp non_fhir_lines mode",
+		},
 		{
 			name: "First step is not an import step - validation step first",
 			config: models.ProjectConfig{
@@ -356,3 +376,76 @@ func TestProjectConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+// TestProjectConfig_Validate_StepOrder verifies that enabled steps must form a coherent pipeline
+func TestProjectConfig_Validate_StepOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		steps  []models.StepName
+		errMsg string
+	}{
+		{
+			name:  "Several import steps before processing - valid",
+			steps: []models.StepName{models.StepTorchImport, models.StepLocalImport, models.StepHttpImport, models.StepDIMP, models.StepImaging, models.StepCSVConversion},
+		},
+		{
+			name:   "Step listed twice",
+			steps:  []models.StepName{models.StepLocalImport, models.StepDIMP, models.StepDIMP},
+			errMsg: "step 'dimp' is listed more than once",
+		},
+		{
+			name:   "Import step after DIMP",
+			steps:  []models.StepName{models.StepLocalImport, models.StepDIMP, models.StepHttpImport},
+			errMsg: "import step 'http_import' must come before 'dimp'",
+		},
+		{
+			name:   "Imaging before DIMP",
+			steps:  []models.StepName{models.StepLocalImport, models.StepImaging, models.StepDIMP},
+			errMsg: "step 'dimp' must come before 'imaging'",
+		},
+		{
+			name:   "Packaging before DIMP",
+			steps:  []models.StepName{models.StepLocalImport, models.StepCSVConversion, models.StepDIMP},
+			errMsg: "step 'dimp' must come before 'csv_conversion'",
+		},
+		{
+			name:   "Packaging before validation",
+			steps:  []models.StepName{models.StepLocalImport, models.StepParquetConversion, models.StepValidation},
+			errMsg: "step 'validation' must come before 'parquet_conversion'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := models.ProjectConfig{
+				Services: models.ServiceConfig{
+					DIMP:              models.DIMPConfig{URL: "http://dimp.example.com"},
+					ParquetConversion: models.ParquetConversionConfig{URL: "http://parquet.example.com"},
+					TORCH: models.TORCHConfig{
+						BaseURL:                   "http://localhost:8080",
+						Username:                  "testuser",
+						Password:                  "testpass",
+						ExtractionTimeoutMinutes:  30,
+						PollingIntervalSeconds:    5,
+						MaxPollingIntervalSeconds: 30,
+					},
+				},
+				Pipeline: models.PipelineConfig{EnabledSteps: tt.steps},
+				Retry: models.RetryConfig{
+					MaxAttempts:      3,
+					InitialBackoffMs: 500,
+					MaxBackoffMs:     5000,
+				},
+				JobsDir: "/tmp/jobs",
+			}
+
+			err := config.Validate()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
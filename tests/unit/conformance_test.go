@@ -0,0 +1,180 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+)
+
+// newConformanceTORCHServer fakes a TORCH server that completes extractions at once and
+// forgets their results on DELETE
+func newConformanceTORCHServer(t *testing.T) (*httptest.Server, *atomic.Bool) {
+	var deleted atomic.Bool
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/fhir/metadata":
+			_, _ = w.Write([]byte(`{"resourceType":"CapabilityStatement","fhirVersion":"4.0.1","software":{"name":"TORCH","version":"1.2.0"}}`))
+		case r.URL.Path == "/fhir/$extract-data":
+			w.Header().Set("Content-Location", server.URL+"/fhir/__status/1")
+			w.WriteHeader(http.StatusAccepted)
+		case strings.HasPrefix(r.URL.Path, "/fhir/__status/") || strings.HasPrefix(r.URL.Path, "/output/"):
+			if r.Method == http.MethodDelete {
+				deleted.Store(true)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if deleted.Load() {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"output":[{"type":"NDJSON","url":"` + server.URL + `/output/1.ndjson"}]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &deleted
+}
+
+// newConformanceDIMPServer fakes a DIMP replacing IDs and identifiers; with consistent set,
+// references are rewritten to the replaced IDs
+func newConformanceDIMPServer(t *testing.T, consistent bool) *httptest.Server {
+	pseudonymize := func(resource map[string]any) {
+		resource["id"] = "pseudo-" + resource["id"].(string)
+		delete(resource, "identifier")
+		if subject, ok := resource["subject"].(map[string]any); ok && consistent {
+			ref := strings.SplitN(subject["reference"].(string), "/", 2)
+			subject["reference"] = ref[0] + "/pseudo-" + ref[1]
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metadata" {
+			w.Header().Set("Server", "dimp-test")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var resource map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&resource))
+		if entries, ok := resource["entry"].([]any); ok {
+			for _, entry := range entries {
+				pseudonymize(entry.(map[string]any)["resource"].(map[string]any))
+			}
+		} else {
+			pseudonymize(resource)
+		}
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func findConformanceCheck(t *testing.T, service pipeline.ConformanceService, name string) pipeline.ConformanceCheck {
+	for _, check := range service.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("check %s not found in %s", name, service.Service)
+	return pipeline.ConformanceCheck{}
+}
+
+func TestConformance_CompatibleServices(t *testing.T) {
+	torchServer, deleted := newConformanceTORCHServer(t)
+	dimpServer := newConformanceDIMPServer(t, true)
+
+	crtdl := filepath.Join(t.TempDir(), "tiny.crtdl")
+	require.NoError(t, os.WriteFile(crtdl, []byte(`{"cohortDefinition":{"inclusionCriteria":[]},"dataExtraction":{"attributeGroups":[]}}`), 0644))
+
+	config := &models.ProjectConfig{
+		Services: models.ServiceConfig{
+			TORCH: models.TORCHConfig{
+				BaseURL: torchServer.URL, Username: "user", Password: "pass",
+				ExtractionTimeoutMinutes: 1, PollingIntervalSeconds: 1, MaxPollingIntervalSeconds: 1,
+			},
+			DIMP: models.DIMPConfig{URL: dimpServer.URL},
+		},
+	}
+
+	httpClient, logger := newPreflightTestClient()
+	report := pipeline.RunConformance(config, httpClient, logger, pipeline.ConformanceOptions{CRTDLPath: crtdl})
+
+	require.Len(t, report.Services, 2)
+	assert.True(t, report.IsCompatible())
+
+	torch := report.Services[0]
+	assert.Equal(t, "torch", torch.Service)
+	assert.Equal(t, "TORCH 1.2.0 (FHIR 4.0.1)", torch.Version.String())
+	assert.Equal(t, pipeline.PreflightPassed, findConformanceCheck(t, torch, "auth").Status)
+	assert.Equal(t, "extraction completed with 1 file(s)", findConformanceCheck(t, torch, "tiny_extraction").Message)
+	assert.Equal(t, pipeline.PreflightPassed, findConformanceCheck(t, torch, "result_cleanup").Status)
+	assert.True(t, deleted.Load(), "the extraction result must be deleted")
+
+	dimp := report.Services[1]
+	assert.Equal(t, "dimp-test", dimp.Version.String())
+	assert.Equal(t, pipeline.PreflightPassed, findConformanceCheck(t, dimp, "pseudonymize_resource").Status)
+	assert.Equal(t, pipeline.PreflightPassed, findConformanceCheck(t, dimp, "pseudonymize_bundle").Status)
+}
+
+func TestConformance_WithoutCRTDLSkipsExtraction(t *testing.T) {
+	torchServer, deleted := newConformanceTORCHServer(t)
+	config := &models.ProjectConfig{
+		Services: models.ServiceConfig{TORCH: models.TORCHConfig{BaseURL: torchServer.URL, Username: "user", Password: "pass"}},
+	}
+
+	httpClient, logger := newPreflightTestClient()
+	report := pipeline.RunConformance(config, httpClient, logger, pipeline.ConformanceOptions{})
+
+	require.Len(t, report.Services, 1)
+	assert.Equal(t, pipeline.PreflightSkipped, findConformanceCheck(t, report.Services[0], "tiny_extraction").Status)
+	assert.Equal(t, pipeline.PreflightSkipped, findConformanceCheck(t, report.Services[0], "result_cleanup").Status)
+	assert.False(t, deleted.Load())
+	assert.True(t, report.IsCompatible())
+}
+
+func TestConformance_InconsistentDIMPReferences(t *testing.T) {
+	dimpServer := newConformanceDIMPServer(t, false)
+	config := &models.ProjectConfig{Services: models.ServiceConfig{DIMP: models.DIMPConfig{URL: dimpServer.URL}}}
+
+	httpClient, logger := newPreflightTestClient()
+	report := pipeline.RunConformance(config, httpClient, logger, pipeline.ConformanceOptions{})
+
+	require.Len(t, report.Services, 1)
+	check := findConformanceCheck(t, report.Services[0], "pseudonymize_bundle")
+	assert.Equal(t, pipeline.PreflightFailed, check.Status)
+	assert.Contains(t, check.Message, "references not rewritten consistently")
+	assert.False(t, report.IsCompatible())
+}
+
+func TestConformance_DIMPReturnsIdentifier(t *testing.T) {
+	echoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var resource map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&resource))
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+	defer echoServer.Close()
+	config := &models.ProjectConfig{Services: models.ServiceConfig{DIMP: models.DIMPConfig{URL: echoServer.URL}}}
+
+	httpClient, logger := newPreflightTestClient()
+	report := pipeline.RunConformance(config, httpClient, logger, pipeline.ConformanceOptions{})
+
+	check := findConformanceCheck(t, report.Services[0], "pseudonymize_resource")
+	assert.Equal(t, pipeline.PreflightFailed, check.Status)
+	assert.Equal(t, "identifier was returned unchanged", check.Message)
+	assert.Equal(t, pipeline.PreflightSkipped, findConformanceCheck(t, report.Services[0], "version").Status)
+}
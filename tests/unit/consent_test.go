@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+)
+
+func consentObservation(id, patient, effective string) map[string]any {
+	observation := map[string]any{
+		"resourceType": "Observation",
+		"id":           id,
+		"subject":      map[string]any{"reference": "Patient/" + patient},
+	}
+	if effective != "" {
+		observation["effectiveDateTime"] = effective
+	}
+	return observation
+}
+
+func resourceIDs(resources []map[string]any) []string {
+	var ids []string
+	for _, resource := range resources {
+		ids = append(ids, resource["id"].(string))
+	}
+	return ids
+}
+
+func loadConsentReport(t *testing.T, jobDir string) pipeline.ConsentReport {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(jobDir, pipeline.ConsentReportFileName))
+	require.NoError(t, err)
+	var report pipeline.ConsentReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	return report
+}
+
+// TestConsentFilter_ConsentResources verifies resources outside the window of an active Consent are
+// removed, and patients without a Consent are dropped by default
+func TestConsentFilter_ConsentResources(t *testing.T) {
+	resources := []map[string]any{
+		{"resourceType": "Patient", "id": "p1", "birthDate": "1970-01-01"},
+		{"resourceType": "Consent", "id": "c1", "status": "active",
+			"patient":   map[string]any{"reference": "Patient/p1"},
+			"provision": map[string]any{"type": "permit", "period": map[string]any{"start": "2020-01-01", "end": "2021-12-31"}}},
+		{"resourceType": "Consent", "id": "c2", "status": "inactive",
+			"patient":   map[string]any{"reference": "Patient/p1"},
+			"provision": map[string]any{"period": map[string]any{"start": "2010-01-01"}}},
+		consentObservation("o-before", "p1", "2019-12-31T23:00:00+01:00"),
+		consentObservation("o-inside", "p1", "2020-06-15"),
+		consentObservation("o-last-day", "p1", "2021-12-31T10:00:00Z"),
+		consentObservation("o-month", "p1", "2021-12"),
+		consentObservation("o-after", "p1", "2022-01-01"),
+		consentObservation("o-undated", "p1", ""),
+		{"resourceType": "Patient", "id": "p2"},
+		consentObservation("o-p2", "p2", "2020-06-15"),
+		{"resourceType": "Organization", "id": "org1"},
+	}
+
+	imported, jobDir := runLocalImportStep(t, "data.ndjson", resources, func(config *models.ProjectConfig) {
+		config.Consent = models.ConsentFilterConfig{Source: models.ConsentFromResources}
+	})
+
+	assert.Equal(t, []string{"p1", "c1", "c2", "o-inside", "o-last-day", "o-month", "o-undated", "org1"}, resourceIDs(imported))
+
+	report := loadConsentReport(t, jobDir)
+	assert.Equal(t, models.ConsentFromResources, report.Source)
+	assert.Equal(t, 1, report.PatientsWithWindow)
+	assert.Equal(t, 4, report.ResourcesRemoved)
+	assert.Equal(t, map[string]int{"p1": 2, "p2": 2}, report.RemovedByPatient)
+	assert.Equal(t, []string{"p2"}, report.PatientsWithoutWindow)
+	assert.Equal(t, 2, report.UndatedKept, "the Patient and the undated Observation")
+}
+
+// TestConsentFilter_CSV verifies windows from a CSV, with several windows per patient,
+// open bounds and patients without a window kept
+func TestConsentFilter_CSV(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "consent.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("patient_id,start,end,note\np1,2018,2018-06,first\np1,2020-01-01,,second\n"), 0644))
+
+	resources := []map[string]any{
+		consentObservation("o-2018", "p1", "2018-06-30"),
+		consentObservation("o-2019", "p1", "2019-03-01"),
+		consentObservation("o-2024", "p1", "2024-01-01"),
+		{"resourceType": "Condition", "id": "c-abs", "subject": map[string]any{"reference": "https://fhir.example.org/Patient/p1/_history/2"}, "recordedDate": "2017-12-31"},
+		consentObservation("o-p2", "p2", "2019-03-01"),
+	}
+
+	imported, jobDir := runLocalImportStep(t, "data.ndjson", resources, func(config *models.ProjectConfig) {
+		config.Consent = models.ConsentFilterConfig{Source: models.ConsentFromCSV, CSVPath: csvPath, Missing: models.ConsentMissingKeep}
+	})
+
+	assert.Equal(t, []string{"o-2018", "o-2024", "o-p2"}, resourceIDs(imported))
+	report := loadConsentReport(t, jobDir)
+	assert.Equal(t, map[string]int{"p1": 2}, report.RemovedByPatient)
+	assert.Equal(t, []string{"p2"}, report.PatientsWithoutWindow)
+}
+
+// TestConsentFilterConfig_Validate verifies source and missing-window policy checks
+func TestConsentFilterConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.ConsentFilterConfig{}.Validate())
+	assert.NoError(t, models.ConsentFilterConfig{Source: models.ConsentFromResources, Missing: models.ConsentMissingKeep}.Validate())
+	assert.ErrorContains(t, models.ConsentFilterConfig{Source: models.ConsentFromCSV}.Validate(), "csv_path")
+	assert.Error(t, models.ConsentFilterConfig{Source: "ldap"}.Validate())
+	assert.Error(t, models.ConsentFilterConfig{Source: models.ConsentFromResources, Missing: "ignore"}.Validate())
+	assert.Equal(t, models.ConsentMissingDrop, models.ConsentFilterConfig{}.GetMissing())
+}
@@ -0,0 +1,102 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const splitTestCRTDL = `{
+  "cohortDefinition": {"version": "1.0.0", "inclusionCriteria": [[{"termCodes": [{"code": "424144002", "system": "http://snomed.info/sct"}]}]]},
+  "dataExtraction": {"attributeGroups": [
+    {"groupReference": "Observation", "filter": [{"type": "date", "name": "date", "start": "2023-02-15", "end": "2023-08-31"}]},
+    {"groupReference": "Condition", "filter": [{"type": "date", "name": "recorded-date", "start": "2023-05-01", "end": "2023-05-31"}]},
+    {"groupReference": "Patient"}
+  ]}
+}`
+
+// chunkGroups returns "<groupReference> <start>/<end>" for every attribute group of a chunk
+func chunkGroups(t *testing.T, chunk services.CRTDLChunk) []string {
+	var crtdl struct {
+		CohortDefinition map[string]any `json:"cohortDefinition"`
+		DataExtraction   struct {
+			AttributeGroups []struct {
+				GroupReference string `json:"groupReference"`
+				Filter         []struct {
+					Start string `json:"start"`
+					End   string `json:"end"`
+				} `json:"filter"`
+			} `json:"attributeGroups"`
+		} `json:"dataExtraction"`
+	}
+	require.NoError(t, json.Unmarshal(chunk.CRTDL, &crtdl))
+	require.NotEmpty(t, crtdl.CohortDefinition, "chunks must keep the cohort definition")
+
+	var groups []string
+	for _, group := range crtdl.DataExtraction.AttributeGroups {
+		if len(group.Filter) == 0 {
+			groups = append(groups, group.GroupReference)
+			continue
+		}
+		groups = append(groups, group.GroupReference+" "+group.Filter[0].Start+"/"+group.Filter[0].End)
+	}
+	return groups
+}
+
+func TestSplitCRTDL_Quarter(t *testing.T) {
+	chunks, err := services.SplitCRTDL([]byte(splitTestCRTDL), models.SplitPeriodQuarter)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	assert.Equal(t, "2023-Q1", chunks[0].Label)
+	assert.Equal(t, []string{"Observation 2023-02-15/2023-03-31", "Patient"}, chunkGroups(t, chunks[0]), "undated groups are extracted with the first period")
+	assert.Equal(t, "2023-Q2", chunks[1].Label)
+	assert.Equal(t, []string{"Observation 2023-04-01/2023-06-30", "Condition 2023-05-01/2023-05-31"}, chunkGroups(t, chunks[1]))
+	assert.Equal(t, "2023-Q3", chunks[2].Label)
+	assert.Equal(t, []string{"Observation 2023-07-01/2023-08-31"}, chunkGroups(t, chunks[2]))
+	assert.Equal(t, "2023-08-31", chunks[2].End.Format("2006-01-02"))
+}
+
+// TestSplitCRTDL_SkipsGaps verifies that periods without any attribute group are not extracted
+func TestSplitCRTDL_SkipsGaps(t *testing.T) {
+	data := `{"cohortDefinition": {}, "dataExtraction": {"attributeGroups": [
+	  {"groupReference": "A", "filter": [{"type": "date", "start": "2023-01-10", "end": "2023-01-20"}]},
+	  {"groupReference": "B", "filter": [{"type": "date", "start": "2023-04-01", "end": "2023-04-30"}]}
+	]}}`
+
+	chunks, err := services.SplitCRTDL([]byte(data), models.SplitPeriodMonth)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "2023-01", chunks[0].Label)
+	assert.Equal(t, "2023-04", chunks[1].Label)
+}
+
+func TestSplitCRTDL_NoSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no date filter", `{"dataExtraction": {"attributeGroups": [{"groupReference": "Patient"}]}}`},
+		{"open-ended date filter", `{"dataExtraction": {"attributeGroups": [{"groupReference": "A", "filter": [{"type": "date", "start": "2020-01-01"}]}]}}`},
+		{"window within one period", `{"dataExtraction": {"attributeGroups": [{"groupReference": "A", "filter": [{"type": "date", "start": "2023-01-01", "end": "2023-12-31"}]}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := services.SplitCRTDL([]byte(tt.data), models.SplitPeriodYear)
+			require.NoError(t, err)
+			assert.Nil(t, chunks)
+		})
+	}
+}
+
+func TestSplitCRTDL_InvalidDate(t *testing.T) {
+	data := `{"dataExtraction": {"attributeGroups": [{"groupReference": "A", "filter": [{"type": "date", "start": "2023-13-01", "end": "2024-01-01"}]}]}}`
+
+	_, err := services.SplitCRTDL([]byte(data), models.SplitPeriodQuarter)
+	assert.ErrorContains(t, err, "invalid date filter start")
+}
@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// createCSVConversionTestJob creates a job whose local import completed, with in-process CSV conversion enabled
+func createCSVConversionTestJob(t *testing.T, flatten models.FlattenConfig) (*models.PipelineJob, string) {
+	jobsDir := t.TempDir()
+	job := &models.PipelineJob{
+		JobID:       "test-csv-job",
+		Status:      models.JobStatusInProgress,
+		CurrentStep: string(models.StepCSVConversion),
+		CreatedAt:   time.Now(),
+		Steps:       []models.PipelineStep{{Name: models.StepLocalImport, Status: models.StepStatusCompleted}},
+		Config: models.ProjectConfig{
+			Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport, models.StepCSVConversion}},
+			Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 10, MaxBackoffMs: 100},
+			Flatten:  flatten,
+			JobsDir:  jobsDir,
+		},
+	}
+
+	jobDir := services.GetJobDir(jobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	writeDIMPNDJSON(t, filepath.Join(importDir, "export.ndjson"), []map[string]any{
+		{"resourceType": "Patient", "id": "p1", "gender": "female", "birthDate": "1970-01-01"},
+		{"resourceType": "Observation", "id": "o1", "subject": map[string]any{"reference": "Patient/p1"},
+			"code":              map[string]any{"coding": []any{map[string]any{"system": "http://loinc.org", "code": "718-7"}}},
+			"effectiveDateTime": "2024-01-01", "valueQuantity": map[string]any{"value": 13.5}},
+		{"resourceType": "Observation", "id": "o2", "subject": map[string]any{"reference": "Patient/p1"},
+			"code":              map[string]any{"coding": []any{map[string]any{"system": "http://loinc.org", "code": "2160-0"}}},
+			"effectiveDateTime": "2024-01-01", "valueQuantity": map[string]any{"value": 0.9}},
+		{"resourceType": "Basic", "id": "b1"},
+	})
+	return job, jobDir
+}
+
+// readCSVTable reads a CSV file written by the conversion step
+func readCSVTable(t *testing.T, path string) [][]string {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+	return records
+}
+
+// TestExecuteCSVConversionStep_WritesTablesAndDictionary verifies one CSV per resource type and the data dictionary
+func TestExecuteCSVConversionStep_WritesTablesAndDictionary(t *testing.T) {
+	job, jobDir := createCSVConversionTestJob(t, models.FlattenConfig{})
+
+	require.NoError(t, pipeline.ExecuteCSVConversionStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError)))
+
+	step, found := models.GetStepByName(*job, models.StepCSVConversion)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusCompleted, step.Status)
+	assert.Equal(t, 1, step.FilesProcessed)
+
+	csvDir := filepath.Join(jobDir, "csv")
+	patients := readCSVTable(t, filepath.Join(csvDir, "Patient.csv"))
+	require.Len(t, patients, 2)
+	assert.Equal(t, "patient_id", patients[0][0])
+	assert.Equal(t, []string{"p1", "female", "1970-01-01"}, patients[1][:3])
+
+	observations := readCSVTable(t, filepath.Join(csvDir, "Observation.csv"))
+	assert.Len(t, observations, 3)
+
+	// Unmapped resource types get no table
+	assert.NoFileExists(t, filepath.Join(csvDir, "Basic.csv"))
+	assert.FileExists(t, filepath.Join(csvDir, services.DataDictionaryFileName))
+	assert.NoFileExists(t, filepath.Join(csvDir, pipeline.WideObservationTable+".csv"))
+}
+
+// TestExecuteCSVConversionStep_WideObservations verifies the pivoted Observation table replaces the long one
+func TestExecuteCSVConversionStep_WideObservations(t *testing.T) {
+	job, jobDir := createCSVConversionTestJob(t, models.FlattenConfig{
+		Observations: models.ObservationPivotConfig{
+			Format: models.ObservationFormatWide,
+			Codes:  []models.PivotCode{{Code: "718-7", Column: "hemoglobin"}, {Code: "2160-0", Column: "creatinine"}},
+		},
+	})
+
+	require.NoError(t, pipeline.ExecuteCSVConversionStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError)))
+
+	csvDir := filepath.Join(jobDir, "csv")
+	assert.NoFileExists(t, filepath.Join(csvDir, "Observation.csv"))
+	wide := readCSVTable(t, filepath.Join(csvDir, pipeline.WideObservationTable+".csv"))
+	assert.Equal(t, [][]string{
+		{"patient", "effective", "hemoglobin", "creatinine"},
+		{"Patient/p1", "2024-01-01", "13.5", "0.9"},
+	}, wide)
+	assert.NoDirExists(t, filepath.Join(jobDir, "observation_pivot"), "spilled observations are removed")
+}
+
+// TestExecuteCSVConversionStep_NoCompletedImport verifies the step fails without data to convert
+func TestExecuteCSVConversionStep_NoCompletedImport(t *testing.T) {
+	job, jobDir := createCSVConversionTestJob(t, models.FlattenConfig{})
+	job.Steps = nil
+
+	err := pipeline.ExecuteCSVConversionStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no completed import")
+}
@@ -0,0 +1,204 @@
+package unit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// readDeltaCommit returns the actions of a Delta commit file keyed by action type
+func readDeltaCommit(t *testing.T, path string) map[string][]map[string]any {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	actions := map[string][]map[string]any{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var action map[string]map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &action))
+		for kind, body := range action {
+			actions[kind] = append(actions[kind], body)
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return actions
+}
+
+// TestWriteDeltaCommit verifies the first commit contains protocol, schema and partitioned add actions
+func TestWriteDeltaCommit(t *testing.T) {
+	tableDir := t.TempDir()
+	for _, file := range []string{"year=2023/part-00000.parquet", "year=2024/part-00000.parquet", "year=2024/notes.txt"} {
+		path := filepath.Join(tableDir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("PAR1"), 0644))
+	}
+
+	files, err := services.CollectDeltaDataFiles(tableDir)
+	require.NoError(t, err)
+	require.Len(t, files, 2, "only Parquet files are collected")
+	assert.Equal(t, "year=2023/part-00000.parquet", files[0].Path)
+	assert.Equal(t, map[string]string{"year": "2023"}, files[0].PartitionValues)
+
+	columns := []services.DeltaColumn{{Name: "observation_id"}, {Name: "value", Type: "double"}, {Name: "year"}}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	version, err := services.WriteDeltaCommit(tableDir, columns, []string{"year"}, files, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), version)
+
+	actions := readDeltaCommit(t, filepath.Join(tableDir, services.DeltaLogDirName, "00000000000000000000.json"))
+	require.Len(t, actions["protocol"], 1)
+	require.Len(t, actions["metaData"], 1)
+	require.Len(t, actions["add"], 2)
+
+	metaData := actions["metaData"][0]
+	assert.Equal(t, []any{"year"}, metaData["partitionColumns"])
+	var schema struct {
+		Fields []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(metaData["schemaString"].(string)), &schema))
+	require.Len(t, schema.Fields, 3)
+	assert.Equal(t, "string", schema.Fields[0].Type, "columns default to string")
+	assert.Equal(t, "double", schema.Fields[1].Type)
+
+	add := actions["add"][1]
+	assert.Equal(t, "year=2024/part-00000.parquet", add["path"])
+	assert.Equal(t, map[string]any{"year": "2024"}, add["partitionValues"])
+	assert.EqualValues(t, 4, add["size"])
+	assert.Equal(t, true, add["dataChange"])
+}
+
+// TestWriteDeltaCommit_Append verifies later commits get the next version without repeating metadata
+func TestWriteDeltaCommit_Append(t *testing.T) {
+	tableDir := t.TempDir()
+	columns := []services.DeltaColumn{{Name: "id"}}
+	now := time.Now()
+
+	_, err := services.WriteDeltaCommit(tableDir, columns, nil, []services.DeltaDataFile{{Path: "part-00000.parquet", Size: 10}}, now)
+	require.NoError(t, err)
+	version, err := services.WriteDeltaCommit(tableDir, columns, nil, []services.DeltaDataFile{{Path: "part-00001.parquet", Size: 20}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+
+	actions := readDeltaCommit(t, filepath.Join(tableDir, services.DeltaLogDirName, "00000000000000000001.json"))
+	assert.Empty(t, actions["metaData"])
+	assert.Empty(t, actions["protocol"])
+	require.Len(t, actions["add"], 1)
+	assert.Equal(t, "Append", actions["commitInfo"][0]["operationParameters"].(map[string]any)["mode"])
+}
+
+// TestCommitDeltaTable_ReplacesPreviousVersion verifies a commit adds the new files and removes the live ones
+func TestCommitDeltaTable_ReplacesPreviousVersion(t *testing.T) {
+	tableDir := t.TempDir()
+	writeFile := func(name string) {
+		require.NoError(t, os.WriteFile(filepath.Join(tableDir, name), []byte("PAR1"), 0644))
+	}
+	columns := []services.DeltaColumn{{Name: "id"}}
+	now := time.Now()
+
+	writeFile("part-00000-a.parquet")
+	version, err := services.CommitDeltaTable(tableDir, columns, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), version)
+
+	writeFile("part-00000-b.parquet")
+	writeFile("part-00001-b.parquet")
+	version, err = services.CommitDeltaTable(tableDir, columns, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+
+	actions := readDeltaCommit(t, filepath.Join(tableDir, services.DeltaLogDirName, "00000000000000000001.json"))
+	require.Len(t, actions["add"], 2)
+	assert.Equal(t, "part-00000-b.parquet", actions["add"][0]["path"])
+	require.Len(t, actions["remove"], 1)
+	assert.Equal(t, "part-00000-a.parquet", actions["remove"][0]["path"])
+	assert.Equal(t, "Overwrite", actions["commitInfo"][0]["operationParameters"].(map[string]any)["mode"])
+	assert.FileExists(t, filepath.Join(tableDir, "part-00000-a.parquet"), "files of older versions stay for time travel")
+}
+
+// TestRemoveUncommittedDeltaFiles verifies only files no commit added are deleted
+func TestRemoveUncommittedDeltaFiles(t *testing.T) {
+	tableDir := t.TempDir()
+	committed := filepath.Join(tableDir, "part-00000-a.parquet")
+	require.NoError(t, os.WriteFile(committed, []byte("PAR1"), 0644))
+	_, err := services.CommitDeltaTable(tableDir, []services.DeltaColumn{{Name: "id"}}, nil, time.Now())
+	require.NoError(t, err)
+
+	leftover := filepath.Join(tableDir, "part-00000-b.parquet")
+	require.NoError(t, os.WriteFile(leftover, []byte("PAR1"), 0644))
+
+	require.NoError(t, services.RemoveUncommittedDeltaFiles(tableDir))
+	assert.FileExists(t, committed)
+	assert.NoFileExists(t, leftover)
+}
+
+// TestWriteDeltaCommit_SchemaEvolution verifies a commit with new columns widens the table schema
+func TestWriteDeltaCommit_SchemaEvolution(t *testing.T) {
+	tableDir := t.TempDir()
+	now := time.Now()
+
+	_, err := services.WriteDeltaCommit(tableDir, []services.DeltaColumn{{Name: "id"}, {Name: "value", Type: "double"}}, []string{"year"}, nil, now)
+	require.NoError(t, err)
+	first := readDeltaCommit(t, filepath.Join(tableDir, services.DeltaLogDirName, "00000000000000000000.json"))["metaData"][0]
+
+	// Later data lacks "value" and adds "unit": the schema becomes the union
+	_, err = services.WriteDeltaCommit(tableDir, []services.DeltaColumn{{Name: "unit"}, {Name: "id"}}, []string{"year"}, nil, now)
+	require.NoError(t, err)
+	evolved := readDeltaCommit(t, filepath.Join(tableDir, services.DeltaLogDirName, "00000000000000000001.json"))["metaData"]
+	require.Len(t, evolved, 1)
+	assert.Equal(t, first["id"], evolved[0]["id"], "the table keeps its identity")
+	assert.Equal(t, []any{"year"}, evolved[0]["partitionColumns"])
+	assert.JSONEq(t, `{"type":"struct","fields":[
+		{"name":"id","type":"string","nullable":true,"metadata":{}},
+		{"name":"value","type":"double","nullable":true,"metadata":{}},
+		{"name":"unit","type":"string","nullable":true,"metadata":{}}]}`, evolved[0]["schemaString"].(string))
+
+	// Columns already in the schema need no new metadata
+	_, err = services.WriteDeltaCommit(tableDir, []services.DeltaColumn{{Name: "value", Type: "double"}}, []string{"year"}, nil, now)
+	require.NoError(t, err)
+	assert.Empty(t, readDeltaCommit(t, filepath.Join(tableDir, services.DeltaLogDirName, "00000000000000000002.json"))["metaData"])
+
+	_, err = services.WriteDeltaCommit(tableDir, []services.DeltaColumn{{Name: "value", Type: "long"}}, []string{"year"}, nil, now)
+	assert.ErrorContains(t, err, "column 'value' changes type from double to long")
+	_, err = services.WriteDeltaCommit(tableDir, []services.DeltaColumn{{Name: "id"}}, []string{"resource_type"}, nil, now)
+	assert.ErrorContains(t, err, "partition columns change")
+}
+
+// TestMergeDeltaColumns verifies column order and type checks of the schema union
+func TestMergeDeltaColumns(t *testing.T) {
+	merged, changed, err := services.MergeDeltaColumns(
+		[]services.DeltaColumn{{Name: "a"}, {Name: "b", Type: "long"}},
+		[]services.DeltaColumn{{Name: "c"}, {Name: "a", Type: "string"}})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []services.DeltaColumn{{Name: "a"}, {Name: "b", Type: "long"}, {Name: "c"}}, merged)
+
+	_, changed, err = services.MergeDeltaColumns([]services.DeltaColumn{{Name: "a"}}, []services.DeltaColumn{{Name: "a"}})
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+// TestPackagingConfig_Validate verifies supported table formats
+func TestPackagingConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.PackagingConfig{}.Validate())
+	assert.NoError(t, models.PackagingConfig{Mode: models.PackagingModeLocal, TableFormat: models.TableFormatDelta}.Validate())
+	assert.ErrorContains(t, models.PackagingConfig{TableFormat: models.TableFormatDelta}.Validate(), "requires pipeline.packaging.mode 'local'")
+	assert.Error(t, models.PackagingConfig{TableFormat: "iceberg"}.Validate())
+	assert.Error(t, models.PackagingConfig{TableFormat: "hudi"}.Validate())
+	assert.NoError(t, models.PackagingConfig{Mode: models.PackagingModeService}.Validate())
+	assert.NoError(t, models.PackagingConfig{Mode: models.PackagingModeLocal}.Validate())
+	assert.Error(t, models.PackagingConfig{Mode: "remote"}.Validate())
+	assert.Error(t, models.PackagingConfig{Parquet: models.ParquetOptions{Compression: "lzma"}}.Validate())
+}
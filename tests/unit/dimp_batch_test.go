@@ -0,0 +1,141 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+)
+
+// batchDIMPServer pseudonymizes single resources and the entries of Bundles by prefixing their IDs
+// With rejectBundles set, Bundles are answered with 400 like a DIMP without batch support
+type batchDIMPServer struct {
+	mu            sync.Mutex
+	requests      int
+	bundles       int
+	rejectBundles bool
+}
+
+func (s *batchDIMPServer) start(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resource map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&resource))
+
+		s.mu.Lock()
+		s.requests++
+		isBundle := resource["resourceType"] == "Bundle"
+		if isBundle {
+			s.bundles++
+		}
+		s.mu.Unlock()
+
+		if isBundle && s.rejectBundles {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		pseudonymize := func(resource map[string]any) {
+			if id, ok := resource["id"].(string); ok {
+				resource["id"] = "pseudo-" + id
+			}
+		}
+		if isBundle {
+			entries, _ := resource["entry"].([]any)
+			for _, entry := range entries {
+				pseudonymize(entry.(map[string]any)["resource"].(map[string]any))
+			}
+		} else {
+			pseudonymize(resource)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeBatchInput writes five Observations with a blank line after the third
+func writeBatchInput(t *testing.T, jobDir string) {
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	content := ""
+	for i, id := range []string{"o1", "o2", "o3", "o4", "o5"} {
+		line, err := json.Marshal(map[string]any{"resourceType": "Observation", "id": id})
+		require.NoError(t, err)
+		content += string(line) + "\n"
+		if i == 2 {
+			content += "\n"
+		}
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(importDir, "obs.ndjson"), []byte(content), 0644))
+}
+
+// outputIDs returns the resource IDs of the DIMP output file in order
+func outputIDs(t *testing.T, jobDir string) []string {
+	var ids []string
+	for _, resource := range readDIMPNDJSON(t, filepath.Join(jobDir, "pseudonymized", "dimped_obs.ndjson")) {
+		ids = append(ids, resource["id"].(string))
+	}
+	return ids
+}
+
+// TestExecuteDIMPStep_BatchSize verifies resources are sent in batches that keep the input order
+// The blank line flushes the batch, so five resources with batch_size 2 take [o1 o2] [o3] [o4 o5]
+func TestExecuteDIMPStep_BatchSize(t *testing.T) {
+	dimp := &batchDIMPServer{}
+	server := dimp.start(t)
+
+	jobDir := t.TempDir()
+	writeBatchInput(t, jobDir)
+	job := createDIMPTestJob(server.URL)
+	job.Config.Services.DIMP.BatchSize = 2
+
+	require.NoError(t, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError), lib.NoProgress))
+
+	assert.Equal(t, []string{"pseudo-o1", "pseudo-o2", "pseudo-o3", "pseudo-o4", "pseudo-o5"}, outputIDs(t, jobDir))
+	assert.Equal(t, 3, dimp.requests)
+	assert.Equal(t, 2, dimp.bundles)
+}
+
+// TestExecuteDIMPStep_BatchFallback verifies a rejected batch is sent one resource at a time
+// and batching stays off for the rest of the file
+func TestExecuteDIMPStep_BatchFallback(t *testing.T) {
+	dimp := &batchDIMPServer{rejectBundles: true}
+	server := dimp.start(t)
+
+	jobDir := t.TempDir()
+	writeBatchInput(t, jobDir)
+	job := createDIMPTestJob(server.URL)
+	job.Config.Services.DIMP.BatchSize = 10
+	job.Config.Retry = models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 1, MaxBackoffMs: 1}
+
+	require.NoError(t, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError), lib.NoProgress))
+
+	assert.Equal(t, []string{"pseudo-o1", "pseudo-o2", "pseudo-o3", "pseudo-o4", "pseudo-o5"}, outputIDs(t, jobDir))
+	assert.Equal(t, 1, dimp.bundles)
+	assert.Equal(t, 6, dimp.requests)
+}
+
+// TestDIMPConfig_BatchSize verifies the batch size default and bounds
+func TestDIMPConfig_BatchSize(t *testing.T) {
+	assert.Equal(t, 1, models.DIMPConfig{}.GetBatchSize())
+	assert.Equal(t, 50, models.DIMPConfig{BatchSize: 50}.GetBatchSize())
+
+	config := models.ProjectConfig{
+		Services: models.ServiceConfig{DIMP: models.DIMPConfig{URL: "http://dimp.example.com", BatchSize: models.MaxDIMPBatchSize + 1}},
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport, models.StepDIMP}},
+		Retry:    models.RetryConfig{MaxAttempts: 3, InitialBackoffMs: 500, MaxBackoffMs: 5000},
+		JobsDir:  "/tmp/jobs",
+	}
+	assert.ErrorContains(t, config.Validate(), "dimp batch_size must be between 1 and")
+}
@@ -0,0 +1,136 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// countingDIMPServer wraps the mock DIMP server and counts the requests it receives
+func countingDIMPServer(t *testing.T) (string, *atomic.Int64) {
+	backend := createMockDIMPServer()
+	t.Cleanup(backend.Close)
+
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		backend.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &requests
+}
+
+// runCachedDIMPJob pseudonymizes the same two-resource file in a new job sharing jobsDir
+func runCachedDIMPJob(t *testing.T, jobsDir string, jobID string, dimpURL string, cacheTTLMinutes int) (*models.PipelineJob, string) {
+	job := createDIMPTestJob(dimpURL)
+	job.JobID = jobID
+	job.Config.JobsDir = jobsDir
+	job.Config.Services.DIMP.OutputCacheTTLMinutes = cacheTTLMinutes
+	jobDir := services.GetJobDir(jobsDir, jobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	content := `{"resourceType":"Patient","id":"p1"}` + "\n\n" + `{"resourceType":"Observation","id":"o1"}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(importDir, "data.ndjson"), []byte(content), 0644))
+
+	require.NoError(t, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress))
+	return job, jobDir
+}
+
+func TestDIMPOutputCache_ReusesIdenticalFile(t *testing.T) {
+	dimpURL, requests := countingDIMPServer(t)
+	jobsDir := t.TempDir()
+
+	_, firstDir := runCachedDIMPJob(t, jobsDir, "job-1", dimpURL, 60)
+	assert.EqualValues(t, 2, requests.Load())
+
+	job, secondDir := runCachedDIMPJob(t, jobsDir, "job-2", dimpURL, 60)
+	assert.EqualValues(t, 2, requests.Load(), "the second job must not call DIMP")
+
+	first, err := os.ReadFile(filepath.Join(firstDir, "pseudonymized", "dimped_data.ndjson"))
+	require.NoError(t, err)
+	second, err := os.ReadFile(filepath.Join(secondDir, "pseudonymized", "dimped_data.ndjson"))
+	require.NoError(t, err)
+	assert.Equal(t, string(first), string(second))
+
+	events, err := services.LoadJobEvents(jobsDir, job.JobID)
+	require.NoError(t, err)
+	var hits []models.JobEvent
+	for _, event := range events {
+		if event.Type == models.EventDIMPCacheHit {
+			hits = append(hits, event)
+		}
+	}
+	require.Len(t, hits, 1)
+	assert.Equal(t, "job-1", hits[0].Fields["cached_job"])
+
+	// Warnings of the cached run are recorded again
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	require.Len(t, step.Warnings, 1)
+	assert.Equal(t, models.WarningEmptyLines, step.Warnings[0].Code)
+}
+
+func TestDIMPOutputCache_Disabled(t *testing.T) {
+	dimpURL, requests := countingDIMPServer(t)
+	jobsDir := t.TempDir()
+
+	runCachedDIMPJob(t, jobsDir, "job-1", dimpURL, 0)
+	runCachedDIMPJob(t, jobsDir, "job-2", dimpURL, 0)
+	assert.EqualValues(t, 4, requests.Load())
+}
+
+func TestDIMPCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.ndjson")
+	require.NoError(t, os.WriteFile(file, []byte(`{"resourceType":"Patient","id":"p1"}`+"\n"), 0644))
+	config := models.DIMPConfig{URL: "http://dimp:32861/fhir"}
+
+	key, err := services.DIMPCacheKey(file, config)
+	require.NoError(t, err)
+
+	same, err := services.DIMPCacheKey(file, models.DIMPConfig{URL: "http://dimp:32861/fhir/", NonFHIRLines: models.NonFHIRLinesFail, BundleSplitThresholdMB: 10})
+	require.NoError(t, err)
+	assert.Equal(t, key, same, "defaults and a trailing slash don't change the key")
+
+	otherConfig, err := services.DIMPCacheKey(file, models.DIMPConfig{URL: config.URL, NonFHIRLines: models.NonFHIRLinesPassThrough})
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherConfig)
+
+	require.NoError(t, os.WriteFile(file, []byte(`{"resourceType":"Patient","id":"p2"}`+"\n"), 0644))
+	otherContent, err := services.DIMPCacheKey(file, config)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherContent)
+}
+
+func TestLoadDIMPCacheEntry_Expired(t *testing.T) {
+	jobsDir := t.TempDir()
+	output := filepath.Join(t.TempDir(), "dimped_a.ndjson")
+	require.NoError(t, os.WriteFile(output, []byte(`{"resourceType":"Patient","id":"x"}`+"\n"), 0644))
+
+	key := "ab" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789ab"
+	created := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, services.SaveDIMPCacheEntry(jobsDir, output, services.DIMPCacheEntry{Key: key, JobID: "job-1", CreatedAt: created, Resources: 1}))
+
+	entry, found := services.LoadDIMPCacheEntry(jobsDir, key, 3*time.Hour, time.Now())
+	require.True(t, found)
+	assert.Equal(t, 1, entry.Resources)
+
+	_, found = services.LoadDIMPCacheEntry(jobsDir, key, time.Hour, time.Now())
+	assert.False(t, found)
+	_, found = services.LoadDIMPCacheEntry(jobsDir, key, 3*time.Hour, time.Now())
+	assert.False(t, found, "expired entries are removed")
+}
@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func TestDIMPConfig_GetDebugLogSampling(t *testing.T) {
+	first, every := models.DIMPConfig{}.GetDebugLogSampling()
+	assert.Equal(t, models.DefaultDIMPDebugLogFirst, first)
+	assert.Equal(t, models.DefaultDIMPDebugLogEvery, every)
+
+	first, every = models.DIMPConfig{DebugLogFirst: 10, DebugLogEvery: 1}.GetDebugLogSampling()
+	assert.Equal(t, 10, first)
+	assert.Equal(t, 1, every)
+}
+
+func TestConfigLoading_DIMPDebugLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+    - dimp
+
+services:
+  dimp:
+    url: "http://localhost:32861/fhir"
+    debug_log_first: 50
+    debug_log_every: 200
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, 50, config.Services.DIMP.DebugLogFirst)
+	assert.Equal(t, 200, config.Services.DIMP.DebugLogEvery)
+
+	config.Services.DIMP.DebugLogEvery = -1
+	assert.Error(t, config.Validate())
+}
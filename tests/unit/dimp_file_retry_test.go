@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// flakyDIMPServer pseudonymizes resources, but cuts off the response for the first request of failID
+// A truncated response fails like a connection dropped mid-response, which the HTTP client doesn't retry
+func flakyDIMPServer(failID string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var received []string
+	failed := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resource map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&resource)
+		id, _ := resource["id"].(string)
+
+		mu.Lock()
+		received = append(received, id)
+		fail := id == failID && !failed
+		if fail {
+			failed = true
+		}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if fail {
+			_, _ = w.Write([]byte(`{"resourceType":`))
+			return
+		}
+		resource["id"] = "pseudo-" + id
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+}
+
+func runFlakyDIMPJob(t *testing.T, fileRetryAttempts int) (*models.PipelineJob, string, []string, error) {
+	server, received := flakyDIMPServer("p2")
+	t.Cleanup(server.Close)
+
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = t.TempDir()
+	job.Config.Services.DIMP.FileRetryAttempts = fileRetryAttempts
+	job.Config.Services.DIMP.FileRetryBackoffSeconds = 1
+	jobDir := services.GetJobDir(job.Config.JobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	writeDIMPNDJSON(t, filepath.Join(importDir, "patients.ndjson"), []map[string]any{
+		{"resourceType": "Patient", "id": "p1"},
+		{"resourceType": "Patient", "id": "p2"},
+		{"resourceType": "Patient", "id": "p3"},
+	})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress)
+	return job, jobDir, received(), err
+}
+
+func TestDIMPFileRetry_ResumesAfterTransientFailure(t *testing.T) {
+	job, jobDir, received, err := runFlakyDIMPJob(t, 1)
+	require.NoError(t, err)
+
+	// p1 was not sent again: the retry resumed at the failed line
+	assert.Equal(t, []string{"p1", "p2", "p2", "p3"}, received)
+
+	output := readDIMPNDJSON(t, filepath.Join(jobDir, "pseudonymized", "dimped_patients.ndjson"))
+	require.Len(t, output, 3)
+	for i, id := range []string{"pseudo-p1", "pseudo-p2", "pseudo-p3"} {
+		assert.Equal(t, id, output[i]["id"])
+	}
+
+	events, err := services.LoadJobEvents(job.Config.JobsDir, job.JobID)
+	require.NoError(t, err)
+	var retries []models.JobEvent
+	for _, event := range events {
+		if event.Type == models.EventRetryScheduled {
+			retries = append(retries, event)
+		}
+	}
+	require.Len(t, retries, 1)
+	assert.Equal(t, "patients.ndjson", retries[0].Fields["file"])
+	assert.EqualValues(t, 2, retries[0].Fields["resume_line"])
+}
+
+func TestDIMPFileRetry_DisabledFailsStep(t *testing.T) {
+	job, jobDir, received, err := runFlakyDIMPJob(t, 0)
+	require.Error(t, err)
+	assert.Equal(t, []string{"p1", "p2"}, received)
+
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	assert.Equal(t, models.ErrorTypeTransient, step.LastError.Type)
+
+	partFiles, _ := filepath.Glob(filepath.Join(jobDir, "pseudonymized", "*.part"))
+	assert.Empty(t, partFiles, "a failed file leaves no partial output")
+}
+
+func TestDIMPConfig_GetFileRetryBackoff(t *testing.T) {
+	config := models.DIMPConfig{}
+	assert.Equal(t, time.Minute, config.GetFileRetryBackoff(0))
+	assert.Equal(t, 4*time.Minute, config.GetFileRetryBackoff(2))
+	assert.Equal(t, models.MaxDIMPFileRetryBackoff, config.GetFileRetryBackoff(10))
+
+	config.FileRetryBackoffSeconds = 5
+	assert.Equal(t, 10*time.Second, config.GetFileRetryBackoff(1))
+}
+
+func TestConfigLoading_DIMPFileRetry(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+    - dimp
+
+services:
+  dimp:
+    url: "http://localhost:32861/fhir"
+    file_retry_attempts: 3
+    file_retry_backoff_seconds: 120
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, 3, config.Services.DIMP.FileRetryAttempts)
+	assert.Equal(t, 120, config.Services.DIMP.FileRetryBackoffSeconds)
+
+	config.Services.DIMP.FileRetryAttempts = 11
+	assert.Error(t, config.Validate())
+}
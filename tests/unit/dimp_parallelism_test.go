@@ -0,0 +1,159 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+)
+
+// concurrencyTrackingDIMPServer pseudonymizes like createMockDIMPServer, answers requests for
+// ids starting with "fail" with 400 and reports the most requests it served at once
+func concurrencyTrackingDIMPServer() (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(5 * time.Millisecond)
+
+		var resource map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&resource)
+		id, _ := resource["id"].(string)
+		if strings.HasPrefix(id, "fail") {
+			http.Error(w, `{"error": "bad request"}`, http.StatusBadRequest)
+			return
+		}
+		resource["id"] = "pseudo-" + id
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return peak
+	}
+}
+
+// writeParallelDIMPInput writes count files of perFile Patients each and returns their names
+func writeParallelDIMPInput(t *testing.T, importDir string, count, perFile int) []string {
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("file%02d.ndjson", i)
+		resources := make([]map[string]any, perFile)
+		for j := range resources {
+			resources[j] = map[string]any{"resourceType": "Patient", "id": fmt.Sprintf("f%d-p%d", i, j)}
+		}
+		writeDIMPNDJSON(t, filepath.Join(importDir, names[i]), resources)
+	}
+	return names
+}
+
+func TestExecuteDIMPStep_ParallelFilesKeepOrder(t *testing.T) {
+	server, peak := concurrencyTrackingDIMPServer()
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.Pipeline.DIMP.Parallelism = 4
+	names := writeParallelDIMPInput(t, filepath.Join(tmpDir, "import"), 8, 10)
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, createDIMPTestLogger(), lib.NoProgress)
+	require.NoError(t, err)
+
+	for i, name := range names {
+		resources := readDIMPNDJSON(t, filepath.Join(tmpDir, "pseudonymized", "dimped_"+name))
+		require.Len(t, resources, 10, name)
+		for j, resource := range resources {
+			assert.Equal(t, fmt.Sprintf("pseudo-f%d-p%d", i, j), resource["id"])
+		}
+	}
+	assert.Greater(t, peak(), 1, "files should be pseudonymized concurrently")
+	assert.LessOrEqual(t, peak(), 4)
+
+	step := job.Steps[0]
+	assert.Equal(t, models.StepStatusCompleted, step.Status)
+	assert.Equal(t, len(names), step.FilesProcessed)
+}
+
+func TestExecuteDIMPStep_ParallelResumeSkipsFinishedFiles(t *testing.T) {
+	server, _ := concurrencyTrackingDIMPServer()
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.Pipeline.DIMP.Parallelism = 3
+	names := writeParallelDIMPInput(t, filepath.Join(tmpDir, "import"), 5, 2)
+
+	// Output of an earlier run that got as far as the second file
+	outputDir := filepath.Join(tmpDir, "pseudonymized")
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	writeDIMPNDJSON(t, filepath.Join(outputDir, "dimped_"+names[1]), []map[string]any{{"resourceType": "Patient", "id": "earlier"}})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, createDIMPTestLogger(), lib.NoProgress)
+	require.NoError(t, err)
+
+	resumed := readDIMPNDJSON(t, filepath.Join(outputDir, "dimped_"+names[1]))
+	require.Len(t, resumed, 1)
+	assert.Equal(t, "earlier", resumed[0]["id"])
+	for _, name := range append(names[:1:1], names[2:]...) {
+		assert.Len(t, readDIMPNDJSON(t, filepath.Join(outputDir, "dimped_"+name)), 2, name)
+	}
+}
+
+func TestExecuteDIMPStep_ParallelFailureKeepsFinishedOutput(t *testing.T) {
+	server, _ := concurrencyTrackingDIMPServer()
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.Pipeline.DIMP.Parallelism = 2
+	importDir := filepath.Join(tmpDir, "import")
+	names := writeParallelDIMPInput(t, importDir, 6, 3)
+	writeDIMPNDJSON(t, filepath.Join(importDir, names[2]), []map[string]any{{"resourceType": "Patient", "id": "fail-1"}})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, createDIMPTestLogger(), lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), names[2])
+
+	outputDir := filepath.Join(tmpDir, "pseudonymized")
+	assert.NoFileExists(t, filepath.Join(outputDir, "dimped_"+names[2]))
+	for _, name := range names[:2] {
+		assert.Len(t, readDIMPNDJSON(t, filepath.Join(outputDir, "dimped_"+name)), 3, name)
+	}
+	// Files after the failure are not started, so none of them is left half-written
+	partFiles, _ := filepath.Glob(filepath.Join(outputDir, "*.part"))
+	assert.Empty(t, partFiles)
+	assert.NoFileExists(t, filepath.Join(outputDir, "dimped_"+names[5]))
+}
+
+func TestPipelineDIMPConfig_Parallelism(t *testing.T) {
+	assert.Equal(t, 1, models.PipelineDIMPConfig{}.GetParallelism())
+	assert.Equal(t, 8, models.PipelineDIMPConfig{Parallelism: 8}.GetParallelism())
+
+	assert.NoError(t, models.PipelineDIMPConfig{Parallelism: models.MaxDIMPParallelism}.Validate())
+	assert.Error(t, models.PipelineDIMPConfig{Parallelism: -1}.Validate())
+	assert.Error(t, models.PipelineDIMPConfig{Parallelism: models.MaxDIMPParallelism + 1}.Validate())
+}
@@ -0,0 +1,99 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func TestEstimateLineCount_SmallFileIsExact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "small.ndjson")
+	content := "{\"resourceType\":\"Patient\",\"id\":\"1\"}\n\n  \n{\"resourceType\":\"Patient\",\"id\":\"2\"}\n{\"resourceType\":\"Patient\",\"id\":\"3\"}"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	estimate, err := pipeline.EstimateLineCount(path)
+	require.NoError(t, err)
+	assert.True(t, estimate.Exact)
+	assert.Equal(t, int64(3), estimate.Lines, "blank lines are not counted, a last line without newline is")
+	assert.Equal(t, int64(len(content)), estimate.Bytes)
+}
+
+func TestEstimateLineCount_LargeFileIsExtrapolated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "large.ndjson")
+	line := fmt.Sprintf("{\"resourceType\":\"Observation\",\"note\":\"%s\"}\n", strings.Repeat("x", 1000))
+	const lines = 20000 // ~20 MB, well beyond the sample
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat(line, lines)), 0644))
+
+	estimate, err := pipeline.EstimateLineCount(path)
+	require.NoError(t, err)
+	assert.False(t, estimate.Exact)
+	assert.InDelta(t, lines, estimate.Lines, lines*0.01)
+}
+
+func TestEstimateLineCount_MissingFile(t *testing.T) {
+	_, err := pipeline.EstimateLineCount(filepath.Join(t.TempDir(), "missing.ndjson"))
+	assert.Error(t, err)
+}
+
+func TestDIMPConfig_GetProgressInterval(t *testing.T) {
+	assert.Equal(t, time.Minute, models.DIMPConfig{}.GetProgressInterval())
+	assert.Equal(t, 5*time.Second, models.DIMPConfig{ProgressIntervalSeconds: 5}.GetProgressInterval())
+	assert.Zero(t, models.DIMPConfig{ProgressIntervalSeconds: -1}.GetProgressInterval())
+}
+
+func TestExecuteDIMPStep_RecordsProgressEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(400 * time.Millisecond)
+		var resource map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&resource)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+	defer server.Close()
+
+	jobsDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = jobsDir
+	job.Config.Services.DIMP.ProgressIntervalSeconds = 1
+	jobDir := services.GetJobDir(jobsDir, job.JobID)
+
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	patients := make([]map[string]any, 4)
+	for i := range patients {
+		patients[i] = map[string]any{"resourceType": "Patient", "id": fmt.Sprintf("p%d", i)}
+	}
+	writeDIMPNDJSON(t, filepath.Join(importDir, "patients.ndjson"), patients)
+
+	require.NoError(t, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress))
+
+	events, err := services.LoadJobEvents(jobsDir, job.JobID)
+	require.NoError(t, err)
+	var progress []models.JobEvent
+	for _, event := range events {
+		if event.Type == models.EventDIMPProgress {
+			progress = append(progress, event)
+		}
+	}
+	require.NotEmpty(t, progress, "a file taking longer than the interval reports progress")
+	first := progress[0]
+	assert.Equal(t, string(models.StepDIMP), first.Step)
+	assert.Contains(t, first.Message, "patients.ndjson")
+	assert.EqualValues(t, 4, first.Fields["estimated_lines"])
+	assert.Contains(t, first.Fields, "eta_seconds")
+}
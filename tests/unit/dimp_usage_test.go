@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestRecordDIMPUsage verifies the monthly counters add up across calls and are listed by month
+func TestRecordDIMPUsage(t *testing.T) {
+	jobsDir := t.TempDir()
+
+	months, err := services.LoadDIMPUsage(jobsDir)
+	require.NoError(t, err)
+	assert.Empty(t, months)
+
+	_, err = services.RecordDIMPUsage(jobsDir, "2026-10", models.DIMPUsage{Requests: 2, Resources: 10, Bytes: 100})
+	require.NoError(t, err)
+	_, err = services.RecordDIMPUsage(jobsDir, "2026-09", models.DIMPUsage{Requests: 1, Resources: 1, Bytes: 5})
+	require.NoError(t, err)
+	total, err := services.RecordDIMPUsage(jobsDir, "2026-10", models.DIMPUsage{Requests: 1, Resources: 5, Bytes: 50})
+	require.NoError(t, err)
+	assert.Equal(t, models.DIMPUsage{Requests: 3, Resources: 15, Bytes: 150}, total)
+
+	months, err = services.LoadDIMPUsage(jobsDir)
+	require.NoError(t, err)
+	require.Len(t, months, 2)
+	assert.Equal(t, "2026-09", months[0].Month)
+	assert.Equal(t, "2026-10", months[1].Month)
+	assert.Equal(t, int64(15), months[1].Resources)
+
+	assert.Equal(t, "2026-10", services.DIMPUsageMonth(time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)))
+}
+
+// TestDIMPQuotaConfig_Check verifies usage is reported from warn_percent of a limit on
+func TestDIMPQuotaConfig_Check(t *testing.T) {
+	quota := models.DIMPQuotaConfig{MonthlyResources: 100, MonthlyMB: 1}
+	assert.True(t, quota.IsActive())
+	assert.False(t, models.DIMPQuotaConfig{}.IsActive())
+
+	assert.Empty(t, quota.Check(models.DIMPUsage{Resources: 79}))
+	assert.Contains(t, quota.Check(models.DIMPUsage{Resources: 80}), "80 of 100 resources (80%)")
+
+	message := quota.Check(models.DIMPUsage{Resources: 120, Bytes: 1024 * 1024})
+	assert.Contains(t, message, "120 of 100 resources (120%)")
+	assert.Contains(t, message, "1.0 of 1 MB (100%)")
+	assert.Equal(t, int64(120), quota.UsedPercent(models.DIMPUsage{Resources: 120}))
+
+	quota.WarnPercent = 95
+	assert.Empty(t, quota.Check(models.DIMPUsage{Resources: 90}))
+	quota.WarnPercent = 101
+	assert.ErrorContains(t, quota.Validate(), "warn_percent")
+}
+
+// TestExecuteDIMPStep_RecordsUsage verifies the step counts what it sent on the job and in the
+// monthly counters, and warns once the month approaches the quota
+func TestExecuteDIMPStep_RecordsUsage(t *testing.T) {
+	dimp := &batchDIMPServer{}
+	server := dimp.start(t)
+
+	jobsDir := t.TempDir()
+	jobDir := filepath.Join(jobsDir, "job")
+	writeBatchInput(t, jobDir)
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = jobsDir
+	job.Config.Services.DIMP.BatchSize = 2
+	job.Config.Services.DIMP.UsageQuota = models.DIMPQuotaConfig{MonthlyResources: 10, WarnPercent: 90}
+
+	month := services.DIMPUsageMonth(time.Now())
+	_, err := services.RecordDIMPUsage(jobsDir, month, models.DIMPUsage{Requests: 4, Resources: 4})
+	require.NoError(t, err)
+
+	require.NoError(t, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError), lib.NoProgress))
+
+	require.NotNil(t, job.DIMPUsage)
+	assert.Equal(t, int64(3), job.DIMPUsage.Requests)
+	assert.Equal(t, int64(5), job.DIMPUsage.Resources)
+	assert.Positive(t, job.DIMPUsage.Bytes)
+
+	months, err := services.LoadDIMPUsage(jobsDir)
+	require.NoError(t, err)
+	require.Len(t, months, 1)
+	assert.Equal(t, int64(9), months[0].Resources)
+
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	var quotaWarnings []models.StepWarning
+	for _, warning := range step.Warnings {
+		if warning.Code == models.WarningDIMPQuota {
+			quotaWarnings = append(quotaWarnings, warning)
+		}
+	}
+	require.Len(t, quotaWarnings, 1)
+	assert.Contains(t, quotaWarnings[0].Message, "9 of 10 resources (90%)")
+
+	_, err = os.Stat(filepath.Join(jobsDir, ".dimp-usage.json"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestAppendJobEvent_RoundTrip verifies events are appended and read back in order
+func TestAppendJobEvent_RoundTrip(t *testing.T) {
+	jobsDir := t.TempDir()
+	jobID := "events-job"
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, jobID), 0755))
+
+	first := models.JobEvent{
+		Timestamp: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+		Type:      models.EventStepStarted,
+		Step:      "dimp",
+		Message:   "step started",
+	}
+	second := models.JobEvent{
+		Timestamp: time.Date(2025, 1, 1, 10, 5, 0, 0, time.UTC),
+		Type:      models.EventFileProcessed,
+		Step:      "dimp",
+		Message:   "file 1/2 processed: a.ndjson",
+		Fields:    map[string]any{"index": 1, "total": 2},
+	}
+	require.NoError(t, services.AppendJobEvent(jobsDir, jobID, first))
+	require.NoError(t, services.AppendJobEvent(jobsDir, jobID, second))
+
+	events, err := services.LoadJobEvents(jobsDir, jobID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventStepStarted, events[0].Type)
+	assert.True(t, first.Timestamp.Equal(events[0].Timestamp))
+	assert.Equal(t, "file 1/2 processed: a.ndjson", events[1].Message)
+	assert.Equal(t, float64(2), events[1].Fields["total"])
+}
+
+// TestAppendJobEvent_UnknownJob verifies events are not written for jobs without a directory
+func TestAppendJobEvent_UnknownJob(t *testing.T) {
+	jobsDir := t.TempDir()
+
+	err := services.AppendJobEvent(jobsDir, "missing-job", models.JobEvent{Type: models.EventJobCreated})
+	assert.Error(t, err)
+	assert.NoDirExists(t, filepath.Join(jobsDir, "missing-job"))
+}
+
+// TestLoadJobEvents_NoEventsAndTruncatedLine verifies missing files and torn writes are tolerated
+func TestLoadJobEvents_NoEventsAndTruncatedLine(t *testing.T) {
+	jobsDir := t.TempDir()
+	jobID := "events-job"
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, jobID), 0755))
+
+	events, err := services.LoadJobEvents(jobsDir, jobID)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	require.NoError(t, services.AppendJobEvent(jobsDir, jobID, models.JobEvent{Type: models.EventJobCreated, Message: "job created"}))
+	f, err := os.OpenFile(services.GetEventsFilePath(jobsDir, jobID), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"type":"step_sta`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	events, err = services.LoadJobEvents(jobsDir, jobID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.EventJobCreated, events[0].Type)
+}
+
+// TestExecuteDIMPStep_RecordsEvents verifies the DIMP step writes a start/per-file/complete timeline
+func TestExecuteDIMPStep_RecordsEvents(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	jobsDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = jobsDir
+	jobDir := filepath.Join(jobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	writeDIMPNDJSON(t, filepath.Join(importDir, "a.ndjson"), []map[string]any{{"resourceType": "Patient", "id": "p1"}})
+	writeDIMPNDJSON(t, filepath.Join(importDir, "b.ndjson"), []map[string]any{{"resourceType": "Patient", "id": "p2"}})
+
+	require.NoError(t, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress))
+
+	events, err := services.LoadJobEvents(jobsDir, job.JobID)
+	require.NoError(t, err)
+
+	types := make([]models.JobEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+		assert.Equal(t, "dimp", event.Step)
+	}
+	assert.Equal(t, []models.JobEventType{
+		models.EventStepStarted,
+		models.EventFileProcessed,
+		models.EventFileProcessed,
+		models.EventStepCompleted,
+	}, types)
+	assert.Equal(t, "file 2/2 processed: b.ndjson", events[2].Message)
+}
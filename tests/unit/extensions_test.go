@@ -0,0 +1,108 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const (
+	miiExtensionURL    = "https://www.medizininformatik-initiative.de/fhir/core/StructureDefinition/mii-ex-test"
+	vendorExtensionURL = "http://vendor.example.org/fhir/StructureDefinition/internal-flag"
+)
+
+// TestExtensionFilterConfig_Keeps verifies exact and prefix matching with deny winning over allow
+func TestExtensionFilterConfig_Keeps(t *testing.T) {
+	filter := models.ExtensionFilterConfig{
+		Allow: []string{"https://www.medizininformatik-initiative.de/fhir/*", "http://hl7.org/fhir/StructureDefinition/data-absent-reason"},
+		Deny:  []string{"https://www.medizininformatik-initiative.de/fhir/core/StructureDefinition/mii-ex-test"},
+	}
+
+	assert.True(t, filter.Keeps("https://www.medizininformatik-initiative.de/fhir/ext/StructureDefinition/other"))
+	assert.True(t, filter.Keeps("http://hl7.org/fhir/StructureDefinition/data-absent-reason"))
+	assert.False(t, filter.Keeps("http://hl7.org/fhir/StructureDefinition/data-absent-reason-2"), "non-wildcard patterns match exactly")
+	assert.False(t, filter.Keeps(miiExtensionURL), "deny wins over allow")
+	assert.False(t, filter.Keeps(vendorExtensionURL))
+
+	denyOnly := models.ExtensionFilterConfig{Deny: []string{"http://vendor.example.org/*"}}
+	assert.True(t, denyOnly.Keeps(miiExtensionURL))
+	assert.False(t, denyOnly.Keeps(vendorExtensionURL))
+}
+
+// TestExtensionFilterConfig_Validate verifies pattern validation
+func TestExtensionFilterConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.ExtensionFilterConfig{Allow: []string{"https://example.org/*"}}.Validate())
+	assert.Error(t, models.ExtensionFilterConfig{Deny: []string{""}}.Validate())
+	assert.Error(t, models.ExtensionFilterConfig{Allow: []string{"https://*.example.org/"}}.Validate())
+}
+
+// TestExtensionFilter_Import verifies rejected extensions are dropped at any depth while modifierExtension is kept
+func TestExtensionFilter_Import(t *testing.T) {
+	resources := []map[string]any{
+		{
+			"resourceType": "Patient",
+			"id":           "p1",
+			"extension": []any{
+				map[string]any{"url": miiExtensionURL, "extension": []any{
+					map[string]any{"url": "part", "valueString": "kept with its parent"},
+				}},
+				map[string]any{"url": vendorExtensionURL, "valueBoolean": true},
+			},
+			"modifierExtension": []any{
+				map[string]any{"url": vendorExtensionURL, "valueBoolean": true},
+			},
+			"birthDate":  "1970",
+			"_birthDate": map[string]any{"extension": []any{map[string]any{"url": vendorExtensionURL, "valueCode": "x"}}},
+			"name": []any{
+				map[string]any{"family": "Test", "extension": []any{map[string]any{"url": vendorExtensionURL, "valueCode": "y"}}},
+			},
+		},
+	}
+
+	imported, _ := runLocalImportStep(t, "Patient.ndjson", resources, func(config *models.ProjectConfig) {
+		config.Extensions = models.ExtensionFilterConfig{Allow: []string{"https://www.medizininformatik-initiative.de/fhir/*"}}
+	})
+	require.Len(t, imported, 1)
+	patient := imported[0]
+
+	extensions := patient["extension"].([]any)
+	require.Len(t, extensions, 1)
+	kept := extensions[0].(map[string]any)
+	assert.Equal(t, miiExtensionURL, kept["url"])
+	assert.Len(t, kept["extension"], 1, "sub-extensions of kept extensions are kept")
+
+	assert.Len(t, patient["modifierExtension"], 1)
+	assert.NotContains(t, patient, "_birthDate", "empty primitive extension objects are removed")
+	assert.Equal(t, "1970", patient["birthDate"])
+	assert.NotContains(t, patient["name"].([]any)[0], "extension")
+}
+
+// TestConfigLoading_Extensions verifies the extensions section is loaded from YAML
+func TestConfigLoading_Extensions(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+extensions:
+  allow:
+    - "https://www.medizininformatik-initiative.de/fhir/*"
+  deny:
+    - "` + vendorExtensionURL + `"
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://www.medizininformatik-initiative.de/fhir/*"}, config.Extensions.Allow)
+	assert.Equal(t, []string{vendorExtensionURL}, config.Extensions.Deny)
+}
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func newFeasibilityTestClient(feasibilityURL string) *services.TORCHClient {
+	logger := lib.NewLogger(lib.LogLevelError)
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1}, logger)
+	config := models.TORCHConfig{BaseURL: "https://torch.example.org", Username: "user", Password: "secret", FeasibilityURL: feasibilityURL}
+	return services.NewTORCHClient(config, httpClient, logger)
+}
+
+func writeFeasibilityCRTDL(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "cohort.crtdl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"cohortDefinition":{"version":"1.0.0","inclusionCriteria":[[{"termCodes":[{"code":"424144002","system":"http://snomed.info/sct"}]}]]},"dataExtraction":{"attributeGroups":[]}}`), 0644))
+	return path
+}
+
+// TestCountCohort verifies that only the cohort definition is sent as a structured query
+func TestCountCohort(t *testing.T) {
+	var contentType string
+	var query map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		user, _, _ := r.BasicAuth()
+		assert.Equal(t, "user", user)
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &query))
+		_, _ = w.Write([]byte("1234\n"))
+	}))
+	defer server.Close()
+
+	count, err := newFeasibilityTestClient(server.URL).CountCohort(writeFeasibilityCRTDL(t))
+	require.NoError(t, err)
+	assert.Equal(t, 1234, count)
+	assert.Equal(t, "application/sq+json", contentType)
+	assert.Contains(t, query, "inclusionCriteria")
+	assert.NotContains(t, query, "dataExtraction")
+}
+
+func TestCountCohort_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		errorType models.ErrorType
+	}{
+		{"rejected query", http.StatusBadRequest, "unknown term code", models.ErrorTypeNonTransient},
+		{"no count", http.StatusOK, `{"status":"ok"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := newFeasibilityTestClient(server.URL).CountCohort(writeFeasibilityCRTDL(t))
+			require.Error(t, err)
+			var torchErr *services.TORCHError
+			if tt.errorType != "" {
+				require.ErrorAs(t, err, &torchErr)
+				assert.Equal(t, "feasibility", torchErr.Operation)
+				assert.Equal(t, tt.errorType, torchErr.ErrorType)
+				assert.Contains(t, err.Error(), tt.body)
+			} else {
+				assert.NotErrorAs(t, err, &torchErr)
+			}
+		})
+	}
+}
+
+func TestTORCHConfig_ValidateCohortSize(t *testing.T) {
+	base := models.TORCHConfig{BaseURL: "https://torch.example.org", ExtractionTimeoutMinutes: 30, PollingIntervalSeconds: 5, MaxPollingIntervalSeconds: 30}
+
+	valid := base
+	valid.FeasibilityURL = "https://flare.example.org/query/execute"
+	valid.MaxCohortSize = 50000
+	valid.CohortSizeAction = models.CohortSizeActionPrompt
+	assert.NoError(t, valid.Validate())
+
+	badURL := valid
+	badURL.FeasibilityURL = "flare.example.org"
+	assert.ErrorContains(t, badURL.Validate(), "feasibility_url")
+
+	badAction := valid
+	badAction.CohortSizeAction = "ask"
+	assert.ErrorContains(t, badAction.Validate(), "cohort_size_action")
+}
@@ -0,0 +1,151 @@
+package unit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func newExportTestClient(config models.FHIRExportConfig) *services.FHIRExportClient {
+	return services.NewFHIRExportClient(config, newSearchTestClient(), lib.NewLogger(lib.LogLevelError))
+}
+
+// TestFHIRExport_KickOffAddsTypes verifies the async kick-off request and the configured _type default
+func TestFHIRExport_KickOffAddsTypes(t *testing.T) {
+	var query, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("_type")
+		auth = r.Header.Get("Authorization")
+		assert.Equal(t, "respond-async", r.Header.Get("Prefer"))
+		assert.Equal(t, "application/fhir+json", r.Header.Get("Accept"))
+		w.Header().Set("Content-Location", "/status/42")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := newExportTestClient(models.FHIRExportConfig{BearerToken: "secret", Types: []string{"Patient", "Condition"}})
+	statusURL, err := client.KickOff(server.URL + "/fhir/$export")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/status/42", statusURL, "relative Content-Location is resolved")
+	assert.Equal(t, "Patient,Condition", query)
+	assert.Equal(t, "Bearer secret", auth)
+
+	// A _type in the URL wins over the configured types
+	_, err = client.KickOff(server.URL + "/fhir/$export?_type=Observation")
+	require.NoError(t, err)
+	assert.Equal(t, "Observation", query)
+}
+
+// TestFHIRExport_KickOffRejected verifies that a refused kick-off keeps the server's OperationOutcome
+func TestFHIRExport_KickOffRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprint(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"error","diagnostics":"unsupported _type"}]}`)
+	}))
+	defer server.Close()
+
+	_, err := newExportTestClient(models.FHIRExportConfig{}).KickOff(server.URL + "/fhir/$export")
+	var exportErr *services.FHIRExportError
+	require.ErrorAs(t, err, &exportErr)
+	assert.Equal(t, "kick-off", exportErr.Operation)
+	assert.Equal(t, models.ErrorTypeNonTransient, exportErr.ErrorType)
+	assert.Contains(t, exportErr.Message, "unsupported _type")
+}
+
+// TestFHIRExport_PollAndDownload verifies polling until completion and naming of downloaded files
+func TestFHIRExport_PollAndDownload(t *testing.T) {
+	var polls int
+	var fileAuth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			if polls++; polls == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"transactionTime":"2026-01-01T00:00:00Z","requiresAccessToken":true,
+			  "output":[{"type":"Observation","url":"files/a"},{"type":"Observation","url":"files/b"},{"type":"../evil","url":"files/c"}],
+			  "error":[{"type":"OperationOutcome","url":"files/err"}]}`)
+		default:
+			fileAuth = append(fileAuth, r.Header.Get("Authorization"))
+			_, _ = fmt.Fprintf(w, "{\"resourceType\":\"Observation\",\"id\":\"%s\"}\n", filepath.Base(r.URL.Path))
+		}
+	}))
+	defer server.Close()
+
+	client := newExportTestClient(models.FHIRExportConfig{Username: "user", Password: "pass", PollingIntervalSeconds: 1})
+	manifest, err := client.PollStatus(server.URL+"/status", lib.NoProgress)
+	require.NoError(t, err)
+	assert.Equal(t, 2, polls)
+	require.Len(t, manifest.Error, 1)
+	assert.Equal(t, server.URL+"/files/err", manifest.Error[0].URL, "file URLs are resolved against the status URL")
+
+	destDir := t.TempDir()
+	files, err := client.DownloadFiles(manifest, manifest.Output, destDir, lib.NoProgress)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, "Observation.ndjson", files[0].FileName)
+	assert.Equal(t, "Observation-2.ndjson", files[1].FileName)
+	assert.Equal(t, "Unknown.ndjson", files[2].FileName, "invalid types never become paths")
+	assert.Equal(t, "Observation", files[1].ResourceType)
+	assert.Equal(t, 1, files[1].LineCount)
+	for _, header := range fileAuth {
+		assert.NotEmpty(t, header, "requiresAccessToken sends credentials with downloads")
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "Observation-2.ndjson"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id":"b"`)
+}
+
+// TestFHIRExport_DownloadWithoutToken verifies that credentials stay with the FHIR server unless required
+func TestFHIRExport_DownloadWithoutToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		_, _ = fmt.Fprint(w, "{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n")
+	}))
+	defer server.Close()
+
+	manifest := &services.FHIRExportManifest{Output: []services.FHIRExportFileItem{{Type: "Patient", URL: server.URL + "/p"}}}
+	client := newExportTestClient(models.FHIRExportConfig{BearerToken: "secret"})
+	files, err := client.DownloadFiles(manifest, manifest.Output, t.TempDir(), lib.NoProgress)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+// TestFHIRExport_FailedExport verifies that an export failing on the server ends polling
+func TestFHIRExport_FailedExport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprint(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"fatal","diagnostics":"out of memory"}]}`)
+	}))
+	defer server.Close()
+
+	_, err := newExportTestClient(models.FHIRExportConfig{}).PollStatus(server.URL+"/status", lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of memory")
+}
+
+// TestFHIRExportConfig_Validate verifies credential and type checks of services.fhir_export
+func TestFHIRExportConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.FHIRExportConfig{}.Validate())
+	assert.NoError(t, models.FHIRExportConfig{Username: "u", Password: "p", Types: []string{"Patient"}}.Validate())
+	assert.Error(t, models.FHIRExportConfig{Username: "u"}.Validate())
+	assert.Error(t, models.FHIRExportConfig{Username: "u", Password: "p", BearerToken: "t"}.Validate())
+	assert.Error(t, models.FHIRExportConfig{TimeoutMinutes: -1}.Validate())
+	assert.Error(t, models.FHIRExportConfig{Types: []string{"Patients"}}.Validate())
+
+	initial, maximum := models.FHIRExportConfig{PollingIntervalSeconds: 90}.GetPollingIntervals()
+	assert.Equal(t, initial, maximum, "the maximum is never below the first interval")
+}
@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func newSearchTestClient() *services.HTTPClient {
+	return services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1}, lib.NewLogger(lib.LogLevelError))
+}
+
+// TestImportFromFHIRSearch_Paging verifies that all pages are fetched and results split by resource type
+func TestImportFromFHIRSearch_Paging(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		assert.Equal(t, "application/fhir+json", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "application/fhir+json")
+		if r.URL.Query().Get("page") == "" {
+			// Relative next link, included Patient, pretty-printed resource
+			_, _ = fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset",
+			  "link":[{"relation":"self","url":"Observation?category=laboratory"},{"relation":"next","url":"Observation?category=laboratory&page=2"}],
+			  "entry":[
+			    {"resource":{"resourceType":"Observation","id":"o1",
+			      "status":"final"},"search":{"mode":"match"}},
+			    {"resource":{"resourceType":"Patient","id":"p1"},"search":{"mode":"include"}}
+			  ]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset","entry":[
+		    {"resource":{"resourceType":"Observation","id":"o2"},"search":{"mode":"match"}},
+		    {"resource":{"resourceType":"Patient","id":"p1"},"search":{"mode":"include"}},
+		    {"resource":{"resourceType":"OperationOutcome","issue":[{"severity":"warning","code":"informational"}]},"search":{"mode":"outcome"}}
+		  ]}`)
+	}))
+	defer server.Close()
+
+	destDir := t.TempDir()
+	files, err := services.ImportFromFHIRSearch(server.URL+"/fhir/Observation?category=laboratory", destDir, newSearchTestClient(), lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"category=laboratory", "category=laboratory&page=2"}, queries)
+
+	require.Len(t, files, 2)
+	assert.Equal(t, "Observation.ndjson", files[0].FileName)
+	assert.Equal(t, "Observation", files[0].ResourceType)
+	assert.Equal(t, 2, files[0].LineCount)
+	assert.Equal(t, "Patient.ndjson", files[1].FileName)
+	assert.Equal(t, 1, files[1].LineCount, "a resource included on several pages is written once")
+
+	data, err := os.ReadFile(filepath.Join(destDir, "Observation.ndjson"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"resourceType":"Observation","id":"o1","status":"final"}`+"\n"+`{"resourceType":"Observation","id":"o2"}`+"\n", string(data))
+	_, err = os.Stat(filepath.Join(destDir, "OperationOutcome.ndjson"))
+	assert.True(t, os.IsNotExist(err), "outcomes are not imported")
+}
+
+func TestImportFromFHIRSearch_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		contains string
+	}{
+		{"server error", http.StatusBadRequest, `{"resourceType":"OperationOutcome"}`, "HTTP 400"},
+		{"not a bundle", http.StatusOK, `{"resourceType":"Patient","id":"p1"}`, "not a Bundle"},
+		{"next link loop", http.StatusOK, `{"resourceType":"Bundle","link":[{"relation":"next","url":"Patient"}]}`, "links back"},
+		{"unsafe resource type", http.StatusOK, `{"resourceType":"Bundle","entry":[{"resource":{"resourceType":"../Patient","id":"p1"}}]}`, "invalid resource type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := services.ImportFromFHIRSearch(server.URL+"/fhir/Patient", t.TempDir(), newSearchTestClient(), lib.NewLogger(lib.LogLevelError))
+			assert.ErrorContains(t, err, tt.contains)
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestDefaultFlattenMapping_Compiles verifies the shipped MII KDS mappings parse and compile
+func TestDefaultFlattenMapping_Compiles(t *testing.T) {
+	mapping := services.DefaultFlattenMapping()
+	for _, resourceType := range []string{"Patient", "Encounter", "Condition", "Observation", "Procedure", "MedicationStatement"} {
+		assert.NotEmpty(t, mapping[resourceType], "default mapping should cover %s", resourceType)
+	}
+
+	_, err := services.NewFlattener(mapping)
+	require.NoError(t, err)
+}
+
+// TestFlattener_DefaultObservation verifies a KDS lab Observation is flattened with the default mapping
+func TestFlattener_DefaultObservation(t *testing.T) {
+	flattener, err := services.NewFlattener(services.DefaultFlattenMapping())
+	require.NoError(t, err)
+
+	observation := map[string]any{
+		"resourceType": "Observation",
+		"id":           "o1",
+		"status":       "final",
+		"subject":      map[string]any{"reference": "Patient/p1"},
+		"code": map[string]any{"coding": []any{
+			map[string]any{"system": "http://snomed.info/sct", "code": "1234"},
+			map[string]any{"system": "http://loinc.org", "code": "718-7", "display": "Hemoglobin"},
+		}},
+		"effectiveDateTime": "2024-03-01",
+		"valueQuantity":     map[string]any{"value": 13.5, "code": "g/dL"},
+	}
+
+	resourceType, row, ok := flattener.Flatten(observation)
+	require.True(t, ok)
+	assert.Equal(t, "Observation", resourceType)
+
+	columns := flattener.Columns("Observation")
+	require.Len(t, row, len(columns))
+	values := map[string]string{}
+	for i, column := range columns {
+		values[column] = row[i]
+	}
+	assert.Equal(t, "o1", values["observation_id"])
+	assert.Equal(t, "Patient/p1", values["patient"])
+	assert.Equal(t, "718-7", values["loinc_code"])
+	assert.Equal(t, "Hemoglobin", values["display"])
+	assert.Equal(t, "13.5", values["value"])
+	assert.Equal(t, "g/dL", values["unit"])
+	assert.Equal(t, "", values["value_string"])
+
+	_, _, ok = flattener.Flatten(map[string]any{"resourceType": "Basic", "id": "b1"})
+	assert.False(t, ok, "resource types without a table are skipped")
+}
+
+// TestLoadFlattenMapping_ProjectOverride verifies project mappings replace or remove default tables
+func TestLoadFlattenMapping_ProjectOverride(t *testing.T) {
+	mappingFile := filepath.Join(t.TempDir(), "mapping.yaml")
+	require.NoError(t, os.WriteFile(mappingFile, []byte(`
+Patient:
+  - subject_id: id
+  - sex: gender
+  - given_names: name.given
+Medication: []
+`), 0644))
+
+	mapping, err := services.LoadFlattenMapping(models.FlattenConfig{MappingFile: mappingFile})
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.FlattenColumn{
+		{Name: "subject_id", Path: "id"},
+		{Name: "sex", Path: "gender"},
+		{Name: "given_names", Path: "name.given"},
+	}, mapping["Patient"], "column order follows the mapping file")
+	assert.NotContains(t, mapping, "Medication", "an empty table removes the default")
+	assert.NotEmpty(t, mapping["Condition"], "other defaults are kept")
+
+	flattener, err := services.NewFlattener(mapping)
+	require.NoError(t, err)
+	_, row, ok := flattener.Flatten(map[string]any{
+		"resourceType": "Patient",
+		"id":           "p1",
+		"gender":       "female",
+		"name":         []any{map[string]any{"given": []any{"Erika", "Maria"}}},
+	})
+	require.True(t, ok)
+	assert.Equal(t, []string{"p1", "female", "Erika|Maria"}, row)
+}
+
+// TestLoadFlattenMapping_Invalid verifies malformed mappings are rejected with a useful error
+func TestLoadFlattenMapping_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		contains string
+	}{
+		{"duplicate column", "Patient:\n  - id: id\n  - id: gender\n", "duplicate column 'id'"},
+		{"invalid FHIRPath", "Patient:\n  - id: name.where(\n", "column 'id'"},
+		{"two keys in one column", "Patient:\n  - id: id\n    sex: gender\n", "name: FHIRPath"},
+		{"empty path", "Patient:\n  - id: ''\n", "FHIRPath is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappingFile := filepath.Join(t.TempDir(), "mapping.yaml")
+			require.NoError(t, os.WriteFile(mappingFile, []byte(tt.content), 0644))
+
+			_, err := services.LoadFlattenMapping(models.FlattenConfig{MappingFile: mappingFile})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.contains)
+		})
+	}
+}
+
+// TestFormatFlattenValue verifies how FHIRPath results are rendered as cells
+func TestFormatFlattenValue(t *testing.T) {
+	assert.Equal(t, "", services.FormatFlattenValue(nil))
+	assert.Equal(t, "42", services.FormatFlattenValue([]any{float64(42)}))
+	assert.Equal(t, "0.001", services.FormatFlattenValue([]any{0.001}))
+	assert.Equal(t, "true", services.FormatFlattenValue([]any{true}))
+	assert.Equal(t, "a|b", services.FormatFlattenValue([]any{"a", "b"}))
+	assert.Equal(t, `{"code":"x"}`, services.FormatFlattenValue([]any{map[string]any{"code": "x"}}))
+}
+
+// TestConfigLoading_FlattenMappingFile verifies an invalid mapping file fails config loading
+func TestConfigLoading_FlattenMappingFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	mappingFile := filepath.Join(tmpDir, "mapping.yaml")
+	require.NoError(t, os.WriteFile(mappingFile, []byte("Patient:\n  - id: id\n  - id: gender\n"), 0644))
+
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+flatten:
+  mapping_file: "` + mappingFile + `"
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	_, err := services.LoadConfig(configFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "flatten.mapping_file")
+
+	require.NoError(t, os.WriteFile(mappingFile, []byte("Patient:\n  - id: id\n"), 0644))
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, mappingFile, config.Flatten.MappingFile)
+}
+
+// TestPivotObservations verifies long Observation rows are pivoted into one column per configured code
+func TestPivotObservations(t *testing.T) {
+	longColumns := []string{"observation_id", "patient", "effective", "loinc_code", "value"}
+	longRows := [][]string{
+		{"o1", "Patient/p1", "2024-03-01", "718-7", "13.5"},
+		{"o2", "Patient/p1", "2024-03-01", "2160-0", "0.9"},
+		{"o3", "Patient/p2", "2024-03-02", "718-7", "12.1"},
+		{"o4", "Patient/p1", "2024-03-01", "718-7", "13.7"},
+		{"o5", "Patient/p1", "2024-03-01", "9999-9", "ignored"},
+	}
+	pivot := models.ObservationPivotConfig{
+		Format: models.ObservationFormatWide,
+		Codes: []models.PivotCode{
+			{Code: "718-7", Column: "hemoglobin"},
+			{Code: "2160-0"},
+		},
+	}
+
+	header, rows, err := services.PivotObservations(longColumns, longRows, pivot)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"patient", "effective", "hemoglobin", "2160-0"}, header)
+	assert.Equal(t, [][]string{
+		{"Patient/p1", "2024-03-01", "13.5|13.7", "0.9"},
+		{"Patient/p2", "2024-03-02", "12.1", ""},
+	}, rows)
+
+	pivot.GroupBy = []string{"patient"}
+	header, rows, err = services.PivotObservations(longColumns, longRows[:3], pivot)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"patient", "hemoglobin", "2160-0"}, header)
+	assert.Len(t, rows, 2)
+
+	pivot.GroupBy = []string{"encounter"}
+	_, _, err = services.PivotObservations(longColumns, longRows, pivot)
+	assert.ErrorContains(t, err, "column 'encounter' is not in the Observation mapping")
+}
+
+// TestObservationPivotSink_MatchesPivotObservations verifies the spilled pivot produces the rows of the
+// in-memory pivot, in the same order, and removes its spill files
+func TestObservationPivotSink_MatchesPivotObservations(t *testing.T) {
+	longColumns := []string{"observation_id", "patient", "effective", "loinc_code", "value"}
+	codes := []string{"718-7", "2160-0", "9999-9"}
+	var longRows [][]string
+	for i := 0; i < 2000; i++ {
+		// Groups recur, so most wide rows collect values from several long rows spread over the input
+		patient := fmt.Sprintf("Patient/p%d", (i*7)%300)
+		longRows = append(longRows, []string{fmt.Sprintf("o%d", i), patient, "2024-03-01", codes[i%len(codes)], strconv.Itoa(i)})
+	}
+	pivot := models.ObservationPivotConfig{
+		Format: models.ObservationFormatWide,
+		Codes:  []models.PivotCode{{Code: "718-7", Column: "hemoglobin"}, {Code: "2160-0"}},
+	}
+	wantHeader, wantRows, err := services.PivotObservations(longColumns, longRows, pivot)
+	require.NoError(t, err)
+
+	spillDir := filepath.Join(t.TempDir(), "pivot")
+	sink, err := services.NewObservationPivotSink(spillDir, longColumns, pivot)
+	require.NoError(t, err)
+	require.NoError(t, sink.WriteRow("Patient", []string{"id"}, []string{"p1"}, nil))
+	for _, row := range longRows {
+		require.NoError(t, sink.WriteRow("Observation", longColumns, row, nil))
+	}
+	require.NoError(t, sink.Close())
+
+	out := &recordingSink{}
+	rows, err := sink.WriteTo("Observation_wide", out)
+	require.NoError(t, err)
+	sink.Abort()
+
+	assert.Equal(t, wantHeader, sink.Columns())
+	assert.Equal(t, len(wantRows), rows)
+	assert.Equal(t, wantRows, out.rows["Observation_wide"])
+	assert.True(t, out.closed)
+	assert.NoDirExists(t, spillDir)
+
+	_, err = services.NewObservationPivotSink(spillDir, longColumns, models.ObservationPivotConfig{GroupBy: []string{"encounter"}})
+	assert.ErrorContains(t, err, "column 'encounter' is not in the Observation mapping")
+}
+
+// TestObservationPivotConfig_Validate verifies format and code validation
+func TestObservationPivotConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.ObservationPivotConfig{}.Validate())
+	assert.True(t, models.ObservationPivotConfig{}.WantsLong())
+	assert.False(t, models.ObservationPivotConfig{}.WantsWide())
+
+	both := models.ObservationPivotConfig{Format: models.ObservationFormatBoth, Codes: []models.PivotCode{{Code: "718-7"}}}
+	assert.NoError(t, both.Validate())
+	assert.True(t, both.WantsLong())
+	assert.True(t, both.WantsWide())
+
+	assert.Error(t, models.ObservationPivotConfig{Format: "tall"}.Validate())
+	assert.ErrorContains(t, models.ObservationPivotConfig{Format: models.ObservationFormatWide}.Validate(), "codes is required")
+	assert.ErrorContains(t, models.ObservationPivotConfig{
+		Format: models.ObservationFormatWide,
+		Codes:  []models.PivotCode{{Code: "718-7", Column: "patient"}},
+	}.Validate(), "duplicate column 'patient'")
+}
+
+// TestConfigLoading_ObservationPivot verifies pivot settings are loaded and checked against the mapping
+func TestConfigLoading_ObservationPivot(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	writeConfig := func(groupBy string) {
+		configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+flatten:
+  observations:
+    format: both
+    group_by: [` + groupBy + `]
+    codes:
+      - code: "718-7"
+        column: hemoglobin
+      - code: "2160-0"
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+		require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+	}
+
+	writeConfig("patient, encounter")
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, models.ObservationFormatBoth, config.Flatten.Observations.Format)
+	assert.Equal(t, []string{"patient", "encounter"}, config.Flatten.Observations.GroupBy)
+	assert.Equal(t, []models.PivotCode{{Code: "718-7", Column: "hemoglobin"}, {Code: "2160-0"}}, config.Flatten.Observations.Codes)
+
+	writeConfig("patient, visit")
+	_, err = services.LoadConfig(configFile)
+	assert.ErrorContains(t, err, "column 'visit' is not in the Observation mapping")
+}
+
+// TestFlattenColumn_GetClassification verifies identifier columns are derived from the FHIRPath
+func TestFlattenColumn_GetClassification(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected models.ColumnClassification
+	}{
+		{"id", models.ColumnIdentifier},
+		{"subject.reference", models.ColumnIdentifier},
+		{"identifier.where(system = 'http://example.org/mrn').value.first()", models.ColumnIdentifier},
+		{"code.coding.where(system = 'http://loinc.org').code.first()", models.ColumnClinical},
+		{"valueQuantity.value", models.ColumnClinical},
+		{"birthDate", models.ColumnClinical},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, models.FlattenColumn{Name: "c", Path: tt.path}.GetClassification(), tt.path)
+	}
+
+	override := models.FlattenColumn{Name: "c", Path: "valueString", Classification: models.ColumnIdentifier}
+	assert.Equal(t, models.ColumnIdentifier, override.GetClassification())
+}
+
+// TestDataDictionary verifies columns are tagged and explicit classifications from the mapping file are kept
+func TestDataDictionary(t *testing.T) {
+	mapping, err := services.ParseFlattenMapping([]byte(`
+Patient:
+  - patient_id: id
+  - study_number:
+      path: extension.where(url = 'http://example.org/study-number').valueString
+      classification: identifier
+  - gender: gender
+`))
+	require.NoError(t, err)
+
+	entries := services.BuildDataDictionary(mapping, true)
+	require.Len(t, entries, 3)
+	assert.Equal(t, services.DataDictionaryEntry{Table: "Patient", Column: "patient_id", Path: "id", Classification: models.ColumnIdentifier, Pseudonymized: true}, entries[0])
+	assert.Equal(t, models.ColumnIdentifier, entries[1].Classification)
+	assert.Equal(t, models.ColumnClinical, entries[2].Classification)
+	assert.False(t, entries[2].Pseudonymized)
+
+	assert.False(t, services.BuildDataDictionary(mapping, false)[0].Pseudonymized, "identifiers are not pseudonymized without DIMP")
+
+	outputDir := t.TempDir()
+	require.NoError(t, services.WriteDataDictionary(outputDir, entries))
+	content, err := os.ReadFile(filepath.Join(outputDir, services.DataDictionaryFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "table,column,fhirpath,classification,pseudonymized\n"+
+		"Patient,patient_id,id,identifier,true\n"+
+		"Patient,study_number,extension.where(url = 'http://example.org/study-number').valueString,identifier,true\n"+
+		"Patient,gender,gender,clinical,false\n", string(content))
+
+	_, err = services.ParseFlattenMapping([]byte("Patient:\n  - id:\n      path: id\n      classification: secret\n"))
+	assert.ErrorContains(t, err, "invalid classification")
+}
+
+// recordingSink collects the rows of a flattening pass
+type recordingSink struct {
+	rows    map[string][][]string
+	closed  bool
+	aborted bool
+}
+
+func (s *recordingSink) WriteRow(table string, columns []string, row []string, resource map[string]any) error {
+	if s.rows == nil {
+		s.rows = map[string][][]string{}
+	}
+	s.rows[table] = append(s.rows[table], row)
+	return nil
+}
+
+func (s *recordingSink) Close() error { s.closed = true; return nil }
+func (s *recordingSink) Abort()       { s.aborted = true }
+
+// TestFlattenFiles_SharedPass verifies one pass feeds the CSV sink and a second sink with the same rows
+func TestFlattenFiles_SharedPass(t *testing.T) {
+	mapping, err := services.ParseFlattenMapping([]byte("Patient:\n  - id: id\n  - sex: gender\n"))
+	require.NoError(t, err)
+	flattener, err := services.NewFlattener(mapping)
+	require.NoError(t, err)
+
+	inputDir := t.TempDir()
+	input := filepath.Join(inputDir, "data.ndjson")
+	require.NoError(t, os.WriteFile(input, []byte(
+		`{"resourceType":"Patient","id":"p1","gender":"female"}`+"\n"+
+			`{"resourceType":"Device","id":"d1"}`+"\n"+
+			`{"resourceType":"Patient","id":"p2","gender":"male"}`+"\n"), 0644))
+
+	csvDir := filepath.Join(t.TempDir(), "csv")
+	other := &recordingSink{}
+	stats, err := services.FlattenFiles([]string{input}, flattener, services.NewCSVTableSink(csvDir), other)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, stats.Resources)
+	assert.Equal(t, 1, stats.Unmapped)
+	assert.Equal(t, map[string]int{"Patient": 2}, stats.Rows)
+	assert.True(t, other.closed)
+	assert.Equal(t, [][]string{{"p1", "female"}, {"p2", "male"}}, other.rows["Patient"])
+
+	data, err := os.ReadFile(filepath.Join(csvDir, "Patient.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "id,sex\np1,female\np2,male\n", string(data))
+	assert.NoFileExists(t, filepath.Join(csvDir, "Device.csv"))
+}
+
+// TestFlattenFiles_Abort verifies a failed pass leaves no partial tables behind
+func TestFlattenFiles_Abort(t *testing.T) {
+	flattener, err := services.NewFlattener(services.DefaultFlattenMapping())
+	require.NoError(t, err)
+
+	input := filepath.Join(t.TempDir(), "data.ndjson")
+	require.NoError(t, os.WriteFile(input, []byte(`{"resourceType":"Patient","id":"p1"}`+"\nnot json\n"), 0644))
+
+	csvDir := t.TempDir()
+	other := &recordingSink{}
+	_, err = services.FlattenFiles([]string{input}, flattener, services.NewCSVTableSink(csvDir), other)
+	assert.ErrorContains(t, err, "data.ndjson")
+	assert.True(t, other.aborted)
+	assert.False(t, other.closed)
+
+	entries, err := os.ReadDir(csvDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// TestCSVTableSink_SchemaEvolution verifies columns that appear mid-pass are appended to the header
+// and earlier rows are padded with empty cells
+func TestCSVTableSink_SchemaEvolution(t *testing.T) {
+	csvDir := t.TempDir()
+	sink := services.NewCSVTableSink(csvDir)
+	require.NoError(t, sink.WriteRow("Patient", []string{"id", "sex"}, []string{"p1", "female"}, nil))
+	require.NoError(t, sink.WriteRow("Patient", []string{"id", "born", "sex"}, []string{"p2", "1970-01-01", "male"}, nil))
+	require.NoError(t, sink.WriteRow("Patient", []string{"id"}, []string{"p3"}, nil))
+	require.NoError(t, sink.Close())
+
+	data, err := os.ReadFile(filepath.Join(csvDir, "Patient.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "id,sex,born\np1,female,\np2,male,1970-01-01\np3,,\n", string(data))
+	assert.NoFileExists(t, filepath.Join(csvDir, "Patient.csv.part"))
+}
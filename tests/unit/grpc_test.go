@@ -0,0 +1,300 @@
+package unit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/server"
+	"github.com/trobanga/aether/internal/server/aetherv1"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const operatorToken = "operator-token-0123456789"
+
+// startGRPCServer serves the gRPC API of config over an in-memory connection
+func startGRPCServer(t *testing.T, config models.ProjectConfig, launcher server.Launcher) aetherv1.JobServiceClient {
+	return dialGRPCServer(t, config, launcher, insecure.NewCredentials())
+}
+
+// dialGRPCServer serves the gRPC API of config over an in-memory connection and connects with creds
+func dialGRPCServer(t *testing.T, config models.ProjectConfig, launcher server.Launcher, creds credentials.TransportCredentials) aetherv1.JobServiceClient {
+	srv := server.New(config, lib.NewLogger(lib.LogLevelError))
+	srv.SetLauncher(launcher)
+	grpcServer, err := srv.GRPCServer()
+	require.NoError(t, err)
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return aetherv1.NewJobServiceClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// TestGRPC_JobService verifies submission, listing, status streaming and cancellation with roles
+func TestGRPC_JobService(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	config.Server.Tokens = []models.APIToken{
+		{Name: "dashboard", Token: viewerToken, Role: models.RoleViewer},
+		{Name: "study-desk", Token: submitterToken, Role: models.RoleSubmitter},
+		{Name: "ops", Token: operatorToken, Role: models.RoleOperator},
+	}
+	config.Server.AllowedInputDirs = []string{t.TempDir()}
+	var launched []string
+	client := startGRPCServer(t, config, func(jobConfig models.ProjectConfig, jobID string) error {
+		assert.Equal(t, "onco", jobConfig.Tenant)
+		launched = append(launched, jobID)
+		return nil
+	})
+
+	_, err := client.ListJobs(context.Background(), &aetherv1.ListJobsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	submit := &aetherv1.SubmitJobRequest{Tenant: "onco", Inputs: []string{config.Server.AllowedInputDirs[0]}}
+	_, err = client.SubmitJob(withToken(viewerToken), submit)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	job, err := client.SubmitJob(withToken(submitterToken), submit)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", job.GetStatus())
+	assert.Equal(t, []string{job.GetJobId()}, launched)
+
+	_, err = client.SubmitJob(withToken(submitterToken), &aetherv1.SubmitJobRequest{Tenant: "onco"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := client.ListJobs(withToken(viewerToken), &aetherv1.ListJobsRequest{Tenant: "onco"})
+	require.NoError(t, err)
+	require.Len(t, list.GetJobs(), 1)
+	assert.Equal(t, job.GetJobId(), list.GetJobs()[0].GetJobId())
+
+	cancelRequest := &aetherv1.CancelJobRequest{Tenant: "onco", JobId: job.GetJobId(), Reason: "wrong cohort"}
+	_, err = client.CancelJob(withToken(submitterToken), cancelRequest)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	cancelled, err := client.CancelJob(withToken(operatorToken), cancelRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", cancelled.GetStatus())
+	assert.Contains(t, cancelled.GetErrorMessage(), "wrong cohort")
+
+	_, err = client.CancelJob(withToken(operatorToken), cancelRequest)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The stream of a finished job replays its events and ends
+	stream, err := client.GetStatus(withToken(viewerToken), &aetherv1.GetStatusRequest{Tenant: "onco", JobId: job.GetJobId(), ReplayEvents: true})
+	require.NoError(t, err)
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "failed", first.GetJob().GetStatus())
+	var eventTypes []string
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		eventTypes = append(eventTypes, update.GetEvent().GetType())
+	}
+	assert.Contains(t, eventTypes, string(models.EventJobCancelled))
+
+	// Errors of server streams arrive with the first Recv
+	missing, err := client.GetStatus(withToken(viewerToken), &aetherv1.GetStatusRequest{JobId: "not-a-job"})
+	require.NoError(t, err)
+	_, err = missing.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestGRPC_SubmitJobInputs verifies remote clients can only submit server paths under server.allowed_input_dirs
+func TestGRPC_SubmitJobInputs(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	config.Server.Tokens = []models.APIToken{{Name: "study-desk", Token: submitterToken, Role: models.RoleSubmitter}}
+	config.Pipeline.EnabledSteps = []models.StepName{models.StepLocalImport, models.StepHttpImport}
+	inbox := t.TempDir()
+	config.Server.AllowedInputDirs = []string{inbox}
+	require.NoError(t, os.Mkdir(filepath.Join(inbox, "study-42"), 0755))
+	require.NoError(t, os.Symlink("/etc", filepath.Join(inbox, "etc")))
+	var launched int
+	client := startGRPCServer(t, config, func(models.ProjectConfig, string) error {
+		launched++
+		return nil
+	})
+
+	for _, input := range []string{"/etc", filepath.Join(inbox, "..", "..", "etc"), filepath.Join(inbox, "etc"), t.TempDir()} {
+		_, err := client.SubmitJob(withToken(submitterToken), &aetherv1.SubmitJobRequest{Tenant: "onco", Inputs: []string{input}})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), input)
+		assert.ErrorContains(t, err, "server.allowed_input_dirs", input)
+	}
+	// A rejected path among other inputs rejects the submission
+	_, err := client.SubmitJob(withToken(submitterToken), &aetherv1.SubmitJobRequest{Tenant: "onco", Inputs: []string{filepath.Join(inbox, "study-42"), "/etc"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Zero(t, launched)
+
+	for _, input := range []string{filepath.Join(inbox, "study-42"), "https://fhir.example.org/fhir/Observation?category=laboratory"} {
+		_, err := client.SubmitJob(withToken(submitterToken), &aetherv1.SubmitJobRequest{Tenant: "onco", Inputs: []string{input}})
+		assert.NoError(t, err, input)
+	}
+	assert.Equal(t, 2, launched)
+}
+
+// TestGRPC_TenantRestriction verifies tenant-restricted tokens and the audit log of RPCs
+func TestGRPC_TenantRestriction(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	config.Server.Tokens = []models.APIToken{{Name: "cardio-ops", Token: cardioToken, Role: models.RoleOperator, Tenant: "cardio"}}
+	client := startGRPCServer(t, config, nil)
+
+	_, err := client.ListJobs(withToken(cardioToken), &aetherv1.ListJobsRequest{Tenant: "onco"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.ListJobs(withToken(cardioToken), &aetherv1.ListJobsRequest{Tenant: "cardio"})
+	assert.NoError(t, err)
+
+	// Without a launcher, submission is disabled
+	_, err = client.SubmitJob(withToken(cardioToken), &aetherv1.SubmitJobRequest{Tenant: "cardio", Inputs: []string{t.TempDir()}})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	entries := readAuditLog(t, config.JobsDir)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "GRPC", entries[0].Method)
+	assert.Equal(t, "/aether.v1.JobService/ListJobs", entries[0].Path)
+	assert.Equal(t, 403, entries[0].Status)
+	assert.Equal(t, "cardio-ops", entries[1].Principal)
+	assert.Equal(t, 200, entries[1].Status)
+}
+
+// TestGRPC_ReadOnlyWithoutTokens verifies jobs cannot be submitted or cancelled without server.tokens
+func TestGRPC_ReadOnlyWithoutTokens(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	client := startGRPCServer(t, config, func(models.ProjectConfig, string) error {
+		t.Error("no job may be launched")
+		return nil
+	})
+
+	_, err := client.SubmitJob(context.Background(), &aetherv1.SubmitJobRequest{Tenant: "onco", Inputs: []string{"/etc"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.ErrorContains(t, err, "read-only without server.tokens")
+
+	_, err = client.CancelJob(context.Background(), &aetherv1.CancelJobRequest{Tenant: "onco", JobId: "any"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.ListJobs(context.Background(), &aetherv1.ListJobsRequest{Tenant: "onco"})
+	assert.NoError(t, err)
+}
+
+// writeTestCertificate writes a self-signed certificate for localhost and its key to dir
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "grpc.crt")
+	keyFile = filepath.Join(dir, "grpc.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+// TestGRPC_TLS verifies server.grpc_tls_cert/grpc_tls_key serve the API over TLS only
+func TestGRPC_TLS(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	certFile, keyFile, pool := writeTestCertificate(t, t.TempDir())
+	config.Server.GRPCTLSCert = certFile
+	config.Server.GRPCTLSKey = keyFile
+
+	client := dialGRPCServer(t, config, nil, credentials.NewClientTLSFromCert(pool, "localhost"))
+	_, err := client.ListJobs(context.Background(), &aetherv1.ListJobsRequest{Tenant: "onco"})
+	assert.NoError(t, err)
+
+	plaintext := dialGRPCServer(t, config, nil, insecure.NewCredentials())
+	_, err = plaintext.ListJobs(context.Background(), &aetherv1.ListJobsRequest{Tenant: "onco"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	config.Server.GRPCTLSKey = filepath.Join(t.TempDir(), "missing.key")
+	_, err = server.New(config, lib.NewLogger(lib.LogLevelError)).GRPCServer()
+	assert.ErrorContains(t, err, "server.grpc_tls_cert")
+}
+
+// TestServerConfig_CheckGRPCTransport verifies plaintext gRPC is only allowed on loopback addresses
+func TestServerConfig_CheckGRPCTransport(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:9091", "[::1]:9091", "localhost:9091"} {
+		assert.NoError(t, models.ServerConfig{GRPCListen: address}.CheckGRPCTransport(), address)
+	}
+	for _, address := range []string{":9091", "0.0.0.0:9091", "10.0.0.5:9091", "aether.example.org:9091"} {
+		assert.ErrorContains(t, models.ServerConfig{GRPCListen: address}.CheckGRPCTransport(), "grpc_tls_cert", address)
+	}
+	assert.NoError(t, models.ServerConfig{GRPCListen: ":9091", GRPCTLSCert: "grpc.crt", GRPCTLSKey: "grpc.key"}.CheckGRPCTransport())
+	assert.NoError(t, models.ServerConfig{}.CheckGRPCTransport())
+
+	assert.ErrorContains(t, models.ServerConfig{GRPCTLSCert: "grpc.crt"}.Validate(), "must be set together")
+}
+
+// TestCancelJob_PendingJob verifies a job that is not running is failed directly
+func TestCancelJob_PendingJob(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	job, err := pipeline.CreateJob(t.TempDir(), config, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+
+	cancelled, err := pipeline.CancelJob(config, job.JobID, "", lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, cancelled.Status)
+	step, found := models.GetStepByName(*cancelled, models.StepLocalImport)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+
+	events, err := services.LoadJobEvents(config.JobsDir, job.JobID)
+	require.NoError(t, err)
+	var eventTypes []models.JobEventType
+	for _, event := range events {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	assert.Contains(t, eventTypes, models.EventJobCancelled)
+
+	_, err = pipeline.CancelJob(config, job.JobID, "", lib.NewLogger(lib.LogLevelError))
+	assert.ErrorIs(t, err, pipeline.ErrJobFinished)
+}
@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/models"
+)
+
+func newHealthCheckTestConfig(jobsDir string, dimpURL string, ttlSeconds int) models.ProjectConfig {
+	return models.ProjectConfig{
+		Services: models.ServiceConfig{
+			DIMP: models.DIMPConfig{URL: dimpURL},
+			TORCH: models.TORCHConfig{
+				BaseURL: "http://localhost:9996", // Unreachable
+			},
+			HealthCheck: models.HealthCheckConfig{
+				TimeoutSeconds:  1,
+				CacheTTLSeconds: ttlSeconds,
+			},
+		},
+		Pipeline: models.PipelineConfig{
+			EnabledSteps: []models.StepName{models.StepTorchImport, models.StepLocalImport, models.StepDIMP},
+		},
+		JobsDir: jobsDir,
+	}
+}
+
+// TestHealthCheck_OnlyChecksServicesForJobSteps verifies TORCH is skipped for local-directory jobs
+func TestHealthCheck_OnlyChecksServicesForJobSteps(t *testing.T) {
+	dimpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer dimpServer.Close()
+
+	config := newHealthCheckTestConfig(t.TempDir(), dimpServer.URL, 0)
+
+	localSteps := models.StepsForInputType(config.Pipeline.EnabledSteps, models.InputTypeLocal)
+	assert.Equal(t, []models.StepName{models.StepLocalImport, models.StepDIMP}, localSteps)
+	assert.NoError(t, config.ValidateServiceConnectivityForSteps(localSteps))
+
+	torchSteps := models.StepsForInputType(config.Pipeline.EnabledSteps, models.InputTypeCRTDL)
+	err := config.ValidateServiceConnectivityForSteps(torchSteps)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TORCH")
+}
+
+// TestHealthCheck_CachesSuccessfulChecks verifies reachable services aren't pinged again within the TTL
+func TestHealthCheck_CachesSuccessfulChecks(t *testing.T) {
+	var requests atomic.Int32
+	dimpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer dimpServer.Close()
+
+	jobsDir := t.TempDir()
+	config := newHealthCheckTestConfig(jobsDir, dimpServer.URL, 60)
+	steps := []models.StepName{models.StepLocalImport, models.StepDIMP}
+
+	require.NoError(t, config.ValidateServiceConnectivityForSteps(steps))
+	require.NoError(t, config.ValidateServiceConnectivityForSteps(steps))
+
+	assert.Equal(t, int32(1), requests.Load(), "Second check should be served from cache")
+	assert.FileExists(t, filepath.Join(jobsDir, models.HealthCheckCacheFileName))
+}
+
+// TestHealthCheck_CacheDisabled verifies a zero TTL always pings services
+func TestHealthCheck_CacheDisabled(t *testing.T) {
+	var requests atomic.Int32
+	dimpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer dimpServer.Close()
+
+	jobsDir := t.TempDir()
+	config := newHealthCheckTestConfig(jobsDir, dimpServer.URL, 0)
+	steps := []models.StepName{models.StepLocalImport, models.StepDIMP}
+
+	require.NoError(t, config.ValidateServiceConnectivityForSteps(steps))
+	require.NoError(t, config.ValidateServiceConnectivityForSteps(steps))
+
+	assert.Equal(t, int32(2), requests.Load())
+	_, err := os.Stat(filepath.Join(jobsDir, models.HealthCheckCacheFileName))
+	assert.True(t, os.IsNotExist(err), "Cache file should not be written when caching is disabled")
+}
+
+// TestHealthCheck_RunsConcurrently verifies slow services are checked in parallel
+func TestHealthCheck_RunsConcurrently(t *testing.T) {
+	slowHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	dimpServer := httptest.NewServer(slowHandler)
+	defer dimpServer.Close()
+	csvServer := httptest.NewServer(slowHandler)
+	defer csvServer.Close()
+	parquetServer := httptest.NewServer(slowHandler)
+	defer parquetServer.Close()
+
+	config := newHealthCheckTestConfig(t.TempDir(), dimpServer.URL, 0)
+	config.Services.CSVConversion.URL = csvServer.URL
+	config.Services.ParquetConversion.URL = parquetServer.URL
+	steps := []models.StepName{models.StepLocalImport, models.StepDIMP, models.StepCSVConversion, models.StepParquetConversion}
+
+	start := time.Now()
+	require.NoError(t, config.ValidateServiceConnectivityForSteps(steps))
+	assert.Less(t, time.Since(start), 800*time.Millisecond, "Checks should not run sequentially")
+}
@@ -0,0 +1,86 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestStartHeartbeat_WritesJobAndGlobalFiles verifies heartbeats are written immediately and refreshed
+func TestStartHeartbeat_WritesJobAndGlobalFiles(t *testing.T) {
+	jobsDir := t.TempDir()
+	jobID := "heartbeat-job"
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, jobID), 0755))
+
+	config := models.HeartbeatConfig{IntervalSeconds: 1, Global: true}
+	hb := services.StartHeartbeat(jobsDir, jobID, config, lib.NewLogger(lib.LogLevelError))
+	require.NotNil(t, hb)
+	defer hb.Stop()
+
+	jobFile := filepath.Join(jobsDir, jobID, services.HeartbeatFileName)
+	globalFile := filepath.Join(jobsDir, services.HeartbeatFileName)
+
+	data, err := os.ReadFile(jobFile)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(data), "job="+jobID))
+	assert.FileExists(t, globalFile)
+
+	first, err := services.ReadHeartbeat(jobsDir, jobID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), first, 5*time.Second)
+
+	// The file is refreshed on the next tick
+	require.Eventually(t, func() bool {
+		latest, err := services.ReadHeartbeat(jobsDir, jobID)
+		return err == nil && latest.After(first)
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// No temp files are left behind
+	leftovers, _ := filepath.Glob(filepath.Join(jobsDir, jobID, ".heartbeat.*"))
+	assert.Empty(t, leftovers)
+}
+
+// TestStartHeartbeat_Disabled verifies a zero interval writes nothing and Stop is safe
+func TestStartHeartbeat_Disabled(t *testing.T) {
+	jobsDir := t.TempDir()
+	jobID := "heartbeat-job"
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, jobID), 0755))
+
+	hb := services.StartHeartbeat(jobsDir, jobID, models.HeartbeatConfig{}, lib.NewLogger(lib.LogLevelError))
+	assert.Nil(t, hb)
+	hb.Stop()
+
+	assert.NoFileExists(t, filepath.Join(jobsDir, jobID, services.HeartbeatFileName))
+}
+
+// TestConfigLoading_HeartbeatDefaults verifies the default interval and that an explicit 0 disables heartbeats
+func TestConfigLoading_HeartbeatDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	jobsDir := filepath.Join(tmpDir, "jobs")
+
+	load := func(extra string) *models.ProjectConfig {
+		configFile := filepath.Join(tmpDir, "config.yaml")
+		content := "pipeline:\n  enabled_steps:\n    - local_import\n" + extra + "jobs_dir: \"" + jobsDir + "\"\n"
+		require.NoError(t, os.WriteFile(configFile, []byte(content), 0644))
+		config, err := services.LoadConfig(configFile)
+		require.NoError(t, err)
+		return config
+	}
+
+	assert.Equal(t, models.DefaultHeartbeatIntervalSeconds, load("").Heartbeat.IntervalSeconds)
+
+	disabled := load("heartbeat:\n  interval_seconds: 0\n")
+	assert.Zero(t, disabled.Heartbeat.GetInterval())
+
+	global := load("heartbeat:\n  interval_seconds: 10\n  global: true\n")
+	assert.Equal(t, 10*time.Second, global.Heartbeat.GetInterval())
+	assert.True(t, global.Heartbeat.Global)
+}
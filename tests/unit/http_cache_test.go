@@ -0,0 +1,174 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// newCountingTORCHServer serves a completed extraction at /status and its file at /file,
+// answers DELETE on /status, and counts the GET and HEAD requests it receives
+func newCountingTORCHServer(t *testing.T, reads *atomic.Int32) *httptest.Server {
+	t.Helper()
+	var deleted atomic.Bool
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted.Store(true)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		reads.Add(1)
+		if deleted.Load() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Path {
+		case "/status":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"output": []map[string]any{{"type": "Patient", "url": server.URL + "/file"}},
+			})
+		case "/file":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newCachedTORCHClient(serverURL string, cache *services.HTTPResponseCache) *services.TORCHClient {
+	logger := lib.NewLogger(lib.LogLevelError)
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 10, MaxBackoffMs: 10}, logger)
+	client := services.NewTORCHClient(models.TORCHConfig{
+		BaseURL:                   serverURL,
+		ExtractionTimeoutMinutes:  1,
+		PollingIntervalSeconds:    1,
+		MaxPollingIntervalSeconds: 1,
+	}, httpClient, logger)
+	client.SetResponseCache(cache)
+	return client
+}
+
+func TestHTTPResponseCache_SharesStatusPollsBetweenClients(t *testing.T) {
+	var reads atomic.Int32
+	server := newCountingTORCHServer(t, &reads)
+	jobsDir := t.TempDir()
+
+	// Two observers (e.g. two processes) with their own client and the same jobs directory
+	for range 2 {
+		client := newCachedTORCHClient(server.URL, services.NewHTTPResponseCache(jobsDir, time.Minute, 1024*1024))
+		fileURLs, err := client.PollExtractionStatus(server.URL+"/status", lib.NoProgress)
+		require.NoError(t, err)
+		assert.Equal(t, []string{server.URL + "/file"}, fileURLs)
+	}
+	assert.Equal(t, int32(1), reads.Load())
+}
+
+func TestHTTPResponseCache_SharesFileHEADs(t *testing.T) {
+	var reads atomic.Int32
+	server := newCountingTORCHServer(t, &reads)
+	cache := services.NewHTTPResponseCache(t.TempDir(), time.Minute, 1024*1024)
+	entry := services.TORCHCacheEntry{StatusURL: server.URL + "/status", FileURLs: []string{server.URL + "/file"}}
+
+	require.NoError(t, newCachedTORCHClient(server.URL, cache).CheckExtractionResult(entry))
+	require.NoError(t, newCachedTORCHClient(server.URL, cache).CheckExtractionResult(entry))
+	assert.Equal(t, int32(2), reads.Load(), "status GET and file HEAD are each sent once")
+}
+
+func TestHTTPResponseCache_Expires(t *testing.T) {
+	var reads atomic.Int32
+	server := newCountingTORCHServer(t, &reads)
+	client := newCachedTORCHClient(server.URL, services.NewHTTPResponseCache(t.TempDir(), 50*time.Millisecond, 1024*1024))
+
+	_, err := client.PollExtractionStatus(server.URL+"/status", lib.NoProgress)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = client.PollExtractionStatus(server.URL+"/status", lib.NoProgress)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), reads.Load())
+}
+
+func TestHTTPResponseCache_DeleteInvalidates(t *testing.T) {
+	var reads atomic.Int32
+	server := newCountingTORCHServer(t, &reads)
+	client := newCachedTORCHClient(server.URL, services.NewHTTPResponseCache(t.TempDir(), time.Minute, 1024*1024))
+
+	_, err := client.PollExtractionStatus(server.URL+"/status", lib.NoProgress)
+	require.NoError(t, err)
+	require.NoError(t, client.DeleteExtractionResult(server.URL+"/status", []string{server.URL + "/file"}))
+
+	// A cached 200 must not make the deleted result look available
+	err = client.CheckExtractionResult(services.TORCHCacheEntry{StatusURL: server.URL + "/status", FileURLs: []string{server.URL + "/file"}})
+	assert.ErrorIs(t, err, services.ErrExtractionResultGone)
+}
+
+func TestHTTPResponseCache_SkipsErrors(t *testing.T) {
+	var reads atomic.Int32
+	server := newCountingTORCHServer(t, &reads)
+	cache := services.NewHTTPResponseCache(t.TempDir(), time.Minute, 1024*1024)
+
+	for range 2 {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/broken", nil)
+		require.NoError(t, err)
+		_, ok := cache.Get(req)
+		assert.False(t, ok)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp, err = cache.Store(req, resp)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	}
+	assert.Equal(t, int32(2), reads.Load())
+}
+
+func TestHTTPResponseCache_SizeLimit(t *testing.T) {
+	jobsDir := t.TempDir()
+	cache := services.NewHTTPResponseCache(jobsDir, time.Minute, 8*1024)
+	body := strings.Repeat("x", 1500)
+
+	for i := range 20 {
+		req := httptest.NewRequest(http.MethodGet, "http://torch/status/"+string(rune('a'+i)), nil)
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(http.StatusAccepted)
+		_, _ = rec.WriteString(body)
+		_, err := cache.Store(req, rec.Result())
+		require.NoError(t, err)
+	}
+
+	var total int64
+	entries, err := os.ReadDir(filepath.Join(jobsDir, ".http-cache"))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		require.NoError(t, err)
+		total += info.Size()
+	}
+	assert.LessOrEqual(t, total, int64(8*1024))
+	assert.NotEmpty(t, entries)
+
+	// The newest entry survives eviction
+	_, ok := cache.Get(httptest.NewRequest(http.MethodGet, "http://torch/status/"+string(rune('a'+19)), nil))
+	assert.True(t, ok)
+}
+
+func TestHTTPResponseCache_Disabled(t *testing.T) {
+	assert.Nil(t, services.NewTORCHResponseCache(t.TempDir(), models.TORCHConfig{}))
+	assert.NotNil(t, services.NewTORCHResponseCache(t.TempDir(), models.TORCHConfig{ResponseCacheSeconds: 5}))
+	assert.Equal(t, int64(models.DefaultResponseCacheMaxMB*1024*1024), models.TORCHConfig{}.GetResponseCacheMaxBytes())
+}
@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/i18n"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// formatVerbs matches fmt verbs, so translations can be checked for the same arguments
+var formatVerbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+// TestMessageCatalog_GermanComplete verifies every message has a German translation with the same format verbs
+func TestMessageCatalog_GermanComplete(t *testing.T) {
+	for _, key := range i18n.Keys() {
+		english, _ := i18n.Lookup(i18n.LocaleEnglish, key)
+		german, ok := i18n.Lookup(i18n.LocaleGerman, key)
+		if !assert.True(t, ok, "missing German translation for %s", key) {
+			continue
+		}
+		assert.Equal(t, formatVerbs.FindAllString(english, -1), formatVerbs.FindAllString(german, -1), "format verbs differ for %s", key)
+	}
+}
+
+// TestMessageCatalog_ResolveLocale verifies the configured locale wins over the environment
+func TestMessageCatalog_ResolveLocale(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "de_DE.UTF-8")
+
+	assert.Equal(t, i18n.LocaleGerman, i18n.Resolve(""))
+	assert.Equal(t, i18n.LocaleEnglish, i18n.Resolve("en"))
+	assert.Equal(t, i18n.LocaleEnglish, i18n.Resolve("fr"), "unsupported locales fall back to English")
+
+	// LC_ALL overrides LANG, and C means untranslated
+	t.Setenv("LC_ALL", "C")
+	assert.Equal(t, i18n.LocaleEnglish, i18n.Resolve(""))
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LANG", "")
+	assert.Equal(t, i18n.DefaultLocale, i18n.Resolve(""))
+}
+
+// TestMessageCatalog_GermanHint verifies the directory hint is translated and errors still wrap
+func TestMessageCatalog_GermanHint(t *testing.T) {
+	i18n.SetLocale(i18n.LocaleGerman)
+	defer i18n.SetLocale(i18n.LocaleEnglish)
+
+	crtdlPath := filepath.Join(t.TempDir(), "query.crtdl")
+	require.NoError(t, os.WriteFile(crtdlPath, []byte(`{"resourceType": "Parameters"}`), 0644))
+
+	err := services.ValidateImportSource(crtdlPath, models.InputTypeLocal)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Verzeichnis erwartet, aber Datei erhalten")
+	assert.Contains(t, err.Error(), "JSON/CRTDL-Datei")
+
+	cause := errors.New("boom")
+	assert.ErrorIs(t, i18n.Errorf(i18n.MsgLoadJobFailed, cause), cause)
+	assert.Equal(t, "Fehler: boom", i18n.T(i18n.MsgError, cause))
+
+	i18n.SetLocale("xx")
+	assert.Equal(t, i18n.LocaleEnglish, i18n.CurrentLocale())
+	assert.Equal(t, "Error: boom", i18n.T(i18n.MsgError, cause))
+}
+
+// TestConfigLoading_Locale verifies the locale key is loaded and validated
+func TestConfigLoading_Locale(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+locale: de_DE
+pipeline:
+  enabled_steps:
+    - local_import
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, i18n.LocaleGerman, i18n.Resolve(config.Locale))
+
+	config.Locale = "fr"
+	assert.Error(t, config.Validate())
+}
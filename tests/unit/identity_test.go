@@ -0,0 +1,135 @@
+package unit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// configureIdentity sets the process-wide client identity for one test
+func configureIdentity(t *testing.T, config models.HTTPClientConfig) {
+	lib.ConfigureClientIdentity(config)
+	t.Cleanup(func() { lib.ConfigureClientIdentity(models.HTTPClientConfig{}) })
+}
+
+func TestUserAgent(t *testing.T) {
+	configureIdentity(t, models.HTTPClientConfig{})
+	assert.Equal(t, "aether/"+lib.Version, lib.UserAgent(""))
+	assert.Equal(t, "aether/"+lib.Version+" job=3f2a9c1e", lib.UserAgent("3f2a9c1e-7b4d-4e2a-9f61-0c8d5e3b2a10"))
+
+	configureIdentity(t, models.HTTPClientConfig{UserAgent: "aether-site-a/2"})
+	assert.Equal(t, "aether-site-a/2 job=3f2a9c1e", lib.UserAgent("3f2a9c1e-7b4d"))
+}
+
+// TestHTTPClient_IdentityHeaders verifies that retries of a request share its request ID
+func TestHTTPClient_IdentityHeaders(t *testing.T) {
+	configureIdentity(t, models.HTTPClientConfig{RequestIDs: true})
+
+	var userAgents, requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		requestIDs = append(requestIDs, r.Header.Get(lib.RequestIDHeader))
+		if len(requestIDs) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 3, InitialBackoffMs: 1, MaxBackoffMs: 10}, lib.NewLogger(lib.LogLevelError))
+	client.SetJobID("3f2a9c1e-7b4d-4e2a-9f61-0c8d5e3b2a10")
+
+	for range 2 {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	require.Len(t, requestIDs, 3)
+	assert.Equal(t, "aether/"+lib.Version+" job=3f2a9c1e", userAgents[0])
+	assert.NotEmpty(t, requestIDs[0])
+	assert.Equal(t, requestIDs[0], requestIDs[1], "retry should keep the request ID")
+	assert.NotEqual(t, requestIDs[1], requestIDs[2], "a new request should get a new ID")
+}
+
+// TestClientIdentity_DefaultTransport verifies that clients without their own transport are identified
+func TestClientIdentity_DefaultTransport(t *testing.T) {
+	configureIdentity(t, models.HTTPClientConfig{})
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	require.NoError(t, services.PostWebhook(server.URL, map[string]string{"status": "completed"}))
+	assert.Equal(t, "aether/"+lib.Version, header.Get("User-Agent"))
+	assert.Empty(t, header.Get(lib.RequestIDHeader), "request IDs are disabled by default")
+}
+
+// TestHTTPClient_RequestIDOnFailure verifies that a request failing after its retries reports its ID
+func TestHTTPClient_RequestIDOnFailure(t *testing.T) {
+	configureIdentity(t, models.HTTPClientConfig{RequestIDs: true})
+
+	var requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs = append(requestIDs, r.Header.Get(lib.RequestIDHeader))
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 2, InitialBackoffMs: 1, MaxBackoffMs: 10}, lib.NewLogger(lib.LogLevelError))
+	_, err := client.Get(server.URL)
+	require.Error(t, err)
+
+	require.Len(t, requestIDs, 2)
+	assert.Equal(t, requestIDs[0], requestIDs[1])
+	assert.Equal(t, requestIDs[0], services.RequestIDOf(fmt.Errorf("import failed: %w", err)))
+}
+
+// TestDIMPClient_ErrorRequestID verifies that DIMP errors name the request to look up in DIMP logs
+func TestDIMPClient_ErrorRequestID(t *testing.T) {
+	configureIdentity(t, models.HTTPClientConfig{RequestIDs: true})
+
+	var requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(lib.RequestIDHeader)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	logger := lib.NewLogger(lib.LogLevelError)
+	client := services.NewDIMPClient(server.URL, services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1}, logger), logger)
+	_, err := client.Pseudonymize(map[string]any{"resourceType": "Patient", "id": "p1"})
+	require.Error(t, err)
+
+	var dimpErr *services.DIMPError
+	require.ErrorAs(t, err, &dimpErr)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, requestID, dimpErr.RequestID)
+	assert.Equal(t, requestID, services.RequestIDOf(err))
+}
+
+func TestRequestIDOf_Disabled(t *testing.T) {
+	configureIdentity(t, models.HTTPClientConfig{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1}, lib.NewLogger(lib.LogLevelError))
+	_, err := client.Get(server.URL)
+	require.Error(t, err)
+	assert.Empty(t, services.RequestIDOf(err))
+}
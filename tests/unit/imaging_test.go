@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+)
+
+const (
+	knownStudyUID   = "1.2.840.113619.2.55.1"
+	unknownStudyUID = "1.2.840.113619.2.55.2"
+)
+
+// createImagingTestJob creates a job with the imaging step enabled after local import
+func createImagingTestJob(imaging models.ImagingConfig) *models.PipelineJob {
+	return &models.PipelineJob{
+		JobID:       "test-imaging-job",
+		Status:      models.JobStatusInProgress,
+		CurrentStep: string(models.StepImaging),
+		CreatedAt:   time.Now(),
+		Config: models.ProjectConfig{
+			Services: models.ServiceConfig{Imaging: imaging},
+			Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport, models.StepImaging}},
+			Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 10, MaxBackoffMs: 100},
+		},
+	}
+}
+
+// createMockDICOMwebServer serves metadata for knownStudyUID and 404 for everything else
+func createMockDICOMwebServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/dicom+json", r.Header.Get("Accept"))
+		if r.URL.Path != "/wado/studies/"+knownStudyUID+"/metadata" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dicom+json")
+		_, _ = w.Write([]byte(`[{"0020000D":{"vr":"UI","Value":["` + knownStudyUID + `"]}}]`))
+	}))
+}
+
+// writeImagingInput writes an import file with two studies, an Endpoint and a Patient
+func writeImagingInput(t *testing.T, jobDir string) {
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	writeDIMPNDJSON(t, filepath.Join(importDir, "export.ndjson"), []map[string]any{
+		{"resourceType": "Patient", "id": "p1"},
+		{"resourceType": "ImagingStudy", "id": "s1", "identifier": []any{
+			map[string]any{"system": "urn:dicom:uid", "value": "urn:oid:" + knownStudyUID},
+		}},
+		{"resourceType": "ImagingStudy", "id": "s2", "identifier": []any{
+			map[string]any{"system": "urn:dicom:uid", "value": "urn:oid:" + unknownStudyUID},
+		}},
+		{"resourceType": "Endpoint", "id": "e1", "address": "http://pacs.internal/dicom-web/studies"},
+	})
+}
+
+// TestExecuteImagingStep_FetchesMetadataAndRewritesEndpoints verifies metadata retrieval, rewriting and the report
+func TestExecuteImagingStep_FetchesMetadataAndRewritesEndpoints(t *testing.T) {
+	server := createMockDICOMwebServer(t)
+	defer server.Close()
+
+	jobDir := t.TempDir()
+	writeImagingInput(t, jobDir)
+
+	job := createImagingTestJob(models.ImagingConfig{
+		DICOMwebURL:  server.URL + "/wado",
+		WADORewrites: []models.WADORewrite{{From: "http://pacs.internal/dicom-web", To: "https://pacs-proxy.example.org/wado"}},
+	})
+
+	require.NoError(t, pipeline.ExecuteImagingStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError)))
+
+	step, found := models.GetStepByName(*job, models.StepImaging)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusCompleted, step.Status)
+	assert.Equal(t, 1, step.FilesProcessed)
+
+	// All resources pass through; only the Endpoint address changes
+	output, err := os.ReadFile(filepath.Join(jobDir, "imaging", "export.ndjson"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[3], "https://pacs-proxy.example.org/wado/studies")
+	assert.NotContains(t, string(output), "pacs.internal")
+
+	assert.FileExists(t, filepath.Join(jobDir, "imaging", "dicom", knownStudyUID+".json"))
+	assert.NoFileExists(t, filepath.Join(jobDir, "imaging", "dicom", unknownStudyUID+".json"))
+
+	data, err := os.ReadFile(filepath.Join(jobDir, "imaging", pipeline.ImagingReportFileName))
+	require.NoError(t, err)
+	var report pipeline.ImagingReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, 2, report.Studies)
+	assert.Equal(t, 1, report.MetadataRetrieved)
+	assert.Equal(t, []string{unknownStudyUID}, report.MissingStudies)
+	assert.Equal(t, 1, report.EndpointsRewritten)
+}
+
+// TestExecuteImagingStep_FailOnMissingMetadata verifies missing studies fail the step when configured
+func TestExecuteImagingStep_FailOnMissingMetadata(t *testing.T) {
+	server := createMockDICOMwebServer(t)
+	defer server.Close()
+
+	jobDir := t.TempDir()
+	writeImagingInput(t, jobDir)
+
+	job := createImagingTestJob(models.ImagingConfig{DICOMwebURL: server.URL + "/wado", FailOnMissingMetadata: true})
+
+	err := pipeline.ExecuteImagingStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing for 1 of 2 studies")
+
+	step, found := models.GetStepByName(*job, models.StepImaging)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	assert.Equal(t, models.ErrorTypeNonTransient, step.LastError.Type)
+}
+
+// TestImagingConfig_Validate verifies imaging settings validation
+func TestImagingConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.ImagingConfig{}.Validate())
+	assert.NoError(t, models.ImagingConfig{DICOMwebURL: "https://pacs.example.org/wado"}.Validate())
+	assert.Error(t, models.ImagingConfig{DICOMwebURL: "ftp://pacs"}.Validate())
+	assert.Error(t, models.ImagingConfig{WADORewrites: []models.WADORewrite{{To: "x"}}}.Validate())
+	assert.Error(t, models.ImagingConfig{FailOnMissingMetadata: true}.Validate())
+
+	rewritten, changed := models.ImagingConfig{WADORewrites: []models.WADORewrite{{From: "http://a", To: "https://b"}}}.RewriteAddress("http://a/studies/1")
+	assert.True(t, changed)
+	assert.Equal(t, "https://b/studies/1", rewritten)
+}
@@ -488,3 +488,202 @@ func TestValidateImportSource_CRTDLFileForLocalInput(t *testing.T) {
 	assert.Contains(t, err.Error(), "dataExtraction", "Error should mention dataExtraction")
 	assert.Contains(t, err.Error(), "verbose logging", "Error should mention verbose logging")
 }
+
+// TestImportFromLocalDirectory_GlobPattern verifies glob sources import matching files and disambiguate duplicate names
+func TestImportFromLocalDirectory_GlobPattern(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, month := range []string{"2024-01", "2024-02", "2023-12"} {
+		dir := filepath.Join(tempDir, "exports", month)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "Patient.ndjson"), []byte(`{"resourceType":"Patient","id":"`+month+`"}`+"\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "checksums.sha256"), []byte("abc"), 0644))
+	}
+	destDir := filepath.Join(tempDir, "dest")
+
+	pattern := filepath.Join(tempDir, "exports", "2024-*", "*")
+	require.NoError(t, services.ValidateImportSource(pattern, models.InputTypeLocal))
+
+	importedFiles, err := services.ImportFromLocalDirectory(pattern, destDir, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	require.Len(t, importedFiles, 2)
+	assert.Equal(t, "Patient.ndjson", importedFiles[0].FileName)
+	assert.Equal(t, "Patient.2.ndjson", importedFiles[1].FileName)
+
+	second, err := os.ReadFile(filepath.Join(destDir, "Patient.2.ndjson"))
+	require.NoError(t, err)
+	assert.Contains(t, string(second), "2024-02")
+	assert.NoFileExists(t, filepath.Join(destDir, "checksums.sha256"))
+}
+
+// TestImportFromLocalDirectory_FileList verifies @file sources import listed paths relative to the list
+func TestImportFromLocalDirectory_FileList(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "data")
+	require.NoError(t, os.MkdirAll(dataDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "Patient.ndjson"), []byte(`{"resourceType":"Patient","id":"p1"}`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "Observation.ndjson"), []byte(`{"resourceType":"Observation","id":"o1"}`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "Skipped.ndjson"), []byte(`{"resourceType":"Encounter","id":"e1"}`+"\n"), 0644))
+
+	listFile := filepath.Join(tempDir, "files.txt")
+	list := "# nightly export\ndata/Patient.ndjson\n\n" + filepath.Join(dataDir, "Observation.ndjson") + "\n"
+	require.NoError(t, os.WriteFile(listFile, []byte(list), 0644))
+
+	count, err := services.CountLocalFHIRFiles("@"+listFile, models.ImportConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	importedFiles, err := services.ImportFromLocalDirectory("@"+listFile, filepath.Join(tempDir, "dest"), lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	require.Len(t, importedFiles, 2)
+	assert.Equal(t, "Observation.ndjson", importedFiles[0].FileName)
+	assert.Equal(t, "Patient.ndjson", importedFiles[1].FileName)
+}
+
+// TestResolveLocalSource_FileListMissingEntry verifies listed paths must exist
+func TestResolveLocalSource_FileListMissingEntry(t *testing.T) {
+	tempDir := t.TempDir()
+	listFile := filepath.Join(tempDir, "files.txt")
+	require.NoError(t, os.WriteFile(listFile, []byte("missing.ndjson\n"), 0644))
+
+	_, err := services.ResolveLocalSource("@" + listFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing.ndjson")
+
+	err = services.ValidateImportSource("@"+listFile, models.InputTypeLocal)
+	assert.Error(t, err)
+}
+
+// TestImportFromLocalSource_LinkModes verifies hardlink and symlink imports avoid copies and report source sizes
+func TestImportFromLocalSource_LinkModes(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	content := `{"resourceType":"Patient","id":"p1"}` + "\n" + `{"resourceType":"Patient","id":"p2"}` + "\n"
+	sourceFile := filepath.Join(sourceDir, "Patient.ndjson")
+	require.NoError(t, os.WriteFile(sourceFile, []byte(content), 0644))
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	sourceInfo, err := os.Stat(sourceFile)
+	require.NoError(t, err)
+
+	// Hardlink: same inode, accurate size and resource count
+	hardlinkDir := filepath.Join(tempDir, "hardlink")
+	files, err := services.ImportFromLocalSource(sourceDir, hardlinkDir, models.ImportConfig{LinkMode: models.ImportLinkHardlink}, logger)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, int64(len(content)), files[0].FileSize)
+	assert.Equal(t, 2, files[0].LineCount)
+	linkedInfo, err := os.Stat(filepath.Join(hardlinkDir, "Patient.ndjson"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(sourceInfo, linkedInfo), "hardlink should share the source inode")
+
+	// Symlink: points at the absolute source path
+	symlinkDir := filepath.Join(tempDir, "symlink")
+	files, err = services.ImportFromLocalSource(sourceDir, symlinkDir, models.ImportConfig{LinkMode: models.ImportLinkSymlink}, logger)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, int64(len(content)), files[0].FileSize)
+	target, err := os.Readlink(filepath.Join(symlinkDir, "Patient.ndjson"))
+	require.NoError(t, err)
+	assert.True(t, filepath.IsAbs(target))
+
+	// Re-importing as copy over an existing hardlink must not truncate the source
+	_, err = services.ImportFromLocalSource(sourceDir, hardlinkDir, models.ImportConfig{LinkMode: models.ImportLinkCopy}, logger)
+	require.NoError(t, err)
+	data, err := os.ReadFile(sourceFile)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	copiedInfo, err := os.Stat(filepath.Join(hardlinkDir, "Patient.ndjson"))
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(sourceInfo, copiedInfo))
+}
+
+// TestImportConfig_Validate verifies only known link and resource type detection modes are accepted
+func TestImportConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.ImportConfig{}.Validate())
+	assert.NoError(t, models.ImportConfig{LinkMode: models.ImportLinkSymlink}.Validate())
+	assert.Error(t, models.ImportConfig{LinkMode: "reflink"}.Validate())
+	assert.NoError(t, models.ImportConfig{ResourceTypeDetection: models.ResourceTypeFromFilename}.Validate())
+	assert.Error(t, models.ImportConfig{ResourceTypeDetection: "magic"}.Validate())
+	assert.Error(t, models.ImportConfig{ResourceTypeSampleLines: -1}.Validate())
+	assert.Equal(t, models.DefaultResourceTypeSampleLines, models.ImportConfig{}.GetResourceTypeSampleLines())
+}
+
+// TestImportConfig_Includes verifies include/exclude patterns match file names and resource types
+func TestImportConfig_Includes(t *testing.T) {
+	filter := models.ImportConfig{
+		Include: []string{"Patient", "Observation", "Condition_*.ndjson"},
+		Exclude: []string{"*_errors.ndjson"},
+	}
+	assert.True(t, filter.Includes("Patient.ndjson"))
+	assert.True(t, filter.Includes("Observation_001.ndjson"))
+	assert.True(t, filter.Includes("Condition_2024.ndjson"))
+	assert.False(t, filter.Includes("Encounter.ndjson"), "not included")
+	assert.False(t, filter.Includes("Patient_errors.ndjson"), "exclude wins over include")
+
+	excludeOnly := models.ImportConfig{Exclude: []string{"manifest*", "OperationOutcome"}}
+	assert.True(t, excludeOnly.Includes("Patient.ndjson"))
+	assert.False(t, excludeOnly.Includes("manifest.ndjson"))
+	assert.False(t, excludeOnly.Includes("OperationOutcome.ndjson"))
+
+	assert.Error(t, models.ImportConfig{Exclude: []string{"[unclosed"}}.Validate())
+}
+
+// TestImportFromLocalSource_IncludeExclude verifies noise files are skipped and not counted
+func TestImportFromLocalSource_IncludeExclude(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "Patient.ndjson"), []byte(`{"resourceType":"Patient","id":"p1"}`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "Observation.ndjson"), []byte(`{"resourceType":"Observation","id":"o1"}`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "export_log.ndjson"), []byte("not fhir\n"), 0644))
+
+	config := models.ImportConfig{Exclude: []string{"*_log.ndjson"}}
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	count, err := services.CountLocalFHIRFiles(sourceDir, config)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	files, err := services.ImportFromLocalSource(sourceDir, filepath.Join(tempDir, "dest"), config, logger)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.FileName)
+	}
+	assert.ElementsMatch(t, []string{"Patient.ndjson", "Observation.ndjson"}, names)
+	assert.NoFileExists(t, filepath.Join(tempDir, "dest", "export_log.ndjson"))
+
+	// Excluding everything reports how many files were filtered out
+	_, err = services.ImportFromLocalSource(sourceDir, filepath.Join(tempDir, "dest2"), models.ImportConfig{Include: []string{"Encounter"}}, logger)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "3 excluded")
+}
+
+// TestConfigLoading_ImportFilters verifies import.include and import.exclude are read from YAML
+func TestConfigLoading_ImportFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+import:
+  link_mode: symlink
+  include:
+    - Patient
+    - Observation
+  exclude:
+    - "*_errors.ndjson"
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportLinkSymlink, config.Import.LinkMode)
+	assert.Equal(t, []string{"Patient", "Observation"}, config.Import.Include)
+	assert.Equal(t, []string{"*_errors.ndjson"}, config.Import.Exclude)
+}
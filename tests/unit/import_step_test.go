@@ -1,6 +1,9 @@
 package unit
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -47,7 +50,7 @@ func TestExecuteImportStep_UnsupportedInputType(t *testing.T) {
 	}
 
 	// Execute import step - should fail with unknown input type
-	updatedJob, err := pipeline.ExecuteImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Verify error
 	require.Error(t, err, "Should fail with unknown input type")
@@ -68,3 +71,149 @@ func TestClassifyImportError_NilError(t *testing.T) {
 	// This is a defensive check that shouldn't normally occur
 	t.Skip("classifyImportError is not exported - covered by integration tests")
 }
+
+// TestExecuteImportStep_NormalizesEncoding verifies imported files have BOMs and CRLF line endings removed
+func TestExecuteImportStep_NormalizesEncoding(t *testing.T) {
+	sourceDir := t.TempDir()
+	content := "\xEF\xBB\xBF{\"resourceType\":\"Patient\",\"id\":\"p1\"}\r\n{\"resourceType\":\"Patient\",\"id\":\"p2\"}\r\n"
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "Patient.ndjson"), []byte(content), 0644))
+
+	jobsDir := t.TempDir()
+	logger := lib.NewLogger(lib.LogLevelError)
+	retryConfig := models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000}
+	httpClient := services.NewHTTPClient(5*time.Second, retryConfig, logger)
+
+	job := &models.PipelineJob{
+		JobID:       "test-encoding-job",
+		InputSource: sourceDir,
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepLocalImport),
+		Status:      models.JobStatusInProgress,
+		Steps:       models.InitializeSteps([]models.StepName{models.StepLocalImport}),
+		Config: models.ProjectConfig{
+			JobsDir:  jobsDir,
+			Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+			Retry:    retryConfig,
+		},
+	}
+
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
+	require.NoError(t, err)
+
+	importDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepLocalImport)
+	data, err := os.ReadFile(filepath.Join(importDir, "Patient.ndjson"))
+	require.NoError(t, err)
+
+	expected := "{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n{\"resourceType\":\"Patient\",\"id\":\"p2\"}\n"
+	assert.Equal(t, expected, string(data))
+	assert.Equal(t, int64(len(expected)), updatedJob.TotalBytes)
+}
+
+// TestExecuteImportStep_MultipleSources verifies additional sources land in the same import directory with provenance
+func TestExecuteImportStep_MultipleSources(t *testing.T) {
+	siteDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(siteDir, "Patient.ndjson"), []byte("{\"resourceType\":\"Patient\",\"id\":\"site\"}\n"), 0644))
+
+	centralDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(centralDir, "Patient.ndjson"), []byte("{\"resourceType\":\"Patient\",\"id\":\"central\"}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(centralDir, "Observation.ndjson"), []byte("{\"resourceType\":\"Observation\",\"id\":\"o1\"}\n"), 0644))
+
+	jobsDir := t.TempDir()
+	logger := lib.NewLogger(lib.LogLevelError)
+	config := models.ProjectConfig{
+		JobsDir:  jobsDir,
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+	}
+
+	job, err := pipeline.CreateMultiSourceJob([]string{siteDir, centralDir}, config, logger)
+	require.NoError(t, err)
+	require.Len(t, job.ExtraSources, 1)
+	assert.Equal(t, models.InputTypeLocal, job.ExtraSources[0].Type)
+
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), pipeline.StartJob(job), logger, nil, lib.NoProgress)
+	require.NoError(t, err)
+	assert.Equal(t, 3, updatedJob.TotalFiles)
+
+	importDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepLocalImport)
+	site, err := os.ReadFile(filepath.Join(importDir, "Patient.ndjson"))
+	require.NoError(t, err)
+	assert.Contains(t, string(site), "site")
+	central, err := os.ReadFile(filepath.Join(importDir, "Patient.src2.ndjson"))
+	require.NoError(t, err)
+	assert.Contains(t, string(central), "central")
+	assert.FileExists(t, filepath.Join(importDir, "Observation.ndjson"))
+	assert.NoDirExists(t, filepath.Join(importDir, ".source-2"))
+
+	sources := map[string]string{}
+	for _, file := range updatedJob.ImportedFiles {
+		sources[file.FileName] = file.Source
+	}
+	assert.Equal(t, map[string]string{
+		"Patient.ndjson":      siteDir,
+		"Patient.src2.ndjson": centralDir,
+		"Observation.ndjson":  centralDir,
+	}, sources)
+}
+
+// TestCreateMultiSourceJob_RejectsCRTDLAsExtraSource verifies only the first source may be a CRTDL
+func TestCreateMultiSourceJob_RejectsCRTDLAsExtraSource(t *testing.T) {
+	siteDir := t.TempDir()
+	crtdl := filepath.Join(t.TempDir(), "query.crtdl")
+	require.NoError(t, os.WriteFile(crtdl, []byte(`{"cohortDefinition":{},"dataExtraction":{}}`), 0644))
+
+	config := models.ProjectConfig{
+		JobsDir:  t.TempDir(),
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+	}
+
+	_, err := pipeline.CreateMultiSourceJob([]string{siteDir, crtdl}, config, lib.NewLogger(lib.LogLevelError))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "first input source")
+}
+
+// TestExecuteImportStep_DetectsResourceTypesFromContent verifies opaque file names get their types from content
+func TestExecuteImportStep_DetectsResourceTypesFromContent(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "part-0001.ndjson"),
+		[]byte("{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "part-0002.ndjson"),
+		[]byte("{\"resourceType\":\"Observation\",\"id\":\"o1\"}\n{\"resourceType\":\"Condition\",\"id\":\"c1\"}\n"), 0644))
+
+	run := func(detection models.ResourceTypeDetection) map[string]models.FHIRDataFile {
+		jobsDir := t.TempDir()
+		logger := lib.NewLogger(lib.LogLevelError)
+		retryConfig := models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000}
+		job := &models.PipelineJob{
+			JobID:       "test-detection-job",
+			InputSource: sourceDir,
+			InputType:   models.InputTypeLocal,
+			CurrentStep: string(models.StepLocalImport),
+			Status:      models.JobStatusInProgress,
+			Steps:       models.InitializeSteps([]models.StepName{models.StepLocalImport}),
+			Config: models.ProjectConfig{
+				JobsDir:  jobsDir,
+				Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+				Retry:    retryConfig,
+				Import:   models.ImportConfig{ResourceTypeDetection: detection},
+			},
+		}
+
+		updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, services.NewHTTPClient(5*time.Second, retryConfig, logger), lib.NoProgress)
+		require.NoError(t, err)
+		files := make(map[string]models.FHIRDataFile)
+		for _, file := range updatedJob.ImportedFiles {
+			files[file.FileName] = file
+		}
+		return files
+	}
+
+	files := run("")
+	assert.Equal(t, "Patient", files["part-0001.ndjson"].ResourceType)
+	assert.Empty(t, files["part-0001.ndjson"].ResourceTypes)
+	assert.Equal(t, models.ResourceTypeMixed, files["part-0002.ndjson"].ResourceType)
+	assert.Equal(t, []string{"Condition", "Observation"}, files["part-0002.ndjson"].ResourceTypes)
+
+	files = run(models.ResourceTypeFromFilename)
+	assert.Equal(t, models.GetResourceTypeFromFilename("part-0001.ndjson"), files["part-0001.ndjson"].ResourceType)
+}
@@ -37,7 +37,7 @@ func TestDownloadFromURL_Success(t *testing.T) {
 
 	// Execute download
 	url := server.URL + "/Patient.ndjson"
-	downloadedFiles, err := services.DownloadFromURL(url, destDir, httpClient, logger, false)
+	downloadedFiles, err := services.DownloadFromURL(url, destDir, httpClient, logger, lib.NoProgress)
 
 	// Verify results
 	assert.NoError(t, err, "Download should succeed")
@@ -76,7 +76,7 @@ func TestDownloadFromURL_HTTP404(t *testing.T) {
 	httpClient := services.DefaultHTTPClient()
 
 	// Execute download
-	downloadedFiles, err := services.DownloadFromURL(server.URL+"/missing.ndjson", destDir, httpClient, logger, false)
+	downloadedFiles, err := services.DownloadFromURL(server.URL+"/missing.ndjson", destDir, httpClient, logger, lib.NoProgress)
 
 	// Verify error
 	assert.Error(t, err, "Should fail with 404")
@@ -104,7 +104,7 @@ func TestDownloadFromURL_HTTP500(t *testing.T) {
 	httpClient := services.DefaultHTTPClient()
 
 	// Execute download
-	downloadedFiles, err := services.DownloadFromURL(server.URL+"/error.ndjson", destDir, httpClient, logger, false)
+	downloadedFiles, err := services.DownloadFromURL(server.URL+"/error.ndjson", destDir, httpClient, logger, lib.NoProgress)
 
 	// Verify error (should eventually fail after retries)
 	assert.Error(t, err, "Should fail with 500 after retries")
@@ -124,7 +124,7 @@ func TestDownloadFromURL_InvalidURL(t *testing.T) {
 	httpClient := services.DefaultHTTPClient()
 
 	// Execute download
-	downloadedFiles, err := services.DownloadFromURL(invalidURL, destDir, httpClient, logger, false)
+	downloadedFiles, err := services.DownloadFromURL(invalidURL, destDir, httpClient, logger, lib.NoProgress)
 
 	// Verify error
 	assert.Error(t, err, "Should fail for unreachable URL")
@@ -174,7 +174,7 @@ func TestDownloadFromURL_FilenameFallback(t *testing.T) {
 
 			// Execute download
 			url := server.URL + tt.urlPath
-			downloadedFiles, err := services.DownloadFromURL(url, destDir, httpClient, logger, false)
+			downloadedFiles, err := services.DownloadFromURL(url, destDir, httpClient, logger, lib.NoProgress)
 
 			// Verify filename
 			assert.NoError(t, err)
@@ -206,7 +206,7 @@ func TestDownloadFromURL_WithProgress(t *testing.T) {
 	httpClient := services.DefaultHTTPClient()
 
 	// Execute download with progress (using internal function)
-	downloadedFiles, err := services.DownloadFromURLWithProgress(server.URL+"/large.ndjson", destDir, httpClient, logger)
+	downloadedFiles, err := services.DownloadFromURL(server.URL+"/large.ndjson", destDir, httpClient, logger, lib.NoProgress)
 
 	// Verify results
 	assert.NoError(t, err, "Download should succeed")
@@ -333,7 +333,7 @@ func TestHTTPClient_Retry(t *testing.T) {
 	// Execute download
 	tempDir := t.TempDir()
 	destDir := filepath.Join(tempDir, "download")
-	downloadedFiles, err := services.DownloadFromURL(server.URL+"/test.ndjson", destDir, httpClient, logger, false)
+	downloadedFiles, err := services.DownloadFromURL(server.URL+"/test.ndjson", destDir, httpClient, logger, lib.NoProgress)
 
 	// Verify retry succeeded
 	assert.NoError(t, err, "Should succeed after retries")
@@ -361,7 +361,7 @@ func TestHTTPClient_NoRetryFor4xx(t *testing.T) {
 	destDir := filepath.Join(tempDir, "download")
 
 	// Execute download
-	downloadedFiles, err := services.DownloadFromURL(server.URL+"/bad.ndjson", destDir, httpClient, logger, false)
+	downloadedFiles, err := services.DownloadFromURL(server.URL+"/bad.ndjson", destDir, httpClient, logger, lib.NoProgress)
 
 	// Verify no retry for 4xx
 	assert.Error(t, err, "Should fail with 400")
@@ -53,6 +53,27 @@ func TestDetectInputType_TORCHUrlHTTPS(t *testing.T) {
 	assert.Equal(t, models.InputTypeTORCHURL, inputType, "HTTPS TORCH URL should be detected as InputTypeTORCHURL")
 }
 
+func TestDetectInputType_FHIRSearchURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected models.InputType
+	}{
+		{"https://fhir.example.org/fhir/Observation?category=laboratory&date=ge2024-01-01", models.InputTypeFHIRSearch},
+		{"http://localhost:8080/fhir/Patient", models.InputTypeFHIRSearch},
+		{"https://fhir.example.org/fhir/MedicationRequest/?status=active", models.InputTypeFHIRSearch},
+		{"https://example.com/Patient.ndjson", models.InputTypeHTTP},
+		{"https://example.com/export/data?format=ndjson", models.InputTypeHTTP},
+		{"https://fhir.example.org/fhir/$export", models.InputTypeBulkExport},
+		{"https://fhir.example.org/fhir/Group/study-42/$export?_type=Patient", models.InputTypeBulkExport},
+		{"http://localhost:8080/fhir/Patient/%24export", models.InputTypeBulkExport},
+	}
+	for _, tt := range tests {
+		inputType, err := lib.DetectInputType(tt.input)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, inputType, tt.input)
+	}
+}
+
 func TestDetectInputType_CRTDLFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	crtdlFile := filepath.Join(tmpDir, "query.crtdl")
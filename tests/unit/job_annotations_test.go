@@ -0,0 +1,71 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// createAnnotatedCRTDLJob creates a TORCH job from a CRTDL whose cohortDefinition names a study
+func createAnnotatedCRTDLJob(t *testing.T, mode models.JobMetadataMode) (*models.PipelineJob, string) {
+	t.Helper()
+	crtdlPath := filepath.Join(t.TempDir(), "study.crtdl")
+	require.NoError(t, os.WriteFile(crtdlPath, []byte(`{
+		"cohortDefinition": {"display": "Diabetes Cohort 2024", "version": "v2", "inclusionCriteria": [[]]},
+		"dataExtraction": {"attributeGroups": []}
+	}`), 0644))
+
+	jobsDir := t.TempDir()
+	config := models.ProjectConfig{
+		Pipeline:    models.PipelineConfig{EnabledSteps: []models.StepName{models.StepTorchImport}},
+		Retry:       models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		JobMetadata: models.JobMetadataConfig{Mode: mode},
+		JobsDir:     jobsDir,
+	}
+	job, err := pipeline.CreateJob(crtdlPath, config, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	return job, jobsDir
+}
+
+func TestCreateJob_AnnotatesCRTDLStudy(t *testing.T) {
+	job, jobsDir := createAnnotatedCRTDLJob(t, "")
+	require.NotNil(t, job.Annotations)
+	assert.Equal(t, models.JobAnnotations{Display: "Diabetes Cohort 2024", Version: "v2"}, *job.Annotations)
+
+	loaded, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, "Diabetes Cohort 2024 (v2)", loaded.Annotations.Label())
+	assert.Contains(t, pipeline.GetJobSummary(loaded), "Study: Diabetes Cohort 2024 (v2)")
+
+	views, err := services.ListJobViews(jobsDir, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.Equal(t, job.Annotations, views[0].Annotations)
+}
+
+func TestCreateJob_HashesStudyName(t *testing.T) {
+	job, jobsDir := createAnnotatedCRTDLJob(t, models.JobMetadataHash)
+
+	loaded, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, loaded.Config.JobMetadata.Hash("Diabetes Cohort 2024"), loaded.Annotations.Display)
+	assert.Equal(t, "v2", loaded.Annotations.Version)
+
+	events, err := os.ReadFile(filepath.Join(services.GetJobDir(jobsDir, job.JobID), services.EventsFileName))
+	require.NoError(t, err)
+	assert.NotContains(t, string(events), "Diabetes Cohort 2024")
+}
+
+func TestJobAnnotations_Label(t *testing.T) {
+	var none *models.JobAnnotations
+	assert.Equal(t, "", none.Label())
+	assert.Equal(t, "", (&models.JobAnnotations{Version: "v1"}).Label(), "a version alone does not name the study")
+	assert.Equal(t, "Study", (&models.JobAnnotations{Display: "Study"}).Label())
+}
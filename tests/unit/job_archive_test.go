@@ -0,0 +1,141 @@
+package unit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// createArchiveTestJob saves a job with import and pseudonymized output plus runtime files
+func createArchiveTestJob(t *testing.T, jobsDir string) *models.PipelineJob {
+	t.Helper()
+	enabled := []models.StepName{models.StepLocalImport, models.StepDIMP}
+	job := &models.PipelineJob{
+		JobID:       uuid.New().String(),
+		InputSource: "/data/export",
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepDIMP),
+		Status:      models.JobStatusInProgress,
+		Steps:       models.InitializeSteps(enabled),
+		Config: models.ProjectConfig{
+			JobsDir:  jobsDir,
+			Pipeline: models.PipelineConfig{EnabledSteps: enabled},
+			Services: models.ServiceConfig{
+				TORCH: models.TORCHConfig{BaseURL: "http://laptop-torch", Username: "u", Password: "secret"},
+				DIMP:  models.DIMPConfig{URL: "http://laptop-dimp"},
+			},
+		},
+	}
+	job.Steps[0].Status = models.StepStatusCompleted
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+
+	jobDir := services.GetJobDir(jobsDir, job.JobID)
+	for _, dir := range []string{"import", "pseudonymized"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(jobDir, dir), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(jobDir, dir, "Patient.ndjson"), []byte("{\"resourceType\":\"Patient\",\"id\":\""+dir+"\"}\n"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(jobDir, services.EventsFileName), []byte("{}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(jobDir, ".lock"), []byte("pid"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(jobDir, services.HeartbeatFileName), []byte("now"), 0644))
+	return job
+}
+
+// TestJobArchive_RoundTrip verifies export and import between two jobs directories
+func TestJobArchive_RoundTrip(t *testing.T) {
+	sourceDir := t.TempDir()
+	job := createArchiveTestJob(t, sourceDir)
+
+	var archive bytes.Buffer
+	require.NoError(t, services.ExportJobArchive(sourceDir, job.JobID, nil, services.ArchiveZstd, &archive))
+	assert.True(t, bytes.HasPrefix(archive.Bytes(), []byte{0x28, 0xb5, 0x2f, 0xfd}), "zstd frame")
+
+	targetDir := t.TempDir()
+	targetConfig := models.ProjectConfig{
+		Services: models.ServiceConfig{DIMP: models.DIMPConfig{URL: "http://server-dimp"}},
+	}
+	imported, err := services.ImportJobArchive(targetDir, bytes.NewReader(archive.Bytes()), targetConfig)
+	require.NoError(t, err)
+
+	assert.Equal(t, job.JobID, imported.JobID)
+	assert.Equal(t, targetDir, imported.Config.JobsDir)
+	assert.Equal(t, "http://server-dimp", imported.Config.Services.DIMP.URL)
+
+	loaded, err := services.LoadJobState(targetDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusInProgress, loaded.Status)
+	assert.Equal(t, targetDir, loaded.Config.JobsDir)
+	assert.Empty(t, loaded.Config.Services.TORCH.Password)
+
+	jobDir := services.GetJobDir(targetDir, job.JobID)
+	assert.FileExists(t, filepath.Join(jobDir, "import", "Patient.ndjson"))
+	assert.FileExists(t, filepath.Join(jobDir, "pseudonymized", "Patient.ndjson"))
+	assert.FileExists(t, filepath.Join(jobDir, services.EventsFileName))
+	assert.NoFileExists(t, filepath.Join(jobDir, ".lock"))
+	assert.NoFileExists(t, filepath.Join(jobDir, services.HeartbeatFileName))
+
+	// No staging directories are left behind
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Importing the same job again fails
+	_, err = services.ImportJobArchive(targetDir, bytes.NewReader(archive.Bytes()), targetConfig)
+	assert.ErrorContains(t, err, "already exists")
+}
+
+// TestJobArchive_IncludeSelectsDataDirs verifies that only the selected output directories are archived
+func TestJobArchive_IncludeSelectsDataDirs(t *testing.T) {
+	sourceDir := t.TempDir()
+	job := createArchiveTestJob(t, sourceDir)
+
+	var archive bytes.Buffer
+	require.NoError(t, services.ExportJobArchive(sourceDir, job.JobID, []string{"pseudonymized"}, services.ArchiveZstd, &archive))
+
+	targetDir := t.TempDir()
+	_, err := services.ImportJobArchive(targetDir, &archive, models.ProjectConfig{})
+	require.NoError(t, err)
+
+	jobDir := services.GetJobDir(targetDir, job.JobID)
+	assert.NoDirExists(t, filepath.Join(jobDir, "import"))
+	assert.FileExists(t, filepath.Join(jobDir, "pseudonymized", "Patient.ndjson"))
+
+	err = services.ExportJobArchive(sourceDir, job.JobID, []string{"secrets"}, services.ArchiveZstd, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "unknown job output directory")
+}
+
+// TestJobArchive_Gzip verifies .tar.gz names select gzip and that imports detect it from the content
+func TestJobArchive_Gzip(t *testing.T) {
+	assert.Equal(t, services.ArchiveZstd, services.ArchiveCompressionForPath("abc.tar.zst"))
+	assert.Equal(t, services.ArchiveZstd, services.ArchiveCompressionForPath("abc.archive"))
+	assert.Equal(t, services.ArchiveGzip, services.ArchiveCompressionForPath("abc.tar.gz"))
+	assert.Equal(t, services.ArchiveGzip, services.ArchiveCompressionForPath("abc.tgz"))
+
+	sourceDir := t.TempDir()
+	job := createArchiveTestJob(t, sourceDir)
+	var archive bytes.Buffer
+	require.NoError(t, services.ExportJobArchive(sourceDir, job.JobID, nil, services.ArchiveGzip, &archive))
+	assert.True(t, bytes.HasPrefix(archive.Bytes(), []byte{0x1f, 0x8b}), "gzip stream")
+
+	targetDir := t.TempDir()
+	_, err := services.ImportJobArchive(targetDir, &archive, models.ProjectConfig{})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(services.GetJobDir(targetDir, job.JobID), "pseudonymized", "Patient.ndjson"))
+}
+
+// TestJobArchive_RejectsInvalidArchive verifies that garbage input leaves no job behind
+func TestJobArchive_RejectsInvalidArchive(t *testing.T) {
+	targetDir := t.TempDir()
+	_, err := services.ImportJobArchive(targetDir, bytes.NewReader([]byte("not an archive")), models.ProjectConfig{})
+	assert.ErrorContains(t, err, "expected .tar.zst or .tar.gz")
+
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
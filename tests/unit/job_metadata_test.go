@@ -0,0 +1,310 @@
+package unit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const sensitiveSourceURL = "https://fhir.example.org/Patient?identifier=MRN-4711"
+
+// jobWithSensitiveMetadata returns an HTTP import job whose sources and error messages mention a search URL
+func jobWithSensitiveMetadata(jobsDir string, mode models.JobMetadataMode) *models.PipelineJob {
+	now := time.Now()
+	return &models.PipelineJob{
+		JobID:              uuid.New().String(),
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		InputSource:        sensitiveSourceURL,
+		InputType:          models.InputTypeHTTP,
+		TORCHExtractionURL: "https://torch.example.org/fhir/__status/cohort-cardiology",
+		ExtraSources:       []models.InputSource{{Source: "/data/exports/cardiology-cohort", Type: models.InputTypeLocal}},
+		ImportedFiles:      []models.FHIRDataFile{{FileName: "Patient.ndjson", Source: sensitiveSourceURL}},
+		CurrentStep:        string(models.StepHttpImport),
+		Status:             models.JobStatusFailed,
+		ErrorMessage:       "download failed: " + sensitiveSourceURL,
+		Steps: []models.PipelineStep{{
+			Name:   models.StepHttpImport,
+			Status: models.StepStatusFailed,
+			LastError: &models.StepError{
+				Type:    models.ErrorTypeTransient,
+				Message: "GET " + sensitiveSourceURL + " returned 503",
+				Context: []string{"level=INFO msg=\"Downloading from URL\" source=" + sensitiveSourceURL},
+			},
+		}},
+		Config: models.ProjectConfig{
+			Pipeline:    models.PipelineConfig{EnabledSteps: []models.StepName{models.StepHttpImport}},
+			Retry:       models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+			JobMetadata: models.JobMetadataConfig{Mode: mode},
+			JobsDir:     jobsDir,
+		},
+	}
+}
+
+// TestJobMetadata_HashPersistsNoRawSources verifies state.json only contains hashes and the in-memory job is untouched
+func TestJobMetadata_HashPersistsNoRawSources(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := jobWithSensitiveMetadata(jobsDir, models.JobMetadataHash)
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+
+	data, err := os.ReadFile(services.GetStateFilePath(jobsDir, job.JobID))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "MRN-4711")
+	assert.NotContains(t, string(data), "cohort-cardiology")
+	assert.NotContains(t, string(data), "cardiology-cohort")
+
+	assert.Equal(t, sensitiveSourceURL, job.InputSource, "the running job keeps the raw source")
+	assert.Contains(t, job.Steps[0].LastError.Message, sensitiveSourceURL)
+
+	loaded, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err, "redacted HTTP sources must pass validation")
+	assert.NotContains(t, string(data), loaded.Config.JobMetadata.HashKey, "the hash key is not persisted")
+	hashed := loaded.Config.JobMetadata.Hash(sensitiveSourceURL)
+	assert.Equal(t, hashed, loaded.InputSource)
+	assert.Equal(t, hashed, loaded.ImportedFiles[0].Source)
+	assert.Equal(t, loaded.Config.JobMetadata.Hash("/data/exports/cardiology-cohort"), loaded.ExtraSources[0].Source)
+	assert.Equal(t, "download failed: "+hashed, loaded.ErrorMessage)
+	assert.Equal(t, "GET "+hashed+" returned 503", loaded.Steps[0].LastError.Message)
+	assert.Contains(t, loaded.Steps[0].LastError.Context[0], hashed)
+
+	// Saving the reloaded job again is stable
+	require.NoError(t, services.SaveJobState(jobsDir, loaded))
+	reloaded, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, hashed, reloaded.InputSource)
+}
+
+// TestJobMetadata_OmitUsesPlaceholder verifies omit mode persists a placeholder
+func TestJobMetadata_OmitUsesPlaceholder(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := jobWithSensitiveMetadata(jobsDir, models.JobMetadataOmit)
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+
+	loaded, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OmittedJobMetadata, loaded.InputSource)
+	assert.Equal(t, models.OmittedJobMetadata, loaded.TORCHExtractionURL)
+	assert.Equal(t, "download failed: "+models.OmittedJobMetadata, loaded.ErrorMessage)
+}
+
+// TestJobMetadata_KeepIsDefault verifies metadata is persisted unchanged without a policy
+func TestJobMetadata_KeepIsDefault(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := jobWithSensitiveMetadata(jobsDir, "")
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+
+	loaded, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, sensitiveSourceURL, loaded.InputSource)
+	assert.Equal(t, job.ErrorMessage, loaded.ErrorMessage)
+}
+
+// TestJobMetadata_EventsAreRedacted verifies the timeline does not record raw sources
+func TestJobMetadata_EventsAreRedacted(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeDIMPNDJSON(t, filepath.Join(sourceDir, "Patient.ndjson"), []map[string]any{{"resourceType": "Patient", "id": "p1"}})
+
+	jobsDir := t.TempDir()
+	config := models.ProjectConfig{
+		Pipeline:    models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+		Retry:       models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		JobMetadata: models.JobMetadataConfig{Mode: models.JobMetadataHash},
+		JobsDir:     jobsDir,
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+	job, err := pipeline.CreateJob(sourceDir, config, logger)
+	require.NoError(t, err)
+
+	_, err = pipeline.ExecuteImportStep(context.Background(), job, logger, nil, lib.NoProgress)
+	require.NoError(t, err)
+
+	events, err := os.ReadFile(filepath.Join(services.GetJobDir(jobsDir, job.JobID), services.EventsFileName))
+	require.NoError(t, err)
+	assert.NotContains(t, string(events), sourceDir)
+	assert.Contains(t, string(events), job.Config.JobMetadata.Hash(sourceDir))
+}
+
+// TestJobMetadata_RedactedSourceCannotBeReimported verifies a reloaded job with a redacted source fails clearly
+func TestJobMetadata_RedactedSourceCannotBeReimported(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := &models.PipelineJob{
+		JobID:       uuid.New().String(),
+		InputSource: models.JobMetadataConfig{HashKey: "test-key-0123456789"}.Hash("/data/exports/cohort"),
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepLocalImport),
+		Status:      models.JobStatusInProgress,
+		Steps:       models.InitializeSteps([]models.StepName{models.StepLocalImport}),
+		Config: models.ProjectConfig{
+			Pipeline:    models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+			Retry:       models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+			JobMetadata: models.JobMetadataConfig{Mode: models.JobMetadataHash},
+			JobsDir:     jobsDir,
+		},
+	}
+
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, lib.NewLogger(lib.LogLevelError), nil, lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "start a new job")
+	step, found := models.GetStepByName(*updatedJob, models.StepLocalImport)
+	require.True(t, found)
+	require.NotNil(t, step.LastError)
+	assert.Equal(t, models.ErrorTypeNonTransient, step.LastError.Type)
+}
+
+// TestDropWatcher_SkipsHashedJobSources verifies filesets of jobs persisted with hashed sources are not reported again
+func TestDropWatcher_SkipsHashedJobSources(t *testing.T) {
+	dropDir := t.TempDir()
+	jobsDir := t.TempDir()
+	export := filepath.Join(dropDir, "export-001")
+	require.NoError(t, os.MkdirAll(export, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(export, "Patient.ndjson"), []byte("{}\n"), 0644))
+
+	config := models.ProjectConfig{
+		Pipeline:    models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+		Retry:       models.RetryConfig{MaxAttempts: 3, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		JobMetadata: models.JobMetadataConfig{Mode: models.JobMetadataHash},
+		JobsDir:     jobsDir,
+	}
+	_, err := pipeline.CreateJob(export, config, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+
+	require.NoError(t, services.EnsureJobMetadataKey(&config.JobMetadata, jobsDir))
+	processed, err := services.ProcessedJobSources(jobsDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.JobMetadata.Hash(export)}, processed)
+
+	watcher, err := services.NewDropWatcher(dropDir, 0, config.JobMetadata)
+	require.NoError(t, err)
+	for _, source := range processed {
+		watcher.MarkProcessed(source)
+	}
+
+	now := time.Now()
+	_, err = watcher.Poll(now)
+	require.NoError(t, err)
+	ready, err := watcher.Poll(now)
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+}
+
+// TestJobMetadataConfig_Validate verifies mode validation and config loading
+func TestJobMetadataConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.JobMetadataConfig{}.Validate())
+	assert.NoError(t, models.JobMetadataConfig{Mode: models.JobMetadataOmit}.Validate())
+	assert.Error(t, models.JobMetadataConfig{Mode: "encrypt"}.Validate())
+	assert.Error(t, models.JobMetadataConfig{Mode: models.JobMetadataHash, HashKey: "short"}.Validate())
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+job_metadata:
+  mode: hash
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobMetadataHash, config.JobMetadata.Mode)
+	key, err := services.LoadJobMetadataKey(filepath.Join(tmpDir, "jobs"))
+	require.NoError(t, err)
+	assert.Equal(t, key, config.JobMetadata.HashKey, "without hash_key the generated key is used")
+}
+
+// TestJobMetadata_HashIsKeyed verifies hashes depend on the key, so they cannot be recomputed from guessed sources
+func TestJobMetadata_HashIsKeyed(t *testing.T) {
+	policy := models.JobMetadataConfig{Mode: models.JobMetadataHash, HashKey: "installation-key-a"}
+	other := models.JobMetadataConfig{Mode: models.JobMetadataHash, HashKey: "installation-key-b"}
+
+	hashed := policy.Redact(sensitiveSourceURL)
+	assert.Equal(t, hashed, policy.Redact(sensitiveSourceURL), "equal sources stay comparable")
+	assert.NotEqual(t, hashed, other.Redact(sensitiveSourceURL))
+	assert.True(t, models.IsRedactedJobMetadata(hashed))
+	assert.Equal(t, "hmac-sha256:"+hmacSHA256Hex(t, "installation-key-a", sensitiveSourceURL), hashed)
+
+	// Values hashed by earlier versions are recognized and not hashed again
+	legacy := "sha256:5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"
+	assert.Equal(t, legacy, policy.Redact(legacy))
+}
+
+// TestJobMetadata_GeneratesKeyOnce verifies hash mode without hash_key generates one key file per jobs_dir
+func TestJobMetadata_GeneratesKeyOnce(t *testing.T) {
+	jobsDir := t.TempDir()
+	first := jobWithSensitiveMetadata(jobsDir, models.JobMetadataHash)
+	second := jobWithSensitiveMetadata(jobsDir, models.JobMetadataHash)
+	require.NoError(t, services.SaveJobState(jobsDir, first))
+	require.NoError(t, services.SaveJobState(jobsDir, second))
+
+	info, err := os.Stat(filepath.Join(jobsDir, services.JobMetadataKeyFileName))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	key, err := services.LoadJobMetadataKey(jobsDir)
+	require.NoError(t, err)
+	assert.Equal(t, key, first.Config.JobMetadata.HashKey)
+
+	loadedFirst, err := services.LoadJobState(jobsDir, first.JobID)
+	require.NoError(t, err)
+	loadedSecond, err := services.LoadJobState(jobsDir, second.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, loadedFirst.InputSource, loadedSecond.InputSource)
+
+	// Another installation hashes the same source differently
+	otherDir := t.TempDir()
+	other := jobWithSensitiveMetadata(otherDir, models.JobMetadataHash)
+	require.NoError(t, services.SaveJobState(otherDir, other))
+	loadedOther, err := services.LoadJobState(otherDir, other.JobID)
+	require.NoError(t, err)
+	assert.NotEqual(t, loadedFirst.InputSource, loadedOther.InputSource)
+}
+
+// TestJobMetadata_ConfiguredHashKey verifies job_metadata.hash_key is used instead of a generated key
+func TestJobMetadata_ConfiguredHashKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	jobsDir := filepath.Join(tmpDir, "jobs")
+	t.Setenv("TEST_JOB_METADATA_KEY", "configured-key-0123456789")
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+job_metadata:
+  mode: hash
+  hash_key: "${TEST_JOB_METADATA_KEY}"
+
+jobs_dir: "` + jobsDir + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "configured-key-0123456789", config.JobMetadata.HashKey)
+	assert.NoFileExists(t, filepath.Join(jobsDir, services.JobMetadataKeyFileName))
+}
+
+// hmacSHA256Hex computes an HMAC-SHA256 independently of the code under test
+func hmacSHA256Hex(t *testing.T, key string, value string) string {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func writeJobTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "nightly.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// TestLoadJobTemplate verifies parsing, path resolution against the template directory and the digest
+func TestLoadJobTemplate(t *testing.T) {
+	path := writeJobTemplate(t, `
+input_type: crtdl_file
+crtdl: queries/diabetes.crtdl
+inputs: [https://fhir.example.org/Patient.ndjson]
+enabled_steps: [import, dimp]
+labels:
+  schedule: nightly
+notifications:
+  webhook_url: https://chat.example.org/hooks/aether
+`)
+
+	template, err := services.LoadJobTemplate(path)
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", template.Name, "the name defaults to the file name")
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "queries", "diabetes.crtdl"), template.CRTDL)
+	assert.Equal(t, []string{template.CRTDL, "https://fhir.example.org/Patient.ndjson"}, template.Sources())
+	assert.Equal(t, map[string]string{"schedule": "nightly"}, template.Labels)
+	assert.Equal(t, path, template.Path)
+	assert.Len(t, template.SHA256, 64)
+
+	_, err = services.LoadJobTemplate(writeJobTemplate(t, "crtdl: q.crtdl\nenabled_step: [dimp]\n"))
+	assert.ErrorContains(t, err, "enabled_step", "unknown keys are rejected")
+	_, err = services.LoadJobTemplate(writeJobTemplate(t, "input_type: local_directory\ncrtdl: q.crtdl\n"))
+	assert.ErrorContains(t, err, "input_type")
+}
+
+// TestJobTemplate_Validate verifies input types, steps, labels and webhook templates are checked
+func TestJobTemplate_Validate(t *testing.T) {
+	assert.NoError(t, models.JobTemplate{Name: "t", EnabledSteps: []models.StepName{"import", models.StepDIMP}}.Validate())
+	assert.Error(t, models.JobTemplate{}.Validate(), "a name is required")
+	assert.Error(t, models.JobTemplate{Name: "t", InputType: "ftp"}.Validate())
+	assert.ErrorContains(t, models.JobTemplate{Name: "t", EnabledSteps: []models.StepName{"pseudonymize"}}.Validate(), "pseudonymize")
+	assert.ErrorContains(t, models.JobTemplate{Name: "t", Labels: map[string]string{"has space": "x"}}.Validate(), "label")
+	assert.ErrorContains(t, models.JobTemplate{Name: "t", Notifications: models.TemplateNotifications{WebhookTemplate: "{{"}}.Validate(), "webhook_template")
+}
+
+// TestJobTemplate_Apply verifies steps (with aliases) and notifications replace the configuration's
+func TestJobTemplate_Apply(t *testing.T) {
+	config := models.ProjectConfig{
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+		SLA:      models.SLAConfig{WebhookURL: "https://old.example.org"},
+	}
+	template := models.JobTemplate{
+		Name:          "t",
+		EnabledSteps:  []models.StepName{"import", models.StepDIMP},
+		Notifications: models.TemplateNotifications{WebhookURL: "https://new.example.org"},
+	}
+
+	require.NoError(t, template.Apply(&config))
+	assert.Equal(t, []models.StepName{models.StepLocalImport, models.StepHttpImport, models.StepDIMP}, config.Pipeline.EnabledSteps, "without TORCH, import does not enable torch")
+	assert.Equal(t, "https://new.example.org", config.SLA.WebhookURL)
+
+	template.EnabledSteps = []models.StepName{models.StepDIMP, models.StepDIMP}
+	assert.Error(t, template.Apply(&config))
+}
+
+// TestCreateTemplateJob verifies the template and its labels are stored with the job and the input type is enforced
+func TestCreateTemplateJob(t *testing.T) {
+	inputDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, "Patient.ndjson"), []byte("{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n"), 0644))
+
+	config := models.ProjectConfig{
+		JobsDir:  t.TempDir(),
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+	template := &models.JobTemplate{Name: "nightly", InputType: models.InputTypeLocal, Inputs: []string{inputDir}, Labels: map[string]string{"schedule": "nightly"}}
+
+	job, err := pipeline.CreateTemplateJob(template.Sources(), template, config, logger)
+	require.NoError(t, err)
+
+	loaded, err := pipeline.LoadJob(config.JobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"schedule": "nightly"}, loaded.Labels)
+	require.NotNil(t, loaded.Template)
+	assert.Equal(t, "nightly", loaded.Template.Name)
+	assert.Equal(t, []string{inputDir}, loaded.Template.Inputs)
+
+	template.InputType = models.InputTypeCRTDL
+	_, err = pipeline.CreateTemplateJob(template.Sources(), template, config, logger)
+	assert.ErrorContains(t, err, "expects crtdl_file")
+
+	redacted := models.JobMetadataConfig{Mode: models.JobMetadataOmit}.RedactJob(*loaded)
+	assert.Equal(t, []string{models.OmittedJobMetadata}, redacted.Template.Inputs)
+	assert.Equal(t, []string{inputDir}, loaded.Template.Inputs, "the job itself is not modified")
+}
@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+)
+
+const encodingTestContent = "{\"resourceType\":\"Patient\",\"id\":\"p1\",\"name\":\"Müller 𝄞\"}\n{\"resourceType\":\"Patient\",\"id\":\"p2\"}\n"
+
+func writeEncodingTestFile(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "patients.ndjson")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func encodeUTF16(s string, bigEndian bool, withBOM bool) []byte {
+	var out []byte
+	units := utf16.Encode([]rune(s))
+	if withBOM {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	for _, u := range units {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+// TestNormalizeNDJSONEncoding_CleanFileUntouched verifies clean UTF-8 files are not rewritten
+func TestNormalizeNDJSONEncoding_CleanFileUntouched(t *testing.T) {
+	path := writeEncodingTestFile(t, []byte(encodingTestContent))
+
+	result, err := lib.NormalizeNDJSONEncoding(path)
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.Equal(t, lib.EncodingUTF8, result.Encoding)
+}
+
+// TestNormalizeNDJSONEncoding verifies BOMs, UTF-16 and Windows line endings are normalized
+func TestNormalizeNDJSONEncoding(t *testing.T) {
+	crlf := "{\"resourceType\":\"Patient\",\"id\":\"p1\",\"name\":\"Müller 𝄞\"}\r\n{\"resourceType\":\"Patient\",\"id\":\"p2\"}\r\n"
+
+	testCases := []struct {
+		name     string
+		input    []byte
+		encoding lib.TextEncoding
+	}{
+		{"UTF-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, encodingTestContent...), lib.EncodingUTF8BOM},
+		{"CRLF line endings", []byte(crlf), lib.EncodingUTF8},
+		{"Lone CR line endings", []byte("{\"resourceType\":\"Patient\",\"id\":\"p1\",\"name\":\"Müller 𝄞\"}\r{\"resourceType\":\"Patient\",\"id\":\"p2\"}\r"), lib.EncodingUTF8},
+		{"UTF-16LE with BOM and CRLF", encodeUTF16(crlf, false, true), lib.EncodingUTF16LE},
+		{"UTF-16BE with BOM", encodeUTF16(encodingTestContent, true, true), lib.EncodingUTF16BE},
+		{"UTF-16LE without BOM", encodeUTF16(encodingTestContent, false, false), lib.EncodingUTF16LE},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeEncodingTestFile(t, tc.input)
+
+			result, err := lib.NormalizeNDJSONEncoding(path)
+			require.NoError(t, err)
+			assert.True(t, result.Changed)
+			assert.Equal(t, tc.encoding, result.Encoding)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, encodingTestContent, string(data))
+			assert.Equal(t, int64(len(encodingTestContent)), result.NewSize)
+			assert.NoFileExists(t, path+".part")
+		})
+	}
+}
+
+// TestNormalizeNDJSONEncoding_TruncatedUTF16 verifies odd-length UTF-16 input is rejected
+func TestNormalizeNDJSONEncoding_TruncatedUTF16(t *testing.T) {
+	data := encodeUTF16(encodingTestContent, false, true)
+	path := writeEncodingTestFile(t, data[:len(data)-1])
+
+	_, err := lib.NormalizeNDJSONEncoding(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "truncated UTF-16")
+	assert.NoFileExists(t, path+".part")
+}
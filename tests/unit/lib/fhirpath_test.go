@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+)
+
+func fhirPathTestPatient() map[string]any {
+	return map[string]any{
+		"resourceType": "Patient",
+		"id":           "p1",
+		"gender":       "female",
+		"birthDate":    "2012-05-01",
+		"active":       true,
+		"name": []any{
+			map[string]any{"use": "official", "family": "Smith", "given": []any{"Anna", "Maria"}},
+			map[string]any{"use": "nickname", "given": []any{"Annie"}},
+		},
+	}
+}
+
+// TestFHIRPath_Evaluate verifies path navigation, functions and operators
+func TestFHIRPath_Evaluate(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   []any
+	}{
+		{"Patient.gender", []any{"female"}},
+		{"gender", []any{"female"}},
+		{"name.given", []any{"Anna", "Maria", "Annie"}},
+		{"name.where(use = 'official').family", []any{"Smith"}},
+		{"name.given.count()", []any{float64(3)}},
+		{"name.given.first()", []any{"Anna"}},
+		{"deceasedBoolean.exists()", []any{false}},
+		{"telecom.empty()", []any{true}},
+		{"birthDate > @2010-01-01", []any{true}},
+		{"birthDate <= '2010-01-01'", []any{false}},
+		{"gender = 'female' and active = true", []any{true}},
+		{"gender = 'male' or (active and birthDate >= @2012-05-01)", []any{true}},
+		{"name.exists(use = 'nickname')", []any{true}},
+		{"active.not()", []any{false}},
+		{"name.given.count() >= 3", []any{true}},
+		{"deceasedBoolean = true", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			path, err := lib.CompileFHIRPath(tc.expression)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, path.Evaluate(fhirPathTestPatient()))
+		})
+	}
+}
+
+// TestFHIRPath_CompileErrors verifies malformed expressions are rejected
+func TestFHIRPath_CompileErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"name.",
+		"gender = 'female",
+		"name.where()",
+		"name.resolve()",
+		"(gender = 'female'",
+		"gender ! 'x'",
+	} {
+		_, err := lib.CompileFHIRPath(expression)
+		assert.Error(t, err, "expression %q should not compile", expression)
+	}
+}
+
+// TestResourceFilter_Matches verifies type, id and FHIRPath filters are combined
+func TestResourceFilter_Matches(t *testing.T) {
+	where, err := lib.CompileFHIRPath("birthDate > @2010-01-01")
+	require.NoError(t, err)
+
+	patient := fhirPathTestPatient()
+	assert.True(t, lib.ResourceFilter{}.Matches(patient))
+	assert.True(t, lib.ResourceFilter{ResourceType: "Patient", ID: "p1", Where: where}.Matches(patient))
+	assert.False(t, lib.ResourceFilter{ResourceType: "Observation"}.Matches(patient))
+	assert.False(t, lib.ResourceFilter{ID: "p2"}.Matches(patient))
+
+	patient["birthDate"] = "1990-01-01"
+	assert.False(t, lib.ResourceFilter{Where: where}.Matches(patient))
+}
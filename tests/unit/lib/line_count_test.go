@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+)
+
+func TestCountResources_SkipsBlankLines(t *testing.T) {
+	for content, expected := range map[string]int{
+		"":                                  0,
+		"\n\n":                              0,
+		"{\"a\":1}\n{\"a\":2}\n":            2,
+		"{\"a\":1}\n  \n\r\n{\"a\":2}":      2, // unterminated last line
+		"{\"a\":1}\r\n{\"a\":2}\r\n":        2,
+		"not json is still a line\n":        1,
+		strings.Repeat("x", 3<<20) + "\n{}": 2, // lines longer than the read buffer
+	} {
+		count, err := lib.CountResources(strings.NewReader(content))
+		require.NoError(t, err)
+		assert.Equal(t, expected, count, "%.20q", content)
+	}
+}
+
+func TestLineCounter_CountsAcrossWrites(t *testing.T) {
+	var counter lib.LineCounter
+	for _, chunk := range []string{"{\"a\"", ":1}\n", "\n ", " \n{\"a\":2", "}\n{"} {
+		n, err := counter.Write([]byte(chunk))
+		require.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.Equal(t, 3, counter.Count())
+}
+
+func TestCountResourcesInFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Patient.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("{\"resourceType\":\"Patient\"}\n\n{\"resourceType\":\"Patient\"}\n"), 0644))
+
+	count, err := lib.CountResourcesInFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = lib.CountResourcesInFile(filepath.Join(t.TempDir(), "missing.ndjson"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+)
+
+// TestLogger_LinesSince verifies history since a mark is returned, including suppressed levels
+func TestLogger_LinesSince(t *testing.T) {
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	logger.Info("before mark")
+	mark := logger.Mark()
+	logger.Debug("downloading", "file", "a.ndjson")
+	logger.Error("download failed")
+
+	lines := logger.LinesSince(mark, 10)
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "[DEBUG] downloading | [file a.ndjson]")
+	assert.Contains(t, lines[1], "[ERROR] download failed")
+
+	// max keeps only the most recent lines
+	lines = logger.LinesSince(mark, 1)
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "download failed")
+
+	assert.Empty(t, logger.LinesSince(logger.Mark(), 10))
+}
+
+// TestLogger_LinesSince_Evicted verifies the history is bounded
+func TestLogger_LinesSince_Evicted(t *testing.T) {
+	logger := lib.NewLogger(lib.LogLevelError)
+	mark := logger.Mark()
+
+	for i := 0; i < 1000; i++ {
+		logger.Debug(fmt.Sprintf("line %d", i))
+	}
+
+	lines := logger.LinesSince(mark, 0)
+	assert.Less(t, len(lines), 1000)
+	assert.Contains(t, lines[len(lines)-1], "line 999")
+}
+
+// TestLogSampler_Allow verifies the first messages are logged, then every Kth, counted per message
+func TestLogSampler_Allow(t *testing.T) {
+	logger := lib.NewLogger(lib.LogLevelError)
+	sampler := lib.NewLogSampler(logger, 3, 5)
+	mark := logger.Mark()
+
+	var allowed []int
+	for i := 1; i <= 20; i++ {
+		if sampler.Allow("Sending resource to DIMP") {
+			allowed = append(allowed, i)
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3, 8, 13, 18}, allowed)
+
+	lines := logger.LinesSince(mark, 10)
+	require.Len(t, lines, 1, "sampling is announced once")
+	assert.Contains(t, lines[0], "Debug message sampled from now on")
+	assert.Contains(t, lines[0], "Sending resource to DIMP")
+
+	assert.True(t, sampler.Allow("Service call"), "other messages are counted separately")
+}
+
+// TestLogSampler_Unsampled verifies a nil sampler and every <= 1 log all messages
+func TestLogSampler_Unsampled(t *testing.T) {
+	var nilSampler *lib.LogSampler
+	all := lib.NewLogSampler(lib.NewLogger(lib.LogLevelError), 0, 1)
+	for i := 0; i < 10; i++ {
+		assert.True(t, nilSampler.Allow("Service call"))
+		assert.True(t, all.Allow("Service call"))
+	}
+}
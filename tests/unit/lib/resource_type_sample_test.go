@@ -0,0 +1,47 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+)
+
+func TestSampleResourceTypes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	mixed := write("mixed.ndjson", "{\"resourceType\":\"Patient\"}\n\n{\"resourceType\":\"Encounter\"}\n{\"resourceType\":\"Patient\"}\n{\"resourceType\":\"Condition\"}\n")
+	types, err := lib.SampleResourceTypes(mixed, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Condition", "Encounter", "Patient"}, types)
+
+	types, err = lib.SampleResourceTypes(mixed, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Encounter", "Patient"}, types, "only the first resources are sampled")
+
+	large := write("large.ndjson", "{\"resourceType\":\"Bundle\",\"data\":\""+strings.Repeat("x", 3<<20)+"\"}\n")
+	types, err = lib.SampleResourceTypes(large, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bundle"}, types)
+
+	truncated := write("truncated.ndjson", "{\"resourceType\":\"Patient\"}\n{\"resourceType\":")
+	types, err = lib.SampleResourceTypes(truncated, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Patient"}, types)
+
+	_, err = lib.SampleResourceTypes(write("garbage.ndjson", "not json\n"), 100)
+	assert.Error(t, err)
+
+	types, err = lib.SampleResourceTypes(write("empty.ndjson", ""), 100)
+	require.NoError(t, err)
+	assert.Empty(t, types)
+}
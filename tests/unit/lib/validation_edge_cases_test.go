@@ -615,3 +615,24 @@ func TestDetectInputType_Integration(t *testing.T) {
 		assert.Equal(t, models.InputTypeLocal, inputType) // Falls back to local
 	})
 }
+
+func TestReadCRTDLAnnotations(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	annotations, err := lib.ReadCRTDLAnnotations(write("named.crtdl",
+		`{"display": "", "cohortDefinition": {"display": " Cardiology ", "version": "2024-03", "inclusionCriteria": [[]]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &models.JobAnnotations{Display: "Cardiology", Version: "2024-03"}, annotations)
+
+	annotations, err = lib.ReadCRTDLAnnotations(write("unnamed.crtdl", `{"cohortDefinition": {"display": 42, "inclusionCriteria": [[]]}}`))
+	require.NoError(t, err)
+	assert.Nil(t, annotations)
+
+	_, err = lib.ReadCRTDLAnnotations(write("broken.crtdl", `{`))
+	assert.Error(t, err)
+}
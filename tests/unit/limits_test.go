@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestLimitsConfig_Defaults verifies zero values fall back to defaults/unlimited
+func TestLimitsConfig_Defaults(t *testing.T) {
+	limits := models.LimitsConfig{}
+
+	assert.Equal(t, models.DefaultMaxLineSizeMB*1024*1024, limits.GetMaxLineBytes())
+	assert.NoError(t, limits.CheckFileCount(100000))
+	assert.NoError(t, limits.CheckBundleEntries(100000))
+	assert.NoError(t, limits.Validate())
+
+	assert.Error(t, models.LimitsConfig{MaxFiles: -1}.Validate())
+}
+
+// TestExecuteImportStep_MaxFilesExceeded verifies local imports fail before copying when too many files are found
+func TestExecuteImportStep_MaxFilesExceeded(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, name := range []string{"a.ndjson", "b.ndjson", "c.ndjson"} {
+		require.NoError(t, os.WriteFile(filepath.Join(sourceDir, name), []byte(`{"resourceType":"Patient","id":"1"}`+"\n"), 0644))
+	}
+
+	jobsDir := t.TempDir()
+	logger := lib.NewLogger(lib.LogLevelError)
+	retryConfig := models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000}
+	httpClient := services.NewHTTPClient(5*time.Second, retryConfig, logger)
+
+	job := &models.PipelineJob{
+		JobID:       "test-limits-job",
+		InputSource: sourceDir,
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepLocalImport),
+		Status:      models.JobStatusInProgress,
+		Steps:       models.InitializeSteps([]models.StepName{models.StepLocalImport}),
+		Config: models.ProjectConfig{
+			JobsDir:  jobsDir,
+			Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+			Retry:    retryConfig,
+			Limits:   models.LimitsConfig{MaxFiles: 2},
+		},
+	}
+
+	_, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limits.max_files")
+
+	importDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepLocalImport)
+	copied, _ := filepath.Glob(filepath.Join(importDir, "*.ndjson"))
+	assert.Empty(t, copied, "No files should be copied when the limit is exceeded")
+}
+
+// TestExecuteDIMPStep_MaxLineSizeExceeded verifies an over-long line fails with a clear error
+func TestExecuteDIMPStep_MaxLineSizeExceeded(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.Limits.MaxLineSizeMB = 1
+	logger := createDIMPTestLogger()
+
+	importDir := filepath.Join(tmpDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	hugeLine := `{"resourceType":"Patient","id":"p2","text":"` + strings.Repeat("x", 2*1024*1024) + `"}`
+	content := `{"resourceType":"Patient","id":"p1"}` + "\n" + hugeLine + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(importDir, "huge.ndjson"), []byte(content), 0644))
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2 of huge.ndjson exceeds limits.max_line_size_mb (1 MB)")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "pseudonymized", "dimped_huge.ndjson"))
+}
+
+// TestExecuteDIMPStep_MaxBundleEntriesExceeded verifies Bundles with too many entries are rejected
+func TestExecuteDIMPStep_MaxBundleEntriesExceeded(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.Limits.MaxBundleEntries = 2
+	logger := createDIMPTestLogger()
+
+	importDir := filepath.Join(tmpDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	entries := []any{}
+	for i := 0; i < 3; i++ {
+		entries = append(entries, map[string]any{"resource": map[string]any{"resourceType": "Patient"}})
+	}
+	writeDIMPNDJSON(t, filepath.Join(importDir, "bundles.ndjson"), []map[string]any{
+		{"resourceType": "Bundle", "id": "b1", "type": "collection", "entry": entries},
+	})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bundle has 3 entries, exceeding limits.max_bundle_entries (2)")
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// createLineageJob creates a job with the given steps completed
+func createLineageJob(t *testing.T, config models.ProjectConfig, sources []string, parents []string, completed ...models.StepName) *models.PipelineJob {
+	t.Helper()
+	job, err := pipeline.CreateDerivedJob(sources, parents, config, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	for _, name := range completed {
+		step, found := models.GetStepByName(*job, name)
+		require.True(t, found)
+		updated := models.ReplaceStep(*job, models.CompleteStep(step, 1, 10))
+		job = &updated
+	}
+	if len(completed) > 0 {
+		job = pipeline.CompleteJob(job)
+	}
+	require.NoError(t, services.SaveJobState(config.JobsDir, job))
+	return job
+}
+
+func lineageConfig(t *testing.T) models.ProjectConfig {
+	return models.ProjectConfig{
+		JobsDir:  t.TempDir(),
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport, models.StepDIMP}},
+	}
+}
+
+// TestParentJobSources_UsesLatestFHIROutput verifies the output of the last completed FHIR step is used
+func TestParentJobSources_UsesLatestFHIROutput(t *testing.T) {
+	config := lineageConfig(t)
+	imported := createLineageJob(t, config, []string{t.TempDir()}, nil, models.StepLocalImport)
+	pseudonymized := createLineageJob(t, config, []string{t.TempDir()}, nil, models.StepLocalImport, models.StepDIMP)
+
+	sources, parents, err := pipeline.ParentJobSources(config.JobsDir, []string{imported.JobID, pseudonymized.JobID, imported.JobID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{imported.JobID, pseudonymized.JobID}, parents)
+	assert.Equal(t, []string{
+		services.GetJobOutputDir(config.JobsDir, imported.JobID, models.StepLocalImport),
+		services.GetJobOutputDir(config.JobsDir, pseudonymized.JobID, models.StepDIMP),
+	}, sources)
+}
+
+// TestParentJobSources_RequiresCompletedJob verifies unfinished or unknown jobs cannot be used as input
+func TestParentJobSources_RequiresCompletedJob(t *testing.T) {
+	config := lineageConfig(t)
+	pending := createLineageJob(t, config, []string{t.TempDir()}, nil)
+
+	_, _, err := pipeline.ParentJobSources(config.JobsDir, []string{pending.JobID})
+	assert.ErrorContains(t, err, "only completed jobs")
+
+	_, _, err = pipeline.ParentJobSources(config.JobsDir, []string{"00000000-0000-0000-0000-000000000000"})
+	assert.ErrorContains(t, err, "job not found")
+}
+
+// TestBuildLineage verifies ancestors and descendants across a merge, including deleted parents
+func TestBuildLineage(t *testing.T) {
+	config := lineageConfig(t)
+	first := createLineageJob(t, config, []string{t.TempDir()}, nil, models.StepLocalImport, models.StepDIMP)
+	second := createLineageJob(t, config, []string{t.TempDir()}, nil, models.StepLocalImport)
+	deleted := createLineageJob(t, config, []string{t.TempDir()}, nil, models.StepLocalImport)
+
+	sources, parents, err := pipeline.ParentJobSources(config.JobsDir, []string{first.JobID, second.JobID, deleted.JobID})
+	require.NoError(t, err)
+	merged := createLineageJob(t, config, sources, parents, models.StepLocalImport)
+	assert.Equal(t, parents, merged.ParentJobs)
+
+	derived := createLineageJob(t, config, []string{services.GetJobOutputDir(config.JobsDir, merged.JobID, models.StepLocalImport)}, []string{merged.JobID})
+	require.NoError(t, os.RemoveAll(services.GetJobDir(config.JobsDir, deleted.JobID)))
+
+	lineage, err := services.BuildLineage(config.JobsDir, merged.JobID, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+
+	ancestors := lineage.Ancestors
+	assert.Equal(t, merged.JobID, ancestors.JobID)
+	require.Len(t, ancestors.Parents, 3)
+	assert.Equal(t, first.JobID, ancestors.Parents[0].JobID)
+	assert.Equal(t, []models.StepName{models.StepLocalImport, models.StepDIMP}, ancestors.Parents[0].CompletedSteps)
+	assert.Equal(t, second.JobID, ancestors.Parents[1].JobID)
+	assert.True(t, ancestors.Parents[2].Missing)
+
+	require.Len(t, lineage.Descendants.Children, 1)
+	assert.Equal(t, derived.JobID, lineage.Descendants.Children[0].JobID)
+
+	// The lineage of a root job lists the merged job and its descendant
+	lineage, err = services.BuildLineage(config.JobsDir, first.JobID, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	assert.Empty(t, lineage.Ancestors.Parents)
+	require.Len(t, lineage.Descendants.Children, 1)
+	require.Len(t, lineage.Descendants.Children[0].Children, 1)
+	assert.Equal(t, derived.JobID, lineage.Descendants.Children[0].Children[0].JobID)
+}
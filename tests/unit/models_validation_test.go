@@ -242,3 +242,14 @@ func TestFHIRDataFile_Validate(t *testing.T) {
 		})
 	}
 }
+
+// TestAllStepNames_Valid verifies the step list used for shell completion holds exactly the valid steps
+func TestAllStepNames_Valid(t *testing.T) {
+	seen := make(map[models.StepName]bool)
+	for _, step := range models.AllStepNames {
+		assert.True(t, models.IsValidStepName(step), "unknown step %s", step)
+		assert.False(t, seen[step], "duplicate step %s", step)
+		seen[step] = true
+	}
+	assert.Len(t, seen, 8)
+}
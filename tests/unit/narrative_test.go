@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const narrativeTestDiv = `<div xmlns="http://www.w3.org/1999/xhtml">Max Mustermann, born 1970-01-01, Hauptstr. 1</div>`
+
+func narrativeTestResources() []map[string]any {
+	return []map[string]any{
+		{
+			"resourceType": "Patient",
+			"id":           "p1",
+			"text":         map[string]any{"status": "generated", "div": narrativeTestDiv},
+		},
+		{
+			"resourceType": "Observation",
+			"id":           "o1",
+			"code":         map[string]any{"text": "Body weight"},
+			"contained": []any{
+				map[string]any{
+					"resourceType": "Practitioner",
+					"id":           "pr1",
+					"text":         map[string]any{"status": "generated", "div": narrativeTestDiv},
+				},
+			},
+		},
+		{"resourceType": "Condition", "id": "c1", "note": []any{map[string]any{"text": "free text note"}}},
+	}
+}
+
+func runImportWithNarrativePolicy(t *testing.T, mode models.NarrativeMode) []map[string]any {
+	t.Helper()
+	imported, _ := runLocalImportStep(t, "Mixed.ndjson", narrativeTestResources(), func(config *models.ProjectConfig) {
+		config.Narrative = models.NarrativeConfig{Mode: mode}
+	})
+	require.Len(t, imported, 3)
+	return imported
+}
+
+// TestNarrativePolicy_Keep verifies narratives are untouched by default
+func TestNarrativePolicy_Keep(t *testing.T) {
+	imported := runImportWithNarrativePolicy(t, "")
+	assert.Equal(t, narrativeTestDiv, imported[0]["text"].(map[string]any)["div"])
+}
+
+// TestNarrativePolicy_Remove verifies resource.text is deleted, including in contained resources
+func TestNarrativePolicy_Remove(t *testing.T) {
+	imported := runImportWithNarrativePolicy(t, models.NarrativeRemove)
+
+	assert.NotContains(t, imported[0], "text")
+	contained := imported[1]["contained"].([]any)[0].(map[string]any)
+	assert.NotContains(t, contained, "text")
+
+	// String-valued text elements are not narratives
+	assert.Equal(t, "Body weight", imported[1]["code"].(map[string]any)["text"])
+	assert.Equal(t, "free text note", imported[2]["note"].([]any)[0].(map[string]any)["text"])
+}
+
+// TestNarrativePolicy_Sanitize verifies narratives are replaced with the placeholder
+func TestNarrativePolicy_Sanitize(t *testing.T) {
+	imported := runImportWithNarrativePolicy(t, models.NarrativeSanitize)
+
+	expected := map[string]any{"status": "empty", "div": pipeline.SanitizedNarrativeDiv}
+	assert.Equal(t, expected, imported[0]["text"])
+	contained := imported[1]["contained"].([]any)[0].(map[string]any)
+	assert.Equal(t, expected, contained["text"])
+}
+
+// TestNarrativeConfig_Validate verifies narrative mode validation
+func TestNarrativeConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.NarrativeConfig{}.Validate())
+	assert.NoError(t, models.NarrativeConfig{Mode: models.NarrativeSanitize}.Validate())
+	assert.Error(t, models.NarrativeConfig{Mode: "blank"}.Validate())
+	assert.False(t, models.NarrativeConfig{Mode: models.NarrativeKeep}.IsActive())
+}
+
+// TestConfigLoading_Narrative verifies the narrative section is loaded from YAML
+func TestConfigLoading_Narrative(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+narrative:
+  mode: remove
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, models.NarrativeRemove, config.Narrative.Mode)
+}
@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/server"
+)
+
+// TestServer_OpenAPI verifies /openapi.json describes every endpoint and its response schema
+func TestServer_OpenAPI(t *testing.T) {
+	config := models.ProjectConfig{
+		JobsDir: t.TempDir(),
+		Server: models.ServerConfig{
+			PublicURL: "https://aether.example.org/",
+			Tokens:    []models.APIToken{{Name: "ci", Token: viewerToken, Role: models.RoleViewer}},
+		},
+	}
+	srv := httptest.NewServer(server.New(config, lib.NewLogger(lib.LogLevelError)).Handler())
+	defer srv.Close()
+
+	// The document is public even when tokens are required
+	resp, err := http.Get(srv.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+				Required   []string                  `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Equal(t, server.APIVersion, spec.Info.Version)
+	require.Len(t, spec.Servers, 1)
+	assert.Equal(t, "https://aether.example.org", spec.Servers[0].URL)
+
+	operationIDs := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method, operation := range item {
+			id, _ := operation["operationId"].(string)
+			require.NotEmpty(t, id, "%s %s", method, path)
+			assert.False(t, operationIDs[id], "duplicate operationId %s", id)
+			operationIDs[id] = true
+		}
+	}
+	for _, id := range []string{"listJobs", "getJob", "createShareLink", "listJobsForTenant", "getJobForTenant", "listTenants", "getSharedJob", "getSharedArtifact"} {
+		assert.True(t, operationIDs[id], "missing operation %s", id)
+	}
+	assert.Contains(t, spec.Paths, "/api/v1/tenants/{tenant}/jobs/{id}")
+	assert.Contains(t, spec.Paths, "/share/{token}/artifacts/{name}")
+	assert.Equal(t, "read", spec.Paths["/api/v1/jobs"]["get"]["x-aether-permission"])
+
+	job := spec.Components.Schemas["JobStatusView"]
+	assert.Contains(t, job.Properties, "job_id")
+	assert.Equal(t, []any{"pending", "in_progress", "completed", "failed"}, job.Properties["status"]["enum"])
+	assert.Contains(t, job.Required, "steps")
+	assert.NotContains(t, job.Required, "error_message")
+	assert.Equal(t, "date-time", spec.Components.Schemas["StepStatusView"].Properties["started_at"]["format"])
+	assert.Contains(t, spec.Components.Schemas, "ShareLink")
+	assert.Contains(t, spec.Components.Schemas["Error"].Properties, "error")
+}
+
+// TestOpenAPI_CommittedDocumentIsCurrent verifies api/openapi.json matches the route table
+// Run `make openapi` after changing endpoints or response types
+func TestOpenAPI_CommittedDocumentIsCurrent(t *testing.T) {
+	committed, err := os.ReadFile(filepath.Join("..", "..", "api", "openapi.json"))
+	require.NoError(t, err)
+
+	generated, err := json.Marshal(server.New(models.ProjectConfig{}, lib.NewLogger(lib.LogLevelError)).OpenAPI())
+	require.NoError(t, err)
+	assert.JSONEq(t, string(generated), string(committed), "api/openapi.json is outdated; run make openapi")
+}
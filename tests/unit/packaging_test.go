@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestParquetOptions_Validate verifies codec, partition and file size validation
+func TestParquetOptions_Validate(t *testing.T) {
+	assert.NoError(t, models.ParquetOptions{}.Validate())
+	assert.NoError(t, models.ParquetOptions{
+		PartitionBy: []models.ParquetPartition{models.PartitionByResourceType, models.PartitionByYear},
+		Compression: models.ParquetZstd,
+	}.Validate())
+
+	assert.ErrorContains(t, models.ParquetOptions{Compression: "brotli"}.Validate(), "compression")
+	assert.ErrorContains(t, models.ParquetOptions{PartitionBy: []models.ParquetPartition{"month"}}.Validate(), "partition_by")
+	assert.ErrorContains(t, models.ParquetOptions{PartitionBy: []models.ParquetPartition{"year", "year"}}.Validate(), "twice")
+	assert.Error(t, models.ParquetOptions{TargetFileSizeMB: -1}.Validate())
+
+	assert.Equal(t, models.ParquetSnappy, models.ParquetOptions{}.GetCompression())
+	assert.Equal(t, int64(128*1024*1024), models.ParquetOptions{}.GetTargetFileSizeBytes())
+	assert.Equal(t, int64(64*1024*1024), models.ParquetOptions{TargetFileSizeMB: 64}.GetTargetFileSizeBytes())
+}
+
+// TestParquetPartitionDir verifies Hive-style partition directories
+func TestParquetPartitionDir(t *testing.T) {
+	options := models.ParquetOptions{PartitionBy: []models.ParquetPartition{models.PartitionByResourceType, models.PartitionByYear}}
+
+	observation := map[string]any{"resourceType": "Observation", "effectiveDateTime": "2024-03-01T10:00:00+01:00"}
+	assert.Equal(t, "resource_type=Observation/year=2024", services.ParquetPartitionDir(options, observation))
+
+	encounter := map[string]any{"resourceType": "Encounter", "period": map[string]any{"start": "2019-12-31"}}
+	assert.Equal(t, "resource_type=Encounter/year=2019", services.ParquetPartitionDir(options, encounter))
+
+	undated := map[string]any{"resourceType": "Medication"}
+	assert.Equal(t, "resource_type=Medication/year="+services.HivePartitionDefault, services.ParquetPartitionDir(options, undated))
+
+	assert.Equal(t, "", services.ParquetPartitionDir(models.ParquetOptions{}, observation))
+}
+
+// TestConfigLoading_ParquetOptions verifies Parquet options are loaded with dictionary encoding on by default
+func TestConfigLoading_ParquetOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	writeConfig := func(parquet string) {
+		configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+  packaging:
+    parquet:
+` + parquet + `
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+		require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+	}
+
+	writeConfig("      compression: zstd\n      partition_by: [resource_type, year]\n      target_file_size_mb: 256\n")
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	parquet := config.Pipeline.Packaging.Parquet
+	assert.Equal(t, models.ParquetZstd, parquet.Compression)
+	assert.Equal(t, []models.ParquetPartition{models.PartitionByResourceType, models.PartitionByYear}, parquet.PartitionBy)
+	assert.Equal(t, 256, parquet.TargetFileSizeMB)
+	assert.True(t, parquet.DictionaryEncoding)
+
+	writeConfig("      dictionary_encoding: false\n")
+	config, err = services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.False(t, config.Pipeline.Packaging.Parquet.DictionaryEncoding)
+}
@@ -0,0 +1,317 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// createParquetConversionTestJob creates a job whose local import completed, with in-process Parquet conversion enabled
+func createParquetConversionTestJob(t *testing.T, flatten models.FlattenConfig, packaging models.PackagingConfig) (*models.PipelineJob, string) {
+	job, jobDir := createCSVConversionTestJob(t, flatten)
+	packaging.Mode = models.PackagingModeLocal
+	job.Config.Pipeline.EnabledSteps = []models.StepName{models.StepLocalImport, models.StepParquetConversion}
+	job.Config.Pipeline.Packaging = packaging
+	job.CurrentStep = string(models.StepParquetConversion)
+	return job, jobDir
+}
+
+// openParquetFile opens a Parquet file for the duration of the test
+func openParquetFile(t *testing.T, path string) *parquet.File {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = file.Close() })
+	info, err := file.Stat()
+	require.NoError(t, err)
+	parquetFile, err := parquet.OpenFile(file, info.Size())
+	require.NoError(t, err)
+	return parquetFile
+}
+
+// readParquetTable reads the column names and rows of a Parquet file; nulls are returned as ""
+func readParquetTable(t *testing.T, path string) ([]string, [][]string) {
+	parquetFile := openParquetFile(t, path)
+
+	var columns []string
+	for _, field := range parquetFile.Schema().Fields() {
+		columns = append(columns, field.Name())
+	}
+
+	var rows [][]string
+	reader := parquet.NewReader(parquetFile)
+	defer func() { _ = reader.Close() }()
+	buffer := make([]parquet.Row, 16)
+	for {
+		n, err := reader.ReadRows(buffer)
+		for _, row := range buffer[:n] {
+			values := make([]string, len(columns))
+			for _, value := range row {
+				if !value.IsNull() {
+					values[value.Column()] = value.String()
+				}
+			}
+			rows = append(rows, values)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return columns, rows
+}
+
+// parquetCodec returns the compression codec of a Parquet file's first column chunk
+func parquetCodec(t *testing.T, path string) format.CompressionCodec {
+	return openParquetFile(t, path).Metadata().RowGroups[0].Columns[0].MetaData.Codec
+}
+
+// TestParquetTableSink_MappingOrderAndNulls verifies columns keep the mapping order and empty cells become null
+func TestParquetTableSink_MappingOrderAndNulls(t *testing.T) {
+	mapping, err := services.ParseFlattenMapping([]byte("Patient:\n  - id: id\n  - sex: gender\n  - born: birthDate\n"))
+	require.NoError(t, err)
+	flattener, err := services.NewFlattener(mapping)
+	require.NoError(t, err)
+
+	input := filepath.Join(t.TempDir(), "data.ndjson")
+	require.NoError(t, os.WriteFile(input, []byte(
+		`{"resourceType":"Patient","id":"p1","gender":"female","birthDate":"1970-01-01"}`+"\n"+
+			`{"resourceType":"Patient","id":"p2"}`+"\n"), 0644))
+
+	parquetDir := filepath.Join(t.TempDir(), "parquet")
+	_, err = services.FlattenFiles([]string{input}, flattener, services.NewParquetTableSink(parquetDir, models.ParquetOptions{}, ""))
+	require.NoError(t, err)
+
+	path := filepath.Join(parquetDir, "Patient", "part-00000.parquet")
+	columns, rows := readParquetTable(t, path)
+	assert.Equal(t, []string{"id", "sex", "born"}, columns)
+	assert.Equal(t, [][]string{{"p1", "female", "1970-01-01"}, {"p2", "", ""}}, rows)
+	assert.Equal(t, format.Snappy, parquetCodec(t, path))
+	assert.NoFileExists(t, path+".part")
+}
+
+// TestParquetTableSink_SchemaEvolution verifies columns that appear mid-pass are appended to every
+// file of the table, with nulls for the rows written before
+func TestParquetTableSink_SchemaEvolution(t *testing.T) {
+	parquetDir := t.TempDir()
+	sink := services.NewParquetTableSink(parquetDir, models.ParquetOptions{PartitionBy: []models.ParquetPartition{models.PartitionByYear}}, "")
+	old := map[string]any{"resourceType": "Patient", "birthDate": "1970-01-01"}
+	young := map[string]any{"resourceType": "Patient", "birthDate": "2001-05-05"}
+	require.NoError(t, sink.WriteRow("Patient", []string{"id", "sex"}, []string{"p1", "female"}, old))
+	require.NoError(t, sink.WriteRow("Patient", []string{"id", "sex", "born"}, []string{"p2", "male", "2001-05-05"}, young))
+	require.NoError(t, sink.WriteRow("Patient", []string{"born", "id"}, []string{"1970-02-02", "p3"}, old))
+	require.NoError(t, sink.Close())
+
+	parts, err := filepath.Glob(filepath.Join(parquetDir, "Patient", "*", "part-*.parquet"))
+	require.NoError(t, err)
+	require.Len(t, parts, 3, "the 1970 partition continues in a new file once born appears")
+	var rows [][]string
+	for _, part := range parts {
+		columns, partRows := readParquetTable(t, part)
+		assert.Equal(t, []string{"id", "sex", "born"}, columns, part)
+		rows = append(rows, partRows...)
+		assert.NoFileExists(t, part+".part")
+	}
+	assert.ElementsMatch(t, [][]string{{"p1", "female", ""}, {"p2", "male", "2001-05-05"}, {"p3", "", "1970-02-02"}}, rows)
+}
+
+// TestExecuteParquetConversionStep_WritesTablesAndDictionary verifies one Parquet table per resource type and the data dictionary
+func TestExecuteParquetConversionStep_WritesTablesAndDictionary(t *testing.T) {
+	job, jobDir := createParquetConversionTestJob(t, models.FlattenConfig{}, models.PackagingConfig{
+		Parquet: models.ParquetOptions{Compression: models.ParquetZstd},
+	})
+
+	require.NoError(t, pipeline.ExecuteParquetConversionStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError)))
+
+	step, found := models.GetStepByName(*job, models.StepParquetConversion)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusCompleted, step.Status)
+	assert.Equal(t, 1, step.FilesProcessed)
+
+	parquetDir := filepath.Join(jobDir, "parquet")
+	patients := filepath.Join(parquetDir, "Patient", "part-00000.parquet")
+	columns, rows := readParquetTable(t, patients)
+	assert.Equal(t, "patient_id", columns[0])
+	require.Len(t, rows, 1)
+	assert.Equal(t, []string{"p1", "female", "1970-01-01"}, rows[0][:3])
+	assert.Equal(t, format.Zstd, parquetCodec(t, patients))
+
+	_, observations := readParquetTable(t, filepath.Join(parquetDir, "Observation", "part-00000.parquet"))
+	assert.Len(t, observations, 2)
+
+	// Unmapped resource types get no table
+	assert.NoDirExists(t, filepath.Join(parquetDir, "Basic"))
+	assert.FileExists(t, filepath.Join(parquetDir, services.DataDictionaryFileName))
+}
+
+// TestExecuteParquetConversionStep_WideObservations verifies the pivoted Observation table replaces the long one
+func TestExecuteParquetConversionStep_WideObservations(t *testing.T) {
+	job, jobDir := createParquetConversionTestJob(t, models.FlattenConfig{
+		Observations: models.ObservationPivotConfig{
+			Format: models.ObservationFormatWide,
+			Codes:  []models.PivotCode{{Code: "718-7", Column: "hemoglobin"}, {Code: "2160-0", Column: "creatinine"}},
+		},
+	}, models.PackagingConfig{})
+
+	require.NoError(t, pipeline.ExecuteParquetConversionStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError)))
+
+	parquetDir := filepath.Join(jobDir, "parquet")
+	assert.NoDirExists(t, filepath.Join(parquetDir, "Observation"))
+	columns, rows := readParquetTable(t, filepath.Join(parquetDir, pipeline.WideObservationTable, "part-00000.parquet"))
+	assert.Equal(t, []string{"patient", "effective", "hemoglobin", "creatinine"}, columns)
+	assert.Equal(t, [][]string{{"Patient/p1", "2024-01-01", "13.5", "0.9"}}, rows)
+}
+
+// parquetEncodings returns the encodings of a Parquet file's first column chunk
+func parquetEncodings(t *testing.T, path string) []format.Encoding {
+	return openParquetFile(t, path).Metadata().RowGroups[0].Columns[0].MetaData.Encoding
+}
+
+// TestParquetTableSink_Partitions verifies rows go to the Hive-style partition directory of their resource
+func TestParquetTableSink_Partitions(t *testing.T) {
+	mapping, err := services.ParseFlattenMapping([]byte("Condition:\n  - id: id\n"))
+	require.NoError(t, err)
+	flattener, err := services.NewFlattener(mapping)
+	require.NoError(t, err)
+
+	input := filepath.Join(t.TempDir(), "data.ndjson")
+	require.NoError(t, os.WriteFile(input, []byte(
+		`{"resourceType":"Condition","id":"c1","onsetDateTime":"2023-05-01"}`+"\n"+
+			`{"resourceType":"Condition","id":"c2","recordedDate":"2024-02-03"}`+"\n"+
+			`{"resourceType":"Condition","id":"c3"}`+"\n"+
+			`{"resourceType":"Condition","id":"c4","onsetDateTime":"2023-11-30"}`+"\n"), 0644))
+
+	parquetDir := t.TempDir()
+	options := models.ParquetOptions{PartitionBy: []models.ParquetPartition{models.PartitionByResourceType, models.PartitionByYear}}
+	_, err = services.FlattenFiles([]string{input}, flattener, services.NewParquetTableSink(parquetDir, options, ""))
+	require.NoError(t, err)
+
+	partition := func(year string) string {
+		return filepath.Join(parquetDir, "Condition", "resource_type=Condition", "year="+year, "part-00000.parquet")
+	}
+	_, rows := readParquetTable(t, partition("2023"))
+	assert.Equal(t, [][]string{{"c1"}, {"c4"}}, rows)
+	_, rows = readParquetTable(t, partition("2024"))
+	assert.Equal(t, [][]string{{"c2"}}, rows)
+	_, rows = readParquetTable(t, partition(services.HivePartitionDefault))
+	assert.Equal(t, [][]string{{"c3"}}, rows)
+}
+
+// TestParquetTableSink_DictionaryEncoding verifies packaging.parquet.dictionary_encoding selects the column encoding
+func TestParquetTableSink_DictionaryEncoding(t *testing.T) {
+	for _, dictionary := range []bool{true, false} {
+		dir := t.TempDir()
+		require.NoError(t, services.WriteParquetTable(dir, "Codes", []string{"code"}, [][]string{{"a"}, {"a"}, {"b"}},
+			models.ParquetOptions{DictionaryEncoding: dictionary}, ""))
+
+		encodings := parquetEncodings(t, filepath.Join(dir, "Codes", "part-00000.parquet"))
+		assert.Equal(t, dictionary, slices.Contains(encodings, format.RLEDictionary), "dictionary_encoding: %v", dictionary)
+	}
+}
+
+// TestParquetTableSink_TargetFileSize verifies a file is rolled over once it reaches target_file_size_mb
+func TestParquetTableSink_TargetFileSize(t *testing.T) {
+	// Distinct 1 KiB values, so neither the dictionary nor compression shrinks them below the target
+	rows := make([][]string, 3000)
+	for i := range rows {
+		rows[i] = []string{fmt.Sprintf("%04d%s", i, strings.Repeat(strconv.Itoa(i%10), 1020))}
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, services.WriteParquetTable(dir, "Notes", []string{"text"}, rows,
+		models.ParquetOptions{TargetFileSizeMB: 1, Compression: models.ParquetNone}, ""))
+
+	parts, err := filepath.Glob(filepath.Join(dir, "Notes", "part-*.parquet"))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(parts), 2)
+	assert.Equal(t, filepath.Join(dir, "Notes", "part-00000.parquet"), parts[0])
+
+	total := 0
+	for _, part := range parts {
+		_, partRows := readParquetTable(t, part)
+		total += len(partRows)
+	}
+	assert.Equal(t, len(rows), total)
+}
+
+// TestExecuteParquetConversionStep_DeltaTable verifies every table gets a Delta commit and a re-run adds a version
+func TestExecuteParquetConversionStep_DeltaTable(t *testing.T) {
+	job, jobDir := createParquetConversionTestJob(t, models.FlattenConfig{}, models.PackagingConfig{
+		TableFormat: models.TableFormatDelta,
+		Parquet:     models.ParquetOptions{PartitionBy: []models.ParquetPartition{models.PartitionByYear}},
+	})
+	logger := lib.NewLogger(lib.LogLevelError)
+	require.NoError(t, pipeline.ExecuteParquetConversionStep(context.Background(), job, jobDir, logger))
+
+	tableDir := filepath.Join(jobDir, "parquet", "Observation")
+	first := readDeltaCommit(t, filepath.Join(tableDir, services.DeltaLogDirName, "00000000000000000000.json"))
+	require.Len(t, first["metaData"], 1)
+	assert.Equal(t, []any{"year"}, first["metaData"][0]["partitionColumns"])
+	assert.Contains(t, first["metaData"][0]["schemaString"], `"name":"year"`)
+	require.Len(t, first["add"], 1)
+	assert.Equal(t, map[string]any{"year": "2024"}, first["add"][0]["partitionValues"])
+	assert.FileExists(t, filepath.Join(tableDir, filepath.FromSlash(first["add"][0]["path"].(string))))
+
+	// A leftover of a run that failed before its commit is not picked up
+	leftover := filepath.Join(tableDir, "year=2024", "part-00000-failed.parquet")
+	require.NoError(t, os.WriteFile(leftover, []byte("PAR1"), 0644))
+
+	job.Steps = job.Steps[:1]
+	require.NoError(t, pipeline.ExecuteParquetConversionStep(context.Background(), job, jobDir, logger))
+
+	second := readDeltaCommit(t, filepath.Join(tableDir, services.DeltaLogDirName, "00000000000000000001.json"))
+	require.Len(t, second["add"], 1)
+	assert.NotEqual(t, first["add"][0]["path"], second["add"][0]["path"])
+	require.Len(t, second["remove"], 1)
+	assert.Equal(t, first["add"][0]["path"], second["remove"][0]["path"])
+	assert.NoFileExists(t, leftover)
+}
+
+// TestExecutePackagingSteps_SharedPass verifies that the first packaging step writes both formats in
+// one pass and the second completes from its recorded result without reading the input again
+func TestExecutePackagingSteps_SharedPass(t *testing.T) {
+	job, jobDir := createParquetConversionTestJob(t, models.FlattenConfig{}, models.PackagingConfig{})
+	job.Config.Pipeline.EnabledSteps = []models.StepName{models.StepLocalImport, models.StepCSVConversion, models.StepParquetConversion}
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	require.NoError(t, pipeline.ExecuteCSVConversionStep(context.Background(), job, jobDir, logger))
+	assert.FileExists(t, filepath.Join(jobDir, "csv", "Patient.csv"))
+	assert.FileExists(t, filepath.Join(jobDir, "parquet", "Patient", "part-00000.parquet"))
+	assert.FileExists(t, filepath.Join(jobDir, "parquet", services.DataDictionaryFileName))
+	assert.FileExists(t, filepath.Join(jobDir, pipeline.PackagingPassFileName))
+
+	require.NoError(t, os.RemoveAll(filepath.Join(jobDir, "import")))
+	require.NoError(t, pipeline.ExecuteParquetConversionStep(context.Background(), job, jobDir, logger))
+
+	step, found := models.GetStepByName(*job, models.StepParquetConversion)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusCompleted, step.Status)
+	assert.Equal(t, 1, step.FilesProcessed)
+	assert.NoFileExists(t, filepath.Join(jobDir, pipeline.PackagingPassFileName))
+	_, observations := readParquetTable(t, filepath.Join(jobDir, "parquet", "Observation", "part-00000.parquet"))
+	assert.Len(t, observations, 2)
+}
+
+// TestExecutePackagingSteps_CompletedFormatNotRewritten verifies a completed format is left alone
+func TestExecutePackagingSteps_CompletedFormatNotRewritten(t *testing.T) {
+	job, jobDir := createParquetConversionTestJob(t, models.FlattenConfig{}, models.PackagingConfig{})
+	job.Config.Pipeline.EnabledSteps = []models.StepName{models.StepLocalImport, models.StepCSVConversion, models.StepParquetConversion}
+	job.Steps = append(job.Steps, models.PipelineStep{Name: models.StepParquetConversion, Status: models.StepStatusCompleted})
+
+	require.NoError(t, pipeline.ExecuteCSVConversionStep(context.Background(), job, jobDir, lib.NewLogger(lib.LogLevelError)))
+	assert.FileExists(t, filepath.Join(jobDir, "csv", "Patient.csv"))
+	assert.NoDirExists(t, filepath.Join(jobDir, "parquet"))
+	assert.NoFileExists(t, filepath.Join(jobDir, pipeline.PackagingPassFileName))
+}
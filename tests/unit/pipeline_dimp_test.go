@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -109,7 +110,7 @@ func TestExecuteDIMPStep_DisabledStep(t *testing.T) {
 	job := createDIMPTestJobDisabled()
 	logger := createDIMPTestLogger()
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 }
 
@@ -119,7 +120,7 @@ func TestExecuteDIMPStep_MissingDIMPURL(t *testing.T) {
 	job := createDIMPTestJob("") // Empty URL
 	logger := createDIMPTestLogger()
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "DIMP service URL not configured")
 }
@@ -139,7 +140,7 @@ func TestExecuteDIMPStep_FailedToCreateOutputDir(t *testing.T) {
 	require.NoError(t, cerr)
 	require.NoError(t, f.Close())
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to create output directory")
 }
@@ -157,7 +158,7 @@ func TestExecuteDIMPStep_NoFilesFound(t *testing.T) {
 	importDir := filepath.Join(tmpDir, "import")
 	require.NoError(t, os.MkdirAll(importDir, 0755))
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no FHIR NDJSON files found")
 }
@@ -182,7 +183,7 @@ func TestExecuteDIMPStep_ProcessSimpleResources(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, inputFile, patients)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify output file was created
@@ -225,7 +226,7 @@ func TestExecuteDIMPStep_ResumeProcessing(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, outputFile, existingData)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify file still has original content (wasn't reprocessed)
@@ -254,7 +255,7 @@ func TestExecuteDIMPStep_InvalidJSON(t *testing.T) {
 	require.NoError(t, ferr)
 	require.NoError(t, f.Close())
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to parse")
 }
@@ -289,7 +290,7 @@ func TestExecuteDIMPStep_ProcessBundleWithoutSplit(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, inputFile, []map[string]any{bundle})
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	outputFile := filepath.Join(tmpDir, "pseudonymized", "dimped_bundles.ndjson")
@@ -314,7 +315,7 @@ func TestExecuteDIMPStep_ProcessBundleWithSplit(t *testing.T) {
 	bundle := CreateTestBundle(20, 100) // 20 entries, ~100KB each = ~2MB total
 	writeDIMPNDJSON(t, inputFile, []map[string]any{bundle})
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	outputFile := filepath.Join(tmpDir, "pseudonymized", "dimped_bundles.ndjson")
@@ -341,7 +342,7 @@ func TestExecuteDIMPStep_DIMPServiceError(t *testing.T) {
 	patients := []map[string]any{{"resourceType": "Patient", "id": "p1"}}
 	writeDIMPNDJSON(t, inputFile, patients)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.Error(t, err)
 }
 
@@ -365,7 +366,7 @@ func TestExecuteDIMPStep_MultipleFiles(t *testing.T) {
 		writeDIMPNDJSON(t, inputFile, data)
 	}
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify all output files were created
@@ -391,7 +392,7 @@ func TestExecuteDIMPStep_StepStateUpdated(t *testing.T) {
 	patients := []map[string]any{{"resourceType": "Patient", "id": "p1"}}
 	writeDIMPNDJSON(t, inputFile, patients)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify step was added to job
@@ -428,7 +429,7 @@ func TestExecuteDIMPStep_EmptyLines(t *testing.T) {
 	require.NoError(t, ferr)
 	require.NoError(t, f.Close())
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	outputFile := filepath.Join(tmpDir, "pseudonymized", "dimped_sparse.ndjson")
@@ -468,7 +469,7 @@ func TestExecuteDIMPStep_OversizedNonBundleResource(t *testing.T) {
 	require.NoError(t, ferr)
 	require.NoError(t, f.Close())
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "oversized")
 }
@@ -495,7 +496,7 @@ func TestExecuteDIMPStep_BundleCalcSizeError(t *testing.T) {
 	require.NoError(t, f.Close())
 
 	// The test should handle this - it shouldn't crash
-	_ = pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	_ = pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 }
 
 // TestExecuteDIMPStep_DefaultBundleThreshold tests default threshold when not configured
@@ -527,7 +528,7 @@ func TestExecuteDIMPStep_DefaultBundleThreshold(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, inputFile, []map[string]any{bundle})
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	outputFile := filepath.Join(tmpDir, "pseudonymized", "dimped_bundles.ndjson")
@@ -560,7 +561,7 @@ func TestExecuteDIMPStep_GetOrCreateStepExisting(t *testing.T) {
 	patients := []map[string]any{{"resourceType": "Patient", "id": "p1"}}
 	writeDIMPNDJSON(t, inputFile, patients)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify still only one step
@@ -592,7 +593,7 @@ func TestExecuteDIMPStep_NegativeBundleThreshold(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, inputFile, []map[string]any{bundle})
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 }
 
@@ -623,7 +624,7 @@ func TestExecuteDIMPStep_AlreadyProcessedFileCountError(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, outputFile, existingData)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 	// Step should complete successfully even if counting fails
 	require.Len(t, job.Steps, 1)
@@ -648,7 +649,7 @@ func TestExecuteDIMPStep_StepErrorRecording(t *testing.T) {
 	patients := []map[string]any{{"resourceType": "Patient", "id": "p1"}}
 	writeDIMPNDJSON(t, inputFile, patients)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.Error(t, err)
 
 	// Verify step was created and has error recorded
@@ -669,7 +670,7 @@ func TestExecuteDIMPStep_FileListingWithoutImportDir(t *testing.T) {
 	logger := createDIMPTestLogger()
 
 	// Don't create import directory - glob should return empty
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no FHIR NDJSON files found")
 }
@@ -694,7 +695,7 @@ func TestExecuteDIMPStep_VeryLargeBundle(t *testing.T) {
 	largeBundle := CreateTestBundle(500, 50) // 500 entries of ~50KB each = ~25MB
 	writeDIMPNDJSON(t, inputFile, []map[string]any{largeBundle})
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify output file was created
@@ -723,7 +724,7 @@ func TestExecuteDIMPStep_MixedResourceTypes(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, inputFile, resources)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify output file exists and has all resources
@@ -771,7 +772,7 @@ func TestExecuteDIMPStep_BundleWithError(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, inputFile, []map[string]any{bundle})
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	outputFile := filepath.Join(tmpDir, "pseudonymized", "dimped_bundles.ndjson")
@@ -804,7 +805,7 @@ func TestExecuteDIMPStep_LargeNumberOfFiles(t *testing.T) {
 		writeDIMPNDJSON(t, inputFile, data)
 	}
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify all files were processed
@@ -832,10 +833,132 @@ func TestExecuteDIMPStep_SpecialCharactersInFilenames(t *testing.T) {
 	}
 	writeDIMPNDJSON(t, inputFile, patients)
 
-	err := pipeline.ExecuteDIMPStep(job, tmpDir, logger)
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
 	assert.NoError(t, err)
 
 	// Verify output file exists
 	outputFile := filepath.Join(tmpDir, "pseudonymized", "dimped_test_data-2025-01-01.ndjson")
 	assert.FileExists(t, outputFile)
 }
+
+// TestExecuteDIMPStep_SplitByResourceType partitions mixed-type input into one file per resourceType
+func TestExecuteDIMPStep_SplitByResourceType(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.Services.DIMP.SplitByResourceType = true
+	logger := createDIMPTestLogger()
+
+	importDir := filepath.Join(tmpDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	writeDIMPNDJSON(t, filepath.Join(importDir, "a.ndjson"), []map[string]any{
+		{"resourceType": "Patient", "id": "p1"},
+		{"resourceType": "Observation", "id": "o1"},
+	})
+	writeDIMPNDJSON(t, filepath.Join(importDir, "b.ndjson"), []map[string]any{
+		{"resourceType": "Observation", "id": "o2"},
+		{"resourceType": "Patient", "id": "p2"},
+	})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
+	require.NoError(t, err)
+
+	pseudonymizedDir := filepath.Join(tmpDir, "pseudonymized")
+
+	patients := readDIMPNDJSON(t, filepath.Join(pseudonymizedDir, "dimped_Patient.ndjson"))
+	require.Len(t, patients, 2)
+	assert.Equal(t, "pseudo-p1", patients[0]["id"])
+	assert.Equal(t, "pseudo-p2", patients[1]["id"])
+
+	observations := readDIMPNDJSON(t, filepath.Join(pseudonymizedDir, "dimped_Observation.ndjson"))
+	require.Len(t, observations, 2)
+	assert.Equal(t, "pseudo-o1", observations[0]["id"])
+	assert.Equal(t, "pseudo-o2", observations[1]["id"])
+
+	// Per-input output is not left behind
+	assert.NoFileExists(t, filepath.Join(pseudonymizedDir, "dimped_a.ndjson"))
+	assert.NoDirExists(t, filepath.Join(pseudonymizedDir, ".unsplit"))
+}
+
+// TestExecuteDIMPStep_NonFHIRLinesPassThrough copies lines without resourceType to the output unchanged
+func TestExecuteDIMPStep_NonFHIRLinesPassThrough(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var resource map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&resource)
+		resource["id"] = "pseudo-" + resource["id"].(string)
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.Services.DIMP.NonFHIRLines = models.NonFHIRLinesPassThrough
+	logger := createDIMPTestLogger()
+
+	importDir := filepath.Join(tmpDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	writeDIMPNDJSON(t, filepath.Join(importDir, "data.ndjson"), []map[string]any{
+		{"extractionId": "abc", "generatedAt": "2025-01-01"},
+		{"resourceType": "Patient", "id": "p1"},
+	})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
+	require.NoError(t, err)
+
+	resources := readDIMPNDJSON(t, filepath.Join(tmpDir, "pseudonymized", "dimped_data.ndjson"))
+	require.Len(t, resources, 2)
+	assert.Equal(t, "abc", resources[0]["extractionId"])
+	assert.Equal(t, "pseudo-p1", resources[1]["id"])
+	assert.Equal(t, 1, requests, "Non-FHIR line should not be sent to DIMP")
+}
+
+// TestExecuteDIMPStep_NonFHIRLinesQuarantine moves lines without resourceType to the quarantine directory
+func TestExecuteDIMPStep_NonFHIRLinesQuarantine(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.Services.DIMP.NonFHIRLines = models.NonFHIRLinesQuarantine
+	logger := createDIMPTestLogger()
+
+	importDir := filepath.Join(tmpDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	writeDIMPNDJSON(t, filepath.Join(importDir, "data.ndjson"), []map[string]any{
+		{"resourceType": "Patient", "id": "p1"},
+		{"extractionId": "abc"},
+	})
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
+	require.NoError(t, err)
+
+	resources := readDIMPNDJSON(t, filepath.Join(tmpDir, "pseudonymized", "dimped_data.ndjson"))
+	require.Len(t, resources, 1)
+	assert.Equal(t, "pseudo-p1", resources[0]["id"])
+
+	quarantined := readDIMPNDJSON(t, filepath.Join(tmpDir, "quarantine", "data.ndjson"))
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, "abc", quarantined[0]["extractionId"])
+	assert.NoFileExists(t, filepath.Join(tmpDir, "quarantine", "data.ndjson.part"))
+}
+
+// TestExecuteDIMPStep_AttachesLogContext verifies a failed step carries the log lines that led to the failure
+func TestExecuteDIMPStep_AttachesLogContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	job := createDIMPTestJob("") // Empty URL
+	logger := createDIMPTestLogger()
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, tmpDir, logger, lib.NoProgress)
+	require.Error(t, err)
+
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	require.NotNil(t, step.LastError)
+	require.NotEmpty(t, step.LastError.Context)
+	assert.Contains(t, strings.Join(step.LastError.Context, "\n"), "DIMP service URL not configured")
+}
@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func writePostConditionFile(t *testing.T, dir, name string, lines int) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	content := strings.Repeat("{\"resourceType\":\"Patient\"}\n", lines) + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestValidatePostConditions(t *testing.T) {
+	assert.NoError(t, models.ValidatePostConditions(map[models.StepName]models.StepPostCondition{
+		models.StepTorchImport: {MinOutputFiles: 1, MinResources: 100},
+		models.StepDIMP:        {MaxShrinkagePercent: floatPtr(5)},
+	}))
+	assert.Error(t, models.ValidatePostConditions(map[models.StepName]models.StepPostCondition{"unknown": {MinResources: 1}}))
+	assert.Error(t, models.ValidatePostConditions(map[models.StepName]models.StepPostCondition{models.StepCSVConversion: {MinOutputFiles: 1}}),
+		"steps without NDJSON output cannot be checked")
+	assert.Error(t, models.ValidatePostConditions(map[models.StepName]models.StepPostCondition{models.StepLocalImport: {MaxShrinkagePercent: floatPtr(5)}}),
+		"imports have no input to compare against")
+	assert.Error(t, models.ValidatePostConditions(map[models.StepName]models.StepPostCondition{models.StepDIMP: {MaxShrinkagePercent: floatPtr(150)}}))
+	assert.Error(t, models.ValidatePostConditions(map[models.StepName]models.StepPostCondition{models.StepDIMP: {MinResources: -1}}))
+}
+
+func TestCheckPostCondition(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputDir := filepath.Join(tmpDir, "import")
+	outputDir := filepath.Join(tmpDir, "pseudonymized")
+	writePostConditionFile(t, inputDir, "a.ndjson", 100)
+	writePostConditionFile(t, outputDir, "dimped_a.ndjson", 97)
+	const maxLineBytes = 1024 * 1024
+
+	output, err := pipeline.CountNDJSONOutput(outputDir, maxLineBytes)
+	require.NoError(t, err)
+	assert.Equal(t, pipeline.NDJSONOutputStats{Files: 1, Resources: 97}, output, "blank lines are not resources")
+
+	assert.NoError(t, pipeline.CheckPostCondition(models.StepDIMP,
+		models.StepPostCondition{MinOutputFiles: 1, MinResources: 97, MaxShrinkagePercent: floatPtr(3)},
+		inputDir, outputDir, maxLineBytes))
+
+	err = pipeline.CheckPostCondition(models.StepDIMP,
+		models.StepPostCondition{MinOutputFiles: 2, MinResources: 100, MaxShrinkagePercent: floatPtr(1)},
+		inputDir, outputDir, maxLineBytes)
+	var postErr *pipeline.PostConditionError
+	require.True(t, errors.As(err, &postErr))
+	assert.Equal(t, models.StepDIMP, postErr.Step)
+	assert.Len(t, postErr.Violations, 3, "all violations are reported")
+	assert.Contains(t, err.Error(), "1 output files, expected at least 2")
+	assert.Contains(t, err.Error(), "97 resources, expected at least 100")
+	assert.Contains(t, err.Error(), "97 of 100 input resources (3.0% lost, at most 1% allowed)")
+}
+
+func TestExecuteDIMPStep_PostConditionFailsStep(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	jobsDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = jobsDir
+	job.Config.Services.DIMP.NonFHIRLines = models.NonFHIRLinesQuarantine
+	job.Config.Pipeline.PostConditions = map[models.StepName]models.StepPostCondition{
+		models.StepDIMP: {MaxShrinkagePercent: floatPtr(10)},
+	}
+	jobDir := services.GetJobDir(jobsDir, job.JobID)
+
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	content := "{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n{\"torch\":\"metadata\"}\n{\"resourceType\":\"Patient\",\"id\":\"p2\"}\n{\"torch\":\"metadata\"}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(importDir, "patients.ndjson"), []byte(content), 0644))
+
+	err := pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "post-conditions of step dimp violated")
+	assert.Contains(t, err.Error(), "2 of 4 input resources")
+
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	require.NotNil(t, step.LastError)
+	assert.Equal(t, models.ErrorTypeNonTransient, step.LastError.Type)
+}
+
+func TestConfigLoading_PostConditions(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - torch
+    - dimp
+  post_conditions:
+    torch:
+      min_output_files: 1
+      min_resources: 500
+    dimp:
+      max_shrinkage_percent: 2.5
+
+services:
+  torch:
+    base_url: "http://localhost:8080"
+  dimp:
+    url: "http://localhost:32861/fhir"
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, models.StepPostCondition{MinOutputFiles: 1, MinResources: 500}, config.Pipeline.PostConditions[models.StepTorchImport])
+	require.NotNil(t, config.Pipeline.PostConditions[models.StepDIMP].MaxShrinkagePercent)
+	assert.Equal(t, 2.5, *config.Pipeline.PostConditions[models.StepDIMP].MaxShrinkagePercent)
+}
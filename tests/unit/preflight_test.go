@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func newPreflightTestClient() (*services.HTTPClient, *lib.Logger) {
+	logger := lib.NewLogger(lib.LogLevelError)
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000}, logger)
+	return httpClient, logger
+}
+
+// newEchoDIMPServer returns a DIMP mock that echoes the posted resource back
+func newEchoDIMPServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resource map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&resource))
+		resource["id"] = "pseudonymized"
+		w.Header().Set("Content-Type", "application/fhir+json")
+		_ = json.NewEncoder(w).Encode(resource)
+	}))
+}
+
+// TestPreflight_AllChecksPass verifies a GO verdict when every enabled step succeeds
+func TestPreflight_AllChecksPass(t *testing.T) {
+	torchServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer torchServer.Close()
+
+	dimpServer := newEchoDIMPServer(t)
+	defer dimpServer.Close()
+
+	var convertedLines int
+	csvServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		convertedLines = len(strings.Split(strings.TrimSpace(string(body)), "\n"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer csvServer.Close()
+
+	config := &models.ProjectConfig{
+		Services: models.ServiceConfig{
+			TORCH:         models.TORCHConfig{BaseURL: torchServer.URL, Username: "user", Password: "pass"},
+			DIMP:          models.DIMPConfig{URL: dimpServer.URL},
+			CSVConversion: models.CSVConversionConfig{URL: csvServer.URL},
+		},
+		Pipeline: models.PipelineConfig{
+			EnabledSteps: []models.StepName{models.StepTorchImport, models.StepDIMP, models.StepValidation, models.StepCSVConversion},
+		},
+	}
+
+	httpClient, logger := newPreflightTestClient()
+	report := pipeline.RunPreflight(config, httpClient, logger)
+
+	require.Len(t, report.Checks, 4)
+	assert.Equal(t, pipeline.PreflightPassed, report.Checks[0].Status)
+	assert.Equal(t, pipeline.PreflightPassed, report.Checks[1].Status)
+	assert.Equal(t, pipeline.PreflightSkipped, report.Checks[2].Status)
+	assert.Equal(t, pipeline.PreflightPassed, report.Checks[3].Status)
+	assert.Equal(t, 3, convertedLines)
+	assert.True(t, report.IsGo())
+}
+
+// TestPreflight_TorchAuthRejected verifies rejected TORCH credentials produce a NO-GO verdict
+func TestPreflight_TorchAuthRejected(t *testing.T) {
+	torchServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer torchServer.Close()
+
+	dimpServer := newEchoDIMPServer(t)
+	defer dimpServer.Close()
+
+	config := &models.ProjectConfig{
+		Services: models.ServiceConfig{
+			TORCH: models.TORCHConfig{BaseURL: torchServer.URL, Username: "user", Password: "wrong"},
+			DIMP:  models.DIMPConfig{URL: dimpServer.URL},
+		},
+		Pipeline: models.PipelineConfig{
+			EnabledSteps: []models.StepName{models.StepTorchImport, models.StepDIMP},
+		},
+	}
+
+	httpClient, logger := newPreflightTestClient()
+	report := pipeline.RunPreflight(config, httpClient, logger)
+
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, pipeline.PreflightFailed, report.Checks[0].Status)
+	assert.Contains(t, report.Checks[0].Message, "credentials rejected")
+	assert.Equal(t, pipeline.PreflightPassed, report.Checks[1].Status, "Later checks still run after a failure")
+	assert.False(t, report.IsGo())
+}
+
+// TestPreflight_ConversionServiceError verifies conversion failures are reported with the HTTP status
+func TestPreflight_ConversionServiceError(t *testing.T) {
+	parquetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("unsupported input"))
+	}))
+	defer parquetServer.Close()
+
+	config := &models.ProjectConfig{
+		Services: models.ServiceConfig{
+			ParquetConversion: models.ParquetConversionConfig{URL: parquetServer.URL},
+		},
+		Pipeline: models.PipelineConfig{
+			EnabledSteps: []models.StepName{models.StepLocalImport, models.StepParquetConversion},
+		},
+	}
+
+	httpClient, logger := newPreflightTestClient()
+	report := pipeline.RunPreflight(config, httpClient, logger)
+
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, pipeline.PreflightPassed, report.Checks[0].Status)
+	assert.Equal(t, pipeline.PreflightFailed, report.Checks[1].Status)
+	assert.Contains(t, report.Checks[1].Message, "HTTP 400")
+	assert.False(t, report.IsGo())
+}
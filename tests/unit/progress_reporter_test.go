@@ -0,0 +1,79 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+)
+
+func decodeProgressEvents(t *testing.T, buf *bytes.Buffer) []lib.ProgressEvent {
+	var events []lib.ProgressEvent
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var event lib.ProgressEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestNoProgress_DiscardsEverything(t *testing.T) {
+	task := lib.NoProgress.Start("anything", 10)
+	task.SetStatus("status")
+	task.SetTotal(20)
+	task.Add(5)
+	task.Clear()
+	task.Done(errors.New("failed"))
+}
+
+func TestJSONProgress_WritesTaskLifecycle(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := lib.NewJSONProgress(&buf)
+
+	task := reporter.Start("Downloading batch-1.ndjson", 0)
+	task.SetStatus("next poll in 2s")
+	task.Add(10) // Throttled: less than a second since the start event
+	task.Done(nil)
+
+	events := decodeProgressEvents(t, &buf)
+	require.Len(t, events, 3)
+	assert.Equal(t, "start", events[0].Event)
+	assert.Equal(t, "Downloading batch-1.ndjson", events[0].Description)
+	assert.Equal(t, "status", events[1].Event)
+	assert.Equal(t, "next poll in 2s", events[1].Status)
+	assert.Equal(t, "done", events[2].Event)
+	assert.Equal(t, int64(10), events[2].Current)
+	assert.Empty(t, events[2].Error)
+}
+
+func TestJSONProgress_SeparatesTasksAndReportsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := lib.NewJSONProgress(&buf)
+
+	first := reporter.Start("Pseudonymizing a.ndjson", 100)
+	second := reporter.Start("Pseudonymizing b.ndjson", 50)
+	second.SetTotal(60)
+	second.Done(errors.New("DIMP unavailable"))
+	first.Done(nil)
+
+	events := decodeProgressEvents(t, &buf)
+	require.Len(t, events, 4)
+	assert.Equal(t, 1, events[0].Task)
+	assert.Equal(t, int64(100), events[0].Total)
+	assert.Equal(t, 2, events[1].Task)
+
+	assert.Equal(t, 2, events[2].Task)
+	assert.Equal(t, "done", events[2].Event)
+	assert.Equal(t, int64(60), events[2].Total)
+	assert.Equal(t, "DIMP unavailable", events[2].Error)
+
+	assert.Equal(t, 1, events[3].Task)
+	assert.Empty(t, events[3].Error)
+}
@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// saveInterruptedJob saves a job that was importing when its process ended, with a partial
+// download and a leftover temporary state file in its directory
+func saveInterruptedJob(t *testing.T, jobsDir string) *models.PipelineJob {
+	t.Helper()
+	job := createTestJob(uuid.New().String(), jobsDir)
+	started := time.Now().Add(-time.Minute)
+	job.Status = models.JobStatusInProgress
+	job.Steps[0].Status = models.StepStatusInProgress
+	job.Steps[0].StartedAt = &started
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+
+	jobDir := services.GetJobDir(jobsDir, job.JobID)
+	require.NoError(t, os.MkdirAll(filepath.Join(jobDir, "import"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(jobDir, "import", "Patient.ndjson"), []byte(`{"resourceType":"Patient"}`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(jobDir, "import", "Observation.ndjson.part"), []byte(`{"resourceType":"Obs`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(jobDir, ".state.tmp.1234"), []byte(`{`), 0644))
+	return job
+}
+
+func TestRecoverJob_InterruptedJob(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := saveInterruptedJob(t, jobsDir)
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	recovery, err := pipeline.RecoverJob(jobsDir, job.JobID, logger)
+	require.NoError(t, err)
+	assert.True(t, recovery.HasActions())
+	assert.Equal(t, string(models.StepLocalImport), recovery.InterruptedStep)
+	assert.Equal(t, []string{".state.tmp.1234", filepath.Join("import", "Observation.ndjson.part")}, recovery.RemovedFiles)
+
+	jobDir := services.GetJobDir(jobsDir, job.JobID)
+	assert.NoFileExists(t, filepath.Join(jobDir, "import", "Observation.ndjson.part"))
+	assert.FileExists(t, filepath.Join(jobDir, "import", "Patient.ndjson"), "complete files are kept")
+
+	recovered, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, recovered.Status)
+	step, found := models.GetStepByName(*recovered, models.StepLocalImport)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	require.NotNil(t, step.LastError)
+	assert.Equal(t, models.ErrorTypeTransient, step.LastError.Type)
+	assert.Contains(t, step.LastError.Message, "interrupted")
+
+	events, err := services.LoadJobEvents(jobsDir, job.JobID)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, models.EventJobInterrupted, events[0].Type)
+
+	// A second pass finds nothing left to do
+	recovery, err = pipeline.RecoverJob(jobsDir, job.JobID, logger)
+	require.NoError(t, err)
+	assert.False(t, recovery.HasActions())
+}
+
+func TestRecoverJob_SkipsRunningJob(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := saveInterruptedJob(t, jobsDir)
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	lock, err := services.AcquireJobLock(jobsDir, job.JobID, logger)
+	require.NoError(t, err)
+	defer func() { _ = lock.Release() }()
+
+	recovery, err := pipeline.RecoverJob(jobsDir, job.JobID, logger)
+	require.NoError(t, err)
+	assert.False(t, recovery.HasActions())
+	assert.FileExists(t, filepath.Join(services.GetJobDir(jobsDir, job.JobID), "import", "Observation.ndjson.part"))
+
+	unchanged, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusInProgress, unchanged.Status)
+}
+
+func TestRecoverJobs_OnlyReportsChangedJobs(t *testing.T) {
+	jobsDir := t.TempDir()
+	interrupted := saveInterruptedJob(t, jobsDir)
+	require.NoError(t, services.SaveJobState(jobsDir, createTestJob(uuid.New().String(), jobsDir)))
+
+	recovered, err := pipeline.RecoverJobs(jobsDir, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	require.Len(t, recovered, 1)
+	assert.Equal(t, interrupted.JobID, recovered[0].JobID)
+}
+
+func TestDownloadFromURL_LeavesNoPartialFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"resourceType":"Patient","id":"p1"}` + "\n"))
+	}))
+	defer server.Close()
+
+	destDir := t.TempDir()
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 1, MaxBackoffMs: 1}, lib.NewLogger(lib.LogLevelError))
+	files, err := services.DownloadFromURL(server.URL+"/Patient.ndjson", destDir, httpClient, lib.NewLogger(lib.LogLevelError), lib.NoProgress)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, 1, files[0].LineCount)
+
+	entries, err := os.ReadDir(destDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Patient.ndjson", entries[0].Name())
+}
@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestParseRepackageFormats verifies the --formats flag values
+func TestParseRepackageFormats(t *testing.T) {
+	steps, err := pipeline.ParseRepackageFormats("csv, parquet,csv")
+	require.NoError(t, err)
+	assert.Equal(t, []models.StepName{models.StepCSVConversion, models.StepParquetConversion}, steps)
+
+	_, err = pipeline.ParseRepackageFormats("csv,xlsx")
+	assert.ErrorContains(t, err, "unknown format 'xlsx'")
+	_, err = pipeline.ParseRepackageFormats(" , ")
+	assert.Error(t, err)
+}
+
+// TestDefaultRepackageSteps verifies repackage defaults to the formats the configuration can produce
+func TestDefaultRepackageSteps(t *testing.T) {
+	config := models.ProjectConfig{}
+	assert.Equal(t, []models.StepName{models.StepCSVConversion}, pipeline.DefaultRepackageSteps(config))
+
+	config.Pipeline.Packaging.Mode = models.PackagingModeLocal
+	assert.Equal(t, []models.StepName{models.StepCSVConversion, models.StepParquetConversion}, pipeline.DefaultRepackageSteps(config))
+
+	config.Services.CSVConversion.URL = "http://csv-converter:8080"
+	assert.Equal(t, []models.StepName{models.StepParquetConversion}, pipeline.DefaultRepackageSteps(config))
+}
+
+// createRepackageTestJob creates a job whose import, dimp and csv_conversion steps have completed
+func createRepackageTestJob(t *testing.T, withPseudonymizedData bool) *models.PipelineJob {
+	t.Helper()
+	jobsDir := t.TempDir()
+	enabled := []models.StepName{models.StepLocalImport, models.StepDIMP, models.StepCSVConversion}
+	job := &models.PipelineJob{
+		JobID:       "test-repackage-job",
+		InputSource: "/data/export",
+		InputType:   models.InputTypeLocal,
+		Status:      models.JobStatusCompleted,
+		Steps:       models.InitializeSteps(enabled),
+		Config: models.ProjectConfig{
+			JobsDir:  jobsDir,
+			Pipeline: models.PipelineConfig{EnabledSteps: enabled},
+		},
+	}
+	for i := range job.Steps {
+		job.Steps[i].Status = models.StepStatusCompleted
+	}
+
+	if withPseudonymizedData {
+		pseudonymizedDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepDIMP)
+		require.NoError(t, os.MkdirAll(pseudonymizedDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(pseudonymizedDir, "Patient.ndjson"), []byte("{\"resourceType\":\"Patient\",\"id\":\"x\"}\n"), 0644))
+	}
+	csvDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepCSVConversion)
+	require.NoError(t, os.MkdirAll(csvDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(csvDir, "Patient.csv"), []byte("old\n"), 0644))
+	return job
+}
+
+// TestPrepareRepackage verifies packaging steps are reset, old output removed and new settings picked up
+func TestPrepareRepackage(t *testing.T) {
+	job := createRepackageTestJob(t, true)
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	config := models.ProjectConfig{
+		Flatten: models.FlattenConfig{MappingFile: "/etc/aether/study-v2.yaml"},
+		Pipeline: models.PipelineConfig{Packaging: models.PackagingConfig{
+			Mode:    models.PackagingModeLocal,
+			Parquet: models.ParquetOptions{Compression: models.ParquetZstd},
+		}},
+	}
+	steps := []models.StepName{models.StepCSVConversion, models.StepParquetConversion}
+	passPath := filepath.Join(services.GetJobDir(job.Config.JobsDir, job.JobID), pipeline.PackagingPassFileName)
+	require.NoError(t, os.WriteFile(passPath, []byte(`{}`), 0644))
+
+	updated, err := pipeline.PrepareRepackage(job, steps, config, logger)
+	require.NoError(t, err)
+	assert.NoFileExists(t, passPath, "results of an earlier shared pass are dropped")
+
+	csvStep, found := models.GetStepByName(*updated, models.StepCSVConversion)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusPending, csvStep.Status)
+	parquetStep, found := models.GetStepByName(*updated, models.StepParquetConversion)
+	require.True(t, found, "formats not enabled at job creation are added")
+	assert.Equal(t, models.StepStatusPending, parquetStep.Status)
+	assert.Contains(t, updated.Config.Pipeline.EnabledSteps, models.StepParquetConversion)
+
+	importStep, _ := models.GetStepByName(*updated, models.StepLocalImport)
+	assert.Equal(t, models.StepStatusCompleted, importStep.Status, "import is not re-run")
+	dimpStep, _ := models.GetStepByName(*updated, models.StepDIMP)
+	assert.Equal(t, models.StepStatusCompleted, dimpStep.Status, "dimp is not re-run")
+
+	assert.Equal(t, "/etc/aether/study-v2.yaml", updated.Config.Flatten.MappingFile)
+	assert.Equal(t, models.ParquetZstd, updated.Config.Pipeline.Packaging.Parquet.Compression)
+	assert.NoDirExists(t, services.GetJobOutputDir(job.Config.JobsDir, job.JobID, models.StepCSVConversion))
+	assert.FileExists(t, filepath.Join(services.GetJobOutputDir(job.Config.JobsDir, job.JobID, models.StepDIMP), "Patient.ndjson"))
+
+	// The original job is not modified
+	originalCSV, _ := models.GetStepByName(*job, models.StepCSVConversion)
+	assert.Equal(t, models.StepStatusCompleted, originalCSV.Status)
+	assert.Len(t, job.Steps, 3)
+}
+
+// TestPrepareRepackage_RequiresPseudonymizedOutput verifies repackaging is refused without DIMP output
+func TestPrepareRepackage_RequiresPseudonymizedOutput(t *testing.T) {
+	logger := lib.NewLogger(lib.LogLevelError)
+	steps := []models.StepName{models.StepCSVConversion}
+
+	job := createRepackageTestJob(t, false)
+	_, err := pipeline.PrepareRepackage(job, steps, models.ProjectConfig{}, logger)
+	assert.ErrorContains(t, err, "missing or empty")
+
+	job = createRepackageTestJob(t, true)
+	job.Steps[1].Status = models.StepStatusFailed
+	_, err = pipeline.PrepareRepackage(job, steps, models.ProjectConfig{}, logger)
+	assert.ErrorContains(t, err, "no completed dimp step")
+	assert.FileExists(t, filepath.Join(services.GetJobOutputDir(job.Config.JobsDir, job.JobID, models.StepCSVConversion), "Patient.csv"), "nothing is removed when refused")
+}
+
+// TestPrepareRepackage_KeepsDeltaTables verifies Delta tables survive repackaging, so the step adds a version
+func TestPrepareRepackage_KeepsDeltaTables(t *testing.T) {
+	job := createRepackageTestJob(t, true)
+	deltaLog := filepath.Join(services.GetJobOutputDir(job.Config.JobsDir, job.JobID, models.StepParquetConversion), "Patient", services.DeltaLogDirName)
+	require.NoError(t, os.MkdirAll(deltaLog, 0755))
+
+	config := models.ProjectConfig{Pipeline: models.PipelineConfig{Packaging: models.PackagingConfig{
+		Mode:        models.PackagingModeLocal,
+		TableFormat: models.TableFormatDelta,
+	}}}
+	_, err := pipeline.PrepareRepackage(job, []models.StepName{models.StepParquetConversion}, config, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	assert.DirExists(t, deltaLog)
+}
+
+// TestPrepareRepackage_RefusesFormatsWithoutWriter verifies formats that would need a conversion
+// service are refused before any output is removed
+func TestPrepareRepackage_RefusesFormatsWithoutWriter(t *testing.T) {
+	logger := lib.NewLogger(lib.LogLevelError)
+	job := createRepackageTestJob(t, true)
+	csvTable := filepath.Join(services.GetJobOutputDir(job.Config.JobsDir, job.JobID, models.StepCSVConversion), "Patient.csv")
+
+	steps := []models.StepName{models.StepCSVConversion, models.StepParquetConversion}
+	_, err := pipeline.PrepareRepackage(job, steps, models.ProjectConfig{}, logger)
+	assert.ErrorContains(t, err, "set pipeline.packaging.mode: local")
+	assert.FileExists(t, csvTable)
+
+	config := models.ProjectConfig{Services: models.ServiceConfig{CSVConversion: models.CSVConversionConfig{URL: "http://csv-converter:8080"}}}
+	_, err = pipeline.PrepareRepackage(job, []models.StepName{models.StepCSVConversion}, config, logger)
+	assert.ErrorContains(t, err, "services.csv_conversion.url")
+	assert.FileExists(t, csvTable)
+}
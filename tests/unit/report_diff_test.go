@@ -0,0 +1,90 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// saveDiffJob saves a CRTDL job whose import and DIMP steps completed, writing lines to its DIMP output
+func saveDiffJob(t *testing.T, jobsDir string, crtdl string, dimpDuration time.Duration, lines string, warnings ...models.StepWarning) *models.PipelineJob {
+	t.Helper()
+	started := time.Now().Add(-time.Hour)
+	importDone := started.Add(time.Minute)
+	dimpDone := importDone.Add(dimpDuration)
+
+	job := &models.PipelineJob{
+		JobID:       uuid.New().String(),
+		CreatedAt:   started,
+		InputSource: crtdl,
+		InputType:   models.InputTypeCRTDL,
+		Status:      models.JobStatusCompleted,
+		Steps: []models.PipelineStep{
+			{Name: models.StepTorchImport, Status: models.StepStatusCompleted, StartedAt: &started, CompletedAt: &importDone, FilesProcessed: 1},
+			{Name: models.StepDIMP, Status: models.StepStatusCompleted, StartedAt: &importDone, CompletedAt: &dimpDone, FilesProcessed: 1, Warnings: warnings},
+		},
+		Config: models.ProjectConfig{JobsDir: jobsDir},
+	}
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+
+	outputDir := services.GetJobOutputDir(jobsDir, job.JobID, models.StepDIMP)
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "dimped_data.ndjson"), []byte(lines), 0644))
+	return job
+}
+
+func writeDiffCRTDL(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "cohort.crtdl")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestDiffJobs_ComparesSteps(t *testing.T) {
+	jobsDir := t.TempDir()
+	a := saveDiffJob(t, jobsDir, writeDiffCRTDL(t, `{"cohortDefinition":{}}`), 10*time.Minute,
+		`{"resourceType":"Patient"}`+"\n"+`{"resourceType":"Observation"}`+"\n")
+	b := saveDiffJob(t, jobsDir, writeDiffCRTDL(t, `{"cohortDefinition":{}}`), 15*time.Minute,
+		`{"resourceType":"Patient"}`+"\n\n",
+		models.StepWarning{Code: models.WarningEmptyLines, File: "data.ndjson", Count: 1})
+
+	diff, err := services.DiffJobs(jobsDir, a.JobID, b.JobID)
+	require.NoError(t, err)
+	assert.True(t, diff.SameInput, "CRTDLs are compared by content: %s", diff.InputNote)
+
+	require.Len(t, diff.Steps, 2)
+	dimp := diff.Steps[1]
+	assert.Equal(t, models.StepDIMP, dimp.Step)
+	assert.Equal(t, 600.0, dimp.A.DurationSeconds)
+	assert.Equal(t, 900.0, dimp.B.DurationSeconds)
+	require.NotNil(t, dimp.A.Resources)
+	require.NotNil(t, dimp.B.Resources)
+	assert.Equal(t, 2, *dimp.A.Resources)
+	assert.Equal(t, 1, *dimp.B.Resources, "empty lines are not counted")
+	assert.Empty(t, dimp.A.Warnings)
+	assert.Equal(t, map[models.WarningCode]int{models.WarningEmptyLines: 1}, dimp.B.Warnings)
+
+	torch := diff.Steps[0]
+	assert.Nil(t, torch.A.Resources, "the import output was not written")
+}
+
+func TestDiffJobs_DifferentInputs(t *testing.T) {
+	jobsDir := t.TempDir()
+	a := saveDiffJob(t, jobsDir, writeDiffCRTDL(t, `{"a":1}`), time.Minute, "")
+	b := saveDiffJob(t, jobsDir, writeDiffCRTDL(t, `{"b":2}`), time.Minute, "")
+
+	diff, err := services.DiffJobs(jobsDir, a.JobID, b.JobID)
+	require.NoError(t, err)
+	assert.False(t, diff.SameInput)
+	assert.Contains(t, diff.InputNote, "different CRTDLs")
+
+	_, err = services.DiffJobs(jobsDir, a.JobID, "missing-job")
+	assert.Error(t, err)
+}
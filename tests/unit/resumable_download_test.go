@@ -0,0 +1,164 @@
+package unit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// flakyFileServer serves content but breaks the first connection after half of the body
+// Range requests are answered with 206 if If-Range matches the current ETag
+type flakyFileServer struct {
+	mu      sync.Mutex
+	content string
+	etag    string
+	ranges  []string // Range header of each request
+	broken  bool
+	nextTag string // ETag after the broken response, to simulate a file changed on the server
+}
+
+func (s *flakyFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+	}
+
+	var start int
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && r.Header.Get("If-Range") == s.etag && s.etag != "" {
+		_, _ = fmt.Sscanf(rangeHeader, "bytes=%d-", &start)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(s.content)-1, len(s.content)))
+		w.Header().Set("Content-Length", fmt.Sprint(len(s.content)-start))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte(s.content[start:]))
+		return
+	}
+
+	w.Header().Set("Content-Length", fmt.Sprint(len(s.content)))
+	if !s.broken {
+		// Declaring more than is written makes the server drop the connection
+		s.broken = true
+		_, _ = w.Write([]byte(s.content[:len(s.content)/2]))
+		if s.nextTag != "" {
+			s.etag = s.nextTag
+		}
+		return
+	}
+	_, _ = w.Write([]byte(s.content))
+}
+
+func resumableTestContent() string {
+	var b strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, "{\"resourceType\":\"Patient\",\"id\":\"p%d\"}\n", i)
+	}
+	return b.String()
+}
+
+func newResumableTestClient() *services.HTTPClient {
+	return services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 3, InitialBackoffMs: 1, MaxBackoffMs: 2}, lib.NewLogger(lib.LogLevelError))
+}
+
+// TestDownloadFromURL_ResumesWithRange verifies a broken download continues where it stopped
+func TestDownloadFromURL_ResumesWithRange(t *testing.T) {
+	content := resumableTestContent()
+	fileServer := &flakyFileServer{content: content, etag: `"v1"`}
+	server := httptest.NewServer(fileServer)
+	defer server.Close()
+
+	destDir := t.TempDir()
+	files, err := services.DownloadFromURL(server.URL+"/Patient.ndjson", destDir, newResumableTestClient(), lib.NewLogger(lib.LogLevelError), lib.NoProgress)
+	require.NoError(t, err)
+
+	require.Len(t, fileServer.ranges, 2)
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", len(content)/2), fileServer.ranges[1])
+	data, err := os.ReadFile(filepath.Join(destDir, "Patient.ndjson"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, 2000, files[0].LineCount)
+	assert.Equal(t, int64(len(content)), files[0].FileSize)
+}
+
+// TestDownloadFromURL_RestartsWithoutValidator verifies a download without ETag starts over
+// and its resources are counted once
+func TestDownloadFromURL_RestartsWithoutValidator(t *testing.T) {
+	content := resumableTestContent()
+	fileServer := &flakyFileServer{content: content}
+	server := httptest.NewServer(fileServer)
+	defer server.Close()
+
+	destDir := t.TempDir()
+	files, err := services.DownloadFromURL(server.URL+"/Patient.ndjson", destDir, newResumableTestClient(), lib.NewLogger(lib.LogLevelError), lib.NoProgress)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", ""}, fileServer.ranges, "no Range without a validator")
+	data, err := os.ReadFile(filepath.Join(destDir, "Patient.ndjson"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, 2000, files[0].LineCount)
+}
+
+// TestTORCHClient_DownloadResumesWithRange verifies TORCH file downloads resume with Range
+// and that a file changed on the server is downloaded again in full
+func TestTORCHClient_DownloadResumesWithRange(t *testing.T) {
+	content := resumableTestContent()
+	fileServer := &flakyFileServer{content: content, etag: `"v1"`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("Authorization"), "resume requests are authorized")
+		fileServer.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	logger := lib.NewLogger(lib.LogLevelError)
+	client := services.NewTORCHClient(models.TORCHConfig{BaseURL: server.URL, Username: "u", Password: "p"}, newResumableTestClient(), logger)
+
+	destDir := t.TempDir()
+	files, err := client.DownloadExtractionFiles([]string{server.URL + "/batch-1.ndjson"}, destDir, lib.NoProgress)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, 2000, files[0].LineCount)
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", len(content)/2), fileServer.ranges[1])
+
+	// The file changes between the broken and the resumed request: If-Range fails, 200 restarts it
+	fileServer.mu.Lock()
+	fileServer.broken = false
+	fileServer.ranges = nil
+	fileServer.nextTag = `"v2"`
+	fileServer.mu.Unlock()
+	files, err = client.DownloadExtractionFiles([]string{server.URL + "/batch-2.ndjson"}, destDir, lib.NoProgress)
+	require.NoError(t, err)
+	assert.Equal(t, 2000, files[0].LineCount, "resources of the discarded part are not counted")
+	assert.Len(t, fileServer.ranges, 2)
+	data, err := os.ReadFile(filepath.Join(destDir, "batch-2.ndjson"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+// TestTORCHClient_DownloadInterruptedTooOften verifies the download fails once retries are used up
+func TestTORCHClient_DownloadInterruptedTooOften(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		_, _ = w.Write([]byte(`{"resourceType":"Patient"}`))
+	}))
+	defer server.Close()
+
+	client := services.NewTORCHClient(models.TORCHConfig{BaseURL: server.URL, Username: "u", Password: "p"}, newResumableTestClient(), lib.NewLogger(lib.LogLevelError))
+	destDir := t.TempDir()
+	_, err := client.DownloadExtractionFiles([]string{server.URL + "/batch.ndjson"}, destDir, lib.NoProgress)
+	assert.ErrorContains(t, err, "download interrupted")
+	assert.NoFileExists(t, filepath.Join(destDir, "batch.ndjson"+services.PartialFileSuffix))
+}
@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -45,7 +46,7 @@ func TestRetryImportStep_StepNotFound(t *testing.T) {
 	}
 
 	// Attempt retry
-	updatedJob, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Verify error
 	require.Error(t, err)
@@ -92,7 +93,7 @@ func TestRetryImportStep_NoErrorToRetry(t *testing.T) {
 	}
 
 	// Attempt retry
-	updatedJob, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Verify error
 	require.Error(t, err)
@@ -144,7 +145,7 @@ func TestRetryImportStep_NonTransientError(t *testing.T) {
 	}
 
 	// Attempt retry
-	updatedJob, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Verify retry is rejected
 	require.Error(t, err)
@@ -197,7 +198,7 @@ func TestRetryImportStep_MaxRetriesExceeded(t *testing.T) {
 	}
 
 	// Attempt retry
-	updatedJob, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+	updatedJob, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Verify retry is rejected
 	require.Error(t, err)
@@ -275,7 +276,7 @@ func TestRetryImportStep_RetryCountIncrement(t *testing.T) {
 
 			// Attempt retry - this will call ExecuteImportStep which will fail
 			// But we can verify the retry count was incremented before the call
-			_, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+			_, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 			// The retry should have been attempted (error from ExecuteImportStep is expected)
 			assert.Error(t, err, "ExecuteImportStep should fail with empty directory")
@@ -380,7 +381,7 @@ func TestRetryImportStep_LastRetryAttempt(t *testing.T) {
 	}
 
 	// Attempt retry - should be allowed
-	_, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+	_, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 	// Retry should be attempted (ExecuteImportStep will fail, but retry was allowed)
 	assert.Error(t, err, "ExecuteImportStep should fail with empty directory")
@@ -443,7 +444,7 @@ func TestRetryImportStep_MultipleRetriesProgression(t *testing.T) {
 				},
 			}
 
-			_, err := pipeline.RetryImportStep(job, logger, httpClient, false)
+			_, err := pipeline.RetryImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
 
 			require.Error(t, err)
 			if state.shouldAllow {
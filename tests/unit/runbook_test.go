@@ -0,0 +1,111 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestClassifyFailure verifies the failure codes derived from step errors
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *models.StepError
+		message  string
+		expected string
+	}{
+		{"interrupted", &models.StepError{Message: "interrupted: the process running the job ended unexpectedly"}, "", services.FailureInterrupted},
+		{"cancelled", &models.StepError{Message: "job cancelled"}, "", services.FailureCancelled},
+		{"auth by status", &models.StepError{Type: models.ErrorTypeNonTransient, HTTPStatus: 401, Message: "unauthorized"}, "", services.FailureAuth},
+		{"auth in message", &models.StepError{Message: "TORCH submit error: HTTP 403: forbidden"}, "", services.FailureAuth},
+		{"payload too large", &models.StepError{HTTPStatus: 413, Message: "request entity too large"}, "", services.FailurePayloadTooLarge},
+		{"oversized entry", &models.StepError{Message: "resource Binary/b1 (20000000 bytes) exceeds threshold (10485760 bytes). Raise it"}, "", services.FailureOversizedEntry},
+		{"line too long", &models.StepError{Message: "line 3 of a.ndjson exceeds limits.max_line_size_mb (100 MB): too long"}, "", services.FailureLineTooLong},
+		{"gone", &models.StepError{HTTPStatus: 410, Message: "gone"}, "", services.FailureNotFound},
+		{"server error", &models.StepError{Type: models.ErrorTypeTransient, HTTPStatus: 503, Message: "unavailable"}, "", services.FailureServiceError},
+		{"timeout", &models.StepError{Message: "TORCH extraction failed: TORCH extraction timeout exceeded"}, "", services.FailureTimeout},
+		{"network", &models.StepError{Type: models.ErrorTypeTransient, Message: "dial tcp: connection refused"}, "", services.FailureNetwork},
+		{"strict", &models.StepError{Message: "strict mode: step dimp has 2 warnings that fail the step: non_fhir_lines"}, "", services.FailureStrictWarnings},
+		{"other transient", &models.StepError{Type: models.ErrorTypeTransient, Message: "temporary failure"}, "", services.FailureTransient},
+		{"job message only", nil, "job exceeded its maximum runtime of 1h0m0s (running since 10:00)", services.FailureRuntimeExceeded},
+		{"unknown", &models.StepError{Type: models.ErrorTypeNonTransient, Message: "invalid CRTDL"}, "", services.FailureStepFailed},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, services.ClassifyFailure(tt.err, tt.message), tt.name)
+	}
+}
+
+func newRunbookTestJob(jobsDir string) *models.PipelineJob {
+	now := time.Now()
+	return &models.PipelineJob{
+		JobID:       uuid.New().String(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		InputSource: "/data/in",
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepDIMP),
+		Status:      models.JobStatusInProgress,
+		Steps: []models.PipelineStep{
+			{Name: models.StepLocalImport, Status: models.StepStatusCompleted},
+			{Name: models.StepDIMP, Status: models.StepStatusInProgress, StartedAt: &now},
+		},
+		Config: models.ProjectConfig{
+			Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport, models.StepDIMP}},
+			Retry:    models.RetryConfig{MaxAttempts: 5, InitialBackoffMs: 1000, MaxBackoffMs: 30000},
+			JobsDir:  jobsDir,
+		},
+	}
+}
+
+// TestSaveJobState_WritesRunbookForFailedJob verifies RUNBOOK.md contents and its removal on resume
+func TestSaveJobState_WritesRunbookForFailedJob(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := newRunbookTestJob(jobsDir)
+	runbookPath := filepath.Join(services.GetJobDir(jobsDir, job.JobID), services.RunbookFileName)
+
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+	assert.NoFileExists(t, runbookPath, "running jobs have no runbook")
+
+	failed := models.ReplaceStep(*job, models.FailStep(job.Steps[1], models.ErrorTypeNonTransient, "DIMP returned HTTP 413: request entity too large", 413))
+	failed.Steps[1].LastError.RequestID = "req-123"
+	failed.Steps[1].LastError.Context = []string{"level=INFO msg=\"Processing file\" file=Patient.ndjson"}
+	failed = models.AddError(failed, "step dimp failed")
+	require.NoError(t, services.SaveJobState(jobsDir, &failed))
+
+	data, err := os.ReadFile(runbookPath)
+	require.NoError(t, err)
+	runbook := string(data)
+	assert.Contains(t, runbook, "- **Step:** `dimp`")
+	assert.Contains(t, runbook, "- **Error code:** `payload_too_large`")
+	assert.Contains(t, runbook, "- **Request ID:** `req-123`")
+	assert.Contains(t, runbook, "bundle_split_threshold_mb")
+	assert.Contains(t, runbook, "aether pipeline continue "+job.JobID+" --jobs-dir "+jobsDir)
+	assert.Contains(t, runbook, "aether job run "+job.JobID+" --step dimp --jobs-dir "+jobsDir)
+	assert.Contains(t, runbook, "Processing file")
+
+	// Resuming the job removes the stale runbook
+	resumed := models.UpdateJobStatus(failed, models.JobStatusInProgress)
+	require.NoError(t, services.SaveJobState(jobsDir, &resumed))
+	assert.NoFileExists(t, runbookPath)
+}
+
+// TestRenderRunbook_TenantAndQuoting verifies that tenant jobs get their tenant flag and paths are shell-quoted
+func TestRenderRunbook_TenantAndQuoting(t *testing.T) {
+	baseDir := filepath.Join(t.TempDir(), "jobs dir")
+	job := newRunbookTestJob(filepath.Join(baseDir, "tenants", "clinic-a"))
+	job.Config.Tenant = "clinic-a"
+	failed := models.AddError(*job, "interrupted: the process running the job ended unexpectedly")
+
+	runbook := services.RenderRunbook(&failed)
+	assert.Contains(t, runbook, "`job_interrupted`")
+	assert.Contains(t, runbook, "--jobs-dir '"+baseDir+"' --tenant clinic-a")
+	assert.Contains(t, runbook, "aether job run "+job.JobID+" --step dimp", "the current step is retried if no step recorded a failure")
+}
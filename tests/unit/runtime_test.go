@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestWatchJobRuntime_AbortsRunawayExtraction verifies an overlong run cancels TORCH, fails the step and notifies
+func TestWatchJobRuntime_AbortsRunawayExtraction(t *testing.T) {
+	var mu sync.Mutex
+	var cancelled bool
+	var notification pipeline.RuntimeExceededNotification
+
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/hook":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		case r.Method == http.MethodPost:
+			w.Header().Set("Content-Location", serverURL+"/fhir/extraction/never-done")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete:
+			cancelled = true
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusAccepted) // Extraction keeps running
+		}
+	}))
+	serverURL = server.URL
+
+	tmpDir := t.TempDir()
+	crtdlPath := filepath.Join(tmpDir, "cohort.crtdl")
+	require.NoError(t, os.WriteFile(crtdlPath, []byte(`{"cohortDefinition":{"inclusionCriteria":[]},"dataExtraction":{"attributeGroups":[]}}`), 0644))
+
+	jobsDir := filepath.Join(tmpDir, "jobs")
+	config := models.ProjectConfig{
+		Services: models.ServiceConfig{TORCH: models.TORCHConfig{
+			BaseURL:                   server.URL,
+			Username:                  "user",
+			Password:                  "pass",
+			ExtractionTimeoutMinutes:  30,
+			PollingIntervalSeconds:    1,
+			MaxPollingIntervalSeconds: 1,
+		}},
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepTorchImport, models.StepDIMP}},
+		Retry:    models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		SLA:      models.SLAConfig{WebhookURL: server.URL + "/hook"},
+		JobsDir:  jobsDir,
+	}
+	logger := lib.NewLogger(lib.LogLevelError)
+	job, err := pipeline.CreateJob(crtdlPath, config, logger)
+	require.NoError(t, err)
+	job = pipeline.StartJob(job)
+	require.NoError(t, pipeline.UpdateJob(jobsDir, job))
+
+	aborted := make(chan struct{})
+	stop := pipeline.WatchJobRuntime(job, 300*time.Millisecond, logger, func() { close(aborted) })
+	defer stop()
+
+	stepDone := make(chan struct{})
+	go func() {
+		defer close(stepDone)
+		_, _ = pipeline.ExecuteImportStep(context.Background(), job, logger, services.NewHTTPClient(5*time.Second, config.Retry, logger), lib.NoProgress)
+	}()
+
+	select {
+	case <-aborted:
+	case <-time.After(10 * time.Second):
+		t.Fatal("runtime watchdog did not fire")
+	}
+
+	// The step goroutine ends once TORCH becomes unreachable
+	server.Close()
+	<-stepDone
+
+	mu.Lock()
+	assert.True(t, cancelled, "the TORCH extraction should be cancelled")
+	assert.Equal(t, job.JobID, notification.JobID)
+	assert.Equal(t, string(models.StepTorchImport), notification.Step)
+	mu.Unlock()
+
+	persisted, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, persisted.Status)
+	assert.Contains(t, persisted.ErrorMessage, "maximum runtime")
+	step, found := models.GetStepByName(*persisted, models.StepTorchImport)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	require.NotNil(t, step.LastError)
+	assert.Equal(t, models.ErrorTypeNonTransient, step.LastError.Type)
+	dimpStep, _ := models.GetStepByName(*persisted, models.StepDIMP)
+	assert.Equal(t, models.StepStatusPending, dimpStep.Status, "later steps stay pending")
+
+	events, err := services.LoadJobEvents(jobsDir, job.JobID)
+	require.NoError(t, err)
+	var exceeded bool
+	for _, event := range events {
+		exceeded = exceeded || event.Type == models.EventRuntimeExceeded
+	}
+	assert.True(t, exceeded, "a runtime_exceeded event should be recorded")
+}
+
+// TestWatchJobRuntime_StopBeforeDeadline verifies a finished run is never aborted
+func TestWatchJobRuntime_StopBeforeDeadline(t *testing.T) {
+	job := &models.PipelineJob{JobID: "finished", Config: models.ProjectConfig{JobsDir: t.TempDir()}}
+	aborted := make(chan struct{})
+
+	stop := pipeline.WatchJobRuntime(job, 50*time.Millisecond, lib.NewLogger(lib.LogLevelError), func() { close(aborted) })
+	stop()
+
+	select {
+	case <-aborted:
+		t.Fatal("stopped watch must not abort")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// A zero limit disables the watch
+	pipeline.WatchJobRuntime(job, 0, lib.NewLogger(lib.LogLevelError), func() { t.Fatal("disabled watch must not abort") })()
+}
+
+// TestConfigLoading_MaxRuntime verifies pipeline.max_runtime_minutes is loaded and validated
+func TestConfigLoading_MaxRuntime(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+  max_runtime_minutes: 240
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, 4*time.Hour, config.Pipeline.GetMaxRuntime())
+	assert.Zero(t, models.PipelineConfig{}.GetMaxRuntime())
+
+	config.Pipeline.MaxRuntimeMinutes = -1
+	assert.Error(t, config.Validate())
+}
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// TestSanityCheck_Validate verifies malformed checks are rejected
+func TestSanityCheck_Validate(t *testing.T) {
+	assert.NoError(t, models.SanityCheck{Name: "patients", Min: intPtr(1)}.Validate())
+	assert.Error(t, models.SanityCheck{Min: intPtr(1)}.Validate(), "name is required")
+	assert.Error(t, models.SanityCheck{Name: "no bounds"}.Validate())
+	assert.Error(t, models.SanityCheck{Name: "inverted", Min: intPtr(5), Max: intPtr(1)}.Validate())
+}
+
+// TestRunSanityChecks verifies counts across files are compared against expected ranges
+func TestRunSanityChecks(t *testing.T) {
+	dataDir := t.TempDir()
+	writeDIMPNDJSON(t, filepath.Join(dataDir, "a.ndjson"), []map[string]any{
+		{"resourceType": "Patient", "id": "p1", "birthDate": "2015-01-01"},
+		{"resourceType": "Patient", "id": "p2", "birthDate": "1980-01-01"},
+		{"resourceType": "Observation", "id": "o1"},
+	})
+	writeDIMPNDJSON(t, filepath.Join(dataDir, "b.ndjson"), []map[string]any{
+		{"resourceType": "Patient", "id": "p3", "birthDate": "1975-06-01"},
+	})
+
+	checks := []models.SanityCheck{
+		{Name: "patients", ResourceType: "Patient", Min: intPtr(1), Max: intPtr(10)},
+		{Name: "born after 2010", ResourceType: "Patient", Where: "birthDate > @2010-01-01", Max: intPtr(0)},
+		{Name: "observations", ResourceType: "Observation", Min: intPtr(1)},
+	}
+
+	results, err := pipeline.RunSanityChecks(dataDir, checks, 1024*1024, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, 3, results[0].Count)
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, 1, results[1].Count)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, 1, results[2].Count)
+	assert.True(t, results[2].Passed)
+	assert.False(t, pipeline.AllSanityChecksPassed(results))
+}
+
+// TestRunSanityChecks_InvalidExpression verifies FHIRPath errors name the offending check
+func TestRunSanityChecks_InvalidExpression(t *testing.T) {
+	checks := []models.SanityCheck{{Name: "broken", Where: "birthDate >", Max: intPtr(0)}}
+
+	_, err := pipeline.RunSanityChecks(t.TempDir(), checks, 1024*1024, lib.NewLogger(lib.LogLevelError))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sanity check 'broken'")
+}
+
+// TestConfigLoading_SanityChecks verifies sanity checks are loaded from YAML
+func TestConfigLoading_SanityChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	jobsDir := filepath.Join(tmpDir, "jobs")
+
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+sanity_checks:
+  - name: "Patients in cohort"
+    resource_type: Patient
+    min: 1
+    max: 5000
+  - name: "No recent births"
+    resource_type: Patient
+    where: "birthDate > @2010-01-01"
+    max: 0
+
+jobs_dir: "` + jobsDir + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+
+	require.Len(t, config.SanityChecks, 2)
+	assert.Equal(t, "Patients in cohort", config.SanityChecks[0].Name)
+	assert.Equal(t, "Patient", config.SanityChecks[0].ResourceType)
+	require.NotNil(t, config.SanityChecks[0].Min)
+	assert.Equal(t, 1, *config.SanityChecks[0].Min)
+	assert.Nil(t, config.SanityChecks[1].Min)
+	require.NotNil(t, config.SanityChecks[1].Max)
+	assert.Equal(t, 0, *config.SanityChecks[1].Max)
+	assert.Equal(t, "birthDate > @2010-01-01", config.SanityChecks[1].Where)
+}
@@ -0,0 +1,122 @@
+package unit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+var producedPatientColumns = []services.DeltaColumn{
+	{Name: "patient_id", Type: "string"},
+	{Name: "birth_date", Type: "string"},
+	{Name: "gender", Type: "string"},
+}
+
+// TestParseExpectedSchema_Formats verifies raw and registry-wrapped JSON Schema and Avro documents
+func TestParseExpectedSchema_Formats(t *testing.T) {
+	jsonSchema, err := services.ParseExpectedSchema([]byte(`{
+		"type": "object",
+		"properties": {"patient_id": {"type": "string"}, "age": {"type": ["integer", "null"]}},
+		"required": ["patient_id"],
+		"additionalProperties": false
+	}`), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.SchemaFormatJSON, jsonSchema.Format)
+	assert.True(t, jsonSchema.ClosedFields)
+	assert.Equal(t, []services.ExpectedField{
+		{Name: "age", Types: []string{"integer", "null"}},
+		{Name: "patient_id", Types: []string{"string"}, Required: true},
+	}, jsonSchema.Fields)
+
+	avro, err := services.ParseExpectedSchema([]byte(`{"subject": "aether-Patient-value", "version": 3,
+		"schema": "{\"type\":\"record\",\"name\":\"Patient\",\"fields\":[{\"name\":\"patient_id\",\"type\":\"string\"},{\"name\":\"birth_date\",\"type\":[\"null\",{\"type\":\"int\",\"logicalType\":\"date\"}]},{\"name\":\"gender\",\"type\":\"string\",\"default\":\"unknown\"}]}"}`), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.SchemaFormatAvro, avro.Format)
+	assert.Equal(t, []services.ExpectedField{
+		{Name: "patient_id", Types: []string{"string"}, Required: true},
+		{Name: "birth_date", Types: []string{"null", "date"}},
+		{Name: "gender", Types: []string{"string"}},
+	}, avro.Fields)
+
+	wrappedJSON, err := services.ParseExpectedSchema([]byte(`{"schemaType": "JSON", "schema": "{\"properties\":{\"gender\":{\"type\":\"string\"}}}"}`), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.SchemaFormatJSON, wrappedJSON.Format)
+
+	_, err = services.ParseExpectedSchema([]byte(`{"schemaType": "PROTOBUF", "schema": "syntax = \"proto3\";"}`), "")
+	assert.ErrorContains(t, err, "unsupported schema type")
+	_, err = services.ParseExpectedSchema([]byte(`{"type": "array"}`), models.SchemaFormatJSON)
+	assert.ErrorContains(t, err, "must describe an object")
+}
+
+// TestCheckTableSchema verifies missing, mistyped and extra columns
+func TestCheckTableSchema(t *testing.T) {
+	compatible := &services.ExpectedTableSchema{Format: models.SchemaFormatAvro, Fields: []services.ExpectedField{
+		{Name: "patient_id", Types: []string{"string"}, Required: true},
+		{Name: "deceased", Types: []string{"null", "string"}}, // Optional and not produced
+	}}
+	assert.Empty(t, services.CheckTableSchema(compatible, producedPatientColumns), "Avro readers ignore extra columns")
+
+	breaking := &services.ExpectedTableSchema{Format: models.SchemaFormatJSON, ClosedFields: true, Fields: []services.ExpectedField{
+		{Name: "patient_id", Types: []string{"string"}, Required: true},
+		{Name: "birth_date", Types: []string{"integer"}},
+		{Name: "age", Types: []string{"integer"}, Required: true},
+	}}
+	assert.Equal(t, []string{
+		"column birth_date is string, the registered schema expects integer",
+		"required column age is missing",
+		"column gender is not in the registered schema",
+	}, services.CheckTableSchema(breaking, producedPatientColumns))
+
+	promoted := &services.ExpectedTableSchema{Format: models.SchemaFormatAvro, Fields: []services.ExpectedField{{Name: "count", Types: []string{"double"}}}}
+	assert.Empty(t, services.CheckTableSchema(promoted, []services.DeltaColumn{{Name: "count", Type: "long"}}), "long promotes to double")
+}
+
+// TestValidateTableSchemas verifies tables are checked against the registry and unregistered tables are skipped
+func TestValidateTableSchemas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/subjects/aether-Patient/versions/latest":
+			_, _ = w.Write([]byte(`{"type":"object","properties":{"patient_id":{"type":"string"},"age":{"type":"integer"}},"required":["patient_id","age"]}`))
+		case "/subjects/aether-Condition/versions/latest":
+			_, _ = w.Write([]byte(`{"type":"record","name":"Condition","fields":[{"name":"condition_id","type":"string"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	config := models.ExpectedSchemaConfig{URL: server.URL + "/subjects/aether-{table}/versions/latest"}
+	httpClient := newResumableTestClient()
+	logger := lib.NewLogger(lib.LogLevelError)
+	tables := map[string][]services.DeltaColumn{
+		"Patient":     producedPatientColumns,
+		"Condition":   {{Name: "condition_id", Type: "string"}},
+		"Observation": {{Name: "observation_id", Type: "string"}},
+	}
+
+	err := services.ValidateTableSchemas(config, tables, httpClient, logger)
+	var incompatible *services.SchemaIncompatibleError
+	require.True(t, errors.As(err, &incompatible))
+	assert.Equal(t, []string{"Patient: required column age is missing"}, incompatible.Problems)
+
+	delete(tables, "Patient")
+	assert.NoError(t, services.ValidateTableSchemas(config, tables, httpClient, logger))
+}
+
+// TestExpectedSchemaConfig_Validate verifies the registry URL needs a table placeholder
+func TestExpectedSchemaConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.ExpectedSchemaConfig{}.Validate())
+	assert.NoError(t, models.ExpectedSchemaConfig{URL: "https://registry/subjects/{table}/versions/latest", Format: models.SchemaFormatAvro}.Validate())
+	assert.ErrorContains(t, models.ExpectedSchemaConfig{URL: "https://registry/schema.json"}.Validate(), "{table}")
+	assert.ErrorContains(t, models.ExpectedSchemaConfig{URL: "registry/{table}"}.Validate(), "http(s) URL")
+	assert.Error(t, models.ExpectedSchemaConfig{URL: "https://registry/{table}", Format: "protobuf"}.Validate())
+	assert.Equal(t, "https://registry/subjects/aether-Patient", models.ExpectedSchemaConfig{URL: "https://registry/subjects/aether-{table}"}.TableURL("Patient"))
+}
@@ -0,0 +1,155 @@
+package unit
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/server"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const (
+	viewerToken    = "viewer-token-0123456789"
+	submitterToken = "submitter-token-0123456789"
+	cardioToken    = "cardio-operator-0123456789"
+)
+
+// TestAPIRole_Allows verifies the permission matrix of the roles
+func TestAPIRole_Allows(t *testing.T) {
+	assert.True(t, models.RoleViewer.Allows(models.PermissionRead))
+	assert.False(t, models.RoleViewer.Allows(models.PermissionShare))
+	assert.True(t, models.RoleSubmitter.Allows(models.PermissionSubmit))
+	assert.False(t, models.RoleSubmitter.Allows(models.PermissionControl))
+	assert.True(t, models.RoleOperator.Allows(models.PermissionControl))
+	assert.False(t, models.APIRole("admin").IsValid())
+}
+
+// TestServerConfig_ValidateTokens verifies token definitions are checked
+func TestServerConfig_ValidateTokens(t *testing.T) {
+	valid := models.APIToken{Name: "ci", Token: viewerToken, Role: models.RoleViewer}
+	assert.NoError(t, models.ServerConfig{Tokens: []models.APIToken{valid}}.Validate())
+
+	short := valid
+	short.Token = "short"
+	assert.ErrorContains(t, models.ServerConfig{Tokens: []models.APIToken{short}}.Validate(), "at least 16")
+
+	badRole := valid
+	badRole.Role = "admin"
+	assert.ErrorContains(t, models.ServerConfig{Tokens: []models.APIToken{badRole}}.Validate(), "invalid role")
+
+	assert.ErrorContains(t, models.ServerConfig{Tokens: []models.APIToken{valid, valid}}.Validate(), "duplicate")
+}
+
+// TestServer_TokenAuthAndAudit verifies authentication, role checks, tenant restriction and the audit log
+func TestServer_TokenAuthAndAudit(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	config.Server = models.ServerConfig{
+		PublicURL:   "https://aether.example.org",
+		ShareSecret: testShareSecret,
+		Tokens: []models.APIToken{
+			{Name: "dashboard", Token: viewerToken, Role: models.RoleViewer},
+			{Name: "study-desk", Token: submitterToken, Role: models.RoleSubmitter},
+			{Name: "cardio-ops", Token: cardioToken, Role: models.RoleOperator, Tenant: "cardio"},
+		},
+	}
+	cardio, err := services.ScopeConfigToTenant(&config, "cardio")
+	require.NoError(t, err)
+	job, err := pipeline.CreateJob(t.TempDir(), *cardio, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(server.New(config, lib.NewLogger(lib.LogLevelError)).Handler())
+	defer srv.Close()
+
+	request := func(method string, path string, token string) int {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	jobPath := "/api/v1/tenants/cardio/jobs/" + job.JobID
+	assert.Equal(t, http.StatusUnauthorized, request("GET", jobPath, ""))
+	assert.Equal(t, http.StatusUnauthorized, request("GET", jobPath, "wrong-token-0123456789"))
+	assert.Equal(t, http.StatusOK, request("GET", jobPath, viewerToken))
+	assert.Equal(t, http.StatusForbidden, request("POST", jobPath+"/share", viewerToken))
+	assert.Equal(t, http.StatusCreated, request("POST", jobPath+"/share", submitterToken))
+
+	// Tenant-restricted tokens only reach their own tenant
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/tenants/cardio/jobs", cardioToken))
+	assert.Equal(t, http.StatusForbidden, request("GET", "/api/v1/tenants/onco/jobs", cardioToken))
+	assert.Equal(t, http.StatusForbidden, request("GET", "/api/v1/jobs", cardioToken))
+
+	entries := readAuditLog(t, config.JobsDir)
+	require.Len(t, entries, 8)
+	assert.Equal(t, "anonymous", entries[0].Principal)
+	assert.Equal(t, http.StatusUnauthorized, entries[0].Status)
+	assert.Equal(t, "dashboard", entries[2].Principal)
+	assert.Equal(t, models.RoleViewer, entries[2].Role)
+	assert.Equal(t, "cardio", entries[2].Tenant)
+	assert.Equal(t, job.JobID, entries[2].JobID)
+	assert.Equal(t, "study-desk", entries[4].Principal)
+	assert.Equal(t, http.StatusCreated, entries[4].Status)
+	for _, entry := range entries {
+		assert.False(t, strings.Contains(entry.Path, "Bearer"))
+	}
+}
+
+// TestServer_ReadOnlyWithoutTokens verifies an API without server.tokens serves reads and refuses share links
+func TestServer_ReadOnlyWithoutTokens(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	config.Server = models.ServerConfig{PublicURL: "https://aether.example.org", ShareSecret: testShareSecret}
+	cardio, err := services.ScopeConfigToTenant(&config, "cardio")
+	require.NoError(t, err)
+	job, err := pipeline.CreateJob(t.TempDir(), *cardio, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(server.New(config, lib.NewLogger(lib.LogLevelError)).Handler())
+	defer srv.Close()
+
+	jobPath := srv.URL + "/api/v1/tenants/cardio/jobs/" + job.JobID
+	resp, err := http.Get(jobPath)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post(jobPath+"/share", "", nil)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	entries := readAuditLog(t, config.JobsDir)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "anonymous", entries[1].Principal)
+	assert.Equal(t, models.RoleViewer, entries[1].Role)
+}
+
+// readAuditLog parses <jobs_dir>/audit.ndjson
+func readAuditLog(t *testing.T, jobsDir string) []models.AuditEntry {
+	file, err := os.Open(services.GetAuditFilePath(jobsDir))
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	var entries []models.AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry models.AuditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,143 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/server"
+	"github.com/trobanga/aether/internal/services"
+)
+
+const testShareSecret = "0123456789abcdef0123"
+
+// TestShareToken_SignAndVerify verifies signature, expiry and tamper detection
+func TestShareToken_SignAndVerify(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	token, err := services.SignShareToken(testShareSecret, "cardio", "job-1", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	grant, err := services.VerifyShareToken(testShareSecret, token, now)
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", grant.JobID)
+	assert.Equal(t, "cardio", grant.Tenant)
+	assert.Equal(t, now.Add(time.Hour).Unix(), grant.ExpiresAt.Unix())
+
+	_, err = services.VerifyShareToken(testShareSecret, token, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, services.ErrShareExpired)
+
+	_, err = services.VerifyShareToken("another-secret-value", token, now)
+	assert.ErrorIs(t, err, services.ErrShareInvalid)
+
+	// Changing the payload invalidates the signature
+	other, err := services.SignShareToken(testShareSecret, "", "job-2", now.Add(time.Hour))
+	require.NoError(t, err)
+	forged := strings.SplitN(other, ".", 2)[0] + "." + strings.SplitN(token, ".", 2)[1]
+	_, err = services.VerifyShareToken(testShareSecret, forged, now)
+	assert.ErrorIs(t, err, services.ErrShareInvalid)
+
+	_, err = services.SignShareToken("", "", "job-1", now)
+	assert.Error(t, err)
+}
+
+// TestServer_ShareLinks verifies that share links expose the status and report files of one job only
+func TestServer_ShareLinks(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := &models.PipelineJob{
+		JobID:       uuid.New().String(),
+		CreatedAt:   time.Now(),
+		InputSource: "/data/secret-cohort",
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepLocalImport),
+		Status:      models.JobStatusInProgress,
+		Steps:       models.InitializeSteps([]models.StepName{models.StepLocalImport}),
+		Config:      models.ProjectConfig{JobsDir: jobsDir},
+	}
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+	csvDir := filepath.Join(services.GetJobDir(jobsDir, job.JobID), "csv")
+	require.NoError(t, os.MkdirAll(csvDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(csvDir, services.DataDictionaryFileName), []byte("table,column\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(csvDir, "Patient.csv"), []byte("id\n"), 0644))
+
+	config := models.ProjectConfig{
+		JobsDir: jobsDir,
+		Server: models.ServerConfig{
+			PublicURL:   "https://aether.example.org/",
+			ShareSecret: testShareSecret,
+			Tokens:      []models.APIToken{{Name: "study-desk", Token: submitterToken, Role: models.RoleSubmitter}},
+		},
+	}
+	srv := httptest.NewServer(server.New(config, lib.NewLogger(lib.LogLevelError)).Handler())
+	defer srv.Close()
+
+	shareRequest, err := http.NewRequest("POST", srv.URL+"/api/v1/jobs/"+job.JobID+"/share?ttl=1h", nil)
+	require.NoError(t, err)
+	shareRequest.Header.Set("Authorization", "Bearer "+submitterToken)
+	resp, err := http.DefaultClient.Do(shareRequest)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var link server.ShareLink
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&link))
+	assert.True(t, strings.HasPrefix(link.URL, "https://aether.example.org/share/"))
+
+	// Status via share link, without the input source
+	statusResp, err := http.Get(srv.URL + "/share/" + link.Token)
+	require.NoError(t, err)
+	defer func() { _ = statusResp.Body.Close() }()
+	require.Equal(t, http.StatusOK, statusResp.StatusCode)
+	var raw map[string]any
+	require.NoError(t, json.NewDecoder(statusResp.Body).Decode(&raw))
+	assert.Equal(t, job.JobID, raw["job_id"])
+	assert.NotContains(t, raw, "input_source")
+	assert.NotContains(t, raw, "config")
+
+	// Report files are shared, data files are not
+	artifactResp, err := http.Get(srv.URL + "/share/" + link.Token + "/artifacts/csv/" + services.DataDictionaryFileName)
+	require.NoError(t, err)
+	_ = artifactResp.Body.Close()
+	assert.Equal(t, http.StatusOK, artifactResp.StatusCode)
+
+	dataResp, err := http.Get(srv.URL + "/share/" + link.Token + "/artifacts/csv/Patient.csv")
+	require.NoError(t, err)
+	_ = dataResp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, dataResp.StatusCode)
+
+	badResp, err := http.Get(srv.URL + "/share/not-a-token")
+	require.NoError(t, err)
+	_ = badResp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, badResp.StatusCode)
+
+	expired, err := services.SignShareToken(testShareSecret, "", job.JobID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	expiredResp, err := http.Get(srv.URL + "/share/" + expired)
+	require.NoError(t, err)
+	_ = expiredResp.Body.Close()
+	assert.Equal(t, http.StatusGone, expiredResp.StatusCode)
+}
+
+// TestCreateShareLink_RequiresConfiguration verifies sharing fails clearly when not configured
+func TestCreateShareLink_RequiresConfiguration(t *testing.T) {
+	_, err := server.CreateShareLink(models.ProjectConfig{}, "job-1", 0, time.Now())
+	assert.ErrorContains(t, err, "share_secret")
+
+	config := models.ProjectConfig{Server: models.ServerConfig{ShareSecret: testShareSecret}}
+	_, err = server.CreateShareLink(config, "job-1", 0, time.Now())
+	assert.ErrorContains(t, err, "public_url")
+
+	config.Server.PublicURL = "https://aether.example.org"
+	now := time.Now()
+	link, err := server.CreateShareLink(config, "job-1", 0, now)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(models.DefaultShareTTLHours*time.Hour), link.ExpiresAt, time.Second)
+}
@@ -0,0 +1,182 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestSLAConfig_ThresholdAndValidate verifies per-step thresholds and config validation
+func TestSLAConfig_ThresholdAndValidate(t *testing.T) {
+	sla := models.SLAConfig{StepMinutes: map[models.StepName]int{models.StepTorchImport: 90}}
+	assert.Equal(t, 90*time.Minute, sla.Threshold(models.StepTorchImport))
+	assert.Zero(t, sla.Threshold(models.StepDIMP))
+	assert.NoError(t, sla.Validate())
+
+	assert.Error(t, models.SLAConfig{StepMinutes: map[models.StepName]int{"bogus": 5}}.Validate())
+	assert.Error(t, models.SLAConfig{StepMinutes: map[models.StepName]int{models.StepDIMP: 0}}.Validate())
+	assert.Error(t, models.SLAConfig{WebhookURL: "ftp://example.com/hook"}.Validate())
+	assert.Error(t, models.SLAConfig{WebhookTemplate: `{"text":"x"}`}.Validate(), "a template needs a webhook URL")
+	assert.Error(t, models.SLAConfig{WebhookURL: "https://example.com/hook", WebhookTemplate: `{"text":{{.Job.JobID}`}.Validate())
+}
+
+// TestRenderWebhookPayload verifies templates are rendered over the event and job and must yield JSON
+func TestRenderWebhookPayload(t *testing.T) {
+	data := services.WebhookTemplateData{
+		Event:        "sla_exceeded",
+		Notification: pipeline.SLAExceededNotification{JobID: "job-1", Step: "dimp", Message: `step "dimp" is slow`},
+		Job:          &models.PipelineJob{JobID: "job-1", Status: models.JobStatusInProgress},
+	}
+
+	body, err := services.RenderWebhookPayload(
+		`{"text": {{json .Notification.Message}}, "event": "{{upper .Event}}", "status": "{{.Job.Status}}"}`, data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text": "step \"dimp\" is slow", "event": "SLA_EXCEEDED", "status": "in_progress"}`, string(body))
+
+	_, err = services.RenderWebhookPayload(`{"text": {{.Notification.Message}}}`, data)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "valid JSON")
+
+	_, err = services.RenderWebhookPayload(`{"id": "{{.Job.Unknown}}"}`, data)
+	assert.Error(t, err)
+}
+
+// TestWatchStepSLA_TemplatedWebhook verifies the webhook receives the rendered template instead of the default payload
+func TestWatchStepSLA_TemplatedWebhook(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer webhook.Close()
+
+	jobsDir := t.TempDir()
+	job := &models.PipelineJob{JobID: "sla-job"}
+	job.Config.JobsDir = jobsDir
+	job.Config.SLA.WebhookURL = webhook.URL
+	job.Config.SLA.WebhookTemplate = `{"summary": "{{.Event}}: {{.Job.JobID}}/{{.Notification.Step}}"}`
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, job.JobID), 0755))
+
+	stop := pipeline.WatchStepSLA(job, models.StepDIMP, 20*time.Millisecond, lib.NewLogger(lib.LogLevelError))
+	defer stop()
+
+	select {
+	case payload := <-received:
+		assert.Equal(t, map[string]any{"summary": "sla_exceeded: sla-job/dimp"}, payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SLA webhook was not called")
+	}
+}
+
+// TestWatchStepSLA_Exceeded verifies an exceeded SLA records an event and posts the webhook
+func TestWatchStepSLA_Exceeded(t *testing.T) {
+	received := make(chan pipeline.SLAExceededNotification, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification pipeline.SLAExceededNotification
+		_ = json.NewDecoder(r.Body).Decode(&notification)
+		received <- notification
+	}))
+	defer webhook.Close()
+
+	jobsDir := t.TempDir()
+	job := &models.PipelineJob{JobID: "sla-job"}
+	job.Config.JobsDir = jobsDir
+	job.Config.SLA.WebhookURL = webhook.URL
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, job.JobID), 0755))
+
+	stop := pipeline.WatchStepSLA(job, models.StepTorchImport, 20*time.Millisecond, lib.NewLogger(lib.LogLevelError))
+	defer stop()
+
+	select {
+	case notification := <-received:
+		assert.Equal(t, "sla-job", notification.JobID)
+		assert.Equal(t, "torch", notification.Step)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SLA webhook was not called")
+	}
+
+	require.Eventually(t, func() bool {
+		events, err := services.LoadJobEvents(jobsDir, job.JobID)
+		return err == nil && len(events) == 1 && events[0].Type == models.EventSLAExceeded
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// TestWatchStepSLA_StoppedInTime verifies no warning is raised when the step finishes within its SLA
+func TestWatchStepSLA_StoppedInTime(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := &models.PipelineJob{JobID: "sla-job"}
+	job.Config.JobsDir = jobsDir
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, job.JobID), 0755))
+
+	stop := pipeline.WatchStepSLA(job, models.StepDIMP, 50*time.Millisecond, lib.NewLogger(lib.LogLevelError))
+	stop()
+	time.Sleep(100 * time.Millisecond)
+
+	events, err := services.LoadJobEvents(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+// TestConfigLoading_SLA verifies sla settings are read from YAML
+func TestConfigLoading_SLA(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	jobsDir := filepath.Join(tmpDir, "jobs")
+
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+sla:
+  step_minutes:
+    local_import: 15
+  webhook_url: "https://alerts.example.com/aether"
+
+jobs_dir: "` + jobsDir + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, 15*time.Minute, config.SLA.Threshold(models.StepLocalImport))
+	assert.Equal(t, "https://alerts.example.com/aether", config.SLA.WebhookURL)
+}
+
+// TestConfigLoading_SLAWebhookTemplateFile verifies the webhook template is read from a file
+func TestConfigLoading_SLAWebhookTemplateFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	templateFile := filepath.Join(tmpDir, "webhook.tmpl")
+	require.NoError(t, os.WriteFile(templateFile, []byte(`{"text": {{json .Notification.Message}}}`), 0644))
+
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+
+sla:
+  webhook_url: "https://alerts.example.com/aether"
+  webhook_template_file: "` + templateFile + `"
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, `{"text": {{json .Notification.Message}}}`, config.SLA.WebhookTemplate)
+}
@@ -311,3 +311,31 @@ func createTestJob(jobID, jobsDir string) *models.PipelineJob {
 		TotalBytes: 0,
 	}
 }
+
+// TestResolveJobFile verifies bare filenames are searched in data directories and paths are confined to the job
+func TestResolveJobFile(t *testing.T) {
+	jobsDir := t.TempDir()
+	jobID := uuid.New().String()
+	jobDir := services.GetJobDir(jobsDir, jobID)
+	require.NoError(t, os.MkdirAll(filepath.Join(jobDir, "pseudonymized"), 0755))
+	require.NoError(t, os.WriteFile(services.GetStateFilePath(jobsDir, jobID), []byte("{}"), 0644))
+	dimped := filepath.Join(jobDir, "pseudonymized", "dimped_Patient.ndjson")
+	require.NoError(t, os.WriteFile(dimped, []byte("{}\n"), 0644))
+
+	path, err := services.ResolveJobFile(jobsDir, jobID, "dimped_Patient.ndjson")
+	require.NoError(t, err)
+	assert.Equal(t, dimped, path)
+
+	path, err = services.ResolveJobFile(jobsDir, jobID, "pseudonymized/dimped_Patient.ndjson")
+	require.NoError(t, err)
+	assert.Equal(t, dimped, path)
+
+	_, err = services.ResolveJobFile(jobsDir, jobID, "missing.ndjson")
+	assert.Error(t, err)
+
+	_, err = services.ResolveJobFile(jobsDir, jobID, "../../etc/passwd")
+	assert.Error(t, err)
+
+	_, err = services.ResolveJobFile(jobsDir, "no-such-job", "dimped_Patient.ndjson")
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// saveStatsJob saves a job created at created whose torch and DIMP steps took the given durations;
+// a failed DIMP step is recorded with a transient error
+func saveStatsJob(t *testing.T, jobsDir string, created time.Time, torch, dimp time.Duration, dimpStatus models.StepStatus) *models.PipelineJob {
+	t.Helper()
+	torchDone := created.Add(torch)
+	dimpDone := torchDone.Add(dimp)
+
+	dimpStep := models.PipelineStep{Name: models.StepDIMP, Status: dimpStatus, StartedAt: &torchDone, FilesProcessed: 2, BytesProcessed: 2048, RetryCount: 1}
+	status := models.JobStatusCompleted
+	if dimpStatus == models.StepStatusFailed {
+		status = models.JobStatusFailed
+		dimpStep.LastError = &models.StepError{Type: models.ErrorTypeTransient, Message: "HTTP 503"}
+	} else {
+		dimpStep.CompletedAt = &dimpDone
+	}
+
+	job := &models.PipelineJob{
+		JobID:       uuid.New().String(),
+		CreatedAt:   created,
+		UpdatedAt:   dimpDone,
+		InputSource: "cohort.crtdl",
+		InputType:   models.InputTypeCRTDL,
+		Status:      status,
+		TotalFiles:  2,
+		TotalBytes:  4096,
+		Steps: []models.PipelineStep{
+			{Name: models.StepTorchImport, Status: models.StepStatusCompleted, StartedAt: &created, CompletedAt: &torchDone, FilesProcessed: 2, BytesProcessed: 2048},
+			dimpStep,
+			{Name: models.StepCSVConversion, Status: models.StepStatusPending},
+		},
+		Config: models.ProjectConfig{JobsDir: jobsDir},
+	}
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+	return job
+}
+
+func TestAggregateJobStats(t *testing.T) {
+	jobsDir := t.TempDir()
+	now := time.Now()
+	saveStatsJob(t, jobsDir, now.Add(-48*time.Hour), 10*time.Minute, 20*time.Minute, models.StepStatusCompleted)
+	saveStatsJob(t, jobsDir, now.Add(-24*time.Hour), 30*time.Minute, 40*time.Minute, models.StepStatusCompleted)
+	saveStatsJob(t, jobsDir, now.Add(-2*time.Hour), 20*time.Minute, 5*time.Minute, models.StepStatusFailed)
+	saveStatsJob(t, jobsDir, now.Add(-60*24*time.Hour), time.Hour, time.Hour, models.StepStatusCompleted) // outside the window
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, "broken"), 0755))
+	require.NoError(t, os.WriteFile(services.GetStateFilePath(jobsDir, "broken"), []byte("{"), 0644))
+
+	stats, err := services.AggregateJobStats(jobsDir, now.Add(-30*24*time.Hour), lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, stats.Jobs)
+	assert.Equal(t, 2, stats.Completed)
+	assert.Equal(t, 1, stats.Failed)
+	assert.Equal(t, 1, stats.Unreadable)
+	assert.Equal(t, int64(3*4096), stats.Bytes)
+	assert.Equal(t, 2, stats.Durations.Count)
+	assert.Equal(t, (70 * time.Minute).Seconds(), stats.Durations.Max)
+
+	require.Len(t, stats.Steps, 2, "pending steps are not counted")
+	torch := stats.Steps[0]
+	assert.Equal(t, models.StepTorchImport, torch.Step)
+	assert.Equal(t, "torch", torch.Service)
+	assert.Equal(t, 3, torch.Runs)
+	assert.Equal(t, 3, torch.Durations.Count)
+	assert.Equal(t, (20 * time.Minute).Seconds(), torch.Durations.Median)
+	assert.Equal(t, (30 * time.Minute).Seconds(), torch.Durations.P95)
+
+	dimp := stats.Steps[1]
+	assert.Equal(t, 2, dimp.Completed)
+	assert.Equal(t, 1, dimp.Failed)
+	assert.InDelta(t, 1.0/3, dimp.FailureRate, 0.001)
+	assert.Equal(t, 3, dimp.Retries)
+	assert.Equal(t, int64(3*2048), dimp.Bytes)
+	assert.Equal(t, 2, dimp.Durations.Count, "only completed runs have a duration")
+
+	require.Len(t, stats.Services, 2)
+	assert.Equal(t, "torch", stats.Services[0].Service)
+	assert.Equal(t, "dimp", stats.Services[1].Service)
+	assert.Equal(t, 1, stats.Services[1].TransientFailures)
+
+	all, err := services.AggregateJobStats(jobsDir, time.Time{}, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	assert.Equal(t, 4, all.Jobs)
+	assert.Nil(t, all.Since)
+}
+
+func TestAggregateJobStats_EmptyJobsDir(t *testing.T) {
+	stats, err := services.AggregateJobStats(filepath.Join(t.TempDir(), "missing"), time.Time{}, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	assert.Zero(t, stats.Jobs)
+	assert.Empty(t, stats.Steps)
+	assert.Empty(t, stats.Services)
+}
+
+func TestParseDuration(t *testing.T) {
+	for input, expected := range map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+		"0d":  0,
+	} {
+		duration, err := lib.ParseDuration(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, duration, input)
+	}
+
+	for _, input := range []string{"", "d", "-3d", "1.5d", "month"} {
+		_, err := lib.ParseDuration(input)
+		assert.Error(t, err, input)
+	}
+}
+
+// TestAggregateJobStats_DIMPUsage verifies DIMP usage is summed over the included jobs and the
+// monthly counters are listed from the month of since on
+func TestAggregateJobStats_DIMPUsage(t *testing.T) {
+	jobsDir := t.TempDir()
+	now := time.Now()
+	for _, created := range []time.Time{now.Add(-time.Hour), now.Add(-2 * time.Hour), now.Add(-400 * 24 * time.Hour)} {
+		job := saveStatsJob(t, jobsDir, created, time.Minute, time.Minute, models.StepStatusCompleted)
+		job.DIMPUsage = &models.DIMPUsage{Requests: 2, Resources: 10, Bytes: 1000}
+		require.NoError(t, services.SaveJobState(jobsDir, job))
+	}
+	_, err := services.RecordDIMPUsage(jobsDir, services.DIMPUsageMonth(now.AddDate(-1, -1, 0)), models.DIMPUsage{Resources: 10})
+	require.NoError(t, err)
+	_, err = services.RecordDIMPUsage(jobsDir, services.DIMPUsageMonth(now), models.DIMPUsage{Resources: 20})
+	require.NoError(t, err)
+
+	stats, err := services.AggregateJobStats(jobsDir, now.Add(-24*time.Hour), lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	assert.Equal(t, models.DIMPUsage{Requests: 4, Resources: 20, Bytes: 2000}, stats.DIMPUsage)
+	require.Len(t, stats.DIMPMonths, 1)
+	assert.Equal(t, services.DIMPUsageMonth(now), stats.DIMPMonths[0].Month)
+
+	all, err := services.AggregateJobStats(jobsDir, time.Time{}, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), all.DIMPUsage.Resources)
+	assert.Len(t, all.DIMPMonths, 2)
+}
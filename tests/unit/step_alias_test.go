@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func TestCanonicalStepNames(t *testing.T) {
+	steps, err := models.CanonicalStepNames([]string{"import", "dimp"})
+	require.NoError(t, err)
+	assert.Equal(t, []models.StepName{models.StepTorchImport, models.StepLocalImport, models.StepHttpImport, models.StepDIMP}, steps)
+
+	steps, err = models.CanonicalStepNames([]string{"torch_import", "dimp", "bogus"})
+	require.NoError(t, err)
+	assert.Equal(t, []models.StepName{models.StepTorchImport, models.StepDIMP, "bogus"}, steps, "unknown names are left for validation")
+
+	_, err = models.CanonicalStepNames([]string{"import", "local_import"})
+	assert.ErrorContains(t, err, "'import' and 'local_import' both enable local_import")
+
+	_, err = models.CanonicalStepNames([]string{"torch", "torch_import"})
+	assert.ErrorContains(t, err, "both enable torch")
+
+	_, err = models.CanonicalStepNames([]string{"dimp", "dimp"})
+	assert.ErrorContains(t, err, "listed more than once")
+}
+
+func TestCanonicalStepName(t *testing.T) {
+	assert.Equal(t, models.StepLocalImport, models.CanonicalStepName("import", models.InputTypeLocal))
+	assert.Equal(t, models.StepTorchImport, models.CanonicalStepName("import", models.InputTypeCRTDL))
+	assert.Equal(t, models.StepTorchImport, models.CanonicalStepName("torch_import", models.InputTypeLocal))
+	assert.Equal(t, models.StepDIMP, models.CanonicalStepName("dimp", models.InputTypeLocal))
+}
+
+func loadStepAliasConfig(t *testing.T, content string) (*models.ProjectConfig, error) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("jobs_dir: "+filepath.Join(tmpDir, "jobs")+"\n"+content), 0644))
+	viper.Reset()
+	return services.LoadConfig(configFile)
+}
+
+func TestLoadConfig_ImportAlias(t *testing.T) {
+	config, err := loadStepAliasConfig(t, `
+pipeline:
+  enabled_steps: [import, dimp]
+services:
+  dimp:
+    url: "http://dimp:8083/fhir"
+sla:
+  step_minutes:
+    import: 30
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []models.StepName{models.StepLocalImport, models.StepHttpImport, models.StepDIMP}, config.Pipeline.EnabledSteps,
+		"without a TORCH base_url, import does not enable torch")
+	assert.Equal(t, 30, config.SLA.StepMinutes[models.StepLocalImport])
+	assert.Equal(t, 30, config.SLA.StepMinutes[models.StepTorchImport])
+
+	config, err = loadStepAliasConfig(t, `
+pipeline:
+  enabled_steps: [import]
+services:
+  torch:
+    base_url: "http://torch:8080"
+    extraction_timeout_minutes: 30
+    polling_interval_seconds: 5
+    max_polling_interval_seconds: 30
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []models.StepName{models.StepTorchImport, models.StepLocalImport, models.StepHttpImport}, config.Pipeline.EnabledSteps)
+}
+
+func TestLoadConfig_IncompatibleImportSteps(t *testing.T) {
+	_, err := loadStepAliasConfig(t, `
+pipeline:
+  enabled_steps: [import, local_import]
+`)
+	assert.ErrorContains(t, err, "invalid pipeline.enabled_steps")
+}
+
+func TestLoadJobState_MigratesStepAliases(t *testing.T) {
+	jobsDir := t.TempDir()
+	job := createTestJob(uuid.New().String(), jobsDir)
+	job.InputType = models.InputTypeCRTDL
+	job.CurrentStep = string(models.StepTorchImport)
+	job.Steps[0].Name = models.StepTorchImport
+	job.Config.Pipeline.EnabledSteps = []models.StepName{models.StepTorchImport}
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+
+	// A state file written with the torch_import alias (step values, not the "torch" config key)
+	statePath := services.GetStateFilePath(jobsDir, job.JobID)
+	data, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	data = regexp.MustCompile(`"torch"(\s*[,\]\n])`).ReplaceAll(data, []byte(`"torch_import"$1`))
+	require.Contains(t, string(data), `"current_step": "torch_import"`)
+	require.NoError(t, os.WriteFile(statePath, data, 0644))
+
+	loaded, err := services.LoadJobState(jobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.StepTorchImport), loaded.CurrentStep)
+	assert.Equal(t, models.StepTorchImport, loaded.Steps[0].Name)
+	assert.Equal(t, []models.StepName{models.StepTorchImport}, loaded.Config.Pipeline.EnabledSteps)
+}
@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// newStepRunnerJob returns a local import job with a job directory for its event log
+func newStepRunnerJob(t *testing.T) *models.PipelineJob {
+	t.Helper()
+	jobsDir := t.TempDir()
+	job := createTestJob(uuid.New().String(), jobsDir)
+	require.NoError(t, os.MkdirAll(services.GetJobDir(jobsDir, job.JobID), 0755))
+	return job
+}
+
+func stepEventTypes(t *testing.T, job *models.PipelineJob) []models.JobEventType {
+	t.Helper()
+	events, err := services.LoadJobEvents(job.Config.JobsDir, job.JobID)
+	require.NoError(t, err)
+	var types []models.JobEventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestRunStep_RecordsTimedEvents(t *testing.T) {
+	job := newStepRunnerJob(t)
+
+	err := pipeline.RunStep(context.Background(), job, models.StepLocalImport, lib.NewLogger(lib.LogLevelError), func(ctx context.Context) error {
+		require.NotNil(t, ctx)
+		job.Steps[0].FilesProcessed = 3
+		return nil
+	})
+	require.NoError(t, err)
+
+	events, err := services.LoadJobEvents(job.Config.JobsDir, job.JobID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventStepStarted, events[0].Type)
+	assert.Equal(t, models.EventStepCompleted, events[1].Type)
+	assert.Equal(t, float64(3), events[1].Fields["files"])
+	assert.Contains(t, events[1].Fields, "duration_seconds")
+}
+
+func TestRunStep_FailureAttachesLogContext(t *testing.T) {
+	job := newStepRunnerJob(t)
+	logger := lib.NewLogger(lib.LogLevelError)
+
+	err := pipeline.RunStep(context.Background(), job, models.StepLocalImport, logger, func(ctx context.Context) error {
+		logger.Error("upstream returned garbage")
+		failed := models.FailStep(job.Steps[0], models.ErrorTypeTransient, "HTTP 503", 503)
+		job.Steps[0] = failed
+		return errors.New("HTTP 503")
+	})
+	require.EqualError(t, err, "HTTP 503")
+
+	assert.Equal(t, []models.JobEventType{models.EventStepStarted, models.EventStepFailed}, stepEventTypes(t, job))
+	require.NotNil(t, job.Steps[0].LastError)
+	assert.Equal(t, models.ErrorTypeTransient, job.Steps[0].LastError.Type, "the step's own error type is kept")
+	assert.NotEmpty(t, job.Steps[0].LastError.Context)
+}
+
+func TestRunStep_RecoversPanic(t *testing.T) {
+	job := newStepRunnerJob(t)
+
+	err := pipeline.RunStep(context.Background(), job, models.StepLocalImport, lib.NewLogger(lib.LogLevelError), func(ctx context.Context) error {
+		var resources map[string]int
+		resources["Patient"]++ // nil map write
+		return nil
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step local_import panicked")
+
+	step := job.Steps[0]
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	require.NotNil(t, step.LastError)
+	assert.Equal(t, models.ErrorTypeNonTransient, step.LastError.Type)
+	assert.Contains(t, step.LastError.Message, "assignment to entry in nil map")
+	assert.Equal(t, []models.JobEventType{models.EventStepStarted, models.EventStepFailed}, stepEventTypes(t, job))
+}
+
+func TestRunStep_CancelledContextMarksStepCancelled(t *testing.T) {
+	job := newStepRunnerJob(t)
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	err := pipeline.RunStep(ctx, job, models.StepLocalImport, lib.NewLogger(lib.LogLevelError), func(ctx context.Context) error {
+		cancel(errors.New("received interrupt"))
+		<-ctx.Done()
+		job.Steps[0] = models.FailStep(job.Steps[0], models.ErrorTypeTransient, ctx.Err().Error(), 0)
+		return ctx.Err()
+	})
+	require.ErrorIs(t, err, pipeline.ErrStepCancelled)
+	assert.Contains(t, err.Error(), "received interrupt")
+
+	step := job.Steps[0]
+	assert.Equal(t, models.StepStatusCancelled, step.Status)
+	require.NotNil(t, step.LastError)
+	assert.Equal(t, models.ErrorTypeTransient, step.LastError.Type, "a cancelled step can be resumed")
+	assert.Equal(t, []models.JobEventType{models.EventStepStarted, models.EventStepCancelled}, stepEventTypes(t, job))
+	assert.True(t, step.Status.CanTransitionTo(models.StepStatusInProgress))
+}
+
+func TestRunStep_ErrorWithoutCancellationIsNotCancelled(t *testing.T) {
+	job := newStepRunnerJob(t)
+
+	err := pipeline.RunStep(context.Background(), job, models.StepLocalImport, lib.NewLogger(lib.LogLevelError), func(ctx context.Context) error {
+		job.Steps[0] = models.FailStep(job.Steps[0], models.ErrorTypeNonTransient, "bad input", 0)
+		return errors.New("bad input")
+	})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, pipeline.ErrStepCancelled)
+	assert.Equal(t, models.StepStatusFailed, job.Steps[0].Status)
+}
@@ -0,0 +1,52 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestCheckJobsDir verifies that a usable jobs directory passes and is created if missing
+func TestCheckJobsDir(t *testing.T) {
+	jobsDir := filepath.Join(t.TempDir(), "jobs")
+	require.NoError(t, services.CheckJobsDir(jobsDir, models.StorageConfig{MinFreeMB: 1, MinFreeInodes: 1}))
+	assert.DirExists(t, jobsDir)
+
+	entries, err := os.ReadDir(jobsDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the write probe is removed")
+}
+
+// TestCheckJobsDir_Failures verifies the diagnostics for unusable jobs directories
+func TestCheckJobsDir_Failures(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	err := services.CheckJobsDir(filepath.Join(file, "jobs"), models.StorageConfig{})
+	assert.ErrorContains(t, err, "cannot create jobs directory")
+	assert.ErrorContains(t, err, "--jobs-dir")
+
+	err = services.CheckJobsDir(t.TempDir(), models.StorageConfig{MinFreeMB: 1 << 40})
+	if err == nil {
+		t.Skip("filesystem statistics are not available on this platform")
+	}
+	assert.ErrorContains(t, err, "storage.min_free_mb")
+
+	assert.NoError(t, services.CheckJobsDir(t.TempDir(), models.StorageConfig{MinFreeMB: -1, MinFreeInodes: -1}))
+}
+
+// TestStorageConfig verifies defaults and validation of the storage thresholds
+func TestStorageConfig(t *testing.T) {
+	assert.Equal(t, uint64(models.DefaultStorageMinFreeMB*1024*1024), models.StorageConfig{}.GetMinFreeBytes())
+	assert.Equal(t, uint64(models.DefaultStorageMinFreeInodes), models.StorageConfig{}.GetMinFreeInodes())
+	assert.Zero(t, models.StorageConfig{MinFreeMB: -1}.GetMinFreeBytes())
+	assert.Zero(t, models.StorageConfig{MinFreeInodes: -1}.GetMinFreeInodes())
+
+	assert.NoError(t, models.StorageConfig{MinFreeMB: -1, MinFreeInodes: 500}.Validate())
+	assert.Error(t, models.StorageConfig{MinFreeMB: -2}.Validate())
+}
@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// runStrictDIMPJob pseudonymizes a file with one blank line and one resource of an unknown type
+func runStrictDIMPJob(t *testing.T, configure func(*models.PipelineConfig)) (*models.PipelineJob, error) {
+	server := createMockDIMPServer()
+	t.Cleanup(server.Close)
+
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = t.TempDir()
+	configure(&job.Config.Pipeline)
+	jobDir := services.GetJobDir(job.Config.JobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	content := `{"resourceType":"Patient","id":"p1"}` + "\n\n" + `{"resourceType":"Observaton","id":"o1"}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(importDir, "data.ndjson"), []byte(content), 0644))
+
+	return job, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress)
+}
+
+func TestStrictMode_UnknownResourceTypeFailsStep(t *testing.T) {
+	job, err := runStrictDIMPJob(t, func(c *models.PipelineConfig) { c.Strict = true })
+
+	var strictErr *pipeline.StrictModeError
+	require.True(t, errors.As(err, &strictErr))
+	assert.Equal(t, models.StepDIMP, strictErr.Step)
+	require.Len(t, strictErr.Warnings, 1, "empty lines are not escalated by default")
+	assert.Equal(t, models.WarningUnknownResourceType, strictErr.Warnings[0].Code)
+	assert.Contains(t, err.Error(), "Observaton")
+
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusFailed, step.Status)
+	assert.Equal(t, models.ErrorTypeNonTransient, step.LastError.Type)
+	assert.Len(t, step.Warnings, 2, "the warnings stay on the failed step")
+}
+
+func TestStrictMode_DisabledKeepsWarnings(t *testing.T) {
+	job, err := runStrictDIMPJob(t, func(c *models.PipelineConfig) {})
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.WarningCount())
+}
+
+func TestStrictMode_StrictWarningsOverrideDefaults(t *testing.T) {
+	_, err := runStrictDIMPJob(t, func(c *models.PipelineConfig) {
+		c.Strict = true
+		c.StrictWarnings = []models.WarningCode{models.WarningEmptyLines}
+	})
+
+	var strictErr *pipeline.StrictModeError
+	require.True(t, errors.As(err, &strictErr))
+	require.Len(t, strictErr.Warnings, 1)
+	assert.Equal(t, models.WarningEmptyLines, strictErr.Warnings[0].Code)
+}
+
+func TestPipelineConfig_IsStrictWarning(t *testing.T) {
+	assert.False(t, models.PipelineConfig{}.IsStrictWarning(models.WarningUnknownResourceType))
+	assert.True(t, models.PipelineConfig{Strict: true}.IsStrictWarning(models.WarningNonFHIRLines))
+	assert.False(t, models.PipelineConfig{Strict: true}.IsStrictWarning(models.WarningFilesIgnored))
+	assert.False(t, models.PipelineConfig{StrictWarnings: []models.WarningCode{models.WarningFilesIgnored}}.IsStrictWarning(models.WarningFilesIgnored),
+		"strict_warnings has no effect without strict")
+}
+
+func TestValidateStrictWarnings(t *testing.T) {
+	assert.NoError(t, models.ValidateStrictWarnings([]models.WarningCode{models.WarningFilesExcluded, models.WarningEmptyResult}))
+	err := models.ValidateStrictWarnings([]models.WarningCode{"broken_references"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken_references")
+}
+
+func TestConfigLoading_Strict(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+pipeline:
+  enabled_steps:
+    - local_import
+    - dimp
+  strict: true
+  strict_warnings:
+    - unknown_resource_type
+    - files_ignored
+
+services:
+  dimp:
+    url: "http://localhost:32861/fhir"
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.True(t, config.Pipeline.Strict)
+	assert.Equal(t, []models.WarningCode{models.WarningUnknownResourceType, models.WarningFilesIgnored}, config.Pipeline.StrictWarnings)
+}
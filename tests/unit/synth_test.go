@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/services"
+)
+
+func readSynthResources(t *testing.T, dir string, resourceType string) []map[string]any {
+	file, err := os.Open(filepath.Join(dir, resourceType+".ndjson"))
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	var resources []map[string]any
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var resource map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &resource))
+		assert.Equal(t, resourceType, resource["resourceType"])
+		resources = append(resources, resource)
+	}
+	require.NoError(t, scanner.Err())
+	return resources
+}
+
+func TestGenerateSyntheticData_ResourcesAndReferences(t *testing.T) {
+	dir := t.TempDir()
+
+	stats, err := services.GenerateSyntheticData(dir, services.SynthOptions{Patients: 25, Seed: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 25, stats["Patient"])
+
+	ids := make(map[string]bool)
+	for _, resourceType := range []string{"Patient", "Encounter", "Condition", "Observation"} {
+		resources := readSynthResources(t, dir, resourceType)
+		assert.Len(t, resources, stats[resourceType])
+		assert.NotEmpty(t, resources)
+		for _, resource := range resources {
+			id := resourceType + "/" + resource["id"].(string)
+			assert.False(t, ids[id], "duplicate id %s", id)
+			ids[id] = true
+
+			meta := resource["meta"].(map[string]any)
+			assert.Contains(t, meta["profile"].([]any)[0], "medizininformatik-initiative.de")
+			assert.Equal(t, services.SynthTagSystem, meta["tag"].([]any)[0].(map[string]any)["system"])
+		}
+	}
+
+	// Every reference resolves to a generated resource
+	for _, resourceType := range []string{"Encounter", "Condition", "Observation"} {
+		for _, resource := range readSynthResources(t, dir, resourceType) {
+			subject := resource["subject"].(map[string]any)["reference"].(string)
+			assert.True(t, ids[subject], "unresolved subject %s", subject)
+			if encounter, ok := resource["encounter"].(map[string]any); ok {
+				assert.True(t, ids[encounter["reference"].(string)], "unresolved encounter %s", encounter["reference"])
+			}
+			for _, diagnosis := range sliceOrNil(resource["diagnosis"]) {
+				ref := diagnosis.(map[string]any)["condition"].(map[string]any)["reference"].(string)
+				assert.True(t, ids[ref], "unresolved diagnosis %s", ref)
+			}
+		}
+	}
+
+	condition := readSynthResources(t, dir, "Condition")[0]
+	coding := condition["code"].(map[string]any)["coding"].([]any)[0].(map[string]any)
+	assert.Equal(t, "http://fhir.de/CodeSystem/bfarm/icd-10-gm", coding["system"])
+
+	observation := readSynthResources(t, dir, "Observation")[0]
+	coding = observation["code"].(map[string]any)["coding"].([]any)[0].(map[string]any)
+	assert.Equal(t, "http://loinc.org", coding["system"])
+	assert.Contains(t, observation, "valueQuantity")
+}
+
+func sliceOrNil(value any) []any {
+	items, _ := value.([]any)
+	return items
+}
+
+func TestGenerateSyntheticData_Deterministic(t *testing.T) {
+	dirA, dirB, dirC := t.TempDir(), t.TempDir(), t.TempDir()
+
+	_, err := services.GenerateSyntheticData(dirA, services.SynthOptions{Patients: 10, Seed: 7})
+	require.NoError(t, err)
+	_, err = services.GenerateSyntheticData(dirB, services.SynthOptions{Patients: 10, Seed: 7})
+	require.NoError(t, err)
+	_, err = services.GenerateSyntheticData(dirC, services.SynthOptions{Patients: 10, Seed: 8})
+	require.NoError(t, err)
+
+	for _, name := range []string{"Patient.ndjson", "Observation.ndjson"} {
+		a, err := os.ReadFile(filepath.Join(dirA, name))
+		require.NoError(t, err)
+		b, err := os.ReadFile(filepath.Join(dirB, name))
+		require.NoError(t, err)
+		c, err := os.ReadFile(filepath.Join(dirC, name))
+		require.NoError(t, err)
+		assert.Equal(t, string(a), string(b), "same seed must produce the same %s", name)
+		assert.NotEqual(t, string(a), string(c), "different seeds should produce different %s", name)
+	}
+}
+
+func TestGenerateSyntheticData_DoesNotOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Encounter.ndjson"), []byte("keep\n"), 0644))
+
+	_, err := services.GenerateSyntheticData(dir, services.SynthOptions{Patients: 1, Seed: 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+
+	data, err := os.ReadFile(filepath.Join(dir, "Encounter.ndjson"))
+	require.NoError(t, err)
+	assert.Equal(t, "keep\n", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "Patient.ndjson"), "partial output should be removed")
+}
+
+func TestGenerateSyntheticData_InvalidPatients(t *testing.T) {
+	_, err := services.GenerateSyntheticData(t.TempDir(), services.SynthOptions{Patients: 0})
+	require.Error(t, err)
+}
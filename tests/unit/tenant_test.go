@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/server"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// tenantTestConfig returns a configuration with two tenants, one with its own credentials and quota
+func tenantTestConfig(jobsDir string) models.ProjectConfig {
+	config := models.DefaultConfig()
+	config.JobsDir = jobsDir
+	config.Pipeline.EnabledSteps = []models.StepName{models.StepLocalImport}
+	config.Services.TORCH = models.TORCHConfig{BaseURL: "http://torch", Username: "global", Password: "global-secret"}
+	config.Tenants = []models.TenantConfig{
+		{
+			Name: "cardio",
+			Services: models.TenantServices{
+				TORCH: models.TenantTORCHConfig{Username: "cardio-user", Password: "cardio-secret"},
+				DIMP:  models.TenantDIMPConfig{URL: "http://dimp-cardio"},
+			},
+			Quota: models.QuotaConfig{MaxJobs: 1},
+		},
+		{Name: "onco"},
+	}
+	return config
+}
+
+// TestForTenant verifies jobs directory, credential and quota scoping
+func TestForTenant(t *testing.T) {
+	config := tenantTestConfig("/data/jobs")
+
+	scoped, err := config.ForTenant("cardio")
+	require.NoError(t, err)
+	assert.Equal(t, "cardio", scoped.Tenant)
+	assert.Equal(t, filepath.Join("/data/jobs", models.TenantsDirName, "cardio"), scoped.JobsDir)
+	assert.Equal(t, "cardio-user", scoped.Services.TORCH.Username)
+	assert.Equal(t, "cardio-secret", scoped.Services.TORCH.Password)
+	assert.Equal(t, "http://torch", scoped.Services.TORCH.BaseURL)
+	assert.Equal(t, "http://dimp-cardio", scoped.Services.DIMP.URL)
+	assert.Equal(t, 1, scoped.Quota.MaxJobs)
+
+	// Without overrides the global services apply
+	onco, err := config.ForTenant("onco")
+	require.NoError(t, err)
+	assert.Equal(t, "global", onco.Services.TORCH.Username)
+
+	_, err = config.ForTenant("unknown")
+	assert.ErrorContains(t, err, "unknown tenant")
+	_, err = scoped.ForTenant("onco")
+	assert.Error(t, err)
+
+	unscoped, err := config.ForTenant("")
+	require.NoError(t, err)
+	assert.Equal(t, "/data/jobs", unscoped.JobsDir)
+}
+
+// TestValidateTenants verifies tenant name and duplicate checks
+func TestValidateTenants(t *testing.T) {
+	assert.NoError(t, models.ValidateTenants([]models.TenantConfig{{Name: "cardio"}, {Name: "onco-2"}}))
+	assert.Error(t, models.ValidateTenants([]models.TenantConfig{{Name: "../etc"}}))
+	assert.Error(t, models.ValidateTenants([]models.TenantConfig{{Name: "Cardio"}}))
+	assert.ErrorContains(t, models.ValidateTenants([]models.TenantConfig{{Name: "a"}, {Name: "a"}}), "duplicate")
+	assert.Error(t, models.ValidateTenants([]models.TenantConfig{{Name: "a", Quota: models.QuotaConfig{MaxJobs: -1}}}))
+}
+
+// TestTenantJobQuota verifies that job creation stops at the tenant's max_jobs
+func TestTenantJobQuota(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	scoped, err := services.ScopeConfigToTenant(&config, "cardio")
+	require.NoError(t, err)
+	assert.DirExists(t, scoped.JobsDir)
+
+	inputDir := t.TempDir()
+	logger := lib.NewLogger(lib.LogLevelError)
+	job, err := pipeline.CreateJob(inputDir, *scoped, logger)
+	require.NoError(t, err)
+	assert.FileExists(t, services.GetStateFilePath(scoped.JobsDir, job.JobID))
+
+	_, err = pipeline.CreateJob(inputDir, *scoped, logger)
+	assert.ErrorContains(t, err, "quota")
+
+	// Other tenants are not affected
+	onco, err := services.ScopeConfigToTenant(&config, "onco")
+	require.NoError(t, err)
+	_, err = pipeline.CreateJob(inputDir, *onco, logger)
+	assert.NoError(t, err)
+}
+
+// TestCheckJobQuota_ActiveJobs verifies that only pending and in-progress jobs count as active
+func TestCheckJobQuota_ActiveJobs(t *testing.T) {
+	jobsDir := t.TempDir()
+	for _, status := range []models.JobStatus{models.JobStatusInProgress, models.JobStatusCompleted} {
+		job := &models.PipelineJob{
+			JobID:       uuid.New().String(),
+			InputSource: "/data",
+			InputType:   models.InputTypeLocal,
+			Status:      status,
+			Config:      models.ProjectConfig{JobsDir: jobsDir},
+		}
+		require.NoError(t, services.SaveJobState(jobsDir, job))
+	}
+
+	assert.NoError(t, services.CheckJobQuota(jobsDir, models.QuotaConfig{MaxActiveJobs: 2}))
+	assert.ErrorContains(t, services.CheckJobQuota(jobsDir, models.QuotaConfig{MaxActiveJobs: 1}), "active job quota")
+	assert.ErrorContains(t, services.CheckJobQuota(jobsDir, models.QuotaConfig{MaxJobs: 2}), "job quota")
+}
+
+// TestServer_TenantIsolation verifies that tenants only see their own jobs
+func TestServer_TenantIsolation(t *testing.T) {
+	config := tenantTestConfig(t.TempDir())
+	cardio, err := services.ScopeConfigToTenant(&config, "cardio")
+	require.NoError(t, err)
+	job, err := pipeline.CreateJob(t.TempDir(), *cardio, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(server.New(config, lib.NewLogger(lib.LogLevelError)).Handler())
+	defer srv.Close()
+
+	listJobs := func(path string) []models.JobStatusView {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var views []models.JobStatusView
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&views))
+		return views
+	}
+
+	cardioJobs := listJobs("/api/v1/tenants/cardio/jobs")
+	require.Len(t, cardioJobs, 1)
+	assert.Equal(t, job.JobID, cardioJobs[0].JobID)
+	assert.Empty(t, listJobs("/api/v1/tenants/onco/jobs"))
+	assert.Empty(t, listJobs("/api/v1/jobs"))
+
+	resp, err := http.Get(srv.URL + "/api/v1/tenants/onco/jobs/" + job.JobID)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/api/v1/tenants/unknown/jobs")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Share links resolve the tenant from the token
+	config.Server = models.ServerConfig{PublicURL: "https://aether.example.org", ShareSecret: testShareSecret}
+	scopedForShare, err := config.ForTenant("cardio")
+	require.NoError(t, err)
+	link, err := server.CreateShareLink(scopedForShare, job.JobID, time.Hour, time.Now())
+	require.NoError(t, err)
+	shareSrv := httptest.NewServer(server.New(config, lib.NewLogger(lib.LogLevelError)).Handler())
+	defer shareSrv.Close()
+	resp, err = http.Get(shareSrv.URL + "/share/" + link.Token)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
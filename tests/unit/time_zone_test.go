@@ -0,0 +1,115 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// useTimeZone switches the configured time zone for the duration of a test
+func useTimeZone(t *testing.T, loc *time.Location) {
+	previous := lib.TimeZone()
+	lib.ConfigureTimeZone(loc)
+	t.Cleanup(func() { lib.ConfigureTimeZone(previous) })
+}
+
+// TestConfigLoading_TimeZone verifies time_zone is loaded, defaults to UTC and is validated
+func TestConfigLoading_TimeZone(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configContent := `
+time_zone: Europe/Berlin
+pipeline:
+  enabled_steps:
+    - local_import
+
+jobs_dir: "` + filepath.Join(tmpDir, "jobs") + `"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(configContent), 0644))
+
+	config, err := services.LoadConfig(configFile)
+	require.NoError(t, err)
+	loc, err := config.GetTimeZone()
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", loc.String())
+
+	loc, err = models.ProjectConfig{}.GetTimeZone()
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	config.TimeZone = "Mars/Olympus_Mons"
+	err = config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid time_zone")
+}
+
+// TestFormatTimestamp verifies timestamps are formatted as RFC3339 in the configured zone
+func TestFormatTimestamp(t *testing.T) {
+	instant := time.Date(2025, 3, 1, 14, 5, 9, 500, time.UTC)
+
+	useTimeZone(t, time.UTC)
+	assert.Equal(t, "2025-03-01T14:05:09Z", lib.FormatTimestamp(instant))
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	useTimeZone(t, berlin)
+	assert.Equal(t, "2025-03-01T15:05:09+01:00", lib.FormatTimestamp(instant))
+	assert.Equal(t, berlin, lib.Now().Location())
+	assert.NotEqual(t, berlin, time.Local, "the process time zone is left alone")
+}
+
+// TestSaveJobState_UsesConfiguredTimeZone verifies state.json is written in the configured zone
+func TestSaveJobState_UsesConfiguredTimeZone(t *testing.T) {
+	useTimeZone(t, time.FixedZone("UTC+5", 5*60*60))
+
+	jobsDir := t.TempDir()
+	created := time.Date(2025, 3, 1, 14, 5, 9, 0, time.UTC)
+	job := &models.PipelineJob{
+		JobID:       "0f7c3c4e-6a8e-4d7c-9a55-2f1f7b8f3a10",
+		CreatedAt:   created,
+		UpdatedAt:   created,
+		InputSource: "/data",
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepLocalImport),
+		Status:      models.JobStatusInProgress,
+		Steps:       []models.PipelineStep{{Name: models.StepLocalImport, Status: models.StepStatusInProgress, StartedAt: &created}},
+		Config:      models.ProjectConfig{JobsDir: jobsDir},
+	}
+	require.NoError(t, services.SaveJobState(jobsDir, job))
+
+	data, err := os.ReadFile(services.GetStateFilePath(jobsDir, job.JobID))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"created_at": "2025-03-01T19:05:09+05:00"`)
+	assert.Contains(t, string(data), `"started_at": "2025-03-01T19:05:09+05:00"`)
+	assert.Equal(t, time.UTC, job.CreatedAt.Location(), "the in-memory job is not changed")
+}
+
+// TestHeartbeat_UsesConfiguredTimeZone verifies heartbeat files carry the offset of the configured zone
+func TestHeartbeat_UsesConfiguredTimeZone(t *testing.T) {
+	useTimeZone(t, time.FixedZone("UTC+5", 5*60*60))
+
+	jobsDir := t.TempDir()
+	jobID := "heartbeat-tz-job"
+	require.NoError(t, os.MkdirAll(filepath.Join(jobsDir, jobID), 0755))
+
+	hb := services.StartHeartbeat(jobsDir, jobID, models.HeartbeatConfig{IntervalSeconds: 60}, lib.NewLogger(lib.LogLevelError))
+	require.NotNil(t, hb)
+	hb.Stop()
+
+	data, err := os.ReadFile(filepath.Join(jobsDir, jobID, services.HeartbeatFileName))
+	require.NoError(t, err)
+	timestamp := strings.Fields(string(data))[0]
+	assert.True(t, strings.HasSuffix(timestamp, "+05:00"), timestamp)
+
+	beat, err := services.ReadHeartbeat(jobsDir, jobID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), beat, 5*time.Second)
+}
@@ -0,0 +1,168 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// newTokenServer returns a token endpoint issuing "token-1", "token-2", ... to client aether/secret
+func newTokenServer(t *testing.T, issued *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		require.NoError(t, r.ParseForm())
+		if !ok || clientID != "aether" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "torch", r.PostForm.Get("scope"))
+
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   300,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newOAuth2TORCHClient(serverURL, tokenURL, clientSecret string) *services.TORCHClient {
+	logger := lib.NewLogger(lib.LogLevelError)
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 10, MaxBackoffMs: 10}, logger)
+	return services.NewTORCHClient(models.TORCHConfig{
+		BaseURL:                   serverURL,
+		AuthMode:                  models.TORCHAuthOAuth2ClientCredentials,
+		TokenURL:                  tokenURL,
+		ClientID:                  "aether",
+		ClientSecret:              clientSecret,
+		Scope:                     "torch",
+		ExtractionTimeoutMinutes:  1,
+		PollingIntervalSeconds:    1,
+		MaxPollingIntervalSeconds: 1,
+	}, httpClient, logger)
+}
+
+func writeTestCRTDL(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.crtdl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"cohortDefinition":{},"dataExtraction":{}}`), 0644))
+	return path
+}
+
+func TestTORCHClient_OAuth2_CachesToken(t *testing.T) {
+	var issued atomic.Int32
+	tokenServer := newTokenServer(t, &issued)
+
+	var authHeaders []string
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/fhir/$extract-data":
+			w.Header().Set("Content-Location", serverURL+"/fhir/extraction/job-1")
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			_, _ = w.Write([]byte(`{"resourceType":"Patient"}` + "\n"))
+		}
+	}))
+	serverURL = server.URL
+	defer server.Close()
+
+	client := newOAuth2TORCHClient(server.URL, tokenServer.URL, "secret")
+	_, err := client.SubmitExtraction(writeTestCRTDL(t))
+	require.NoError(t, err)
+	_, err = client.DownloadExtractionFiles([]string{server.URL + "/files/Patient.ndjson"}, t.TempDir(), lib.NoProgress)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1"}, authHeaders)
+	assert.Equal(t, int32(1), issued.Load(), "the token is requested once and reused")
+}
+
+func TestTORCHClient_OAuth2_RefreshesTokenOn401(t *testing.T) {
+	var issued atomic.Int32
+	tokenServer := newTokenServer(t, &issued)
+
+	var serverURL string
+	var submissions atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first token is revoked on the server (e.g. Keycloak session ended)
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var params map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params), "the body is sent again")
+		submissions.Add(1)
+		w.Header().Set("Content-Location", serverURL+"/fhir/extraction/job-1")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	serverURL = server.URL
+	defer server.Close()
+
+	client := newOAuth2TORCHClient(server.URL, tokenServer.URL, "secret")
+	location, err := client.SubmitExtraction(writeTestCRTDL(t))
+	require.NoError(t, err)
+	assert.Equal(t, serverURL+"/fhir/extraction/job-1", location)
+	assert.Equal(t, int32(2), issued.Load())
+	assert.Equal(t, int32(1), submissions.Load())
+}
+
+func TestTORCHClient_OAuth2_RejectedClient(t *testing.T) {
+	var issued atomic.Int32
+	tokenServer := newTokenServer(t, &issued)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("TORCH must not be called without a token")
+	}))
+	defer server.Close()
+
+	client := newOAuth2TORCHClient(server.URL, tokenServer.URL, "wrong")
+	_, err := client.SubmitExtraction(writeTestCRTDL(t))
+	require.Error(t, err)
+
+	var torchErr *services.TORCHError
+	require.ErrorAs(t, err, &torchErr)
+	assert.Equal(t, "auth", torchErr.Operation)
+	assert.Equal(t, http.StatusUnauthorized, torchErr.StatusCode)
+	assert.False(t, torchErr.IsRetryable(), "a rejected client is not retried")
+	assert.Contains(t, torchErr.Message, "invalid_client")
+}
+
+func TestTORCHClient_BearerToken(t *testing.T) {
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	logger := lib.NewLogger(lib.LogLevelError)
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 10, MaxBackoffMs: 10}, logger)
+	client := services.NewTORCHClient(models.TORCHConfig{
+		BaseURL:     server.URL,
+		AuthMode:    models.TORCHAuthBearerToken,
+		BearerToken: "static-token",
+	}, httpClient, logger)
+
+	err := client.CheckAuth()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "services.torch.bearer_token")
+	assert.Equal(t, "Bearer static-token", authHeader)
+}
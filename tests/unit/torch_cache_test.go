@@ -0,0 +1,60 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func TestTORCHCacheEntry_TTL(t *testing.T) {
+	jobsDir := t.TempDir()
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	entry := services.TORCHCacheEntry{
+		CRTDLHash: "abc123",
+		StatusURL: "https://torch.example.org/fhir/extraction/1",
+		FileURLs:  []string{"https://torch.example.org/output/1.ndjson"},
+		JobID:     "job-1",
+		CreatedAt: created,
+	}
+	require.NoError(t, services.SaveTORCHCacheEntry(jobsDir, "https://torch.example.org", entry))
+
+	loaded, found := services.LoadTORCHCacheEntry(jobsDir, "https://torch.example.org/", "abc123", time.Hour, created.Add(30*time.Minute))
+	require.True(t, found)
+	assert.Equal(t, entry.FileURLs, loaded.FileURLs)
+
+	_, found = services.LoadTORCHCacheEntry(jobsDir, "https://other.example.org", "abc123", time.Hour, created.Add(30*time.Minute))
+	assert.False(t, found, "entries are kept per TORCH server")
+
+	_, found = services.LoadTORCHCacheEntry(jobsDir, "https://torch.example.org", "abc123", time.Hour, created.Add(2*time.Hour))
+	assert.False(t, found)
+	_, err := os.Stat(services.GetTORCHCachePath(jobsDir, "https://torch.example.org", "abc123"))
+	assert.True(t, os.IsNotExist(err), "expired entries are removed")
+}
+
+func TestHashCRTDL(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.crtdl")
+	b := filepath.Join(dir, "b.crtdl")
+	require.NoError(t, os.WriteFile(a, []byte(`{"cohortDefinition":{}}`), 0644))
+	require.NoError(t, os.WriteFile(b, []byte(`{"cohortDefinition":{}}`), 0644))
+
+	hashA, err := services.HashCRTDL(a)
+	require.NoError(t, err)
+	hashB, err := services.HashCRTDL(b)
+	require.NoError(t, err)
+	assert.Equal(t, hashA, hashB, "the hash depends on content, not on the file name")
+}
+
+func TestTORCHConfig_GetResultCacheTTL(t *testing.T) {
+	assert.Zero(t, models.TORCHConfig{}.GetResultCacheTTL())
+	assert.Equal(t, 90*time.Minute, models.TORCHConfig{ResultCacheTTLMinutes: 90}.GetResultCacheTTL())
+	assert.Zero(t, models.TORCHConfig{ResultCacheTTLMinutes: 90, CleanupAfterDownload: true}.GetResultCacheTTL(),
+		"cleanup deletes the results the cache would reuse")
+}
@@ -174,7 +174,7 @@ func TestTORCHClient_PollExtractionStatus_ImmediateSuccess(t *testing.T) {
 	}
 
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
-	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 
 	assert.NoError(t, err)
 	require.Len(t, urls, 2)
@@ -217,7 +217,7 @@ func TestTORCHClient_PollExtractionStatus_EmptyOutput(t *testing.T) {
 	}
 
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
-	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 
 	// Should return error with helpful message
 	assert.Error(t, err)
@@ -245,7 +245,7 @@ func TestTORCHClient_PollExtractionStatus_Timeout(t *testing.T) {
 	}
 
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
-	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "timeout")
@@ -281,7 +281,7 @@ func TestTORCHClient_PollExtractionStatus_ServerError(t *testing.T) {
 	}
 
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
-	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "500")
@@ -324,7 +324,7 @@ func TestTORCHClient_DownloadExtractionFiles_Success(t *testing.T) {
 		server.URL + "/output/batch-2.ndjson",
 	}
 
-	files, err := client.DownloadExtractionFiles(fileURLs, tempDir, false)
+	files, err := client.DownloadExtractionFiles(fileURLs, tempDir, lib.NoProgress)
 
 	assert.NoError(t, err)
 	assert.Len(t, files, 2)
@@ -373,7 +373,7 @@ func TestTORCHClient_DownloadExtractionFiles_PartialFailure(t *testing.T) {
 		server.URL + "/output/batch-2.ndjson",
 	}
 
-	_, err := client.DownloadExtractionFiles(fileURLs, tempDir, false)
+	_, err := client.DownloadExtractionFiles(fileURLs, tempDir, lib.NoProgress)
 
 	// Should fail on second file
 	assert.Error(t, err)
@@ -484,7 +484,7 @@ func TestTORCHClient_PollExtractionStatus_ExponentialBackoff(t *testing.T) {
 	}
 
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
-	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 
 	assert.NoError(t, err)
 	assert.Len(t, urls, 1)
@@ -676,7 +676,7 @@ func TestTORCHClient_ParseExtractionResult_FHIRFormat(t *testing.T) {
 	}
 
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
-	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 
 	assert.NoError(t, err)
 	assert.Len(t, urls, 1)
@@ -714,7 +714,7 @@ func TestTORCHClient_ParseExtractionResult_SimpleFormat(t *testing.T) {
 	}
 
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
-	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 
 	assert.NoError(t, err)
 	assert.Len(t, urls, 1)
@@ -741,7 +741,7 @@ func TestTORCHClient_ParseExtractionResult_InvalidJSON(t *testing.T) {
 	}
 
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
-	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", false)
+	_, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/job-123", lib.NoProgress)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid JSON")
@@ -787,7 +787,7 @@ func TestTORCHClient_DownloadExtractionFiles_EmptyFileList(t *testing.T) {
 	tempDir := t.TempDir()
 	client := services.NewTORCHClient(torchConfig, httpClient, logger)
 
-	files, err := client.DownloadExtractionFiles([]string{}, tempDir, false)
+	files, err := client.DownloadExtractionFiles([]string{}, tempDir, lib.NoProgress)
 
 	assert.NoError(t, err)
 	assert.Len(t, files, 0)
@@ -816,7 +816,53 @@ func TestTORCHClient_DownloadExtractionFiles_InvalidDestinationPermissions(t *te
 
 	// Try to download to root directory (will fail with permission error)
 	fileURLs := []string{server.URL + "/output/batch-1.ndjson"}
-	_, err := client.DownloadExtractionFiles(fileURLs, "/root/invalid", false)
+	_, err := client.DownloadExtractionFiles(fileURLs, "/root/invalid", lib.NoProgress)
 
 	assert.Error(t, err)
 }
+
+// newCleanupTestServer serves an extraction result at /status/1 and /output/1.ndjson until DELETE
+// /status/1 is received; deleteStatus is the answer to the DELETE, keepFiles leaves the file in place
+func newCleanupTestServer(t *testing.T, deleteStatus int, keepFiles bool) (*httptest.Server, *services.TORCHClient) {
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/status/1":
+			deleted = deleteStatus < 300
+			w.WriteHeader(deleteStatus)
+		case deleted && (r.URL.Path == "/status/1" || !keepFiles):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	logger := lib.NewLogger(lib.LogLevelError)
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000}, logger)
+	torchConfig := models.TORCHConfig{BaseURL: server.URL, Username: "testuser", Password: "testpass"}
+	return server, services.NewTORCHClient(torchConfig, httpClient, logger)
+}
+
+func TestTORCHClient_DeleteExtractionResult_Verified(t *testing.T) {
+	server, client := newCleanupTestServer(t, http.StatusNoContent, false)
+
+	err := client.DeleteExtractionResult(server.URL+"/status/1", []string{server.URL + "/output/1.ndjson"})
+	assert.NoError(t, err)
+}
+
+func TestTORCHClient_DeleteExtractionResult_Unsupported(t *testing.T) {
+	server, client := newCleanupTestServer(t, http.StatusMethodNotAllowed, false)
+
+	err := client.DeleteExtractionResult(server.URL+"/status/1", []string{server.URL + "/output/1.ndjson"})
+	assert.ErrorIs(t, err, services.ErrTORCHCleanupUnsupported)
+}
+
+func TestTORCHClient_DeleteExtractionResult_FilesStillServed(t *testing.T) {
+	server, client := newCleanupTestServer(t, http.StatusAccepted, true)
+
+	err := client.DeleteExtractionResult(server.URL+"/status/1", []string{server.URL + "/output/1.ndjson"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/output/1.ndjson still answers HTTP 200")
+}
@@ -208,7 +208,7 @@ func TestCreatePollRequest_SuccessByVerifyingPollExecution(t *testing.T) {
 	extractionURL := server.URL + "/fhir/extraction/job-123"
 
 	// This tests poll request creation indirectly by executing polling
-	fileURLs, err := client.PollExtractionStatus(extractionURL, false)
+	fileURLs, err := client.PollExtractionStatus(extractionURL, lib.NoProgress)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, fileURLs)
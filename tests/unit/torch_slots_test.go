@@ -0,0 +1,97 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func newSlotTestClient(maxActive int) (*services.TORCHClient, *services.HTTPClient) {
+	logger := lib.NewLogger(lib.LogLevelError)
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1}, logger)
+	config := models.TORCHConfig{BaseURL: "https://torch.example.org", PollingIntervalSeconds: 1, MaxActiveExtractions: maxActive}
+	return services.NewTORCHClient(config, httpClient, logger), httpClient
+}
+
+func TestAcquireExtractionSlot_Unlimited(t *testing.T) {
+	client, _ := newSlotTestClient(0)
+
+	slot, err := client.AcquireExtractionSlot(t.TempDir())
+	require.NoError(t, err)
+	assert.Nil(t, slot)
+	assert.NoError(t, slot.Release())
+}
+
+// TestAcquireExtractionSlot_Queues verifies that a job waits until another job's extraction finishes
+func TestAcquireExtractionSlot_Queues(t *testing.T) {
+	jobsDir := t.TempDir()
+	first, _ := newSlotTestClient(2)
+	second, _ := newSlotTestClient(2)
+	third, _ := newSlotTestClient(2)
+
+	var events []models.JobEvent
+	third.SetEventSink(func(event models.JobEvent) { events = append(events, event) })
+
+	slotA, err := first.AcquireExtractionSlot(jobsDir)
+	require.NoError(t, err)
+	slotB, err := second.AcquireExtractionSlot(jobsDir)
+	require.NoError(t, err)
+	assert.NotEqual(t, slotA.Slot, slotB.Slot)
+
+	acquired := make(chan *services.ExtractionSlot)
+	go func() {
+		slot, err := third.AcquireExtractionSlot(jobsDir)
+		assert.NoError(t, err)
+		acquired <- slot
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("third extraction should be queued while both slots are taken")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	require.NoError(t, slotB.Release())
+	select {
+	case slot := <-acquired:
+		assert.Equal(t, slotB.Slot, slot.Slot)
+		assert.NoError(t, slot.Release())
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued extraction did not get the released slot")
+	}
+	assert.NoError(t, slotA.Release())
+
+	require.Len(t, events, 1)
+	assert.Equal(t, models.EventTORCHQueued, events[0].Type)
+}
+
+func TestAcquireExtractionSlot_Deadline(t *testing.T) {
+	jobsDir := t.TempDir()
+	holder, _ := newSlotTestClient(1)
+	waiter, httpClient := newSlotTestClient(1)
+
+	slot, err := holder.AcquireExtractionSlot(jobsDir)
+	require.NoError(t, err)
+	defer func() { _ = slot.Release() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	httpClient.SetContext(ctx)
+
+	_, err = waiter.AcquireExtractionSlot(jobsDir)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestGetExtractionSlotsDir verifies that slots are shared per server, not per job
+func TestGetExtractionSlotsDir(t *testing.T) {
+	a := services.GetExtractionSlotsDir("/jobs", "https://torch.example.org")
+	assert.Equal(t, a, services.GetExtractionSlotsDir("/jobs", "https://torch.example.org/"))
+	assert.NotEqual(t, a, services.GetExtractionSlotsDir("/jobs", "https://other.example.org"))
+}
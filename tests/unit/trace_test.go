@@ -0,0 +1,63 @@
+package unit
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestStepExitCode verifies the exit code convention for workflow engines
+func TestStepExitCode(t *testing.T) {
+	completed := models.PipelineStep{Name: models.StepDIMP, Status: models.StepStatusCompleted}
+	assert.Equal(t, 0, services.StepExitCode(completed))
+
+	transient := models.FailStep(models.PipelineStep{Name: models.StepDIMP}, models.ErrorTypeTransient, "connection refused", 0)
+	assert.Equal(t, services.ExitCodeTransient, services.StepExitCode(transient))
+
+	permanent := models.FailStep(models.PipelineStep{Name: models.StepDIMP}, models.ErrorTypeNonTransient, "bad request", 400)
+	assert.Equal(t, services.ExitCodeFailed, services.StepExitCode(permanent))
+}
+
+// TestWriteStepTrace verifies a trace file has a header and one tab-separated row
+func TestWriteStepTrace(t *testing.T) {
+	traceDir := t.TempDir()
+	started := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	step := models.FailStep(models.PipelineStep{
+		Name:           models.StepDIMP,
+		StartedAt:      &started,
+		FilesProcessed: 2,
+		BytesProcessed: 1024,
+		RetryCount:     1,
+	}, models.ErrorTypeTransient, "timeout\tafter 30s\nretrying", 0)
+
+	require.NoError(t, services.WriteStepTrace(traceDir, services.NewStepTrace("job-1", step)))
+
+	content, err := os.ReadFile(services.GetStepTracePath(traceDir, models.StepDIMP))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	header := strings.Split(lines[0], "\t")
+	row := strings.Split(lines[1], "\t")
+	require.Len(t, row, len(header))
+	fields := map[string]string{}
+	for i, column := range header {
+		fields[column] = row[i]
+	}
+	assert.Equal(t, "job-1", fields["job_id"])
+	assert.Equal(t, "dimp", fields["step"])
+	assert.Equal(t, "failed", fields["status"])
+	assert.Equal(t, "75", fields["exit"])
+	assert.Equal(t, "2025-01-01T10:00:00Z", fields["start"])
+	assert.NotEmpty(t, fields["complete"])
+	assert.Equal(t, "2", fields["files"])
+	assert.Equal(t, "1024", fields["bytes"])
+	assert.Equal(t, "timeout after 30s retrying", fields["error"])
+}
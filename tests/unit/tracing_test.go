@@ -0,0 +1,179 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in memory
+// Tracing is turned off again when the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		_, err := lib.ConfigureTracing(models.TracingConfig{}, lib.NewLogger(lib.LogLevelError))
+		require.NoError(t, err)
+	})
+	return recorder
+}
+
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+// TestDIMPClient_PropagatesTraceContext verifies DIMP requests are traced and carry traceparent
+func TestDIMPClient_PropagatesTraceContext(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte(`{"resourceType":"Patient","id":"pseudo-1"}`))
+	}))
+	defer server.Close()
+
+	ctx, root := lib.StartSpan(context.Background(), "test run")
+	httpClient := services.NewHTTPClient(0, models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 1, MaxBackoffMs: 2}, lib.NewLogger(lib.LogLevelError))
+	httpClient.SetContext(ctx)
+	_, err := services.NewDIMPClient(server.URL, httpClient, lib.NewLogger(lib.LogLevelError)).Pseudonymize(map[string]any{"resourceType": "Patient", "id": "1"})
+	require.NoError(t, err)
+	root.End()
+
+	traceID := root.SpanContext().TraceID().String()
+	assert.Contains(t, traceparent, traceID, "DIMP receives the trace of the run")
+	assert.Equal(t, []string{"HTTP POST", "dimp.pseudonymize", "test run"}, spanNames(recorder))
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+	}
+	assert.Equal(t, recorder.Ended()[1].SpanContext().SpanID(), recorder.Ended()[0].Parent().SpanID(), "the HTTP span is a child of the DIMP span")
+}
+
+// TestTORCHClient_TracesPollAndDownload verifies each poll and each download gets a span
+func TestTORCHClient_TracesPollAndDownload(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var mu sync.Mutex
+	polls := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.NotEmpty(t, r.Header.Get("traceparent"))
+		switch r.URL.Path {
+		case "/status":
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			_, _ = w.Write([]byte(`{"output":[{"type":"Patient","url":"` + server.URL + `/files/batch-1.ndjson"}]}`))
+		default:
+			_, _ = w.Write([]byte("{\"resourceType\":\"Patient\",\"id\":\"p1\"}\n"))
+		}
+	}))
+	defer server.Close()
+
+	client := services.NewTORCHClient(models.TORCHConfig{BaseURL: server.URL, Username: "u", Password: "p", ExtractionTimeoutMinutes: 1, PollingIntervalSeconds: 1, MaxPollingIntervalSeconds: 1}, newResumableTestClient(), lib.NewLogger(lib.LogLevelError))
+	fileURLs, err := client.PollExtractionStatus(server.URL+"/status", lib.NoProgress)
+	require.NoError(t, err)
+	_, err = client.DownloadExtractionFiles(fileURLs, t.TempDir(), lib.NoProgress)
+	require.NoError(t, err)
+
+	names := spanNames(recorder)
+	assert.Equal(t, 2, countOf(names, "torch.poll"))
+	assert.Equal(t, 1, countOf(names, "torch.download"))
+	assert.Equal(t, 3, countOf(names, "HTTP GET"))
+}
+
+func countOf(values []string, value string) int {
+	count := 0
+	for _, v := range values {
+		if v == value {
+			count++
+		}
+	}
+	return count
+}
+
+// TestRunStep_TracesStep verifies a step is a span, failed steps are marked as errors
+// and the trace ID is recorded with step_started
+func TestRunStep_TracesStep(t *testing.T) {
+	recorder := recordSpans(t)
+	job := newStepRunnerJob(t)
+
+	err := pipeline.RunStep(context.Background(), job, models.StepLocalImport, lib.NewLogger(lib.LogLevelError), func(ctx context.Context) error {
+		assert.NotEmpty(t, lib.TraceID(ctx), "the step's context carries its span")
+		return errors.New("boom")
+	})
+	require.Error(t, err)
+
+	require.Len(t, recorder.Ended(), 1)
+	span := recorder.Ended()[0]
+	assert.Equal(t, "step local_import", span.Name())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	events, err := services.LoadJobEvents(job.Config.JobsDir, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, span.SpanContext().TraceID().String(), events[0].Fields["trace_id"])
+}
+
+// TestConfigureTracing_ExportsOTLP verifies spans are exported to the OTLP/HTTP endpoint on shutdown
+func TestConfigureTracing_ExportsOTLP(t *testing.T) {
+	var mu sync.Mutex
+	var paths, apiKeys []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		apiKeys = append(apiKeys, r.Header.Get("X-Api-Key"))
+	}))
+	defer collector.Close()
+
+	logger := lib.NewLogger(lib.LogLevelError)
+	shutdown, err := lib.ConfigureTracing(models.TracingConfig{Endpoint: collector.URL, Headers: map[string]string{"x-api-key": "secret"}}, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = lib.ConfigureTracing(models.TracingConfig{}, logger) })
+
+	_, span := lib.StartSpan(context.Background(), "test span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/v1/traces"}, paths)
+	assert.Equal(t, []string{"secret"}, apiKeys)
+}
+
+// TestTracingConfig_Validate verifies endpoint, protocol and sample ratio checks
+func TestTracingConfig_Validate(t *testing.T) {
+	assert.NoError(t, models.TracingConfig{}.Validate())
+	assert.NoError(t, models.TracingConfig{Endpoint: "http://collector:4317", Protocol: models.TracingGRPC, SampleRatio: 0.1}.Validate())
+	assert.ErrorContains(t, models.TracingConfig{Endpoint: "collector:4318"}.Validate(), "http(s) URL")
+	assert.ErrorContains(t, models.TracingConfig{Endpoint: "http://collector:4318", Protocol: "zipkin"}.Validate(), "tracing.protocol")
+	assert.ErrorContains(t, models.TracingConfig{Endpoint: "http://collector:4318", SampleRatio: 2}.Validate(), "sample_ratio")
+	assert.Equal(t, models.TracingHTTP, models.TracingConfig{}.GetProtocol())
+	assert.Equal(t, 1.0, models.TracingConfig{}.GetSampleRatio())
+}
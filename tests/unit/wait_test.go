@@ -0,0 +1,136 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// waitRecorder collects the wait states reported by a client
+type waitRecorder struct {
+	mu     sync.Mutex
+	states []*models.WaitState
+}
+
+func (r *waitRecorder) sink(state *models.WaitState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+}
+
+// TestHTTPClient_ReportsRetryWaits verifies each retry backoff is reported and cleared afterwards
+func TestHTTPClient_ReportsRetryWaits(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 3, InitialBackoffMs: 10, MaxBackoffMs: 50}, lib.NewLogger(lib.LogLevelError))
+	recorder := &waitRecorder{}
+	client.SetWaitSink(recorder.sink)
+
+	before := time.Now()
+	resp, err := client.Get(server.URL + "/fhir/Patient")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	require.Len(t, recorder.states, 4, "two retries, each reported and cleared")
+	first := recorder.states[0]
+	require.NotNil(t, first)
+	assert.Equal(t, models.WaitRetry, first.Reason)
+	assert.Equal(t, "GET /fhir/Patient", first.Target)
+	assert.Equal(t, 1, first.Attempt)
+	assert.Equal(t, 2, first.MaxAttempts)
+	assert.True(t, first.NextAt.After(before))
+	assert.Nil(t, recorder.states[1])
+	assert.Equal(t, 2, recorder.states[2].Attempt)
+	assert.Nil(t, recorder.states[3])
+}
+
+// TestTORCHClient_ReportsPollWaits verifies the next poll and the timeout are reported while an extraction runs
+func TestTORCHClient_ReportsPollWaits(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls == 1 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output": [{"type": "data", "url": "/downloads/result.ndjson"}]}`))
+	}))
+	defer server.Close()
+
+	logger := lib.NewLogger(lib.LogLevelError)
+	httpClient := services.NewHTTPClient(5*time.Second, models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 10, MaxBackoffMs: 50}, logger)
+	recorder := &waitRecorder{}
+	httpClient.SetWaitSink(recorder.sink)
+	client := services.NewTORCHClient(models.TORCHConfig{
+		BaseURL:                   server.URL,
+		ExtractionTimeoutMinutes:  30,
+		PollingIntervalSeconds:    1,
+		MaxPollingIntervalSeconds: 1,
+	}, httpClient, logger)
+
+	before := time.Now()
+	urls, err := client.PollExtractionStatus(server.URL+"/fhir/extraction/abc", lib.NoProgress)
+	require.NoError(t, err)
+	assert.Len(t, urls, 1)
+
+	require.Len(t, recorder.states, 2)
+	wait := recorder.states[0]
+	require.NotNil(t, wait)
+	assert.Equal(t, models.WaitPoll, wait.Reason)
+	assert.Equal(t, 2, wait.Attempt, "the upcoming poll is the second")
+	assert.WithinDuration(t, before.Add(time.Second), wait.NextAt, 500*time.Millisecond)
+	assert.WithinDuration(t, before.Add(30*time.Minute), wait.GivesUpAt, 5*time.Second)
+	assert.Nil(t, recorder.states[1])
+}
+
+// TestWaitState_SaveLoadAndDescribe verifies wait.json round-trips and is removed when the wait ends
+func TestWaitState_SaveLoadAndDescribe(t *testing.T) {
+	jobsDir := t.TempDir()
+	jobID := "waiting-job"
+
+	state, err := services.LoadWaitState(jobsDir, jobID)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, os.MkdirAll(services.GetJobDir(jobsDir, jobID), 0755))
+	now := time.Now()
+	wait := &models.WaitState{Reason: models.WaitRetry, Target: "POST /fhir/$de-identify", Attempt: 2, MaxAttempts: 4, NextAt: now.Add(20 * time.Second)}
+	require.NoError(t, services.SaveWaitState(jobsDir, jobID, wait))
+
+	state, err = services.LoadWaitState(jobsDir, jobID)
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, wait.Target, state.Target)
+	assert.Contains(t, state.Describe(now), "retry 2/4 of POST /fhir/$de-identify")
+	assert.Contains(t, state.Describe(now), "(in 20s)")
+	assert.Contains(t, state.Describe(now.Add(time.Minute)), "overdue by 40s")
+
+	poll := models.WaitState{Reason: models.WaitPoll, Target: "TORCH extraction", Attempt: 7, NextAt: now.Add(30 * time.Second), GivesUpAt: now.Add(time.Hour)}
+	assert.Contains(t, poll.Describe(now), "poll 7 of TORCH extraction")
+	assert.Contains(t, poll.Describe(now), "gives up at")
+
+	require.NoError(t, services.SaveWaitState(jobsDir, jobID, nil))
+	state, err = services.LoadWaitState(jobsDir, jobID)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+	require.NoError(t, services.SaveWaitState(jobsDir, jobID, nil), "clearing twice is fine")
+}
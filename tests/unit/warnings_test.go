@@ -0,0 +1,176 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+func findWarning(step models.PipelineStep, code models.WarningCode) *models.StepWarning {
+	for i := range step.Warnings {
+		if step.Warnings[i].Code == code {
+			return &step.Warnings[i]
+		}
+	}
+	return nil
+}
+
+func TestStepWarning_SameCodeAndFileIsReplaced(t *testing.T) {
+	step := models.PipelineStep{Name: models.StepDIMP}
+	step.SetWarning(models.StepWarning{Code: models.WarningEmptyLines, File: "a.ndjson", Count: 2})
+	step.SetWarning(models.StepWarning{Code: models.WarningEmptyLines, File: "b.ndjson", Count: 1})
+	step.SetWarning(models.StepWarning{Code: models.WarningEmptyLines, File: "a.ndjson", Count: 3})
+
+	require.Len(t, step.Warnings, 2)
+	assert.Equal(t, 3, step.Warnings[0].Count, "processing a file again updates its warning")
+}
+
+func TestStepWarning_ClearFileWarnings(t *testing.T) {
+	step := models.PipelineStep{Name: models.StepDIMP}
+	step.SetWarning(models.StepWarning{Code: models.WarningEmptyLines, File: "a.ndjson", Count: 2})
+	step.SetWarning(models.StepWarning{Code: models.WarningUnknownResourceType, File: "a.ndjson", Count: 1})
+	step.SetWarning(models.StepWarning{Code: models.WarningFilesIgnored, Count: 1})
+
+	step.ClearFileWarnings("a.ndjson")
+	require.Len(t, step.Warnings, 1)
+	assert.Equal(t, models.WarningFilesIgnored, step.Warnings[0].Code)
+}
+
+func TestStepWarning_CappedAtMaxStepWarnings(t *testing.T) {
+	step := models.PipelineStep{Name: models.StepDIMP}
+	for i := 0; i < models.MaxStepWarnings+5; i++ {
+		step.SetWarning(models.StepWarning{Code: models.WarningEmptyLines, File: fmt.Sprintf("%d.ndjson", i)})
+	}
+
+	assert.Len(t, step.Warnings, models.MaxStepWarnings)
+	assert.Equal(t, 5, step.WarningsDropped)
+
+	job := models.PipelineJob{Steps: []models.PipelineStep{step}}
+	assert.Equal(t, models.MaxStepWarnings+5, job.WarningCount())
+}
+
+func TestExecuteDIMPStep_RecordsWarnings(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	jobsDir := t.TempDir()
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = jobsDir
+	job.Config.Services.DIMP.NonFHIRLines = models.NonFHIRLinesPassThrough
+	jobDir := services.GetJobDir(jobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+
+	content := `{"resourceType":"Patient","id":"p1"}` + "\n\n" +
+		`{"resourceType":"Patient","id":"p2"}` + "\n\n\n" +
+		`{"resourceType":"Observaton","id":"o1"}` + "\n" +
+		`{"meta":"torch"}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(importDir, "mixed.ndjson"), []byte(content), 0644))
+
+	require.NoError(t, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress))
+
+	step, found := models.GetStepByName(*job, models.StepDIMP)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusCompleted, step.Status)
+
+	emptyLines := findWarning(step, models.WarningEmptyLines)
+	require.NotNil(t, emptyLines)
+	assert.Equal(t, "mixed.ndjson", emptyLines.File)
+	assert.Equal(t, 3, emptyLines.Count)
+
+	unknown := findWarning(step, models.WarningUnknownResourceType)
+	require.NotNil(t, unknown)
+	assert.Equal(t, 1, unknown.Count)
+	assert.Contains(t, unknown.Message, "Observaton")
+
+	nonFHIR := findWarning(step, models.WarningNonFHIRLines)
+	require.NotNil(t, nonFHIR)
+	assert.Equal(t, 1, nonFHIR.Count)
+
+	events, err := services.LoadJobEvents(jobsDir, job.JobID)
+	require.NoError(t, err)
+	warningEvents := 0
+	for _, event := range events {
+		if event.Type == models.EventStepWarning {
+			warningEvents++
+		}
+	}
+	assert.Equal(t, 3, warningEvents)
+	assert.Contains(t, pipeline.GetJobSummary(job), "Warnings: 3")
+}
+
+func TestExecuteDIMPStep_CleanFileHasNoWarnings(t *testing.T) {
+	server := createMockDIMPServer()
+	defer server.Close()
+
+	job := createDIMPTestJob(server.URL)
+	job.Config.JobsDir = t.TempDir()
+	jobDir := services.GetJobDir(job.Config.JobsDir, job.JobID)
+	importDir := filepath.Join(jobDir, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0755))
+	writeDIMPNDJSON(t, filepath.Join(importDir, "Patient.ndjson"), []map[string]any{
+		{"resourceType": "Patient", "id": "p1"},
+	})
+
+	require.NoError(t, pipeline.ExecuteDIMPStep(context.Background(), job, jobDir, createDIMPTestLogger(), lib.NoProgress))
+	assert.Zero(t, job.WarningCount())
+	assert.NotContains(t, pipeline.GetJobSummary(job), "Warnings")
+}
+
+func TestExecuteImportStep_RecordsExcludedAndIgnoredFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	patient := `{"resourceType":"Patient","id":"p1"}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "Patient.ndjson"), []byte(patient), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "Patient.test.ndjson"), []byte(patient), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "README.txt"), []byte("export"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, ".checksum"), []byte("x"), 0644))
+
+	jobsDir := t.TempDir()
+	logger := lib.NewLogger(lib.LogLevelError)
+	retryConfig := models.RetryConfig{MaxAttempts: 1, InitialBackoffMs: 100, MaxBackoffMs: 1000}
+	httpClient := services.NewHTTPClient(5*time.Second, retryConfig, logger)
+
+	job := &models.PipelineJob{
+		JobID:       "test-warnings-job",
+		InputSource: sourceDir,
+		InputType:   models.InputTypeLocal,
+		CurrentStep: string(models.StepLocalImport),
+		Status:      models.JobStatusInProgress,
+		Steps:       models.InitializeSteps([]models.StepName{models.StepLocalImport}),
+		Config: models.ProjectConfig{
+			JobsDir:  jobsDir,
+			Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+			Retry:    retryConfig,
+			Import:   models.ImportConfig{Exclude: []string{"*.test.ndjson"}},
+		},
+	}
+
+	updatedJob, err := pipeline.ExecuteImportStep(context.Background(), job, logger, httpClient, lib.NoProgress)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updatedJob.TotalFiles)
+
+	step, found := models.GetStepByName(*updatedJob, models.StepLocalImport)
+	require.True(t, found)
+	assert.Equal(t, models.StepStatusCompleted, step.Status)
+
+	excluded := findWarning(step, models.WarningFilesExcluded)
+	require.NotNil(t, excluded)
+	assert.Equal(t, 1, excluded.Count)
+	assert.Contains(t, excluded.Message, "Patient.test.ndjson")
+
+	ignored := findWarning(step, models.WarningFilesIgnored)
+	require.NotNil(t, ignored)
+	assert.Equal(t, 1, ignored.Count, "hidden files are not reported")
+	assert.Contains(t, ignored.Message, "README.txt")
+}
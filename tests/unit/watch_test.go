@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trobanga/aether/internal/lib"
+	"github.com/trobanga/aether/internal/models"
+	"github.com/trobanga/aether/internal/pipeline"
+	"github.com/trobanga/aether/internal/services"
+)
+
+// TestDropWatcher_StabilityWindow verifies filesets are reported once, after staying unchanged for the window
+func TestDropWatcher_StabilityWindow(t *testing.T) {
+	dropDir := t.TempDir()
+	export := filepath.Join(dropDir, "export-001")
+	require.NoError(t, os.MkdirAll(export, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(export, "Patient.ndjson"), []byte(`{"resourceType":"Patient","id":"p1"}`+"\n"), 0644))
+
+	// Ignored: hidden upload directory and a directory without NDJSON files
+	require.NoError(t, os.MkdirAll(filepath.Join(dropDir, ".incoming"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dropDir, ".incoming", "Patient.ndjson"), []byte("{}\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dropDir, "empty"), 0755))
+
+	watcher, err := services.NewDropWatcher(dropDir, time.Minute, models.JobMetadataConfig{})
+	require.NoError(t, err)
+	start := time.Now()
+
+	ready, err := watcher.Poll(start)
+	require.NoError(t, err)
+	assert.Empty(t, ready, "first sighting starts the stability window")
+
+	// A change restarts the window
+	require.NoError(t, os.WriteFile(filepath.Join(export, "Observation.ndjson"), []byte(`{"resourceType":"Observation","id":"o1"}`+"\n"), 0644))
+	ready, err = watcher.Poll(start.Add(30 * time.Second))
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+
+	ready, err = watcher.Poll(start.Add(60 * time.Second))
+	require.NoError(t, err)
+	assert.Empty(t, ready, "window restarted by the new file")
+
+	ready, err = watcher.Poll(start.Add(91 * time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, []string{export}, ready)
+
+	// Reported filesets are not returned again
+	ready, err = watcher.Poll(start.Add(10 * time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+}
+
+// TestDropWatcher_SkipsProcessedFilesets verifies filesets used by existing jobs are not reported
+func TestDropWatcher_SkipsProcessedFilesets(t *testing.T) {
+	dropDir := t.TempDir()
+	jobsDir := t.TempDir()
+	export := filepath.Join(dropDir, "export-001")
+	require.NoError(t, os.MkdirAll(export, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(export, "Patient.ndjson"), []byte("{}\n"), 0644))
+
+	config := models.ProjectConfig{
+		Pipeline: models.PipelineConfig{EnabledSteps: []models.StepName{models.StepLocalImport}},
+		Retry:    models.RetryConfig{MaxAttempts: 3, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+		JobsDir:  jobsDir,
+	}
+	_, err := pipeline.CreateJob(export, config, lib.NewLogger(lib.LogLevelError))
+	require.NoError(t, err)
+
+	processed, err := services.ProcessedJobSources(jobsDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{export}, processed)
+
+	watcher, err := services.NewDropWatcher(dropDir, 0, models.JobMetadataConfig{})
+	require.NoError(t, err)
+	for _, source := range processed {
+		watcher.MarkProcessed(source)
+	}
+
+	now := time.Now()
+	_, err = watcher.Poll(now)
+	require.NoError(t, err)
+	ready, err := watcher.Poll(now)
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+}
+
+// TestNewDropWatcher_InvalidDirectory verifies a missing drop directory is rejected
+func TestNewDropWatcher_InvalidDirectory(t *testing.T) {
+	_, err := services.NewDropWatcher(filepath.Join(t.TempDir(), "missing"), time.Minute, models.JobMetadataConfig{})
+	assert.Error(t, err)
+}